@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -18,6 +19,7 @@ import (
 	"github.com/naiba/bonds/internal/dav"
 	"github.com/naiba/bonds/internal/frontend"
 	"github.com/naiba/bonds/internal/handlers"
+	"github.com/naiba/bonds/internal/mailin"
 	appMiddleware "github.com/naiba/bonds/internal/middleware"
 	"github.com/naiba/bonds/internal/models"
 	"github.com/naiba/bonds/internal/services"
@@ -98,17 +100,44 @@ func main() {
 	if err := models.BackfillMonicaActivityNotes(db); err != nil {
 		log.Printf("WARNING: failed to migrate Monica activity notes: %v", err)
 	}
+	if err := models.BackfillVaultChanges(db); err != nil {
+		log.Printf("WARNING: failed to backfill the vault change log: %v", err)
+	}
+	if ambiguous, err := models.BackfillPhoneNumbers(db); err != nil {
+		log.Printf("WARNING: failed to normalize phone numbers: %v", err)
+	} else if ambiguous > 0 {
+		log.Printf("WARNING: %d phone numbers were not normalized because the users of their account are in different phone regions; they are normalized when a user of the account sets a phone region", ambiguous)
+	}
 	scheduler := cron.NewScheduler(db)
 	scheduler.Start()
 
 	mailer := services.NewDynamicMailer(systemSettingService)
 	notificationSender := services.NewShoutrrrSender()
 	reminderScheduler := services.NewReminderSchedulerService(db, mailer, notificationSender)
+	webPushService := services.NewWebPushService(systemSettingService)
+	reminderScheduler.SetWebPushSender(webPushService)
+	reminderActionService := services.NewReminderActionService(db, cfg.JWT.Secret, cfg.App.URL)
+	reminderActionService.SetSystemSettings(systemSettingService)
+	reminderScheduler.SetActionService(reminderActionService)
 	if err := scheduler.RegisterJob("0 * * * * *", "process_reminders", func() {
 		reminderScheduler.ProcessDueReminders()
 	}); err != nil {
 		log.Printf("WARNING: Failed to register reminder cron job: %v", err)
 	}
+	taskNotificationService := services.NewTaskNotificationService(db, mailer, notificationSender)
+	taskNotificationService.SetWebPushSender(webPushService)
+	if err := scheduler.RegisterJob("0 * * * * *", "process_task_notifications", func() {
+		taskNotificationService.ProcessTaskNotifications()
+	}); err != nil {
+		log.Printf("WARNING: Failed to register task notification cron job: %v", err)
+	}
+	memoryService := services.NewMemoryService(db, mailer, notificationSender)
+	memoryService.SetWebPushSender(webPushService)
+	if err := scheduler.RegisterJob("0 */5 * * * *", "process_memory_notifications", func() {
+		memoryService.ProcessDailyNotifications()
+	}); err != nil {
+		log.Printf("WARNING: Failed to register memory notification cron job: %v", err)
+	}
 
 	vcardService := services.NewVCardService(db)
 	davClientService := services.NewDavClientService(db, cfg.JWT.Secret)
@@ -123,6 +152,25 @@ func main() {
 		log.Printf("WARNING: Failed to register DAV sync cron job: %v", err)
 	}
 
+	idempotencyService := services.NewIdempotencyService(db)
+	if err := scheduler.RegisterJob("0 0 * * * *", "cleanup_idempotency_keys", func() {
+		if _, err := idempotencyService.CleanupExpired(); err != nil {
+			log.Printf("[cron] cleanup_idempotency_keys error: %v", err)
+		}
+	}); err != nil {
+		log.Printf("WARNING: Failed to register idempotency key cleanup cron job: %v", err)
+	}
+
+	automationService := services.NewAutomationService(db)
+	automationService.SetSystemSettings(systemSettingService)
+	if err := scheduler.RegisterJob("0 30 3 * * *", "cleanup_automation_log", func() {
+		if _, err := automationService.CleanupExecutions(); err != nil {
+			log.Printf("[cron] cleanup_automation_log error: %v", err)
+		}
+	}); err != nil {
+		log.Printf("WARNING: Failed to register automation log cleanup cron job: %v", err)
+	}
+
 	backupService := services.NewBackupService(db, cfg)
 	backupService.SetSystemSettings(systemSettingService)
 	// reloadBackup keeps the backup cron job in sync with the backup.cron
@@ -152,9 +200,46 @@ func main() {
 	e.Use(echoMiddleware.Recover())
 	e.Use(appMiddleware.Locale())
 
-	handlers.RegisterRoutes(e, db, cfg, Version, reloadBackup)
+	workers := handlers.RegisterRoutes(e, db, cfg, Version, reloadBackup)
+	jobService := workers.Jobs
+	jobService.SetLocker(scheduler)
+	// Jobs start as soon as they are queued; the cron job picks up the ones
+	// left behind by a restart or queued on another replica.
+	if err := scheduler.RegisterJob("30 * * * * *", "run_background_jobs", func() {
+		jobService.RunPending()
+	}); err != nil {
+		log.Printf("WARNING: Failed to register background job cron job: %v", err)
+	}
+	// Automation rules run once the write that queued them has committed;
+	// the cron job picks up the rest, like it does for jobs.
+	if err := scheduler.RegisterJob("40 * * * * *", "run_automation_rules", func() {
+		workers.Automation.RunPending()
+	}); err != nil {
+		log.Printf("WARNING: Failed to register automation rule cron job: %v", err)
+	}
+	if err := scheduler.RegisterJob("0 45 3 * * *", "cleanup_jobs", func() {
+		if _, err := jobService.Cleanup(); err != nil {
+			log.Printf("[cron] cleanup_jobs error: %v", err)
+		}
+	}); err != nil {
+		log.Printf("WARNING: Failed to register job cleanup cron job: %v", err)
+	}
+	if err := scheduler.RegisterJob("15 * * * * *", "poll_inbound_mail", func() {
+		if _, err := workers.EmailIngest.PollIMAP(); err != nil {
+			log.Printf("[cron] poll_inbound_mail error: %v", err)
+		}
+	}); err != nil {
+		log.Printf("WARNING: Failed to register inbound mail cron job: %v", err)
+	}
+	if err := scheduler.RegisterJob("45 * * * * *", "geocode_addresses", func() {
+		if _, err := workers.Geocoding.ProcessQueue(); err != nil {
+			log.Printf("[cron] geocode_addresses error: %v", err)
+		}
+	}); err != nil {
+		log.Printf("WARNING: Failed to register geocoding cron job: %v", err)
+	}
 
-	dav.SetupDAVRoutes(e, db)
+	dav.SetupDAVRoutes(e, db, services.NewLDAPService(db, systemSettingService))
 
 	if frontend.HasDistFiles() {
 		frontend.RegisterSPARoutes(e)
@@ -166,6 +251,36 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	var inboundSMTP *mailin.SMTPServer
+	if cfg.InboundMail.SMTPListen != "" {
+		listener, err := net.Listen("tcp", cfg.InboundMail.SMTPListen)
+		if err != nil {
+			log.Fatalf("Failed to listen for inbound mail: %v", err)
+		}
+		var maxSize int64
+		if mb := systemSettingService.GetInt64("storage.max_size_mb", 0); mb > 0 {
+			// Leave room for the base64 encoding of attachments.
+			maxSize = 2 * mb * 1024 * 1024
+		}
+		inboundSMTP = &mailin.SMTPServer{
+			Domain:  cfg.InboundMail.SMTPDomain,
+			MaxSize: maxSize,
+			Accept: func(rcpt string) bool {
+				return workers.EmailIngest.Accepts(rcpt)
+			},
+			Deliver: func(from string, rcpts []string, data []byte) error {
+				_, err := workers.EmailIngest.Ingest(data, rcpts)
+				return err
+			},
+		}
+		go func() {
+			log.Printf("Receiving inbound mail on %s", cfg.InboundMail.SMTPListen)
+			if err := inboundSMTP.Serve(listener); err != nil {
+				log.Printf("Inbound mail receiver stopped: %v", err)
+			}
+		}()
+	}
+
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
 	go func() {
 		log.Printf("Starting server on %s", addr)
@@ -177,6 +292,10 @@ func main() {
 	<-ctx.Done()
 	log.Println("Shutting down...")
 
+	if inboundSMTP != nil {
+		inboundSMTP.Close()
+	}
+
 	cronCtx := scheduler.Stop()
 	select {
 	case <-cronCtx.Done():
@@ -0,0 +1,183 @@
+package chatlog
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// androidBackup is a calls.xml or sms.xml of "SMS Backup & Restore".
+type androidBackup struct {
+	Calls []struct {
+		Number      string `xml:"number,attr"`
+		Duration    int    `xml:"duration,attr"`
+		Date        int64  `xml:"date,attr"`
+		Type        int    `xml:"type,attr"`
+		ContactName string `xml:"contact_name,attr"`
+	} `xml:"call"`
+	SMS []struct {
+		Address     string `xml:"address,attr"`
+		Date        int64  `xml:"date,attr"`
+		Type        int    `xml:"type,attr"`
+		Body        string `xml:"body,attr"`
+		ContactName string `xml:"contact_name,attr"`
+	} `xml:"sms"`
+	MMS []struct {
+		Address     string `xml:"address,attr"`
+		Date        int64  `xml:"date,attr"`
+		MsgBox      int    `xml:"msg_box,attr"`
+		ContactName string `xml:"contact_name,attr"`
+		Parts       []struct {
+			ContentType string `xml:"ct,attr"`
+			Text        string `xml:"text,attr"`
+		} `xml:"parts>part"`
+		Addrs []struct {
+			Address string `xml:"address,attr"`
+			Type    int    `xml:"type,attr"`
+		} `xml:"addrs>addr"`
+	} `xml:"mms"`
+}
+
+// Android call log types.
+const (
+	androidCallIncoming  = 1
+	androidCallOutgoing  = 2
+	androidCallMissed    = 3
+	androidCallVoicemail = 4
+	androidCallRejected  = 5
+	androidCallBlocked   = 6
+	androidCallExternal  = 7
+)
+
+// Android message boxes.
+const (
+	androidMessageInbox = 1
+	androidMessageSent  = 2
+	// androidMMSFrom is the addr type of an MMS sender.
+	androidMMSFrom = 137
+)
+
+func parseAndroid(data []byte, opts Options) (*History, error) {
+	var backup androidBackup
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// The app writes XML 1.0 declarations but may leave control characters
+	// of message bodies in attributes.
+	decoder.Strict = false
+	if err := decoder.Decode(&backup); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	if len(backup.Calls) == 0 && len(backup.SMS) == 0 && len(backup.MMS) == 0 {
+		return nil, fmt.Errorf("%w: no calls or messages found", ErrInvalidExport)
+	}
+
+	h := &History{}
+	for _, c := range backup.Calls {
+		call := Call{
+			Time:        time.UnixMilli(c.Date).In(opts.Location),
+			Participant: androidParticipant(c.Number, c.ContactName),
+			Duration:    time.Duration(c.Duration) * time.Second,
+		}
+		switch c.Type {
+		case androidCallIncoming, androidCallExternal:
+			call.Direction = CallIncoming
+		case androidCallOutgoing:
+			call.Direction = CallOutgoing
+		case androidCallMissed, androidCallVoicemail:
+			call.Direction = CallMissed
+		case androidCallRejected:
+			call.Direction = CallRejected
+		default:
+			// Blocked calls never reached the owner.
+			continue
+		}
+		if call.Participant.Phone == "" {
+			continue
+		}
+		h.Calls = append(h.Calls, call)
+	}
+
+	threads := map[string]*Thread{}
+	var order []string
+	add := func(addresses []string, name, sender string, fromMe bool, t time.Time, text string) {
+		var participants []Participant
+		var keys []string
+		for _, address := range addresses {
+			if p := androidParticipant(address, ""); p.Phone != "" {
+				participants = append(participants, p)
+				keys = append(keys, p.Key())
+			}
+		}
+		if len(participants) == 0 {
+			return
+		}
+		if len(participants) == 1 {
+			participants[0] = androidParticipant(addresses[0], name)
+		}
+		sort.Strings(keys)
+		key := strings.Join(keys, ",")
+		thread, ok := threads[key]
+		if !ok {
+			thread = &Thread{Key: key, Participants: participants}
+			labels := make([]string, len(participants))
+			for i, p := range participants {
+				labels[i] = p.Label()
+			}
+			thread.Name = strings.Join(labels, ", ")
+			threads[key] = thread
+			order = append(order, key)
+		}
+		thread.Messages = append(thread.Messages, Message{Time: t, Sender: sender, FromMe: fromMe, Text: strings.TrimSpace(text)})
+	}
+	for _, m := range backup.SMS {
+		if m.Type != androidMessageInbox && m.Type != androidMessageSent {
+			continue
+		}
+		fromMe := m.Type == androidMessageSent
+		sender := m.Address
+		if fromMe {
+			sender = ""
+		}
+		add([]string{m.Address}, m.ContactName, sender, fromMe, time.UnixMilli(m.Date).In(opts.Location), m.Body)
+	}
+	for _, m := range backup.MMS {
+		if m.MsgBox != androidMessageInbox && m.MsgBox != androidMessageSent {
+			continue
+		}
+		var text []string
+		for _, part := range m.Parts {
+			if part.ContentType == "text/plain" && part.Text != "" && part.Text != "null" {
+				text = append(text, part.Text)
+			}
+		}
+		fromMe := m.MsgBox == androidMessageSent
+		sender := ""
+		if !fromMe {
+			for _, addr := range m.Addrs {
+				if addr.Type == androidMMSFrom {
+					sender = addr.Address
+				}
+			}
+		}
+		add(strings.Split(m.Address, "~"), m.ContactName, sender, fromMe, time.UnixMilli(m.Date).In(opts.Location), strings.Join(text, "\n"))
+	}
+	for _, key := range order {
+		h.Threads = append(h.Threads, *threads[key])
+	}
+	return h, nil
+}
+
+// androidParticipant ignores the placeholders the app writes for numbers
+// without a contact.
+func androidParticipant(number, name string) Participant {
+	p := Participant{Phone: strings.TrimSpace(number)}
+	if len(digits(p.Phone)) < 3 {
+		p.Phone = ""
+	}
+	if name = strings.TrimSpace(name); name != "" && name != "(Unknown)" && name != "null" {
+		p.Name = name
+	}
+	return p
+}
@@ -0,0 +1,173 @@
+// Package chatlog reads the message and call history that phones and chat
+// apps export: WhatsApp chat exports, Telegram Desktop JSON exports and the
+// XML backups of the Android app "SMS Backup & Restore".
+package chatlog
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Export formats.
+const (
+	FormatWhatsApp = "whatsapp"
+	FormatTelegram = "telegram"
+	FormatAndroid  = "android"
+)
+
+// Call directions. Rejected calls are incoming calls the owner declined.
+const (
+	CallIncoming = "incoming"
+	CallOutgoing = "outgoing"
+	CallMissed   = "missed"
+	CallRejected = "rejected"
+)
+
+// maxChatSize bounds the chat read from a WhatsApp archive.
+const maxChatSize = 50 << 20
+
+var (
+	ErrUnknownFormat = errors.New("unknown export format")
+	ErrInvalidExport = errors.New("invalid export")
+)
+
+// Options tell the parsers what the export itself does not.
+type Options struct {
+	// Owner is the name the exporting user appears under, used to tell
+	// their messages apart when the export does not say.
+	Owner string
+	// Filename is the name of the uploaded file. WhatsApp puts the chat
+	// name in it.
+	Filename string
+	// Location is the time zone of timestamps without one. Defaults to UTC.
+	Location *time.Location
+}
+
+// Participant is someone the owner talked to, as the export names them.
+// Either field may be empty.
+type Participant struct {
+	Name  string
+	Phone string
+}
+
+// Key identifies the participant within one export.
+func (p Participant) Key() string {
+	if p.Phone != "" {
+		return "phone:" + digits(p.Phone)
+	}
+	return "name:" + strings.ToLower(strings.TrimSpace(p.Name))
+}
+
+// Label is how the participant is shown to the user.
+func (p Participant) Label() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.Phone
+}
+
+type Message struct {
+	Time   time.Time
+	Sender string
+	FromMe bool
+	Text   string
+}
+
+// Thread is one conversation. Participants are the other people in it.
+type Thread struct {
+	// Key identifies the conversation, so importing a later export of the
+	// same chat finds what an earlier one created.
+	Key          string
+	Name         string
+	Participants []Participant
+	Messages     []Message
+}
+
+type Call struct {
+	Time        time.Time
+	Participant Participant
+	Direction   string
+	Duration    time.Duration
+}
+
+// Answered reports whether the call connected.
+func (c Call) Answered() bool {
+	switch c.Direction {
+	case CallMissed, CallRejected:
+		return false
+	case CallOutgoing:
+		return c.Duration > 0
+	}
+	return true
+}
+
+type History struct {
+	Threads []Thread
+	Calls   []Call
+}
+
+// Parse reads an export of the given format.
+func Parse(format string, data []byte, opts Options) (*History, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	var (
+		h   *History
+		err error
+	)
+	switch format {
+	case FormatWhatsApp:
+		h, err = parseWhatsApp(data, opts)
+	case FormatTelegram:
+		h, err = parseTelegram(data, opts)
+	case FormatAndroid:
+		h, err = parseAndroid(data, opts)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range h.Threads {
+		sort.SliceStable(h.Threads[i].Messages, func(a, b int) bool {
+			return h.Threads[i].Messages[a].Time.Before(h.Threads[i].Messages[b].Time)
+		})
+	}
+	sort.SliceStable(h.Calls, func(a, b int) bool { return h.Calls[a].Time.Before(h.Calls[b].Time) })
+	return h, nil
+}
+
+// looksLikePhone reports whether a sender name is really a phone number,
+// as chat apps show people who are not in the address book.
+func looksLikePhone(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("+0123456789 -().\u00a0\u202a\u202c", r) {
+			return false
+		}
+	}
+	return len(digits(s)) >= 6
+}
+
+func participantFor(sender string) Participant {
+	sender = strings.Trim(strings.TrimSpace(sender), "\u202a\u202c")
+	if looksLikePhone(sender) {
+		return Participant{Phone: sender}
+	}
+	return Participant{Name: sender}
+}
+
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,203 @@
+package chatlog
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const androidWhatsApp = "31/12/2023, 21:15 - Messages and calls are end-to-end encrypted.\n" +
+	"31/12/2023, 21:15 - Alice Smith: Happy new year!\n" +
+	"See you tomorrow\n" +
+	"31/12/2023, 21:17 - Test User: You too\n" +
+	"01/01/2024, 09:02 - Alice Smith: <Media omitted>\n"
+
+func TestParseWhatsAppAndroid(t *testing.T) {
+	h, err := Parse(FormatWhatsApp, []byte(androidWhatsApp), Options{Filename: "WhatsApp Chat with Alice Smith.txt"})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(h.Threads) != 1 {
+		t.Fatalf("expected 1 thread, got %d", len(h.Threads))
+	}
+	thread := h.Threads[0]
+	if thread.Name != "Alice Smith" || thread.Key != "chat:alice smith" {
+		t.Errorf("unexpected thread %q %q", thread.Name, thread.Key)
+	}
+	if len(thread.Participants) != 1 || thread.Participants[0].Name != "Alice Smith" {
+		t.Errorf("participants = %+v", thread.Participants)
+	}
+	if len(thread.Messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(thread.Messages))
+	}
+	first := thread.Messages[0]
+	if first.Text != "Happy new year!\nSee you tomorrow" || first.FromMe {
+		t.Errorf("first message = %+v", first)
+	}
+	if !first.Time.Equal(time.Date(2023, 12, 31, 21, 15, 0, 0, time.UTC)) {
+		t.Errorf("first time = %v", first.Time)
+	}
+	if !thread.Messages[1].FromMe {
+		t.Error("expected the owner's reply to be from them")
+	}
+}
+
+func TestParseWhatsAppIOSTwelveHour(t *testing.T) {
+	chat := "\u200e[1/2/24, 9:05:10\u202fPM] Bob: Hi\n" +
+		"[1/2/24, 12:30:00\u202fAM] +1 (555) 010-2000: Who is this?\n" +
+		"[1/3/24, 8:00:00\u202fAM] Me: Hello both\n"
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("_chat.txt")
+	w.Write([]byte(chat))
+	zw.Close()
+
+	h, err := Parse(FormatWhatsApp, buf.Bytes(), Options{Filename: "WhatsApp Chat - Weekend.zip", Owner: "me"})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	thread := h.Threads[0]
+	if thread.Name != "Weekend" || len(thread.Participants) != 2 || thread.Participants[1].Phone != "+1 (555) 010-2000" {
+		t.Errorf("unexpected thread %+v", thread)
+	}
+	// Without a day above 12, 12-hour times mean month first.
+	if got := thread.Messages[0].Time; !got.Equal(time.Date(2024, 1, 2, 0, 30, 0, 0, time.UTC)) {
+		t.Errorf("first message at %v", got)
+	}
+	if got := thread.Messages[1].Time; got.Hour() != 21 {
+		t.Errorf("PM message at %v", got)
+	}
+	if !thread.Messages[2].FromMe {
+		t.Error("expected the Owner option to mark the owner's messages")
+	}
+}
+
+func TestParseWhatsAppRejectsOtherText(t *testing.T) {
+	_, err := Parse(FormatWhatsApp, []byte("just some notes\n"), Options{})
+	if !errors.Is(err, ErrInvalidExport) {
+		t.Errorf("expected ErrInvalidExport, got %v", err)
+	}
+	if _, err := Parse("signal", nil, Options{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestParseTelegramChat(t *testing.T) {
+	export := `{
+	  "name": "Alice Smith", "type": "personal_chat", "id": 42,
+	  "messages": [
+	    {"id": 1, "type": "message", "date": "2024-03-01T10:00:00", "date_unixtime": "1709287200", "from": "Alice Smith", "from_id": "user42", "text": "Coffee?"},
+	    {"id": 2, "type": "message", "date": "2024-03-01T10:01:00", "date_unixtime": "1709287260", "from": "Me", "from_id": "user7", "text": ["Sure, ", {"type": "bold", "text": "at 3"}]},
+	    {"id": 3, "type": "message", "date": "2024-03-01T10:02:00", "date_unixtime": "1709287320", "from": "Alice Smith", "from_id": "user42", "text": "", "photo": "photos/1.jpg"},
+	    {"id": 4, "type": "service", "date": "2024-03-02T18:00:00", "date_unixtime": "1709402400", "actor": "Me", "actor_id": "user7", "action": "phone_call", "duration_seconds": 125, "discard_reason": "hangup"},
+	    {"id": 5, "type": "service", "date": "2024-03-03T18:00:00", "date_unixtime": "1709488800", "actor": "Alice Smith", "actor_id": "user42", "action": "phone_call", "discard_reason": "missed"}
+	  ]
+	}`
+	h, err := Parse(FormatTelegram, []byte(export), Options{})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(h.Threads) != 1 || h.Threads[0].Key != "chat:42" {
+		t.Fatalf("unexpected threads %+v", h.Threads)
+	}
+	thread := h.Threads[0]
+	if len(thread.Messages) != 3 || thread.Messages[1].Text != "Sure, at 3" || !thread.Messages[1].FromMe || thread.Messages[2].Text != "[photo]" {
+		t.Errorf("unexpected messages %+v", thread.Messages)
+	}
+	if len(thread.Participants) != 1 || thread.Participants[0].Name != "Alice Smith" {
+		t.Errorf("participants = %+v", thread.Participants)
+	}
+	if len(h.Calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(h.Calls))
+	}
+	if c := h.Calls[0]; c.Direction != CallOutgoing || c.Duration != 125*time.Second || !c.Answered() {
+		t.Errorf("first call = %+v", c)
+	}
+	if c := h.Calls[1]; c.Direction != CallMissed || c.Answered() {
+		t.Errorf("second call = %+v", c)
+	}
+}
+
+func TestParseTelegramFullExport(t *testing.T) {
+	export := `{
+	  "personal_information": {"user_id": 7, "first_name": "Test"},
+	  "contacts": {"list": [{"first_name": "Bob", "last_name": "Jones", "phone_number": "+44 20 7946 0000"}]},
+	  "chats": {"list": [
+	    {"name": "Hiking", "type": "private_group", "id": 99, "messages": [
+	      {"type": "message", "date": "2024-05-01T08:00:00", "from": "Bob Jones", "from_id": "user5", "text": "Ready?"},
+	      {"type": "message", "date": "2024-05-01T08:01:00", "from": "Test", "from_id": "user7", "text": "Yes"}
+	    ]},
+	    {"name": "News", "type": "public_channel", "id": 100, "messages": [
+	      {"type": "message", "date": "2024-05-01T08:00:00", "from": "News", "from_id": "channel100", "text": "Headline"}
+	    ]}
+	  ]}
+	}`
+	h, err := Parse(FormatTelegram, []byte(export), Options{})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(h.Threads) != 1 || h.Threads[0].Name != "Hiking" {
+		t.Fatalf("expected only the group, got %+v", h.Threads)
+	}
+	thread := h.Threads[0]
+	if len(thread.Participants) != 1 || thread.Participants[0].Phone != "+44 20 7946 0000" {
+		t.Errorf("expected Bob with his phone, got %+v", thread.Participants)
+	}
+	if thread.Messages[0].FromMe || !thread.Messages[1].FromMe {
+		t.Errorf("unexpected owners %+v", thread.Messages)
+	}
+}
+
+func TestParseAndroid(t *testing.T) {
+	calls := `<?xml version='1.0' encoding='UTF-8' standalone='yes' ?>
+<calls count="4">
+  <call number="+15550102000" duration="65" date="1704067200000" type="1" contact_name="Alice Smith" />
+  <call number="5550103000" duration="0" date="1704070800000" type="2" contact_name="(Unknown)" />
+  <call number="+15550102000" duration="0" date="1704074400000" type="3" contact_name="Alice Smith" />
+  <call number="+15550109999" duration="0" date="1704078000000" type="6" contact_name="null" />
+</calls>`
+	h, err := Parse(FormatAndroid, []byte(calls), Options{})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(h.Calls) != 3 {
+		t.Fatalf("expected the blocked call to be left out, got %d calls", len(h.Calls))
+	}
+	if c := h.Calls[0]; c.Direction != CallIncoming || c.Duration != 65*time.Second || c.Participant.Name != "Alice Smith" {
+		t.Errorf("first call = %+v", c)
+	}
+	if c := h.Calls[1]; c.Direction != CallOutgoing || c.Answered() || c.Participant.Name != "" {
+		t.Errorf("unanswered outgoing call = %+v", c)
+	}
+
+	sms := `<?xml version='1.0' encoding='UTF-8' standalone='yes' ?>
+<smses count="3">
+  <sms address="+15550102000" date="1704067200000" type="1" body="Running late" contact_name="Alice Smith" />
+  <sms address="+15550102000" date="1704067260000" type="2" body="No worries" contact_name="Alice Smith" />
+  <mms address="+15550102000~+15550104000" date="1704153600000" msg_box="1" contact_name="Alice Smith, Carol">
+    <parts><part ct="application/smil" text="null" /><part ct="text/plain" text="Group photo" /></parts>
+    <addrs><addr address="+15550104000" type="137" /><addr address="+15550102000" type="151" /></addrs>
+  </mms>
+</smses>`
+	h, err = Parse(FormatAndroid, []byte(sms), Options{})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(h.Threads) != 2 {
+		t.Fatalf("expected a direct and a group thread, got %d", len(h.Threads))
+	}
+	direct := h.Threads[0]
+	if direct.Name != "Alice Smith" || len(direct.Messages) != 2 || !direct.Messages[1].FromMe {
+		t.Errorf("unexpected direct thread %+v", direct)
+	}
+	group := h.Threads[1]
+	if len(group.Participants) != 2 || group.Messages[0].Text != "Group photo" || group.Messages[0].Sender != "+15550104000" {
+		t.Errorf("unexpected group thread %+v", group)
+	}
+	if !strings.Contains(group.Key, "15550104000") {
+		t.Errorf("group key = %q", group.Key)
+	}
+}
@@ -0,0 +1,232 @@
+package chatlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telegramExport is the result.json of Telegram Desktop, either of a single
+// chat or of the whole account.
+type telegramExport struct {
+	PersonalInformation *struct {
+		UserID    int64  `json:"user_id"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	} `json:"personal_information"`
+	Contacts *struct {
+		List []struct {
+			FirstName   string `json:"first_name"`
+			LastName    string `json:"last_name"`
+			PhoneNumber string `json:"phone_number"`
+		} `json:"list"`
+	} `json:"contacts"`
+	Chats *struct {
+		List []telegramChat `json:"list"`
+	} `json:"chats"`
+	telegramChat
+}
+
+type telegramChat struct {
+	Name     *string           `json:"name"`
+	Type     string            `json:"type"`
+	ID       int64             `json:"id"`
+	Messages []telegramMessage `json:"messages"`
+}
+
+type telegramMessage struct {
+	Type            string          `json:"type"`
+	Date            string          `json:"date"`
+	DateUnixtime    string          `json:"date_unixtime"`
+	From            *string         `json:"from"`
+	FromID          string          `json:"from_id"`
+	Actor           *string         `json:"actor"`
+	ActorID         string          `json:"actor_id"`
+	Action          string          `json:"action"`
+	DurationSeconds int             `json:"duration_seconds"`
+	DiscardReason   string          `json:"discard_reason"`
+	Text            json.RawMessage `json:"text"`
+	MediaType       string          `json:"media_type"`
+	Photo           string          `json:"photo"`
+	File            string          `json:"file"`
+}
+
+// telegramChatTypes are the chats with people. Channels, bots and saved
+// messages are left out.
+var telegramChatTypes = map[string]bool{
+	"personal_chat":      true,
+	"private_group":      true,
+	"private_supergroup": true,
+	"public_supergroup":  true,
+}
+
+func parseTelegram(data []byte, opts Options) (*History, error) {
+	var export telegramExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	chats := []telegramChat{export.telegramChat}
+	if export.Chats != nil {
+		chats = export.Chats.List
+	}
+	ownerID := ""
+	if export.PersonalInformation != nil && export.PersonalInformation.UserID != 0 {
+		ownerID = "user" + strconv.FormatInt(export.PersonalInformation.UserID, 10)
+	}
+	phones := map[string]string{}
+	if export.Contacts != nil {
+		for _, c := range export.Contacts.List {
+			name := strings.ToLower(strings.TrimSpace(c.FirstName + " " + c.LastName))
+			if name != "" && c.PhoneNumber != "" {
+				phones[name] = c.PhoneNumber
+			}
+		}
+	}
+
+	h := &History{}
+	found := false
+	for _, chat := range chats {
+		if !telegramChatTypes[chat.Type] {
+			continue
+		}
+		found = true
+		// In a personal chat the chat's ID is the other person's.
+		otherID := ""
+		if chat.Type == "personal_chat" {
+			otherID = "user" + strconv.FormatInt(chat.ID, 10)
+		}
+		isOwner := func(id, name string) bool {
+			switch {
+			case ownerID != "":
+				return id == ownerID
+			case otherID != "":
+				return id != otherID
+			}
+			return opts.Owner != "" && strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(opts.Owner))
+		}
+		participant := func(name string) Participant {
+			p := participantFor(name)
+			if p.Phone == "" {
+				p.Phone = phones[strings.ToLower(p.Name)]
+			}
+			return p
+		}
+
+		thread := Thread{Key: "chat:" + strconv.FormatInt(chat.ID, 10)}
+		if chat.Name != nil {
+			thread.Name = *chat.Name
+		}
+		seen := map[string]bool{}
+		for _, m := range chat.Messages {
+			t, ok := telegramTime(m, opts.Location)
+			if !ok {
+				continue
+			}
+			if m.Type == "service" {
+				if m.Action == "phone_call" && otherID != "" && thread.Name != "" {
+					h.Calls = append(h.Calls, telegramCall(m, t, participant(thread.Name), isOwner(m.ActorID, ptrValue(m.Actor))))
+				}
+				continue
+			}
+			if m.Type != "message" {
+				continue
+			}
+			sender := ptrValue(m.From)
+			fromMe := isOwner(m.FromID, sender)
+			if !fromMe && sender != "" && !seen[m.FromID] {
+				seen[m.FromID] = true
+				thread.Participants = append(thread.Participants, participant(sender))
+			}
+			text := telegramText(m.Text)
+			if text == "" {
+				text = telegramMedia(m)
+			}
+			thread.Messages = append(thread.Messages, Message{Time: t, Sender: sender, FromMe: fromMe, Text: text})
+		}
+		if len(thread.Participants) == 0 && otherID != "" && thread.Name != "" {
+			thread.Participants = append(thread.Participants, participant(thread.Name))
+		}
+		if len(thread.Messages) > 0 {
+			h.Threads = append(h.Threads, thread)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: no Telegram chats found", ErrInvalidExport)
+	}
+	return h, nil
+}
+
+func telegramCall(m telegramMessage, t time.Time, p Participant, outgoing bool) Call {
+	call := Call{Time: t, Participant: p, Duration: time.Duration(m.DurationSeconds) * time.Second, Direction: CallIncoming}
+	switch {
+	case outgoing:
+		call.Direction = CallOutgoing
+	case m.DiscardReason == "missed":
+		call.Direction = CallMissed
+	case m.DiscardReason == "busy" || (m.DiscardReason == "hangup" && m.DurationSeconds == 0):
+		call.Direction = CallRejected
+	}
+	return call
+}
+
+// telegramTime prefers the Unix time newer exports include over the local
+// date.
+func telegramTime(m telegramMessage, loc *time.Location) (time.Time, bool) {
+	if sec, err := strconv.ParseInt(m.DateUnixtime, 10, 64); err == nil {
+		return time.Unix(sec, 0).In(loc), true
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", m.Date, loc)
+	return t, err == nil
+}
+
+// telegramText flattens the text of a message, which is a string or a list
+// of strings and formatted parts.
+func telegramText(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	var parts []json.RawMessage
+	if json.Unmarshal(raw, &parts) != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		var text string
+		if json.Unmarshal(part, &text) != nil {
+			var entity struct {
+				Text string `json:"text"`
+			}
+			json.Unmarshal(part, &entity)
+			text = entity.Text
+		}
+		b.WriteString(text)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func telegramMedia(m telegramMessage) string {
+	switch {
+	case m.MediaType != "":
+		return "[" + strings.ReplaceAll(m.MediaType, "_", " ") + "]"
+	case m.Photo != "":
+		return "[photo]"
+	case m.File != "":
+		return "[file]"
+	}
+	return ""
+}
+
+func ptrValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
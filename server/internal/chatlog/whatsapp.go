@@ -0,0 +1,246 @@
+package chatlog
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// whatsAppLine matches the first line of a message in both the Android
+// ("31/12/2023, 21:15 - Alice: Hi") and the iOS
+// ("[31/12/23, 9:15:03 PM] Alice: Hi") export styles.
+var whatsAppLine = regexp.MustCompile(`^\[?(\d{1,4})[./-](\d{1,2})[./-](\d{1,4}),? +(\d{1,2})[:.](\d{2})(?:[:.](\d{2}))? *([AaPp]\.? ?[Mm]\.?)?\]? *(?:- )?(.*)$`)
+
+// whatsAppSpaces turns the narrow and non-breaking spaces newer exports
+// put before AM/PM into plain ones and drops byte order marks.
+var whatsAppSpaces = strings.NewReplacer("\u202f", " ", "\u00a0", " ", "\ufeff", "")
+
+// whatsAppChatPrefixes precede the chat name in export file names, such as
+// "WhatsApp Chat with Alice.txt" or "WhatsApp Chat - Alice.zip".
+var whatsAppChatPrefixes = []string{" with ", " - ", " mit ", " con ", " avec ", " com "}
+
+type whatsAppEntry struct {
+	date   [3]int
+	hour   int
+	minute int
+	second int
+	ampm   string
+	sender string
+	text   string
+}
+
+func parseWhatsApp(data []byte, opts Options) (*History, error) {
+	filename := opts.Filename
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		text, name, err := whatsAppChatFromZip(data)
+		if err != nil {
+			return nil, err
+		}
+		if name != "_chat.txt" {
+			filename = name
+		}
+		data = text
+	}
+
+	var entries []*whatsAppEntry
+	var current *whatsAppEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := whatsAppSpaces.Replace(scanner.Text())
+		line = strings.TrimLeft(line, "\u200e\u200f")
+		m := whatsAppLine.FindStringSubmatch(line)
+		if m == nil {
+			if current != nil {
+				current.text += "\n" + line
+			}
+			continue
+		}
+		sender, text, ok := strings.Cut(m[8], ": ")
+		if !ok {
+			// Changes to the chat, such as someone joining, have no sender.
+			current = nil
+			continue
+		}
+		e := &whatsAppEntry{sender: strings.TrimSpace(sender), text: strings.TrimLeft(text, "\u200e"), ampm: m[7]}
+		for i := 0; i < 3; i++ {
+			e.date[i], _ = strconv.Atoi(m[i+1])
+		}
+		e.hour, _ = strconv.Atoi(m[4])
+		e.minute, _ = strconv.Atoi(m[5])
+		e.second, _ = strconv.Atoi(m[6])
+		entries = append(entries, e)
+		current = e
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: no WhatsApp messages found", ErrInvalidExport)
+	}
+
+	order := whatsAppDateOrder(entries)
+	chatName := whatsAppChatName(filename)
+	var senders []string
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if !seen[strings.ToLower(e.sender)] {
+			seen[strings.ToLower(e.sender)] = true
+			senders = append(senders, e.sender)
+		}
+	}
+	owner := ""
+	for _, s := range senders {
+		if strings.EqualFold(s, strings.TrimSpace(opts.Owner)) {
+			owner = s
+		}
+	}
+	if owner == "" && chatName != "" && len(senders) == 2 {
+		// In a chat with one person, whoever is not the chat is the owner.
+		for i, s := range senders {
+			if strings.EqualFold(s, chatName) {
+				owner = senders[1-i]
+			}
+		}
+	}
+
+	thread := Thread{Name: chatName}
+	for _, s := range senders {
+		if !strings.EqualFold(s, owner) {
+			thread.Participants = append(thread.Participants, participantFor(s))
+		}
+	}
+	if len(thread.Participants) == 0 && chatName != "" {
+		thread.Participants = append(thread.Participants, participantFor(chatName))
+	}
+	for _, e := range entries {
+		t, ok := e.time(order, opts.Location)
+		if !ok {
+			continue
+		}
+		thread.Messages = append(thread.Messages, Message{
+			Time:   t,
+			Sender: e.sender,
+			FromMe: strings.EqualFold(e.sender, owner),
+			Text:   strings.TrimSpace(e.text),
+		})
+	}
+	if thread.Name == "" {
+		labels := make([]string, len(thread.Participants))
+		for i, p := range thread.Participants {
+			labels[i] = p.Label()
+		}
+		thread.Name = strings.Join(labels, ", ")
+	}
+	if chatName != "" {
+		thread.Key = "chat:" + strings.ToLower(chatName)
+	} else {
+		keys := make([]string, len(thread.Participants))
+		for i, p := range thread.Participants {
+			keys[i] = p.Key()
+		}
+		sort.Strings(keys)
+		thread.Key = strings.Join(keys, ",")
+	}
+	return &History{Threads: []Thread{thread}}, nil
+}
+
+// whatsAppDateOrder finds where the day, month and year are in the dates,
+// which follow the phone's locale: "ymd", "dmy" or "mdy". Days above 12
+// give it away; without one, 12-hour times suggest a US phone.
+func whatsAppDateOrder(entries []*whatsAppEntry) string {
+	twelveHour := false
+	for _, e := range entries {
+		switch {
+		case e.date[0] > 31:
+			return "ymd"
+		case e.date[0] > 12:
+			return "dmy"
+		case e.date[1] > 12:
+			return "mdy"
+		}
+		if e.ampm != "" {
+			twelveHour = true
+		}
+	}
+	if twelveHour {
+		return "mdy"
+	}
+	return "dmy"
+}
+
+func (e *whatsAppEntry) time(order string, loc *time.Location) (time.Time, bool) {
+	var year, month, day int
+	switch order {
+	case "ymd":
+		year, month, day = e.date[0], e.date[1], e.date[2]
+	case "mdy":
+		month, day, year = e.date[0], e.date[1], e.date[2]
+	default:
+		day, month, year = e.date[0], e.date[1], e.date[2]
+	}
+	if year < 100 {
+		year += 2000
+	}
+	hour := e.hour
+	if e.ampm != "" {
+		pm := strings.HasPrefix(strings.ToLower(e.ampm), "p")
+		if hour == 12 {
+			hour = 0
+		}
+		if pm {
+			hour += 12
+		}
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || e.minute > 59 || e.second > 59 {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), day, hour, e.minute, e.second, 0, loc), true
+}
+
+// whatsAppChatName reads the chat name from an export file name.
+func whatsAppChatName(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if !strings.Contains(strings.ToLower(name), "whatsapp") {
+		return ""
+	}
+	for _, prefix := range whatsAppChatPrefixes {
+		if i := strings.LastIndex(name, prefix); i >= 0 {
+			return strings.TrimSpace(name[i+len(prefix):])
+		}
+	}
+	return ""
+}
+
+// whatsAppChatFromZip returns the chat of an export that includes media.
+func whatsAppChatFromZip(data []byte) ([]byte, string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Ext(f.Name), ".txt") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidExport, err)
+		}
+		text, err := io.ReadAll(io.LimitReader(rc, maxChatSize))
+		rc.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidExport, err)
+		}
+		return text, path.Base(f.Name), nil
+	}
+	return nil, "", fmt.Errorf("%w: no chat in the archive", ErrInvalidExport)
+}
@@ -2,12 +2,17 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
 )
 
-var ErrUnsafeJWTSecret = errors.New("unsafe JWT secret")
+var (
+	ErrUnsafeJWTSecret           = errors.New("unsafe JWT secret")
+	ErrForwardAuthTrustedProxies = errors.New("forward auth requires FORWARD_AUTH_TRUSTED_PROXIES")
+)
 
 func splitAndTrim(s, sep string) []string {
 	parts := strings.Split(s, sep)
@@ -35,6 +40,8 @@ type Config struct {
 	Bleve        BleveConfig
 	Backup       BackupConfig
 	Security     SecurityConfig
+	ForwardAuth  ForwardAuthConfig
+	InboundMail  InboundMailConfig
 	Announcement string
 }
 
@@ -42,6 +49,69 @@ type SecurityConfig struct {
 	SettingsEncKey string
 }
 
+// ForwardAuthConfig configures trusting the identity headers of an
+// authenticating reverse proxy such as Authelia, Authentik or oauth2-proxy.
+// It is deliberately env-only: deciding which peers may vouch for users is
+// an infrastructure decision, not something to toggle from the admin UI.
+type ForwardAuthConfig struct {
+	Enabled bool
+	// TrustedProxies lists the CIDRs (or single IPs) of the proxies whose
+	// headers are honoured. Headers from any other peer are ignored.
+	TrustedProxies []string
+	UserHeader     string
+	EmailHeader    string
+	NameHeader     string
+	GroupsHeader   string
+	// AdminGroup, when set, makes members of the group account
+	// administrators and everyone else a regular member.
+	AdminGroup string
+	// AccountID is the account new users join. When empty every new user
+	// gets an account of their own, as with self-registration.
+	AccountID     string
+	AutoProvision bool
+	// LinkByEmail lets a username seen for the first time take over the
+	// existing user with the same email. Only enable it when the proxy
+	// verifies the email addresses it reports.
+	LinkByEmail bool
+}
+
+// TrustedNetworks parses TrustedProxies. Bare IPs are treated as single
+// host networks.
+func (f ForwardAuthConfig) TrustedNetworks() ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(f.TrustedProxies))
+	for _, entry := range f.TrustedProxies {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// InboundMailConfig configures the built-in SMTP receiver for inbox
+// addresses. Like the HTTP listen address it is env-only; the IMAP mailbox
+// polled instead is set in the admin panel.
+type InboundMailConfig struct {
+	// SMTPListen is the address the receiver listens on, e.g. ":2525".
+	// Empty disables it.
+	SMTPListen string
+	// SMTPDomain is the host name the receiver greets with.
+	SMTPDomain string
+}
+
 type ServerConfig struct {
 	Port string
 	Host string
@@ -160,11 +230,37 @@ func Load() *Config {
 		Security: SecurityConfig{
 			SettingsEncKey: getEnv("SETTINGS_ENC_KEY", ""),
 		},
+		ForwardAuth: ForwardAuthConfig{
+			Enabled:        getEnvBool("FORWARD_AUTH_ENABLED", false),
+			TrustedProxies: splitAndTrim(getEnv("FORWARD_AUTH_TRUSTED_PROXIES", ""), ","),
+			UserHeader:     getEnv("FORWARD_AUTH_USER_HEADER", "Remote-User"),
+			EmailHeader:    getEnv("FORWARD_AUTH_EMAIL_HEADER", "Remote-Email"),
+			NameHeader:     getEnv("FORWARD_AUTH_NAME_HEADER", "Remote-Name"),
+			GroupsHeader:   getEnv("FORWARD_AUTH_GROUPS_HEADER", "Remote-Groups"),
+			AdminGroup:     getEnv("FORWARD_AUTH_ADMIN_GROUP", ""),
+			AccountID:      getEnv("FORWARD_AUTH_ACCOUNT_ID", ""),
+			AutoProvision:  getEnvBool("FORWARD_AUTH_AUTO_PROVISION", true),
+			LinkByEmail:    getEnvBool("FORWARD_AUTH_LINK_BY_EMAIL", false),
+		},
+		InboundMail: InboundMailConfig{
+			SMTPListen: getEnv("INBOUND_SMTP_LISTEN", ""),
+			SMTPDomain: getEnv("INBOUND_SMTP_DOMAIN", "localhost"),
+		},
 		Announcement: getEnv("ANNOUNCEMENT", ""),
 	}
 }
 
 func (c *Config) Validate() error {
+	if c.ForwardAuth.Enabled {
+		// Trusting headers from every peer would let anyone log in as anyone.
+		if len(c.ForwardAuth.TrustedProxies) == 0 {
+			return ErrForwardAuthTrustedProxies
+		}
+		if _, err := c.ForwardAuth.TrustedNetworks(); err != nil {
+			return err
+		}
+	}
+
 	if !strings.EqualFold(strings.TrimSpace(c.App.Env), "production") {
 		return nil
 	}
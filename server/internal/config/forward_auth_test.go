@@ -0,0 +1,55 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestForwardAuthTrustedNetworks(t *testing.T) {
+	cfg := ForwardAuthConfig{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1", "fd00::1"}}
+	networks, err := cfg.TrustedNetworks()
+	if err != nil {
+		t.Fatalf("TrustedNetworks() error = %v", err)
+	}
+	if len(networks) != 3 {
+		t.Fatalf("expected 3 networks, got %d", len(networks))
+	}
+	cases := map[string]bool{
+		"10.20.30.40": true,
+		"192.0.2.1":   true,
+		"192.0.2.2":   false,
+		"fd00::1":     true,
+		"fd00::2":     false,
+	}
+	for ip, want := range cases {
+		got := false
+		for _, n := range networks {
+			got = got || n.Contains(net.ParseIP(ip))
+		}
+		if got != want {
+			t.Errorf("%s trusted = %v, want %v", ip, got, want)
+		}
+	}
+
+	if _, err := (ForwardAuthConfig{TrustedProxies: []string{"proxy.local"}}).TrustedNetworks(); err == nil {
+		t.Error("expected an invalid entry to be rejected")
+	}
+}
+
+func TestConfigValidateForwardAuthRequiresTrustedProxies(t *testing.T) {
+	configuration := &Config{ForwardAuth: ForwardAuthConfig{Enabled: true}}
+	if err := configuration.Validate(); !errors.Is(err, ErrForwardAuthTrustedProxies) {
+		t.Fatalf("Validate() error = %v, want %v", err, ErrForwardAuthTrustedProxies)
+	}
+
+	configuration.ForwardAuth.TrustedProxies = []string{"10.0.0.0/33"}
+	if err := configuration.Validate(); err == nil {
+		t.Fatal("expected an invalid CIDR to be rejected")
+	}
+
+	configuration.ForwardAuth.TrustedProxies = []string{"172.16.0.0/12"}
+	if err := configuration.Validate(); err != nil {
+		t.Fatalf("Validate() returned unexpected error: %v", err)
+	}
+}
@@ -2,14 +2,13 @@ package cron
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"hash/fnv"
 	"log"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/naiba/bonds/internal/database"
 	"github.com/naiba/bonds/internal/models"
 	robfigcron "github.com/robfig/cron/v3"
 	"gorm.io/gorm"
@@ -98,6 +97,12 @@ func (s *Scheduler) runJob(name string, fn func()) {
 	log.Printf("[cron] Job %q completed", name)
 }
 
+// TryLock claims the lock of name the way scheduled jobs do, so work started
+// outside the scheduler runs on one instance only.
+func (s *Scheduler) TryLock(name string) (bool, error) {
+	return s.acquireLock(name)
+}
+
 // acquireLock claims exclusive permission to run a job. Postgres uses
 // pg_try_advisory_xact_lock so two replicas firing at the same instant cannot
 // both observe a stale last_run_at; SQLite relies on its writer serialisation.
@@ -126,7 +131,7 @@ func (s *Scheduler) acquireLockGeneric(name string) (bool, error) {
 	if err == nil {
 		return true, nil
 	}
-	if isUniqueConstraintErr(err) {
+	if database.IsUniqueConstraintErr(err) {
 		return false, nil
 	}
 	return false, err
@@ -157,7 +162,7 @@ func (s *Scheduler) acquireLockPostgres(name string) (bool, error) {
 
 		now := time.Now()
 		if err := tx.Create(&models.Cron{Command: name, LastRunAt: &now}).Error; err != nil {
-			if isUniqueConstraintErr(err) {
+			if database.IsUniqueConstraintErr(err) {
 				return nil
 			}
 			return err
@@ -174,16 +179,3 @@ func jobLockKey(name string) int64 {
 	_, _ = h.Write([]byte(name))
 	return int64(h.Sum64())
 }
-
-func isUniqueConstraintErr(err error) bool {
-	if err == nil {
-		return false
-	}
-	if errors.Is(err, gorm.ErrDuplicatedKey) {
-		return true
-	}
-	msg := err.Error()
-	return strings.Contains(msg, "UNIQUE constraint failed") ||
-		strings.Contains(msg, "duplicate key value") ||
-		strings.Contains(msg, "violates unique constraint")
-}
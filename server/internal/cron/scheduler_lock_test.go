@@ -141,28 +141,3 @@ func TestJobLockKeyStable(t *testing.T) {
 		t.Fatal("jobLockKey must differ for different names")
 	}
 }
-
-func TestIsUniqueConstraintErrMatches(t *testing.T) {
-	cases := []struct {
-		msg  string
-		want bool
-	}{
-		{"UNIQUE constraint failed: crons.command", true},
-		{"pq: duplicate key value violates unique constraint \"crons_command_key\"", true},
-		{"some other error", false},
-		{"", false},
-	}
-	for _, c := range cases {
-		err := errString(c.msg)
-		if got := isUniqueConstraintErr(err); got != c.want {
-			t.Errorf("isUniqueConstraintErr(%q) = %v, want %v", c.msg, got, c.want)
-		}
-	}
-	if isUniqueConstraintErr(nil) {
-		t.Error("isUniqueConstraintErr(nil) should be false")
-	}
-}
-
-type errString string
-
-func (e errString) Error() string { return string(e) }
@@ -0,0 +1,24 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// IsUniqueConstraintErr reports whether err is a unique constraint violation.
+// Drivers without gorm's TranslateError report it only in the message, which
+// differs between SQLite and PostgreSQL.
+func IsUniqueConstraintErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed") ||
+		strings.Contains(msg, "duplicate key value") ||
+		strings.Contains(msg, "violates unique constraint")
+}
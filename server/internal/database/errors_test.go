@@ -0,0 +1,28 @@
+package database
+
+import "testing"
+
+func TestIsUniqueConstraintErrMatches(t *testing.T) {
+	cases := []struct {
+		msg  string
+		want bool
+	}{
+		{"UNIQUE constraint failed: crons.command", true},
+		{"pq: duplicate key value violates unique constraint \"crons_command_key\"", true},
+		{"some other error", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		err := errString(c.msg)
+		if got := IsUniqueConstraintErr(err); got != c.want {
+			t.Errorf("IsUniqueConstraintErr(%q) = %v, want %v", c.msg, got, c.want)
+		}
+	}
+	if IsUniqueConstraintErr(nil) {
+		t.Error("IsUniqueConstraintErr(nil) should be false")
+	}
+}
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
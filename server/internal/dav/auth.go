@@ -8,11 +8,16 @@ import (
 	"time"
 
 	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/services"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-func BasicAuthMiddleware(db *gorm.DB) func(next http.Handler) http.Handler {
+// BasicAuthMiddleware authenticates DAV requests with the user's email and
+// either a personal access token or their password. When ldapService allows
+// DAV logins, directory credentials are accepted as well; successful ones
+// are cached briefly so that polling clients do not bind on every request.
+func BasicAuthMiddleware(db *gorm.DB, ldapService *services.LDAPService) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.Method == http.MethodOptions {
@@ -23,44 +28,52 @@ func BasicAuthMiddleware(db *gorm.DB) func(next http.Handler) http.Handler {
 
 			email, password, ok := r.BasicAuth()
 			if !ok {
-				w.Header().Set("WWW-Authenticate", `Basic realm="Bonds DAV"`)
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				unauthorized(w, "Unauthorized")
 				return
 			}
 
 			var user models.User
-			if err := db.Where("email = ?", email).First(&user).Error; err != nil {
-				w.Header().Set("WWW-Authenticate", `Basic realm="Bonds DAV"`)
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			found := db.Where("email = ?", email).First(&user).Error == nil
+			if !found && !ldapService.DAVEnabled() {
+				unauthorized(w, "Unauthorized")
 				return
 			}
 
-			if user.Disabled {
+			if found && user.Disabled {
 				http.Error(w, "Forbidden", http.StatusForbidden)
 				return
 			}
 
 			if strings.HasPrefix(password, "bonds_") {
-				if !authenticateWithPAT(db, password, user.ID) {
-					w.Header().Set("WWW-Authenticate", `Basic realm="Bonds DAV"`)
-					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				if !found || !authenticateWithPAT(db, password, user.ID) {
+					unauthorized(w, "Unauthorized")
 					return
 				}
 			} else {
-				if user.TwoFactorConfirmedAt != nil {
-					w.Header().Set("WWW-Authenticate", `Basic realm="Bonds DAV"`)
-					http.Error(w, "2FA enabled: use a Personal Access Token instead of password", http.StatusUnauthorized)
+				if found && user.TwoFactorConfirmedAt != nil {
+					unauthorized(w, twoFactorMessage)
 					return
 				}
-				if user.Password == nil {
-					w.Header().Set("WWW-Authenticate", `Basic realm="Bonds DAV"`)
-					http.Error(w, "Unauthorized", http.StatusUnauthorized)
-					return
-				}
-				if err := bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(password)); err != nil {
-					w.Header().Set("WWW-Authenticate", `Basic realm="Bonds DAV"`)
-					http.Error(w, "Unauthorized", http.StatusUnauthorized)
-					return
+				if !found || user.Password == nil || bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(password)) != nil {
+					if !ldapService.DAVEnabled() {
+						unauthorized(w, "Unauthorized")
+						return
+					}
+					// The login may also be a directory username.
+					ldapUser, err := ldapService.AuthenticateDAV(email, password)
+					if err != nil {
+						unauthorized(w, "Unauthorized")
+						return
+					}
+					if ldapUser.Disabled {
+						http.Error(w, "Forbidden", http.StatusForbidden)
+						return
+					}
+					if ldapUser.TwoFactorConfirmedAt != nil {
+						unauthorized(w, twoFactorMessage)
+						return
+					}
+					user = *ldapUser
 				}
 			}
 
@@ -71,6 +84,13 @@ func BasicAuthMiddleware(db *gorm.DB) func(next http.Handler) http.Handler {
 	}
 }
 
+const twoFactorMessage = "2FA enabled: use a Personal Access Token instead of password"
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="Bonds DAV"`)
+	http.Error(w, message, http.StatusUnauthorized)
+}
+
 func authenticateWithPAT(db *gorm.DB, rawToken, userID string) bool {
 	h := sha256.Sum256([]byte(rawToken))
 	hash := hex.EncodeToString(h[:])
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/services"
 	"github.com/naiba/bonds/internal/testutil"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -27,7 +28,7 @@ func TestBasicAuth_ValidCredentials(t *testing.T) {
 		t.Fatalf("create user: %v", err)
 	}
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 
 	var gotUserID, gotAccountID string
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -67,7 +68,7 @@ func TestBasicAuth_InvalidPassword(t *testing.T) {
 		t.Fatalf("create user: %v", err)
 	}
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -86,7 +87,7 @@ func TestBasicAuth_InvalidPassword(t *testing.T) {
 func TestBasicAuth_UserNotFound(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -105,7 +106,7 @@ func TestBasicAuth_UserNotFound(t *testing.T) {
 func TestBasicAuth_NoCredentials(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -127,7 +128,7 @@ func TestBasicAuth_NoCredentials(t *testing.T) {
 func TestBasicAuth_OptionsBypassesChallenge(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 	called := false
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		called = true
@@ -167,7 +168,7 @@ func TestBasicAuth_NilPassword(t *testing.T) {
 		t.Fatalf("create user: %v", err)
 	}
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -202,7 +203,7 @@ func TestBasicAuth_DisabledUser(t *testing.T) {
 		t.Fatalf("disable user: %v", err)
 	}
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -236,7 +237,7 @@ func TestBasicAuth_TwoFactorEnabledUser_PasswordBlocked(t *testing.T) {
 		t.Fatalf("create user: %v", err)
 	}
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -278,7 +279,7 @@ func TestBasicAuth_PAT_Valid(t *testing.T) {
 		t.Fatalf("create PAT: %v", err)
 	}
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 	var gotUserID string
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		gotUserID = UserIDFromContext(r.Context())
@@ -333,7 +334,7 @@ func TestBasicAuth_PAT_Expired(t *testing.T) {
 		t.Fatalf("create PAT: %v", err)
 	}
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -369,7 +370,7 @@ func TestBasicAuth_PAT_WrongUser(t *testing.T) {
 	}
 	db.Create(&pat)
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -415,7 +416,7 @@ func TestBasicAuth_PAT_TwoFactorEnabled(t *testing.T) {
 		t.Fatalf("create PAT: %v", err)
 	}
 
-	mw := BasicAuthMiddleware(db)
+	mw := BasicAuthMiddleware(db, nil)
 	var gotUserID string
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		gotUserID = UserIDFromContext(r.Context())
@@ -435,3 +436,66 @@ func TestBasicAuth_PAT_TwoFactorEnabled(t *testing.T) {
 		t.Errorf("expected user ID %q, got %q", user.ID, gotUserID)
 	}
 }
+
+func TestBasicAuth_LDAPCredentials(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	settings := services.NewSystemSettingService(db)
+	for k, v := range map[string]string{
+		"ldap.enabled": "true",
+		"ldap.url": testutil.StartLDAPServer(t, []testutil.LDAPEntry{{
+			DN:       "uid=carol,ou=people,dc=example,dc=com",
+			Password: "directory-password",
+			Attributes: map[string][]string{
+				"uid":       {"carol"},
+				"givenName": {"Carol"},
+				"mail":      {"carol@example.com"},
+			},
+		}}),
+		"ldap.base_dn": "dc=example,dc=com",
+	} {
+		if err := settings.Set(k, v); err != nil {
+			t.Fatalf("set %s: %v", k, err)
+		}
+	}
+	mw := BasicAuthMiddleware(db, services.NewLDAPService(db, settings))
+
+	var gotUserID string
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID = UserIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(login, password string) int {
+		req := httptest.NewRequest("GET", "/dav/", nil)
+		req.SetBasicAuth(login, password)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("carol", "directory-password"); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 while LDAP is not enabled for DAV, got %d", code)
+	}
+
+	if err := settings.Set("ldap.dav_enabled", "true"); err != nil {
+		t.Fatalf("set ldap.dav_enabled: %v", err)
+	}
+	if code := serve("carol", "directory-password"); code != http.StatusOK {
+		t.Fatalf("expected 200 for directory credentials, got %d", code)
+	}
+	var user models.User
+	if err := db.Where("email = ?", "carol@example.com").First(&user).Error; err != nil {
+		t.Fatalf("expected the directory user to be provisioned: %v", err)
+	}
+	if gotUserID != user.ID {
+		t.Errorf("expected user ID %q, got %q", user.ID, gotUserID)
+	}
+	if code := serve("carol@example.com", "wrong-password"); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a wrong directory password, got %d", code)
+	}
+
+	now := time.Now()
+	db.Model(&user).Update("two_factor_confirmed_at", &now)
+	if code := serve("carol", "directory-password"); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 (2FA-enabled user must use PAT for DAV), got %d", code)
+	}
+}
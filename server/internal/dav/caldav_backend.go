@@ -307,7 +307,12 @@ func (b *CalDAVBackend) putEvent(_ context.Context, path string, comp *ical.Comp
 		existing.Day = day
 		existing.Month = month
 		existing.Year = year
-		if err := b.db.Save(&existing).Error; err != nil {
+		if err := b.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+			return services.RecordVaultChange(tx, vaultID, models.VaultChangeImportantDate, existing.ID, models.VaultChangeUpdated)
+		}); err != nil {
 			return nil, err
 		}
 		return &caldav.CalendarObject{
@@ -334,7 +339,12 @@ func (b *CalDAVBackend) putEvent(_ context.Context, path string, comp *ical.Comp
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
-	if err := b.db.Create(&importantDate).Error; err != nil {
+	if err := b.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&importantDate).Error; err != nil {
+			return err
+		}
+		return services.RecordVaultChange(tx, vaultID, models.VaultChangeImportantDate, importantDate.ID, models.VaultChangeCreated)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -370,7 +380,21 @@ func (b *CalDAVBackend) putTodo(_ context.Context, path string, comp *ical.Compo
 		if description != "" {
 			existing.Description = &description
 		}
-		if err := b.db.Save(&existing).Error; err != nil {
+		wasCompleted := existing.Completed
+		if err := applyTodoToTask(comp, &existing); err != nil {
+			return nil, err
+		}
+		// Completing a recurring todo spawns its next occurrence; the
+		// client picks the new UID up on its next sync.
+		if err := b.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+			if err := services.RecordVaultChange(tx, vaultID, models.VaultChangeTask, existing.ID, models.VaultChangeUpdated); err != nil {
+				return err
+			}
+			return services.SyncTaskOccurrence(tx, &existing, wasCompleted)
+		}); err != nil {
 			return nil, err
 		}
 		return &caldav.CalendarObject{
@@ -396,14 +420,22 @@ func (b *CalDAVBackend) putTodo(_ context.Context, path string, comp *ical.Compo
 	if description != "" {
 		task.Description = &description
 	}
+	if err := applyTodoToTask(comp, &task); err != nil {
+		return nil, err
+	}
 	if err := b.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(&task).Error; err != nil {
 			return err
 		}
+		if err := services.RecordVaultChange(tx, vaultID, models.VaultChangeTask, task.ID, models.VaultChangeCreated); err != nil {
+			return err
+		}
 		if hasContact {
-			return tx.Create(&models.TaskContact{ContactTaskID: task.ID, ContactID: contact.ID}).Error
+			if err := tx.Create(&models.TaskContact{ContactTaskID: task.ID, ContactID: contact.ID}).Error; err != nil {
+				return err
+			}
 		}
-		return nil
+		return services.SyncTaskOccurrence(tx, &task, false)
 	}); err != nil {
 		return nil, err
 	}
@@ -416,6 +448,69 @@ func (b *CalDAVBackend) putTodo(_ context.Context, path string, comp *ical.Compo
 	}, nil
 }
 
+// applyTodoToTask copies DUE, RRULE and completion state from a VTODO onto a
+// task. A DUE that moved to another day re-derives the lunar anchor of a
+// lunar task so Original* never disagrees with DueAt. Invalid RRULEs are
+// rejected with 400 instead of being dropped silently.
+func applyTodoToTask(comp *ical.Component, task *models.ContactTask) error {
+	var due *time.Time
+	if prop := comp.Props.Get(ical.PropDue); prop != nil {
+		if dt, err := prop.DateTime(time.UTC); err == nil {
+			due = &dt
+		}
+	}
+	dayChanged := due == nil || task.DueAt == nil ||
+		due.Format("2006-01-02") != task.DueAt.Format("2006-01-02")
+	task.DueAt = due
+	switch {
+	case due == nil:
+		task.CalendarType = string(calendarPkg.Gregorian)
+		task.OriginalDay, task.OriginalMonth, task.OriginalYear = nil, nil, nil
+	case dayChanged && task.CalendarType == string(calendarPkg.Lunar):
+		converter, _ := calendarPkg.Get(calendarPkg.Lunar)
+		info, err := converter.FromGregorian(calendarPkg.GregorianDate{Day: due.Day(), Month: int(due.Month()), Year: due.Year()})
+		if err != nil {
+			return err
+		}
+		task.OriginalDay = &info.Day
+		task.OriginalMonth = &info.Month
+		if task.OriginalYear != nil {
+			task.OriginalYear = &info.Year
+		}
+	}
+
+	rule := ""
+	if prop := comp.Props.Get(ical.PropRecurrenceRule); prop != nil {
+		rule = prop.Value
+	}
+	if err := services.ApplyTaskRecurrence(task, rule); err != nil {
+		return webdav.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	status, _ := comp.Props.Text(ical.PropStatus)
+	completed := strings.EqualFold(status, "COMPLETED")
+	if completed == task.Completed {
+		return nil
+	}
+	task.Completed = completed
+	if completed {
+		completedAt := time.Now()
+		if prop := comp.Props.Get(ical.PropCompleted); prop != nil {
+			if dt, err := prop.DateTime(time.UTC); err == nil {
+				completedAt = dt
+			}
+		}
+		task.CompletedAt = &completedAt
+		task.Status = models.TaskStatusDone
+	} else {
+		task.CompletedAt = nil
+		if task.Status == models.TaskStatusDone {
+			task.Status = models.TaskStatusTodo
+		}
+	}
+	return nil
+}
+
 func (b *CalDAVBackend) DeleteCalendarObject(ctx context.Context, path string) error {
 	userID := UserIDFromContext(ctx)
 	if userID == "" {
@@ -433,7 +528,12 @@ func (b *CalDAVBackend) DeleteCalendarObject(ctx context.Context, path string) e
 		if err := b.verifyVaultAccess(userID, importantDate.Contact.VaultID); err != nil {
 			return err
 		}
-		return b.db.Delete(&importantDate).Error
+		return b.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&importantDate).Error; err != nil {
+				return err
+			}
+			return services.RecordVaultChange(tx, importantDate.Contact.VaultID, models.VaultChangeImportantDate, importantDate.ID, models.VaultChangeDeleted)
+		})
 	}
 
 	var task models.ContactTask
@@ -445,7 +545,10 @@ func (b *CalDAVBackend) DeleteCalendarObject(ctx context.Context, path string) e
 			if err := tx.Where("contact_task_id = ?", task.ID).Delete(&models.TaskContact{}).Error; err != nil {
 				return err
 			}
-			return tx.Delete(&task).Error
+			if err := tx.Delete(&task).Error; err != nil {
+				return err
+			}
+			return services.RecordVaultChange(tx, task.VaultID, models.VaultChangeTask, task.ID, models.VaultChangeDeleted)
 		})
 	}
 
@@ -644,6 +747,11 @@ func buildCalendarFromTask(t *models.ContactTask) *ical.Calendar {
 	if t.DueAt != nil {
 		todo.Props.SetDateTime(ical.PropDue, *t.DueAt)
 	}
+	if rule := services.TaskRecurrenceRuleForExport(t); rule != "" {
+		rruleProp := ical.NewProp(ical.PropRecurrenceRule)
+		rruleProp.Value = rule
+		todo.Props.Set(rruleProp)
+	}
 
 	todo.Props.SetText(ical.PropPercentComplete, func() string {
 		if t.Completed {
@@ -3,6 +3,7 @@ package dav
 import (
 	"testing"
 
+	"github.com/emersion/go-ical"
 	"github.com/naiba/bonds/internal/models"
 )
 
@@ -63,3 +64,67 @@ func TestDeleteCalendarObjectAlsoDeletesPivot(t *testing.T) {
 	}
 }
 
+func TestPutTodoRecurrenceRoundTrip(t *testing.T) {
+	backend, db, ctx, vaultID, userID := setupCalDAVTest(t)
+
+	uid := "recurring-todo-uid"
+	newTodoCalendar := func(status string) *ical.Calendar {
+		cal := ical.NewCalendar()
+		cal.Props.SetText(ical.PropProductID, "-//Bonds Test//EN")
+		cal.Props.SetText(ical.PropVersion, "2.0")
+		todo := ical.NewComponent(ical.CompToDo)
+		todo.Props.SetText(ical.PropUID, uid)
+		todo.Props.SetText(ical.PropSummary, "Mooncakes")
+		todo.Props.SetText(ical.PropStatus, status)
+		due := ical.NewProp(ical.PropDue)
+		due.SetValueType(ical.ValueDate)
+		due.Value = "20260925"
+		todo.Props.Set(due)
+		rrule := ical.NewProp(ical.PropRecurrenceRule)
+		rrule.Value = "RSCALE=CHINESE;FREQ=YEARLY"
+		todo.Props.Set(rrule)
+		cal.Children = append(cal.Children, todo)
+		return cal
+	}
+
+	path := "/dav/calendars/" + userID + "/" + vaultID + "/" + uid + ".ics"
+	obj, err := backend.PutCalendarObject(ctx, path, newTodoCalendar("NEEDS-ACTION"), nil)
+	if err != nil {
+		t.Fatalf("PutCalendarObject: %v", err)
+	}
+	rrule := obj.Data.Children[0].Props.Get(ical.PropRecurrenceRule)
+	if rrule == nil || rrule.Value != "RSCALE=CHINESE;FREQ=YEARLY" {
+		t.Fatalf("expected RRULE to round-trip, got %+v", rrule)
+	}
+
+	var stored models.ContactTask
+	if err := db.Where("uuid = ?", uid).First(&stored).Error; err != nil {
+		t.Fatalf("load task: %v", err)
+	}
+	if stored.CalendarType != "lunar" || stored.OriginalMonth == nil || *stored.OriginalMonth != 8 || *stored.OriginalDay != 15 {
+		t.Fatalf("expected RSCALE=CHINESE to anchor the task on lunar 8/15, got %+v", stored)
+	}
+
+	if _, err := backend.PutCalendarObject(ctx, path, newTodoCalendar("COMPLETED"), nil); err != nil {
+		t.Fatalf("complete todo: %v", err)
+	}
+	if err := db.Where("uuid = ?", uid).First(&stored).Error; err != nil {
+		t.Fatalf("reload task: %v", err)
+	}
+	if !stored.Completed || stored.NextOccurrenceID == nil {
+		t.Fatalf("expected completion to spawn the next occurrence, got %+v", stored)
+	}
+	var next models.ContactTask
+	if err := db.First(&next, *stored.NextOccurrenceID).Error; err != nil {
+		t.Fatalf("load next occurrence: %v", err)
+	}
+	if next.DueAt == nil || next.DueAt.Format("2006-01-02") != "2027-09-15" {
+		t.Fatalf("expected next lunar occurrence on 2027-09-15, got %v", next.DueAt)
+	}
+	if next.UUID == nil || *next.UUID == uid {
+		t.Fatalf("expected the next occurrence to get its own UID, got %v", next.UUID)
+	}
+	if data := buildCalendarFromTask(&stored); data.Children[0].Props.Get(ical.PropRecurrenceRule) != nil {
+		t.Error("completed occurrence should be exported without RRULE")
+	}
+}
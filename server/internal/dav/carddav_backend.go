@@ -251,11 +251,16 @@ func (b *CardDAVBackend) PutAddressObject(ctx context.Context, path string, card
 			contact.Nickname = strPtrOrNil(nickname)
 			contact.JobPosition = strPtrOrNil(title)
 			contact.LastUpdatedAt = &now
-			if err := b.db.Save(&contact).Error; err != nil {
-				return nil, err
-			}
-
-			if err := replaceContactVCardFields(b.db, card, contact.ID, vaultID, accountID); err != nil {
+			phoneRegion := services.PhoneRegionForUser(b.db, userID)
+			if err := b.db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Save(&contact).Error; err != nil {
+					return err
+				}
+				if err := services.RecordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated); err != nil {
+					return err
+				}
+				return replaceContactVCardFields(tx, card, contact.ID, vaultID, accountID, phoneRegion)
+			}); err != nil {
 				return nil, err
 			}
 
@@ -277,20 +282,26 @@ func (b *CardDAVBackend) PutAddressObject(ctx context.Context, path string, card
 		JobPosition:   strPtrOrNil(title),
 		LastUpdatedAt: &now,
 	}
-	if err := b.db.Create(&contact).Error; err != nil {
-		return nil, err
-	}
+	phoneRegion := services.PhoneRegionForUser(b.db, userID)
+	if err := b.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&contact).Error; err != nil {
+			return err
+		}
+		if err := services.RecordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeCreated); err != nil {
+			return err
+		}
 
-	cvu := models.ContactVaultUser{
-		ContactID: contact.ID,
-		UserID:    userID,
-		VaultID:   vaultID,
-	}
-	if err := b.db.Create(&cvu).Error; err != nil {
-		return nil, err
-	}
+		cvu := models.ContactVaultUser{
+			ContactID: contact.ID,
+			UserID:    userID,
+			VaultID:   vaultID,
+		}
+		if err := tx.Create(&cvu).Error; err != nil {
+			return err
+		}
 
-	if err := saveContactVCardFields(b.db, card, contact.ID, vaultID, accountID); err != nil {
+		return saveContactVCardFields(tx, card, contact.ID, vaultID, accountID, phoneRegion)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -324,7 +335,10 @@ func (b *CardDAVBackend) DeleteAddressObject(ctx context.Context, path string) e
 		return webdav.NewHTTPError(http.StatusForbidden, fmt.Errorf("contact cannot be deleted"))
 	}
 
-	return b.db.Delete(&contact).Error
+	if err := b.db.Delete(&contact).Error; err != nil {
+		return err
+	}
+	return services.RecordContactDeletion(b.db, &contact)
 }
 
 func (b *CardDAVBackend) verifyVaultAccess(userID, vaultID string) error {
@@ -440,7 +454,8 @@ func textMatchField(value string, tm carddav.TextMatch) bool {
 }
 
 // saveContactVCardFields creates TEL, EMAIL, ADR, BDAY records from a vCard.
-func saveContactVCardFields(db *gorm.DB, card vcard.Card, contactID, vaultID, accountID string) error {
+// Phone numbers without a country code are read in phoneRegion.
+func saveContactVCardFields(db *gorm.DB, card vcard.Card, contactID, vaultID, accountID, phoneRegion string) error {
 	// TEL
 	if fields := card[vcard.FieldTelephone]; len(fields) > 0 {
 		var phoneType models.ContactInformationType
@@ -450,9 +465,10 @@ func saveContactVCardFields(db *gorm.DB, card vcard.Card, contactID, vaultID, ac
 					continue
 				}
 				if err := db.Create(&models.ContactInformation{
-					ContactID: contactID,
-					TypeID:    phoneType.ID,
-					Data:      f.Value,
+					ContactID:      contactID,
+					TypeID:         phoneType.ID,
+					Data:           f.Value,
+					NormalizedData: models.NormalizedPhone(f.Value, phoneRegion),
 				}).Error; err != nil {
 					return err
 				}
@@ -502,6 +518,9 @@ func saveContactVCardFields(db *gorm.DB, card vcard.Card, contactID, vaultID, ac
 			}).Error; err != nil {
 				return err
 			}
+			if _, err := services.QueueGeocoding(db, a); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -524,6 +543,9 @@ func saveContactVCardFields(db *gorm.DB, card vcard.Card, contactID, vaultID, ac
 				if err := db.Create(&cid).Error; err != nil {
 					return err
 				}
+				if err := services.RecordVaultChange(db, vaultID, models.VaultChangeImportantDate, cid.ID, models.VaultChangeCreated); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -532,7 +554,7 @@ func saveContactVCardFields(db *gorm.DB, card vcard.Card, contactID, vaultID, ac
 }
 
 // replaceContactVCardFields deletes existing records and recreates from vCard.
-func replaceContactVCardFields(db *gorm.DB, card vcard.Card, contactID, vaultID, accountID string) error {
+func replaceContactVCardFields(db *gorm.DB, card vcard.Card, contactID, vaultID, accountID, phoneRegion string) error {
 	db.Where("contact_id = ?", contactID).Delete(&models.ContactInformation{})
 
 	var pivots []models.ContactAddress
@@ -546,9 +568,16 @@ func replaceContactVCardFields(db *gorm.DB, card vcard.Card, contactID, vaultID,
 		db.Where("id IN ?", addressIDs).Delete(&models.Address{})
 	}
 
+	var dateIDs []uint
+	db.Model(&models.ContactImportantDate{}).Where("contact_id = ?", contactID).Pluck("id", &dateIDs)
 	db.Where("contact_id = ?", contactID).Delete(&models.ContactImportantDate{})
+	for _, id := range dateIDs {
+		if err := services.RecordVaultChange(db, vaultID, models.VaultChangeImportantDate, id, models.VaultChangeDeleted); err != nil {
+			return err
+		}
+	}
 
-	return saveContactVCardFields(db, card, contactID, vaultID, accountID)
+	return saveContactVCardFields(db, card, contactID, vaultID, accountID, phoneRegion)
 }
 
 // parseBirthdayString parses vCard BDAY formats: "19900115", "1990-01-15", "--0115" (no year)
@@ -10,20 +10,22 @@ import (
 	"github.com/emersion/go-webdav/caldav"
 	"github.com/emersion/go-webdav/carddav"
 	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/services"
 	"gorm.io/gorm"
 )
 
 const utf8Charset = "utf-8"
 
 // SetupDAVRoutes registers CardDAV and CalDAV routes on the Echo instance.
-func SetupDAVRoutes(e *echo.Echo, db *gorm.DB) {
+// ldapService may be nil.
+func SetupDAVRoutes(e *echo.Echo, db *gorm.DB, ldapService *services.LDAPService) {
 	cardBackend := NewCardDAVBackend(db)
 	calBackend := NewCalDAVBackend(db)
 
 	cardHandler := &carddav.Handler{Backend: cardBackend, Prefix: "/dav"}
 	calHandler := &caldav.Handler{Backend: calBackend, Prefix: "/dav"}
 
-	authMw := BasicAuthMiddleware(db)
+	authMw := BasicAuthMiddleware(db, ldapService)
 
 	davHandler := authMw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
@@ -57,6 +57,8 @@ type InstanceInfoResponse struct {
 	OAuthProviders           []string            `json:"oauth_providers" example:"github,google"`
 	OAuthProviderDetails     []OAuthProviderInfo `json:"oauth_provider_details"`
 	WebAuthnEnabled          bool                `json:"webauthn_enabled" example:"true"`
+	ForwardAuthEnabled       bool                `json:"forward_auth_enabled" example:"false"`
+	LDAPEnabled              bool                `json:"ldap_enabled" example:"false"`
 	AppName                  string              `json:"app_name" example:"Bonds"`
 }
 
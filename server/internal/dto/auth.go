@@ -19,12 +19,30 @@ type RefreshRequest struct {
 	Token string `json:"token" validate:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
 }
 
+// LinkLDAPRequest links a directory account to the signed-in user. The
+// directory password confirms that the account is theirs.
+type LinkLDAPRequest struct {
+	Login    string `json:"login" validate:"required" example:"alice"`
+	Password string `json:"password" validate:"required" example:"directoryP@ss"`
+}
+
+// RefreshSessionRequest redeems a single-use refresh token for a new access
+// token and the next refresh token.
+type RefreshSessionRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required" example:"Jx3k9...Qw"`
+}
+
+// AuthResponse carries a refresh token when a session starts or a refresh
+// token is redeemed. It carries none with the temporary token of a two-factor
+// login or when an access token extends its session through /auth/refresh.
 type AuthResponse struct {
-	Token             string       `json:"token" example:"eyJhbGciOiJIUzI1NiIs..."`
-	ExpiresAt         time.Time    `json:"expires_at" example:"2026-01-15T10:30:00Z"`
-	User              UserResponse `json:"user"`
-	RequiresTwoFactor bool         `json:"requires_two_factor,omitempty" example:"false"`
-	TempToken         string       `json:"temp_token,omitempty" example:"eyJhbGciOiJIUzI1NiIs..."`
+	Token                 string       `json:"token" example:"eyJhbGciOiJIUzI1NiIs..."`
+	ExpiresAt             time.Time    `json:"expires_at" example:"2026-01-15T10:30:00Z"`
+	RefreshToken          string       `json:"refresh_token,omitempty" example:"Jx3k9...Qw"`
+	RefreshTokenExpiresAt *time.Time   `json:"refresh_token_expires_at,omitempty" example:"2026-01-22T10:30:00Z"`
+	User                  UserResponse `json:"user"`
+	RequiresTwoFactor     bool         `json:"requires_two_factor,omitempty" example:"false"`
+	TempToken             string       `json:"temp_token,omitempty" example:"eyJhbGciOiJIUzI1NiIs..."`
 }
 
 type TwoFactorSetupResponse struct {
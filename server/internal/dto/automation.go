@@ -0,0 +1,85 @@
+package dto
+
+import "time"
+
+// AutomationCondition compares a field of the triggering contact or source
+// with Value. Fields holding several values, such as contact.label_ids,
+// match when any of them does.
+type AutomationCondition struct {
+	Field    string `json:"field" validate:"required" example:"call.emotion"`
+	Operator string `json:"operator" validate:"required" example:"equals"`
+	Value    string `json:"value" example:"sad"`
+}
+
+// AutomationAction is one step of a rule. Which fields apply depends on
+// Type.
+type AutomationAction struct {
+	Type string `json:"type" validate:"required" example:"create_task"`
+	// Label names the task or the reminder to create.
+	Label       string `json:"label,omitempty" example:"Follow up"`
+	Description string `json:"description,omitempty" example:"Check how they are doing"`
+	// DueInDays sets the task due date, in days after the trigger.
+	DueInDays *int `json:"due_in_days,omitempty" example:"3"`
+	// ReminderType is one_time, recurring_week, recurring_month or
+	// recurring_year.
+	ReminderType    string `json:"reminder_type,omitempty" example:"recurring_year"`
+	FrequencyNumber *int   `json:"frequency_number,omitempty" example:"1"`
+	LeadTimeDays    []int  `json:"lead_time_days,omitempty" example:"14"`
+	// DaysFromNow dates the reminder relative to the trigger. When omitted,
+	// a reminder triggered by an important date takes that date.
+	DaysFromNow *int   `json:"days_from_now,omitempty" example:"30"`
+	LabelID     *uint  `json:"label_id,omitempty" example:"1"`
+	GroupID     *uint  `json:"group_id,omitempty" example:"1"`
+	Subject     string `json:"subject,omitempty" example:"New client"`
+	Message     string `json:"message,omitempty" example:"A contact was tagged as client"`
+	URL         string `json:"url,omitempty" example:"https://example.com/hooks/bonds"`
+}
+
+type CreateAutomationRuleRequest struct {
+	Name       string                `json:"name" validate:"required" example:"Follow up sad calls"`
+	Trigger    string                `json:"trigger" validate:"required" example:"call_logged"`
+	Conditions []AutomationCondition `json:"conditions"`
+	Actions    []AutomationAction    `json:"actions" validate:"required"`
+	Enabled    *bool                 `json:"enabled" example:"true"`
+}
+
+type UpdateAutomationRuleRequest struct {
+	Name       string                `json:"name" validate:"required" example:"Follow up sad calls"`
+	Trigger    string                `json:"trigger" validate:"required" example:"call_logged"`
+	Conditions []AutomationCondition `json:"conditions"`
+	Actions    []AutomationAction    `json:"actions" validate:"required"`
+	Enabled    *bool                 `json:"enabled" example:"true"`
+}
+
+type AutomationRuleResponse struct {
+	ID         uint                  `json:"id" example:"1"`
+	VaultID    string                `json:"vault_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	CreatedBy  string                `json:"created_by" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name       string                `json:"name" example:"Follow up sad calls"`
+	Trigger    string                `json:"trigger" example:"call_logged"`
+	Conditions []AutomationCondition `json:"conditions"`
+	Actions    []AutomationAction    `json:"actions"`
+	Enabled    bool                  `json:"enabled" example:"true"`
+	CreatedAt  time.Time             `json:"created_at" example:"2026-01-15T10:30:00Z"`
+	UpdatedAt  time.Time             `json:"updated_at" example:"2026-01-15T10:30:00Z"`
+}
+
+// AutomationActionResult is the outcome of one action of an execution.
+type AutomationActionResult struct {
+	Type  string `json:"type" example:"create_task"`
+	OK    bool   `json:"ok" example:"true"`
+	Error string `json:"error,omitempty" example:"label not found"`
+}
+
+type AutomationExecutionResponse struct {
+	ID         uint                     `json:"id" example:"1"`
+	RuleID     uint                     `json:"rule_id" example:"1"`
+	ContactID  string                   `json:"contact_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Trigger    string                   `json:"trigger" example:"call_logged"`
+	FeedItemID uint                     `json:"feed_item_id" example:"42"`
+	Depth      int                      `json:"depth" example:"0"`
+	Status     string                   `json:"status" example:"succeeded"`
+	Error      string                   `json:"error,omitempty" example:""`
+	Results    []AutomationActionResult `json:"results"`
+	CreatedAt  time.Time                `json:"created_at" example:"2026-01-15T10:30:00Z"`
+}
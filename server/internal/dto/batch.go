@@ -0,0 +1,31 @@
+package dto
+
+import "encoding/json"
+
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations" validate:"required"`
+}
+
+// BatchOperation is one API request of a batch. Path is relative to /api.
+// Path and string values in Body may contain {{ref.field}} placeholders that
+// resolve to a field of the data returned by the earlier operation with that
+// ref.
+type BatchOperation struct {
+	Ref    string          `json:"ref,omitempty" example:"contact"`
+	Method string          `json:"method" validate:"required" example:"POST"`
+	Path   string          `json:"path" validate:"required" example:"/vaults/{vault_id}/contacts/{{contact.id}}/notes"`
+	Body   json.RawMessage `json:"body,omitempty" swaggertype:"object"`
+}
+
+type BatchResponse struct {
+	Committed bool                   `json:"committed" example:"true"`
+	Results   []BatchOperationResult `json:"results"`
+}
+
+// BatchOperationResult holds the status and the response body of an
+// operation that ran. Operations after a failed one do not run.
+type BatchOperationResult struct {
+	Ref    string          `json:"ref,omitempty" example:"contact"`
+	Status int             `json:"status" example:"201"`
+	Body   json.RawMessage `json:"body,omitempty" swaggertype:"object"`
+}
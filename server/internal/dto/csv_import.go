@@ -23,10 +23,42 @@ type CSVColumnMapping struct {
 	AddressState      string `json:"address_state"`
 	AddressPostalCode string `json:"address_postal_code"`
 	AddressCountry    string `json:"address_country"`
+	LinkedIn          string `json:"linkedin"`
+}
+
+// CSVImportOptions controls how an import treats rows that match an
+// existing contact by email or phone. OnDuplicate is "create" (the default),
+// "skip" or "update".
+type CSVImportOptions struct {
+	DryRun      bool
+	OnDuplicate string
 }
 
 type CSVImportResponse struct {
-	ImportedContacts int      `json:"imported_contacts" example:"10"`
-	SkippedCount     int      `json:"skipped_count"     example:"0"`
-	Errors           []string `json:"errors,omitempty"`
+	DryRun           bool                 `json:"dry_run"`
+	ImportedContacts int                  `json:"imported_contacts" example:"10"`
+	UpdatedContacts  int                  `json:"updated_contacts"  example:"0"`
+	SkippedCount     int                  `json:"skipped_count"     example:"0"`
+	Errors           []string             `json:"errors,omitempty"`
+	Rows             []CSVImportRowResult `json:"rows,omitempty"`
+}
+
+// CSVImportRowResult is the dry-run report of one data row. Action is
+// "create", "update" or "skip".
+type CSVImportRowResult struct {
+	Row            int      `json:"row" example:"2"`
+	Name           string   `json:"name" example:"Alice Smith"`
+	Action         string   `json:"action" example:"create"`
+	DuplicateOf    string   `json:"duplicate_of,omitempty"`
+	DuplicateOfRow int      `json:"duplicate_of_row,omitempty"`
+	MatchedBy      string   `json:"matched_by,omitempty" example:"email"`
+	Errors         []string `json:"errors,omitempty"`
+}
+
+type CSVImportPreviewResponse struct {
+	Preset     string           `json:"preset,omitempty" example:"google"`
+	Headers    []string         `json:"headers"`
+	Mapping    CSVColumnMapping `json:"mapping"`
+	SampleRows [][]string       `json:"sample_rows"`
+	RowCount   int              `json:"row_count" example:"120"`
 }
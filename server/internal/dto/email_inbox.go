@@ -0,0 +1,31 @@
+package dto
+
+import "time"
+
+type EmailInboxResponse struct {
+	ID      uint   `json:"id" example:"1"`
+	VaultID string `json:"vault_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// Address is where to BCC or forward mail. It is empty while the
+	// administrator has not set inbound_mail.address.
+	Address   string    `json:"address" example:"bonds+3f2a9c1d8e7b6a5f4c3d2e1f0a9b8c7d@mail.example.com"`
+	Mode      string    `json:"mode" example:"activity"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UpdateEmailInboxRequest struct {
+	// Mode is activity, to log one email activity with every matched
+	// contact, or note, to add a note to each of them.
+	Mode string `json:"mode" validate:"required,oneof=activity note" example:"activity"`
+}
+
+type IngestedEmailResponse struct {
+	ID           uint      `json:"id" example:"1"`
+	MessageID    string    `json:"message_id" example:"CAF=abc123@mail.example.com"`
+	Subject      string    `json:"subject" example:"Lunch next week"`
+	FromAddress  string    `json:"from_address" example:"alice@example.com"`
+	ContactCount int       `json:"contact_count" example:"2"`
+	ActivityID   *uint     `json:"activity_id" example:"42"`
+	Status       string    `json:"status" example:"logged"`
+	CreatedAt    time.Time `json:"created_at"`
+}
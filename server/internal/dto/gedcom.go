@@ -0,0 +1,10 @@
+package dto
+
+type GedcomImportResponse struct {
+	ImportedContacts     int      `json:"imported_contacts" example:"10"`
+	MatchedContacts      int      `json:"matched_contacts" example:"2"`
+	ImportedDates        int      `json:"imported_dates" example:"12"`
+	CreatedRelationships int      `json:"created_relationships" example:"16"`
+	SkippedCount         int      `json:"skipped_count" example:"0"`
+	Errors               []string `json:"errors,omitempty"`
+}
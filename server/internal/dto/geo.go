@@ -0,0 +1,89 @@
+package dto
+
+// NearbyContactsQuery selects contacts by where they live: within RadiusKM
+// of a point, or inside a bounding box. Place is geocoded to the point
+// when it is given.
+type NearbyContactsQuery struct {
+	Place       string
+	Latitude    *float64
+	Longitude   *float64
+	RadiusKM    float64
+	MinLat      *float64
+	MinLng      *float64
+	MaxLat      *float64
+	MaxLng      *float64
+	IncludePast bool
+}
+
+type NearbyContactsResponse struct {
+	// Center is the point distances are measured from: the given or
+	// geocoded point, or the middle of the bounding box.
+	Center   GeoPoint            `json:"center"`
+	RadiusKM float64             `json:"radius_km,omitempty" example:"50"`
+	Contacts []NearbyContactItem `json:"contacts"`
+}
+
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude" example:"45.5152"`
+	Longitude float64 `json:"longitude" example:"-122.6784"`
+}
+
+// NearbyContactItem is a contact with their address closest to the center.
+type NearbyContactItem struct {
+	ContactID     string  `json:"contact_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ContactName   string  `json:"contact_name" example:"John Doe"`
+	AddressID     uint    `json:"address_id" example:"1"`
+	City          string  `json:"city" example:"Portland"`
+	Province      string  `json:"province" example:"Oregon"`
+	Country       string  `json:"country" example:"US"`
+	Latitude      float64 `json:"latitude" example:"45.5231"`
+	Longitude     float64 `json:"longitude" example:"-122.6765"`
+	IsPastAddress bool    `json:"is_past_address" example:"false"`
+	DistanceKM    float64 `json:"distance_km" example:"0.9"`
+}
+
+// MapQuery selects the addresses of a vault map view. Zoom follows web map
+// zoom levels, 0 showing the whole world, and sets how close addresses
+// must be to be clustered.
+type MapQuery struct {
+	Zoom        *int
+	MinLat      *float64
+	MinLng      *float64
+	MaxLat      *float64
+	MaxLng      *float64
+	IncludePast bool
+}
+
+// GeoJSONFeatureCollection is a GeoJSON (RFC 7946) collection of points.
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type" example:"FeatureCollection"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+type GeoJSONFeature struct {
+	Type     string       `json:"type" example:"Feature"`
+	Geometry GeoJSONPoint `json:"geometry"`
+	// BBox bounds the addresses of a cluster as [west, south, east, north].
+	BBox       []float64            `json:"bbox,omitempty"`
+	Properties MapFeatureProperties `json:"properties"`
+}
+
+type GeoJSONPoint struct {
+	Type string `json:"type" example:"Point"`
+	// Coordinates are [longitude, latitude].
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// MapFeatureProperties describe a single address, or a cluster of
+// addresses when Cluster is set.
+type MapFeatureProperties struct {
+	Cluster       bool     `json:"cluster"`
+	PointCount    int      `json:"point_count" example:"1"`
+	ContactIDs    []string `json:"contact_ids"`
+	ContactID     string   `json:"contact_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ContactName   string   `json:"contact_name,omitempty" example:"John Doe"`
+	AddressID     uint     `json:"address_id,omitempty" example:"1"`
+	City          string   `json:"city,omitempty" example:"Portland"`
+	Country       string   `json:"country,omitempty" example:"US"`
+	IsPastAddress bool     `json:"is_past_address" example:"false"`
+}
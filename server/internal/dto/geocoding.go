@@ -0,0 +1,10 @@
+package dto
+
+type GeocodingBackfillResponse struct {
+	// Queued is the number of addresses without coordinates that were
+	// queued by this request.
+	Queued int `json:"queued" example:"42"`
+	// Pending is the number of addresses of the vault waiting for the
+	// geocoding worker, including ones queued earlier.
+	Pending int64 `json:"pending" example:"45"`
+}
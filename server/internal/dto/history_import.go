@@ -0,0 +1,29 @@
+package dto
+
+// HistoryImportOptions choose how a chat or call history is imported.
+type HistoryImportOptions struct {
+	// Format is whatsapp, telegram or android.
+	Format string `json:"format" example:"whatsapp"`
+	// Mode is activity, to log one activity per conversation and day, or
+	// note, to add a note to each contact in it.
+	Mode string `json:"mode" example:"activity"`
+	// Owner is the name the user appears under in the export. It defaults
+	// to the user's name.
+	Owner string `json:"owner,omitempty" example:"Jane Doe"`
+	// Filename is the name of the uploaded file, which names the chat of a
+	// WhatsApp export.
+	Filename string `json:"filename,omitempty" example:"WhatsApp Chat with Alice.txt"`
+}
+
+type HistoryImportResponse struct {
+	Format                string `json:"format" example:"whatsapp"`
+	ImportedCalls         int    `json:"imported_calls" example:"12"`
+	ImportedConversations int    `json:"imported_conversations" example:"30"`
+	UpdatedConversations  int    `json:"updated_conversations" example:"1"`
+	SkippedCount          int    `json:"skipped_count" example:"0"`
+	MatchedContacts       int    `json:"matched_contacts" example:"4"`
+	// Unmatched lists the people of the export no contact was found for,
+	// by name or phone number.
+	Unmatched []string `json:"unmatched"`
+	Errors    []string `json:"errors,omitempty"`
+}
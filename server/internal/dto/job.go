@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type JobResponse struct {
+	ID              uint            `json:"id" example:"1"`
+	Type            string          `json:"type" example:"monica_import"`
+	Status          string          `json:"status" example:"running"`
+	VaultID         *string         `json:"vault_id,omitempty"`
+	Progress        int             `json:"progress" example:"40"`
+	ProcessedItems  int             `json:"processed_items" example:"120"`
+	TotalItems      int             `json:"total_items" example:"300"`
+	ErrorCount      int             `json:"error_count" example:"2"`
+	Result          json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	Error           *string         `json:"error,omitempty"`
+	CancelRequested bool            `json:"cancel_requested"`
+	StartedAt       *time.Time      `json:"started_at,omitempty"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+type JobErrorResponse struct {
+	ID        uint      `json:"id" example:"1"`
+	Message   string    `json:"message" example:"row 12: invalid email address"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+// JournalImportOptions choose how journal entries are imported.
+type JournalImportOptions struct {
+	// JournalID is the journal the entries are added to.
+	JournalID uint `json:"journal_id" example:"1"`
+	// Format is markdown (a Bonds export), dayone or obsidian.
+	Format string `json:"format" example:"markdown"`
+	// Filename is the name of the uploaded file, which dates an Obsidian
+	// daily note uploaded on its own.
+	Filename string `json:"filename,omitempty" example:"2024-01-05.md"`
+}
+
+type JournalImportResponse struct {
+	Format          string `json:"format" example:"obsidian"`
+	ImportedPosts   int    `json:"imported_posts" example:"120"`
+	ImportedPhotos  int    `json:"imported_photos" example:"14"`
+	SkippedCount    int    `json:"skipped_count" example:"2"`
+	MatchedContacts int    `json:"matched_contacts" example:"6"`
+	CreatedTags     int    `json:"created_tags" example:"3"`
+	// Unmatched lists the contacts named by the entries that no contact of
+	// the vault was found for.
+	Unmatched []string `json:"unmatched"`
+	// SkippedFiles lists the files that are not journal entries, such as
+	// notes without a date, with the reason.
+	SkippedFiles []string `json:"skipped_files,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+}
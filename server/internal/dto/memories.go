@@ -0,0 +1,53 @@
+package dto
+
+import "time"
+
+// MemoryResponse is a journal post, activity, call, note, photo or
+// important date from an earlier year. Kind and ID identify it when it is
+// dismissed or marked as a favorite.
+type MemoryResponse struct {
+	Kind      string `json:"kind" example:"post"`
+	ID        uint   `json:"id" example:"1"`
+	VaultID   string `json:"vault_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	VaultName string `json:"vault_name" example:"Family"`
+	// Date is when it happened. Activities and important dates have no time
+	// of day and are given at midnight UTC.
+	Date         time.Time `json:"date" example:"2019-10-19T12:30:00Z"`
+	YearsAgo     int       `json:"years_ago" example:"7"`
+	CalendarType string    `json:"calendar_type" example:"gregorian"`
+	Title        string    `json:"title" example:"Lunch at the lake"`
+	Summary      string    `json:"summary,omitempty" example:"We finally tried the new place by the water."`
+	ContactID    string    `json:"contact_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ContactName  string    `json:"contact_name,omitempty" example:"Alice Smith"`
+	JournalID    *uint     `json:"journal_id,omitempty" example:"1"`
+	// PostID is the journal post a photo belongs to.
+	PostID      *uint  `json:"post_id,omitempty" example:"1"`
+	PhotoIDs    []uint `json:"photo_ids,omitempty"`
+	IsFavorite  bool   `json:"is_favorite" example:"false"`
+	IsDismissed bool   `json:"is_dismissed" example:"false"`
+}
+
+type OnThisDayResponse struct {
+	Date     string           `json:"date" example:"2026-10-19"`
+	Memories []MemoryResponse `json:"memories"`
+}
+
+// UpdateMemoryStateRequest dismisses a memory or marks it as a favorite.
+// Fields left out keep their value.
+type UpdateMemoryStateRequest struct {
+	Dismissed *bool `json:"dismissed" example:"true"`
+	Favorite  *bool `json:"favorite" example:"false"`
+}
+
+// MemoryPreferenceResponse describes the daily "On this day" notification
+// of the current user. NotifyHour is the hour, in the user's timezone, from
+// which it is sent.
+type MemoryPreferenceResponse struct {
+	NotifyDaily bool `json:"notify_daily" example:"true"`
+	NotifyHour  int  `json:"notify_hour" example:"8"`
+}
+
+type UpdateMemoryPreferenceRequest struct {
+	NotifyDaily bool `json:"notify_daily" example:"true"`
+	NotifyHour  int  `json:"notify_hour" validate:"min=0,max=23" example:"8"`
+}
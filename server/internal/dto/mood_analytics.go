@@ -0,0 +1,113 @@
+package dto
+
+// MoodAnalyticsQuery selects the days covered by the mood analytics. From
+// and To are dates (YYYY-MM-DD) in the user's timezone; without From the
+// analytics start at the first entry, without To they end today. Period is
+// "week" (the default) or "month"; Window is the length of the rolling
+// average in days (7 by default).
+type MoodAnalyticsQuery struct {
+	From   string
+	To     string
+	Period string
+	Window int
+}
+
+// MoodAnalyticsResponse aggregates the mood entries of the current user in
+// a vault. Scores run from 1 for the last mood parameter to the number of
+// parameters for the first, so with the default scale "Awesome" is 5 and
+// "Awful" is 1.
+type MoodAnalyticsResponse struct {
+	From         string                `json:"from" example:"2026-01-01"`
+	To           string                `json:"to" example:"2026-10-19"`
+	Timezone     string                `json:"timezone" example:"Europe/Lisbon"`
+	Period       string                `json:"period" example:"week"`
+	Window       int                   `json:"window" example:"7"`
+	Scale        []MoodScaleItem       `json:"scale"`
+	Summary      MoodSummary           `json:"summary"`
+	Periods      []MoodPeriodItem      `json:"periods"`
+	Days         []MoodDayItem         `json:"days"`
+	Weekdays     []MoodWeekdayItem     `json:"weekdays"`
+	Correlations []MoodCorrelationItem `json:"correlations"`
+}
+
+type MoodScaleItem struct {
+	ParameterID uint   `json:"parameter_id" example:"1"`
+	Label       string `json:"label" example:"Awesome"`
+	HexColor    string `json:"hex_color" example:"bg-lime-500"`
+	Score       int    `json:"score" example:"5"`
+	Count       int    `json:"count" example:"12"`
+}
+
+// MoodSummary covers the whole range. Streaks count consecutive days with
+// at least one entry; the current streak may end today or yesterday.
+type MoodSummary struct {
+	Entries       int      `json:"entries" example:"42"`
+	DaysLogged    int      `json:"days_logged" example:"38"`
+	AverageScore  *float64 `json:"average_score" example:"3.6"`
+	AverageSleep  *float64 `json:"average_sleep" example:"7.25"`
+	CurrentStreak int      `json:"current_streak" example:"4"`
+	LongestStreak int      `json:"longest_streak" example:"11"`
+}
+
+// MoodPeriodItem is a week, starting on the user's week_start day, or a
+// calendar month with at least one entry.
+type MoodPeriodItem struct {
+	Start        string                 `json:"start" example:"2026-10-12"`
+	End          string                 `json:"end" example:"2026-10-18"`
+	Entries      int                    `json:"entries" example:"6"`
+	AverageScore *float64               `json:"average_score" example:"3.83"`
+	AverageSleep *float64               `json:"average_sleep" example:"7"`
+	Distribution []MoodDistributionItem `json:"distribution"`
+}
+
+// MoodDayItem is a day with at least one entry. The rolling averages cover
+// the entries of the Window days ending on Date.
+type MoodDayItem struct {
+	Date         string   `json:"date" example:"2026-10-19"`
+	Entries      int      `json:"entries" example:"1"`
+	AverageScore float64  `json:"average_score" example:"4"`
+	AverageSleep *float64 `json:"average_sleep" example:"8"`
+	RollingScore float64  `json:"rolling_score" example:"3.71"`
+	RollingSleep *float64 `json:"rolling_sleep" example:"7.2"`
+}
+
+// MoodWeekdayItem aggregates the entries of one day of the week. Weekday
+// runs from 1 for Monday to 7 for Sunday; the list starts on the user's
+// week_start day.
+type MoodWeekdayItem struct {
+	Weekday      int                    `json:"weekday" example:"1"`
+	Name         string                 `json:"name" example:"monday"`
+	Entries      int                    `json:"entries" example:"6"`
+	AverageScore *float64               `json:"average_score" example:"3.2"`
+	Distribution []MoodDistributionItem `json:"distribution"`
+}
+
+type MoodDistributionItem struct {
+	ParameterID uint `json:"parameter_id" example:"1"`
+	Count       int  `json:"count" example:"3"`
+}
+
+// MoodCorrelationItem is the Pearson correlation between the daily average
+// mood score and another daily value: the hours slept ("sleep"), the sum of
+// a journal metric over the posts written that day ("post_metric"), or the
+// number of "+1" increments of a life metric ("life_metric"). ID and Label
+// identify the metric. Coefficient is null with fewer than 3 days or when
+// either value never changes.
+type MoodCorrelationItem struct {
+	Source      string   `json:"source" example:"post_metric"`
+	ID          *uint    `json:"id,omitempty" example:"1"`
+	Label       string   `json:"label,omitempty" example:"Running: Kilometers"`
+	Samples     int      `json:"samples" example:"21"`
+	Coefficient *float64 `json:"coefficient" example:"0.42"`
+}
+
+// MoodFeedItem is one day of the mood feed, a flat series for dashboards.
+// Time is local midnight of the day.
+type MoodFeedItem struct {
+	Time         string   `json:"time" example:"2026-10-19T00:00:00+01:00"`
+	Entries      int      `json:"entries" example:"1"`
+	Score        float64  `json:"score" example:"4"`
+	Sleep        *float64 `json:"sleep" example:"8"`
+	RollingScore float64  `json:"rolling_score" example:"3.71"`
+	RollingSleep *float64 `json:"rolling_sleep" example:"7.2"`
+}
@@ -31,3 +31,30 @@ type OAuthProviderResponse struct {
 	DiscoveryURL string `json:"discovery_url" example:""`
 	Scopes       string `json:"scopes" example:""`
 }
+
+type OAuthClaimRuleRequest struct {
+	Claim        string `json:"claim" validate:"required" example:"groups"`
+	Value        string `json:"value" validate:"required" example:"family"`
+	AccountAdmin bool   `json:"account_admin" example:"false"`
+	VaultName    string `json:"vault_name" example:"Family"`
+	Permission   int    `json:"permission" example:"200"`
+}
+
+type UpdateOAuthClaimRulesRequest struct {
+	AccountID string                  `json:"account_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Rules     []OAuthClaimRuleRequest `json:"rules"`
+}
+
+type OAuthClaimRuleResponse struct {
+	ID           uint   `json:"id" example:"1"`
+	Claim        string `json:"claim" example:"groups"`
+	Value        string `json:"value" example:"family"`
+	AccountAdmin bool   `json:"account_admin" example:"false"`
+	VaultName    string `json:"vault_name" example:"Family"`
+	Permission   int    `json:"permission" example:"200"`
+}
+
+type OAuthClaimRulesResponse struct {
+	AccountID string                   `json:"account_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Rules     []OAuthClaimRuleResponse `json:"rules"`
+}
@@ -0,0 +1,160 @@
+package dto
+
+import "time"
+
+// The OAuth protocol endpoints answer in the formats of their RFCs, without
+// the API response envelope.
+
+type OAuthAuthorizeParams struct {
+	ResponseType        string `query:"response_type"`
+	ClientID            string `query:"client_id"`
+	RedirectURI         string `query:"redirect_uri"`
+	Scope               string `query:"scope"`
+	State               string `query:"state"`
+	CodeChallenge       string `query:"code_challenge"`
+	CodeChallengeMethod string `query:"code_challenge_method"`
+}
+
+// OAuthAuthorizeResult tells the authorization endpoint where to send the
+// browser. RedirectURI is set once it has been verified, so that errors can
+// be reported to the client.
+type OAuthAuthorizeResult struct {
+	RequestID   string
+	RedirectURI string
+	State       string
+}
+
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	DeviceCode   string `form:"device_code"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token" example:"bonds_0123abcd"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in" example:"3600"`
+	RefreshToken string `json:"refresh_token,omitempty" example:"bondsrt_0123abcd"`
+	Scope        string `json:"scope" example:"full"`
+}
+
+type OAuthErrorResponse struct {
+	Error            string `json:"error" example:"invalid_grant"`
+	ErrorDescription string `json:"error_description,omitempty" example:"authorization code is invalid or expired"`
+}
+
+type OAuthClientRegistrationRequest struct {
+	ClientName              string   `json:"client_name" example:"My CLI"`
+	RedirectURIs            []string `json:"redirect_uris" example:"http://127.0.0.1/callback"`
+	GrantTypes              []string `json:"grant_types" example:"authorization_code,refresh_token"`
+	ResponseTypes           []string `json:"response_types" example:"code"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method" example:"none"`
+	Scope                   string   `json:"scope" example:"full"`
+}
+
+type OAuthClientRegistrationResponse struct {
+	ClientID                string   `json:"client_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ClientSecret            string   `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64    `json:"client_id_issued_at" example:"1767225600"`
+	ClientSecretExpiresAt   int64    `json:"client_secret_expires_at" example:"0"`
+	ClientName              string   `json:"client_name" example:"My CLI"`
+	RedirectURIs            []string `json:"redirect_uris" example:"http://127.0.0.1/callback"`
+	GrantTypes              []string `json:"grant_types" example:"authorization_code,refresh_token"`
+	ResponseTypes           []string `json:"response_types" example:"code"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method" example:"none"`
+}
+
+type OAuthDeviceAuthorizationRequest struct {
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Scope        string `form:"scope"`
+}
+
+type OAuthDeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code" example:"BCDF-GHJK"`
+	VerificationURI         string `json:"verification_uri" example:"https://bonds.example.com/oauth/device"`
+	VerificationURIComplete string `json:"verification_uri_complete" example:"https://bonds.example.com/oauth/device?user_code=BCDF-GHJK"`
+	ExpiresIn               int    `json:"expires_in" example:"600"`
+	Interval                int    `json:"interval" example:"5"`
+}
+
+type OAuthRevokeRequest struct {
+	Token        string `form:"token"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type OAuthServerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	RegistrationEndpoint              string   `json:"registration_endpoint,omitempty"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	AuthorizationResponseIssParameter bool     `json:"authorization_response_iss_parameter_supported"`
+}
+
+type OAuthProtectedResourceMetadata struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers"`
+	ScopesSupported        []string `json:"scopes_supported"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported"`
+}
+
+// The consent and authorized-app endpoints below are regular API endpoints.
+
+type OAuthScopeInfo struct {
+	Name        string `json:"name" example:"full"`
+	Description string `json:"description" example:"Full access to your Bonds data"`
+}
+
+type OAuthConsentResponse struct {
+	ClientID    string           `json:"client_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ClientName  string           `json:"client_name" example:"My CLI"`
+	RedirectURI string           `json:"redirect_uri,omitempty" example:"http://127.0.0.1/callback"`
+	UserCode    string           `json:"user_code,omitempty" example:"BCDF-GHJK"`
+	Scopes      []OAuthScopeInfo `json:"scopes"`
+}
+
+type OAuthConsentDecisionRequest struct {
+	Approve bool `json:"approve" example:"true"`
+}
+
+type OAuthConsentDecisionResponse struct {
+	RedirectURL string `json:"redirect_url" example:"http://127.0.0.1/callback?code=abc&state=xyz"`
+}
+
+type OAuthDeviceDecisionRequest struct {
+	UserCode string `json:"user_code" validate:"required" example:"BCDF-GHJK"`
+	Approve  bool   `json:"approve" example:"true"`
+}
+
+type AuthorizedAppResponse struct {
+	ID         uint       `json:"id" example:"1"`
+	ClientID   string     `json:"client_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ClientName string     `json:"client_name" example:"My CLI"`
+	Scopes     []string   `json:"scopes" example:"full"`
+	LastUsedAt *time.Time `json:"last_used_at" example:"2026-02-23T08:00:00Z"`
+	CreatedAt  time.Time  `json:"created_at" example:"2026-01-15T10:30:00Z"`
+}
+
+type OAuthClientResponse struct {
+	ID           string    `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name         string    `json:"name" example:"My CLI"`
+	RedirectURIs []string  `json:"redirect_uris" example:"http://127.0.0.1/callback"`
+	GrantTypes   []string  `json:"grant_types" example:"authorization_code,refresh_token"`
+	Confidential bool      `json:"confidential" example:"false"`
+	Dynamic      bool      `json:"dynamic" example:"true"`
+	CreatedAt    time.Time `json:"created_at" example:"2026-01-15T10:30:00Z"`
+}
@@ -15,6 +15,13 @@ type CreateReminderRequest struct {
 	FrequencyNumber *int     `json:"frequency_number" example:"1"`
 	Audience        string   `json:"audience" example:"all_vault_users"`
 	SelectedUserIDs []string `json:"selected_user_ids"`
+	// LeadTimeDays — days before each occurrence to fire, e.g. [14, 3, 0].
+	// Empty means on the day only. Each value must be within 0..365.
+	LeadTimeDays []int `json:"lead_time_days" example:"14,3,0"`
+	// EscalateAfterHours — notify the other vault members when a recipient
+	// has not acknowledged an occurrence after this many hours (1..720).
+	// Omitted or 0 disables escalation.
+	EscalateAfterHours *int `json:"escalate_after_hours" example:"24"`
 }
 
 type UpdateReminderRequest struct {
@@ -30,6 +37,11 @@ type UpdateReminderRequest struct {
 	FrequencyNumber *int      `json:"frequency_number" example:"1"`
 	Audience        *string   `json:"audience" example:"all_vault_users"`
 	SelectedUserIDs *[]string `json:"selected_user_ids"`
+	// LeadTimeDays / EscalateAfterHours — see CreateReminderRequest. nil
+	// leaves the current value untouched; an empty list resets lead times to
+	// "on the day" and 0 disables escalation.
+	LeadTimeDays       *[]int `json:"lead_time_days" example:"14,3,0"`
+	EscalateAfterHours *int   `json:"escalate_after_hours" example:"24"`
 }
 
 type ReminderResponse struct {
@@ -51,4 +63,15 @@ type ReminderResponse struct {
 	SelectedUserIDs      []string   `json:"selected_user_ids"`
 	CreatedAt            time.Time  `json:"created_at" example:"2026-01-15T10:30:00Z"`
 	UpdatedAt            time.Time  `json:"updated_at" example:"2026-01-15T10:30:00Z"`
+	LeadTimeDays         []int      `json:"lead_time_days" example:"14,3,0"`
+	EscalateAfterHours   *int       `json:"escalate_after_hours" example:"24"`
+}
+
+// ReminderActionResponse describes a reminder action link, before and after
+// it is applied.
+type ReminderActionResponse struct {
+	Action        string     `json:"action" example:"snooze_1d"`
+	ReminderLabel string     `json:"reminder_label" example:"Call Mom"`
+	SnoozedUntil  *time.Time `json:"snoozed_until" example:"2026-01-16T09:00:00Z"`
+	Locale        string     `json:"-"`
 }
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+// UserSessionResponse is one signed-in device in the session list. Device is
+// a short description derived from the user agent.
+type UserSessionResponse struct {
+	ID         string    `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Device     string    `json:"device" example:"Firefox on Linux"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"`
+	IPAddress  string    `json:"ip_address" example:"203.0.113.7"`
+	Current    bool      `json:"current" example:"true"`
+	LastUsedAt time.Time `json:"last_used_at" example:"2026-01-15T10:30:00Z"`
+	ExpiresAt  time.Time `json:"expires_at" example:"2026-01-22T10:30:00Z"`
+	CreatedAt  time.Time `json:"created_at" example:"2026-01-15T10:30:00Z"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked" example:"3"`
+}
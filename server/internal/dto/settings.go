@@ -8,6 +8,7 @@ type PreferencesResponse struct {
 	WeekStart                 string   `json:"week_start" example:"sunday"`
 	Timezone                  string   `json:"timezone" example:"America/New_York"`
 	Locale                    string   `json:"locale" example:"en"`
+	PhoneRegion               string   `json:"phone_region" example:"US"`
 	NumberFormat              string   `json:"number_format" example:"1,234.56"`
 	DistanceFormat            string   `json:"distance_format" example:"km"`
 	DefaultMapSite            string   `json:"default_map_site" example:"google_maps"`
@@ -46,6 +47,7 @@ type UpdatePreferencesRequest struct {
 	WeekStart                 string   `json:"week_start" example:"sunday"`
 	Timezone                  string   `json:"timezone" example:"America/New_York"`
 	Locale                    string   `json:"locale" example:"en"`
+	PhoneRegion               string   `json:"phone_region" example:"US"`
 	NumberFormat              string   `json:"number_format" example:"1,234.56"`
 	DistanceFormat            string   `json:"distance_format" example:"km"`
 	DefaultMapSite            string   `json:"default_map_site" example:"google_maps"`
@@ -72,6 +74,26 @@ type UpdateNotificationChannelRequest struct {
 	PreferredTime string `json:"preferred_time" example:"09:00"`
 }
 
+// WebPushSubscribeRequest registers a browser push subscription as a
+// notification channel. Endpoint and Keys are PushSubscription.toJSON().
+type WebPushSubscribeRequest struct {
+	Endpoint      string               `json:"endpoint" validate:"required" example:"https://fcm.googleapis.com/fcm/send/abc123"`
+	Keys          WebPushSubscribeKeys `json:"keys"`
+	Label         string               `json:"label" example:"Phone"`
+	PreferredTime string               `json:"preferred_time" example:"09:00"`
+}
+
+type WebPushSubscribeKeys struct {
+	P256dh string `json:"p256dh" example:"BNcRdreALRFXTkOOUHK1EtK2wtaz5Ry4YfYCA_0QTpQtUbVlUls0VJXg7A8u-Ts1XbjhazAkj7I99e8QcYP7DkM"`
+	Auth   string `json:"auth" example:"tBHItJI5svbpez7KI4CCXg"`
+}
+
+// WebPushPublicKeyResponse carries the VAPID application server key for
+// pushManager.subscribe.
+type WebPushPublicKeyResponse struct {
+	PublicKey string `json:"public_key" example:"BEl62iUYgUivxIkv69yViEuiBIa-Ib9-SkvMeAtA3LFgDzkrxZJjSgSnfckjBJuBkr3qBUYIHBQFLXYp5Nksh8U"`
+}
+
 type NotificationChannelResponse struct {
 	ID            uint       `json:"id" example:"1"`
 	Type          string     `json:"type" example:"email"`
@@ -84,6 +106,24 @@ type NotificationChannelResponse struct {
 	UpdatedAt     time.Time  `json:"updated_at" example:"2026-01-15T10:30:00Z"`
 }
 
+// TaskNotificationPreferenceResponse describes which task notifications the
+// current user receives on their active notification channels.
+// DueLeadMinutes is how long before a task's due date the "due soon"
+// notification fires.
+type TaskNotificationPreferenceResponse struct {
+	NotifyOnAssignment bool `json:"notify_on_assignment" example:"true"`
+	NotifyBeforeDue    bool `json:"notify_before_due" example:"true"`
+	DueLeadMinutes     int  `json:"due_lead_minutes" example:"1440"`
+	NotifyWhenOverdue  bool `json:"notify_when_overdue" example:"true"`
+}
+
+type UpdateTaskNotificationPreferenceRequest struct {
+	NotifyOnAssignment bool `json:"notify_on_assignment" example:"true"`
+	NotifyBeforeDue    bool `json:"notify_before_due" example:"true"`
+	DueLeadMinutes     int  `json:"due_lead_minutes" validate:"min=0,max=43200" example:"1440"`
+	NotifyWhenOverdue  bool `json:"notify_when_overdue" example:"true"`
+}
+
 type PersonalizeEntityRequest struct {
 	Label    string `json:"label" example:"Male"`
 	Name     string `json:"name" example:"genders"`
@@ -26,7 +26,11 @@ type CreateTaskRequest struct {
 	OriginalDay   *int   `json:"original_day" example:"15"`
 	OriginalMonth *int   `json:"original_month" example:"8"`
 	OriginalYear  *int   `json:"original_year" example:"2026"`
-	Status        string `json:"status" example:"todo"`
+	// RecurrenceRule — optional RFC 5545 RRULE (e.g. "FREQ=WEEKLY;INTERVAL=2").
+	// Requires DueAt. A yearly rule on a lunar due date recurs on the lunar
+	// date. Completing the task spawns the next occurrence.
+	RecurrenceRule string `json:"recurrence_rule" example:"FREQ=YEARLY"`
+	Status         string `json:"status" example:"todo"`
 	// ContactIDs — additional assignees besides the contact in the URL. The
 	// URL's contact is always included; extras here add a multi-person task.
 	ContactIDs []string `json:"contact_ids" example:"[\"550e8400-e29b-41d4-a716-446655440000\"]"`
@@ -46,7 +50,9 @@ type UpdateTaskRequest struct {
 	OriginalDay   *int   `json:"original_day" example:"15"`
 	OriginalMonth *int   `json:"original_month" example:"8"`
 	OriginalYear  *int   `json:"original_year" example:"2026"`
-	Status        string `json:"status" example:"todo"`
+	// RecurrenceRule — see CreateTaskRequest. Empty clears the recurrence.
+	RecurrenceRule string `json:"recurrence_rule" example:"FREQ=YEARLY"`
+	Status         string `json:"status" example:"todo"`
 	// ContactIDs — when nil, assignees are left untouched. When provided
 	// (even as an empty slice), the assignee set is replaced verbatim. The
 	// contact in the URL path is always re-added to keep the task visible
@@ -85,4 +91,22 @@ type TaskResponse struct {
 	Contacts      []TaskContactRef `json:"contacts"`
 	CreatedAt     time.Time        `json:"created_at" example:"2026-01-15T10:30:00Z"`
 	UpdatedAt     time.Time        `json:"updated_at" example:"2026-01-15T10:30:00Z"`
+	// RecurrenceRule is the RRULE of a recurring task, empty otherwise.
+	// NextOccurrenceID is set on completed occurrences whose successor has
+	// already been spawned; such rows are completion history.
+	RecurrenceRule   string `json:"recurrence_rule" example:"FREQ=YEARLY"`
+	NextOccurrenceID *uint  `json:"next_occurrence_id" example:"43"`
+}
+
+// TaskCompletionResponse is one entry of a recurring task's completion
+// history: a completed occurrence with the due date it was completed for.
+type TaskCompletionResponse struct {
+	TaskID           uint       `json:"task_id" example:"42"`
+	DueAt            *time.Time `json:"due_at" example:"2026-01-15T10:30:00Z"`
+	CompletedAt      *time.Time `json:"completed_at" example:"2026-01-16T08:00:00Z"`
+	CalendarType     string     `json:"calendar_type" example:"gregorian"`
+	OriginalDay      *int       `json:"original_day" example:"15"`
+	OriginalMonth    *int       `json:"original_month" example:"8"`
+	OriginalYear     *int       `json:"original_year" example:"2026"`
+	NextOccurrenceID *uint      `json:"next_occurrence_id" example:"43"`
 }
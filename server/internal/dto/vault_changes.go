@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+type VaultChangesResponse struct {
+	Changes []VaultChangeItem `json:"changes"`
+	Cursor  string            `json:"cursor" example:"MTI4"`
+	HasMore bool              `json:"has_more"`
+}
+
+// VaultChangeItem is the latest state of one entity. Data holds the same
+// object the entity's own endpoints return and is omitted for deletions.
+type VaultChangeItem struct {
+	EntityType string      `json:"entity_type" example:"note"`
+	EntityID   string      `json:"entity_id" example:"42"`
+	Action     string      `json:"action" example:"updated"`
+	ChangedAt  time.Time   `json:"changed_at" example:"2026-01-15T10:30:00Z"`
+	Data       interface{} `json:"data,omitempty"`
+}
+
+// VaultStreamEvent is one server-sent event of the vault change stream.
+// Type is the entity type and the action, such as note.updated; Data is the
+// VaultChangeItem of a change or the FeedItemResponse of a new feed item.
+// ID is empty for events that are not the last of their page.
+type VaultStreamEvent struct {
+	ID   string      `json:"id,omitempty" example:"128-57"`
+	Type string      `json:"type" example:"note.updated"`
+	Data interface{} `json:"data"`
+}
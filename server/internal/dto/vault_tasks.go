@@ -25,6 +25,12 @@ type VaultTaskResponse struct {
 	OriginalYear  *int      `json:"original_year" example:"2026"`
 	CreatedAt     time.Time `json:"created_at" example:"2026-01-15T10:30:00Z"`
 	UpdatedAt     time.Time `json:"updated_at" example:"2026-01-15T10:30:00Z"`
+	// RecurrenceRule / NextOccurrenceID — see TaskResponse.
+	RecurrenceRule   string `json:"recurrence_rule" example:"FREQ=YEARLY"`
+	NextOccurrenceID *uint  `json:"next_occurrence_id" example:"43"`
+	// AssigneeUserIDs — vault users responsible for the task. They receive
+	// assignment, due-soon and overdue notifications.
+	AssigneeUserIDs []string `json:"assignee_user_ids" example:"[\"550e8400-e29b-41d4-a716-446655440001\"]"`
 }
 
 type CreateVaultTaskRequest struct {
@@ -45,6 +51,11 @@ type CreateVaultTaskRequest struct {
 	OriginalDay   *int   `json:"original_day" example:"15"`
 	OriginalMonth *int   `json:"original_month" example:"8"`
 	OriginalYear  *int   `json:"original_year" example:"2026"`
+	// RecurrenceRule — optional RFC 5545 RRULE; see CreateTaskRequest.
+	RecurrenceRule string `json:"recurrence_rule" example:"FREQ=YEARLY"`
+	// AssigneeUserIDs — vault users to assign. Every ID must be a member of
+	// the vault.
+	AssigneeUserIDs []string `json:"assignee_user_ids" example:"[\"550e8400-e29b-41d4-a716-446655440001\"]"`
 }
 
 // UpdateVaultTaskRequest replaces the editable fields of a vault task in one
@@ -67,4 +78,9 @@ type UpdateVaultTaskRequest struct {
 	OriginalDay   *int   `json:"original_day" example:"15"`
 	OriginalMonth *int   `json:"original_month" example:"8"`
 	OriginalYear  *int   `json:"original_year" example:"2026"`
+	// RecurrenceRule — empty clears the recurrence.
+	RecurrenceRule string `json:"recurrence_rule" example:"FREQ=YEARLY"`
+	// AssigneeUserIDs replaces the assigned users when provided; nil leaves
+	// them untouched.
+	AssigneeUserIDs *[]string `json:"assignee_user_ids" example:"[\"550e8400-e29b-41d4-a716-446655440001\"]"`
 }
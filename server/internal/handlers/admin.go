@@ -16,12 +16,13 @@ type AdminHandler struct {
 	adminService   *services.AdminService
 	settingService *services.SystemSettingService
 	searchService  *services.SearchService
+	jobService     *services.JobService
 	db             *gorm.DB
 	reloaders      []func()
 }
 
-func NewAdminHandler(adminService *services.AdminService, settingService *services.SystemSettingService, searchService *services.SearchService, db *gorm.DB) *AdminHandler {
-	return &AdminHandler{adminService: adminService, settingService: settingService, searchService: searchService, db: db}
+func NewAdminHandler(adminService *services.AdminService, settingService *services.SystemSettingService, searchService *services.SearchService, jobService *services.JobService, db *gorm.DB) *AdminHandler {
+	return &AdminHandler{adminService: adminService, settingService: settingService, searchService: searchService, jobService: jobService, db: db}
 }
 
 func (h *AdminHandler) RegisterReloader(fn func()) {
@@ -267,16 +268,25 @@ func (h *AdminHandler) UpdateSettings(c echo.Context) error {
 // RebuildSearchIndex godoc
 //
 //	@Summary		Rebuild search index
-//	@Description	Rebuild the full-text search index by re-indexing all contacts and notes (instance admin only)
+//	@Description	Rebuild the full-text search index by re-indexing all contacts and notes (instance admin only). With async=true the rebuild runs as a background job and the job is returned.
 //	@Tags			admin
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Success		200	{object}	response.APIResponse{data=dto.RebuildSearchIndexResponse}
+//	@Param			async	query		boolean	false	"Run as a background job"
+//	@Success		200		{object}	response.APIResponse{data=dto.RebuildSearchIndexResponse}
+//	@Success		202		{object}	response.APIResponse{data=dto.JobResponse}
 //	@Failure		401	{object}	response.APIResponse
 //	@Failure		403	{object}	response.APIResponse
 //	@Failure		500	{object}	response.APIResponse
 //	@Router			/admin/search/rebuild [post]
 func (h *AdminHandler) RebuildSearchIndex(c echo.Context) error {
+	async, err := runAsync(c)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if async {
+		return enqueueJob(c, h.jobService, nil, services.JobTypeSearchRebuild, nil, nil)
+	}
 	contactCount, noteCount, err := h.searchService.RebuildIndex(h.db)
 	if err != nil {
 		return response.InternalError(c, "err.failed_to_rebuild_search_index")
@@ -60,7 +60,7 @@ func (h *AuthHandler) Register(c echo.Context) error {
 // Login godoc
 //
 //	@Summary		Log in
-//	@Description	Authenticate with email and password
+//	@Description	Authenticate with email and password, or with LDAP credentials when LDAP is enabled
 //	@Tags			auth
 //	@Accept			json
 //	@Produce		json
@@ -68,6 +68,7 @@ func (h *AuthHandler) Register(c echo.Context) error {
 //	@Success		200		{object}	response.APIResponse{data=dto.AuthResponse}
 //	@Failure		400		{object}	response.APIResponse
 //	@Failure		401		{object}	response.APIResponse
+//	@Failure		403		{object}	response.APIResponse
 //	@Failure		422		{object}	response.APIResponse
 //	@Failure		500		{object}	response.APIResponse
 //	@Router			/auth/login [post]
@@ -80,7 +81,8 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		return response.ValidationError(c, map[string]string{"validation": err.Error()})
 	}
 
-	if h.settingService != nil && !h.settingService.GetBool("auth.password.enabled", true) {
+	if h.settingService != nil && !h.settingService.GetBool("auth.password.enabled", true) &&
+		!h.settingService.GetBool("ldap.enabled", false) {
 		return response.Forbidden(c, "err.password_auth_disabled")
 	}
 
@@ -92,22 +94,76 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		if errors.Is(err, services.ErrUserDisabled) {
 			return response.Forbidden(c, "err.user_account_disabled")
 		}
+		if errors.Is(err, services.ErrLDAPUserNotProvisioned) {
+			return response.Forbidden(c, "err.ldap_user_not_provisioned")
+		}
+		if errors.Is(err, services.ErrLDAPMissingEmail) {
+			return response.Forbidden(c, "err.ldap_missing_email")
+		}
+		if errors.Is(err, services.ErrLDAPAccountNotLinked) {
+			return response.Forbidden(c, "err.ldap_account_not_linked")
+		}
+		if errors.Is(err, services.ErrLDAPUnavailable) {
+			return response.InternalError(c, "err.ldap_unavailable")
+		}
 		return response.InternalError(c, "err.failed_to_login")
 	}
 
 	return response.OK(c, result)
 }
 
+// LinkLDAP godoc
+//
+//	@Summary		Link a directory account
+//	@Description	Link the LDAP directory account of the login to the current user. The directory password confirms the link; afterwards LDAP logins sign in as the current user. A directory account whose email matches an existing user is never linked without this step.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		dto.LinkLDAPRequest	true	"Directory login and password"
+//	@Success		204
+//	@Failure		400		{object}	response.APIResponse
+//	@Failure		403		{object}	response.APIResponse
+//	@Failure		409		{object}	response.APIResponse
+//	@Failure		422		{object}	response.APIResponse
+//	@Failure		500		{object}	response.APIResponse
+//	@Router			/settings/ldap/link [post]
+func (h *AuthHandler) LinkLDAP(c echo.Context) error {
+	var req dto.LinkLDAPRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if err := validateRequest(req); err != nil {
+		return response.ValidationError(c, map[string]string{"validation": err.Error()})
+	}
+	if err := h.authService.LinkLDAP(middleware.GetUserID(c), req); err != nil {
+		switch {
+		case errors.Is(err, services.ErrLDAPDisabled):
+			return response.Forbidden(c, "err.ldap_disabled")
+		case errors.Is(err, services.ErrInvalidCredentials):
+			return response.BadRequest(c, "err.invalid_email_or_password", nil)
+		case errors.Is(err, services.ErrLDAPAlreadyLinked):
+			return response.Conflict(c, "err.ldap_already_linked")
+		case errors.Is(err, services.ErrLDAPUnavailable):
+			return response.InternalError(c, "err.ldap_unavailable")
+		}
+		return response.InternalError(c, "err.failed_to_link_ldap")
+	}
+	return response.NoContent(c)
+}
+
 // Refresh godoc
 //
 //	@Summary		Refresh JWT token
-//	@Description	Obtain a new JWT token using the current valid token
+//	@Description	Deprecated: use /auth/refresh-token. Trades a still valid token issued before sessions existed for a session; the response includes its refresh token. Tokens of a session are refused with 401 and must be renewed with their refresh token.
 //	@Tags			auth
 //	@Produce		json
 //	@Security		BearerAuth
 //	@Success		200	{object}	response.APIResponse{data=dto.AuthResponse}
 //	@Failure		401	{object}	response.APIResponse
+//	@Failure		403	{object}	response.APIResponse
 //	@Failure		500	{object}	response.APIResponse
+//	@Deprecated
 //	@Router			/auth/refresh [post]
 func (h *AuthHandler) Refresh(c echo.Context) error {
 	claims := middleware.GetClaims(c)
@@ -117,6 +173,56 @@ func (h *AuthHandler) Refresh(c echo.Context) error {
 
 	result, err := h.authService.RefreshToken(claims)
 	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			return response.Unauthorized(c, "err.user_not_found")
+		}
+		if errors.Is(err, services.ErrUserDisabled) {
+			return response.Forbidden(c, "err.user_account_disabled")
+		}
+		if errors.Is(err, services.ErrRefreshTokenRequired) {
+			return response.Unauthorized(c, "err.refresh_token_required")
+		}
+		return response.InternalError(c, "err.failed_to_refresh_token")
+	}
+
+	return response.OK(c, result)
+}
+
+// RefreshSession godoc
+//
+//	@Summary		Redeem a refresh token
+//	@Description	Exchange a single-use refresh token for a new access token and the next refresh token. Presenting an already used refresh token revokes its session.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		dto.RefreshSessionRequest	true	"Refresh token"
+//	@Success		200		{object}	response.APIResponse{data=dto.AuthResponse}
+//	@Failure		400		{object}	response.APIResponse
+//	@Failure		401		{object}	response.APIResponse
+//	@Failure		403		{object}	response.APIResponse
+//	@Failure		422		{object}	response.APIResponse
+//	@Failure		500		{object}	response.APIResponse
+//	@Router			/auth/refresh-token [post]
+func (h *AuthHandler) RefreshSession(c echo.Context) error {
+	var req dto.RefreshSessionRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if err := validateRequest(req); err != nil {
+		return response.ValidationError(c, map[string]string{"validation": err.Error()})
+	}
+
+	result, err := h.authService.RefreshSession(req.RefreshToken, services.SessionClient{
+		UserAgent: c.Request().UserAgent(),
+		IPAddress: c.RealIP(),
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			return response.Unauthorized(c, "err.invalid_refresh_token")
+		}
+		if errors.Is(err, services.ErrRefreshTokenReused) {
+			return response.Unauthorized(c, "err.refresh_token_reused")
+		}
 		if errors.Is(err, services.ErrUserNotFound) {
 			return response.Unauthorized(c, "err.user_not_found")
 		}
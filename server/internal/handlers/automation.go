@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+type AutomationHandler struct {
+	automationService *services.AutomationService
+}
+
+func NewAutomationHandler(automationService *services.AutomationService) *AutomationHandler {
+	return &AutomationHandler{automationService: automationService}
+}
+
+// List godoc
+//
+//	@Summary		List automation rules
+//	@Description	Return the automation rules of a vault
+//	@Tags			automation
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Success		200			{object}	response.APIResponse{data=[]dto.AutomationRuleResponse}
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/automation-rules [get]
+func (h *AutomationHandler) List(c echo.Context) error {
+	rules, err := h.automationService.List(c.Param("vault_id"))
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_list_automation_rules")
+	}
+	return response.OK(c, rules)
+}
+
+// Get godoc
+//
+//	@Summary		Get an automation rule
+//	@Description	Return a single automation rule by ID
+//	@Tags			automation
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			id			path		integer	true	"Rule ID"
+//	@Success		200			{object}	response.APIResponse{data=dto.AutomationRuleResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/automation-rules/{id} [get]
+func (h *AutomationHandler) Get(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_automation_rule_id", nil)
+	}
+	rule, err := h.automationService.Get(uint(id), c.Param("vault_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrAutomationRuleNotFound) {
+			return response.NotFound(c, "err.automation_rule_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_get_automation_rule")
+	}
+	return response.OK(c, rule)
+}
+
+// Create godoc
+//
+//	@Summary		Create an automation rule
+//	@Description	Create a rule that runs its actions for a contact when a feed action of the vault matches the trigger and every condition holds. Actions are create_task, create_reminder, add_label, add_to_group, send_notification and call_webhook.
+//	@Tags			automation
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string								true	"Vault ID"
+//	@Param			request		body		dto.CreateAutomationRuleRequest	true	"Create automation rule request"
+//	@Success		201			{object}	response.APIResponse{data=dto.AutomationRuleResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		422			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/automation-rules [post]
+func (h *AutomationHandler) Create(c echo.Context) error {
+	var req dto.CreateAutomationRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if err := validateRequest(req); err != nil {
+		return response.ValidationError(c, map[string]string{"validation": err.Error()})
+	}
+	rule, err := h.automationService.Create(c.Param("vault_id"), middleware.GetUserID(c), req)
+	if err != nil {
+		return automationRuleError(c, err, "err.failed_to_create_automation_rule")
+	}
+	return response.Created(c, rule)
+}
+
+// Update godoc
+//
+//	@Summary		Update an automation rule
+//	@Description	Replace the definition of an automation rule
+//	@Tags			automation
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string								true	"Vault ID"
+//	@Param			id			path		integer								true	"Rule ID"
+//	@Param			request		body		dto.UpdateAutomationRuleRequest	true	"Update automation rule request"
+//	@Success		200			{object}	response.APIResponse{data=dto.AutomationRuleResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		422			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/automation-rules/{id} [put]
+func (h *AutomationHandler) Update(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_automation_rule_id", nil)
+	}
+	var req dto.UpdateAutomationRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if err := validateRequest(req); err != nil {
+		return response.ValidationError(c, map[string]string{"validation": err.Error()})
+	}
+	rule, err := h.automationService.Update(uint(id), c.Param("vault_id"), req)
+	if err != nil {
+		return automationRuleError(c, err, "err.failed_to_update_automation_rule")
+	}
+	return response.OK(c, rule)
+}
+
+// Delete godoc
+//
+//	@Summary		Delete an automation rule
+//	@Description	Delete an automation rule and its log
+//	@Tags			automation
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path	string	true	"Vault ID"
+//	@Param			id			path	integer	true	"Rule ID"
+//	@Success		204			"No Content"
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/automation-rules/{id} [delete]
+func (h *AutomationHandler) Delete(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_automation_rule_id", nil)
+	}
+	if err := h.automationService.Delete(uint(id), c.Param("vault_id")); err != nil {
+		if errors.Is(err, services.ErrAutomationRuleNotFound) {
+			return response.NotFound(c, "err.automation_rule_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_delete_automation_rule")
+	}
+	return response.NoContent(c)
+}
+
+// ListExecutions godoc
+//
+//	@Summary		List automation executions
+//	@Description	Return the automation log of a vault, newest first. Loops and chains that grow too long show up as skipped executions.
+//	@Tags			automation
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			rule_id		query		integer	false	"Only list the executions of this rule"
+//	@Param			page		query		integer	false	"Page number"
+//	@Param			per_page	query		integer	false	"Items per page (default 20, max 100)"
+//	@Success		200			{object}	response.APIResponse{data=[]dto.AutomationExecutionResponse}
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/automation-rules/executions [get]
+func (h *AutomationHandler) ListExecutions(c echo.Context) error {
+	ruleID, _ := strconv.ParseUint(c.QueryParam("rule_id"), 10, 64)
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	perPage, _ := strconv.Atoi(c.QueryParam("per_page"))
+	executions, meta, err := h.automationService.ListExecutions(c.Param("vault_id"), uint(ruleID), page, perPage)
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_list_automation_executions")
+	}
+	return response.Paginated(c, executions, meta)
+}
+
+func automationRuleError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrAutomationRuleNotFound):
+		return response.NotFound(c, "err.automation_rule_not_found")
+	case errors.Is(err, services.ErrInvalidAutomationTrigger):
+		return response.BadRequest(c, "err.invalid_automation_trigger", nil)
+	case errors.Is(err, services.ErrInvalidAutomationCondition):
+		return response.BadRequest(c, "err.invalid_automation_condition", nil)
+	case errors.Is(err, services.ErrInvalidAutomationAction), errors.Is(err, services.ErrAutomationActionsRequired),
+		errors.Is(err, services.ErrLabelNotFound), errors.Is(err, services.ErrGroupNotFound):
+		return response.BadRequest(c, "err.invalid_automation_action", nil)
+	case errors.Is(err, services.ErrAutomationWebhookBlocked):
+		return response.BadRequest(c, "err.automation_webhook_blocked", nil)
+	case errors.Is(err, services.ErrTooManyAutomationRules):
+		return response.BadRequest(c, "err.too_many_automation_rules", nil)
+	}
+	return response.InternalError(c, fallback)
+}
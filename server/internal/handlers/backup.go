@@ -14,10 +14,11 @@ var _ dto.BackupConfigResponse
 
 type BackupHandler struct {
 	backupService *services.BackupService
+	jobService    *services.JobService
 }
 
-func NewBackupHandler(svc *services.BackupService) *BackupHandler {
-	return &BackupHandler{backupService: svc}
+func NewBackupHandler(svc *services.BackupService, jobService *services.JobService) *BackupHandler {
+	return &BackupHandler{backupService: svc, jobService: jobService}
 }
 
 // List godoc
@@ -135,12 +136,14 @@ func (h *BackupHandler) Delete(c echo.Context) error {
 // Restore godoc
 //
 //	@Summary		Restore backup
-//	@Description	Restore from a backup file
+//	@Description	Restore from a backup file. With async=true the restore runs as a background job and the job is returned.
 //	@Tags			admin
 //	@Produce		json
 //	@Security		BearerAuth
 //	@Param			filename	path	string	true	"Backup filename"
+//	@Param			async		query	boolean	false	"Run as a background job"
 //	@Success		200	{object}	response.APIResponse
+//	@Success		202	{object}	response.APIResponse{data=dto.JobResponse}
 //	@Failure		400	{object}	response.APIResponse
 //	@Failure		401	{object}	response.APIResponse
 //	@Failure		403	{object}	response.APIResponse
@@ -149,15 +152,28 @@ func (h *BackupHandler) Delete(c echo.Context) error {
 //	@Router			/admin/backups/{filename}/restore [post]
 func (h *BackupHandler) Restore(c echo.Context) error {
 	filename := c.Param("filename")
-	err := h.backupService.Restore(filename)
+	async, err := runAsync(c)
 	if err != nil {
-		if errors.Is(err, services.ErrBackupNotFound) {
-			return response.NotFound(c, "err.backup_not_found")
-		}
-		if errors.Is(err, services.ErrBackupInvalidFilename) {
-			return response.BadRequest(c, "err.invalid_backup_filename", nil)
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if async {
+		if _, err := h.backupService.GetFilePath(filename); err != nil {
+			return backupRestoreError(c, err)
 		}
-		return response.InternalError(c, "err.failed_to_restore_backup")
+		return enqueueJob(c, h.jobService, nil, services.JobTypeBackupRestore, services.BackupRestoreJobParams{Filename: filename}, nil)
+	}
+	if err := h.backupService.Restore(filename); err != nil {
+		return backupRestoreError(c, err)
 	}
 	return response.OK(c, map[string]string{"status": "restored"})
 }
+
+func backupRestoreError(c echo.Context, err error) error {
+	if errors.Is(err, services.ErrBackupNotFound) {
+		return response.NotFound(c, "err.backup_not_found")
+	}
+	if errors.Is(err, services.ErrBackupInvalidFilename) {
+		return response.BadRequest(c, "err.invalid_backup_filename", nil)
+	}
+	return response.InternalError(c, "err.failed_to_restore_backup")
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/i18n"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+const maxBatchOperations = 100
+
+var (
+	batchRefPattern         = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	batchPlaceholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)((?:\.[A-Za-z0-9_-]+)*)\s*\}\}`)
+
+	errBatchAborted          = errors.New("batch aborted")
+	errInvalidBatchReference = errors.New("invalid batch reference")
+)
+
+// batchRoute is an API route that a batch can run. handler builds the
+// route's handler on the services of the batch's transaction; permission is
+// the vault access the route needs, as for a direct request.
+type batchRoute struct {
+	method     string
+	path       string
+	permission int
+	handler    func(b *services.BatchTx) echo.HandlerFunc
+}
+
+var batchRoutes = []batchRoute{
+	{http.MethodPost, "/vaults/:vault_id/contacts", models.PermissionEditor, func(b *services.BatchTx) echo.HandlerFunc {
+		return NewContactHandler(b.Contacts()).Create
+	}},
+	{http.MethodPut, "/vaults/:vault_id/contacts/:id", models.PermissionEditor, func(b *services.BatchTx) echo.HandlerFunc {
+		return NewContactHandler(b.Contacts()).Update
+	}},
+	{http.MethodPost, "/vaults/:vault_id/contacts/:contact_id/contactInformation", models.PermissionEditor, func(b *services.BatchTx) echo.HandlerFunc {
+		return NewContactInformationHandler(b.ContactInformation()).Create
+	}},
+	{http.MethodPut, "/vaults/:vault_id/contacts/:contact_id/contactInformation/:id", models.PermissionEditor, func(b *services.BatchTx) echo.HandlerFunc {
+		return NewContactInformationHandler(b.ContactInformation()).Update
+	}},
+	{http.MethodPost, "/vaults/:vault_id/contacts/:contact_id/labels", models.PermissionEditor, func(b *services.BatchTx) echo.HandlerFunc {
+		return NewContactLabelHandler(b.ContactLabels()).Add
+	}},
+	{http.MethodPost, "/vaults/:vault_id/settings/labels", models.PermissionManager, func(b *services.BatchTx) echo.HandlerFunc {
+		return (&VaultSettingsHandler{labelService: b.Labels()}).CreateLabel
+	}},
+	{http.MethodPost, "/vaults/:vault_id/contacts/:contact_id/notes", models.PermissionEditor, func(b *services.BatchTx) echo.HandlerFunc {
+		return NewNoteHandler(b.Notes()).Create
+	}},
+	{http.MethodPost, "/vaults/:vault_id/contacts/:contact_id/reminders", models.PermissionEditor, func(b *services.BatchTx) echo.HandlerFunc {
+		return NewReminderHandler(b.Reminders()).Create
+	}},
+	{http.MethodPost, "/vaults/:vault_id/contacts/:contact_id/dates", models.PermissionEditor, func(b *services.BatchTx) echo.HandlerFunc {
+		return NewImportantDateHandler(b.ImportantDates()).Create
+	}},
+	{http.MethodPost, "/vaults/:vault_id/contacts/:contact_id/tasks", models.PermissionEditor, func(b *services.BatchTx) echo.HandlerFunc {
+		return NewTaskHandler(b.Tasks()).Create
+	}},
+}
+
+// batchContextKeys are the values the authentication and locale middleware
+// set on the batch request that its operations' handlers read.
+var batchContextKeys = []string{"user_id", "account_id", "email", "is_admin", "is_instance_admin", "email_verified", "locale"}
+
+// matchBatchRoute returns the route of method and path with the values of
+// its parameters. A placeholder matches any parameter.
+func matchBatchRoute(method, path string) (*batchRoute, []string, bool) {
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	for i := range batchRoutes {
+		route := &batchRoutes[i]
+		pattern := strings.Split(route.path, "/")
+		if route.method != method || len(pattern) != len(segments) {
+			continue
+		}
+		var values []string
+		matched := true
+		for j, part := range pattern {
+			if strings.HasPrefix(part, ":") && segments[j] != "" {
+				values = append(values, segments[j])
+			} else if part != segments[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return route, values, true
+		}
+	}
+	return nil, nil, false
+}
+
+type BatchHandler struct {
+	batchService *services.BatchService
+}
+
+func NewBatchHandler(batchService *services.BatchService) *BatchHandler {
+	return &BatchHandler{batchService: batchService}
+}
+
+// Execute godoc
+//
+//	@Summary		Run a batch of API requests
+//	@Description	Run up to 100 write requests in order and in one database transaction. Paths are relative to /api; a batch can create and update contacts and their contact information (emails, phone numbers and other channels), create vault labels, add labels to contacts and create notes, reminders, important dates and tasks. A {{ref.field}} placeholder in a path or in a string of a body resolves to a field of the data returned by the earlier operation with that ref. The first operation that fails with a status of 400 or above stops the batch and rolls back every operation. Automation rules, search indexing and DAV pushes run once the batch has committed.
+//	@Tags			batch
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		dto.BatchRequest	true	"Operations"
+//	@Success		200		{object}	response.APIResponse{data=dto.BatchResponse}
+//	@Failure		400		{object}	response.APIResponse
+//	@Failure		422		{object}	response.APIResponse
+//	@Failure		500		{object}	response.APIResponse
+//	@Router			/batch [post]
+func (h *BatchHandler) Execute(c echo.Context) error {
+	var req dto.BatchRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if err := validateRequest(req); err != nil {
+		return response.ValidationError(c, map[string]string{"validation": err.Error()})
+	}
+	if len(req.Operations) > maxBatchOperations {
+		return response.BadRequest(c, "err.too_many_batch_operations", nil)
+	}
+	refs := make(map[string]bool, len(req.Operations))
+	for _, op := range req.Operations {
+		if !validBatchOperation(op) || refs[op.Ref] {
+			return response.BadRequest(c, "err.invalid_batch_operation", nil)
+		}
+		if op.Ref != "" {
+			refs[op.Ref] = true
+		}
+	}
+
+	result := dto.BatchResponse{Results: make([]dto.BatchOperationResult, 0, len(req.Operations))}
+	err := h.batchService.Transaction(func(b *services.BatchTx) error {
+		data := make(map[string]interface{}, len(refs))
+		for _, op := range req.Operations {
+			opResult := h.run(c, b, op, data)
+			result.Results = append(result.Results, opResult)
+			if opResult.Status >= http.StatusBadRequest {
+				return errBatchAborted
+			}
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		result.Committed = true
+	case errors.Is(err, errBatchAborted):
+	default:
+		return response.InternalError(c, "err.failed_to_run_batch")
+	}
+	return response.OK(c, result)
+}
+
+func validBatchOperation(op dto.BatchOperation) bool {
+	if op.Ref != "" && !batchRefPattern.MatchString(op.Ref) {
+		return false
+	}
+	path, _, _ := strings.Cut(op.Path, "?")
+	_, _, ok := matchBatchRoute(op.Method, path)
+	return ok
+}
+
+// run serves one operation with the handler of its route, so it gets the
+// same permission checks, validation and response as a direct request.
+func (h *BatchHandler) run(c echo.Context, b *services.BatchTx, op dto.BatchOperation, data map[string]interface{}) dto.BatchOperationResult {
+	result := dto.BatchOperationResult{Ref: op.Ref}
+	path, err := expandBatchPlaceholders(op.Path, data, url.PathEscape)
+	body := op.Body
+	if err == nil {
+		body, err = resolveBatchBody(op.Body, data)
+	}
+	var route *batchRoute
+	var params []string
+	if err == nil {
+		var ok bool
+		route, params, ok = matchBatchRoute(op.Method, strings.SplitN(path, "?", 2)[0])
+		if !ok {
+			err = errInvalidBatchReference
+		}
+	}
+	for i := 0; err == nil && i < len(params); i++ {
+		params[i], err = url.PathUnescape(params[i])
+	}
+	var sub *http.Request
+	if err == nil {
+		var reader io.Reader
+		if len(body) > 0 {
+			reader = bytes.NewReader(body)
+		}
+		sub, err = http.NewRequestWithContext(c.Request().Context(), op.Method, "/api"+path, reader)
+	}
+	if err != nil {
+		result.Status = http.StatusBadRequest
+		result.Body, _ = json.Marshal(response.APIResponse{Error: &response.APIError{
+			Code:    "BAD_REQUEST",
+			Message: i18n.T(middleware.GetLocale(c), "err.invalid_batch_reference"),
+		}})
+		return result
+	}
+	if len(body) > 0 {
+		sub.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+
+	rec := httptest.NewRecorder()
+	subCtx := c.Echo().NewContext(sub, rec)
+	subCtx.SetPath("/api" + route.path)
+	var names []string
+	for _, part := range strings.Split(route.path, "/") {
+		if strings.HasPrefix(part, ":") {
+			names = append(names, part[1:])
+		}
+	}
+	subCtx.SetParamNames(names...)
+	subCtx.SetParamValues(params...)
+	for _, key := range batchContextKeys {
+		subCtx.Set(key, c.Get(key))
+	}
+	handler := VaultPermissionMiddleware(b.Vaults(), route.permission)(route.handler(b))
+	if err := handler(subCtx); err != nil {
+		c.Echo().HTTPErrorHandler(err, subCtx)
+	}
+
+	result.Status = rec.Code
+	if json.Valid(rec.Body.Bytes()) {
+		result.Body = rec.Body.Bytes()
+	}
+	if op.Ref != "" && result.Status < http.StatusBadRequest {
+		var envelope struct {
+			Data interface{} `json:"data"`
+		}
+		decoder := json.NewDecoder(bytes.NewReader(rec.Body.Bytes()))
+		decoder.UseNumber()
+		if decoder.Decode(&envelope) == nil {
+			data[op.Ref] = envelope.Data
+		}
+	}
+	return result
+}
+
+func resolveBatchBody(body json.RawMessage, data map[string]interface{}) (json.RawMessage, error) {
+	if !bytes.Contains(body, []byte("{{")) {
+		return body, nil
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	resolved, err := resolveBatchValue(value, data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resolved)
+}
+
+// resolveBatchValue replaces the placeholders in the strings of a decoded
+// body. A string that is a single placeholder takes the referenced value
+// with its JSON type, so IDs stay numbers.
+func resolveBatchValue(value interface{}, data map[string]interface{}) (interface{}, error) {
+	var err error
+	switch v := value.(type) {
+	case string:
+		if m := batchPlaceholderPattern.FindStringSubmatch(v); m != nil && m[0] == v {
+			resolved, ok := lookupBatchReference(data, m[1], m[2])
+			if !ok {
+				return nil, errInvalidBatchReference
+			}
+			return resolved, nil
+		}
+		return expandBatchPlaceholders(v, data, func(s string) string { return s })
+	case map[string]interface{}:
+		for key, item := range v {
+			if v[key], err = resolveBatchValue(item, data); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if v[i], err = resolveBatchValue(item, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return value, nil
+}
+
+// expandBatchPlaceholders replaces each placeholder in s with the escaped
+// text of the scalar it references.
+func expandBatchPlaceholders(s string, data map[string]interface{}, escape func(string) string) (string, error) {
+	var err error
+	expanded := batchPlaceholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		m := batchPlaceholderPattern.FindStringSubmatch(match)
+		value, ok := lookupBatchReference(data, m[1], m[2])
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		case json.Number:
+			text = v.String()
+		case bool:
+			text = strconv.FormatBool(v)
+		default:
+			ok = false
+		}
+		if !ok {
+			err = errInvalidBatchReference
+			return match
+		}
+		return escape(text)
+	})
+	return expanded, err
+}
+
+func lookupBatchReference(data map[string]interface{}, ref, fieldPath string) (interface{}, bool) {
+	value, ok := data[ref]
+	if !ok {
+		return nil, false
+	}
+	for _, field := range strings.Split(strings.TrimPrefix(fieldPath, "."), ".") {
+		if field == "" {
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value, ok = v[field]
+		case []interface{}:
+			i, err := strconv.Atoi(field)
+			ok = err == nil && i >= 0 && i < len(v)
+			if ok {
+				value = v[i]
+			}
+		default:
+			ok = false
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
 	"github.com/naiba/bonds/internal/services"
 	"github.com/naiba/bonds/pkg/response"
 )
@@ -75,7 +76,7 @@ func (h *ContactInformationHandler) Create(c echo.Context) error {
 		return response.ValidationError(c, map[string]string{"validation": err.Error()})
 	}
 
-	item, err := h.contactInformationService.Create(contactID, vaultID, req)
+	item, err := h.contactInformationService.Create(contactID, vaultID, middleware.GetUserID(c), req)
 	if err != nil {
 		if errors.Is(err, services.ErrContactNotFound) {
 			return response.NotFound(c, "err.contact_not_found")
@@ -120,7 +121,7 @@ func (h *ContactInformationHandler) Update(c echo.Context) error {
 		return response.ValidationError(c, map[string]string{"validation": err.Error()})
 	}
 
-	item, err := h.contactInformationService.Update(uint(id), contactID, vaultID, req)
+	item, err := h.contactInformationService.Update(uint(id), contactID, vaultID, middleware.GetUserID(c), req)
 	if err != nil {
 		if errors.Is(err, services.ErrContactNotFound) {
 			return response.NotFound(c, "err.contact_not_found")
@@ -136,7 +137,7 @@ func (h *ContactInformationHandler) Update(c echo.Context) error {
 // FindByIdentity godoc
 //
 //	@Summary		Find contact information by identity value
-//	@Description	Search a vault for contact information rows matching a given identity value (e.g. an email or phone number). The match is case-insensitive. Optionally restrict the search to a single ContactInformationType via the type_id query parameter.
+//	@Description	Search a vault for contact information rows matching a given identity value (e.g. an email or phone number). The match is case-insensitive, and phone numbers also match when they are the same number in E.164 form, such as (555) 010-2000 and +1 555 010 2000 for a user whose phone region is US. Optionally restrict the search to a single ContactInformationType via the type_id query parameter.
 //	@Tags			contact-information
 //	@Produce		json
 //	@Security		BearerAuth
@@ -164,7 +165,7 @@ func (h *ContactInformationHandler) FindByIdentity(c echo.Context) error {
 		typeID = uint(parsed)
 	}
 
-	matches, err := h.contactInformationService.FindByIdentity(vaultID, data, typeID)
+	matches, err := h.contactInformationService.FindByIdentity(vaultID, middleware.GetUserID(c), data, typeID)
 	if err != nil {
 		return response.InternalError(c, "err.failed_to_find_contact_information")
 	}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"strconv"
 	"strings"
 
 	"github.com/labstack/echo/v4"
@@ -15,73 +17,146 @@ import (
 var _ dto.CSVImportResponse
 
 type CSVImportHandler struct {
-	svc *services.CSVImportService
+	svc        *services.CSVImportService
+	jobService *services.JobService
 }
 
-func NewCSVImportHandler(svc *services.CSVImportService) *CSVImportHandler {
-	return &CSVImportHandler{svc: svc}
+func NewCSVImportHandler(svc *services.CSVImportService, jobService *services.JobService) *CSVImportHandler {
+	return &CSVImportHandler{svc: svc, jobService: jobService}
 }
 
-// Import godoc
+// Preview godoc
 //
-//	@Summary		Import contacts from a CSV file
-//	@Description	Import contacts from a CSV file with a user-defined column mapping
+//	@Summary		Preview a CSV file
+//	@Description	Read the header of a CSV file and propose a column mapping. Exports of Google Contacts, Outlook, Apple Numbers and LinkedIn Connections are recognised and reported as the preset.
 //	@Tags			Vault Settings
 //	@Accept			multipart/form-data
 //	@Produce		json
 //	@Security		BearerAuth
 //	@Param			vault_id	path		string	true	"Vault ID"
 //	@Param			file		formData	file	true	"CSV file"
-//	@Param			mapping		formData	string	true	"JSON column mapping"
-//	@Success		200			{object}	response.APIResponse{data=dto.CSVImportResponse}
+//	@Success		200			{object}	response.APIResponse{data=dto.CSVImportPreviewResponse}
 //	@Failure		400			{object}	response.APIResponse
 //	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/settings/import/csv/preview [post]
+func (h *CSVImportHandler) Preview(c echo.Context) error {
+	data, errKey := readCSVUpload(c)
+	if errKey != "" {
+		return csvUploadError(c, errKey)
+	}
+	result, err := h.svc.Preview(data)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_csv_file", nil)
+	}
+	return response.OK(c, result)
+}
+
+// Import godoc
+//
+//	@Summary		Import contacts from a CSV file
+//	@Description	Import contacts from a CSV file with a user-defined column mapping. Rows that share an email address or phone number with an existing contact are duplicates; on_duplicate decides whether they create a new contact (the default), are skipped or update the match. A dry run writes nothing and reports what each row would do. With async=true the import runs as a background job and the job is returned; row errors become the job's errors.
+//	@Tags			Vault Settings
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id		path		string	true	"Vault ID"
+//	@Param			file			formData	file	true	"CSV file"
+//	@Param			mapping			formData	string	true	"JSON column mapping"
+//	@Param			dry_run			formData	boolean	false	"Report without importing"
+//	@Param			on_duplicate	formData	string	false	"create, skip or update"
+//	@Param			async			formData	boolean	false	"Run as a background job"
+//	@Success		200				{object}	response.APIResponse{data=dto.CSVImportResponse}
+//	@Success		202				{object}	response.APIResponse{data=dto.JobResponse}
+//	@Failure		400				{object}	response.APIResponse
+//	@Failure		500				{object}	response.APIResponse
 //	@Router			/vaults/{vault_id}/settings/import/csv [post]
 func (h *CSVImportHandler) Import(c echo.Context) error {
 	vaultID := c.Param("vault_id")
 	userID := middleware.GetUserID(c)
 
+	data, errKey := readCSVUpload(c)
+	if errKey != "" {
+		return csvUploadError(c, errKey)
+	}
+
+	mappingJSON := c.FormValue("mapping")
+	var mapping dto.CSVColumnMapping
+	if mappingJSON != "" {
+		if err := json.Unmarshal([]byte(mappingJSON), &mapping); err != nil {
+			return response.BadRequest(c, "err.invalid_csv_mapping", nil)
+		}
+	}
+
+	opts := dto.CSVImportOptions{OnDuplicate: c.FormValue("on_duplicate")}
+	if v := c.FormValue("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return response.BadRequest(c, "err.invalid_request_body", nil)
+		}
+		opts.DryRun = dryRun
+	}
+	async, err := runAsync(c)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if async {
+		switch opts.OnDuplicate {
+		case "", services.CSVDuplicateCreate, services.CSVDuplicateSkip, services.CSVDuplicateUpdate:
+		default:
+			return response.BadRequest(c, "err.invalid_csv_duplicate_mode", nil)
+		}
+		params := services.CSVImportJobParams{Mapping: mapping, DryRun: opts.DryRun, OnDuplicate: opts.OnDuplicate}
+		return enqueueJob(c, h.jobService, &vaultID, services.JobTypeCSVImport, params, data)
+	}
+
+	result, err := h.svc.Import(vaultID, userID, data, mapping, opts)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCSVDuplicateMode) {
+			return response.BadRequest(c, "err.invalid_csv_duplicate_mode", nil)
+		}
+		return response.InternalError(c, "err.failed_to_import_csv")
+	}
+
+	return response.OK(c, result)
+}
+
+// readCSVUpload reads the uploaded file, or returns the error key to
+// respond with.
+func readCSVUpload(c echo.Context) ([]byte, string) {
 	file, err := c.FormFile("file")
 	if err != nil {
-		return response.BadRequest(c, "err.file_required", nil)
+		return nil, "err.file_required"
 	}
 
 	// Server-side size guard (accept header is only a browser hint).
 	if file.Size > services.MaxCSVFileSize {
-		return response.BadRequest(c, "err.file_too_large", nil)
+		return nil, "err.file_too_large"
 	}
 
 	// Server-side type guard: reject obvious non-CSV uploads.
 	ct := file.Header.Get("Content-Type")
 	if ct != "" && !isCSVContentType(ct) {
-		return response.BadRequest(c, "err.invalid_file_type", nil)
+		return nil, "err.invalid_file_type"
 	}
 
 	src, err := file.Open()
 	if err != nil {
-		return response.InternalError(c, "err.failed_to_read_file")
+		return nil, "err.failed_to_read_file"
 	}
 	defer src.Close()
 
 	var buf bytes.Buffer
 	if _, err := buf.ReadFrom(src); err != nil {
-		return response.InternalError(c, "err.failed_to_read_file")
-	}
-
-	mappingJSON := c.FormValue("mapping")
-	var mapping dto.CSVColumnMapping
-	if mappingJSON != "" {
-		if err := json.Unmarshal([]byte(mappingJSON), &mapping); err != nil {
-			return response.BadRequest(c, "err.invalid_csv_mapping", nil)
-		}
+		return nil, "err.failed_to_read_file"
 	}
+	return buf.Bytes(), ""
+}
 
-	result, err := h.svc.Import(vaultID, userID, buf.Bytes(), mapping)
-	if err != nil {
-		return response.InternalError(c, "err.failed_to_import_csv")
+func csvUploadError(c echo.Context, key string) error {
+	if key == "err.failed_to_read_file" {
+		return response.InternalError(c, key)
 	}
-
-	return response.OK(c, result)
+	return response.BadRequest(c, key, nil)
 }
 
 // isCSVContentType returns true for content types that represent CSV data.
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+type EmailInboxHandler struct {
+	emailIngestService *services.EmailIngestService
+}
+
+func NewEmailInboxHandler(emailIngestService *services.EmailIngestService) *EmailInboxHandler {
+	return &EmailInboxHandler{emailIngestService: emailIngestService}
+}
+
+// Get godoc
+//
+//	@Summary		Get my email inbox
+//	@Description	Return the current user's inbox of the vault. Mail sent, BCCed or forwarded to its address is logged against the contacts whose email addresses appear in it.
+//	@Tags			email-inbox
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Success		200			{object}	response.APIResponse{data=dto.EmailInboxResponse}
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/email-inbox [get]
+func (h *EmailInboxHandler) Get(c echo.Context) error {
+	inbox, err := h.emailIngestService.GetInbox(c.Param("vault_id"), middleware.GetUserID(c))
+	if err != nil {
+		return emailInboxError(c, err, "err.failed_to_get_email_inbox")
+	}
+	return response.OK(c, inbox)
+}
+
+// Update godoc
+//
+//	@Summary		Set up my email inbox
+//	@Description	Create the current user's inbox of the vault, or change how it logs mail: activity logs one email activity with every matched contact, note adds a note to each of them.
+//	@Tags			email-inbox
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string							true	"Vault ID"
+//	@Param			request		body		dto.UpdateEmailInboxRequest	true	"Inbox settings"
+//	@Success		200			{object}	response.APIResponse{data=dto.EmailInboxResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		422			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/email-inbox [put]
+func (h *EmailInboxHandler) Update(c echo.Context) error {
+	var req dto.UpdateEmailInboxRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if err := validateRequest(req); err != nil {
+		return response.ValidationError(c, map[string]string{"validation": err.Error()})
+	}
+	inbox, err := h.emailIngestService.UpdateInbox(c.Param("vault_id"), middleware.GetUserID(c), req)
+	if err != nil {
+		return emailInboxError(c, err, "err.failed_to_update_email_inbox")
+	}
+	return response.OK(c, inbox)
+}
+
+// Rotate godoc
+//
+//	@Summary		Rotate my email inbox address
+//	@Description	Give the inbox a new address. Mail to the old address is refused from then on.
+//	@Tags			email-inbox
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Success		200			{object}	response.APIResponse{data=dto.EmailInboxResponse}
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/email-inbox/rotate [post]
+func (h *EmailInboxHandler) Rotate(c echo.Context) error {
+	inbox, err := h.emailIngestService.RotateInbox(c.Param("vault_id"), middleware.GetUserID(c))
+	if err != nil {
+		return emailInboxError(c, err, "err.failed_to_rotate_email_inbox")
+	}
+	return response.OK(c, inbox)
+}
+
+// Delete godoc
+//
+//	@Summary		Delete my email inbox
+//	@Description	Stop receiving mail for the vault. What the inbox already logged is kept.
+//	@Tags			email-inbox
+//	@Security		BearerAuth
+//	@Param			vault_id	path	string	true	"Vault ID"
+//	@Success		204
+//	@Failure		404	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/email-inbox [delete]
+func (h *EmailInboxHandler) Delete(c echo.Context) error {
+	if err := h.emailIngestService.DeleteInbox(c.Param("vault_id"), middleware.GetUserID(c)); err != nil {
+		return emailInboxError(c, err, "err.failed_to_delete_email_inbox")
+	}
+	return response.NoContent(c)
+}
+
+// ListMessages godoc
+//
+//	@Summary		List messages of my email inbox
+//	@Description	Return the messages the inbox received, newest first. Messages with no known contact are listed as unmatched.
+//	@Tags			email-inbox
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			page		query		integer	false	"Page number"
+//	@Param			per_page	query		integer	false	"Items per page (default 20, max 100)"
+//	@Success		200			{object}	response.APIResponse{data=[]dto.IngestedEmailResponse}
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/email-inbox/messages [get]
+func (h *EmailInboxHandler) ListMessages(c echo.Context) error {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	perPage, _ := strconv.Atoi(c.QueryParam("per_page"))
+	messages, meta, err := h.emailIngestService.ListMessages(c.Param("vault_id"), middleware.GetUserID(c), page, perPage)
+	if err != nil {
+		return emailInboxError(c, err, "err.failed_to_list_inbox_messages")
+	}
+	return response.Paginated(c, messages, meta)
+}
+
+func emailInboxError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrEmailInboxNotFound):
+		return response.NotFound(c, "err.email_inbox_not_found")
+	case errors.Is(err, services.ErrInboundMailNotConfigured):
+		return response.BadRequest(c, "err.inbound_mail_not_configured", nil)
+	}
+	return response.InternalError(c, fallback)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+const gedcomContentType = "text/vnd.familysearch.gedcom; charset=utf-8"
+
+var _ dto.GedcomImportResponse
+
+type GedcomHandler struct {
+	gedcomService *services.GedcomService
+}
+
+func NewGedcomHandler(gedcomService *services.GedcomService) *GedcomHandler {
+	return &GedcomHandler{gedcomService: gedcomService}
+}
+
+// Export godoc
+//
+//	@Summary		Export family tree as GEDCOM
+//	@Description	Export every contact in the vault that has a parent, child or spouse relationship, with their families, as GEDCOM 5.5.1 (default) or 7.0
+//	@Tags			gedcom
+//	@Produce		octet-stream
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			version		query		string	false	"GEDCOM version"	Enums(5.5.1, 7.0)
+//	@Success		200			{file}		file
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/contacts/export/gedcom [get]
+func (h *GedcomHandler) Export(c echo.Context) error {
+	vaultID := c.Param("vault_id")
+
+	data, err := h.gedcomService.Export(vaultID, c.QueryParam("version"))
+	if err != nil {
+		if errors.Is(err, services.ErrGedcomUnsupportedVersion) {
+			return response.BadRequest(c, "err.unsupported_gedcom_version", nil)
+		}
+		return response.InternalError(c, "err.failed_to_export_gedcom")
+	}
+
+	c.Response().Header().Set("Content-Disposition", "attachment; filename=family.ged")
+	return c.Blob(http.StatusOK, gedcomContentType, data)
+}
+
+// Import godoc
+//
+//	@Summary		Import family tree from GEDCOM
+//	@Description	Import individuals, birth/death dates and parent/spouse relationships from a GEDCOM 5.5.1 or 7.0 file, matching existing contacts where possible
+//	@Tags			Vault Settings
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			file		formData	file	true	"GEDCOM file (.ged)"
+//	@Success		200			{object}	response.APIResponse{data=dto.GedcomImportResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/settings/import/gedcom [post]
+func (h *GedcomHandler) Import(c echo.Context) error {
+	vaultID := c.Param("vault_id")
+	userID := middleware.GetUserID(c)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return response.BadRequest(c, "err.file_required", nil)
+	}
+	if file.Size > services.MaxGedcomFileSize {
+		return response.BadRequest(c, "err.file_too_large", nil)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_read_file")
+	}
+	defer src.Close()
+
+	result, err := h.gedcomService.Import(vaultID, userID, src)
+	if err != nil {
+		if errors.Is(err, services.ErrGedcomInvalidData) {
+			return response.BadRequest(c, "err.invalid_gedcom_data", nil)
+		}
+		return response.InternalError(c, "err.failed_to_import_gedcom")
+	}
+
+	return response.OK(c, result)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+type GeocodingHandler struct {
+	svc *services.GeocodingService
+}
+
+func NewGeocodingHandler(svc *services.GeocodingService) *GeocodingHandler {
+	return &GeocodingHandler{svc: svc}
+}
+
+// Backfill godoc
+//
+//	@Summary		Geocode addresses without coordinates
+//	@Description	Queue every address of the vault that has no latitude or longitude for the geocoding worker, which looks them up in the background at the pace the geocoding provider allows.
+//	@Tags			Vault Settings
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Success		202			{object}	response.APIResponse{data=dto.GeocodingBackfillResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/settings/geocode [post]
+func (h *GeocodingHandler) Backfill(c echo.Context) error {
+	vaultID := c.Param("vault_id")
+	result, err := h.svc.Backfill(vaultID)
+	if err != nil {
+		if errors.Is(err, services.ErrGeocodingDisabled) {
+			return response.BadRequest(c, "err.geocoding_disabled", nil)
+		}
+		return response.InternalError(c, "err.failed_to_queue_geocoding")
+	}
+	return response.Accepted(c, result)
+}
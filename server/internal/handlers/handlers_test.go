@@ -1,6 +1,8 @@
 package handlers_test
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -9,11 +11,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/textproto"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/markbates/goth"
@@ -488,28 +492,57 @@ func TestMe_InvalidToken(t *testing.T) {
 	}
 }
 
-func TestRefresh_Success(t *testing.T) {
+func TestRefresh_RefusesSessionAccessToken(t *testing.T) {
 	ts := setupTestServer(t)
 	token, _ := ts.registerTestUser(t, "refresh@example.com")
 
+	// A session access token must not renew itself without its refresh token.
 	rec := ts.doRequest(http.MethodPost, "/api/auth/refresh", "", token)
 
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if resp := parseResponse(t, rec); resp.Error == nil || resp.Error.Message != "Renew this session with its refresh token at /api/auth/refresh-token" {
+		t.Errorf("expected a pointer to the refresh-token endpoint, got %s", rec.Body.String())
+	}
+}
+
+func TestRefresh_StartsSessionForSessionlessToken(t *testing.T) {
+	ts := setupTestServer(t)
+	_, auth := ts.registerTestUser(t, "refresh-legacy@example.com")
+	claims := &middleware.JWTClaims{
+		UserID:    auth.User.ID,
+		AccountID: auth.User.AccountID,
+		Email:     auth.User.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ts.cfg.JWT.Secret))
+	if err != nil {
+		t.Fatalf("sign JWT: %v", err)
+	}
+
+	rec := ts.doRequest(http.MethodGet, "/api/auth/me", "", legacy)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a token without a session to be refused, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodPost, "/api/auth/refresh", "", legacy)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
 	}
-	resp := parseResponse(t, rec)
-	if !resp.Success {
-		t.Fatal("expected success=true")
-	}
-	var data authData
-	if err := json.Unmarshal(resp.Data, &data); err != nil {
+	var data dto.AuthResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &data); err != nil {
 		t.Fatalf("failed to parse auth data: %v", err)
 	}
-	if data.Token == "" {
-		t.Error("expected non-empty refreshed token")
+	if data.Token == "" || data.RefreshToken == "" {
+		t.Fatalf("expected a session with a refresh token, got %+v", data)
 	}
-	if data.User.Email != "refresh@example.com" {
-		t.Errorf("expected email=refresh@example.com, got %s", data.User.Email)
+	rec = ts.doRequest(http.MethodGet, "/api/auth/me", "", data.Token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the new token to work, got %d: %s", rec.Code, rec.Body.String())
 	}
 }
 
@@ -523,6 +556,98 @@ func TestRefresh_WithoutToken(t *testing.T) {
 	}
 }
 
+func TestRefreshToken_RejectsAccessToken(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "refresh-access@example.com")
+
+	rec := ts.doRequest(http.MethodPost, "/api/auth/refresh-token", `{"refresh_token":"`+token+`"}`, "")
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRefreshToken_RotatesAndRevokesOnReuse(t *testing.T) {
+	ts := setupTestServer(t)
+	rec := ts.doRequest(http.MethodPost, "/api/auth/register",
+		`{"first_name":"Test","last_name":"User","email":"rotate@example.com","password":"password123"}`, "")
+	var registered dto.AuthResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &registered); err != nil {
+		t.Fatalf("parse auth data: %v", err)
+	}
+	if registered.RefreshToken == "" {
+		t.Fatal("expected a refresh token on registration")
+	}
+
+	rec = ts.doRequest(http.MethodPost, "/api/auth/refresh-token", `{"refresh_token":"`+registered.RefreshToken+`"}`, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rotated dto.AuthResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &rotated); err != nil {
+		t.Fatalf("parse auth data: %v", err)
+	}
+	if rotated.Token == "" || rotated.RefreshToken == "" || rotated.RefreshToken == registered.RefreshToken {
+		t.Fatalf("expected a new access and refresh token, got %+v", rotated)
+	}
+
+	rec = ts.doRequest(http.MethodPost, "/api/auth/refresh-token", `{"refresh_token":"`+registered.RefreshToken+`"}`, "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 on reuse, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/auth/me", "", rotated.Token)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the session to be revoked after reuse, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSessions_ListRevokeAndLogoutEverywhere(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "sessions@example.com")
+	rec := ts.doRequest(http.MethodPost, "/api/auth/login", `{"email":"sessions@example.com","password":"password123"}`, "")
+	var other authData
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &other); err != nil {
+		t.Fatalf("parse auth data: %v", err)
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/settings/sessions", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var sessions []dto.UserSessionResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &sessions); err != nil {
+		t.Fatalf("parse sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+	var otherID string
+	for _, s := range sessions {
+		if !s.Current {
+			otherID = s.ID
+		}
+	}
+
+	rec = ts.doRequest(http.MethodDelete, "/api/settings/sessions/"+otherID, "", token)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec = ts.doRequest(http.MethodGet, "/api/auth/me", "", other.Token); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked session's token to be rejected, got %d", rec.Code)
+	}
+	if rec = ts.doRequest(http.MethodDelete, "/api/settings/sessions/"+otherID, "", token); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an already revoked session, got %d", rec.Code)
+	}
+
+	rec = ts.doRequest(http.MethodDelete, "/api/settings/sessions", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec = ts.doRequest(http.MethodGet, "/api/auth/me", "", token); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected log out everywhere to end the current session too, got %d", rec.Code)
+	}
+}
+
 func TestVaultList_Empty(t *testing.T) {
 	ts := setupTestServer(t)
 	token, _ := ts.registerTestUser(t, "vlist@example.com")
@@ -919,7 +1044,7 @@ func TestContactStayInTouchFields_CreateUpdateAndCatchUp(t *testing.T) {
 
 	viewer := createSecondUser(t, ts, auth.User.AccountID, "contact-stay-in-touch-viewer@example.com", false)
 	addUserToVault(t, ts, viewer.ID, vault.ID, models.PermissionViewer)
-	viewerToken := generateJWT(viewer.ID, viewer.AccountID, viewer.Email, false, false)
+	viewerToken := ts.generateJWT(viewer.ID, viewer.AccountID, viewer.Email, false, false)
 	rec = ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/contacts/"+created.ID+"/catchUp", "", viewerToken)
 	if rec.Code != http.StatusForbidden {
 		t.Fatalf("viewer mark caught up: expected 403, got %d: %s", rec.Code, rec.Body.String())
@@ -927,7 +1052,7 @@ func TestContactStayInTouchFields_CreateUpdateAndCatchUp(t *testing.T) {
 
 	editor := createSecondUser(t, ts, auth.User.AccountID, "contact-stay-in-touch-editor@example.com", false)
 	addUserToVault(t, ts, editor.ID, vault.ID, models.PermissionEditor)
-	editorToken := generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
+	editorToken := ts.generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
 	rec = ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/contacts/"+created.ID+"/catchUp", "", editorToken)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("mark caught up: expected 200, got %d: %s", rec.Code, rec.Body.String())
@@ -1388,7 +1513,7 @@ func TestDashboardCatchUp_ReturnsDuePrompts(t *testing.T) {
 
 	viewer := createSecondUser(t, ts, auth.User.AccountID, "dashboard-catch-up-viewer@example.com", false)
 	addUserToVault(t, ts, viewer.ID, vault.ID, models.PermissionViewer)
-	viewerToken := generateJWT(viewer.ID, viewer.AccountID, viewer.Email, false, false)
+	viewerToken := ts.generateJWT(viewer.ID, viewer.AccountID, viewer.Email, false, false)
 	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/dashboard/catchUp", "", viewerToken)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("viewer catch-up dashboard: expected 200, got %d: %s", rec.Code, rec.Body.String())
@@ -1581,6 +1706,64 @@ func TestVaultTaskCreateRecordsFeedThroughRegisteredRoutes(t *testing.T) {
 	}
 }
 
+func TestVaultTaskRecurrence_CompletionHistory(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "vault-task-recurrence@example.com")
+	vault := ts.createTestVault(t, token, "Recurring Task Vault")
+	basePath := "/api/vaults/" + vault.ID + "/tasks"
+
+	rec := ts.doRequest(http.MethodPost, basePath, `{"label":"Daily","recurrence_rule":"FREQ=DAILY"}`, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without due date, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodPost, basePath, `{"label":"Daily","due_at":"2026-01-01T08:00:00Z","recurrence_rule":"FREQ=DAILY"}`, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create recurring task: status=%d body=%s", rec.Code, rec.Body.String())
+	}
+	var task struct {
+		ID             uint   `json:"id"`
+		RecurrenceRule string `json:"recurrence_rule"`
+	}
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &task); err != nil {
+		t.Fatalf("unmarshal task: %v", err)
+	}
+	if task.RecurrenceRule != "FREQ=DAILY" {
+		t.Fatalf("recurrence_rule = %q, want FREQ=DAILY", task.RecurrenceRule)
+	}
+
+	rec = ts.doRequest(http.MethodPatch, fmt.Sprintf("%s/%d/status", basePath, task.ID), `{"status":"done"}`, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("complete task: status=%d body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodGet, basePath, "", token)
+	var board []struct {
+		ID    uint      `json:"id"`
+		DueAt time.Time `json:"due_at"`
+	}
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &board); err != nil {
+		t.Fatalf("unmarshal board: %v", err)
+	}
+	if len(board) != 1 || board[0].ID == task.ID || !board[0].DueAt.Equal(time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected only the next occurrence on the board, got %+v", board)
+	}
+
+	rec = ts.doRequest(http.MethodGet, fmt.Sprintf("%s/%d/completions", basePath, board[0].ID), "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list completions: status=%d body=%s", rec.Code, rec.Body.String())
+	}
+	var history []struct {
+		TaskID uint `json:"task_id"`
+	}
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &history); err != nil {
+		t.Fatalf("unmarshal history: %v", err)
+	}
+	if len(history) != 1 || history[0].TaskID != task.ID {
+		t.Fatalf("unexpected completion history: %+v", history)
+	}
+}
+
 func TestTaskList_Success(t *testing.T) {
 	ts := setupTestServer(t)
 	token, _ := ts.registerTestUser(t, "task-list@example.com")
@@ -1676,6 +1859,83 @@ func TestReminderList_Success(t *testing.T) {
 	}
 }
 
+func TestReminderCreate_LeadTimesAndEscalation(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "reminder-lead@example.com")
+	vault := ts.createTestVault(t, token, "Reminder Lead Vault")
+	contact := ts.createTestContact(t, token, vault.ID, "John")
+	basePath := "/api/vaults/" + vault.ID + "/contacts/" + contact.ID + "/reminders"
+
+	rec := ts.doRequest(http.MethodPost, basePath,
+		`{"label":"Birthday","day":15,"month":6,"type":"recurring_year","lead_time_days":[3,14,0],"escalate_after_hours":24}`, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var reminder dto.ReminderResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &reminder); err != nil {
+		t.Fatalf("failed to parse reminder: %v", err)
+	}
+	if len(reminder.LeadTimeDays) != 3 || reminder.LeadTimeDays[0] != 14 || reminder.EscalateAfterHours == nil || *reminder.EscalateAfterHours != 24 {
+		t.Fatalf("unexpected lead times/escalation: %v / %v", reminder.LeadTimeDays, reminder.EscalateAfterHours)
+	}
+
+	rec = ts.doRequest(http.MethodPost, basePath,
+		`{"label":"Bad","day":15,"month":6,"type":"recurring_year","lead_time_days":[400]}`, token)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for an out-of-range lead time, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestReminderAction_ConfirmAndAcknowledge(t *testing.T) {
+	ts := setupTestServer(t)
+	token, auth := ts.registerTestUser(t, "reminder-action@example.com")
+	vault := ts.createTestVault(t, token, "Reminder Action Vault")
+	contact := ts.createTestContact(t, token, vault.ID, "John")
+	rec := ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/contacts/"+contact.ID+"/reminders",
+		`{"label":"Call Mom","day":15,"month":6,"type":"recurring_year"}`, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var reminder dto.ReminderResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &reminder)
+
+	var scheduled models.ContactReminderScheduled
+	if err := ts.db.Where("contact_reminder_id = ?", reminder.ID).First(&scheduled).Error; err != nil {
+		t.Fatalf("expected a scheduled delivery: %v", err)
+	}
+	ts.db.Model(&scheduled).Update("triggered_at", time.Now())
+	link, err := services.NewReminderActionService(ts.db, ts.cfg.JWT.Secret, "").
+		ActionURL(scheduled.ID, auth.User.ID, services.ReminderActionAcknowledge)
+	if err != nil {
+		t.Fatalf("ActionURL failed: %v", err)
+	}
+	path := link[strings.Index(link, "/api/"):]
+
+	rec = ts.doRequest(http.MethodGet, path, "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `<form method="post">`) {
+		t.Fatalf("expected a confirmation page, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var acknowledged int64
+	ts.db.Model(&models.ContactReminderDeliveryState{}).Where("acknowledged_at IS NOT NULL").Count(&acknowledged)
+	if acknowledged != 0 {
+		t.Fatal("expected opening the link not to acknowledge")
+	}
+
+	rec = ts.doRequest(http.MethodPost, path, "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Reminder acknowledged.") {
+		t.Fatalf("expected the acknowledgement page, got %d: %s", rec.Code, rec.Body.String())
+	}
+	ts.db.Model(&models.ContactReminderDeliveryState{}).Where("acknowledged_at IS NOT NULL").Count(&acknowledged)
+	if acknowledged != 1 {
+		t.Fatalf("expected one acknowledged occurrence, got %d", acknowledged)
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/reminders/actions/not-a-token", "", "")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid or has expired") {
+		t.Fatalf("expected an error page for a bad link, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
 // ==================== Calls ====================
 
 func TestCallCreate_Success(t *testing.T) {
@@ -2222,6 +2482,89 @@ func TestAddressCreate_Success(t *testing.T) {
 	}
 }
 
+func TestAddressGeocodeBackfill(t *testing.T) {
+	ts := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Geocoding = config.GeocodingConfig{Provider: "nominatim"}
+	})
+	token, _ := ts.registerTestUser(t, "address-geocode@example.com")
+	vault := ts.createTestVault(t, token, "Geocode Vault")
+	contact := ts.createTestContact(t, token, vault.ID, "John")
+
+	rec := ts.doRequest(http.MethodPost,
+		"/api/vaults/"+vault.ID+"/contacts/"+contact.ID+"/addresses",
+		`{"line_1":"123 Main St","city":"Portland","country":"US"}`, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/settings/geocode", "", token)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result dto.GeocodingBackfillResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &result); err != nil {
+		t.Fatalf("parse backfill response: %v", err)
+	}
+	if result.Queued != 1 || result.Pending != 1 {
+		t.Fatalf("unexpected backfill result: %+v", result)
+	}
+
+	disabled := setupTestServer(t)
+	token, _ = disabled.registerTestUser(t, "address-geocode-off@example.com")
+	vault = disabled.createTestVault(t, token, "No Geocoding Vault")
+	rec = disabled.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/settings/geocode", "", token)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a provider, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAddressesNearbyAndMap(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "addresses-nearby@example.com")
+	vault := ts.createTestVault(t, token, "Nearby Vault")
+	contact := ts.createTestContact(t, token, vault.ID, "John")
+
+	rec := ts.doRequest(http.MethodPost,
+		"/api/vaults/"+vault.ID+"/contacts/"+contact.ID+"/addresses",
+		`{"city":"Lisbon","country":"PT","latitude":38.7223,"longitude":-9.1393}`, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/reports/addresses/nearby?latitude=38.75&longitude=-9.15&radius_km=10", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var nearby dto.NearbyContactsResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &nearby); err != nil {
+		t.Fatalf("parse nearby response: %v", err)
+	}
+	if len(nearby.Contacts) != 1 || nearby.Contacts[0].ContactID != contact.ID || nearby.Contacts[0].City != "Lisbon" {
+		t.Fatalf("unexpected nearby contacts: %+v", nearby.Contacts)
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/reports/map?zoom=5", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var collection dto.GeoJSONFeatureCollection
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &collection); err != nil {
+		t.Fatalf("parse map response: %v", err)
+	}
+	if len(collection.Features) != 1 || collection.Features[0].Geometry.Coordinates != [2]float64{-9.1393, 38.7223} {
+		t.Fatalf("unexpected map features: %+v", collection.Features)
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/reports/addresses/nearby?latitude=abc&longitude=1", "", token)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid latitude, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/reports/addresses/nearby?place=Lisbon", "", token)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a place without geocoding, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
 // ==================== Important Dates ====================
 
 func TestImportantDateCreate_Success(t *testing.T) {
@@ -2568,6 +2911,256 @@ func TestVCard_ExportVault(t *testing.T) {
 	}
 }
 
+func TestGedcom_ImportAndExport(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "gedcom@example.com")
+	vault := ts.createTestVault(t, token, "GEDCOM Vault")
+
+	gedcom := "0 HEAD\n1 GEDC\n2 VERS 7.0\n" +
+		"0 @I1@ INDI\n1 NAME Ada /Parent/\n1 SEX F\n" +
+		"0 @I2@ INDI\n1 NAME Ben /Parent/\n1 BIRT\n2 DATE 2 FEB 2002\n" +
+		"0 @F1@ FAM\n1 WIFE @I1@\n1 CHIL @I2@\n0 TRLR\n"
+	rec := ts.doMultipartUpload(t, "/api/vaults/"+vault.ID+"/settings/import/gedcom", token,
+		"file", "family.ged", "application/octet-stream", []byte(gedcom))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result dto.GedcomImportResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &result); err != nil {
+		t.Fatalf("parse import response: %v", err)
+	}
+	if result.ImportedContacts != 2 || result.CreatedRelationships != 2 {
+		t.Fatalf("unexpected import result: %+v", result)
+	}
+
+	rec = ts.doMultipartUpload(t, "/api/vaults/"+vault.ID+"/settings/import/gedcom", token,
+		"file", "broken.ged", "application/octet-stream", []byte("hello"))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid GEDCOM, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts/export/gedcom?version=7.0", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "1 NAME Ben /Parent/") || !strings.Contains(body, "2 DATE 2 FEB 2002") {
+		t.Fatalf("unexpected GEDCOM export:\n%s", body)
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts/export/gedcom?version=3", "", token)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unsupported version, got %d", rec.Code)
+	}
+}
+
+func TestHistoryImport_Android(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "history@example.com")
+	vault := ts.createTestVault(t, token, "History Vault")
+	ts.createTestContact(t, token, vault.ID, "Alice")
+
+	calls := `<calls count="1"><call number="+15550102000" duration="65" date="1704067200000" type="1" contact_name="Alice Doe" /></calls>`
+	rec := ts.doMultipartUpload(t, "/api/vaults/"+vault.ID+"/settings/import/history?format=android", token,
+		"file", "calls.xml", "text/xml", []byte(calls))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result dto.HistoryImportResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &result); err != nil {
+		t.Fatalf("parse import response: %v", err)
+	}
+	if result.ImportedCalls != 1 || result.MatchedContacts != 1 {
+		t.Fatalf("unexpected import result: %+v", result)
+	}
+
+	rec = ts.doMultipartUpload(t, "/api/vaults/"+vault.ID+"/settings/import/history?format=signal", token,
+		"file", "calls.xml", "text/xml", []byte(calls))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestJournalImportExport(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "journal-archive@example.com")
+	vault := ts.createTestVault(t, token, "Journal Archive Vault")
+	rec := ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/journals", `{"name":"Diary"}`, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var journal dto.JournalResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &journal); err != nil {
+		t.Fatalf("parse journal response: %v", err)
+	}
+	journalPath := fmt.Sprintf("/api/vaults/%s/journals/%d", vault.ID, journal.ID)
+
+	note := "---\ntags: [walks]\n---\nA long walk.\n"
+	rec = ts.doMultipartUpload(t, journalPath+"/import?format=obsidian", token, "file", "2024-04-02.md", "text/markdown", []byte(note))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result dto.JournalImportResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &result); err != nil {
+		t.Fatalf("parse import response: %v", err)
+	}
+	if result.ImportedPosts != 1 || result.CreatedTags != 1 {
+		t.Fatalf("unexpected import result: %+v", result)
+	}
+
+	rec = ts.doRequest(http.MethodGet, journalPath+"/export", "", token)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("expected a zip, got %d %s: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil || len(zr.File) != 1 || zr.File[0].Name != "2024-04-02.md" {
+		t.Fatalf("unexpected export: %v", err)
+	}
+
+	rec = ts.doMultipartUpload(t, journalPath+"/import?format=notion", token, "file", "a.md", "text/markdown", []byte(note))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMemoriesOnThisDay(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "memories@example.com")
+	vault := ts.createTestVault(t, token, "Memories Vault")
+	rec := ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/journals", `{"name":"Diary"}`, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var journal dto.JournalResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &journal); err != nil {
+		t.Fatalf("parse journal response: %v", err)
+	}
+	importPath := fmt.Sprintf("/api/vaults/%s/journals/%d/import?format=obsidian", vault.ID, journal.ID)
+	rec = ts.doMultipartUpload(t, importPath, token, "file", "2020-04-02.md", "text/markdown", []byte("A long walk.\n"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/memories/onThisDay?date=2026-04-02", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var day dto.OnThisDayResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &day); err != nil {
+		t.Fatalf("parse memories: %v", err)
+	}
+	if len(day.Memories) != 1 || day.Memories[0].Kind != "post" || day.Memories[0].YearsAgo != 6 {
+		t.Fatalf("unexpected memories: %+v", day.Memories)
+	}
+
+	memoryPath := fmt.Sprintf("/api/memories/post/%d", day.Memories[0].ID)
+	rec = ts.doRequest(http.MethodPut, memoryPath, `{"dismissed":true}`, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/memories/onThisDay?date=2026-04-02", "", token)
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &day); err != nil || len(day.Memories) != 0 {
+		t.Fatalf("expected the dismissed memory to be gone, got %+v (%v)", day.Memories, err)
+	}
+
+	otherToken, _ := ts.registerTestUser(t, "memories-other@example.com")
+	rec = ts.doRequest(http.MethodPut, memoryPath, `{"favorite":true}`, otherToken)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another user's memory, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodPut, "/api/memories/letter/1", `{"favorite":true}`, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown kind, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodPut, "/api/settings/notifications/memories", `{"notify_daily":true,"notify_hour":7}`, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/settings/notifications/memories", "", token)
+	var prefs dto.MemoryPreferenceResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &prefs); err != nil || !prefs.NotifyDaily || prefs.NotifyHour != 7 {
+		t.Fatalf("unexpected preferences %+v (%v)", prefs, err)
+	}
+}
+
+func TestMoodAnalyticsAndFeeds(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "mood-analytics@example.com")
+	vault := ts.createTestVault(t, token, "Mood Vault")
+	rec := ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/settings/moodParams", "", token)
+	var params []dto.MoodTrackingParameterResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &params); err != nil || len(params) == 0 {
+		t.Fatalf("parse mood parameters: %v (%s)", err, rec.Body.String())
+	}
+	for _, ratedAt := range []string{"2026-03-02T09:00:00Z", "2026-03-03T09:00:00Z"} {
+		body := fmt.Sprintf(`{"mood_tracking_parameter_id":%d,"rated_at":"%s","number_of_hours_slept":7}`, params[0].ID, ratedAt)
+		rec = ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/moodTrackingEvents", body, token)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	base := "/api/vaults/" + vault.ID + "/reports/mood"
+	rec = ts.doRequest(http.MethodGet, base+"/analytics?from=2026-03-01&to=2026-03-03&period=month", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var analytics dto.MoodAnalyticsResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &analytics); err != nil {
+		t.Fatalf("parse analytics: %v", err)
+	}
+	if analytics.Summary.Entries != 2 || analytics.Summary.CurrentStreak != 2 || len(analytics.Periods) != 1 {
+		t.Fatalf("unexpected analytics %+v", analytics)
+	}
+	rec = ts.doRequest(http.MethodGet, base+"/analytics?window=abc", "", token)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid window, got %d", rec.Code)
+	}
+
+	rec = ts.doRequest(http.MethodPost, "/api/settings/tokens", `{"name":"Grafana","scopes":["reports:read"]}`, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var pat struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &pat); err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+
+	rec = ts.doRequest(http.MethodGet, base+".json?to=2026-03-03&token="+pat.Token, "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var feed []dto.MoodFeedItem
+	if err := json.Unmarshal(rec.Body.Bytes(), &feed); err != nil || len(feed) != 2 || feed[0].Time != "2026-03-02T00:00:00Z" {
+		t.Fatalf("unexpected feed %s (%v)", rec.Body.String(), err)
+	}
+
+	rec = ts.doRequest(http.MethodGet, base+".csv?to=2026-03-03", "", pat.Token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("expected a CSV content type, got %q", ct)
+	}
+	if lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n"); len(lines) != 3 {
+		t.Errorf("expected a header and 2 rows, got %q", rec.Body.String())
+	}
+
+	// The scoped token only reaches the feeds.
+	rec = ts.doRequest(http.MethodGet, base+"/analytics", "", pat.Token)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a reports:read token on the API, got %d", rec.Code)
+	}
+	otherToken, _ := ts.registerTestUser(t, "mood-analytics-other@example.com")
+	rec = ts.doRequest(http.MethodGet, base+".csv", "", otherToken)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 outside the vault, got %d", rec.Code)
+	}
+}
+
 // ==================== Invitations ====================
 
 func TestInvitation_List(t *testing.T) {
@@ -4538,14 +5131,92 @@ func TestNotificationUpdate_Success(t *testing.T) {
 	}
 }
 
-func TestNotificationUpdate_NotFound(t *testing.T) {
+func TestNotificationUpdate_NotFound(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "notif-update-nf@test.com")
+
+	rec := ts.doRequest(http.MethodPut, "/api/settings/notifications/99999",
+		`{"label":"X","content":"x@example.com"}`, token)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTaskNotificationPreferences_GetAndUpdate(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "task-notif-prefs@test.com")
+
+	rec := ts.doRequest(http.MethodGet, "/api/settings/notifications/tasks", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var prefs dto.TaskNotificationPreferenceResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &prefs); err != nil {
+		t.Fatalf("unmarshal prefs: %v", err)
+	}
+	if !prefs.NotifyOnAssignment || !prefs.NotifyBeforeDue || !prefs.NotifyWhenOverdue || prefs.DueLeadMinutes != 1440 {
+		t.Fatalf("unexpected defaults: %+v", prefs)
+	}
+
+	rec = ts.doRequest(http.MethodPut, "/api/settings/notifications/tasks",
+		`{"notify_on_assignment":false,"notify_before_due":true,"due_lead_minutes":90,"notify_when_overdue":false}`, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/settings/notifications/tasks", "", token)
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &prefs); err != nil {
+		t.Fatalf("unmarshal prefs: %v", err)
+	}
+	if prefs.NotifyOnAssignment || !prefs.NotifyBeforeDue || prefs.NotifyWhenOverdue || prefs.DueLeadMinutes != 90 {
+		t.Fatalf("preferences not persisted: %+v", prefs)
+	}
+
+	rec = ts.doRequest(http.MethodPut, "/api/settings/notifications/tasks", `{"due_lead_minutes":-5}`, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for negative lead time, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestNotificationWebPush_KeyAndSubscribe(t *testing.T) {
 	ts := setupTestServer(t)
-	token, _ := ts.registerTestUser(t, "notif-update-nf@test.com")
+	token, _ := ts.registerTestUser(t, "webpush-subscribe@test.com")
 
-	rec := ts.doRequest(http.MethodPut, "/api/settings/notifications/99999",
-		`{"label":"X","content":"x@example.com"}`, token)
-	if rec.Code != http.StatusNotFound {
-		t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+	rec := ts.doRequest(http.MethodGet, "/api/settings/notifications/webpush/key", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var key dto.WebPushPublicKeyResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &key); err != nil {
+		t.Fatalf("unmarshal key: %v", err)
+	}
+	if key.PublicKey == "" {
+		t.Fatal("expected a VAPID public key")
+	}
+
+	// Subscription keys from RFC 8291 Appendix A.
+	body := `{"endpoint":"https://push.example.com/send/abc","label":"Phone",` +
+		`"keys":{"p256dh":"BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4","auth":"BTBZMqHH6r4Tts7J_aSIgg"}}`
+	rec = ts.doRequest(http.MethodPost, "/api/settings/notifications/webpush/subscribe", body, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var channel dto.NotificationChannelResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &channel); err != nil {
+		t.Fatalf("unmarshal channel: %v", err)
+	}
+	if channel.Type != "webpush" || !channel.Active || channel.Label != "Phone" {
+		t.Fatalf("unexpected channel: %+v", channel)
+	}
+
+	rec = ts.doRequest(http.MethodPost, "/api/settings/notifications/webpush/subscribe", body, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for an existing endpoint, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodPost, "/api/settings/notifications/webpush/subscribe",
+		`{"endpoint":"http://push.example.com/send/abc","keys":{"p256dh":"x","auth":"y"}}`, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid subscription, got %d: %s", rec.Code, rec.Body.String())
 	}
 }
 
@@ -5673,6 +6344,55 @@ func TestAdminOAuthProviders_CRUD(t *testing.T) {
 	}
 }
 
+func TestAdminOAuthProviders_ClaimRules(t *testing.T) {
+	ts := setupTestServer(t)
+	adminToken, admin := ts.registerTestUser(t, "admin-oauth-claims@example.com")
+
+	rec := ts.doRequest(http.MethodPost, "/api/admin/oauth-providers",
+		`{"type":"oidc","name":"family-sso","client_id":"k","client_secret":"s","discovery_url":"https://idp.example.com/.well-known/openid-configuration","enabled":false}`,
+		adminToken)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		ID uint `json:"id"`
+	}
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &created); err != nil {
+		t.Fatalf("parse created: %v", err)
+	}
+	rulesURL := fmt.Sprintf("/api/admin/oauth-providers/%d/claim-rules", created.ID)
+
+	rec = ts.doRequest(http.MethodPut, rulesURL,
+		`{"account_id":"`+admin.User.AccountID+`","rules":[{"claim":"groups","value":"family","vault_name":"Family"}]}`, adminToken)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid rule: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodPut, rulesURL,
+		`{"account_id":"`+admin.User.AccountID+`","rules":[{"claim":"groups","value":"bonds-admins","account_admin":true},{"claim":"groups","value":"family","vault_name":"Family","permission":200}]}`,
+		adminToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodGet, rulesURL, "", adminToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rules dto.OAuthClaimRulesResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &rules); err != nil {
+		t.Fatalf("parse rules: %v", err)
+	}
+	if rules.AccountID != admin.User.AccountID || len(rules.Rules) != 2 || rules.Rules[1].VaultName != "Family" {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/admin/oauth-providers/999/claim-rules", "", adminToken)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown provider: expected 404, got %d", rec.Code)
+	}
+}
+
 func TestAdminOAuthProviders_NonAdminForbidden(t *testing.T) {
 	ts := setupTestServer(t)
 	ts.registerTestUser(t, "admin-oauth-first@example.com")
@@ -7347,6 +8067,47 @@ func TestContactInformation_FindByIdentity_Match(t *testing.T) {
 	}
 }
 
+func TestContactInformation_FindByIdentity_PhoneInUserRegion(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "fbi-phone@example.com")
+	vault := ts.createTestVault(t, token, "FBI Phone Vault")
+	contact := ts.createTestContact(t, token, vault.ID, "Amélie")
+
+	if rec := ts.doRequest(http.MethodPut, "/api/settings/preferences", `{"phone_region":"XX"}`, token); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected an unknown region to be rejected, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := ts.doRequest(http.MethodPut, "/api/settings/preferences", `{"phone_region":"FR"}`, token); rec.Code != http.StatusOK {
+		t.Fatalf("set phone region failed: %d %s", rec.Code, rec.Body.String())
+	}
+	rec := ts.doRequest(http.MethodPost,
+		"/api/vaults/"+vault.ID+"/contacts/"+contact.ID+"/contactInformation",
+		`{"type_id":2,"data":"06 12 34 56 78"}`, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create phone failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var created dto.ContactInformationResponse
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &created); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if created.Data != "06 12 34 56 78" || created.NormalizedData != "+33612345678" {
+		t.Errorf("expected the original and the E.164 form, got %+v", created)
+	}
+
+	rec = ts.doRequest(http.MethodGet,
+		"/api/vaults/"+vault.ID+"/contactInformation/by-identity?data="+url.QueryEscape("+33 6 12 34 56 78"),
+		"", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var matches []dto.ContactInformationByIdentityMatch
+	if err := json.Unmarshal(parseResponse(t, rec).Data, &matches); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(matches) != 1 || matches[0].ContactID != contact.ID {
+		t.Errorf("expected the international form to match, got %+v", matches)
+	}
+}
+
 func TestContactInformation_FindByIdentity_MissingDataParam(t *testing.T) {
 	ts := setupTestServer(t)
 	token, _ := ts.registerTestUser(t, "fbi-missing@example.com")
@@ -7429,3 +8190,548 @@ func TestAdmin_GetSettings_RedactsSecrets(t *testing.T) {
 		}
 	}
 }
+
+func TestVaultChanges_DeltaSync(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "changes@example.com")
+	vault := ts.createTestVault(t, token, "Sync Vault")
+	contact := ts.createTestContact(t, token, vault.ID, "Alice")
+
+	type changes struct {
+		Changes []struct {
+			EntityType string          `json:"entity_type"`
+			EntityID   string          `json:"entity_id"`
+			Action     string          `json:"action"`
+			Data       json.RawMessage `json:"data"`
+		} `json:"changes"`
+		Cursor  string `json:"cursor"`
+		HasMore bool   `json:"has_more"`
+	}
+	rec := ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/changes", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list changes failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var full changes
+	json.Unmarshal(parseResponse(t, rec).Data, &full)
+	if len(full.Changes) != 1 || full.Changes[0].EntityID != contact.ID || full.Changes[0].Action != "created" {
+		t.Fatalf("unexpected full sync %s", rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodDelete, "/api/vaults/"+vault.ID+"/contacts/"+contact.ID, "", token)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete contact failed: %d %s", rec.Code, rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/changes?since="+full.Cursor, "", token)
+	var delta changes
+	json.Unmarshal(parseResponse(t, rec).Data, &delta)
+	if len(delta.Changes) != 1 || delta.Changes[0].Action != "deleted" || len(delta.Changes[0].Data) != 0 {
+		t.Fatalf("expected a tombstone for the contact, got %s", rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/changes?since=%21%21", "", token)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid cursor, got %d", rec.Code)
+	}
+}
+
+func TestBatch_CommitsOperationsWithReferences(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "batch@example.com")
+	vault := ts.createTestVault(t, token, "Batch Vault")
+
+	body := `{"operations":[
+		{"ref":"contact","method":"POST","path":"/vaults/` + vault.ID + `/contacts","body":{"first_name":"Ada","last_name":"Lovelace"}},
+		{"ref":"note","method":"POST","path":"/vaults/` + vault.ID + `/contacts/{{contact.id}}/notes","body":{"title":"About {{contact.first_name}}","body":"Met at the workshop"}},
+		{"method":"POST","path":"/vaults/` + vault.ID + `/contacts/{{contact.id}}/tasks","body":{"label":"Send the slides"}}
+	]}`
+	rec := ts.doRequest(http.MethodPost, "/api/batch", body, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("batch failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var result struct {
+		Committed bool `json:"committed"`
+		Results   []struct {
+			Ref    string          `json:"ref"`
+			Status int             `json:"status"`
+			Body   json.RawMessage `json:"body"`
+		} `json:"results"`
+	}
+	json.Unmarshal(parseResponse(t, rec).Data, &result)
+	if !result.Committed || len(result.Results) != 3 {
+		t.Fatalf("unexpected batch result %s", rec.Body.String())
+	}
+	if result.Results[0].Status != http.StatusCreated || result.Results[2].Status != http.StatusCreated {
+		t.Fatalf("unexpected statuses %s", rec.Body.String())
+	}
+	var contact struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(result.Results[0].Body, &contact)
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts/"+contact.Data.ID+"/notes", "", token)
+	if !strings.Contains(rec.Body.String(), `"title":"About Ada"`) {
+		t.Errorf("expected the note to reference the contact, got %s", rec.Body.String())
+	}
+}
+
+func TestBatch_RollsBackOnFailure(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "batch-rollback@example.com")
+	otherToken, _ := ts.registerTestUser(t, "batch-other@example.com")
+	vault := ts.createTestVault(t, token, "Batch Vault")
+	otherVault := ts.createTestVault(t, otherToken, "Other Vault")
+
+	// The second operation targets a vault the caller cannot access.
+	body := `{"operations":[
+		{"method":"POST","path":"/vaults/` + vault.ID + `/contacts","body":{"first_name":"Ada"}},
+		{"method":"POST","path":"/vaults/` + otherVault.ID + `/contacts","body":{"first_name":"Eve"}},
+		{"method":"POST","path":"/vaults/` + vault.ID + `/contacts","body":{"first_name":"Never"}}
+	]}`
+	rec := ts.doRequest(http.MethodPost, "/api/batch", body, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("batch failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var result struct {
+		Committed bool `json:"committed"`
+		Results   []struct {
+			Status int `json:"status"`
+		} `json:"results"`
+	}
+	json.Unmarshal(parseResponse(t, rec).Data, &result)
+	if result.Committed || len(result.Results) != 2 || result.Results[1].Status != http.StatusForbidden {
+		t.Fatalf("expected the batch to stop at the forbidden operation, got %s", rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts", "", token)
+	if strings.Contains(rec.Body.String(), "Ada") {
+		t.Errorf("expected the first contact to be rolled back, got %s", rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodPost, "/api/batch", `{"operations":[{"method":"POST","path":"/batch"}]}`, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected nested batches to be rejected, got %d", rec.Code)
+	}
+	rec = ts.doRequest(http.MethodPost, "/api/batch", `{"operations":[{"method":"GET","path":"/vaults/`+vault.ID+`/contacts"}]}`, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a route batches cannot run to be rejected, got %d", rec.Code)
+	}
+	rec = ts.doRequest(http.MethodPost, "/api/batch", `{"operations":[{"method":"POST","path":"/vaults/{{missing.id}}/contacts","body":{"first_name":"Ada"}}]}`, token)
+	var unresolved struct {
+		Committed bool `json:"committed"`
+		Results   []struct {
+			Status int `json:"status"`
+		} `json:"results"`
+	}
+	json.Unmarshal(parseResponse(t, rec).Data, &unresolved)
+	if unresolved.Committed || len(unresolved.Results) != 1 || unresolved.Results[0].Status != http.StatusBadRequest {
+		t.Errorf("expected an unresolved reference to fail the batch, got %s", rec.Body.String())
+	}
+}
+
+func TestBatch_ContactInformationAndLabels(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "batch-labels@example.com")
+	vault := ts.createTestVault(t, token, "Batch Vault")
+
+	type batchResult struct {
+		Committed bool `json:"committed"`
+		Results   []struct {
+			Status int `json:"status"`
+		} `json:"results"`
+	}
+	runBatch := func(failing string) batchResult {
+		t.Helper()
+		body := `{"operations":[
+			{"ref":"contact","method":"POST","path":"/vaults/` + vault.ID + `/contacts","body":{"first_name":"Ada"}},
+			{"ref":"label","method":"POST","path":"/vaults/` + vault.ID + `/settings/labels","body":{"name":"Mathematicians"}},
+			{"method":"POST","path":"/vaults/` + vault.ID + `/contacts/{{contact.id}}/labels","body":{"label_id":"{{label.id}}"}},
+			{"method":"POST","path":"/vaults/` + vault.ID + `/contacts/{{contact.id}}/contactInformation","body":{"type_id":1,"data":"ada@example.com"}},
+			` + failing + `
+		]}`
+		rec := ts.doRequest(http.MethodPost, "/api/batch", body, token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("batch failed: %d %s", rec.Code, rec.Body.String())
+		}
+		var result batchResult
+		json.Unmarshal(parseResponse(t, rec).Data, &result)
+		return result
+	}
+	assertRolledBack := func() {
+		t.Helper()
+		var contacts, labels, infos int64
+		ts.db.Model(&models.Contact{}).Where("vault_id = ? AND first_name = ?", vault.ID, "Ada").Count(&contacts)
+		ts.db.Model(&models.Label{}).Where("vault_id = ? AND name = ?", vault.ID, "Mathematicians").Count(&labels)
+		ts.db.Model(&models.ContactInformation{}).Where("data = ?", "ada@example.com").Count(&infos)
+		if contacts != 0 || labels != 0 || infos != 0 {
+			t.Errorf("expected the batch to roll back, found %d contacts, %d labels and %d emails", contacts, labels, infos)
+		}
+	}
+
+	// A label that does not exist fails the last operation.
+	result := runBatch(`{"method":"POST","path":"/vaults/` + vault.ID + `/contacts/{{contact.id}}/labels","body":{"label_id":999999}}`)
+	if result.Committed || len(result.Results) != 5 || result.Results[4].Status != http.StatusNotFound {
+		t.Fatalf("expected the unknown label to stop the batch, got %+v", result)
+	}
+	assertRolledBack()
+
+	// So does an email without an address.
+	result = runBatch(`{"method":"POST","path":"/vaults/` + vault.ID + `/contacts/{{contact.id}}/contactInformation","body":{"type_id":1}}`)
+	if result.Committed || len(result.Results) != 5 || result.Results[4].Status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected the invalid email to stop the batch, got %+v", result)
+	}
+	assertRolledBack()
+
+	result = runBatch(`{"method":"POST","path":"/vaults/` + vault.ID + `/contacts/{{contact.id}}/contactInformation","body":{"type_id":1,"data":"ada@work.example.com"}}`)
+	if !result.Committed {
+		t.Fatalf("expected the batch to commit, got %+v", result)
+	}
+	var contact models.Contact
+	if err := ts.db.Where("vault_id = ? AND first_name = ?", vault.ID, "Ada").First(&contact).Error; err != nil {
+		t.Fatalf("find contact: %v", err)
+	}
+	rec := ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts/"+contact.ID+"/labels", "", token)
+	if !strings.Contains(rec.Body.String(), `"name":"Mathematicians"`) {
+		t.Errorf("expected the new label on the contact, got %s", rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts/"+contact.ID+"/contactInformation", "", token)
+	if !strings.Contains(rec.Body.String(), "ada@example.com") || !strings.Contains(rec.Body.String(), "ada@work.example.com") {
+		t.Errorf("expected both emails, got %s", rec.Body.String())
+	}
+}
+
+func TestIdempotencyKey_ReplaysRetries(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "idempotency@example.com")
+	vault := ts.createTestVault(t, token, "Idempotent Vault")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/contacts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Idempotency-Key", "create-ada")
+		rec := httptest.NewRecorder()
+		ts.e.ServeHTTP(rec, req)
+		return rec
+	}
+	first := post(`{"first_name":"Ada"}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("create failed: %d %s", first.Code, first.Body.String())
+	}
+	retry := post(`{"first_name":"Ada"}`)
+	if retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "true" || retry.Body.String() != first.Body.String() {
+		t.Fatalf("expected the first response to be replayed, got %d %s", retry.Code, retry.Body.String())
+	}
+	if rec := post(`{"first_name":"Eve"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a different body to be rejected, got %d", rec.Code)
+	}
+
+	rec := ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts", "", token)
+	if n := strings.Count(rec.Body.String(), `"first_name":"Ada"`); n != 1 {
+		t.Errorf("expected one contact, found %d in %s", n, rec.Body.String())
+	}
+}
+
+func TestIdempotencyKey_NeverRunsTwice(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	e := echo.New()
+	runs := map[string]int{}
+	setUser := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user_id", "user-1")
+			return next(c)
+		}
+	}
+	idempotency := handlers.IdempotencyMiddleware(services.NewIdempotencyService(db))
+	e.POST("/large", func(c echo.Context) error {
+		runs["large"]++
+		return c.Blob(http.StatusOK, "text/plain", bytes.Repeat([]byte("x"), 2<<20))
+	}, setUser, idempotency)
+	e.POST("/fail", func(c echo.Context) error {
+		runs["fail"]++
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed after writing")
+	}, setUser, idempotency)
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Idempotency-Key", "key"+path)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("/large"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the first request to run, got %d", rec.Code)
+	}
+	if rec := post("/large"); rec.Code != http.StatusConflict {
+		t.Errorf("expected a retry of an unstored response to be refused, got %d", rec.Code)
+	}
+	if runs["large"] != 1 {
+		t.Errorf("expected the large handler to run once, ran %d times", runs["large"])
+	}
+
+	post("/fail")
+	retry := post("/fail")
+	if retry.Code != http.StatusInternalServerError || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected the server error to be replayed, got %d", retry.Code)
+	}
+	if runs["fail"] != 1 {
+		t.Errorf("expected the failing handler to run once, ran %d times", runs["fail"])
+	}
+}
+
+func TestVaultStream_PushesChanges(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "stream@example.com")
+	vault := ts.createTestVault(t, token, "Stream Vault")
+	server := httptest.NewServer(ts.e)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/vaults/"+vault.ID+"/stream?token="+token, nil)
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("open stream failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected stream response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	contact := ts.createTestContact(t, token, vault.ID, "Alice")
+	lines := bufio.NewScanner(resp.Body)
+	var event, data string
+	for lines.Scan() {
+		line := lines.Text()
+		if strings.HasPrefix(line, "event: ") {
+			event = strings.TrimPrefix(line, "event: ")
+		}
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(line, "data: ")
+		}
+		if line == "" && event == "contact.created" {
+			break
+		}
+	}
+	if event != "contact.created" || !strings.Contains(data, contact.ID) {
+		t.Fatalf("expected a contact.created event for %s, got %q %q (%v)", contact.ID, event, data, lines.Err())
+	}
+
+	rec := ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/stream?last_event_id=bad", "", token)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid event ID, got %d", rec.Code)
+	}
+}
+
+func TestAutomationRules_RunOnContactCreated(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "automation@example.com")
+	vault := ts.createTestVault(t, token, "Automation Vault")
+
+	rec := ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/groups", `{"name":"New people"}`, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create group failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var group dto.GroupResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &group)
+
+	body := fmt.Sprintf(`{"name":"Group new contacts","trigger":"contact_created","actions":[{"type":"add_to_group","group_id":%d}]}`, group.ID)
+	rec = ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/automation-rules", body, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create rule failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var rule dto.AutomationRuleResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &rule)
+
+	rec = ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/automation-rules", `{"name":"Bad","trigger":"contact_created","actions":[{"type":"explode"}]}`, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown action, got %d", rec.Code)
+	}
+
+	rec = ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/automation-rules", `{"name":"Metadata","trigger":"contact_created","actions":[{"type":"call_webhook","url":"http://169.254.169.254/latest/meta-data"}]}`, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a link-local webhook, got %d", rec.Code)
+	}
+
+	// Rules run in the background once the contact is saved.
+	contact := ts.createTestContact(t, token, vault.ID, "Alice")
+	var executions []dto.AutomationExecutionResponse
+	deadline := time.Now().Add(10 * time.Second)
+	for len(executions) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the rule did not run")
+		}
+		time.Sleep(20 * time.Millisecond)
+		rec = ts.doRequest(http.MethodGet, fmt.Sprintf("/api/vaults/%s/automation-rules/executions?rule_id=%d", vault.ID, rule.ID), "", token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list executions failed: %d %s", rec.Code, rec.Body.String())
+		}
+		json.Unmarshal(parseResponse(t, rec).Data, &executions)
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts/"+contact.ID+"/groups", "", token)
+	var groups []dto.GroupResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &groups)
+	if len(groups) != 1 || groups[0].ID != group.ID {
+		t.Fatalf("expected the rule to add the contact to the group, got %s", rec.Body.String())
+	}
+	if len(executions) != 1 || executions[0].Status != "succeeded" || executions[0].ContactID != contact.ID {
+		t.Fatalf("expected one successful execution, got %s", rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodDelete, fmt.Sprintf("/api/vaults/%s/automation-rules/%d", vault.ID, rule.ID), "", token)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("delete rule failed: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCSVImport_PreviewAndDryRun(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "csv-preview@example.com")
+	vault := ts.createTestVault(t, token, "CSV Vault")
+	base := "/api/vaults/" + vault.ID + "/settings/import/csv"
+	file := []byte("First Name,Last Name,File As,E-mail 1 - Value\nAda,Lovelace,,ada@example.com\n")
+
+	rec := ts.doMultipartUpload(t, base+"/preview", token, "file", "contacts.csv", "text/csv", file)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("preview failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var preview dto.CSVImportPreviewResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &preview)
+	if preview.Preset != "google" || preview.Mapping.Email != "E-mail 1 - Value" {
+		t.Fatalf("expected the Google preset, got %+v", preview)
+	}
+
+	mapping, _ := json.Marshal(preview.Mapping)
+	query := url.Values{"mapping": {string(mapping)}, "dry_run": {"true"}, "on_duplicate": {"skip"}}
+	rec = ts.doMultipartUpload(t, base+"?"+query.Encode(), token, "file", "contacts.csv", "text/csv", file)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("dry run failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var result dto.CSVImportResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &result)
+	if !result.DryRun || result.ImportedContacts != 1 || len(result.Rows) != 1 || result.Rows[0].Action != "create" {
+		t.Fatalf("unexpected dry-run result %s", rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts", "", token)
+	var contacts []dto.ContactResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &contacts)
+	for _, c := range contacts {
+		if c.FirstName == "Ada" {
+			t.Fatal("expected a dry run not to create contacts")
+		}
+	}
+
+	query.Set("on_duplicate", "merge")
+	rec = ts.doMultipartUpload(t, base+"?"+query.Encode(), token, "file", "contacts.csv", "text/csv", file)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown duplicate mode, got %d", rec.Code)
+	}
+}
+
+func TestJobs_AsyncCSVImport(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "csv-job@example.com")
+	otherToken, _ := ts.registerTestUser(t, "csv-job-other@example.com")
+	vault := ts.createTestVault(t, token, "Job Vault")
+	file := []byte("First Name,Email\nAda,ada@example.com\nGrace,not-an-email\n")
+	mapping, _ := json.Marshal(dto.CSVColumnMapping{FirstName: "First Name", Email: "Email"})
+	query := url.Values{"mapping": {string(mapping)}, "async": {"true"}}
+
+	rec := ts.doMultipartUpload(t, "/api/vaults/"+vault.ID+"/settings/import/csv?"+query.Encode(), token, "file", "contacts.csv", "text/csv", file)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d %s", rec.Code, rec.Body.String())
+	}
+	var job dto.JobResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &job)
+	if job.Type != "csv_import" || job.ID == 0 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	path := fmt.Sprintf("/api/jobs/%d", job.ID)
+	deadline := time.Now().Add(10 * time.Second)
+	for job.Status != "succeeded" && job.Status != "failed" {
+		if time.Now().After(deadline) {
+			t.Fatalf("job stuck in %q", job.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+		rec = ts.doRequest(http.MethodGet, path, "", token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("get job failed: %d %s", rec.Code, rec.Body.String())
+		}
+		json.Unmarshal(parseResponse(t, rec).Data, &job)
+	}
+	var result dto.CSVImportResponse
+	json.Unmarshal(job.Result, &result)
+	if job.Status != "succeeded" || job.Progress != 100 || result.ImportedContacts != 2 || job.ErrorCount != 1 {
+		t.Fatalf("unexpected finished job %+v with result %s", job, job.Result)
+	}
+
+	rec = ts.doRequest(http.MethodGet, path+"/errors", "", token)
+	var jobErrors []dto.JobErrorResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &jobErrors)
+	if len(jobErrors) != 1 {
+		t.Errorf("expected the invalid email as a job error, got %s", rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodPost, path+"/cancel", "", token)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 when cancelling a finished job, got %d", rec.Code)
+	}
+	rec = ts.doRequest(http.MethodGet, path, "", otherToken)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected other users not to see the job, got %d", rec.Code)
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/jobs", "", token)
+	var jobs []dto.JobResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &jobs)
+	if len(jobs) != 1 {
+		t.Errorf("expected one job in the list, got %d", len(jobs))
+	}
+}
+
+func TestEmailInbox_SetUpAndRotate(t *testing.T) {
+	ts := setupTestServer(t)
+	token, _ := ts.registerTestUser(t, "inbox@example.com")
+	vault := ts.createTestVault(t, token, "Inbox Vault")
+	path := "/api/vaults/" + vault.ID + "/email-inbox"
+
+	rec := ts.doRequest(http.MethodPut, path, `{"mode":"activity"}`, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 while inbound mail is not configured, got %d %s", rec.Code, rec.Body.String())
+	}
+	if err := services.NewSystemSettingService(ts.db).Set("inbound_mail.address", "bonds@mail.example.com"); err != nil {
+		t.Fatalf("set inbound address: %v", err)
+	}
+	rec = ts.doRequest(http.MethodPut, path, `{"mode":"thread"}`, token)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for an unknown mode, got %d", rec.Code)
+	}
+	rec = ts.doRequest(http.MethodPut, path, `{"mode":"note"}`, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body.String())
+	}
+	var inbox dto.EmailInboxResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &inbox)
+	if inbox.Mode != "note" || !strings.HasPrefix(inbox.Address, "bonds+") {
+		t.Fatalf("unexpected inbox %+v", inbox)
+	}
+
+	rec = ts.doRequest(http.MethodPost, path+"/rotate", "", token)
+	var rotated dto.EmailInboxResponse
+	json.Unmarshal(parseResponse(t, rec).Data, &rotated)
+	if rec.Code != http.StatusOK || rotated.Address == inbox.Address || rotated.Mode != "note" {
+		t.Errorf("expected a new address, got %d %+v", rec.Code, rotated)
+	}
+	rec = ts.doRequest(http.MethodGet, path+"/messages", "", token)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 listing messages, got %d", rec.Code)
+	}
+
+	rec = ts.doRequest(http.MethodDelete, path, "", token)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	rec = ts.doRequest(http.MethodGet, path, "", token)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after deleting the inbox, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+type HistoryImportHandler struct {
+	svc        *services.HistoryImportService
+	jobService *services.JobService
+}
+
+func NewHistoryImportHandler(svc *services.HistoryImportService, jobService *services.JobService) *HistoryImportHandler {
+	return &HistoryImportHandler{svc: svc, jobService: jobService}
+}
+
+// Import godoc
+//
+//	@Summary		Import chat and call history
+//	@Description	Import a WhatsApp chat export (.txt, or .zip with media), a Telegram Desktop JSON export (result.json) or an Android "SMS Backup & Restore" calls or messages XML. People are matched to contacts by phone number or name. Calls become calls of the contact; each day of a conversation becomes an activity with the matched contacts, or with mode=note a note on each of them. Importing a newer export of the same history adds only what is new. With async=true the import runs as a background job and the job is returned.
+//	@Tags			Vault Settings
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			file		formData	file	true	"History export"
+//	@Param			format		formData	string	true	"whatsapp, telegram or android"
+//	@Param			mode		formData	string	false	"activity (default) or note"
+//	@Param			owner		formData	string	false	"Your name as it appears in the export (defaults to your name)"
+//	@Param			async		formData	boolean	false	"Run as a background job"
+//	@Success		200			{object}	response.APIResponse{data=dto.HistoryImportResponse}
+//	@Success		202			{object}	response.APIResponse{data=dto.JobResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/settings/import/history [post]
+func (h *HistoryImportHandler) Import(c echo.Context) error {
+	vaultID := c.Param("vault_id")
+	userID := middleware.GetUserID(c)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return response.BadRequest(c, "err.file_required", nil)
+	}
+	if file.Size > services.MaxHistoryFileSize {
+		return response.BadRequest(c, "err.file_too_large", nil)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_read_file")
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, services.MaxHistoryFileSize))
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_read_file")
+	}
+
+	opts := dto.HistoryImportOptions{
+		Format:   c.FormValue("format"),
+		Mode:     c.FormValue("mode"),
+		Owner:    c.FormValue("owner"),
+		Filename: file.Filename,
+	}
+	async, err := runAsync(c)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if async {
+		if err := services.ValidateHistoryImportOptions(opts); err != nil {
+			return historyImportError(c, err)
+		}
+		return enqueueJob(c, h.jobService, &vaultID, services.JobTypeHistoryImport, opts, data)
+	}
+
+	result, err := h.svc.Import(vaultID, userID, data, opts)
+	if err != nil {
+		return historyImportError(c, err)
+	}
+	return response.OK(c, result)
+}
+
+func historyImportError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidHistoryFormat):
+		return response.BadRequest(c, "err.invalid_history_format", nil)
+	case errors.Is(err, services.ErrInvalidHistoryMode):
+		return response.BadRequest(c, "err.invalid_history_mode", nil)
+	case errors.Is(err, services.ErrInvalidHistoryFile):
+		return response.BadRequest(c, "err.invalid_history_file", nil)
+	}
+	return response.InternalError(c, "err.failed_to_import_history")
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+	// Request bodies beyond this size are hashed from a temporary file
+	// instead of memory.
+	idempotencyMemoryBodyBytes = 1 << 20
+	// Larger responses are not stored; retries of their requests are
+	// refused instead of replayed.
+	maxIdempotentResponseBytes = 1 << 20
+)
+
+// IdempotencyMiddleware replays the stored response when a POST, PUT, PATCH
+// or DELETE request is retried with the same Idempotency-Key header. Keys are
+// scoped to the user, so it must run after authentication.
+func IdempotencyMiddleware(idempotencyService *services.IdempotencyService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			key := req.Header.Get(idempotencyKeyHeader)
+			switch req.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				return next(c)
+			}
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return response.BadRequest(c, "err.invalid_idempotency_key", nil)
+			}
+
+			hash, cleanup, err := hashRequest(req)
+			if err != nil {
+				return response.BadRequest(c, "err.invalid_request_body", nil)
+			}
+			defer cleanup()
+
+			record, claimed, err := idempotencyService.Begin(middleware.GetUserID(c), key, hash)
+			if err != nil {
+				if errors.Is(err, services.ErrIdempotencyKeyMismatch) {
+					return response.BadRequest(c, "err.idempotency_key_reused", nil)
+				}
+				if errors.Is(err, services.ErrIdempotencyKeyInProgress) {
+					return response.Conflict(c, "err.idempotency_request_in_progress")
+				}
+				return response.InternalError(c, "err.database_error")
+			}
+			if !claimed {
+				if record.ResponseTooLarge {
+					return response.Conflict(c, "err.idempotency_response_not_stored")
+				}
+				c.Response().Header().Set("Idempotent-Replayed", "true")
+				if len(record.Body) == 0 {
+					return c.NoContent(record.StatusCode)
+				}
+				return c.Blob(record.StatusCode, record.ContentType, record.Body)
+			}
+
+			capture := &responseCapture{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = capture
+			release := func() {
+				if err := idempotencyService.Release(record); err != nil {
+					log.Printf("[idempotency] failed to release key %d: %v", record.ID, err)
+				}
+			}
+			// A panic before any response leaves nothing to replay; the key
+			// is given back rather than blocking retries until the pending
+			// timeout.
+			defer func() {
+				if p := recover(); p != nil {
+					if !c.Response().Committed {
+						release()
+					}
+					panic(p)
+				}
+			}()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			// The handler may have changed data before answering, even with
+			// a server error, so the key is only given back when nothing was
+			// sent at all. Every response is replayed.
+			res := c.Response()
+			if !res.Committed {
+				release()
+				return nil
+			}
+			if capture.overflow {
+				if err := idempotencyService.CompleteTooLarge(record, res.Status); err != nil {
+					log.Printf("[idempotency] failed to complete key %d: %v", record.ID, err)
+				}
+				return nil
+			}
+			if err := idempotencyService.Complete(record, res.Status, res.Header().Get(echo.HeaderContentType), capture.body.Bytes()); err != nil {
+				log.Printf("[idempotency] failed to store the response for key %d: %v", record.ID, err)
+			}
+			return nil
+		}
+	}
+}
+
+// hashRequest hashes the method, URI and body of req and gives the handler
+// a fresh copy of the body. Large bodies are spooled to a temporary file,
+// which cleanup removes.
+func hashRequest(req *http.Request) (hash string, cleanup func(), err error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.RequestURI())
+	cleanup = func() {}
+	if req.Body == nil {
+		return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+	}
+
+	var head bytes.Buffer
+	n, err := io.Copy(io.MultiWriter(h, &head), io.LimitReader(req.Body, idempotencyMemoryBodyBytes))
+	if err != nil {
+		return "", nil, err
+	}
+	if n < idempotencyMemoryBodyBytes {
+		req.Body = io.NopCloser(&head)
+		return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+	}
+
+	spool, err := os.CreateTemp("", "bonds-request-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+	if _, err := io.Copy(io.MultiWriter(h, spool), req.Body); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	req.Body = io.NopCloser(io.MultiReader(&head, spool))
+	return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+}
+
+// responseCapture keeps a copy of the response body for replay.
+type responseCapture struct {
+	http.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *responseCapture) Write(b []byte) (int, error) {
+	if !w.overflow {
+		if w.body.Len()+len(b) > maxIdempotentResponseBytes {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseCapture) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -13,6 +13,7 @@ type InstanceHandler struct {
 	settingService  *services.SystemSettingService
 	oauthService    *services.OAuthService
 	webauthnService *services.WebAuthnService
+	forwardAuth     bool
 	version         string
 }
 
@@ -20,12 +21,14 @@ func NewInstanceHandler(
 	settingService *services.SystemSettingService,
 	oauthService *services.OAuthService,
 	webauthnService *services.WebAuthnService,
+	forwardAuth bool,
 	version string,
 ) *InstanceHandler {
 	return &InstanceHandler{
 		settingService:  settingService,
 		oauthService:    oauthService,
 		webauthnService: webauthnService,
+		forwardAuth:     forwardAuth,
 		version:         version,
 	}
 }
@@ -68,6 +71,8 @@ func (h *InstanceHandler) GetInfo(c echo.Context) error {
 		OAuthProviders:           oauthNames,
 		OAuthProviderDetails:     oauthDetails,
 		WebAuthnEnabled:          webauthnEnabled,
+		ForwardAuthEnabled:       h.forwardAuth,
+		LDAPEnabled:              h.settingService.GetBool("ldap.enabled", false),
 		AppName:                  appName,
 		RequireEmailVerification: emailVerificationActive,
 	}
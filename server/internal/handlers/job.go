@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+var _ dto.JobResponse
+
+type JobHandler struct {
+	jobService *services.JobService
+}
+
+func NewJobHandler(jobService *services.JobService) *JobHandler {
+	return &JobHandler{jobService: jobService}
+}
+
+// List godoc
+//
+//	@Summary		List background jobs
+//	@Description	Return the background jobs of the current user, newest first
+//	@Tags			jobs
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			page		query		integer	false	"Page number"
+//	@Param			per_page	query		integer	false	"Items per page (default 20, max 100)"
+//	@Success		200			{object}	response.APIResponse{data=[]dto.JobResponse}
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/jobs [get]
+func (h *JobHandler) List(c echo.Context) error {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	perPage, _ := strconv.Atoi(c.QueryParam("per_page"))
+	jobs, meta, err := h.jobService.List(middleware.GetUserID(c), page, perPage)
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_list_jobs")
+	}
+	return response.Paginated(c, jobs, meta)
+}
+
+// Get godoc
+//
+//	@Summary		Get a background job
+//	@Description	Return the status, progress and result of a background job
+//	@Tags			jobs
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		integer	true	"Job ID"
+//	@Success		200	{object}	response.APIResponse{data=dto.JobResponse}
+//	@Failure		400	{object}	response.APIResponse
+//	@Failure		404	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/jobs/{id} [get]
+func (h *JobHandler) Get(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_job_id", nil)
+	}
+	job, err := h.jobService.Get(uint(id), middleware.GetUserID(c))
+	if err != nil {
+		if errors.Is(err, services.ErrJobNotFound) {
+			return response.NotFound(c, "err.job_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_get_job")
+	}
+	return response.OK(c, job)
+}
+
+// ListErrors godoc
+//
+//	@Summary		List the errors of a background job
+//	@Description	Return the problems a job ran into with single items, such as rows it could not import
+//	@Tags			jobs
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id			path		integer	true	"Job ID"
+//	@Param			page		query		integer	false	"Page number"
+//	@Param			per_page	query		integer	false	"Items per page (default 20, max 100)"
+//	@Success		200			{object}	response.APIResponse{data=[]dto.JobErrorResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/jobs/{id}/errors [get]
+func (h *JobHandler) ListErrors(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_job_id", nil)
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	perPage, _ := strconv.Atoi(c.QueryParam("per_page"))
+	jobErrors, meta, err := h.jobService.ListErrors(uint(id), middleware.GetUserID(c), page, perPage)
+	if err != nil {
+		if errors.Is(err, services.ErrJobNotFound) {
+			return response.NotFound(c, "err.job_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_list_job_errors")
+	}
+	return response.Paginated(c, jobErrors, meta)
+}
+
+// Cancel godoc
+//
+//	@Summary		Cancel a background job
+//	@Description	Cancel a queued job, or ask a running job to stop. A running job stops between items and keeps what it already did, except for vCard imports, which roll back.
+//	@Tags			jobs
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		integer	true	"Job ID"
+//	@Success		200	{object}	response.APIResponse{data=dto.JobResponse}
+//	@Failure		400	{object}	response.APIResponse
+//	@Failure		404	{object}	response.APIResponse
+//	@Failure		409	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/jobs/{id}/cancel [post]
+func (h *JobHandler) Cancel(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_job_id", nil)
+	}
+	job, err := h.jobService.Cancel(uint(id), middleware.GetUserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrJobNotFound):
+			return response.NotFound(c, "err.job_not_found")
+		case errors.Is(err, services.ErrJobFinished):
+			return response.Conflict(c, "err.job_already_finished")
+		}
+		return response.InternalError(c, "err.failed_to_cancel_job")
+	}
+	return response.OK(c, job)
+}
+
+// runAsync reports whether the request asked to run as a background job
+// with the async form or query parameter.
+func runAsync(c echo.Context) (bool, error) {
+	v := c.FormValue("async")
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// enqueueJob queues a job for the current user and responds with it.
+func enqueueJob(c echo.Context, jobService *services.JobService, vaultID *string, jobType string, params interface{}, input []byte) error {
+	job, err := jobService.Enqueue(middleware.GetUserID(c), vaultID, jobType, params, input)
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_enqueue_job")
+	}
+	return response.Accepted(c, job)
+}
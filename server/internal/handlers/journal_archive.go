@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+type JournalArchiveHandler struct {
+	svc        *services.JournalArchiveService
+	jobService *services.JobService
+}
+
+func NewJournalArchiveHandler(svc *services.JournalArchiveService, jobService *services.JobService) *JournalArchiveHandler {
+	return &JournalArchiveHandler{svc: svc, jobService: jobService}
+}
+
+// Export godoc
+//
+//	@Summary		Export journal as Markdown
+//	@Description	Export the posts of a journal as a zip of Markdown files, one per post, with YAML front matter (title, date, published, slice, tags, contacts, metrics, sections, photos) and the post photos under photos/. Contact mentions are written as [[Name]] links, so the folder also opens in Obsidian.
+//	@Tags			journals
+//	@Produce		application/zip
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			id			path		integer	true	"Journal ID"
+//	@Success		200			{file}		file
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/journals/{id}/export [get]
+func (h *JournalArchiveHandler) Export(c echo.Context) error {
+	vaultID := c.Param("vault_id")
+	journalID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_journal_id", nil)
+	}
+	var buf bytes.Buffer
+	if err := h.svc.Export(uint(journalID), vaultID, middleware.GetUserID(c), &buf); err != nil {
+		if errors.Is(err, services.ErrJournalNotFound) {
+			return response.NotFound(c, "err.journal_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_export_journal")
+	}
+	c.Response().Header().Set("Content-Disposition", "attachment; filename=journal.zip")
+	return c.Blob(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// Import godoc
+//
+//	@Summary		Import journal entries
+//	@Description	Add entries to a journal from a Bonds Markdown export (format=markdown), a Day One JSON export (dayone, the .json or the .zip with photos) or a zip of Obsidian daily notes (obsidian), or a single Markdown file. Tags become post tags, named contacts and [[Name]] links to contacts become post contacts and mentions, and slices and metrics are created as needed. Entries the journal already has a post for, with the same date and title, are skipped. With async=true the import runs as a background job and the job is returned.
+//	@Tags			journals
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			id			path		integer	true	"Journal ID"
+//	@Param			file		formData	file	true	"Journal export"
+//	@Param			format		formData	string	true	"markdown, dayone or obsidian"
+//	@Param			async		formData	boolean	false	"Run as a background job"
+//	@Success		200			{object}	response.APIResponse{data=dto.JournalImportResponse}
+//	@Success		202			{object}	response.APIResponse{data=dto.JobResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/journals/{id}/import [post]
+func (h *JournalArchiveHandler) Import(c echo.Context) error {
+	vaultID := c.Param("vault_id")
+	userID := middleware.GetUserID(c)
+	journalID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_journal_id", nil)
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return response.BadRequest(c, "err.file_required", nil)
+	}
+	if file.Size > services.MaxJournalFileSize {
+		return response.BadRequest(c, "err.file_too_large", nil)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_read_file")
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, services.MaxJournalFileSize))
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_read_file")
+	}
+
+	opts := dto.JournalImportOptions{
+		JournalID: uint(journalID),
+		Format:    c.FormValue("format"),
+		Filename:  file.Filename,
+	}
+	async, err := runAsync(c)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if async {
+		if err := services.ValidateJournalImportOptions(opts); err != nil {
+			return journalImportError(c, err)
+		}
+		return enqueueJob(c, h.jobService, &vaultID, services.JobTypeJournalImport, opts, data)
+	}
+
+	result, err := h.svc.Import(vaultID, userID, data, opts)
+	if err != nil {
+		return journalImportError(c, err)
+	}
+	return response.OK(c, result)
+}
+
+func journalImportError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidJournalFormat):
+		return response.BadRequest(c, "err.invalid_journal_format", nil)
+	case errors.Is(err, services.ErrInvalidJournalFile):
+		return response.BadRequest(c, "err.invalid_journal_file", nil)
+	case errors.Is(err, services.ErrJournalNotFound):
+		return response.NotFound(c, "err.journal_not_found")
+	}
+	return response.InternalError(c, "err.failed_to_import_journal")
+}
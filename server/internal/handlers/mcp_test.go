@@ -297,7 +297,7 @@ func TestMCPExecuteActionUsesExistingPermissions(t *testing.T) {
 
 	viewer := createSecondUser(t, ts, managerAuth.User.AccountID, "mcp-viewer@example.com", false)
 	addUserToVault(t, ts, viewer.ID, vault.ID, models.PermissionViewer)
-	viewerToken := generateJWT(viewer.ID, viewer.AccountID, viewer.Email, false, false)
+	viewerToken := ts.generateJWT(viewer.ID, viewer.AccountID, viewer.Email, false, false)
 	rec = ts.doRequest(http.MethodPost, "/mcp", body, viewerToken)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected MCP 200 with tool error, got %d: %s", rec.Code, rec.Body.String())
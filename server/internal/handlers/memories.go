@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+type MemoryHandler struct {
+	memoryService *services.MemoryService
+}
+
+func NewMemoryHandler(memoryService *services.MemoryService) *MemoryHandler {
+	return &MemoryHandler{memoryService: memoryService}
+}
+
+// OnThisDay godoc
+//
+//	@Summary		List memories from this day
+//	@Description	Return the journal posts, activities, calls, notes, photos and important dates from the same calendar day in earlier years, across all vaults of the current user. Dates recorded in the lunar calendar come up on their lunar day. The day is today in the user's timezone unless date is given. Dismissed memories are left out; favorites come first, then the most recent years.
+//	@Tags			dashboard
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			date	query		string	false	"Day to look back from (YYYY-MM-DD)"
+//	@Success		200		{object}	response.APIResponse{data=dto.OnThisDayResponse}
+//	@Failure		400		{object}	response.APIResponse
+//	@Failure		401		{object}	response.APIResponse
+//	@Failure		500		{object}	response.APIResponse
+//	@Router			/memories/onThisDay [get]
+func (h *MemoryHandler) OnThisDay(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	result, err := h.memoryService.OnThisDay(userID, c.QueryParam("date"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMemoryDate) {
+			return response.BadRequest(c, "err.invalid_memory_date", nil)
+		}
+		return response.InternalError(c, "err.failed_to_get_memories")
+	}
+	return response.OK(c, result)
+}
+
+// Favorites godoc
+//
+//	@Summary		List favorite memories
+//	@Description	Return the memories the current user marked as favorites, newest first
+//	@Tags			dashboard
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	response.APIResponse{data=[]dto.MemoryResponse}
+//	@Failure		401	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/memories/favorites [get]
+func (h *MemoryHandler) Favorites(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	memories, err := h.memoryService.Favorites(userID)
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_get_memories")
+	}
+	return response.OK(c, memories)
+}
+
+// UpdateState godoc
+//
+//	@Summary		Dismiss or favorite a memory
+//	@Description	Dismiss a memory so it no longer comes up, or mark it as a favorite. Fields left out keep their value.
+//	@Tags			dashboard
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			kind	path		string							true	"post, activity, call, note, photo or important_date"
+//	@Param			id		path		integer							true	"ID of the post, activity, call, note, photo or important date"
+//	@Param			request	body		dto.UpdateMemoryStateRequest	true	"State"
+//	@Success		200		{object}	response.APIResponse{data=dto.MemoryResponse}
+//	@Failure		400		{object}	response.APIResponse
+//	@Failure		401		{object}	response.APIResponse
+//	@Failure		404		{object}	response.APIResponse
+//	@Failure		500		{object}	response.APIResponse
+//	@Router			/memories/{kind}/{id} [put]
+func (h *MemoryHandler) UpdateState(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_memory_id", nil)
+	}
+	var req dto.UpdateMemoryStateRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	memory, err := h.memoryService.UpdateState(userID, c.Param("kind"), uint(id), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMemoryKind):
+			return response.BadRequest(c, "err.invalid_memory_kind", nil)
+		case errors.Is(err, services.ErrMemoryNotFound):
+			return response.NotFound(c, "err.memory_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_update_memory")
+	}
+	return response.OK(c, memory)
+}
+
+// GetPreferences godoc
+//
+//	@Summary		Get "On this day" notification preferences
+//	@Description	Return whether the current user gets a daily notification with their memories, and from which hour of the day in their timezone
+//	@Tags			notifications
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	response.APIResponse{data=dto.MemoryPreferenceResponse}
+//	@Failure		401	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/settings/notifications/memories [get]
+func (h *MemoryHandler) GetPreferences(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	prefs, err := h.memoryService.GetPreferences(userID)
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_get_memory_preferences")
+	}
+	return response.OK(c, prefs)
+}
+
+// UpdatePreferences godoc
+//
+//	@Summary		Update "On this day" notification preferences
+//	@Description	Turn the daily "On this day" notification on or off. notify_hour (0-23) is the hour of the day, in the user's timezone, from which it is sent to the active notification channels. Days without memories send nothing.
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		dto.UpdateMemoryPreferenceRequest	true	"Preferences"
+//	@Success		200		{object}	response.APIResponse{data=dto.MemoryPreferenceResponse}
+//	@Failure		400		{object}	response.APIResponse
+//	@Failure		401		{object}	response.APIResponse
+//	@Failure		422		{object}	response.APIResponse
+//	@Failure		500		{object}	response.APIResponse
+//	@Router			/settings/notifications/memories [put]
+func (h *MemoryHandler) UpdatePreferences(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req dto.UpdateMemoryPreferenceRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if err := validateRequest(req); err != nil {
+		return response.ValidationError(c, map[string]string{"validation": err.Error()})
+	}
+	prefs, err := h.memoryService.UpdatePreferences(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMemoryNotifyHour) {
+			return response.BadRequest(c, "err.invalid_memory_notify_hour", nil)
+		}
+		return response.InternalError(c, "err.failed_to_update_memory_preferences")
+	}
+	return response.OK(c, prefs)
+}
@@ -16,23 +16,26 @@ var _ dto.MonicaImportResponse
 
 type MonicaImportHandler struct {
 	monicaImportService *services.MonicaImportService
+	jobService          *services.JobService
 }
 
-func NewMonicaImportHandler(svc *services.MonicaImportService) *MonicaImportHandler {
-	return &MonicaImportHandler{monicaImportService: svc}
+func NewMonicaImportHandler(svc *services.MonicaImportService, jobService *services.JobService) *MonicaImportHandler {
+	return &MonicaImportHandler{monicaImportService: svc, jobService: jobService}
 }
 
 // Import godoc
 //
 //	@Summary		Import Monica 4.x JSON data
-//	@Description	Import contacts and related data from a Monica 4.x JSON export file
+//	@Description	Import contacts and related data from a Monica 4.x JSON export file. With async=true the import runs as a background job and the job is returned.
 //	@Tags			Vault Settings
 //	@Accept			multipart/form-data
 //	@Produce		json
 //	@Security		BearerAuth
 //	@Param			vault_id	path		string	true	"Vault ID"
 //	@Param			file		formData	file	true	"Monica JSON export file"
+//	@Param			async		formData	boolean	false	"Run as a background job"
 //	@Success		200			{object}	response.APIResponse{data=dto.MonicaImportResponse}
+//	@Success		202			{object}	response.APIResponse{data=dto.JobResponse}
 //	@Failure		400			{object}	response.APIResponse
 //	@Failure		403			{object}	response.APIResponse
 //	@Failure		500			{object}	response.APIResponse
@@ -41,6 +44,11 @@ func (h *MonicaImportHandler) Import(c echo.Context) error {
 	vaultID := c.Param("vault_id")
 	userID := middleware.GetUserID(c)
 
+	async, err := runAsync(c)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+
 	file, err := c.FormFile("file")
 	if err != nil {
 		return response.BadRequest(c, "err.file_required", nil)
@@ -57,6 +65,10 @@ func (h *MonicaImportHandler) Import(c echo.Context) error {
 		return response.InternalError(c, "err.failed_to_read_file")
 	}
 
+	if async {
+		return enqueueJob(c, h.jobService, &vaultID, services.JobTypeMonicaImport, nil, buf.Bytes())
+	}
+
 	result, err := h.monicaImportService.Import(vaultID, userID, buf.Bytes())
 	if err != nil {
 		if errors.Is(err, services.ErrMonicaInvalidJSON) || errors.Is(err, services.ErrMonicaInvalidVersion) {
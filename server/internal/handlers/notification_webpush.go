@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+// WebPushPublicKey godoc
+//
+//	@Summary		Get the Web Push public key
+//	@Description	Return the VAPID application server key to pass to pushManager.subscribe
+//	@Tags			notifications
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	response.APIResponse{data=dto.WebPushPublicKeyResponse}
+//	@Failure		401	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/settings/notifications/webpush/key [get]
+func (h *NotificationHandler) WebPushPublicKey(c echo.Context) error {
+	key, err := h.notificationService.WebPushPublicKey()
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_get_webpush_key")
+	}
+	return response.OK(c, key)
+}
+
+// SubscribeWebPush godoc
+//
+//	@Summary		Register a Web Push subscription
+//	@Description	Register the browser's PushSubscription as a Web Push notification channel, or refresh it if the endpoint is already registered
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		dto.WebPushSubscribeRequest	true	"Push subscription"
+//	@Success		200		{object}	response.APIResponse{data=dto.NotificationChannelResponse}
+//	@Success		201		{object}	response.APIResponse{data=dto.NotificationChannelResponse}
+//	@Failure		400		{object}	response.APIResponse
+//	@Failure		401		{object}	response.APIResponse
+//	@Failure		422		{object}	response.APIResponse
+//	@Failure		500		{object}	response.APIResponse
+//	@Router			/settings/notifications/webpush/subscribe [post]
+func (h *NotificationHandler) SubscribeWebPush(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req dto.WebPushSubscribeRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if err := validateRequest(req); err != nil {
+		return response.ValidationError(c, map[string]string{"validation": err.Error()})
+	}
+	channel, created, err := h.notificationService.SubscribeWebPush(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrWebPushInvalidSubscription) {
+			return response.BadRequest(c, "err.invalid_webpush_subscription", nil)
+		}
+		return response.InternalError(c, "err.failed_to_subscribe_webpush")
+	}
+	if created {
+		return response.Created(c, channel)
+	}
+	return response.OK(c, channel)
+}
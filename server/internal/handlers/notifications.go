@@ -105,6 +105,9 @@ func (h *NotificationHandler) Update(c echo.Context) error {
 		if errors.Is(err, services.ErrNotificationChannelNotFound) {
 			return response.NotFound(c, "err.notification_channel_not_found")
 		}
+		if errors.Is(err, services.ErrWebPushInvalidSubscription) {
+			return response.BadRequest(c, "err.invalid_webpush_subscription", nil)
+		}
 		return response.InternalError(c, "err.failed_to_update_notification_channel")
 	}
 	return response.OK(c, channel)
@@ -226,6 +229,9 @@ func (h *NotificationHandler) SendTest(c echo.Context) error {
 		if errors.Is(err, services.ErrNotificationChannelNotFound) {
 			return response.NotFound(c, "err.notification_channel_not_found")
 		}
+		if errors.Is(err, services.ErrWebPushSubscriptionGone) {
+			return response.BadRequest(c, "err.webpush_subscription_expired", nil)
+		}
 		return response.InternalError(c, "err.failed_to_send_test")
 	}
 	return response.OK(c, map[string]string{"status": "sent"})
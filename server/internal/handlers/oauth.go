@@ -105,7 +105,7 @@ func (h *OAuthHandler) Callback(c echo.Context) error {
 	}
 
 	locale := middleware.GetLocale(c)
-	authResp, linkInfo, err := h.oauthService.FindOrCreateUser(provider, gothUser.UserID, gothUser.Email, gothUser.Name, locale)
+	authResp, linkInfo, err := h.oauthService.FindOrCreateUser(provider, gothUser.UserID, gothUser.Email, gothUser.Name, locale, gothUser.RawData)
 	if err != nil {
 		if errors.Is(err, services.ErrOAuthAccountNotLinked) {
 			linkToken, tokenErr := h.oauthService.GenerateLinkToken(linkInfo)
@@ -116,6 +116,10 @@ func (h *OAuthHandler) Callback(c echo.Context) error {
 			return c.Redirect(http.StatusTemporaryRedirect,
 				fmt.Sprintf("%s/auth/oauth-link?link_token=%s", h.getAppURL(), linkToken))
 		}
+		if errors.Is(err, services.ErrUserDisabled) {
+			return c.Redirect(http.StatusTemporaryRedirect,
+				fmt.Sprintf("%s/login?error=account_disabled", h.getAppURL()))
+		}
 		return c.Redirect(http.StatusTemporaryRedirect,
 			fmt.Sprintf("%s/login?error=oauth_failed", h.getAppURL()))
 	}
@@ -128,7 +132,7 @@ func (h *OAuthHandler) Callback(c echo.Context) error {
 		gothUser.AccessToken, gothUser.RefreshToken, expiresIn)
 
 	return c.Redirect(http.StatusTemporaryRedirect,
-		fmt.Sprintf("%s/auth/callback?token=%s", h.getAppURL(), authResp.Token))
+		fmt.Sprintf("%s/auth/callback?token=%s&refresh_token=%s", h.getAppURL(), authResp.Token, authResp.RefreshToken))
 }
 
 func (h *OAuthHandler) extractLinkUserID(c echo.Context) string {
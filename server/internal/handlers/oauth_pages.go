@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The consent, device and authorized-apps pages are part of the SPA. The
+// authorization server keeps its own URLs for them, because apps link to
+// those and the device flow shows one to the user, and redirects to the SPA
+// routes below.
+const (
+	oauthConsentAppPath = "/authorize"
+	oauthDeviceAppPath  = "/device"
+	oauthAppsAppPath    = "/settings/authorized-apps"
+)
+
+// ConsentPage godoc
+//
+//	@Summary		Consent page
+//	@Description	Redirect to the web app's page where the signed-in user allows or denies an app's authorization request. The authorization endpoint redirects here.
+//	@Tags			oauth-server
+//	@Param			request_id	query	string	true	"Authorization request ID"
+//	@Success		302
+//	@Router			/oauth/consent [get]
+func (h *OAuthServerHandler) ConsentPage(c echo.Context) error {
+	return redirectToOAuthAppPage(c, oauthConsentAppPath, "request_id", c.QueryParam("request_id"))
+}
+
+// DevicePage godoc
+//
+//	@Summary		Device code page
+//	@Description	Redirect to the web app's page where the signed-in user enters the code shown on a device and allows or denies it. This is the verification URI of the device authorization grant.
+//	@Tags			oauth-server
+//	@Param			user_code	query	string	false	"User code shown on the device"
+//	@Success		302
+//	@Router			/oauth/device [get]
+func (h *OAuthServerHandler) DevicePage(c echo.Context) error {
+	return redirectToOAuthAppPage(c, oauthDeviceAppPath, "user_code", c.QueryParam("user_code"))
+}
+
+// AppsPage godoc
+//
+//	@Summary		Authorized apps page
+//	@Description	Redirect to the web app's settings page listing the apps the signed-in user has authorized.
+//	@Tags			oauth-server
+//	@Success		302
+//	@Router			/oauth/apps [get]
+func (h *OAuthServerHandler) AppsPage(c echo.Context) error {
+	return redirectToOAuthAppPage(c, oauthAppsAppPath, "", "")
+}
+
+func redirectToOAuthAppPage(c echo.Context, path, param, value string) error {
+	if value != "" {
+		path += "?" + url.Values{param: {value}}.Encode()
+	}
+	// The request ID must not leak through the Referer.
+	header := c.Response().Header()
+	header.Set("Cache-Control", "no-store")
+	header.Set("Referrer-Policy", "no-referrer")
+	return c.Redirect(http.StatusFound, path)
+}
@@ -139,3 +139,77 @@ func (h *OAuthProviderHandler) Delete(c echo.Context) error {
 	}
 	return response.NoContent(c)
 }
+
+// GetClaimRules godoc
+//
+//	@Summary		Get OAuth claim rules
+//	@Description	Get the claim-to-role rules of an OAuth provider (instance admin only)
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Provider ID"
+//	@Success		200	{object}	response.APIResponse{data=dto.OAuthClaimRulesResponse}
+//	@Failure		400	{object}	response.APIResponse
+//	@Failure		401	{object}	response.APIResponse
+//	@Failure		403	{object}	response.APIResponse
+//	@Failure		404	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/admin/oauth-providers/{id}/claim-rules [get]
+func (h *OAuthProviderHandler) GetClaimRules(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+
+	rules, err := h.svc.GetClaimRules(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrOAuthProviderNotFoundByID) {
+			return response.NotFound(c, "err.oauth_provider_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_get_claim_rules")
+	}
+	return response.OK(c, rules)
+}
+
+// UpdateClaimRules godoc
+//
+//	@Summary		Replace OAuth claim rules
+//	@Description	Replace the claim-to-role rules of an OAuth provider. Rules manage the users of account_id and are re-applied on every login (instance admin only)
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id		path		int									true	"Provider ID"
+//	@Param			request	body		dto.UpdateOAuthClaimRulesRequest	true	"Claim rules"
+//	@Success		200		{object}	response.APIResponse{data=dto.OAuthClaimRulesResponse}
+//	@Failure		400		{object}	response.APIResponse
+//	@Failure		401		{object}	response.APIResponse
+//	@Failure		403		{object}	response.APIResponse
+//	@Failure		404		{object}	response.APIResponse
+//	@Failure		500		{object}	response.APIResponse
+//	@Router			/admin/oauth-providers/{id}/claim-rules [put]
+func (h *OAuthProviderHandler) UpdateClaimRules(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+
+	var req dto.UpdateOAuthClaimRulesRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+
+	rules, err := h.svc.SetClaimRules(uint(id), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOAuthProviderNotFoundByID):
+			return response.NotFound(c, "err.oauth_provider_not_found")
+		case errors.Is(err, services.ErrInvalidOAuthClaimRule):
+			return response.BadRequest(c, "err.invalid_oauth_claim_rule", nil)
+		case errors.Is(err, services.ErrOAuthClaimAccountNotFound):
+			return response.BadRequest(c, "err.oauth_claim_account_not_found", nil)
+		}
+		return response.InternalError(c, "err.failed_to_update_claim_rules")
+	}
+	return response.OK(c, rules)
+}
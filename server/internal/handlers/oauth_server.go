@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/i18n"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+type OAuthServerHandler struct {
+	service *services.OAuthServerService
+}
+
+func NewOAuthServerHandler(service *services.OAuthServerService) *OAuthServerHandler {
+	return &OAuthServerHandler{service: service}
+}
+
+// oauthErrorCodes maps service errors onto RFC 6749, 7591 and 8628 error
+// codes and the status each is reported with.
+var oauthErrorCodes = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{services.ErrOAuthInvalidRequest, "invalid_request", http.StatusBadRequest},
+	{services.ErrOAuthInvalidClient, "invalid_client", http.StatusUnauthorized},
+	{services.ErrOAuthInvalidGrant, "invalid_grant", http.StatusBadRequest},
+	{services.ErrOAuthUnauthorizedClient, "unauthorized_client", http.StatusBadRequest},
+	{services.ErrOAuthUnsupportedGrantType, "unsupported_grant_type", http.StatusBadRequest},
+	{services.ErrOAuthUnsupportedResponseType, "unsupported_response_type", http.StatusBadRequest},
+	{services.ErrOAuthInvalidScope, "invalid_scope", http.StatusBadRequest},
+	{services.ErrOAuthInvalidRedirectURI, "invalid_redirect_uri", http.StatusBadRequest},
+	{services.ErrOAuthInvalidClientMetadata, "invalid_client_metadata", http.StatusBadRequest},
+	{services.ErrOAuthRegistrationDisabled, "access_denied", http.StatusForbidden},
+	{services.ErrOAuthAuthorizationPending, "authorization_pending", http.StatusBadRequest},
+	{services.ErrOAuthSlowDown, "slow_down", http.StatusBadRequest},
+	{services.ErrOAuthAccessDenied, "access_denied", http.StatusBadRequest},
+	{services.ErrOAuthExpiredToken, "expired_token", http.StatusBadRequest},
+}
+
+func oauthErrorCode(err error) (string, int, string) {
+	for _, e := range oauthErrorCodes {
+		if errors.Is(err, e.err) {
+			return e.code, e.status, e.err.Error()
+		}
+	}
+	return "server_error", http.StatusInternalServerError, "the server failed to process the request"
+}
+
+func oauthError(c echo.Context, err error) error {
+	code, status, description := oauthErrorCode(err)
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(status, dto.OAuthErrorResponse{Error: code, ErrorDescription: description})
+}
+
+// clientCredentials prefers HTTP Basic credentials over form fields, as
+// client_secret_basic clients send them.
+func clientCredentials(c echo.Context, clientID, clientSecret *string) {
+	id, secret, ok := c.Request().BasicAuth()
+	if !ok {
+		return
+	}
+	if decoded, err := url.QueryUnescape(id); err == nil {
+		id = decoded
+	}
+	if decoded, err := url.QueryUnescape(secret); err == nil {
+		secret = decoded
+	}
+	*clientID, *clientSecret = id, secret
+}
+
+// AuthorizationServerMetadata serves the RFC 8414 discovery document. Like
+// the other protocol endpoints it lives outside /api and answers without the
+// API envelope.
+func (h *OAuthServerHandler) AuthorizationServerMetadata(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.service.Metadata())
+}
+
+// ProtectedResourceMetadata serves RFC 9728 metadata for the resource the
+// route is registered for, naming Bonds as its authorization server.
+func (h *OAuthServerHandler) ProtectedResourceMetadata(c echo.Context) error {
+	resource := strings.TrimPrefix(c.Path(), "/.well-known/oauth-protected-resource")
+	return c.JSON(http.StatusOK, h.service.ProtectedResourceMetadata(resource))
+}
+
+// Authorize is the authorization endpoint. Valid requests continue on the
+// consent page; errors are redirected to the client once its redirect URI is
+// verified and shown directly otherwise.
+func (h *OAuthServerHandler) Authorize(c echo.Context) error {
+	var params dto.OAuthAuthorizeParams
+	if err := c.Bind(&params); err != nil {
+		return oauthError(c, services.ErrOAuthInvalidRequest)
+	}
+	result, err := h.service.StartAuthorization(params)
+	if err != nil {
+		if result == nil {
+			return oauthError(c, err)
+		}
+		code, _, description := oauthErrorCode(err)
+		return c.Redirect(http.StatusFound, h.service.AuthorizationErrorURL(result, code, description))
+	}
+	return c.Redirect(http.StatusFound, h.service.ConsentURL(result.RequestID))
+}
+
+// Token is the token endpoint for the authorization code, refresh token and
+// device code grants.
+func (h *OAuthServerHandler) Token(c echo.Context) error {
+	var req dto.OAuthTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, services.ErrOAuthInvalidRequest)
+	}
+	clientCredentials(c, &req.ClientID, &req.ClientSecret)
+	resp, err := h.service.Token(req)
+	if err != nil {
+		return oauthError(c, err)
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Register is the RFC 7591 dynamic client registration endpoint.
+func (h *OAuthServerHandler) Register(c echo.Context) error {
+	var req dto.OAuthClientRegistrationRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, services.ErrOAuthInvalidClientMetadata)
+	}
+	resp, err := h.service.RegisterClient(req, nil)
+	if err != nil {
+		return oauthError(c, err)
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// DeviceAuthorization is the RFC 8628 device authorization endpoint.
+func (h *OAuthServerHandler) DeviceAuthorization(c echo.Context) error {
+	var req dto.OAuthDeviceAuthorizationRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, services.ErrOAuthInvalidRequest)
+	}
+	clientCredentials(c, &req.ClientID, &req.ClientSecret)
+	resp, err := h.service.StartDeviceAuthorization(req)
+	if err != nil {
+		return oauthError(c, err)
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Revoke is the RFC 7009 revocation endpoint.
+func (h *OAuthServerHandler) Revoke(c echo.Context) error {
+	var req dto.OAuthRevokeRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, services.ErrOAuthInvalidRequest)
+	}
+	clientCredentials(c, &req.ClientID, &req.ClientSecret)
+	if err := h.service.Revoke(req); err != nil {
+		return oauthError(c, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func localizeScopes(c echo.Context, consent *dto.OAuthConsentResponse) {
+	locale := middleware.GetLocale(c)
+	for i := range consent.Scopes {
+		consent.Scopes[i].Description = i18n.T(locale, "oauth.scope."+consent.Scopes[i].Name)
+	}
+}
+
+// GetAuthorization godoc
+//
+//	@Summary		Get a pending authorization
+//	@Description	Describe the app and scopes of an authorization request for the consent page.
+//	@Tags			oauth-server
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request_id	path		string	true	"Authorization request ID"
+//	@Success		200			{object}	response.APIResponse{data=dto.OAuthConsentResponse}
+//	@Failure		401			{object}	response.APIResponse
+//	@Failure		403			{object}	response.APIResponse
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/oauth/authorize/{request_id} [get]
+func (h *OAuthServerHandler) GetAuthorization(c echo.Context) error {
+	consent, err := h.service.GetAuthorization(c.Param("request_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrOAuthRequestNotFound) {
+			return response.NotFound(c, "err.oauth_request_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_get_oauth_request")
+	}
+	localizeScopes(c, consent)
+	return response.OK(c, consent)
+}
+
+// DecideAuthorization godoc
+//
+//	@Summary		Approve or deny an authorization
+//	@Description	Record the user's consent and return the URL to send the browser back to the app.
+//	@Tags			oauth-server
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request_id	path		string							true	"Authorization request ID"
+//	@Param			request		body		dto.OAuthConsentDecisionRequest	true	"Decision"
+//	@Success		200			{object}	response.APIResponse{data=dto.OAuthConsentDecisionResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		401			{object}	response.APIResponse
+//	@Failure		403			{object}	response.APIResponse
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/oauth/authorize/{request_id} [post]
+func (h *OAuthServerHandler) DecideAuthorization(c echo.Context) error {
+	var req dto.OAuthConsentDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	redirectURL, err := h.service.DecideAuthorization(c.Param("request_id"), middleware.GetUserID(c), req.Approve)
+	if err != nil {
+		if errors.Is(err, services.ErrOAuthRequestNotFound) {
+			return response.NotFound(c, "err.oauth_request_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_decide_oauth_request")
+	}
+	return response.OK(c, dto.OAuthConsentDecisionResponse{RedirectURL: redirectURL})
+}
+
+// GetDeviceAuthorization godoc
+//
+//	@Summary		Get a pending device authorization
+//	@Description	Describe the app and scopes behind a user code for the device consent page.
+//	@Tags			oauth-server
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			user_code	query		string	true	"User code shown on the device"
+//	@Success		200			{object}	response.APIResponse{data=dto.OAuthConsentResponse}
+//	@Failure		401			{object}	response.APIResponse
+//	@Failure		403			{object}	response.APIResponse
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/oauth/device [get]
+func (h *OAuthServerHandler) GetDeviceAuthorization(c echo.Context) error {
+	consent, err := h.service.GetDeviceAuthorization(c.QueryParam("user_code"))
+	if err != nil {
+		if errors.Is(err, services.ErrOAuthDeviceCodeNotFound) {
+			return response.NotFound(c, "err.oauth_device_code_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_get_oauth_request")
+	}
+	localizeScopes(c, consent)
+	return response.OK(c, consent)
+}
+
+// DecideDeviceAuthorization godoc
+//
+//	@Summary		Approve or deny a device
+//	@Description	Record the user's consent for a user code. The device receives its tokens on its next poll.
+//	@Tags			oauth-server
+//	@Accept			json
+//	@Security		BearerAuth
+//	@Param			request	body	dto.OAuthDeviceDecisionRequest	true	"Decision"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	response.APIResponse
+//	@Failure		401		{object}	response.APIResponse
+//	@Failure		403		{object}	response.APIResponse
+//	@Failure		404		{object}	response.APIResponse
+//	@Failure		422		{object}	response.APIResponse
+//	@Failure		500		{object}	response.APIResponse
+//	@Router			/oauth/device [post]
+func (h *OAuthServerHandler) DecideDeviceAuthorization(c echo.Context) error {
+	var req dto.OAuthDeviceDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if err := validateRequest(req); err != nil {
+		return response.ValidationError(c, map[string]string{"validation": err.Error()})
+	}
+	if err := h.service.DecideDeviceAuthorization(req.UserCode, middleware.GetUserID(c), req.Approve); err != nil {
+		if errors.Is(err, services.ErrOAuthDeviceCodeNotFound) {
+			return response.NotFound(c, "err.oauth_device_code_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_decide_oauth_request")
+	}
+	return response.NoContent(c)
+}
+
+// ListAuthorizedApps godoc
+//
+//	@Summary		List authorized apps
+//	@Description	Return the third-party apps the authenticated user has granted access to.
+//	@Tags			oauth-server
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	response.APIResponse{data=[]dto.AuthorizedAppResponse}
+//	@Failure		401	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/settings/authorized-apps [get]
+func (h *OAuthServerHandler) ListAuthorizedApps(c echo.Context) error {
+	apps, err := h.service.ListAuthorizedApps(middleware.GetUserID(c))
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_list_authorized_apps")
+	}
+	return response.OK(c, apps)
+}
+
+// RevokeAuthorizedApp godoc
+//
+//	@Summary		Revoke an authorized app
+//	@Description	Withdraw an app's access. Its access and refresh tokens stop working immediately.
+//	@Tags			oauth-server
+//	@Security		BearerAuth
+//	@Param			id	path	integer	true	"Authorized app ID"
+//	@Success		204	"No Content"
+//	@Failure		400	{object}	response.APIResponse
+//	@Failure		401	{object}	response.APIResponse
+//	@Failure		404	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/settings/authorized-apps/{id} [delete]
+func (h *OAuthServerHandler) RevokeAuthorizedApp(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_authorized_app_id", nil)
+	}
+	if err := h.service.RevokeAuthorizedApp(middleware.GetUserID(c), uint(id)); err != nil {
+		if errors.Is(err, services.ErrOAuthGrantNotFound) {
+			return response.NotFound(c, "err.authorized_app_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_revoke_authorized_app")
+	}
+	return response.NoContent(c)
+}
+
+// ListClients godoc
+//
+//	@Summary		List OAuth clients (admin)
+//	@Description	Return every app registered with the authorization server, including dynamically registered ones.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	response.APIResponse{data=[]dto.OAuthClientResponse}
+//	@Failure		401	{object}	response.APIResponse
+//	@Failure		403	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/admin/oauth-clients [get]
+func (h *OAuthServerHandler) ListClients(c echo.Context) error {
+	clients, err := h.service.ListClients()
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_list_oauth_clients")
+	}
+	return response.OK(c, clients)
+}
+
+// CreateClient godoc
+//
+//	@Summary		Register an OAuth client (admin)
+//	@Description	Register an app by hand, regardless of the dynamic registration setting. The client secret of confidential clients is only returned once.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		dto.OAuthClientRegistrationRequest	true	"Client metadata"
+//	@Success		201		{object}	response.APIResponse{data=dto.OAuthClientRegistrationResponse}
+//	@Failure		400		{object}	response.APIResponse
+//	@Failure		401		{object}	response.APIResponse
+//	@Failure		403		{object}	response.APIResponse
+//	@Failure		500		{object}	response.APIResponse
+//	@Router			/admin/oauth-clients [post]
+func (h *OAuthServerHandler) CreateClient(c echo.Context) error {
+	var req dto.OAuthClientRegistrationRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	userID := middleware.GetUserID(c)
+	client, err := h.service.RegisterClient(req, &userID)
+	if err != nil {
+		if errors.Is(err, services.ErrOAuthInvalidRedirectURI) {
+			return response.BadRequest(c, "err.invalid_oauth_redirect_uri", nil)
+		}
+		if errors.Is(err, services.ErrOAuthInvalidClientMetadata) {
+			return response.BadRequest(c, "err.invalid_oauth_client_metadata", nil)
+		}
+		return response.InternalError(c, "err.failed_to_create_oauth_client")
+	}
+	return response.Created(c, client)
+}
+
+// DeleteClient godoc
+//
+//	@Summary		Delete an OAuth client (admin)
+//	@Description	Remove an app together with every user's grant to it.
+//	@Tags			admin
+//	@Security		BearerAuth
+//	@Param			id	path	string	true	"Client ID"
+//	@Success		204	"No Content"
+//	@Failure		401	{object}	response.APIResponse
+//	@Failure		403	{object}	response.APIResponse
+//	@Failure		404	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/admin/oauth-clients/{id} [delete]
+func (h *OAuthServerHandler) DeleteClient(c echo.Context) error {
+	if err := h.service.DeleteClient(c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrOAuthClientNotFound) {
+			return response.NotFound(c, "err.oauth_client_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_delete_oauth_client")
+	}
+	return response.NoContent(c)
+}
+
+// BearerChallenge points clients that fail authentication at the resource
+// metadata, which is how MCP clients find out where to authorize.
+func (h *OAuthServerHandler) BearerChallenge(resourcePath string) echo.MiddlewareFunc {
+	challenge := `Bearer resource_metadata="` + h.service.Issuer() + "/.well-known/oauth-protected-resource" + resourcePath + `"`
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			res := c.Response()
+			res.Before(func() {
+				if res.Status == http.StatusUnauthorized {
+					res.Header().Set("WWW-Authenticate", challenge)
+				}
+			})
+			return next(c)
+		}
+	}
+}
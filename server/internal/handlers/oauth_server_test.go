@@ -0,0 +1,229 @@
+package handlers_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+)
+
+func (ts *testServer) enableDynamicRegistration(t *testing.T) {
+	t.Helper()
+	if err := services.NewSystemSettingService(ts.db).Set("oauth_server.dynamic_registration", "true"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+}
+
+func (ts *testServer) doFormRequest(path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	ts.e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOAuthServer_MCPClientFlow(t *testing.T) {
+	ts := setupTestServer(t)
+	ts.e.Use(middleware.Locale())
+	ts.enableDynamicRegistration(t)
+	token, _ := ts.registerTestUser(t, "oauth-flow@example.com")
+
+	// An MCP client starts without credentials and follows the challenge.
+	rec := ts.doRequest(http.MethodPost, "/mcp", `{"jsonrpc":"2.0","id":1,"method":"ping"}`, "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+	challenge := rec.Header().Get("WWW-Authenticate")
+	if !strings.Contains(challenge, `resource_metadata="http://localhost:8080/.well-known/oauth-protected-resource/mcp"`) {
+		t.Fatalf("unexpected challenge %q", challenge)
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/.well-known/oauth-protected-resource/mcp", "", "")
+	var resource struct {
+		Resource             string   `json:"resource"`
+		AuthorizationServers []string `json:"authorization_servers"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resource); err != nil || resource.Resource != "http://localhost:8080/mcp" || len(resource.AuthorizationServers) != 1 {
+		t.Fatalf("unexpected resource metadata %s", rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/.well-known/oauth-authorization-server", "", "")
+	var meta struct {
+		RegistrationEndpoint string `json:"registration_endpoint"`
+		TokenEndpoint        string `json:"token_endpoint"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &meta); err != nil || meta.RegistrationEndpoint != "http://localhost:8080/oauth/register" || meta.TokenEndpoint != "http://localhost:8080/oauth/token" {
+		t.Fatalf("unexpected server metadata %s", rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodPost, "/oauth/register", `{"client_name":"Agent","redirect_uris":["http://127.0.0.1:33418/callback"],"token_endpoint_auth_method":"none"}`, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var client struct {
+		ClientID string `json:"client_id"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &client)
+
+	verifier := strings.Repeat("v", 50)
+	sum := sha256.Sum256([]byte(verifier))
+	authorize := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ClientID},
+		"redirect_uri":          {"http://127.0.0.1:33418/callback"},
+		"state":                 {"abc"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	rec = ts.doRequest(http.MethodGet, "/oauth/authorize?"+authorize.Encode(), "", "")
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected a redirect to the consent page, got %d %s", rec.Code, rec.Body.String())
+	}
+	consentURL, _ := url.Parse(rec.Header().Get("Location"))
+	requestID := consentURL.Query().Get("request_id")
+	if consentURL.Path != "/oauth/consent" || requestID == "" {
+		t.Fatalf("unexpected consent redirect %q", consentURL)
+	}
+	rec = ts.doRequest(http.MethodGet, consentURL.RequestURI(), "", "")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/authorize?request_id="+url.QueryEscape(requestID) {
+		t.Fatalf("expected a redirect to the consent page of the web app, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = ts.doRequestWithLocale(http.MethodGet, "/api/oauth/authorize/"+requestID, "", token, "de")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get consent failed: %d %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "Vollzugriff") {
+		t.Errorf("expected localized scope descriptions, got %s", rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodPost, "/api/oauth/authorize/"+requestID, `{"approve":true}`, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("approve failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var decision struct {
+		RedirectURL string `json:"redirect_url"`
+	}
+	json.Unmarshal(parseResponse(t, rec).Data, &decision)
+	callback, _ := url.Parse(decision.RedirectURL)
+	if callback.Query().Get("state") != "abc" || callback.Query().Get("code") == "" {
+		t.Fatalf("unexpected callback %q", decision.RedirectURL)
+	}
+
+	rec = ts.doFormRequest("/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {client.ClientID},
+		"code":          {callback.Query().Get("code")},
+		"redirect_uri":  {"http://127.0.0.1:33418/callback"},
+		"code_verifier": {verifier},
+	})
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("token failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &tokens)
+
+	rec = ts.doRequest(http.MethodPost, "/mcp", `{"jsonrpc":"2.0","id":1,"method":"ping"}`, tokens.AccessToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the access token to work on /mcp, got %d %s", rec.Code, rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/oauth/authorize/"+requestID, "", tokens.AccessToken)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected app tokens to be refused on the consent API, got %d", rec.Code)
+	}
+
+	rec = ts.doRequest(http.MethodGet, "/api/settings/authorized-apps", "", token)
+	var apps []struct {
+		ID         uint   `json:"id"`
+		ClientName string `json:"client_name"`
+	}
+	json.Unmarshal(parseResponse(t, rec).Data, &apps)
+	if len(apps) != 1 || apps[0].ClientName != "Agent" {
+		t.Fatalf("unexpected authorized apps %s", rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodDelete, "/api/settings/authorized-apps/"+strconv.FormatUint(uint64(apps[0].ID), 10), "", token)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke failed: %d %s", rec.Code, rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/api/vaults", "", tokens.AccessToken)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected the revoked access token to be rejected, got %d", rec.Code)
+	}
+	rec = ts.doFormRequest("/oauth/token", url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {client.ClientID},
+		"refresh_token": {tokens.RefreshToken},
+	})
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"invalid_grant"`) {
+		t.Errorf("expected invalid_grant for the revoked refresh token, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestOAuthServer_AuthorizeErrors(t *testing.T) {
+	ts := setupTestServer(t)
+	ts.enableDynamicRegistration(t)
+
+	rec := ts.doRequest(http.MethodGet, "/oauth/authorize?response_type=code&client_id=unknown", "", "")
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `"invalid_client"`) {
+		t.Fatalf("expected unknown clients to get an error page, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = ts.doRequest(http.MethodPost, "/oauth/register", `{"redirect_uris":["https://app.example.com/cb"],"token_endpoint_auth_method":"none"}`, "")
+	var client struct {
+		ClientID string `json:"client_id"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &client)
+
+	// Once the redirect URI is verified, errors go back to the client.
+	rec = ts.doRequest(http.MethodGet, "/oauth/authorize?response_type=code&state=s1&client_id="+client.ClientID, "", "")
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || location.Host != "app.example.com" || location.Query().Get("error") != "invalid_request" || location.Query().Get("state") != "s1" {
+		t.Errorf("expected a missing PKCE challenge to be reported to the client, got %d %q", rec.Code, location)
+	}
+
+	rec = ts.doFormRequest("/oauth/token", url.Values{"grant_type": {"password"}, "client_id": {client.ClientID}})
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"unsupported_grant_type"`) {
+		t.Errorf("expected unsupported_grant_type, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestOAuthServer_DynamicRegistrationOffByDefault(t *testing.T) {
+	ts := setupTestServer(t)
+
+	rec := ts.doRequest(http.MethodPost, "/oauth/register", `{"redirect_uris":["https://app.example.com/cb"]}`, "")
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected dynamic registration to be refused, got %d %s", rec.Code, rec.Body.String())
+	}
+	rec = ts.doRequest(http.MethodGet, "/.well-known/oauth-authorization-server", "", "")
+	if strings.Contains(rec.Body.String(), "registration_endpoint") {
+		t.Errorf("expected no registration endpoint in the metadata, got %s", rec.Body.String())
+	}
+}
+
+func TestOAuthServer_PagesRedirectToWebApp(t *testing.T) {
+	ts := setupTestServer(t)
+
+	for path, want := range map[string]string{
+		"/oauth/device?user_code=BCDF-GHJK": "/device?user_code=BCDF-GHJK",
+		"/oauth/device":                     "/device",
+		"/oauth/apps":                       "/settings/authorized-apps",
+		"/oauth/device?user_code=//evil.example.com/?x=1": "/device?user_code=%2F%2Fevil.example.com%2F%3Fx%3D1",
+	} {
+		rec := ts.doRequest(http.MethodGet, path, "", "")
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != want {
+			t.Errorf("%s: expected a redirect to %s, got %d %q", path, want, rec.Code, rec.Header().Get("Location"))
+		}
+		if rec.Header().Get("Cache-Control") != "no-store" || rec.Header().Get("Referrer-Policy") != "no-referrer" {
+			t.Errorf("%s: expected the redirect not to be cached or leak its URL", path)
+		}
+	}
+}
@@ -8,16 +8,27 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/naiba/bonds/internal/middleware"
 	"github.com/naiba/bonds/internal/models"
 )
 
-func generateJWT(userID, accountID, email string, isAdmin bool, twoFactorPending bool) string {
-	return generateJWTFull(userID, accountID, email, isAdmin, false, twoFactorPending)
+// generateJWT creates a session for the user and signs an access token for it.
+func (ts *testServer) generateJWT(userID, accountID, email string, isAdmin bool, twoFactorPending bool) string {
+	return ts.generateJWTFull(userID, accountID, email, isAdmin, false, twoFactorPending)
 }
 
 // generateJWTFull creates a JWT with all claim fields including IsInstanceAdmin.
-func generateJWTFull(userID, accountID, email string, isAdmin, isInstanceAdmin, twoFactorPending bool) string {
+func (ts *testServer) generateJWTFull(userID, accountID, email string, isAdmin, isInstanceAdmin, twoFactorPending bool) string {
+	session := models.UserSession{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		LastUsedAt: time.Now(),
+		ExpiresAt:  time.Now().Add(24 * time.Hour),
+	}
+	if err := ts.db.Create(&session).Error; err != nil {
+		panic("failed to create test session: " + err.Error())
+	}
 	claims := &middleware.JWTClaims{
 		UserID:           userID,
 		AccountID:        accountID,
@@ -26,6 +37,7 @@ func generateJWTFull(userID, accountID, email string, isAdmin, isInstanceAdmin,
 		IsInstanceAdmin:  isInstanceAdmin,
 		TwoFactorPending: twoFactorPending,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        session.ID,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -94,7 +106,7 @@ func TestTwoFactorPendingTokenBlocked(t *testing.T) {
 
 	_, auth := ts.registerTestUser(t, "2fa-block@example.com")
 
-	pendingToken := generateJWT(auth.User.ID, auth.User.AccountID, auth.User.Email, auth.User.IsAdmin, true)
+	pendingToken := ts.generateJWT(auth.User.ID, auth.User.AccountID, auth.User.Email, auth.User.IsAdmin, true)
 
 	rec := ts.doRequest(http.MethodGet, "/api/vaults", "", pendingToken)
 	if rec.Code != http.StatusForbidden {
@@ -122,7 +134,7 @@ func TestViewerCannotCreateContact(t *testing.T) {
 	user2 := createSecondUser(t, ts, auth1.User.AccountID, "viewer-test-user2@example.com", false)
 	addUserToVault(t, ts, user2.ID, vault.ID, models.PermissionViewer)
 
-	token2 := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token2 := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	rec := ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts", "", token2)
 	if rec.Code != http.StatusOK {
@@ -145,7 +157,7 @@ func TestEditorCanCreateContact(t *testing.T) {
 	user2 := createSecondUser(t, ts, auth1.User.AccountID, "editor-test-user2@example.com", false)
 	addUserToVault(t, ts, user2.ID, vault.ID, models.PermissionEditor)
 
-	token2 := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token2 := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	body := `{"first_name":"EditorContact","last_name":"Doe"}`
 	rec := ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/contacts", body, token2)
@@ -165,7 +177,7 @@ func TestNonAdminCanReadButCannotMutateSharedAccountData(t *testing.T) {
 	token1, auth1 := ts.registerTestUser(t, "personalize-admin@example.com")
 
 	user2 := createSecondUser(t, ts, auth1.User.AccountID, "personalize-nonadmin@example.com", false)
-	token2 := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token2 := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	rec := ts.doRequest(http.MethodGet, "/api/settings/personalize/genders", "", token2)
 	if rec.Code != http.StatusOK {
@@ -192,7 +204,7 @@ func TestAuthenticatedUserCanAccessPetCategories(t *testing.T) {
 	ts := setupTestServer(t)
 	_, auth := ts.registerTestUser(t, "pet-categories-user@example.com")
 	user2 := createSecondUser(t, ts, auth.User.AccountID, "pet-categories-user2@example.com", false)
-	token := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	rec := ts.doRequest(http.MethodGet, "/api/pet-categories", "", token)
 	if rec.Code != http.StatusOK {
@@ -206,7 +218,7 @@ func TestNonAdminCannotAccessInvitations(t *testing.T) {
 	token1, auth1 := ts.registerTestUser(t, "invitations-admin@example.com")
 
 	user2 := createSecondUser(t, ts, auth1.User.AccountID, "invitations-nonadmin@example.com", false)
-	token2 := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token2 := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	rec := ts.doRequest(http.MethodGet, "/api/settings/invitations", "", token2)
 	if rec.Code != http.StatusForbidden {
@@ -515,7 +527,7 @@ func setupViewerTest(t *testing.T) (ts *testServer, adminToken string, viewerTok
 
 	viewer := createSecondUser(t, ts, auth.User.AccountID, "viewer-perm-user@example.com", false)
 	addUserToVault(t, ts, viewer.ID, vault.ID, models.PermissionViewer)
-	viewerToken = generateJWT(viewer.ID, viewer.AccountID, viewer.Email, false, false)
+	viewerToken = ts.generateJWT(viewer.ID, viewer.AccountID, viewer.Email, false, false)
 
 	return ts, adminToken, viewerToken, vault.ID, contact.ID
 }
@@ -640,7 +652,7 @@ func TestEditorCannotDeleteVault(t *testing.T) {
 
 	editor := createSecondUser(t, ts, auth1.User.AccountID, "editor-del-vault-user@example.com", false)
 	addUserToVault(t, ts, editor.ID, vault.ID, models.PermissionEditor)
-	editorToken := generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
+	editorToken := ts.generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
 
 	rec := ts.doRequest(http.MethodDelete, "/api/vaults/"+vault.ID, "", editorToken)
 	if rec.Code != http.StatusForbidden {
@@ -657,7 +669,7 @@ func TestEditorCanCreateUpdateDeleteNotes(t *testing.T) {
 
 	editor := createSecondUser(t, ts, auth1.User.AccountID, "editor-notes-user@example.com", false)
 	addUserToVault(t, ts, editor.ID, vault.ID, models.PermissionEditor)
-	editorToken := generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
+	editorToken := ts.generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
 
 	basePath := fmt.Sprintf("/api/vaults/%s/contacts/%s/notes", vault.ID, contact.ID)
 
@@ -691,7 +703,7 @@ func TestEditorCanCreateUpdateDeleteContacts(t *testing.T) {
 
 	editor := createSecondUser(t, ts, auth1.User.AccountID, "editor-contacts-user@example.com", false)
 	addUserToVault(t, ts, editor.ID, vault.ID, models.PermissionEditor)
-	editorToken := generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
+	editorToken := ts.generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
 
 	rec := ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/contacts",
 		`{"first_name":"EditorCreated","last_name":"Contact"}`, editorToken)
@@ -726,7 +738,7 @@ func TestNoVaultAccessUserCannotRead(t *testing.T) {
 	ts.createTestContact(t, token1, vault.ID, "Restricted")
 
 	user2 := createSecondUser(t, ts, auth1.User.AccountID, "no-vault-access-user@example.com", false)
-	token2 := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token2 := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	rec := ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts", "", token2)
 	if rec.Code != http.StatusForbidden {
@@ -741,7 +753,7 @@ func TestNoVaultAccessUserCannotWrite(t *testing.T) {
 	vault := ts.createTestVault(t, token1, "Write Restricted Vault")
 
 	user2 := createSecondUser(t, ts, auth1.User.AccountID, "no-vault-write-user@example.com", false)
-	token2 := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token2 := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	rec := ts.doRequest(http.MethodPost, "/api/vaults/"+vault.ID+"/contacts",
 		`{"first_name":"Blocked","last_name":"User"}`, token2)
@@ -756,7 +768,7 @@ func TestTwoFactorPendingCannotAccessSettings(t *testing.T) {
 	ts := setupTestServer(t)
 
 	_, auth := ts.registerTestUser(t, "2fa-settings-block@example.com")
-	pendingToken := generateJWT(auth.User.ID, auth.User.AccountID, auth.User.Email, auth.User.IsAdmin, true)
+	pendingToken := ts.generateJWT(auth.User.ID, auth.User.AccountID, auth.User.Email, auth.User.IsAdmin, true)
 
 	rec := ts.doRequest(http.MethodGet, "/api/settings/preferences", "", pendingToken)
 	if rec.Code != http.StatusForbidden {
@@ -768,7 +780,7 @@ func TestTwoFactorPendingCannotCreateVault(t *testing.T) {
 	ts := setupTestServer(t)
 
 	_, auth := ts.registerTestUser(t, "2fa-vault-block@example.com")
-	pendingToken := generateJWT(auth.User.ID, auth.User.AccountID, auth.User.Email, auth.User.IsAdmin, true)
+	pendingToken := ts.generateJWT(auth.User.ID, auth.User.AccountID, auth.User.Email, auth.User.IsAdmin, true)
 
 	rec := ts.doRequest(http.MethodPost, "/api/vaults", `{"name":"Blocked","description":"nope"}`, pendingToken)
 	if rec.Code != http.StatusForbidden {
@@ -782,7 +794,7 @@ func TestTwoFactorPendingCannotAccessContacts(t *testing.T) {
 	token, auth := ts.registerTestUser(t, "2fa-contacts-block@example.com")
 	vault := ts.createTestVault(t, token, "2FA Contact Vault")
 
-	pendingToken := generateJWT(auth.User.ID, auth.User.AccountID, auth.User.Email, auth.User.IsAdmin, true)
+	pendingToken := ts.generateJWT(auth.User.ID, auth.User.AccountID, auth.User.Email, auth.User.IsAdmin, true)
 
 	rec := ts.doRequest(http.MethodGet, "/api/vaults/"+vault.ID+"/contacts", "", pendingToken)
 	if rec.Code != http.StatusForbidden {
@@ -797,7 +809,7 @@ func TestNonAdminCannotCreatePersonalize(t *testing.T) {
 
 	_, auth := ts.registerTestUser(t, "nonadmin-create-pers-admin@example.com")
 	user2 := createSecondUser(t, ts, auth.User.AccountID, "nonadmin-create-pers@example.com", false)
-	token2 := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token2 := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	rec := ts.doRequest(http.MethodPost, "/api/settings/personalize/genders", `{"name":"Custom"}`, token2)
 	if rec.Code != http.StatusForbidden {
@@ -810,7 +822,7 @@ func TestNonAdminCannotUpdatePersonalize(t *testing.T) {
 
 	_, auth := ts.registerTestUser(t, "nonadmin-update-pers-admin@example.com")
 	user2 := createSecondUser(t, ts, auth.User.AccountID, "nonadmin-update-pers@example.com", false)
-	token2 := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token2 := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	rec := ts.doRequest(http.MethodPut, "/api/settings/personalize/genders/1", `{"name":"Updated"}`, token2)
 	if rec.Code != http.StatusForbidden {
@@ -823,7 +835,7 @@ func TestNonAdminCannotDeletePersonalize(t *testing.T) {
 
 	_, auth := ts.registerTestUser(t, "nonadmin-delete-pers-admin@example.com")
 	user2 := createSecondUser(t, ts, auth.User.AccountID, "nonadmin-delete-pers@example.com", false)
-	token2 := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token2 := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	rec := ts.doRequest(http.MethodDelete, "/api/settings/personalize/genders/1", "", token2)
 	if rec.Code != http.StatusForbidden {
@@ -836,7 +848,7 @@ func TestNonAdminCannotCreateInvitation(t *testing.T) {
 
 	_, auth := ts.registerTestUser(t, "nonadmin-create-inv-admin@example.com")
 	user2 := createSecondUser(t, ts, auth.User.AccountID, "nonadmin-create-inv@example.com", false)
-	token2 := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token2 := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	rec := ts.doRequest(http.MethodPost, "/api/settings/invitations",
 		`{"email":"invited@example.com","permission":200}`, token2)
@@ -850,7 +862,7 @@ func TestNonAdminCannotDeleteInvitation(t *testing.T) {
 
 	_, auth := ts.registerTestUser(t, "nonadmin-delete-inv-admin@example.com")
 	user2 := createSecondUser(t, ts, auth.User.AccountID, "nonadmin-delete-inv@example.com", false)
-	token2 := generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
+	token2 := ts.generateJWT(user2.ID, user2.AccountID, user2.Email, false, false)
 
 	rec := ts.doRequest(http.MethodDelete, "/api/settings/invitations/1", "", token2)
 	if rec.Code != http.StatusForbidden {
@@ -1713,7 +1725,7 @@ func setupEditorTest(t *testing.T) (ts *testServer, adminToken string, editorTok
 
 	editor := createSecondUser(t, ts, auth.User.AccountID, "editor-perm-user@example.com", false)
 	addUserToVault(t, ts, editor.ID, vault.ID, models.PermissionEditor)
-	editorToken = generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
+	editorToken = ts.generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
 
 	return ts, adminToken, editorToken, vault.ID, contact.ID
 }
@@ -2290,7 +2302,7 @@ func TestBulkContactMoveTargetVaultInsufficientPermissionReturns403(t *testing.T
 	editor := createSecondUser(t, ts, auth.User.AccountID, "bulk-move-target-viewer-editor@example.com", false)
 	addUserToVault(t, ts, editor.ID, sourceVault.ID, models.PermissionEditor)
 	addUserToVault(t, ts, editor.ID, targetVault.ID, models.PermissionViewer)
-	editorToken := generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
+	editorToken := ts.generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
 
 	path := fmt.Sprintf("/api/vaults/%s/contacts/move", sourceVault.ID)
 	rec := ts.doRequest(http.MethodPost, path, fmt.Sprintf(`{"contact_ids":[%q],"target_vault_id":%q}`, contact.ID, targetVault.ID), editorToken)
@@ -2326,7 +2338,7 @@ func TestContactMoveTargetVaultForbiddenReturns403(t *testing.T) {
 
 	editor := createSecondUser(t, ts, auth.User.AccountID, "move-target-source-editor@example.com", false)
 	addUserToVault(t, ts, editor.ID, sourceVault.ID, models.PermissionEditor)
-	editorToken := generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
+	editorToken := ts.generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
 
 	path := fmt.Sprintf("/api/vaults/%s/contacts/%s/move", sourceVault.ID, contact.ID)
 	rec := ts.doRequest(http.MethodPost, path, fmt.Sprintf(`{"target_vault_id":%q}`, targetVault.ID), editorToken)
@@ -2349,7 +2361,7 @@ func TestContactMoveTargetVaultInsufficientPermissionReturns403(t *testing.T) {
 	editor := createSecondUser(t, ts, auth.User.AccountID, "move-target-viewer-editor@example.com", false)
 	addUserToVault(t, ts, editor.ID, sourceVault.ID, models.PermissionEditor)
 	addUserToVault(t, ts, editor.ID, targetVault.ID, models.PermissionViewer)
-	editorToken := generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
+	editorToken := ts.generateJWT(editor.ID, editor.AccountID, editor.Email, false, false)
 
 	path := fmt.Sprintf("/api/vaults/%s/contacts/%s/move", sourceVault.ID, contact.ID)
 	rec := ts.doRequest(http.MethodPost, path, fmt.Sprintf(`{"target_vault_id":%q}`, targetVault.ID), editorToken)
@@ -2512,7 +2524,7 @@ func TestNonAdminCannotListUsers(t *testing.T) {
 	ts := setupTestServer(t)
 	_, authResp := ts.registerTestUser(t, "admin-user-mgmt@example.com")
 	nonAdminUser := createSecondUser(t, ts, authResp.User.AccountID, "nonadmin-user-mgmt@example.com", false)
-	nonAdminToken := generateJWT(nonAdminUser.ID, nonAdminUser.AccountID, nonAdminUser.Email, false, false)
+	nonAdminToken := ts.generateJWT(nonAdminUser.ID, nonAdminUser.AccountID, nonAdminUser.Email, false, false)
 
 	rec := ts.doRequest(http.MethodGet, "/api/settings/users", "", nonAdminToken)
 	if rec.Code != http.StatusForbidden {
@@ -2524,7 +2536,7 @@ func TestNonAdminCannotUpdateUser(t *testing.T) {
 	ts := setupTestServer(t)
 	_, authResp := ts.registerTestUser(t, "admin-usr-upd@example.com")
 	nonAdminUser := createSecondUser(t, ts, authResp.User.AccountID, "nonadmin-usr-upd@example.com", false)
-	nonAdminToken := generateJWT(nonAdminUser.ID, nonAdminUser.AccountID, nonAdminUser.Email, false, false)
+	nonAdminToken := ts.generateJWT(nonAdminUser.ID, nonAdminUser.AccountID, nonAdminUser.Email, false, false)
 
 	path := fmt.Sprintf("/api/settings/users/%s", nonAdminUser.ID)
 	body := `{"first_name":"Hacked"}`
@@ -2538,7 +2550,7 @@ func TestNonAdminCannotDeleteUser(t *testing.T) {
 	ts := setupTestServer(t)
 	_, authResp := ts.registerTestUser(t, "admin-usr-del@example.com")
 	nonAdminUser := createSecondUser(t, ts, authResp.User.AccountID, "nonadmin-usr-del@example.com", false)
-	nonAdminToken := generateJWT(nonAdminUser.ID, nonAdminUser.AccountID, nonAdminUser.Email, false, false)
+	nonAdminToken := ts.generateJWT(nonAdminUser.ID, nonAdminUser.AccountID, nonAdminUser.Email, false, false)
 
 	path := fmt.Sprintf("/api/settings/users/%s", nonAdminUser.ID)
 	rec := ts.doRequest(http.MethodDelete, path, "", nonAdminToken)
@@ -2553,7 +2565,7 @@ func TestNonAdminCannotCancelAccount(t *testing.T) {
 	ts := setupTestServer(t)
 	_, authResp := ts.registerTestUser(t, "admin-cancel@example.com")
 	nonAdminUser := createSecondUser(t, ts, authResp.User.AccountID, "nonadmin-cancel@example.com", false)
-	nonAdminToken := generateJWT(nonAdminUser.ID, nonAdminUser.AccountID, nonAdminUser.Email, false, false)
+	nonAdminToken := ts.generateJWT(nonAdminUser.ID, nonAdminUser.AccountID, nonAdminUser.Email, false, false)
 
 	body := `{"password":"password123"}`
 	rec := ts.doRequest(http.MethodDelete, "/api/settings/account", body, nonAdminToken)
@@ -3092,7 +3104,7 @@ func TestNonInstanceAdminCannotListAdminUsers(t *testing.T) {
 	ts := setupTestServer(t)
 	auth := registerNonInstanceAdminTestUser(t, ts, "non-ia-list-users@example.com")
 	// Account admin but NOT instance admin
-	token := generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
+	token := ts.generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
 
 	rec := ts.doRequest(http.MethodGet, "/api/admin/users", "", token)
 	if rec.Code != http.StatusForbidden {
@@ -3103,7 +3115,7 @@ func TestNonInstanceAdminCannotListAdminUsers(t *testing.T) {
 func TestNonInstanceAdminCannotToggleUser(t *testing.T) {
 	ts := setupTestServer(t)
 	auth := registerNonInstanceAdminTestUser(t, ts, "non-ia-toggle@example.com")
-	token := generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
+	token := ts.generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
 
 	rec := ts.doRequest(http.MethodPut, "/api/admin/users/fake-id/toggle", "", token)
 	if rec.Code != http.StatusForbidden {
@@ -3114,7 +3126,7 @@ func TestNonInstanceAdminCannotToggleUser(t *testing.T) {
 func TestNonInstanceAdminCannotSetAdmin(t *testing.T) {
 	ts := setupTestServer(t)
 	auth := registerNonInstanceAdminTestUser(t, ts, "non-ia-setadmin@example.com")
-	token := generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
+	token := ts.generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
 
 	rec := ts.doRequest(http.MethodPut, "/api/admin/users/fake-id/admin", `{"is_instance_admin":true}`, token)
 	if rec.Code != http.StatusForbidden {
@@ -3125,7 +3137,7 @@ func TestNonInstanceAdminCannotSetAdmin(t *testing.T) {
 func TestNonInstanceAdminCannotDeleteUser(t *testing.T) {
 	ts := setupTestServer(t)
 	auth := registerNonInstanceAdminTestUser(t, ts, "non-ia-deluser@example.com")
-	token := generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
+	token := ts.generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
 
 	rec := ts.doRequest(http.MethodDelete, "/api/admin/users/fake-id", "", token)
 	if rec.Code != http.StatusForbidden {
@@ -3136,7 +3148,7 @@ func TestNonInstanceAdminCannotDeleteUser(t *testing.T) {
 func TestNonInstanceAdminCannotGetAdminSettings(t *testing.T) {
 	ts := setupTestServer(t)
 	auth := registerNonInstanceAdminTestUser(t, ts, "non-ia-settings@example.com")
-	token := generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
+	token := ts.generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
 
 	rec := ts.doRequest(http.MethodGet, "/api/admin/settings", "", token)
 	if rec.Code != http.StatusForbidden {
@@ -3147,7 +3159,7 @@ func TestNonInstanceAdminCannotGetAdminSettings(t *testing.T) {
 func TestNonInstanceAdminCannotUpdateAdminSettings(t *testing.T) {
 	ts := setupTestServer(t)
 	auth := registerNonInstanceAdminTestUser(t, ts, "non-ia-upd-settings@example.com")
-	token := generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
+	token := ts.generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
 
 	rec := ts.doRequest(http.MethodPut, "/api/admin/settings", `{"key":"value"}`, token)
 	if rec.Code != http.StatusForbidden {
@@ -3158,7 +3170,7 @@ func TestNonInstanceAdminCannotUpdateAdminSettings(t *testing.T) {
 func TestNonInstanceAdminCannotSetStorageLimit(t *testing.T) {
 	ts := setupTestServer(t)
 	auth := registerNonInstanceAdminTestUser(t, ts, "non-ia-storage@example.com")
-	token := generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
+	token := ts.generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
 
 	rec := ts.doRequest(http.MethodPut, "/api/admin/users/fake-id/storage-limit", `{"limit":1000}`, token)
 	if rec.Code != http.StatusForbidden {
@@ -3169,7 +3181,7 @@ func TestNonInstanceAdminCannotSetStorageLimit(t *testing.T) {
 func TestNonInstanceAdminCannotManageOAuthProviders(t *testing.T) {
 	ts := setupTestServer(t)
 	auth := registerNonInstanceAdminTestUser(t, ts, "non-ia-oauth@example.com")
-	token := generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
+	token := ts.generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
 
 	rec := ts.doRequest(http.MethodGet, "/api/admin/oauth-providers", "", token)
 	if rec.Code != http.StatusForbidden {
@@ -3185,7 +3197,7 @@ func TestNonInstanceAdminCannotManageOAuthProviders(t *testing.T) {
 func TestNonInstanceAdminCannotManageBackups(t *testing.T) {
 	ts := setupTestServer(t)
 	auth := registerNonInstanceAdminTestUser(t, ts, "non-ia-backup@example.com")
-	token := generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
+	token := ts.generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
 
 	rec := ts.doRequest(http.MethodGet, "/api/admin/backups", "", token)
 	if rec.Code != http.StatusForbidden {
@@ -3201,7 +3213,7 @@ func TestNonInstanceAdminCannotManageBackups(t *testing.T) {
 func TestNonInstanceAdminCannotRebuildSearchIndex(t *testing.T) {
 	ts := setupTestServer(t)
 	auth := registerNonInstanceAdminTestUser(t, ts, "non-ia-search@example.com")
-	token := generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
+	token := ts.generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, false, false)
 
 	rec := ts.doRequest(http.MethodPost, "/api/admin/search/rebuild", "", token)
 	if rec.Code != http.StatusForbidden {
@@ -3214,7 +3226,7 @@ func TestInstanceAdminCanAccessAdminRoutes(t *testing.T) {
 	_, auth := ts.registerTestUser(t, "ia-access@example.com")
 	// Mark user as instance admin in DB so middleware lookup passes
 	ts.db.Model(&models.User{}).Where("id = ?", auth.User.ID).Update("is_instance_administrator", true)
-	token := generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, true, false)
+	token := ts.generateJWTFull(auth.User.ID, auth.User.AccountID, auth.User.Email, true, true, false)
 
 	rec := ts.doRequest(http.MethodGet, "/api/admin/users", "", token)
 	if rec.Code != http.StatusOK {
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/i18n"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+)
+
+// reminderActionPage is served straight to the browser that opened a
+// reminder link, so it must work without the SPA or a session. Opening the
+// link only shows a confirmation; the action runs on the form POST, which
+// keeps mail scanners that prefetch links from snoozing reminders.
+var reminderActionPage = template.Must(template.New("reminder_action").Parse(`<!DOCTYPE html>
+<html lang="{{.Locale}}"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title></head>
+<body style="font-family: sans-serif; max-width: 32rem; margin: 4rem auto; padding: 0 1rem">
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .Button}}<form method="post"><button type="submit">{{.Button}}</button></form>{{end}}
+</body></html>`))
+
+type reminderActionPageData struct {
+	Locale  string
+	Title   string
+	Message string
+	Button  string
+}
+
+type ReminderActionHandler struct {
+	reminderActionService *services.ReminderActionService
+}
+
+func NewReminderActionHandler(reminderActionService *services.ReminderActionService) *ReminderActionHandler {
+	return &ReminderActionHandler{reminderActionService: reminderActionService}
+}
+
+// Show godoc
+//
+//	@Summary		Confirm a reminder action
+//	@Description	Render a confirmation page for a signed acknowledge/snooze link from a reminder notification
+//	@Tags			reminders
+//	@Produce		html
+//	@Param			token	path		string	true	"Signed action token"
+//	@Success		200		{string}	string	"HTML confirmation page"
+//	@Failure		400		{string}	string	"HTML error page"
+//	@Router			/reminders/actions/{token} [get]
+func (h *ReminderActionHandler) Show(c echo.Context) error {
+	action, err := h.reminderActionService.Preview(c.Param("token"))
+	if err != nil {
+		return h.renderError(c, err)
+	}
+	return renderReminderActionPage(c, http.StatusOK, reminderActionPageData{
+		Locale:  action.Locale,
+		Title:   action.ReminderLabel,
+		Message: i18n.T(action.Locale, "reminder_action.confirm."+action.Action),
+		Button:  i18n.T(action.Locale, "reminder_action.button"),
+	})
+}
+
+// Apply godoc
+//
+//	@Summary		Apply a reminder action
+//	@Description	Acknowledge or snooze a reminder through a signed link from a reminder notification
+//	@Tags			reminders
+//	@Produce		html
+//	@Param			token	path		string	true	"Signed action token"
+//	@Success		200		{string}	string	"HTML result page"
+//	@Failure		400		{string}	string	"HTML error page"
+//	@Router			/reminders/actions/{token} [post]
+func (h *ReminderActionHandler) Apply(c echo.Context) error {
+	action, err := h.reminderActionService.Apply(c.Param("token"))
+	if err != nil {
+		return h.renderError(c, err)
+	}
+	return renderReminderActionPage(c, http.StatusOK, reminderActionPageData{
+		Locale:  action.Locale,
+		Title:   action.ReminderLabel,
+		Message: reminderActionDoneMessage(action),
+	})
+}
+
+func (h *ReminderActionHandler) renderError(c echo.Context, err error) error {
+	locale := middleware.GetLocale(c)
+	status, message := http.StatusInternalServerError, "reminder_action.failed"
+	if errors.Is(err, services.ErrReminderActionInvalid) {
+		status, message = http.StatusBadRequest, "reminder_action.invalid"
+	}
+	return renderReminderActionPage(c, status, reminderActionPageData{
+		Locale:  locale,
+		Title:   i18n.T(locale, "reminder_action.title"),
+		Message: i18n.T(locale, message),
+	})
+}
+
+func reminderActionDoneMessage(action *dto.ReminderActionResponse) string {
+	if action.SnoozedUntil == nil {
+		return i18n.T(action.Locale, "reminder_action.done."+action.Action)
+	}
+	return i18n.Tt(action.Locale, "reminder_action.done.snooze", map[string]string{
+		"date": action.SnoozedUntil.Format("2006-01-02 15:04"),
+	})
+}
+
+func renderReminderActionPage(c echo.Context, status int, data reminderActionPageData) error {
+	var buf bytes.Buffer
+	if err := reminderActionPage.Execute(&buf, data); err != nil {
+		return err
+	}
+	return c.HTMLBlob(status, buf.Bytes())
+}
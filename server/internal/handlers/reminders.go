@@ -86,6 +86,9 @@ func (h *ReminderHandler) Create(c echo.Context) error {
 		if errors.Is(err, services.ErrReminderInvalidAudience) || errors.Is(err, services.ErrReminderAudienceUserNotInVault) {
 			return response.ValidationError(c, map[string]string{"validation": err.Error()})
 		}
+		if errors.Is(err, services.ErrReminderInvalidLeadTime) || errors.Is(err, services.ErrReminderInvalidEscalation) {
+			return response.ValidationError(c, map[string]string{"validation": err.Error()})
+		}
 		return response.InternalError(c, "err.failed_to_create_reminder")
 	}
 	return response.Created(c, reminder)
@@ -140,6 +143,9 @@ func (h *ReminderHandler) Update(c echo.Context) error {
 		if errors.Is(err, services.ErrReminderInvalidAudience) || errors.Is(err, services.ErrReminderAudienceUserNotInVault) {
 			return response.ValidationError(c, map[string]string{"validation": err.Error()})
 		}
+		if errors.Is(err, services.ErrReminderInvalidLeadTime) || errors.Is(err, services.ErrReminderInvalidEscalation) {
+			return response.ValidationError(c, map[string]string{"validation": err.Error()})
+		}
 		return response.InternalError(c, "err.failed_to_update_reminder")
 	}
 	return response.OK(c, reminder)
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+// AddressesNearby godoc
+//
+//	@Summary		Find contacts near a place
+//	@Description	Return the contacts living within radius_km of a point, given as latitude and longitude or as a place name to geocode, or inside a bounding box (min_lat, min_lng, max_lat, max_lng), closest first. Only geocoded addresses are considered; past addresses only with include_past=true.
+//	@Tags			reports
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id		path		string	true	"Vault ID"
+//	@Param			place			query		string	false	"Place to geocode, e.g. Lisbon, Portugal"
+//	@Param			latitude		query		number	false	"Latitude of the center"
+//	@Param			longitude		query		number	false	"Longitude of the center"
+//	@Param			radius_km		query		number	false	"Radius in kilometers (default 50)"
+//	@Param			min_lat			query		number	false	"Bounding box south edge"
+//	@Param			min_lng			query		number	false	"Bounding box west edge"
+//	@Param			max_lat			query		number	false	"Bounding box north edge"
+//	@Param			max_lng			query		number	false	"Bounding box east edge"
+//	@Param			include_past	query		boolean	false	"Include past addresses"
+//	@Success		200				{object}	response.APIResponse{data=dto.NearbyContactsResponse}
+//	@Failure		400				{object}	response.APIResponse
+//	@Failure		404				{object}	response.APIResponse
+//	@Failure		500				{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/reports/addresses/nearby [get]
+func (h *ReportHandler) AddressesNearby(c echo.Context) error {
+	vaultID := c.Param("vault_id")
+	var q dto.NearbyContactsQuery
+	var err error
+	q.Place = c.QueryParam("place")
+	if q.Latitude, err = queryFloat(c, "latitude"); err != nil {
+		return response.BadRequest(c, "err.invalid_location", nil)
+	}
+	if q.Longitude, err = queryFloat(c, "longitude"); err != nil {
+		return response.BadRequest(c, "err.invalid_location", nil)
+	}
+	radius, err := queryFloat(c, "radius_km")
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_location", nil)
+	}
+	if radius != nil {
+		q.RadiusKM = *radius
+	}
+	if q.MinLat, q.MinLng, q.MaxLat, q.MaxLng, err = queryBounds(c); err != nil {
+		return response.BadRequest(c, "err.invalid_location", nil)
+	}
+	if q.IncludePast, err = queryBool(c, "include_past"); err != nil {
+		return response.BadRequest(c, "err.invalid_location", nil)
+	}
+
+	data, err := h.reportService.NearbyContacts(vaultID, middleware.GetUserID(c), q)
+	if err != nil {
+		return geoReportError(c, err)
+	}
+	return response.OK(c, data)
+}
+
+// Map godoc
+//
+//	@Summary		Get contacts map
+//	@Description	Return the geocoded addresses of a vault as a GeoJSON FeatureCollection for a map, optionally limited to a bounding box. Addresses close together at the zoom level (0-20, default 2) are merged into cluster points carrying their count, contact IDs and bounds.
+//	@Tags			reports
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id		path		string	true	"Vault ID"
+//	@Param			zoom			query		integer	false	"Map zoom level"
+//	@Param			min_lat			query		number	false	"Bounding box south edge"
+//	@Param			min_lng			query		number	false	"Bounding box west edge"
+//	@Param			max_lat			query		number	false	"Bounding box north edge"
+//	@Param			max_lng			query		number	false	"Bounding box east edge"
+//	@Param			include_past	query		boolean	false	"Include past addresses"
+//	@Success		200				{object}	response.APIResponse{data=dto.GeoJSONFeatureCollection}
+//	@Failure		400				{object}	response.APIResponse
+//	@Failure		500				{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/reports/map [get]
+func (h *ReportHandler) Map(c echo.Context) error {
+	vaultID := c.Param("vault_id")
+	var q dto.MapQuery
+	var err error
+	if v := c.QueryParam("zoom"); v != "" {
+		zoom, err := strconv.Atoi(v)
+		if err != nil {
+			return response.BadRequest(c, "err.invalid_location", nil)
+		}
+		q.Zoom = &zoom
+	}
+	if q.MinLat, q.MinLng, q.MaxLat, q.MaxLng, err = queryBounds(c); err != nil {
+		return response.BadRequest(c, "err.invalid_location", nil)
+	}
+	if q.IncludePast, err = queryBool(c, "include_past"); err != nil {
+		return response.BadRequest(c, "err.invalid_location", nil)
+	}
+
+	data, err := h.reportService.MapReport(vaultID, middleware.GetUserID(c), q)
+	if err != nil {
+		return geoReportError(c, err)
+	}
+	return response.OK(c, data)
+}
+
+func geoReportError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidGeoQuery):
+		return response.BadRequest(c, "err.invalid_location", nil)
+	case errors.Is(err, services.ErrPlaceNotFound):
+		return response.NotFound(c, "err.place_not_found")
+	case errors.Is(err, services.ErrGeocodingDisabled):
+		return response.BadRequest(c, "err.geocoding_disabled", nil)
+	}
+	return response.InternalError(c, "err.failed_to_get_address_report")
+}
+
+// queryFloat parses an optional number query parameter.
+func queryFloat(c echo.Context, name string) (*float64, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, strconv.ErrSyntax
+	}
+	return &f, nil
+}
+
+func queryBool(c echo.Context, name string) (bool, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+func queryBounds(c echo.Context) (minLat, minLng, maxLat, maxLng *float64, err error) {
+	if minLat, err = queryFloat(c, "min_lat"); err != nil {
+		return
+	}
+	if minLng, err = queryFloat(c, "min_lng"); err != nil {
+		return
+	}
+	if maxLat, err = queryFloat(c, "max_lat"); err != nil {
+		return
+	}
+	maxLng, err = queryFloat(c, "max_lng")
+	return
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+// MoodAnalytics godoc
+//
+//	@Summary		Get mood and sleep analytics
+//	@Description	Aggregate the current user's mood entries in a vault: weekly or monthly averages, daily averages with a rolling average, streaks, the mood distribution by weekday, and correlations of the daily mood with hours slept, journal post metrics and life metric increments. Scores run from 1 for the last mood parameter to the number of parameters for the first. Days are in the user's timezone.
+//	@Tags			reports
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			from		query		string	false	"First day (YYYY-MM-DD), default the first entry"
+//	@Param			to			query		string	false	"Last day (YYYY-MM-DD), default today"
+//	@Param			period		query		string	false	"week (default) or month"
+//	@Param			window		query		integer	false	"Days in the rolling average, 1-365 (default 7)"
+//	@Success		200			{object}	response.APIResponse{data=dto.MoodAnalyticsResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/reports/mood/analytics [get]
+func (h *ReportHandler) MoodAnalytics(c echo.Context) error {
+	q, err := moodAnalyticsQuery(c)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_mood_analytics_query", nil)
+	}
+	data, err := h.reportService.MoodAnalytics(c.Param("vault_id"), middleware.GetUserID(c), q)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMoodAnalyticsQuery) {
+			return response.BadRequest(c, "err.invalid_mood_analytics_query", nil)
+		}
+		return response.InternalError(c, "err.failed_to_get_mood_analytics")
+	}
+	return response.OK(c, data)
+}
+
+// MoodFeed godoc
+//
+//	@Summary		Get mood feed
+//	@Description	Return one item per logged day with the average mood score, hours slept and their rolling averages, as a plain JSON array for dashboards such as Grafana. Authenticate with a reports:read scoped personal access token, in the Authorization header or the token query parameter.
+//	@Tags			reports
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			from		query		string	false	"First day (YYYY-MM-DD), default the first entry"
+//	@Param			to			query		string	false	"Last day (YYYY-MM-DD), default today"
+//	@Param			window		query		integer	false	"Days in the rolling average, 1-365 (default 7)"
+//	@Success		200			{array}		dto.MoodFeedItem
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		403			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/reports/mood.json [get]
+func (h *ReportHandler) MoodFeed(c echo.Context) error {
+	q, err := moodAnalyticsQuery(c)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_mood_analytics_query", nil)
+	}
+	data, err := h.reportService.MoodFeed(c.Param("vault_id"), middleware.GetUserID(c), q)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMoodAnalyticsQuery) {
+			return response.BadRequest(c, "err.invalid_mood_analytics_query", nil)
+		}
+		return response.InternalError(c, "err.failed_to_get_mood_analytics")
+	}
+	return c.JSON(http.StatusOK, data)
+}
+
+// MoodCSV godoc
+//
+//	@Summary		Export mood entries as CSV
+//	@Description	Return the current user's mood entries in a vault as CSV, one row per entry with its time in the user's timezone, mood, score, hours slept and note. Authenticate with a reports:read scoped personal access token, in the Authorization header or the token query parameter.
+//	@Tags			reports
+//	@Produce		text/csv
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			from		query		string	false	"First day (YYYY-MM-DD), default the first entry"
+//	@Param			to			query		string	false	"Last day (YYYY-MM-DD), default today"
+//	@Success		200			{string}	string	"CSV file"
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		403			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/reports/mood.csv [get]
+func (h *ReportHandler) MoodCSV(c echo.Context) error {
+	q := dto.MoodAnalyticsQuery{From: c.QueryParam("from"), To: c.QueryParam("to")}
+	data, err := h.reportService.ExportMoodCSV(c.Param("vault_id"), middleware.GetUserID(c), q)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMoodAnalyticsQuery) {
+			return response.BadRequest(c, "err.invalid_mood_analytics_query", nil)
+		}
+		return response.InternalError(c, "err.failed_to_export_mood")
+	}
+	c.Response().Header().Set("Content-Disposition", `attachment; filename="mood.csv"`)
+	return c.Blob(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+func moodAnalyticsQuery(c echo.Context) (dto.MoodAnalyticsQuery, error) {
+	q := dto.MoodAnalyticsQuery{
+		From:   c.QueryParam("from"),
+		To:     c.QueryParam("to"),
+		Period: c.QueryParam("period"),
+	}
+	if v := c.QueryParam("window"); v != "" {
+		window, err := strconv.Atoi(v)
+		if err != nil || window < 1 {
+			return q, services.ErrInvalidMoodAnalyticsQuery
+		}
+		q.Window = window
+	}
+	return q, nil
+}
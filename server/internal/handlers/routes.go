@@ -19,7 +19,19 @@ import (
 	_ "github.com/naiba/bonds/docs"
 )
 
-func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version string, backupReloader func()) {
+// Workers are the services that also work outside requests, for the caller
+// to schedule.
+type Workers struct {
+	Jobs        *services.JobService
+	EmailIngest *services.EmailIngestService
+	Geocoding   *services.GeocodingService
+	Automation  *services.AutomationService
+}
+
+// RegisterRoutes wires the services and routes of the API. It returns the
+// workers so the caller can have the scheduler run queued jobs and
+// automation rules, poll for inbound mail and geocode addresses.
+func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version string, backupReloader func()) *Workers {
 	authMiddleware := middleware.NewAuthMiddleware(cfg.JWT.Secret, db)
 
 	systemSettingService := services.NewSystemSettingServiceWithCipher(db, cfg.Security.SettingsEncKey)
@@ -29,6 +41,12 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 		log.Printf("Encrypted %d previously-plaintext secret system settings", migrated)
 	}
 
+	if cfg.ForwardAuth.Enabled {
+		if err := authMiddleware.EnableForwardAuth(cfg.ForwardAuth, cfg.App.URL, services.NewForwardAuthService(db, cfg.ForwardAuth)); err != nil {
+			log.Fatalf("Invalid forward auth configuration: %v", err)
+		}
+	}
+
 	feedRecorder := services.NewFeedRecorder(db)
 
 	authService := services.NewAuthService(db, &cfg.JWT)
@@ -61,6 +79,8 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	calendarICSService := services.NewCalendarICSService(db)
 	reportService := services.NewReportService(db)
 	feedService := services.NewFeedService(db)
+	vaultChangeService := services.NewVaultChangeService(db)
+	vaultStreamService := services.NewVaultStreamService(db)
 	preferenceService := services.NewPreferenceService(db)
 	notificationSender := services.NewShoutrrrSender()
 	notificationService := services.NewNotificationService(db)
@@ -104,25 +124,49 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	davPushService := services.NewDavPushService(db, davClientService, vcardService)
 	monicaImportService := services.NewMonicaImportService(db, cfg.Storage.UploadDir)
 	csvImportService := services.NewCSVImportService(db)
+	historyImportService := services.NewHistoryImportService(db)
+	journalArchiveService := services.NewJournalArchiveService(db)
+	journalArchiveService.SetVaultFileService(vaultFileService)
+	gedcomService := services.NewGedcomService(db)
 	adminService := services.NewAdminService(db, cfg.Storage.UploadDir)
 
 	patService := services.NewPersonalAccessTokenService(db)
+	sessionService := services.NewSessionService(db)
+	oauthServerService := services.NewOAuthServerService(db, cfg.App.URL)
+	oauthServerService.SetSystemSettings(systemSettingService)
 
 	mailer := services.NewDynamicMailer(systemSettingService)
 	authService.SetMailer(mailer)
 	authService.SetSystemSettings(systemSettingService)
+	authService.SetLDAP(services.NewLDAPService(db, systemSettingService))
 	invitationService := services.NewInvitationService(db, mailer, cfg.App.URL)
 	invitationService.SetSystemSettings(systemSettingService)
+	reminderActionService := services.NewReminderActionService(db, cfg.JWT.Secret, cfg.App.URL)
+	reminderActionService.SetSystemSettings(systemSettingService)
 	notificationService.SetMailer(mailer)
 	notificationService.SetSender(notificationSender)
 	notificationService.SetSystemSettings(systemSettingService)
-
+	webPushService := services.NewWebPushService(systemSettingService)
+	notificationService.SetWebPush(webPushService)
+	taskNotificationService := services.NewTaskNotificationService(db, mailer, notificationSender)
+	taskNotificationService.SetWebPushSender(webPushService)
+	memoryService := services.NewMemoryService(db, mailer, notificationSender)
+	memoryService.SetWebPushSender(webPushService)
+	automationService := services.NewAutomationService(db)
+	automationService.SetSystemSettings(systemSettingService)
+	automationService.SetMailer(mailer)
+	automationService.SetSender(notificationSender)
+	automationService.SetWebPush(webPushService)
+	feedRecorder.SetAutomation(automationService)
+
+	geocodingService := services.NewGeocodingService(db)
 	geocodingProvider := systemSettingService.GetWithDefault("geocoding.provider", cfg.Geocoding.Provider)
 	if geocodingProvider != "" {
 		geocodingAPIKey := systemSettingService.GetWithDefault("geocoding.api_key", cfg.Geocoding.APIKey)
 		geocoder := services.NewGeocoder(geocodingProvider, geocodingAPIKey)
-		addressService.SetGeocoder(geocoder)
+		geocodingService.SetGeocoder(geocodingProvider, geocoder)
 	}
+	reportService.SetGeocodingService(geocodingService)
 
 	oauthProviderService := services.NewOAuthProviderServiceWithCipher(db, cfg.Security.SettingsEncKey)
 	oauthProviderService.SetSystemSettings(systemSettingService)
@@ -167,6 +211,8 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	loanService.SetFeedRecorder(feedRecorder)
 	relationshipService.SetFeedRecorder(feedRecorder)
 	vaultFileService.SetFeedRecorder(feedRecorder)
+	contactLabelService.SetFeedRecorder(feedRecorder)
+	importantDateService.SetFeedRecorder(feedRecorder)
 	quickFactService.SetFileService(vaultFileService)
 
 	contactService.SetSearchService(searchService)
@@ -175,11 +221,37 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	contactMoveService.SetDavPushService(davPushService)
 	contactMoveService.SetFileService(vaultFileService)
 	noteService.SetSearchService(searchService)
-	monicaImportService.SetFeedRecorder(feedRecorder)
+	// Imports record feed items without running automation rules for every
+	// imported contact.
+	importFeedRecorder := services.NewFeedRecorder(db)
+	monicaImportService.SetFeedRecorder(importFeedRecorder)
 	monicaImportService.SetSearchEngine(searchEngine)
-	csvImportService.SetFeedRecorder(feedRecorder)
+	csvImportService.SetFeedRecorder(importFeedRecorder)
 	csvImportService.SetSearchService(searchService)
 	csvImportService.SetDavPushService(davPushService)
+	historyImportService.SetSearchService(searchService)
+	gedcomService.SetFeedRecorder(importFeedRecorder)
+	gedcomService.SetSearchService(searchService)
+	gedcomService.SetDavPushService(davPushService)
+
+	jobService := services.NewJobService(db)
+	jobService.SetSystemSettings(systemSettingService)
+	jobService.SetMailer(mailer)
+	jobService.SetSender(notificationSender)
+	jobService.SetWebPush(webPushService)
+	jobService.Register(services.JobTypeMonicaImport, monicaImportService.RunImportJob)
+	jobService.Register(services.JobTypeCSVImport, csvImportService.RunImportJob)
+	jobService.Register(services.JobTypeVCardImport, vcardService.RunImportJob)
+	jobService.Register(services.JobTypeHistoryImport, historyImportService.RunImportJob)
+	jobService.Register(services.JobTypeJournalImport, journalArchiveService.RunImportJob)
+	jobService.Register(services.JobTypeSearchRebuild, searchService.RebuildIndexJob(db))
+	jobService.Register(services.JobTypeBackupRestore, backupService.RunRestoreJob)
+
+	emailIngestService := services.NewEmailIngestService(db)
+	emailIngestService.SetSystemSettings(systemSettingService)
+	emailIngestService.SetFileService(vaultFileService, storageInfoService)
+	emailIngestService.SetFeedRecorder(feedRecorder)
+	emailIngestService.SetSearchService(searchService)
 
 	postPhotoHandler := NewPostPhotoHandler(vaultFileService, storageInfoService, systemSettingService)
 	contactPhotoHandler := NewContactPhotoHandler(vaultFileService)
@@ -214,16 +286,26 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	calendarHandler := NewCalendarHandler(calendarService, calendarICSService)
 	reportHandler := NewReportHandler(reportService)
 	feedHandler := NewFeedHandler(feedService)
+	vaultChangeHandler := NewVaultChangeHandler(vaultChangeService)
+	automationHandler := NewAutomationHandler(automationService)
+	emailInboxHandler := NewEmailInboxHandler(emailIngestService)
 	preferenceHandler := NewPreferenceHandler(preferenceService)
 	notificationHandler := NewNotificationHandler(notificationService)
+	taskNotificationHandler := NewTaskNotificationHandler(taskNotificationService)
+	memoryHandler := NewMemoryHandler(memoryService)
 	personalizeHandler := NewPersonalizeHandler(personalizeService)
 	twoFactorHandler := NewTwoFactorHandler(twoFactorService)
 	searchHandler := NewSearchHandler(searchService)
 	oauthHandler := NewOAuthHandler(oauthService, systemSettingService, cfg.JWT.Secret)
-	vcardHandler := NewVCardHandler(vcardService)
-	monicaImportHandler := NewMonicaImportHandler(monicaImportService)
-	csvImportHandler := NewCSVImportHandler(csvImportService)
+	vcardHandler := NewVCardHandler(vcardService, jobService)
+	monicaImportHandler := NewMonicaImportHandler(monicaImportService, jobService)
+	csvImportHandler := NewCSVImportHandler(csvImportService, jobService)
+	historyImportHandler := NewHistoryImportHandler(historyImportService, jobService)
+	journalArchiveHandler := NewJournalArchiveHandler(journalArchiveService, jobService)
+	geocodingHandler := NewGeocodingHandler(geocodingService)
+	gedcomHandler := NewGedcomHandler(gedcomService)
 	invitationHandler := NewInvitationHandler(invitationService)
+	reminderActionHandler := NewReminderActionHandler(reminderActionService)
 	contactLabelHandler := NewContactLabelHandler(contactLabelService)
 	contactReligionHandler := NewContactReligionHandler(contactReligionService)
 	contactJobHandler := NewContactJobHandler(contactJobService)
@@ -249,10 +331,11 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	userManagementHandler := NewUserManagementHandler(userManagementService)
 	accountCancelHandler := NewAccountCancelHandler(accountCancelService)
 	storageInfoHandler := NewStorageInfoHandler(storageInfoService)
-	backupHandler := NewBackupHandler(backupService)
+	backupHandler := NewBackupHandler(backupService, jobService)
+	jobHandler := NewJobHandler(jobService)
 	currencyHandler := NewCurrencyHandler(currencyService)
 	davClientHandler := NewDavClientHandler(davClientService, davSyncService)
-	adminHandler := NewAdminHandler(adminService, systemSettingService, searchService, db)
+	adminHandler := NewAdminHandler(adminService, systemSettingService, searchService, jobService, db)
 	adminHandler.RegisterReloader(func() {
 		oauthProviderService.ReloadProviders()
 	})
@@ -265,9 +348,17 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 		adminHandler.RegisterReloader(backupReloader)
 	}
 	oauthProviderHandler := NewOAuthProviderHandler(oauthProviderService)
-	instanceHandler := NewInstanceHandler(systemSettingService, oauthService, webauthnService, version)
+	instanceHandler := NewInstanceHandler(systemSettingService, oauthService, webauthnService, cfg.ForwardAuth.Enabled, version)
 
 	patHandler := NewPersonalAccessTokenHandler(patService)
+	sessionHandler := NewSessionHandler(sessionService)
+	oauthServerHandler := NewOAuthServerHandler(oauthServerService)
+
+	batchHandler := NewBatchHandler(services.NewBatchService(
+		db, contactService, contactInformationService, contactLabelService, vaultLabelService,
+		noteService, reminderService, importantDateService, taskService,
+	))
+	vaultStreamHandler := NewVaultStreamHandler(vaultStreamService)
 
 	e.Use(middleware.CORS())
 
@@ -278,6 +369,18 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 		return echoSwagger.WrapHandler(c)
 	})
 
+	e.GET("/.well-known/oauth-authorization-server", oauthServerHandler.AuthorizationServerMetadata)
+	e.GET("/.well-known/oauth-protected-resource", oauthServerHandler.ProtectedResourceMetadata)
+	e.GET("/.well-known/oauth-protected-resource/mcp", oauthServerHandler.ProtectedResourceMetadata)
+	e.GET("/oauth/authorize", oauthServerHandler.Authorize)
+	e.POST("/oauth/token", oauthServerHandler.Token)
+	e.POST("/oauth/register", oauthServerHandler.Register)
+	e.POST("/oauth/device_authorization", oauthServerHandler.DeviceAuthorization)
+	e.POST("/oauth/revoke", oauthServerHandler.Revoke)
+	e.GET("/oauth/consent", oauthServerHandler.ConsentPage)
+	e.GET("/oauth/device", oauthServerHandler.DevicePage)
+	e.GET("/oauth/apps", oauthServerHandler.AppsPage)
+
 	api := e.Group("/api")
 
 	api.GET("/announcement", func(c echo.Context) error {
@@ -288,7 +391,8 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	auth := api.Group("/auth")
 	auth.POST("/register", authHandler.Register)
 	auth.POST("/login", authHandler.Login)
-	auth.POST("/refresh", authHandler.Refresh, authMiddleware.Authenticate)
+	auth.POST("/refresh", authHandler.Refresh, authMiddleware.AuthenticateLegacy)
+	auth.POST("/refresh-token", authHandler.RefreshSession)
 	auth.GET("/me", authHandler.Me, authMiddleware.Authenticate)
 	auth.GET("/providers", oauthHandler.AvailableProviders)
 	auth.POST("/oauth/link", oauthHandler.LinkProvider, authMiddleware.Authenticate)
@@ -305,6 +409,8 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	auth.POST("/2fa/verify", authHandler.VerifyTwoFactor)
 
 	api.POST("/invitations/accept", invitationHandler.Accept)
+	api.GET("/reminders/actions/:token", reminderActionHandler.Show)
+	api.POST("/reminders/actions/:token", reminderActionHandler.Apply)
 
 	api.GET("/instance/info", instanceHandler.GetInfo)
 
@@ -315,7 +421,11 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 		return systemSettingService.GetWithDefault("smtp.host", "") != ""
 	}
 
-	adminGroup := api.Group("/admin", authMiddleware.Authenticate, middleware.RequireEmailVerification(emailVerificationRequired), middleware.DenyScopedPAT, authMiddleware.RequireInstanceAdmin)
+	idempotencyService := services.NewIdempotencyService(db)
+	idempotencyService.SetSystemSettings(systemSettingService)
+	idempotency := IdempotencyMiddleware(idempotencyService)
+
+	adminGroup := api.Group("/admin", authMiddleware.Authenticate, middleware.RequireEmailVerification(emailVerificationRequired), middleware.DenyScopedPAT, authMiddleware.RequireInstanceAdmin, idempotency)
 	adminGroup.GET("/users", adminHandler.ListUsers)
 	adminGroup.PUT("/users/:id/toggle", adminHandler.ToggleUser)
 	adminGroup.PUT("/users/:id/admin", adminHandler.SetAdmin)
@@ -327,6 +437,11 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	adminGroup.POST("/oauth-providers", oauthProviderHandler.Create)
 	adminGroup.PUT("/oauth-providers/:id", oauthProviderHandler.Update)
 	adminGroup.DELETE("/oauth-providers/:id", oauthProviderHandler.Delete)
+	adminGroup.GET("/oauth-providers/:id/claim-rules", oauthProviderHandler.GetClaimRules)
+	adminGroup.PUT("/oauth-providers/:id/claim-rules", oauthProviderHandler.UpdateClaimRules)
+	adminGroup.GET("/oauth-clients", oauthServerHandler.ListClients)
+	adminGroup.POST("/oauth-clients", oauthServerHandler.CreateClient)
+	adminGroup.DELETE("/oauth-clients/:id", oauthServerHandler.DeleteClient)
 
 	adminGroup.POST("/search/rebuild", adminHandler.RebuildSearchIndex)
 	backupGroup := adminGroup.Group("/backups")
@@ -337,13 +452,28 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	backupGroup.DELETE("/:filename", backupHandler.Delete)
 	backupGroup.POST("/:filename/restore", backupHandler.Restore)
 
-	protected := api.Group("", authMiddleware.Authenticate, middleware.RequireEmailVerification(emailVerificationRequired), middleware.DenyScopedPAT)
+	protected := api.Group("", authMiddleware.Authenticate, middleware.RequireEmailVerification(emailVerificationRequired), middleware.DenyScopedPAT, idempotency)
 
 	protected.GET("/account", accountHandler.GetAccount)
 
 	// Cross-vault relationship contacts (no vault scope — returns contacts from all accessible vaults)
 	protected.GET("/relationships/contacts", relationshipHandler.ListContactsAcrossVaults)
 
+	// Each operation runs the handler of its route on services bound to the batch's transaction.
+	protected.POST("/batch", batchHandler.Execute)
+
+	// "On this day" gathers memories from every vault of the user.
+	memoriesGroup := protected.Group("/memories")
+	memoriesGroup.GET("/onThisDay", memoryHandler.OnThisDay)
+	memoriesGroup.GET("/favorites", memoryHandler.Favorites)
+	memoriesGroup.PUT("/:kind/:id", memoryHandler.UpdateState)
+
+	jobsGroup := protected.Group("/jobs")
+	jobsGroup.GET("", jobHandler.List)
+	jobsGroup.GET("/:id", jobHandler.Get)
+	jobsGroup.GET("/:id/errors", jobHandler.ListErrors)
+	jobsGroup.POST("/:id/cancel", jobHandler.Cancel)
+
 	vaults := protected.Group("/vaults")
 	vaults.GET("", vaultHandler.List)
 	vaults.POST("", vaultHandler.Create)
@@ -368,6 +498,7 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	contacts.PUT("/:id/archive", contactHandler.ToggleArchive, requireEditor)
 	contacts.PUT("/:id/favorite", contactHandler.ToggleFavorite)
 	contacts.GET("/export", vcardHandler.ExportVault)
+	contacts.GET("/export/gedcom", gedcomHandler.Export)
 	contacts.POST("/import", vcardHandler.ImportVCard, requireEditor)
 
 	contactSub := protected.Group("/vaults/:vault_id/contacts/:contact_id", VaultPermissionMiddleware(vaultService, models.PermissionViewer))
@@ -530,6 +661,8 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 
 	journalRoutes.GET("/:id/photos", journalHandler.GetPhotos)
 	journalRoutes.GET("/:id/years/:year", journalHandler.GetByYear)
+	journalRoutes.GET("/:id/export", journalArchiveHandler.Export)
+	journalRoutes.POST("/:id/import", journalArchiveHandler.Import, requireEditor)
 
 	journalMetricRoutes := vaultScoped.Group("/journals/:journal_id/metrics")
 	journalMetricRoutes.GET("", journalMetricHandler.List)
@@ -565,6 +698,7 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	postRoutes.DELETE("/:id/photos/:photoId", postPhotoHandler.Delete, requireEditor)
 
 	vaultScoped.GET("/tasks", vaultTaskHandler.List)
+	vaultScoped.GET("/tasks/:id/completions", vaultTaskHandler.ListCompletions)
 	vaultScoped.POST("/tasks", vaultTaskHandler.Create, requireEditor)
 	vaultScoped.PATCH("/tasks/:id", vaultTaskHandler.Update, requireEditor)
 	vaultScoped.DELETE("/tasks/:id", vaultTaskHandler.Delete, requireEditor)
@@ -605,10 +739,24 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	vaultScoped.GET("/reports", reportHandler.Index)
 	vaultScoped.GET("/reports/overview", reportHandler.Overview)
 	vaultScoped.GET("/reports/addresses", reportHandler.Addresses)
+	vaultScoped.GET("/reports/addresses/nearby", reportHandler.AddressesNearby)
 	vaultScoped.GET("/reports/addresses/city/:city", reportHandler.AddressesByCity)
 	vaultScoped.GET("/reports/addresses/country/:country", reportHandler.AddressesByCountry)
+	vaultScoped.GET("/reports/map", reportHandler.Map)
 	vaultScoped.GET("/reports/importantDates", reportHandler.ImportantDates)
 	vaultScoped.GET("/reports/moodTrackingEvents", reportHandler.MoodTrackingEvents)
+	vaultScoped.GET("/reports/mood/analytics", reportHandler.MoodAnalytics)
+
+	// Like calendar.ics, the mood feeds accept a reports:read PAT so that
+	// dashboards can poll them without a full-access token.
+	moodFeeds := api.Group("/vaults/:vault_id/reports",
+		authMiddleware.Authenticate,
+		middleware.RequireEmailVerification(emailVerificationRequired),
+		middleware.RequireScope(middleware.ScopeReportsRead),
+		VaultPermissionMiddleware(vaultService, models.PermissionViewer),
+	)
+	moodFeeds.GET("/mood.json", reportHandler.MoodFeed)
+	moodFeeds.GET("/mood.csv", reportHandler.MoodCSV)
 	vaultScoped.POST("/moodTrackingEvents", moodTrackingHandler.Create)
 	vaultScoped.GET("/moodTrackingEvents", moodTrackingHandler.List)
 
@@ -639,6 +787,23 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	davSubs.GET("/:sub_id/logs", davClientHandler.GetSyncLogs)
 
 	vaultScoped.GET("/feed", feedHandler.Get)
+	vaultScoped.GET("/changes", vaultChangeHandler.List)
+	vaultScoped.GET("/stream", vaultStreamHandler.Stream)
+
+	automationRules := vaultScoped.Group("/automation-rules", VaultPermissionMiddleware(vaultService, models.PermissionManager))
+	automationRules.GET("", automationHandler.List)
+	automationRules.POST("", automationHandler.Create)
+	automationRules.GET("/executions", automationHandler.ListExecutions)
+	automationRules.GET("/:id", automationHandler.Get)
+	automationRules.PUT("/:id", automationHandler.Update)
+	automationRules.DELETE("/:id", automationHandler.Delete)
+
+	emailInbox := vaultScoped.Group("/email-inbox", requireEditor)
+	emailInbox.GET("", emailInboxHandler.Get)
+	emailInbox.PUT("", emailInboxHandler.Update)
+	emailInbox.DELETE("", emailInboxHandler.Delete)
+	emailInbox.POST("/rotate", emailInboxHandler.Rotate)
+	emailInbox.GET("/messages", emailInboxHandler.ListMessages)
 	vaultScoped.GET("/search", searchHandler.Search)
 	vaultScoped.GET("/search/mostConsulted", mostConsultedHandler.List)
 	vaultScoped.POST("/search/contacts", contactHandler.QuickSearch)
@@ -660,6 +825,12 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	notifGroup := settingsGroup.Group("/notifications")
 	notifGroup.GET("", notificationHandler.List)
 	notifGroup.POST("", notificationHandler.Create)
+	notifGroup.GET("/tasks", taskNotificationHandler.GetPreferences)
+	notifGroup.PUT("/tasks", taskNotificationHandler.UpdatePreferences)
+	notifGroup.GET("/memories", memoryHandler.GetPreferences)
+	notifGroup.PUT("/memories", memoryHandler.UpdatePreferences)
+	notifGroup.GET("/webpush/key", notificationHandler.WebPushPublicKey)
+	notifGroup.POST("/webpush/subscribe", notificationHandler.SubscribeWebPush)
 	notifGroup.PUT("/:id", notificationHandler.Update)
 	notifGroup.PUT("/:id/toggle", notificationHandler.Toggle)
 	notifGroup.DELETE("/:id", notificationHandler.Delete)
@@ -742,12 +913,31 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 	tokenGroup.POST("", patHandler.Create)
 	tokenGroup.DELETE("/:id", patHandler.Delete)
 
+	sessionGroup := settingsGroup.Group("/sessions")
+	sessionGroup.GET("", sessionHandler.List)
+	sessionGroup.DELETE("", sessionHandler.RevokeAll)
+	sessionGroup.DELETE("/:id", sessionHandler.Revoke)
+
+	authorizedAppGroup := settingsGroup.Group("/authorized-apps")
+	authorizedAppGroup.GET("", oauthServerHandler.ListAuthorizedApps)
+	authorizedAppGroup.DELETE("/:id", oauthServerHandler.RevokeAuthorizedApp)
+
+	// Consent is given in the browser; no token, not even an app's own, may
+	// authorize further apps.
+	consentGroup := protected.Group("/oauth", middleware.DenyPAT)
+	consentGroup.GET("/authorize/:request_id", oauthServerHandler.GetAuthorization)
+	consentGroup.POST("/authorize/:request_id", oauthServerHandler.DecideAuthorization)
+	consentGroup.GET("/device", oauthServerHandler.GetDeviceAuthorization)
+	consentGroup.POST("/device", oauthServerHandler.DecideDeviceAuthorization)
+
 	usersGroup := settingsGroup.Group("/users", authMiddleware.RequireAdmin)
 	usersGroup.GET("", userManagementHandler.List)
 	usersGroup.GET("/:id", userManagementHandler.Get)
 	usersGroup.PUT("/:id", userManagementHandler.Update)
 	usersGroup.DELETE("/:id", userManagementHandler.Delete)
 
+	settingsGroup.POST("/ldap/link", authHandler.LinkLDAP, middleware.DenyPAT)
+
 	oauthGroup := settingsGroup.Group("/oauth")
 	oauthGroup.GET("", oauthHandler.ListProviders)
 	oauthGroup.DELETE("/:driver", oauthHandler.UnlinkProvider)
@@ -810,14 +1000,20 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, version strin
 
 	vaultSettings.POST("/import/monica", monicaImportHandler.Import)
 	vaultSettings.POST("/import/csv", csvImportHandler.Import)
+	vaultSettings.POST("/import/csv/preview", csvImportHandler.Preview)
+	vaultSettings.POST("/import/gedcom", gedcomHandler.Import)
+	vaultSettings.POST("/import/history", historyImportHandler.Import)
+	vaultSettings.POST("/geocode", geocodingHandler.Backfill)
 
 	mcpRegistry := internalmcp.NewActionRegistry(e)
 	mcpExecutor := internalmcp.NewActionExecutor(e, mcpRegistry)
 	mcpSearcher := internalmcp.NewBondsSearcher(db, searchService, vaultService)
 	mcpFetcher := internalmcp.NewResourceFetcher(db, vaultService)
 	mcpHandler := internalmcp.NewHandler(db, mcpRegistry, mcpExecutor, mcpSearcher, mcpFetcher)
-	mcpMiddleware := []echo.MiddlewareFunc{internalmcp.RequireAllowedOrigin(cfg.App.URL, "http://localhost:5173", "http://localhost:3000"), authMiddleware.Authenticate, middleware.RequireEmailVerification(emailVerificationRequired)}
+	mcpMiddleware := []echo.MiddlewareFunc{internalmcp.RequireAllowedOrigin(cfg.App.URL, "http://localhost:5173", "http://localhost:3000"), oauthServerHandler.BearerChallenge("/mcp"), authMiddleware.Authenticate, middleware.RequireEmailVerification(emailVerificationRequired)}
 	e.POST("/mcp", mcpHandler.Handle, mcpMiddleware...)
 	e.GET("/mcp", mcpHandler.MethodNotAllowed, mcpMiddleware...)
 	e.DELETE("/mcp", mcpHandler.MethodNotAllowed, mcpMiddleware...)
+
+	return &Workers{Jobs: jobService, EmailIngest: emailIngestService, Geocoding: geocodingService, Automation: automationService}
 }
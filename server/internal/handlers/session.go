@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+type SessionHandler struct {
+	service *services.SessionService
+}
+
+func NewSessionHandler(service *services.SessionService) *SessionHandler {
+	return &SessionHandler{service: service}
+}
+
+// List godoc
+//
+//	@Summary		List active sessions
+//	@Description	Return the devices the authenticated user is signed in on. The session used for this request is marked as current.
+//	@Tags			sessions
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	response.APIResponse{data=[]dto.UserSessionResponse}
+//	@Failure		401	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/settings/sessions [get]
+func (h *SessionHandler) List(c echo.Context) error {
+	sessions, err := h.service.List(middleware.GetUserID(c), middleware.GetSessionID(c))
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_list_sessions")
+	}
+	return response.OK(c, sessions)
+}
+
+// Revoke godoc
+//
+//	@Summary		Revoke a session
+//	@Description	Sign out one device. Its access and refresh tokens stop working immediately.
+//	@Tags			sessions
+//	@Security		BearerAuth
+//	@Param			id	path	string	true	"Session ID"
+//	@Success		204	"No Content"
+//	@Failure		401	{object}	response.APIResponse
+//	@Failure		404	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/settings/sessions/{id} [delete]
+func (h *SessionHandler) Revoke(c echo.Context) error {
+	if err := h.service.Revoke(middleware.GetUserID(c), c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return response.NotFound(c, "err.session_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_revoke_session")
+	}
+	return response.NoContent(c)
+}
+
+// RevokeAll godoc
+//
+//	@Summary		Log out everywhere
+//	@Description	Revoke every active session of the authenticated user, including the current one unless keep_current is true
+//	@Tags			sessions
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			keep_current	query		bool	false	"Keep the session used for this request"
+//	@Success		200				{object}	response.APIResponse{data=dto.RevokeSessionsResponse}
+//	@Failure		401				{object}	response.APIResponse
+//	@Failure		500				{object}	response.APIResponse
+//	@Router			/settings/sessions [delete]
+func (h *SessionHandler) RevokeAll(c echo.Context) error {
+	keep := ""
+	if c.QueryParam("keep_current") == "true" {
+		keep = middleware.GetSessionID(c)
+	}
+	revoked, err := h.service.RevokeAll(middleware.GetUserID(c), keep)
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_revoke_session")
+	}
+	return response.OK(c, dto.RevokeSessionsResponse{Revoked: revoked})
+}
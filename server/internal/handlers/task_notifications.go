@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+type TaskNotificationHandler struct {
+	taskNotificationService *services.TaskNotificationService
+}
+
+func NewTaskNotificationHandler(taskNotificationService *services.TaskNotificationService) *TaskNotificationHandler {
+	return &TaskNotificationHandler{taskNotificationService: taskNotificationService}
+}
+
+// GetPreferences godoc
+//
+//	@Summary		Get task notification preferences
+//	@Description	Return which task notifications (assignment, due soon, overdue) the current user receives, and the lead time for due-soon notifications
+//	@Tags			notifications
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	response.APIResponse{data=dto.TaskNotificationPreferenceResponse}
+//	@Failure		401	{object}	response.APIResponse
+//	@Failure		500	{object}	response.APIResponse
+//	@Router			/settings/notifications/tasks [get]
+func (h *TaskNotificationHandler) GetPreferences(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	prefs, err := h.taskNotificationService.GetPreferences(userID)
+	if err != nil {
+		return response.InternalError(c, "err.failed_to_get_task_notification_preferences")
+	}
+	return response.OK(c, prefs)
+}
+
+// UpdatePreferences godoc
+//
+//	@Summary		Update task notification preferences
+//	@Description	Replace the current user's task notification preferences. due_lead_minutes is capped at 30 days (43200).
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		dto.UpdateTaskNotificationPreferenceRequest	true	"Preferences"
+//	@Success		200		{object}	response.APIResponse{data=dto.TaskNotificationPreferenceResponse}
+//	@Failure		400		{object}	response.APIResponse
+//	@Failure		401		{object}	response.APIResponse
+//	@Failure		422		{object}	response.APIResponse
+//	@Failure		500		{object}	response.APIResponse
+//	@Router			/settings/notifications/tasks [put]
+func (h *TaskNotificationHandler) UpdatePreferences(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req dto.UpdateTaskNotificationPreferenceRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+	if err := validateRequest(req); err != nil {
+		return response.ValidationError(c, map[string]string{"validation": err.Error()})
+	}
+	prefs, err := h.taskNotificationService.UpdatePreferences(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTaskDueLeadTime) {
+			return response.BadRequest(c, "err.invalid_task_due_lead_time", nil)
+		}
+		return response.InternalError(c, "err.failed_to_update_task_notification_preferences")
+	}
+	return response.OK(c, prefs)
+}
@@ -117,6 +117,12 @@ func (h *TaskHandler) Create(c echo.Context) error {
 		if errors.Is(err, services.ErrInvalidParentTask) {
 			return response.BadRequest(c, "err.invalid_parent_task", nil)
 		}
+		if errors.Is(err, services.ErrInvalidRecurrenceRule) {
+			return response.BadRequest(c, "err.invalid_recurrence_rule", nil)
+		}
+		if errors.Is(err, services.ErrRecurrenceRequiresDueDate) {
+			return response.BadRequest(c, "err.recurrence_requires_due_date", nil)
+		}
 		return response.InternalError(c, "err.failed_to_create_task")
 	}
 	return response.Created(c, task)
@@ -172,6 +178,12 @@ func (h *TaskHandler) Update(c echo.Context) error {
 		if errors.Is(err, services.ErrInvalidParentTask) {
 			return response.BadRequest(c, "err.invalid_parent_task", nil)
 		}
+		if errors.Is(err, services.ErrInvalidRecurrenceRule) {
+			return response.BadRequest(c, "err.invalid_recurrence_rule", nil)
+		}
+		if errors.Is(err, services.ErrRecurrenceRequiresDueDate) {
+			return response.BadRequest(c, "err.recurrence_requires_due_date", nil)
+		}
 		return response.InternalError(c, "err.failed_to_update_task")
 	}
 	return response.OK(c, task)
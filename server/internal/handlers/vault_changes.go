@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+var _ dto.VaultChangesResponse
+
+type VaultChangeHandler struct {
+	vaultChangeService *services.VaultChangeService
+}
+
+func NewVaultChangeHandler(vaultChangeService *services.VaultChangeService) *VaultChangeHandler {
+	return &VaultChangeHandler{vaultChangeService: vaultChangeService}
+}
+
+// List godoc
+//
+//	@Summary		List vault changes
+//	@Description	Return the contacts, notes, tasks, reminders, important dates, activities, posts and files created, updated or deleted since a cursor. Omit since for a full sync, then pass the returned cursor on the next call; deleted entities are returned without data.
+//	@Tags			sync
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			since		query		string	false	"Cursor returned by the previous call"
+//	@Param			limit		query		integer	false	"Maximum number of changes (default 500, max 1000)"
+//	@Success		200			{object}	response.APIResponse{data=dto.VaultChangesResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/changes [get]
+func (h *VaultChangeHandler) List(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	changes, err := h.vaultChangeService.List(c.Param("vault_id"), middleware.GetUserID(c), c.QueryParam("since"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSyncCursor) {
+			return response.BadRequest(c, "err.invalid_sync_cursor", nil)
+		}
+		return response.InternalError(c, "err.failed_to_list_changes")
+	}
+	return response.OK(c, changes)
+}
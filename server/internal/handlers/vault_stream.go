@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/services"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+const (
+	// vaultStreamAccessInterval is how often a stream checks that its
+	// subscriber is still signed in and can view the vault.
+	vaultStreamAccessInterval = 30 * time.Second
+	// vaultStreamKeepAlive keeps proxies from closing an idle stream.
+	vaultStreamKeepAlive   = 25 * time.Second
+	vaultStreamRetryMillis = 3000
+)
+
+type VaultStreamHandler struct {
+	vaultStreamService *services.VaultStreamService
+}
+
+func NewVaultStreamHandler(vaultStreamService *services.VaultStreamService) *VaultStreamHandler {
+	return &VaultStreamHandler{vaultStreamService: vaultStreamService}
+}
+
+// Stream godoc
+//
+//	@Summary		Stream vault changes
+//	@Description	Push the vault's changes as server-sent events while the connection is open. Each change of a contact, note, task, reminder, important date, activity, post or file is an event named after the entity type and action, such as note.updated, with a VaultChangeItem as data; each new feed item is a feed_item.created event with a FeedItemResponse. Reconnect with the Last-Event-ID header (or last_event_id query parameter) to resume; without it the stream starts with the next change. Browsers may pass the access token as the token query parameter. The stream ends when the subscriber loses access to the vault, their session is revoked or their account is disabled, and when the access token expires.
+//	@Tags			sync
+//	@Produce		text/event-stream
+//	@Security		BearerAuth
+//	@Param			vault_id		path		string	true	"Vault ID"
+//	@Param			Last-Event-ID	header		string	false	"ID of the last event received"
+//	@Param			last_event_id	query		string	false	"ID of the last event received"
+//	@Success		200				{object}	dto.VaultStreamEvent
+//	@Failure		400				{object}	response.APIResponse
+//	@Failure		500				{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/stream [get]
+func (h *VaultStreamHandler) Stream(c echo.Context) error {
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.QueryParam("last_event_id")
+	}
+	stream, err := h.vaultStreamService.Open(c.Param("vault_id"), middleware.GetUserID(c), middleware.GetSessionID(c), lastEventID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidStreamEventID) {
+			return response.BadRequest(c, "err.invalid_stream_event_id", nil)
+		}
+		return response.InternalError(c, "err.failed_to_open_stream")
+	}
+	defer stream.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(res, "retry: %d\n\n", vaultStreamRetryMillis); err != nil {
+		return nil
+	}
+	res.Flush()
+
+	// The token authenticated the request only; the stream must not outlive
+	// it.
+	var expired <-chan time.Time
+	if claims := middleware.GetClaims(c); claims != nil && claims.ExpiresAt != nil {
+		expiry := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+		defer expiry.Stop()
+		expired = expiry.C
+	}
+
+	ctx := c.Request().Context()
+	access := time.NewTicker(vaultStreamAccessInterval)
+	defer access.Stop()
+	keepAlive := time.NewTicker(vaultStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-expired:
+			return nil
+		case <-access.C:
+			if err := stream.CheckAccess(); err != nil {
+				logVaultStreamError(c, err)
+				return nil
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case <-stream.Wake():
+			events, err := stream.Poll()
+			if err != nil {
+				logVaultStreamError(c, err)
+				return nil
+			}
+			for _, event := range events {
+				if err := writeStreamEvent(res, event); err != nil {
+					return nil
+				}
+			}
+			if len(events) > 0 {
+				res.Flush()
+			}
+		}
+	}
+}
+
+// logVaultStreamError logs why a stream ended, unless the subscriber merely
+// lost access.
+func logVaultStreamError(c echo.Context, err error) {
+	for _, expected := range []error{
+		services.ErrVaultForbidden,
+		services.ErrInsufficientPerm,
+		services.ErrUserNotFound,
+		services.ErrUserDisabled,
+		services.ErrSessionRevoked,
+	} {
+		if errors.Is(err, expected) {
+			return
+		}
+	}
+	log.Printf("[vault-stream] failed to poll vault %s: %v", c.Param("vault_id"), err)
+}
+
+func writeStreamEvent(res *echo.Response, event dto.VaultStreamEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	if event.ID != "" {
+		if _, err := fmt.Fprintf(res, "id: %s\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
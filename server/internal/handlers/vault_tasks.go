@@ -12,6 +12,7 @@ import (
 )
 
 var _ dto.VaultTaskResponse
+var _ dto.TaskCompletionResponse
 
 type VaultTaskHandler struct {
 	vaultTaskService *services.VaultTaskService
@@ -98,6 +99,15 @@ func (h *VaultTaskHandler) Create(c echo.Context) error {
 		if errors.Is(err, services.ErrInvalidParentTask) {
 			return response.BadRequest(c, "err.invalid_parent_task", nil)
 		}
+		if errors.Is(err, services.ErrInvalidRecurrenceRule) {
+			return response.BadRequest(c, "err.invalid_recurrence_rule", nil)
+		}
+		if errors.Is(err, services.ErrRecurrenceRequiresDueDate) {
+			return response.BadRequest(c, "err.recurrence_requires_due_date", nil)
+		}
+		if errors.Is(err, services.ErrTaskAssigneeNotInVault) {
+			return response.BadRequest(c, "err.task_assignee_not_in_vault", nil)
+		}
 		return response.InternalError(c, "err.failed_to_create_task")
 	}
 	return response.Created(c, task)
@@ -150,6 +160,15 @@ func (h *VaultTaskHandler) Update(c echo.Context) error {
 		if errors.Is(err, services.ErrInvalidParentTask) {
 			return response.BadRequest(c, "err.invalid_parent_task", nil)
 		}
+		if errors.Is(err, services.ErrInvalidRecurrenceRule) {
+			return response.BadRequest(c, "err.invalid_recurrence_rule", nil)
+		}
+		if errors.Is(err, services.ErrRecurrenceRequiresDueDate) {
+			return response.BadRequest(c, "err.recurrence_requires_due_date", nil)
+		}
+		if errors.Is(err, services.ErrTaskAssigneeNotInVault) {
+			return response.BadRequest(c, "err.task_assignee_not_in_vault", nil)
+		}
 		return response.InternalError(c, "err.failed_to_update_task")
 	}
 	return response.OK(c, task)
@@ -271,3 +290,34 @@ func (h *VaultTaskHandler) Delete(c echo.Context) error {
 	}
 	return response.NoContent(c)
 }
+
+// ListCompletions godoc
+//
+//	@Summary		List task completion history
+//	@Description	Return the completed occurrences of a recurring task's series, newest first. Any occurrence of the series can be used. A non-recurring task returns itself once completed.
+//	@Tags			vault-tasks
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			vault_id	path		string	true	"Vault ID"
+//	@Param			id			path		integer	true	"Task ID"
+//	@Success		200			{object}	response.APIResponse{data=[]dto.TaskCompletionResponse}
+//	@Failure		400			{object}	response.APIResponse
+//	@Failure		401			{object}	response.APIResponse
+//	@Failure		404			{object}	response.APIResponse
+//	@Failure		500			{object}	response.APIResponse
+//	@Router			/vaults/{vault_id}/tasks/{id}/completions [get]
+func (h *VaultTaskHandler) ListCompletions(c echo.Context) error {
+	vaultID := c.Param("vault_id")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_task_id", nil)
+	}
+	completions, err := h.vaultTaskService.ListCompletions(uint(id), vaultID)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			return response.NotFound(c, "err.task_not_found")
+		}
+		return response.InternalError(c, "err.failed_to_list_task_completions")
+	}
+	return response.OK(c, completions)
+}
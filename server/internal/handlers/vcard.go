@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"errors"
+	"io"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -17,10 +18,11 @@ var _ dto.VCardImportResponse
 
 type VCardHandler struct {
 	vcardService *services.VCardService
+	jobService   *services.JobService
 }
 
-func NewVCardHandler(vcardService *services.VCardService) *VCardHandler {
-	return &VCardHandler{vcardService: vcardService}
+func NewVCardHandler(vcardService *services.VCardService, jobService *services.JobService) *VCardHandler {
+	return &VCardHandler{vcardService: vcardService, jobService: jobService}
 }
 
 // ExportContact godoc
@@ -83,14 +85,16 @@ func (h *VCardHandler) ExportVault(c echo.Context) error {
 // ImportVCard godoc
 //
 //	@Summary		Import contacts from vCard
-//	@Description	Import contacts from a .vcf file
+//	@Description	Import contacts from a .vcf file. With async=true the import runs as a background job and the job is returned.
 //	@Tags			vcard
 //	@Accept			mpfd
 //	@Produce		json
 //	@Security		BearerAuth
 //	@Param			vault_id	path		string	true	"Vault ID"
 //	@Param			file		formData	file	true	"vCard file (.vcf)"
+//	@Param			async		formData	boolean	false	"Run as a background job"
 //	@Success		201			{object}	response.APIResponse{data=dto.VCardImportResponse}
+//	@Success		202			{object}	response.APIResponse{data=dto.JobResponse}
 //	@Failure		400			{object}	response.APIResponse
 //	@Failure		500			{object}	response.APIResponse
 //	@Router			/vaults/{vault_id}/contacts/import [post]
@@ -98,6 +102,11 @@ func (h *VCardHandler) ImportVCard(c echo.Context) error {
 	vaultID := c.Param("vault_id")
 	userID := middleware.GetUserID(c)
 
+	async, err := runAsync(c)
+	if err != nil {
+		return response.BadRequest(c, "err.invalid_request_body", nil)
+	}
+
 	file, err := c.FormFile("file")
 	if err != nil {
 		return response.BadRequest(c, "err.file_required", nil)
@@ -109,6 +118,14 @@ func (h *VCardHandler) ImportVCard(c echo.Context) error {
 	}
 	defer src.Close()
 
+	if async {
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return response.InternalError(c, "err.failed_to_read_file")
+		}
+		return enqueueJob(c, h.jobService, &vaultID, services.JobTypeVCardImport, nil, data)
+	}
+
 	result, err := h.vcardService.ImportVCard(vaultID, userID, src)
 	if err != nil {
 		if errors.Is(err, services.ErrVCardInvalidData) {
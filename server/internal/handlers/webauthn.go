@@ -181,7 +181,7 @@ func (h *WebAuthnHandler) FinishLogin(c echo.Context) error {
 		return response.InternalError(c, "err.failed_to_finish_webauthn_login")
 	}
 
-	authResp, err := h.authService.RefreshToken(&middleware.JWTClaims{UserID: userID})
+	authResp, err := h.authService.SignIn(userID)
 	if err != nil {
 		return response.InternalError(c, "err.failed_to_generate_token")
 	}
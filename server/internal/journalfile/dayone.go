@@ -0,0 +1,125 @@
+package journalfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dayOneExport is the JSON file of a Day One journal export.
+type dayOneExport struct {
+	Entries []dayOneEntry `json:"entries"`
+}
+
+type dayOneEntry struct {
+	UUID         string        `json:"uuid"`
+	CreationDate string        `json:"creationDate"`
+	TimeZone     string        `json:"timeZone"`
+	Text         string        `json:"text"`
+	Tags         []string      `json:"tags"`
+	Photos       []dayOnePhoto `json:"photos"`
+}
+
+type dayOnePhoto struct {
+	Identifier string `json:"identifier"`
+	MD5        string `json:"md5"`
+	Type       string `json:"type"`
+}
+
+var (
+	// dayOneMoment is how an entry's text places its photos.
+	dayOneMoment = regexp.MustCompile(`!\[[^\]]*\]\(dayone-moment:/+([^)\s]+)\)`)
+	// dayOneEscape matches the backslashes Day One puts before Markdown
+	// punctuation in plain text.
+	dayOneEscape = regexp.MustCompile(`\\([\\.!\-()\[\]#*_+{}>` + "`" + `])`)
+)
+
+func parseDayOne(data []byte, opts Options) (*Journal, error) {
+	files, err := readArchive(data, opts.Filename)
+	if err != nil {
+		return nil, err
+	}
+	media := map[string][]byte{}
+	for _, f := range files {
+		if !strings.EqualFold(path.Ext(f.name), ".json") {
+			stem := strings.TrimSuffix(path.Base(f.name), path.Ext(f.name))
+			media[strings.ToLower(stem)] = f.data
+		}
+	}
+
+	j := &Journal{}
+	found := false
+	for _, f := range files {
+		if !strings.EqualFold(path.Ext(f.name), ".json") {
+			continue
+		}
+		var export dayOneExport
+		if err := json.Unmarshal(f.data, &export); err != nil || export.Entries == nil {
+			continue
+		}
+		found = true
+		for _, e := range export.Entries {
+			entry, err := dayOneToEntry(e, opts, media)
+			if err != nil {
+				j.Skipped = append(j.Skipped, fmt.Sprintf("%s: entry %s: %v", f.name, e.UUID, err))
+				continue
+			}
+			entry.Source = f.name
+			j.Entries = append(j.Entries, *entry)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: no Day One journal JSON", ErrInvalidExport)
+	}
+	return j, nil
+}
+
+func dayOneToEntry(e dayOneEntry, opts Options, media map[string][]byte) (*Entry, error) {
+	date, err := time.Parse(time.RFC3339, e.CreationDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid creationDate %q", e.CreationDate)
+	}
+	if loc, err := time.LoadLocation(e.TimeZone); e.TimeZone != "" && err == nil {
+		date = date.In(loc)
+	} else {
+		date = date.In(opts.Location)
+	}
+	entry := &Entry{Date: date, Published: true}
+	for _, tag := range e.Tags {
+		entry.Tags = appendUnique(entry.Tags, tag)
+	}
+
+	photos := map[string]dayOnePhoto{}
+	for _, p := range e.Photos {
+		photos[p.Identifier] = p
+	}
+	text := dayOneMoment.ReplaceAllStringFunc(normalizeText([]byte(e.Text)), func(ref string) string {
+		p, ok := photos[dayOneMoment.FindStringSubmatch(ref)[1]]
+		if !ok {
+			return ref
+		}
+		content, ok := media[strings.ToLower(p.MD5)]
+		if !ok {
+			return ""
+		}
+		ext := p.Type
+		if ext == "" {
+			ext = "jpeg"
+		}
+		entry.Photos = append(entry.Photos, Photo{Name: p.MD5 + "." + ext, Data: content})
+		return ""
+	})
+	text = dayOneEscape.ReplaceAllString(text, "$1")
+
+	// Day One shows the first line of an entry as its title.
+	text = strings.TrimLeft(text, "\n")
+	if first, rest, _ := strings.Cut(text, "\n"); strings.HasPrefix(first, "# ") {
+		entry.Title = strings.TrimSpace(strings.TrimPrefix(first, "# "))
+		text = rest
+	}
+	entry.Sections = []Section{{Content: strings.TrimSpace(text)}}
+	return entry, nil
+}
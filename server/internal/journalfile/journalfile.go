@@ -0,0 +1,238 @@
+// Package journalfile reads and writes journal entries as files: Markdown
+// files with YAML front matter as Bonds exports them, Day One JSON exports
+// and Obsidian daily notes.
+package journalfile
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Journal formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatDayOne   = "dayone"
+	FormatObsidian = "obsidian"
+)
+
+const (
+	// maxFileSize bounds each file read from an archive.
+	maxFileSize = 50 << 20
+	// maxArchiveSize bounds all the files of an archive together, three
+	// times the largest upload accepted, so a small zip of highly
+	// compressed files cannot fill the server's memory.
+	maxArchiveSize = 300 << 20
+	// maxArchiveFiles bounds how many files an archive may hold.
+	maxArchiveFiles = 20000
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown journal format")
+	ErrInvalidExport = errors.New("invalid journal export")
+)
+
+// Options tell the parsers what the files themselves do not.
+type Options struct {
+	// Filename is the name of the uploaded file, which dates an Obsidian
+	// daily note uploaded on its own.
+	Filename string
+	// Location is the time zone of dates without one. Defaults to UTC.
+	Location *time.Location
+}
+
+type Entry struct {
+	// Source is the file the entry was read from.
+	Source    string
+	Title     string
+	Date      time.Time
+	Published bool
+	Slice     string
+	Tags      []string
+	// Contacts are the people the entry is about, by name. Mentions are
+	// the names it links to, which may be people or anything else.
+	Contacts []string
+	Mentions []string
+	Metrics  []Metric
+	Sections []Section
+	Photos   []Photo
+}
+
+type Section struct {
+	Label   string
+	Content string
+}
+
+type Metric struct {
+	Label string
+	Value int
+}
+
+type Photo struct {
+	Name string
+	Data []byte
+}
+
+// Journal is what was read from an export. Skipped lists the files that
+// were left out, with the reason.
+type Journal struct {
+	Entries []Entry
+	Skipped []string
+}
+
+// Parse reads an export of the given format, a single file or a zip.
+func Parse(format string, data []byte, opts Options) (*Journal, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	var (
+		j   *Journal
+		err error
+	)
+	switch format {
+	case FormatMarkdown:
+		j, err = parseMarkdown(data, opts, false)
+	case FormatObsidian:
+		j, err = parseMarkdown(data, opts, true)
+	case FormatDayOne:
+		j, err = parseDayOne(data, opts)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(j.Entries, func(a, b int) bool { return j.Entries[a].Date.Before(j.Entries[b].Date) })
+	return j, nil
+}
+
+// archiveFile is a file of an export.
+type archiveFile struct {
+	name string
+	data []byte
+}
+
+// readArchive lists the files of a zip, or the upload itself when it is
+// not one. Folders that apps keep their own state in are left out.
+func readArchive(data []byte, filename string) ([]archiveFile, error) {
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return []archiveFile{{name: path.Base(filename), data: data}}, nil
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	if len(zr.File) > maxArchiveFiles {
+		return nil, fmt.Errorf("%w: more than %d files", ErrInvalidExport, maxArchiveFiles)
+	}
+	var files []archiveFile
+	var total int64
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(path.Clean(strings.ReplaceAll(f.Name, "\\", "/")), "/")
+		if f.FileInfo().IsDir() || hiddenPath(name) {
+			continue
+		}
+		if f.UncompressedSize64 > maxFileSize {
+			return nil, fmt.Errorf("%w: %s is too large", ErrInvalidExport, name)
+		}
+		// Reading stops one byte past what the file may still add to the
+		// archive's total.
+		limit := min(int64(maxFileSize), maxArchiveSize-total)
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, limit+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+		}
+		if int64(len(content)) > limit {
+			if limit < maxFileSize {
+				return nil, fmt.Errorf("%w: the archive expands to more than %d MiB", ErrInvalidExport, maxArchiveSize>>20)
+			}
+			return nil, fmt.Errorf("%w: %s is too large", ErrInvalidExport, name)
+		}
+		total += int64(len(content))
+		files = append(files, archiveFile{name: name, data: content})
+	}
+	return files, nil
+}
+
+func hiddenPath(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+var imageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".heic": true, ".heif": true,
+}
+
+func isImage(name string) bool {
+	return imageExtensions[strings.ToLower(path.Ext(name))]
+}
+
+// fileDate finds a YYYY-MM-DD date in a file name, as daily notes and
+// Bonds exports are named.
+var fileDate = regexp.MustCompile(`(\d{4})-(\d{2})-(\d{2})`)
+
+func dateFromName(name string, loc *time.Location) time.Time {
+	m := fileDate.FindString(path.Base(name))
+	if m == "" {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation("2006-01-02", m, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// parseDate reads the dates people write in front matter. Dates without
+// a time are midnight in loc.
+func parseDate(s string, loc *time.Location) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// appendUnique adds the names not in list yet, ignoring case.
+func appendUnique(list []string, names ...string) []string {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, existing := range list {
+			if strings.EqualFold(existing, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, name)
+		}
+	}
+	return list
+}
+
+func normalizeText(data []byte) string {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	return strings.ReplaceAll(text, "\r\n", "\n")
+}
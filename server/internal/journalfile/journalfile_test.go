@@ -0,0 +1,169 @@
+package journalfile
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	lisbon, _ := time.LoadLocation("Europe/Lisbon")
+	entry := Entry{
+		Title:     "Trip to Porto",
+		Date:      time.Date(2024, 6, 1, 0, 30, 0, 0, lisbon),
+		Published: true,
+		Slice:     "Summer 2024",
+		Tags:      []string{"travel", "family"},
+		Contacts:  []string{"Alice Smith"},
+		Metrics:   []Metric{{Label: "Steps", Value: 12000}},
+		Sections: []Section{
+			{Label: "Story", Content: "We met [[Alice Smith]] at the station."},
+			{Label: "Thoughts", Content: "## Not a section\nWorth it."},
+		},
+		Photos: []Photo{{Name: "bridge.jpg", Data: []byte("jpeg")}, {Name: "bridge.jpg", Data: []byte("jpeg2")}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, []Entry{entry, {Title: "Trip to Porto", Date: entry.Date, Sections: []Section{{Content: "Again"}}}}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	want := "photos/2024-06-01-trip-to-porto/bridge.jpg photos/2024-06-01-trip-to-porto/bridge-2.jpg 2024-06-01-trip-to-porto.md 2024-06-01-trip-to-porto-2.md"
+	if strings.Join(names, " ") != want {
+		t.Errorf("files = %v", names)
+	}
+
+	j, err := Parse(FormatMarkdown, buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(j.Entries) != 2 || len(j.Skipped) != 0 {
+		t.Fatalf("expected 2 entries, got %d (skipped %v)", len(j.Entries), j.Skipped)
+	}
+	got := j.Entries[0]
+	if got.Source != "2024-06-01-trip-to-porto.md" {
+		got = j.Entries[1]
+	}
+	if got.Title != entry.Title || !got.Date.Equal(entry.Date) || !got.Published || got.Slice != entry.Slice {
+		t.Errorf("entry = %+v", got)
+	}
+	if strings.Join(got.Tags, ",") != "travel,family" || strings.Join(got.Contacts, ",") != "Alice Smith" || strings.Join(got.Mentions, ",") != "Alice Smith" {
+		t.Errorf("tags %v, contacts %v, mentions %v", got.Tags, got.Contacts, got.Mentions)
+	}
+	if len(got.Metrics) != 1 || got.Metrics[0] != entry.Metrics[0] {
+		t.Errorf("metrics = %v", got.Metrics)
+	}
+	if len(got.Sections) != 2 || got.Sections[0] != entry.Sections[0] || got.Sections[1] != entry.Sections[1] {
+		t.Errorf("sections = %+v", got.Sections)
+	}
+	if len(got.Photos) != 2 || got.Photos[1].Name != "bridge-2.jpg" || string(got.Photos[1].Data) != "jpeg2" {
+		t.Errorf("photos = %+v", got.Photos)
+	}
+}
+
+func TestParseObsidianDailyNotes(t *testing.T) {
+	data := zipFiles(t, map[string]string{
+		"Daily/2024-03-10.md":      "---\ntags: diary, walks\n---\nWalked with [[People/Bob Jones|Bob]] and [[Carol]] #outdoors\n\n![[river.png]]\n\n```\n#not-a-tag\n```\n",
+		"Daily/2024-03-11.md":      "# Heading\nQuiet day #1",
+		"People/Bob Jones.md":      "Bob's page",
+		"Attachments/river.png":    "png",
+		".obsidian/app.json":       "{}",
+		"Daily/Trip 2024-03-12.md": "Packed",
+	})
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	j, err := Parse(FormatObsidian, data, Options{Location: loc})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(j.Entries) != 3 || len(j.Skipped) != 1 || !strings.HasPrefix(j.Skipped[0], "People/Bob Jones.md: no date") {
+		t.Fatalf("entries %d, skipped %v", len(j.Entries), j.Skipped)
+	}
+	first := j.Entries[0]
+	if !first.Date.Equal(time.Date(2024, 3, 10, 0, 0, 0, 0, loc)) || first.Title != "" {
+		t.Errorf("first entry dated %v titled %q", first.Date, first.Title)
+	}
+	if strings.Join(first.Tags, ",") != "diary,walks,outdoors" {
+		t.Errorf("tags = %v", first.Tags)
+	}
+	if strings.Join(first.Mentions, ",") != "Bob Jones,Carol" {
+		t.Errorf("mentions = %v", first.Mentions)
+	}
+	if len(first.Photos) != 1 || first.Photos[0].Name != "river.png" || strings.Contains(first.Sections[0].Content, "river") {
+		t.Errorf("photos %+v, content %q", first.Photos, first.Sections[0].Content)
+	}
+	if len(j.Entries[1].Tags) != 0 {
+		t.Errorf("expected headings and numbers not to be tags, got %v", j.Entries[1].Tags)
+	}
+	if j.Entries[2].Title != "Trip 2024-03-12" {
+		t.Errorf("expected a dated note with a name to keep it as title, got %q", j.Entries[2].Title)
+	}
+}
+
+const dayOneJSON = `{"metadata":{"version":"1.0"},"entries":[
+{"uuid":"A1","creationDate":"2024-02-01T18:30:00Z","timeZone":"Europe/Paris","text":"# Dinner\n\nGreat food\\! ![](dayone-moment://P1)\nSee you soon\\.","tags":["food"],"photos":[{"identifier":"P1","md5":"abc123","type":"jpeg"}]},
+{"uuid":"A2","creationDate":"not a date","text":"Broken"}]}`
+
+func TestParseDayOne(t *testing.T) {
+	data := zipFiles(t, map[string]string{"Journal.json": dayOneJSON, "photos/abc123.jpeg": "jpeg"})
+	j, err := Parse(FormatDayOne, data, Options{})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(j.Entries) != 1 || len(j.Skipped) != 1 {
+		t.Fatalf("entries %d, skipped %v", len(j.Entries), j.Skipped)
+	}
+	e := j.Entries[0]
+	if e.Title != "Dinner" || e.Sections[0].Content != "Great food! \nSee you soon." {
+		t.Errorf("title %q, content %q", e.Title, e.Sections[0].Content)
+	}
+	if _, offset := e.Date.Zone(); offset != 3600 || e.Date.Hour() != 19 {
+		t.Errorf("expected the entry's time zone, got %v", e.Date)
+	}
+	if len(e.Tags) != 1 || len(e.Photos) != 1 || e.Photos[0].Name != "abc123.jpeg" {
+		t.Errorf("tags %v, photos %+v", e.Tags, e.Photos)
+	}
+
+	if _, err := Parse(FormatDayOne, []byte(`{"not":"day one"}`), Options{Filename: "x.json"}); !errors.Is(err, ErrInvalidExport) {
+		t.Errorf("expected ErrInvalidExport, got %v", err)
+	}
+	if _, err := Parse("notion", nil, Options{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestReadArchiveLimitsFileCount(t *testing.T) {
+	files := make(map[string]string, maxArchiveFiles+1)
+	for i := 0; i <= maxArchiveFiles; i++ {
+		files[fmt.Sprintf("notes/%d.md", i)] = ""
+	}
+	if _, err := readArchive(zipFiles(t, files), "export.zip"); !errors.Is(err, ErrInvalidExport) {
+		t.Errorf("expected ErrInvalidExport, got %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package journalfile
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter is the YAML header of an entry file. Obsidian notes may use
+// any of it, or none.
+type frontMatter struct {
+	Title     string         `yaml:"title,omitempty"`
+	Date      string         `yaml:"date,omitempty"`
+	Created   string         `yaml:"created,omitempty"`
+	Published *bool          `yaml:"published,omitempty"`
+	Slice     string         `yaml:"slice,omitempty"`
+	Tags      stringList     `yaml:"tags,omitempty"`
+	Contacts  stringList     `yaml:"contacts,omitempty"`
+	People    stringList     `yaml:"people,omitempty"`
+	Metrics   map[string]int `yaml:"metrics,omitempty"`
+	Sections  []string       `yaml:"sections,omitempty"`
+	Photos    []string       `yaml:"photos,omitempty"`
+}
+
+// stringList reads a YAML list, or a comma separated string as Obsidian
+// allows for tags.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*l = nil
+		for _, item := range strings.Split(value.Value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*l = append(*l, item)
+			}
+		}
+		return nil
+	case yaml.SequenceNode:
+		var items []string
+		if err := value.Decode(&items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	}
+	return fmt.Errorf("line %d: expected a list", value.Line)
+}
+
+var (
+	// wikiLink matches [[Name]], [[Name|alias]], [[Name#heading]] and
+	// embeds such as ![[photo.jpg]].
+	wikiLink = regexp.MustCompile(`(!?)\[\[([^\]|#\n]+)(?:#[^\]|\n]*)?(?:\|[^\]\n]*)?\]\]`)
+	// inlineTag matches #tags, but not headings or anchors in links.
+	inlineTag = regexp.MustCompile(`(?:^|[\s(])#([\p{L}\p{N}_/-]*[\p{L}_/-][\p{L}\p{N}_/-]*)`)
+)
+
+func parseMarkdown(data []byte, opts Options, obsidian bool) (*Journal, error) {
+	files, err := readArchive(data, opts.Filename)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string][]byte{}
+	byBase := map[string]string{}
+	for _, f := range files {
+		byName[f.name] = f.data
+		if _, ok := byBase[strings.ToLower(path.Base(f.name))]; !ok {
+			byBase[strings.ToLower(path.Base(f.name))] = f.name
+		}
+	}
+
+	j := &Journal{}
+	for _, f := range files {
+		if !strings.EqualFold(path.Ext(f.name), ".md") {
+			continue
+		}
+		entry, err := parseMarkdownEntry(f, opts, obsidian, byName, byBase)
+		if err != nil {
+			j.Skipped = append(j.Skipped, fmt.Sprintf("%s: %v", f.name, err))
+			continue
+		}
+		j.Entries = append(j.Entries, *entry)
+	}
+	if len(j.Entries) == 0 && len(j.Skipped) == 0 {
+		return nil, fmt.Errorf("%w: no Markdown files", ErrInvalidExport)
+	}
+	return j, nil
+}
+
+func parseMarkdownEntry(f archiveFile, opts Options, obsidian bool, byName map[string][]byte, byBase map[string]string) (*Entry, error) {
+	text := normalizeText(f.data)
+	var fm frontMatter
+	header, body, ok := splitFrontMatter(text)
+	if ok {
+		if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+			return nil, fmt.Errorf("invalid front matter: %v", err)
+		}
+	} else {
+		body = text
+	}
+
+	entry := &Entry{Source: f.name, Title: strings.TrimSpace(fm.Title), Slice: strings.TrimSpace(fm.Slice), Published: true}
+	if fm.Published != nil {
+		entry.Published = *fm.Published
+	}
+	for _, s := range []string{fm.Date, fm.Created} {
+		if entry.Date = parseDate(s, opts.Location); !entry.Date.IsZero() {
+			break
+		}
+	}
+	if entry.Date.IsZero() {
+		entry.Date = dateFromName(f.name, opts.Location)
+	}
+	if entry.Date.IsZero() {
+		return nil, fmt.Errorf("no date")
+	}
+	if entry.Title == "" && obsidian {
+		base := strings.TrimSuffix(path.Base(f.name), path.Ext(f.name))
+		if !fileDate.MatchString(base) || len(base) > len("2006-01-02") {
+			entry.Title = base
+		}
+	}
+
+	for _, tag := range fm.Tags {
+		entry.Tags = appendUnique(entry.Tags, strings.TrimPrefix(tag, "#"))
+	}
+	entry.Contacts = appendUnique(entry.Contacts, fm.Contacts...)
+	entry.Contacts = appendUnique(entry.Contacts, fm.People...)
+	labels := make([]string, 0, len(fm.Metrics))
+	for label := range fm.Metrics {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		entry.Metrics = append(entry.Metrics, Metric{Label: label, Value: fm.Metrics[label]})
+	}
+
+	dir := path.Dir(f.name)
+	addPhoto := func(ref string) bool {
+		name := path.Clean(path.Join(dir, ref))
+		content, ok := byName[name]
+		if !ok {
+			if name, ok = byBase[strings.ToLower(path.Base(ref))]; ok {
+				content = byName[name]
+			}
+		}
+		if !ok || !isImage(name) {
+			return false
+		}
+		entry.Photos = append(entry.Photos, Photo{Name: path.Base(name), Data: content})
+		return true
+	}
+	for _, ref := range fm.Photos {
+		// Bonds exports list photos from the root of the archive.
+		if content, ok := byName[path.Clean(ref)]; ok && isImage(ref) {
+			entry.Photos = append(entry.Photos, Photo{Name: path.Base(ref), Data: content})
+		} else {
+			addPhoto(ref)
+		}
+	}
+
+	body = wikiLink.ReplaceAllStringFunc(body, func(link string) string {
+		m := wikiLink.FindStringSubmatch(link)
+		target := strings.TrimSpace(m[2])
+		if m[1] == "!" {
+			if addPhoto(target) {
+				return ""
+			}
+			return link
+		}
+		entry.Mentions = appendUnique(entry.Mentions, path.Base(target))
+		return link
+	})
+	if obsidian {
+		for _, m := range inlineTag.FindAllStringSubmatch(stripCode(body), -1) {
+			entry.Tags = appendUnique(entry.Tags, m[1])
+		}
+	}
+	entry.Sections = splitSections(body, fm.Sections)
+	return entry, nil
+}
+
+// splitFrontMatter separates a leading "---" YAML block from the body.
+func splitFrontMatter(text string) (string, string, bool) {
+	if !strings.HasPrefix(text, "---\n") {
+		return "", text, false
+	}
+	rest := text[len("---\n"):]
+	if strings.HasPrefix(rest, "---\n") || rest == "---" {
+		return "", strings.TrimPrefix(rest, "---"), true
+	}
+	end := strings.Index(rest, "\n---\n")
+	if end < 0 {
+		if !strings.HasSuffix(rest, "\n---") {
+			return "", text, false
+		}
+		end = len(rest) - len("\n---")
+		return rest[:end], "", true
+	}
+	return rest[:end], rest[end+len("\n---\n"):], true
+}
+
+// splitSections cuts a body at the "## Label" headings of the listed
+// sections, in order. Anything before the first of them is a section
+// without a label.
+func splitSections(body string, labels []string) []Section {
+	var sections []Section
+	current := Section{}
+	var lines []string
+	next := 0
+	flush := func() {
+		current.Content = strings.TrimSpace(strings.Join(lines, "\n"))
+		if current.Label != "" || current.Content != "" {
+			sections = append(sections, current)
+		}
+		lines = nil
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if next < len(labels) && strings.TrimSpace(line) == "## "+labels[next] {
+			flush()
+			current = Section{Label: labels[next]}
+			next++
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	if len(sections) == 0 {
+		sections = []Section{{}}
+	}
+	return sections
+}
+
+// stripCode blanks out fenced and inline code, where # is not a tag.
+var codeSpans = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+
+func stripCode(s string) string {
+	return codeSpans.ReplaceAllString(s, " ")
+}
+
+// Markdown renders an entry as a Markdown file with YAML front matter.
+// photos are the archive paths of its photos.
+func Markdown(e Entry, photos []string) ([]byte, error) {
+	published := e.Published
+	fm := frontMatter{
+		Title:     e.Title,
+		Date:      e.Date.Format(time.RFC3339),
+		Published: &published,
+		Slice:     e.Slice,
+		Tags:      e.Tags,
+		Contacts:  e.Contacts,
+		Photos:    photos,
+	}
+	if len(e.Metrics) > 0 {
+		fm.Metrics = map[string]int{}
+		for _, m := range e.Metrics {
+			fm.Metrics[m.Label] = m.Value
+		}
+	}
+	var body strings.Builder
+	headings := len(e.Sections) > 1 || (len(e.Sections) == 1 && e.Sections[0].Label != "")
+	for _, s := range e.Sections {
+		if headings && s.Label != "" {
+			fm.Sections = append(fm.Sections, s.Label)
+			body.WriteString("## " + s.Label + "\n\n")
+		}
+		if content := strings.TrimSpace(s.Content); content != "" {
+			body.WriteString(content + "\n\n")
+		}
+	}
+	header, err := yaml.Marshal(fm)
+	if err != nil {
+		return nil, err
+	}
+	return []byte("---\n" + string(header) + "---\n\n" + strings.TrimRight(body.String(), "\n") + "\n"), nil
+}
@@ -0,0 +1,103 @@
+package journalfile
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+)
+
+const maxSlugLength = 60
+
+// Write writes entries as a zip of Markdown files named after their date
+// and title, with their photos in a folder of the same name under photos/.
+func Write(w io.Writer, entries []Entry) error {
+	zw := zip.NewWriter(w)
+	used := map[string]bool{}
+	for _, e := range entries {
+		base := uniqueName(entryBaseName(e), used)
+		var photoPaths []string
+		photoNames := map[string]bool{}
+		for _, photo := range e.Photos {
+			name := uniquePhotoName(path.Base(strings.ReplaceAll(photo.Name, "\\", "/")), photoNames)
+			photoPath := "photos/" + base + "/" + name
+			fw, err := zw.Create(photoPath)
+			if err != nil {
+				return err
+			}
+			if _, err := fw.Write(photo.Data); err != nil {
+				return err
+			}
+			photoPaths = append(photoPaths, photoPath)
+		}
+		content, err := Markdown(e, photoPaths)
+		if err != nil {
+			return fmt.Errorf("entry %s: %w", base, err)
+		}
+		fw, err := zw.Create(base + ".md")
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func entryBaseName(e Entry) string {
+	name := e.Date.Format("2006-01-02")
+	if slug := slugify(e.Title); slug != "" {
+		name += "-" + slug
+	}
+	return name
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+func uniquePhotoName(name string, used map[string]bool) string {
+	if name == "" || name == "." || name == "/" {
+		name = "photo"
+	}
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s-%d%s", stem, i, ext)
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+// slugify keeps the letters and digits of a title, joined by dashes.
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	count := 0
+	for _, r := range strings.ToLower(title) {
+		if count >= maxSlugLength {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			count++
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+			count++
+		}
+	}
+	return strings.TrimRight(b.String(), "-")
+}
@@ -0,0 +1,205 @@
+package mailin
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+)
+
+// IMAP connection security modes.
+const (
+	SecurityTLS      = "tls"
+	SecuritySTARTTLS = "starttls"
+	SecurityNone     = "none"
+)
+
+// maxMessageSize bounds one message read over IMAP.
+const maxMessageSize = 50 << 20
+
+// FlagFailed marks messages that could not be imported, so that later polls
+// skip them instead of failing on them forever. They stay unseen for the
+// user to look at; removing the keyword makes the poller try again.
+const FlagFailed imap.Flag = "$BondsFailed"
+
+// IMAPConfig is the mailbox the IMAP poller reads.
+type IMAPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// Security is SecurityTLS (the default), SecuritySTARTTLS or SecurityNone.
+	Security string
+	// Mailbox defaults to INBOX.
+	Mailbox string
+	// Limit caps the messages read per poll; 0 means 100.
+	Limit int
+	// Timeout bounds connecting to the server; 0 means 30 seconds. The
+	// client bounds each response itself.
+	Timeout time.Duration
+	// TLSConfig overrides the TLS settings, for tests.
+	TLSConfig *tls.Config
+}
+
+// FetchUnseen reads the unseen messages of the mailbox and passes each one
+// to handle. Messages handle accepts are flagged \Seen so the next poll
+// skips them. Messages handle fails on and messages that are too large are
+// flagged FlagFailed, or \Seen when the mailbox does not keep custom
+// keywords, so that they cannot take up every poll. It returns how many
+// messages were handled.
+func FetchUnseen(cfg IMAPConfig, handle func(raw []byte) error) (int, error) {
+	c, err := dialIMAP(cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	if err := c.Login(cfg.Username, cfg.Password).Wait(); err != nil {
+		return 0, fmt.Errorf("IMAP login: %w", err)
+	}
+	mailbox := cfg.Mailbox
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	selected, err := c.Select(mailbox, nil).Wait()
+	if err != nil {
+		return 0, fmt.Errorf("IMAP select %s: %w", mailbox, err)
+	}
+	failed := imap.FlagSeen
+	if slices.Contains(selected.PermanentFlags, imap.FlagWildcard) {
+		failed = FlagFailed
+	}
+	search, err := c.UIDSearch(&imap.SearchCriteria{NotFlag: []imap.Flag{imap.FlagSeen, FlagFailed}}, nil).Wait()
+	if err != nil {
+		return 0, fmt.Errorf("IMAP search: %w", err)
+	}
+	uids := search.AllUIDs()
+	limit := cfg.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if len(uids) > limit {
+		uids = uids[:limit]
+	}
+
+	handled := 0
+	var handleErrs []error
+	for _, uid := range uids {
+		raw, err := fetchMessage(c, uid)
+		switch {
+		case errors.Is(err, errMessageTooLarge):
+			// Flagged like a message handle fails on.
+		case err != nil:
+			return handled, err
+		case raw == nil:
+			continue
+		default:
+			err = handle(raw)
+		}
+		if err != nil {
+			handleErrs = append(handleErrs, fmt.Errorf("message %d: %w", uid, err))
+			if err := storeFlag(c, uid, failed); err != nil {
+				return handled, err
+			}
+			continue
+		}
+		if err := storeFlag(c, uid, imap.FlagSeen); err != nil {
+			return handled, err
+		}
+		handled++
+	}
+	_ = c.Logout().Wait()
+	return handled, errors.Join(handleErrs...)
+}
+
+var errMessageTooLarge = fmt.Errorf("message is larger than %d bytes", maxMessageSize)
+
+func storeFlag(c *imapclient.Client, uid imap.UID, flag imap.Flag) error {
+	store := &imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{flag}}
+	if err := c.Store(imap.UIDSetNum(uid), store, nil).Close(); err != nil {
+		return fmt.Errorf("IMAP store: %w", err)
+	}
+	return nil
+}
+
+func dialIMAP(cfg IMAPConfig) (*imapclient.Client, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	security := cfg.Security
+	if security == "" {
+		security = SecurityTLS
+	}
+	port := cfg.Port
+	if port == "" {
+		port = "993"
+		if security != SecurityTLS {
+			port = "143"
+		}
+	}
+	tlsConfig := cfg.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: cfg.Host}
+	}
+	options := &imapclient.Options{TLSConfig: tlsConfig, Dialer: &net.Dialer{Timeout: timeout}}
+	addr := net.JoinHostPort(cfg.Host, port)
+
+	var c *imapclient.Client
+	var err error
+	switch security {
+	case SecurityTLS:
+		c, err = imapclient.DialTLS(addr, options)
+	case SecuritySTARTTLS:
+		c, err = imapclient.DialStartTLS(addr, options)
+	case SecurityNone:
+		c, err = imapclient.DialInsecure(addr, options)
+	default:
+		return nil, fmt.Errorf("unknown IMAP security %q", security)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect to IMAP server: %w", err)
+	}
+	if err := c.WaitGreeting(); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("IMAP greeting: %w", err)
+	}
+	return c, nil
+}
+
+// fetchMessage reads the whole message without flagging it \Seen. It
+// returns nil when the message is gone.
+func fetchMessage(c *imapclient.Client, uid imap.UID) ([]byte, error) {
+	cmd := c.Fetch(imap.UIDSetNum(uid), &imap.FetchOptions{
+		BodySection: []*imap.FetchItemBodySection{{Peek: true}},
+	})
+	var raw []byte
+	var readErr error
+	for msg := cmd.Next(); msg != nil; msg = cmd.Next() {
+		for item := msg.Next(); item != nil; item = msg.Next() {
+			body, ok := item.(imapclient.FetchItemDataBodySection)
+			if !ok || body.Literal == nil || readErr != nil {
+				continue
+			}
+			data, err := io.ReadAll(io.LimitReader(body.Literal, maxMessageSize+1))
+			switch {
+			case err != nil:
+				readErr = err
+			case len(data) > maxMessageSize:
+				readErr = errMessageTooLarge
+			default:
+				raw = data
+			}
+		}
+	}
+	if err := cmd.Close(); err != nil {
+		return nil, fmt.Errorf("IMAP fetch: %w", err)
+	}
+	return raw, readErr
+}
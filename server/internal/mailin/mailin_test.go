@@ -0,0 +1,315 @@
+package mailin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapserver"
+	"github.com/emersion/go-imap/v2/imapserver/imapmemserver"
+)
+
+const testMessage = "Message-ID: <abc@example.com>\r\n" +
+	"From: Alice Example <Alice@Example.com>\r\n" +
+	"To: bonds+token123@bonds.test, Bob <bob@example.com>\r\n" +
+	"Cc: carol@example.com, alice@example.com\r\n" +
+	"Delivered-To: bonds+token123@bonds.test\r\n" +
+	"Subject: =?UTF-8?Q?Caf=C3=A9_tomorrow?=\r\n" +
+	"Date: Mon, 05 Oct 2026 10:30:00 +0200\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"See you at the caf=C3=A9.\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>See you at the caf&eacute;.</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/plain; name=\"agenda.txt\"\r\n" +
+	"Content-Disposition: attachment; filename=\"agenda.txt\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"Q29mZmVlIGFu\r\n" +
+	"ZCBjYWtl\r\n" +
+	"--outer--\r\n"
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(testMessage))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if m.MessageID != "abc@example.com" {
+		t.Errorf("MessageID = %q", m.MessageID)
+	}
+	if m.Subject != "Café tomorrow" {
+		t.Errorf("Subject = %q", m.Subject)
+	}
+	if m.Text != "See you at the café." {
+		t.Errorf("Text = %q", m.Text)
+	}
+	if m.Date.IsZero() || m.Date.UTC().Hour() != 8 {
+		t.Errorf("Date = %v", m.Date)
+	}
+	if len(m.DeliveredTo) != 1 || m.DeliveredTo[0] != "bonds+token123@bonds.test" {
+		t.Errorf("DeliveredTo = %v", m.DeliveredTo)
+	}
+	want := []string{"alice@example.com", "bonds+token123@bonds.test", "bob@example.com", "carol@example.com"}
+	if got := m.Addresses(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Addresses = %v, want %v", got, want)
+	}
+	if len(m.Attachments) != 1 {
+		t.Fatalf("expected 1 attachment, got %d", len(m.Attachments))
+	}
+	if a := m.Attachments[0]; a.Filename != "agenda.txt" || string(a.Data) != "Coffee and cake" {
+		t.Errorf("attachment = %q %q", a.Filename, a.Data)
+	}
+}
+
+func TestParseHTMLOnlyAndMissingMessageID(t *testing.T) {
+	raw := "From: a@example.com\r\nContent-Type: text/html\r\n\r\n<div>Hello<br>there &amp; bye</div><style>p{}</style>"
+	m, err := Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if m.Text != "Hello\nthere & bye" {
+		t.Errorf("Text = %q", m.Text)
+	}
+	if !strings.HasPrefix(m.MessageID, "sha256:") {
+		t.Errorf("MessageID = %q", m.MessageID)
+	}
+	again, _ := Parse([]byte(raw))
+	if again.MessageID != m.MessageID {
+		t.Error("expected a stable MessageID for the same content")
+	}
+}
+
+// serveIMAP serves an in-memory mailbox with the given messages over
+// plain IMAP and returns its address and the user to inspect it.
+func serveIMAP(t *testing.T, messages ...string) (string, string, *imapmemserver.User) {
+	t.Helper()
+	user := imapmemserver.NewUser("inbox", `se"cret`)
+	if err := user.Create("INBOX", nil); err != nil {
+		t.Fatalf("create INBOX: %v", err)
+	}
+	for _, message := range messages {
+		if _, err := user.Append("INBOX", strings.NewReader(message), &imap.AppendOptions{}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	mem := imapmemserver.New()
+	mem.AddUser(user)
+	server := imapserver.New(&imapserver.Options{
+		NewSession: func(*imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			return mem.NewSession(), nil, nil
+		},
+		InsecureAuth: true,
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	return host, port, user
+}
+
+func TestFetchUnseen(t *testing.T) {
+	host, port, user := serveIMAP(t,
+		"Subject: first\r\n\r\nhello\r\n",
+		"Subject: second\r\n\r\nbroken\r\n",
+	)
+	var subjects []string
+	handled, err := FetchUnseen(IMAPConfig{
+		Host: host, Port: port, Username: "inbox", Password: `se"cret`,
+		Security: SecurityNone, Timeout: 5 * time.Second,
+	}, func(raw []byte) error {
+		m, err := Parse(raw)
+		if err != nil {
+			return err
+		}
+		subjects = append(subjects, m.Subject)
+		if m.Subject == "second" {
+			return errors.New("cannot handle")
+		}
+		return nil
+	})
+	if handled != 1 {
+		t.Errorf("handled = %d, want 1", handled)
+	}
+	if err == nil || !strings.Contains(err.Error(), "message 2") {
+		t.Errorf("expected an error for message 2, got %v", err)
+	}
+	if strings.Join(subjects, ",") != "first,second" {
+		t.Errorf("subjects = %v", subjects)
+	}
+	status, err := user.Status("INBOX", &imap.StatusOptions{NumUnseen: true})
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if status.NumUnseen == nil || *status.NumUnseen != 1 {
+		t.Errorf("expected only the failed message to stay unseen, got %v", status.NumUnseen)
+	}
+
+	// The failed message is flagged and not read again.
+	subjects = nil
+	handled, err = FetchUnseen(IMAPConfig{Host: host, Port: port, Username: "inbox", Password: `se"cret`, Security: SecurityNone},
+		func(raw []byte) error {
+			m, _ := Parse(raw)
+			subjects = append(subjects, m.Subject)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("second poll: %v", err)
+	}
+	if handled != 0 || len(subjects) != 0 {
+		t.Errorf("second poll read %v", subjects)
+	}
+	failed, err := user.Status("INBOX", &imap.StatusOptions{NumMessages: true})
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if failed.NumMessages == nil || *failed.NumMessages != 2 {
+		t.Errorf("expected both messages to stay in the mailbox, got %v", failed.NumMessages)
+	}
+}
+
+func TestFetchUnseenSkipsMessagesThatKeepFailing(t *testing.T) {
+	host, port, _ := serveIMAP(t,
+		"Subject: broken 1\r\n\r\nx\r\n",
+		"Subject: broken 2\r\n\r\nx\r\n",
+		"Subject: broken 3\r\n\r\nx\r\n",
+		"Subject: good\r\n\r\nhello\r\n",
+	)
+	cfg := IMAPConfig{Host: host, Port: port, Username: "inbox", Password: `se"cret`, Security: SecurityNone, Limit: 2}
+	var read []string
+	handle := func(raw []byte) error {
+		m, err := Parse(raw)
+		if err != nil {
+			return err
+		}
+		read = append(read, m.Subject)
+		if strings.HasPrefix(m.Subject, "broken") {
+			return errors.New("cannot handle")
+		}
+		return nil
+	}
+
+	// More failing messages than one poll reads must not keep newer mail
+	// from being read.
+	total := 0
+	for range 3 {
+		handled, _ := FetchUnseen(cfg, handle)
+		total += handled
+	}
+	if total != 1 {
+		t.Errorf("expected the good message to be handled once, handled %d", total)
+	}
+	if strings.Join(read, ",") != "broken 1,broken 2,broken 3,good" {
+		t.Errorf("expected every message to be read once, read %v", read)
+	}
+}
+
+func TestFetchUnseenLoginFailure(t *testing.T) {
+	host, port, _ := serveIMAP(t)
+	_, err := FetchUnseen(IMAPConfig{Host: host, Port: port, Username: "x", Password: "y", Security: SecurityNone, Timeout: 5 * time.Second},
+		func([]byte) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "IMAP login") {
+		t.Errorf("expected a login error, got %v", err)
+	}
+}
+
+func TestSMTPServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	type delivery struct {
+		from  string
+		rcpts []string
+		data  string
+	}
+	deliveries := make(chan delivery, 2)
+	server := &SMTPServer{
+		Domain:  "bonds.test",
+		MaxSize: 1024,
+		Accept:  func(rcpt string) bool { return strings.HasSuffix(rcpt, "@bonds.test") },
+		Deliver: func(from string, rcpts []string, data []byte) error {
+			if strings.Contains(string(data), "reject me") {
+				return fmt.Errorf("%w: unknown inbox", ErrRejected)
+			}
+			deliveries <- delivery{from, rcpts, string(data)}
+			return nil
+		},
+	}
+	go server.Serve(l)
+	defer server.Close()
+
+	c, err := smtp.Dial(l.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	if err := c.Mail("alice@example.com"); err != nil {
+		t.Fatalf("MAIL: %v", err)
+	}
+	if err := c.Rcpt("elsewhere@example.com"); err == nil {
+		t.Error("expected a relay attempt to be refused")
+	}
+	if err := c.Rcpt("bonds+abc@bonds.test"); err != nil {
+		t.Fatalf("RCPT: %v", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		t.Fatalf("DATA: %v", err)
+	}
+	fmt.Fprint(w, "Subject: hi\r\n\r\n.leading dot\r\n")
+	if err := w.Close(); err != nil {
+		t.Fatalf("end of DATA: %v", err)
+	}
+
+	select {
+	case d := <-deliveries:
+		if d.from != "alice@example.com" || len(d.rcpts) != 1 || d.rcpts[0] != "bonds+abc@bonds.test" {
+			t.Errorf("unexpected envelope %+v", d)
+		}
+		if !strings.Contains(d.data, "\n.leading dot") {
+			t.Errorf("unexpected data %q", d.data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+
+	for _, body := range []string{"reject me", strings.Repeat("xxxxxxxxxxxxxxx\r\n", 128)} {
+		if err := c.Mail("alice@example.com"); err != nil {
+			t.Fatalf("MAIL: %v", err)
+		}
+		if err := c.Rcpt("bonds@bonds.test"); err != nil {
+			t.Fatalf("RCPT: %v", err)
+		}
+		w, err := c.Data()
+		if err != nil {
+			t.Fatalf("DATA: %v", err)
+		}
+		fmt.Fprint(w, "Subject: hi\r\n\r\n"+body+"\r\n")
+		if err := w.Close(); err == nil {
+			t.Errorf("expected %.10q... to be refused", body)
+		}
+	}
+	if err := c.Quit(); err != nil {
+		t.Errorf("QUIT: %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+// Package mailin receives email for Bonds: it parses messages, polls an
+// IMAP mailbox and runs a small SMTP receiver. It knows nothing about
+// contacts; the services decide what a message means.
+package mailin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxParts bounds how many MIME parts of one message are walked.
+const maxParts = 100
+
+// Message is the part of an email Bonds keeps.
+type Message struct {
+	// MessageID is the Message-ID header without angle brackets. Messages
+	// without one get an ID derived from their content.
+	MessageID string
+	Subject   string
+	Date      time.Time
+	From      []*mail.Address
+	To        []*mail.Address
+	Cc        []*mail.Address
+	// DeliveredTo lists the Delivered-To and X-Original-To headers, which
+	// keep a BCC recipient that the To and Cc headers do not show.
+	DeliveredTo []string
+	Text        string
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to a message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Addresses returns the lowercased From, To and Cc addresses, without
+// duplicates.
+func (m *Message) Addresses() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, list := range [][]*mail.Address{m.From, m.To, m.Cc} {
+		for _, addr := range list {
+			email := strings.ToLower(strings.TrimSpace(addr.Address))
+			if email != "" && !seen[email] {
+				seen[email] = true
+				out = append(out, email)
+			}
+		}
+	}
+	return out
+}
+
+var wordDecoder = new(mime.WordDecoder)
+
+// Parse reads a raw RFC 5322 message.
+func Parse(raw []byte) (*Message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("read message: %w", err)
+	}
+	m := &Message{
+		MessageID: strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>"),
+		Subject:   decodeHeader(msg.Header.Get("Subject")),
+	}
+	if m.MessageID == "" {
+		sum := sha256.Sum256(raw)
+		m.MessageID = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		m.Date = date
+	}
+	m.From = addressList(msg.Header, "From")
+	m.To = addressList(msg.Header, "To")
+	m.Cc = addressList(msg.Header, "Cc")
+	for _, key := range []string{"Delivered-To", "X-Original-To"} {
+		for _, value := range msg.Header[key] {
+			if addr, err := mail.ParseAddress(value); err == nil {
+				m.DeliveredTo = append(m.DeliveredTo, addr.Address)
+			} else if v := strings.Trim(strings.TrimSpace(value), "<>"); v != "" {
+				m.DeliveredTo = append(m.DeliveredTo, v)
+			}
+		}
+	}
+
+	var html string
+	parts := 0
+	var walk func(header map[string][]string, body io.Reader) error
+	walk = func(header map[string][]string, body io.Reader) error {
+		if parts++; parts > maxParts {
+			return nil
+		}
+		get := func(key string) string {
+			if values := header[key]; len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+		mediaType, params, err := mime.ParseMediaType(get("Content-Type"))
+		if err != nil {
+			mediaType, params = "text/plain", map[string]string{}
+		}
+		if strings.HasPrefix(mediaType, "multipart/") {
+			reader := multipart.NewReader(body, params["boundary"])
+			for {
+				part, err := reader.NextRawPart()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if err := walk(part.Header, part); err != nil {
+					return err
+				}
+			}
+		}
+		data, err := io.ReadAll(decodeTransfer(get("Content-Transfer-Encoding"), body))
+		if err != nil {
+			return err
+		}
+		disposition, dispParams, _ := mime.ParseMediaType(get("Content-Disposition"))
+		filename := decodeHeader(dispParams["filename"])
+		if filename == "" {
+			filename = decodeHeader(params["name"])
+		}
+		switch {
+		case disposition == "attachment" || filename != "":
+			if filename == "" {
+				filename = "attachment"
+			}
+			m.Attachments = append(m.Attachments, Attachment{Filename: filename, ContentType: mediaType, Data: data})
+		case mediaType == "text/plain" && m.Text == "":
+			m.Text = strings.TrimSpace(string(data))
+		case mediaType == "text/html" && html == "":
+			html = string(data)
+		}
+		return nil
+	}
+	if err := walk(msg.Header, msg.Body); err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	if m.Text == "" && html != "" {
+		m.Text = htmlToText(html)
+	}
+	return m, nil
+}
+
+func decodeHeader(value string) string {
+	if decoded, err := wordDecoder.DecodeHeader(value); err == nil {
+		return strings.TrimSpace(decoded)
+	}
+	return strings.TrimSpace(value)
+}
+
+func addressList(header mail.Header, key string) []*mail.Address {
+	list, err := header.AddressList(key)
+	if err != nil {
+		return nil
+	}
+	return list
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// newlineStripper drops the line breaks base64 bodies are wrapped with.
+type newlineStripper struct{ r io.Reader }
+
+func (n newlineStripper) Read(p []byte) (int, error) {
+	count, err := n.r.Read(p)
+	out := p[:0]
+	for _, b := range p[:count] {
+		if b != '\r' && b != '\n' {
+			out = append(out, b)
+		}
+	}
+	return len(out), err
+}
+
+var (
+	htmlBlockPattern = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	htmlBreakPattern = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6])[^>]*>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+	blankLinePattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText is a rough conversion for messages that only have an HTML
+// body.
+func htmlToText(html string) string {
+	text := htmlBlockPattern.ReplaceAllString(html, "")
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = strings.NewReplacer("&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&#39;", "'").Replace(text)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLinePattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
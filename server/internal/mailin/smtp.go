@@ -0,0 +1,159 @@
+package mailin
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-smtp"
+)
+
+// ErrRejected is returned by SMTPServer.Deliver to refuse a message for
+// good, instead of asking the sender to try again later.
+var ErrRejected = errors.New("message rejected")
+
+var (
+	errNoMailbox = &smtp.SMTPError{Code: 550, EnhancedCode: smtp.EnhancedCode{5, 1, 1}, Message: "No such mailbox here"}
+	errTryLater  = &smtp.SMTPError{Code: 451, EnhancedCode: smtp.EnhancedCode{4, 3, 0}, Message: "Try again later"}
+)
+
+// SMTPServer receives mail for Bonds over SMTP. It only accepts mail for
+// recipients Accept approves, so it cannot be used as a relay.
+type SMTPServer struct {
+	// Domain is the host name the server greets with.
+	Domain string
+	// MaxSize bounds a message in bytes; 0 means 25 MB.
+	MaxSize int64
+	// MaxRecipients bounds the recipients of a message; 0 means 50.
+	MaxRecipients int
+	Timeout       time.Duration
+	// Accept reports whether mail for the recipient is accepted.
+	Accept func(rcpt string) bool
+	// Deliver stores a message received for the accepted recipients.
+	Deliver func(from string, rcpts []string, data []byte) error
+
+	mu     sync.Mutex
+	server *smtp.Server
+	// deliveries counts the Deliver calls in progress, so Close can wait
+	// for them.
+	deliveries sync.WaitGroup
+	closed     bool
+}
+
+// Serve accepts connections on l until Close is called.
+func (s *SMTPServer) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return net.ErrClosed
+	}
+	s.server = s.newServer()
+	server := s.server
+	s.mu.Unlock()
+	return server.Serve(l)
+}
+
+// Close stops the listener, drops open connections and waits for the
+// messages being delivered.
+func (s *SMTPServer) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	server := s.server
+	s.mu.Unlock()
+	var err error
+	if server != nil {
+		err = server.Close()
+	}
+	s.deliveries.Wait()
+	return err
+}
+
+func (s *SMTPServer) newServer() *smtp.Server {
+	server := smtp.NewServer(smtp.BackendFunc(func(*smtp.Conn) (smtp.Session, error) {
+		return &smtpSession{s: s}, nil
+	}))
+	server.Domain = s.Domain
+	if server.Domain == "" {
+		server.Domain = "localhost"
+	}
+	server.MaxMessageBytes = s.MaxSize
+	if server.MaxMessageBytes <= 0 {
+		server.MaxMessageBytes = 25 << 20
+	}
+	server.MaxRecipients = s.MaxRecipients
+	if server.MaxRecipients <= 0 {
+		server.MaxRecipients = 50
+	}
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	server.ReadTimeout = timeout
+	server.WriteTimeout = timeout
+	return server
+}
+
+type smtpSession struct {
+	s     *SMTPServer
+	from  string
+	rcpts []string
+}
+
+func (ss *smtpSession) Reset() {
+	ss.from, ss.rcpts = "", nil
+}
+
+func (ss *smtpSession) Logout() error {
+	return nil
+}
+
+func (ss *smtpSession) Mail(from string, _ *smtp.MailOptions) error {
+	ss.from = from
+	return nil
+}
+
+func (ss *smtpSession) Rcpt(to string, _ *smtp.RcptOptions) error {
+	if ss.s.Accept == nil || !ss.s.Accept(to) {
+		return errNoMailbox
+	}
+	ss.rcpts = append(ss.rcpts, to)
+	return nil
+}
+
+// Data reads the message, which the server cuts off at MaxSize, and hands
+// it to Deliver.
+func (ss *smtpSession) Data(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return ss.s.deliver(ss.from, ss.rcpts, data)
+}
+
+func (s *SMTPServer) deliver(from string, rcpts []string, data []byte) error {
+	s.mu.Lock()
+	if s.closed || s.Deliver == nil {
+		s.mu.Unlock()
+		return errTryLater
+	}
+	s.deliveries.Add(1)
+	s.mu.Unlock()
+	defer s.deliveries.Done()
+
+	if err := s.Deliver(from, rcpts, data); err != nil {
+		if errors.Is(err, ErrRejected) {
+			return &smtp.SMTPError{Code: 554, EnhancedCode: smtp.EnhancedCode{5, 6, 0}, Message: smtpText(err.Error())}
+		}
+		log.Printf("mailin: delivery failed: %v", err)
+		return errTryLater
+	}
+	return nil
+}
+
+func smtpText(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
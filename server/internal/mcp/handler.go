@@ -143,6 +143,20 @@ func (h *Handler) tools() []toolDefinition {
 			}, []string{"vault_id", "query"}),
 			Annotations: readOnlyAnnotations(),
 		},
+		{
+			Name:        "find_nearby_contacts",
+			Title:       "Find Nearby Contacts",
+			Description: "List the contacts of a vault living near a place or a latitude/longitude, closest first, with their distance in kilometers. Only geocoded addresses are considered.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"vault_id":     map[string]interface{}{"type": "string"},
+				"place":        map[string]interface{}{"type": "string", "description": "Place name to geocode, such as a city or an address. Requires geocoding to be configured."},
+				"latitude":     map[string]interface{}{"type": "number"},
+				"longitude":    map[string]interface{}{"type": "number"},
+				"radius_km":    map[string]interface{}{"type": "number", "description": "Search radius in kilometers, 50 by default."},
+				"include_past": map[string]interface{}{"type": "boolean", "description": "Also match past addresses."},
+			}, []string{"vault_id"}),
+			Annotations: readOnlyAnnotations(),
+		},
 		{
 			Name:        "fetch_resource",
 			Title:       "Fetch Bonds Resource",
@@ -199,6 +213,23 @@ func (h *Handler) callTool(c echo.Context, params toolCallParams) toolResult {
 			return toolFailure("search_bonds failed", err.Error())
 		}
 		return toolSuccess(result)
+	case "find_nearby_contacts":
+		args, err := decodeParams[FindNearbyContactsArgs](params.Arguments)
+		if err != nil {
+			return toolFailure("invalid find_nearby_contacts arguments", err.Error())
+		}
+		execArgs, err := args.executeArgs()
+		if err != nil {
+			return toolFailure("invalid find_nearby_contacts arguments", err.Error())
+		}
+		result, err := h.executor.Execute(execArgs, c.Request().Header.Get("Authorization"))
+		if err != nil {
+			return toolFailure("find_nearby_contacts failed", err.Error())
+		}
+		if result.Status >= 400 {
+			return toolFailure("find_nearby_contacts returned error status", result)
+		}
+		return toolSuccess(result.Data)
 	case "fetch_resource":
 		args, err := decodeParams[FetchResourceArgs](params.Arguments)
 		if err != nil {
@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"errors"
+	"net/http"
+)
+
+// nearbyContactsActionID is the API action find_nearby_contacts runs, so
+// the tool goes through the same route, scopes and vault permissions.
+var nearbyContactsActionID = actionID(http.MethodGet, "/api/vaults/:vault_id/reports/addresses/nearby")
+
+type FindNearbyContactsArgs struct {
+	VaultID     string   `json:"vault_id"`
+	Place       string   `json:"place"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	RadiusKM    *float64 `json:"radius_km"`
+	IncludePast bool     `json:"include_past"`
+}
+
+func (args FindNearbyContactsArgs) executeArgs() (ExecuteActionArgs, error) {
+	if args.VaultID == "" {
+		return ExecuteActionArgs{}, errors.New("vault_id is required")
+	}
+	if args.Place == "" && (args.Latitude == nil || args.Longitude == nil) {
+		return ExecuteActionArgs{}, errors.New("place or latitude and longitude are required")
+	}
+	query := map[string]interface{}{}
+	if args.Place != "" {
+		query["place"] = args.Place
+	} else {
+		query["latitude"] = *args.Latitude
+		query["longitude"] = *args.Longitude
+	}
+	if args.RadiusKM != nil {
+		query["radius_km"] = *args.RadiusKM
+	}
+	if args.IncludePast {
+		query["include_past"] = true
+	}
+	return ExecuteActionArgs{
+		ActionID:   nearbyContactsActionID,
+		PathParams: map[string]string{"vault_id": args.VaultID},
+		Query:      query,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestFindNearbyContactsForwardsToReportRoute(t *testing.T) {
+	e := echo.New()
+	e.GET("/api/vaults/:vault_id/reports/addresses/nearby", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{
+			"vault_id":  c.Param("vault_id"),
+			"latitude":  c.QueryParam("latitude"),
+			"longitude": c.QueryParam("longitude"),
+			"radius_km": c.QueryParam("radius_km"),
+			"place":     c.QueryParam("place"),
+		})
+	})
+	executor := NewActionExecutor(e, NewActionRegistry(e))
+
+	lat, lng, radius := 38.72, -9.14, 25.0
+	args, err := FindNearbyContactsArgs{VaultID: "v1", Latitude: &lat, Longitude: &lng, RadiusKM: &radius}.executeArgs()
+	if err != nil {
+		t.Fatalf("executeArgs returned error: %v", err)
+	}
+	result, err := executor.Execute(args, "Bearer token")
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	data, ok := result.Data.(map[string]interface{})
+	if result.Status != http.StatusOK || !ok {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if data["vault_id"] != "v1" || data["latitude"] != "38.72" || data["longitude"] != "-9.14" || data["radius_km"] != "25" || data["place"] != "" {
+		t.Fatalf("unexpected query: %+v", data)
+	}
+
+	if _, err := (FindNearbyContactsArgs{VaultID: "v1", Latitude: &lat}).executeArgs(); err == nil {
+		t.Fatal("expected an error without a place or a full coordinate")
+	}
+}
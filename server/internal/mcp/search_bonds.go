@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/phone"
 	"github.com/naiba/bonds/internal/search"
 	"github.com/naiba/bonds/internal/services"
 	"github.com/naiba/bonds/internal/utils"
@@ -171,7 +172,8 @@ func (s *BondsSearcher) sqlSearch(vaultID, userID, query string, limit int) ([]S
 	}
 	items = append(items, contacts...)
 
-	contactInfo, err := s.searchContactInformation(vaultID, likeTerm, limit, nameOrder)
+	normalizedPhone := phone.Normalize(query, services.PhoneRegionForUser(s.db, userID))
+	contactInfo, err := s.searchContactInformation(vaultID, likeTerm, normalizedPhone, limit, nameOrder)
 	if err != nil {
 		return nil, err
 	}
@@ -232,7 +234,10 @@ func (s *BondsSearcher) searchContacts(vaultID, likeTerm string, limit int, name
 	return items, nil
 }
 
-func (s *BondsSearcher) searchContactInformation(vaultID, likeTerm string, limit int, nameOrder string) ([]SearchItem, error) {
+// searchContactInformation matches contact information containing the term.
+// When the query is a phone number, phones with the same E.164 form match
+// too, however they were written.
+func (s *BondsSearcher) searchContactInformation(vaultID, likeTerm, normalizedPhone string, limit int, nameOrder string) ([]SearchItem, error) {
 	type row struct {
 		ID         uint
 		Data       string
@@ -246,12 +251,16 @@ func (s *BondsSearcher) searchContactInformation(vaultID, likeTerm string, limit
 		Prefix     *string
 		Suffix     *string
 	}
+	match := s.db.Where("LOWER(contact_information.data) LIKE ?", likeTerm)
+	if normalizedPhone != "" {
+		match = match.Or("contact_information.normalized_data = ?", normalizedPhone)
+	}
 	var rows []row
 	err := s.db.Table("contact_information").
 		Select("contact_information.id, contact_information.data, contacts.id AS contact_id, contacts.vault_id, contacts.first_name, contacts.middle_name, contacts.last_name, contacts.nickname, contacts.maiden_name, contacts.prefix, contacts.suffix").
 		Joins("JOIN contacts ON contacts.id = contact_information.contact_id").
 		Where("contacts.vault_id = ? AND contacts.listed = ?", vaultID, true).
-		Where("LOWER(contact_information.data) LIKE ?", likeTerm).
+		Where(match).
 		Limit(limit).
 		Scan(&rows).Error
 	if err != nil {
@@ -3,6 +3,7 @@ package middleware
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"strings"
 	"time"
 
@@ -24,8 +25,9 @@ type JWTClaims struct {
 }
 
 type AuthMiddleware struct {
-	secret []byte
-	db     *gorm.DB
+	secret      []byte
+	db          *gorm.DB
+	forwardAuth *forwardAuth
 }
 
 func NewAuthMiddleware(secret string, db *gorm.DB) *AuthMiddleware {
@@ -34,7 +36,10 @@ func NewAuthMiddleware(secret string, db *gorm.DB) *AuthMiddleware {
 
 const patPrefix = "bonds_"
 
-const ScopeCalendarRead = "calendar:read"
+const (
+	ScopeCalendarRead = "calendar:read"
+	ScopeReportsRead  = "reports:read"
+)
 
 const (
 	ctxPATScopes   = "pat_scopes"
@@ -42,6 +47,17 @@ const (
 )
 
 func (m *AuthMiddleware) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return m.authenticate(next, false)
+}
+
+// AuthenticateLegacy is Authenticate for POST /auth/refresh only. It also
+// accepts JWTs issued before sessions existed, so that their holders can
+// trade them for a session instead of being signed out.
+func (m *AuthMiddleware) AuthenticateLegacy(next echo.HandlerFunc) echo.HandlerFunc {
+	return m.authenticate(next, true)
+}
+
+func (m *AuthMiddleware) authenticate(next echo.HandlerFunc, allowSessionless bool) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		var tokenString string
 
@@ -53,6 +69,9 @@ func (m *AuthMiddleware) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
 			}
 		} else if qt := c.QueryParam("token"); qt != "" {
 			tokenString = qt
+		} else if identity := m.forwardAuth.identity(c.Request()); identity != nil {
+			// Explicit tokens win, so PATs keep working behind the proxy.
+			return m.authenticateWithForwardAuth(c, next, identity)
 		} else {
 			return response.Unauthorized(c, "err.missing_authorization_header")
 		}
@@ -61,11 +80,11 @@ func (m *AuthMiddleware) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
 			return m.authenticateWithPAT(c, next, tokenString)
 		}
 
-		return m.authenticateWithJWT(c, next, tokenString)
+		return m.authenticateWithJWT(c, next, tokenString, allowSessionless)
 	}
 }
 
-func (m *AuthMiddleware) authenticateWithJWT(c echo.Context, next echo.HandlerFunc, tokenString string) error {
+func (m *AuthMiddleware) authenticateWithJWT(c echo.Context, next echo.HandlerFunc, tokenString string, allowSessionless bool) error {
 	claims := &JWTClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -84,6 +103,18 @@ func (m *AuthMiddleware) authenticateWithJWT(c echo.Context, next echo.HandlerFu
 		return response.Forbidden(c, "err.two_factor_required")
 	}
 
+	// Every access token belongs to a session so that it can be revoked.
+	// Tokens issued before sessions existed carry no jti and are refused
+	// everywhere but /auth/refresh, which trades them for a session.
+	if claims.ID != "" || !allowSessionless {
+		if err := m.checkSession(c, claims); err != nil {
+			if errors.Is(err, errSessionRevoked) {
+				return response.Unauthorized(c, "err.session_revoked")
+			}
+			return response.InternalError(c, "err.database_error")
+		}
+	}
+
 	user := &models.User{}
 	if err := m.db.Select("disabled, email_verified_at, is_account_administrator, is_instance_administrator").Where("id = ?", claims.UserID).First(user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -107,6 +138,42 @@ func (m *AuthMiddleware) authenticateWithJWT(c echo.Context, next echo.HandlerFu
 	return next(c)
 }
 
+// sessionTouchInterval limits how often a session's last-used time is
+// written while its device stays the same.
+const sessionTouchInterval = 5 * time.Minute
+
+var errSessionRevoked = errors.New("session revoked")
+
+// checkSession rejects tokens of revoked or expired sessions and records
+// the device the session was last used from.
+func (m *AuthMiddleware) checkSession(c echo.Context, claims *JWTClaims) error {
+	if claims.ID == "" {
+		return errSessionRevoked
+	}
+	var session models.UserSession
+	if err := m.db.Where("id = ?", claims.ID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errSessionRevoked
+		}
+		return err
+	}
+	now := time.Now()
+	if session.UserID != claims.UserID || session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return errSessionRevoked
+	}
+
+	userAgent, ip := c.Request().UserAgent(), c.RealIP()
+	if session.UserAgent != userAgent || session.IPAddress != ip || now.Sub(session.LastUsedAt) > sessionTouchInterval {
+		m.db.Model(&session).Updates(map[string]interface{}{
+			"user_agent":   userAgent,
+			"ip_address":   ip,
+			"last_used_at": now,
+		})
+	}
+	c.Set("session_id", session.ID)
+	return nil
+}
+
 func (m *AuthMiddleware) authenticateWithPAT(c echo.Context, next echo.HandlerFunc, rawToken string) error {
 	hash := sha256Hash(rawToken)
 
@@ -201,6 +268,13 @@ func GetAccountID(c echo.Context) string {
 	return id
 }
 
+// GetSessionID returns the session of the request's access token, or ""
+// for personal access tokens and tokens issued before sessions existed.
+func GetSessionID(c echo.Context) string {
+	id, _ := c.Get("session_id").(string)
+	return id
+}
+
 func GetClaims(c echo.Context) *JWTClaims {
 	claims, _ := c.Get("claims").(*JWTClaims)
 	return claims
@@ -251,6 +325,17 @@ func DenyScopedPAT(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// DenyPAT rejects every personal access token, scoped or not, including the
+// access tokens of OAuth apps, so that a token cannot authorize further apps.
+func DenyPAT(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if authType, _ := c.Get("auth_type").(string); authType == "pat" {
+			return response.Forbidden(c, "err.token_not_allowed")
+		}
+		return next(c)
+	}
+}
+
 // RequireScope allows an endpoint to be reached by a scope-limited PAT only if
 // the token carries the given scope. Full-access tokens (JWT or unscoped PAT)
 // always pass.
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+const sessionTestSecret = "session-test-secret"
+
+func setupSessionAuthTest(t *testing.T) (*gorm.DB, models.User) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	account := models.Account{}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	user := models.User{AccountID: account.ID, Email: "session-auth@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return db, user
+}
+
+func signSessionTestToken(t *testing.T, user models.User, sessionID string) string {
+	t.Helper()
+	claims := &JWTClaims{
+		UserID:    user.ID,
+		AccountID: user.AccountID,
+		Email:     user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(sessionTestSecret))
+	if err != nil {
+		t.Fatalf("sign JWT: %v", err)
+	}
+	return signed
+}
+
+func serveWithSessionAuth(db *gorm.DB, token string) (*httptest.ResponseRecorder, string) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", "SessionTest/1.0")
+	rec := httptest.NewRecorder()
+	var sessionID string
+	handler := NewAuthMiddleware(sessionTestSecret, db).Authenticate(func(c echo.Context) error {
+		sessionID = GetSessionID(c)
+		return c.NoContent(http.StatusNoContent)
+	})
+	_ = handler(e.NewContext(req, rec))
+	return rec, sessionID
+}
+
+func TestJWTAuthenticationEnforcesSessions(t *testing.T) {
+	db, user := setupSessionAuthTest(t)
+	session := models.UserSession{
+		ID:         "11111111-1111-1111-1111-111111111111",
+		UserID:     user.ID,
+		LastUsedAt: time.Now().Add(-time.Hour),
+		ExpiresAt:  time.Now().Add(24 * time.Hour),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	rec, sessionID := serveWithSessionAuth(db, signSessionTestToken(t, user, session.ID))
+	if rec.Code != http.StatusNoContent || sessionID != session.ID {
+		t.Fatalf("expected an active session to pass, got %d (session %q)", rec.Code, sessionID)
+	}
+	var touched models.UserSession
+	db.First(&touched, "id = ?", session.ID)
+	if touched.UserAgent != "SessionTest/1.0" || touched.IPAddress == "" || !touched.LastUsedAt.After(session.LastUsedAt) {
+		t.Errorf("expected the session to record its device, got %+v", touched)
+	}
+
+	if rec, _ := serveWithSessionAuth(db, signSessionTestToken(t, user, "unknown-session")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected an unknown session to be rejected, got %d", rec.Code)
+	}
+
+	db.Model(&session).Update("revoked_at", time.Now())
+	if rec, _ := serveWithSessionAuth(db, signSessionTestToken(t, user, session.ID)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a revoked session to be rejected, got %d", rec.Code)
+	}
+
+	// Tokens issued before sessions existed have no jti and cannot be
+	// revoked, so they are refused.
+	if rec, _ := serveWithSessionAuth(db, signSessionTestToken(t, user, "")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a token without jti to be rejected, got %d", rec.Code)
+	}
+	// Only /auth/refresh takes them, to trade them for a session.
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", nil)
+	req.Header.Set("Authorization", "Bearer "+signSessionTestToken(t, user, ""))
+	rec = httptest.NewRecorder()
+	handler := NewAuthMiddleware(sessionTestSecret, db).AuthenticateLegacy(func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	_ = handler(e.NewContext(req, rec))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected the legacy refresh route to accept a token without jti, got %d", rec.Code)
+	}
+}
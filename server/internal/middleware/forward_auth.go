@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naiba/bonds/internal/config"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/pkg/response"
+)
+
+var (
+	// ErrForwardAuthUserNotProvisioned is returned by a provisioner for a
+	// user it does not know and may not create.
+	ErrForwardAuthUserNotProvisioned = errors.New("forward auth user is not provisioned")
+	ErrForwardAuthMissingEmail       = errors.New("forward auth request carries no email")
+	// ErrForwardAuthEmailInUse is returned for a new username whose email
+	// belongs to an existing user that may not be linked automatically.
+	ErrForwardAuthEmailInUse = errors.New("forward auth email belongs to an existing user")
+)
+
+// ForwardAuthIdentity is the user a trusted reverse proxy vouched for.
+type ForwardAuthIdentity struct {
+	Username string
+	Email    string
+	Name     string
+	Groups   []string
+	Locale   string
+}
+
+// ForwardAuthProvisioner resolves a proxy identity to a Bonds user, creating
+// the user when the deployment allows it. It lives outside this package
+// because provisioning needs the services layer.
+type ForwardAuthProvisioner interface {
+	ForwardAuthUser(identity ForwardAuthIdentity) (*models.User, error)
+}
+
+type forwardAuth struct {
+	cfg         config.ForwardAuthConfig
+	networks    []*net.IPNet
+	appOrigin   string
+	provisioner ForwardAuthProvisioner
+}
+
+// EnableForwardAuth makes Authenticate accept the identity headers of the
+// configured reverse proxy for requests that carry no token. appURL is the
+// public URL of Bonds, the only origin allowed to make unsafe requests.
+func (m *AuthMiddleware) EnableForwardAuth(cfg config.ForwardAuthConfig, appURL string, provisioner ForwardAuthProvisioner) error {
+	networks, err := cfg.TrustedNetworks()
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(appURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid app URL %q", appURL)
+	}
+	m.forwardAuth = &forwardAuth{
+		cfg:         cfg,
+		networks:    networks,
+		appOrigin:   u.Scheme + "://" + u.Host,
+		provisioner: provisioner,
+	}
+	return nil
+}
+
+// identity returns the identity the proxy vouched for, or nil when the
+// request did not come straight from a trusted proxy or carries no user,
+// and always when forward auth is disabled. The peer address is used rather
+// than X-Forwarded-For, which the client controls.
+func (f *forwardAuth) identity(r *http.Request) *ForwardAuthIdentity {
+	if f == nil {
+		return nil
+	}
+	username := strings.TrimSpace(r.Header.Get(f.cfg.UserHeader))
+	if username == "" || !f.trusts(r.RemoteAddr) {
+		return nil
+	}
+	identity := &ForwardAuthIdentity{
+		Username: username,
+		Email:    strings.TrimSpace(r.Header.Get(f.cfg.EmailHeader)),
+		Name:     strings.TrimSpace(r.Header.Get(f.cfg.NameHeader)),
+	}
+	// Authelia and oauth2-proxy separate groups with commas, Authentik with
+	// pipes.
+	for _, group := range strings.FieldsFunc(r.Header.Get(f.cfg.GroupsHeader), func(r rune) bool {
+		return r == ',' || r == '|'
+	}) {
+		if group = strings.TrimSpace(group); group != "" {
+			identity.Groups = append(identity.Groups, group)
+		}
+	}
+	return identity
+}
+
+func (f *forwardAuth) trusts(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range f.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameOrigin reports whether an unsafe request comes from Bonds itself. The
+// proxy's session cookie is sent with requests from any site, so without
+// this check a form on another site could write as the signed-in user.
+// Browsers send Sec-Fetch-Site or Origin with every cross-site POST;
+// requests with neither are refused too, and other clients use a token.
+func (f *forwardAuth) sameOrigin(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin"
+	}
+	return strings.EqualFold(r.Header.Get(echo.HeaderOrigin), f.appOrigin)
+}
+
+func (m *AuthMiddleware) authenticateWithForwardAuth(c echo.Context, next echo.HandlerFunc, identity *ForwardAuthIdentity) error {
+	if !m.forwardAuth.sameOrigin(c.Request()) {
+		return response.Forbidden(c, "err.forward_auth_cross_site_request")
+	}
+	identity.Locale, _ = c.Get("locale").(string)
+	user, err := m.forwardAuth.provisioner.ForwardAuthUser(*identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrForwardAuthUserNotProvisioned):
+			return response.Unauthorized(c, "err.forward_auth_user_not_provisioned")
+		case errors.Is(err, ErrForwardAuthMissingEmail):
+			return response.Unauthorized(c, "err.forward_auth_missing_email")
+		case errors.Is(err, ErrForwardAuthEmailInUse):
+			return response.Unauthorized(c, "err.forward_auth_email_in_use")
+		}
+		return response.InternalError(c, "err.database_error")
+	}
+	if user.Disabled {
+		return response.Forbidden(c, "err.user_account_disabled")
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("account_id", user.AccountID)
+	c.Set("email", user.Email)
+	c.Set("is_admin", user.IsAccountAdministrator)
+	c.Set("is_instance_admin", user.IsInstanceAdministrator)
+	c.Set("email_verified", user.EmailVerifiedAt != nil)
+	c.Set("auth_type", "forward_auth")
+
+	return next(c)
+}
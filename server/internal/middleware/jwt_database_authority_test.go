@@ -112,11 +112,17 @@ func runJWTAdministratorPrivilegeContract(t *testing.T, contract jwtAdministrato
 				t.Fatalf("create user: %v", err)
 			}
 
+			session := models.UserSession{ID: "jwt-database-authority-session", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+			if err := db.Create(&session).Error; err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+
 			claims := &JWTClaims{
 				UserID:    user.ID,
 				AccountID: account.ID,
 				Email:     user.Email,
 				RegisteredClaims: jwt.RegisteredClaims{
+					ID:        session.ID,
 					ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
 					IssuedAt:  jwt.NewNumericDate(time.Now()),
 				},
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+const (
+	AutomationExecutionSucceeded = "succeeded"
+	AutomationExecutionFailed    = "failed"
+	AutomationExecutionSkipped   = "skipped"
+)
+
+// AutomationRule runs its actions for a contact when a feed action of the
+// vault matches Trigger and every condition holds. Conditions and Actions
+// are JSON arrays of dto.AutomationCondition and dto.AutomationAction.
+type AutomationRule struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	VaultID    string    `json:"vault_id" gorm:"type:text;not null;index:idx_automation_rule_vault_trigger"`
+	CreatedBy  string    `json:"created_by" gorm:"type:text;not null;index"`
+	Name       string    `json:"name" gorm:"not null"`
+	Trigger    string    `json:"trigger" gorm:"column:trigger_action;size:64;not null;index:idx_automation_rule_vault_trigger"`
+	Conditions string    `json:"conditions" gorm:"type:text;not null"`
+	Actions    string    `json:"actions" gorm:"type:text;not null"`
+	Enabled    bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AutomationExecution is one entry of a vault's automation log. Results is
+// a JSON array of dto.AutomationActionResult.
+type AutomationExecution struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	VaultID    string    `json:"vault_id" gorm:"type:text;not null;index"`
+	RuleID     uint      `json:"rule_id" gorm:"not null;index"`
+	ContactID  string    `json:"contact_id" gorm:"type:text;not null"`
+	Trigger    string    `json:"trigger" gorm:"column:trigger_action;size:64;not null"`
+	FeedItemID uint      `json:"feed_item_id"`
+	Depth      int       `json:"depth"`
+	Status     string    `json:"status" gorm:"size:16;not null"`
+	Error      *string   `json:"error" gorm:"type:text"`
+	Results    string    `json:"results" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// AutomationQueueItem is a feed item whose rules have yet to run. It is
+// written in the same transaction as the feed item. Chain is the JSON of
+// the rules that led to the item, or empty when a user caused it.
+type AutomationQueueItem struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	VaultID    string    `json:"vault_id" gorm:"type:text;not null;index"`
+	FeedItemID uint      `json:"feed_item_id" gorm:"not null"`
+	Chain      string    `json:"chain" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -46,7 +46,7 @@ func TestBackfillInteractionActivityTypesSeedsEveryVaultIdempotently(t *testing.
 		if err := db.Model(&ActivityType{}).Joins("JOIN activity_categories ON activity_categories.id = activity_types.activity_category_id").Where("activity_categories.vault_id = ? AND counts_as_interaction = ?", vaultID, true).Count(&count).Error; err != nil {
 			t.Fatal(err)
 		}
-		if count != 3 {
+		if count != 5 {
 			t.Fatalf("vault %s interaction types=%d", vaultID, count)
 		}
 	}
@@ -0,0 +1,66 @@
+package models
+
+import "gorm.io/gorm"
+
+// BackfillPhoneNumbers fills ContactInformation.NormalizedData for phone
+// numbers stored before numbers were normalized. Numbers without a country
+// code are read in the region all users of the account share. When the
+// users of an account are in different regions, its numbers without a
+// country code are left alone and counted in ambiguous, together with the
+// account's numbers that cannot be parsed; they are normalized once a user
+// sets their phone region. Numbers that cannot be parsed stay empty, so it
+// is safe to rerun.
+func BackfillPhoneNumbers(db *gorm.DB) (ambiguous int, err error) {
+	var rows []struct {
+		ID        uint
+		Data      string
+		AccountID string
+	}
+	if err := db.Table("contact_information").
+		Select("contact_information.id, contact_information.data, contact_information_types.account_id").
+		Joins("JOIN contact_information_types ON contact_information_types.id = contact_information.type_id").
+		Where("contact_information_types.type = ? AND contact_information.normalized_data IS NULL", "phone").
+		Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+	regions := make(map[string]string)
+	shared := make(map[string]bool)
+	for _, row := range rows {
+		region, ok := regions[row.AccountID]
+		if !ok {
+			if region, ok, err = accountPhoneRegion(db, row.AccountID); err != nil {
+				return 0, err
+			}
+			regions[row.AccountID], shared[row.AccountID] = region, ok
+		}
+		normalized := NormalizedPhone(row.Data, region)
+		if normalized == nil {
+			if !shared[row.AccountID] {
+				ambiguous++
+			}
+			continue
+		}
+		if err := db.Model(&ContactInformation{}).Where("id = ?", row.ID).UpdateColumn("normalized_data", *normalized).Error; err != nil {
+			return 0, err
+		}
+	}
+	return ambiguous, nil
+}
+
+// accountPhoneRegion returns the phone region of the account's users, and
+// false when they do not all have the same one.
+func accountPhoneRegion(db *gorm.DB, accountID string) (string, bool, error) {
+	var users []User
+	if err := db.Select("phone_region", "locale").Where("account_id = ?", accountID).Find(&users).Error; err != nil {
+		return "", false, err
+	}
+	region := ""
+	for i, user := range users {
+		if i == 0 {
+			region = user.EffectivePhoneRegion()
+		} else if user.EffectivePhoneRegion() != region {
+			return "", false, nil
+		}
+	}
+	return region, true, nil
+}
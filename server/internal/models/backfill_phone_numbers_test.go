@@ -0,0 +1,102 @@
+package models
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBackfillPhoneNumbersUsesAccountRegion(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent), DisableForeignKeyConstraintWhenMigrating: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&User{}, &ContactInformationType{}, &ContactInformation{}); err != nil {
+		t.Fatal(err)
+	}
+	phoneType, emailType := "phone", "email"
+	if err := db.Create(&User{ID: "user-a", AccountID: "account-a", Email: "a@example.com", Locale: "de"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	types := []ContactInformationType{
+		{ID: 1, AccountID: "account-a", Type: &phoneType},
+		{ID: 2, AccountID: "account-a", Type: &emailType},
+	}
+	if err := db.Create(&types).Error; err != nil {
+		t.Fatal(err)
+	}
+	infos := []ContactInformation{
+		{ID: 1, ContactID: "c", TypeID: 1, Data: "030 1234567"},
+		{ID: 2, ContactID: "c", TypeID: 1, Data: "+1 555 010 2000"},
+		{ID: 3, ContactID: "c", TypeID: 1, Data: "call the office"},
+		{ID: 4, ContactID: "c", TypeID: 2, Data: "0301234567"},
+	}
+	if err := db.Create(&infos).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if ambiguous, err := BackfillPhoneNumbers(db); err != nil || ambiguous != 0 {
+			t.Fatalf("BackfillPhoneNumbers = %d, %v", ambiguous, err)
+		}
+	}
+
+	want := map[uint]string{1: "+49301234567", 2: "+15550102000", 3: "", 4: ""}
+	for id, normalized := range want {
+		var info ContactInformation
+		if err := db.First(&info, id).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got := valueOf(info.NormalizedData); got != normalized {
+			t.Errorf("row %d normalized to %q, want %q", id, got, normalized)
+		}
+	}
+}
+
+func TestBackfillPhoneNumbersSkipsNationalNumbersOfMixedRegionAccounts(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent), DisableForeignKeyConstraintWhenMigrating: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&User{}, &ContactInformationType{}, &ContactInformation{}); err != nil {
+		t.Fatal(err)
+	}
+	phoneType := "phone"
+	users := []User{
+		{ID: "user-a", AccountID: "account-a", Email: "a@example.com", Locale: "de"},
+		{ID: "user-b", AccountID: "account-a", Email: "b@example.com", Locale: "en", PhoneRegion: "GB"},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&ContactInformationType{ID: 1, AccountID: "account-a", Type: &phoneType}).Error; err != nil {
+		t.Fatal(err)
+	}
+	infos := []ContactInformation{
+		{ID: 1, ContactID: "c", TypeID: 1, Data: "030 1234567"},
+		{ID: 2, ContactID: "c", TypeID: 1, Data: "+1 555 010 2000"},
+	}
+	if err := db.Create(&infos).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ambiguous, err := BackfillPhoneNumbers(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ambiguous != 1 {
+		t.Errorf("expected the national number to be reported, got %d", ambiguous)
+	}
+	want := map[uint]string{1: "", 2: "+15550102000"}
+	for id, normalized := range want {
+		var info ContactInformation
+		if err := db.First(&info, id).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got := valueOf(info.NormalizedData); got != normalized {
+			t.Errorf("row %d normalized to %q, want %q", id, got, normalized)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VaultChangeSeedVersion grows whenever a type is added to
+// vaultChangeSources, so that vaults seeded before get the entities of the
+// new type written to the log as well.
+const VaultChangeSeedVersion = 2
+
+// vaultChangeSources selects the vault and ID of every live entity of each
+// tracked type.
+var vaultChangeSources = []struct {
+	entityType string
+	query      string
+}{
+	{VaultChangeContact, "SELECT vault_id, CAST(id AS TEXT) AS entity_id FROM contacts WHERE deleted_at IS NULL"},
+	{VaultChangeNote, "SELECT notes.vault_id, CAST(notes.id AS TEXT) AS entity_id FROM notes JOIN contacts ON contacts.id = notes.contact_id WHERE contacts.deleted_at IS NULL"},
+	{VaultChangeTask, "SELECT vault_id, CAST(id AS TEXT) AS entity_id FROM contact_tasks WHERE deleted_at IS NULL"},
+	{VaultChangeReminder, "SELECT contacts.vault_id, CAST(contact_reminders.id AS TEXT) AS entity_id FROM contact_reminders JOIN contacts ON contacts.id = contact_reminders.contact_id WHERE contacts.deleted_at IS NULL"},
+	{VaultChangeImportantDate, "SELECT contacts.vault_id, CAST(contact_important_dates.id AS TEXT) AS entity_id FROM contact_important_dates JOIN contacts ON contacts.id = contact_important_dates.contact_id WHERE contact_important_dates.deleted_at IS NULL AND contacts.deleted_at IS NULL"},
+	{VaultChangeActivity, "SELECT vault_id, CAST(id AS TEXT) AS entity_id FROM activities"},
+	{VaultChangePost, "SELECT journals.vault_id, CAST(posts.id AS TEXT) AS entity_id FROM posts JOIN journals ON journals.id = posts.journal_id"},
+	{VaultChangeFile, "SELECT vault_id, CAST(id AS TEXT) AS entity_id FROM files"},
+	{VaultChangeCall, "SELECT contacts.vault_id, CAST(calls.id AS TEXT) AS entity_id FROM calls JOIN contacts ON contacts.id = calls.contact_id WHERE contacts.deleted_at IS NULL"},
+}
+
+// BackfillVaultChanges records a "created" change for every entity of the
+// vaults that pre-date the change log, so that a client syncing from the
+// start receives their data. Each vault is done once per
+// VaultChangeSeedVersion, in a transaction that also stores the version on
+// its counter, so later starts skip it. Entities that already have a change
+// are left alone.
+func BackfillVaultChanges(db *gorm.DB) error {
+	var vaultIDs []string
+	if err := db.Model(&Vault{}).
+		Where("id NOT IN (?)", db.Model(&VaultChangeCounter{}).Select("vault_id").Where("seed_version >= ?", VaultChangeSeedVersion)).
+		Pluck("id", &vaultIDs).Error; err != nil {
+		return err
+	}
+	for _, vaultID := range vaultIDs {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := backfillVaultChanges(tx, vaultID); err != nil {
+				return err
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&VaultChangeCounter{VaultID: vaultID}).Error; err != nil {
+				return err
+			}
+			return tx.Model(&VaultChangeCounter{}).Where("vault_id = ?", vaultID).UpdateColumn("seed_version", VaultChangeSeedVersion).Error
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackfillVaultChangesForVault records a "created" change for every entity
+// of the vault that has none yet. Bulk imports call it once instead of
+// recording every row they write.
+func BackfillVaultChangesForVault(db *gorm.DB, vaultID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return backfillVaultChanges(tx, vaultID)
+	})
+}
+
+// backfillVaultChanges inserts the missing entries without a Seq and then
+// numbers them after the vault's existing entries, in ID order.
+func backfillVaultChanges(tx *gorm.DB, vaultID string) error {
+	for _, source := range vaultChangeSources {
+		if err := tx.Exec(`INSERT INTO vault_changes (vault_id, seq, entity_type, entity_id, action, created_at)
+			SELECT src.vault_id, 0, ?, src.entity_id, ?, CURRENT_TIMESTAMP FROM (`+source.query+`) src
+			WHERE src.vault_id = ?
+			AND NOT EXISTS (SELECT 1 FROM vault_changes vc WHERE vc.entity_type = ? AND vc.entity_id = src.entity_id)`,
+			source.entityType, VaultChangeCreated, vaultID, source.entityType).Error; err != nil {
+			return err
+		}
+	}
+	var bounds struct {
+		MinID uint
+		MaxID uint
+	}
+	if err := tx.Model(&VaultChange{}).Select("COALESCE(MIN(id), 0) AS min_id, COALESCE(MAX(id), 0) AS max_id").
+		Where("vault_id = ? AND seq = 0", vaultID).Scan(&bounds).Error; err != nil {
+		return err
+	}
+	if bounds.MaxID == 0 {
+		return nil
+	}
+	first, err := ReserveVaultChangeSeqs(tx, vaultID, bounds.MaxID-bounds.MinID+1)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&VaultChange{}).Where("vault_id = ? AND seq = 0", vaultID).
+		UpdateColumn("seq", gorm.Expr("id - ? + ?", bounds.MinID, first)).Error
+}
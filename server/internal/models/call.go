@@ -36,10 +36,16 @@ type Call struct {
 	Duration     *int      `json:"duration"`
 	Type         string    `json:"type" gorm:"not null"`
 	Description  *string   `json:"description" gorm:"type:text"`
-	Answered     bool      `json:"answered" gorm:"default:true"`
-	WhoInitiated string    `json:"who_initiated" gorm:"not null"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	// Answered has no column default: GORM would write the default in place
+	// of false, so every call sets it.
+	Answered     bool   `json:"answered"`
+	WhoInitiated string `json:"who_initiated" gorm:"not null"`
+	// SourceType / SourceUUID identify a call brought in from a phone's or
+	// chat app's history, so importing the same history again skips it.
+	SourceType *string   `json:"source_type" gorm:"size:64;index:idx_call_source"`
+	SourceUUID *string   `json:"source_uuid" gorm:"size:191;index:idx_call_source"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 
 	Contact    Contact     `json:"contact,omitempty" gorm:"foreignKey:ContactID"`
 	Author     *User       `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
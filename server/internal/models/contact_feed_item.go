@@ -2,10 +2,14 @@ package models
 
 import "time"
 
+// ContactFeedItem is one entry of the activity feed. Seq comes from the
+// vault's VaultChangeCounter, so that the vault stream can follow the feed
+// in commit order; items written before it was added have 0.
 type ContactFeedItem struct {
 	ID                  uint      `json:"id" gorm:"primaryKey;autoIncrement"`
 	AuthorID            *string   `json:"author_id" gorm:"type:text;index"`
-	VaultID             string    `json:"vault_id" gorm:"type:text;index:idx_feed_vault_created,priority:1"`
+	VaultID             string    `json:"vault_id" gorm:"type:text;index:idx_feed_vault_created,priority:1;index:idx_feed_vault_seq,priority:1"`
+	Seq                 uint      `json:"seq" gorm:"not null;default:0;index:idx_feed_vault_seq,priority:2"`
 	ContactID           string    `json:"contact_id" gorm:"type:text;not null;index"`
 	ContactNameSnapshot string    `json:"contact_name_snapshot"`
 	Action              string    `json:"action" gorm:"not null"`
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/naiba/bonds/internal/phone"
+)
 
 type ContactInformationType struct {
 	ID                 uint      `json:"id" gorm:"primaryKey;autoIncrement"`
@@ -17,14 +21,18 @@ type ContactInformationType struct {
 }
 
 type ContactInformation struct {
-	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	ContactID string    `json:"contact_id" gorm:"type:text;not null;index"`
-	TypeID    uint      `json:"type_id" gorm:"not null;index"`
-	Data      string    `json:"data" gorm:"not null"`
-	Kind      *string   `json:"kind"`
-	Pref      bool      `json:"pref" gorm:"default:true"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	ContactID string `json:"contact_id" gorm:"type:text;not null;index"`
+	TypeID    uint   `json:"type_id" gorm:"not null;index"`
+	Data      string `json:"data" gorm:"not null"`
+	// NormalizedData is the E.164 form of a phone number, used to match
+	// numbers written in different styles. Nil for other types and for
+	// numbers that could not be parsed.
+	NormalizedData *string   `json:"normalized_data" gorm:"type:text;index"`
+	Kind           *string   `json:"kind"`
+	Pref           bool      `json:"pref" gorm:"default:true"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 
 	Contact                Contact                `json:"contact,omitempty" gorm:"foreignKey:ContactID"`
 	ContactInformationType ContactInformationType `json:"contact_information_type,omitempty" gorm:"foreignKey:TypeID"`
@@ -33,3 +41,12 @@ type ContactInformation struct {
 func (ContactInformation) TableName() string {
 	return "contact_information"
 }
+
+// NormalizedPhone returns the E.164 form of a phone number as stored in
+// ContactInformation.NormalizedData, or nil when it cannot be parsed.
+func NormalizedPhone(data, region string) *string {
+	if normalized := phone.Normalize(data, region); normalized != "" {
+		return &normalized
+	}
+	return nil
+}
@@ -27,6 +27,14 @@ type ContactReminder struct {
 	CreatedAt            time.Time  `json:"created_at"`
 	UpdatedAt            time.Time  `json:"updated_at"`
 
+	// LeadTimeDays lists the offsets, in days before each occurrence, at
+	// which the reminder fires, comma-separated and descending ("14,3,0").
+	// NULL means "on the day" only.
+	LeadTimeDays *string `json:"lead_time_days" gorm:"type:text"`
+	// EscalateAfterHours, when set, notifies the other vault members once a
+	// recipient has left an occurrence unacknowledged for that many hours.
+	EscalateAfterHours *int `json:"escalate_after_hours"`
+
 	Contact       Contact                       `json:"contact,omitempty" gorm:"foreignKey:ContactID"`
 	SelectedUsers []ContactReminderSelectedUser `json:"selected_users,omitempty" gorm:"foreignKey:ContactReminderID"`
 }
@@ -52,6 +60,13 @@ type ContactReminderScheduled struct {
 	CreatedAt                 time.Time  `json:"created_at"`
 	UpdatedAt                 time.Time  `json:"updated_at"`
 
+	// LeadDays is how many days before the occurrence this delivery fires.
+	LeadDays int `json:"lead_days" gorm:"not null;default:0"`
+	// SnoozeOfID points at the delivered schedule a snooze was requested
+	// from. Snoozed deliveries are one-offs: they never reschedule the
+	// recurrence, which the original delivery already did.
+	SnoozeOfID *uint `json:"snooze_of_id" gorm:"index"`
+
 	ContactReminder         ContactReminder         `json:"contact_reminder,omitempty" gorm:"foreignKey:ContactReminderID"`
 	UserNotificationChannel UserNotificationChannel `json:"user_notification_channel,omitempty" gorm:"foreignKey:UserNotificationChannelID"`
 }
@@ -59,3 +74,27 @@ type ContactReminderScheduled struct {
 func (ContactReminderScheduled) TableName() string {
 	return "contact_reminder_scheduled"
 }
+
+// ContactReminderDeliveryState tracks one recipient's response to one
+// occurrence of a reminder, across all of their channels and lead-time
+// deliveries. OccurrenceOn is the occurrence date (YYYY-MM-DD) in the
+// recipient's timezone. Acknowledging suppresses the remaining lead-time
+// deliveries of the occurrence and its escalation.
+type ContactReminderDeliveryState struct {
+	ID                uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ContactReminderID uint       `json:"contact_reminder_id" gorm:"not null;index;uniqueIndex:idx_contact_reminder_delivery_state_unique"`
+	UserID            string     `json:"user_id" gorm:"type:text;not null;index;uniqueIndex:idx_contact_reminder_delivery_state_unique"`
+	OccurrenceOn      string     `json:"occurrence_on" gorm:"size:10;not null;uniqueIndex:idx_contact_reminder_delivery_state_unique"`
+	LastNotifiedAt    *time.Time `json:"last_notified_at"`
+	AcknowledgedAt    *time.Time `json:"acknowledged_at"`
+	SnoozedUntil      *time.Time `json:"snoozed_until"`
+	EscalatedAt       *time.Time `json:"escalated_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	ContactReminder ContactReminder `json:"contact_reminder,omitempty" gorm:"foreignKey:ContactReminderID"`
+}
+
+func (ContactReminderDeliveryState) TableName() string {
+	return "contact_reminder_delivery_states"
+}
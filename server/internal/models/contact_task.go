@@ -57,6 +57,19 @@ type ContactTask struct {
 	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
+	// RecurrenceRule — optional RFC 5545 RRULE value (no "RRULE:" prefix).
+	// A yearly rule on a lunar-anchored task recurs on the lunar date; the
+	// RSCALE=CHINESE marker CalDAV clients use is derived from CalendarType
+	// on export rather than stored. COUNT is decremented on every spawned
+	// occurrence, so it always reads as "occurrences left".
+	RecurrenceRule *string `json:"recurrence_rule" gorm:"type:text"`
+	// RecurrenceSeriesID groups every occurrence of one recurring task so
+	// the completion history can be listed across rows.
+	RecurrenceSeriesID *string `json:"recurrence_series_id" gorm:"type:text;index"`
+	// NextOccurrenceID is set once this occurrence has been completed and
+	// its successor spawned. Non-NULL rows are history: lists that show
+	// "current" tasks (kanban, contact task list) filter them out.
+	NextOccurrenceID *uint `json:"next_occurrence_id" gorm:"index"`
 
 	Author     *User         `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
 	ParentTask *ContactTask  `json:"parent_task,omitempty" gorm:"foreignKey:ParentTaskID"`
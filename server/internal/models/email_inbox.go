@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	EmailInboxModeActivity = "activity"
+	EmailInboxModeNote     = "note"
+)
+
+const (
+	IngestedEmailLogged    = "logged"
+	IngestedEmailUnmatched = "unmatched"
+)
+
+// EmailInbox is a user's routing address into a vault. Mail sent or
+// forwarded to the inbound address with "+Token" is logged against the
+// contacts of the vault it names.
+type EmailInbox struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    string    `json:"user_id" gorm:"type:text;not null;uniqueIndex:idx_email_inbox_user_vault"`
+	VaultID   string    `json:"vault_id" gorm:"type:text;not null;uniqueIndex:idx_email_inbox_user_vault;index"`
+	Token     string    `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	Mode      string    `json:"mode" gorm:"size:16;not null;default:'activity'"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IngestedEmail records a message an inbox received, so a message that is
+// delivered twice, or both by BCC and by forwarding, is only logged once.
+type IngestedEmail struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	VaultID      string    `json:"vault_id" gorm:"type:text;not null;uniqueIndex:idx_ingested_email_message"`
+	MessageID    string    `json:"message_id" gorm:"size:512;not null;uniqueIndex:idx_ingested_email_message"`
+	InboxID      uint      `json:"inbox_id" gorm:"not null;index"`
+	Subject      string    `json:"subject" gorm:"type:text"`
+	FromAddress  string    `json:"from_address" gorm:"type:text"`
+	ContactCount int       `json:"contact_count"`
+	ActivityID   *uint     `json:"activity_id"`
+	Status       string    `json:"status" gorm:"size:16;not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"index"`
+}
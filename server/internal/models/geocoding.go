@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// GeocodingCacheEntry remembers what a geocoding provider answered for an
+// address. It is shared by all accounts, so an address is only looked up
+// once however many contacts live there. Entries without coordinates
+// record addresses the provider could not find.
+type GeocodingCacheEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	QueryHash string    `json:"query_hash" gorm:"size:64;not null;uniqueIndex"`
+	Query     string    `json:"query" gorm:"type:text;not null"`
+	Provider  string    `json:"provider" gorm:"size:32;not null"`
+	Latitude  *float64  `json:"latitude"`
+	Longitude *float64  `json:"longitude"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (GeocodingCacheEntry) TableName() string {
+	return "geocoding_cache"
+}
+
+// GeocodingTask queues an address for the geocoding worker. Failed
+// lookups are retried at NextAttemptAt with a growing delay.
+type GeocodingTask struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	AddressID     uint      `json:"address_id" gorm:"not null;uniqueIndex"`
+	VaultID       string    `json:"vault_id" gorm:"type:text;not null;index"`
+	Attempts      int       `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt time.Time `json:"next_attempt_at" gorm:"not null;index"`
+	LastError     *string   `json:"last_error" gorm:"type:text"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// GeocodingRateLimit holds when a provider may next be asked, as Unix
+// milliseconds, so that every server sharing the database keeps to the
+// provider's rate limit together.
+type GeocodingRateLimit struct {
+	Provider      string `json:"provider" gorm:"primaryKey;size:32"`
+	NextRequestAt int64  `json:"next_request_at" gorm:"not null"`
+}
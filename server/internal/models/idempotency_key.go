@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// IdempotencyKey is the first response to a mutating API request sent with
+// an Idempotency-Key header. Retries with the same key and request replay it
+// instead of running again. A row without a response belongs to a request
+// that is still running. A completed row whose response was too large to
+// keep only marks that the request ran.
+type IdempotencyKey struct {
+	ID          uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      string `json:"user_id" gorm:"type:text;not null;uniqueIndex:idx_idempotency_user_key"`
+	Key         string `json:"key" gorm:"column:idempotency_key;size:255;not null;uniqueIndex:idx_idempotency_user_key"`
+	RequestHash string `json:"-" gorm:"size:64;not null"`
+	Completed   bool   `json:"completed" gorm:"not null;default:false"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type" gorm:"size:255"`
+	Body        []byte `json:"-"`
+	// ResponseTooLarge is set when the request ran but Body was not stored.
+	ResponseTooLarge bool      `json:"response_too_large" gorm:"not null;default:false"`
+	CreatedAt        time.Time `json:"created_at"`
+	ExpiresAt        time.Time `json:"expires_at" gorm:"index"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a long-running operation, such as an import, that runs in the
+// background instead of inside the request that started it. Input holds the
+// uploaded file until the job finishes.
+type Job struct {
+	ID              uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID          string     `json:"user_id" gorm:"type:text;not null;index"`
+	VaultID         *string    `json:"vault_id" gorm:"type:text;index"`
+	Type            string     `json:"type" gorm:"size:64;not null"`
+	Status          string     `json:"status" gorm:"size:16;not null;default:'queued';index"`
+	Params          string     `json:"-" gorm:"type:text"`
+	Input           []byte     `json:"-"`
+	Progress        int        `json:"progress" gorm:"not null;default:0"`
+	ProcessedItems  int        `json:"processed_items" gorm:"not null;default:0"`
+	TotalItems      int        `json:"total_items" gorm:"not null;default:0"`
+	ErrorCount      int        `json:"error_count" gorm:"not null;default:0"`
+	Result          *string    `json:"-" gorm:"type:text"`
+	Error           *string    `json:"error" gorm:"type:text"`
+	CancelRequested bool       `json:"cancel_requested" gorm:"not null;default:false"`
+	StartedAt       *time.Time `json:"started_at"`
+	HeartbeatAt     *time.Time `json:"heartbeat_at"`
+	FinishedAt      *time.Time `json:"finished_at" gorm:"index"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// JobError is a problem a job ran into with one item, such as a row it
+// could not import.
+type JobError struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	JobID     uint      `json:"job_id" gorm:"not null;index"`
+	Message   string    `json:"message" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Memory kinds resurfaced by "On this day". A memory is identified by its
+// kind and the ID of the row it comes from.
+const (
+	MemoryKindPost          = "post"
+	MemoryKindActivity      = "activity"
+	MemoryKindCall          = "call"
+	MemoryKindNote          = "note"
+	MemoryKindPhoto         = "photo"
+	MemoryKindImportantDate = "important_date"
+)
+
+// MemoryState records how one user reacted to a memory. Memories without a
+// row are neither dismissed nor favorites. VaultID is the vault of the
+// memory, so favorites are only listed while the user can still see that
+// vault, and deleting the vault drops them.
+type MemoryState struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    string    `json:"user_id" gorm:"type:text;not null;uniqueIndex:idx_memory_state_unique"`
+	VaultID   string    `json:"vault_id" gorm:"type:text;not null;index"`
+	Kind      string    `json:"kind" gorm:"size:32;not null;uniqueIndex:idx_memory_state_unique"`
+	ItemID    uint      `json:"item_id" gorm:"not null;uniqueIndex:idx_memory_state_unique"`
+	Dismissed bool      `json:"dismissed" gorm:"not null"`
+	Favorite  bool      `json:"favorite" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MemoryPreference holds one user's "On this day" notification setting.
+// Users without a row are not notified. NotifyHour is the hour of the day,
+// in the user's timezone, from which the daily notification is sent.
+type MemoryPreference struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      string    `json:"user_id" gorm:"type:text;not null;uniqueIndex"`
+	NotifyDaily bool      `json:"notify_daily" gorm:"not null"`
+	NotifyHour  int       `json:"notify_hour" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// MemoryNotificationDelivery records that the "On this day" notification of
+// one user and day was claimed for delivery. As with
+// TaskNotificationDelivery, the unique index keeps replicas from sending
+// the same notification twice. Date is the user's local day (YYYY-MM-DD).
+type MemoryNotificationDelivery struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    string     `json:"user_id" gorm:"type:text;not null;uniqueIndex:idx_memory_notification_delivery_unique"`
+	Date      string     `json:"date" gorm:"size:10;not null;uniqueIndex:idx_memory_notification_delivery_unique"`
+	ClaimedAt time.Time  `json:"claimed_at" gorm:"not null"`
+	SentAt    *time.Time `json:"sent_at"`
+	Error     *string    `json:"error" gorm:"type:text"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -13,6 +13,23 @@ type OAuthProvider struct {
 	DisplayName  string    `json:"display_name" gorm:"type:text"`  // shown on login page
 	DiscoveryURL string    `json:"discovery_url" gorm:"type:text"` // for OIDC only
 	Scopes       string    `json:"scopes" gorm:"type:text"`        // comma-separated custom scopes
+	AccountID    *string   `json:"account_id" gorm:"type:text"`    // account managed by the claim rules
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// OAuthClaimRule grants a role to users whose ID token or userinfo claim
+// contains a value, e.g. group "family" gets Editor on vault "Family". Rules
+// are re-applied on every login through the provider. A vault that does not
+// exist yet is created in the provider's account.
+type OAuthClaimRule struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProviderID   uint      `json:"provider_id" gorm:"not null;index"`
+	Claim        string    `json:"claim" gorm:"type:text;not null"` // dotted path, e.g. realm_access.roles
+	Value        string    `json:"value" gorm:"type:text;not null"`
+	AccountAdmin bool      `json:"account_admin" gorm:"default:false"`
+	VaultName    string    `json:"vault_name" gorm:"type:text"`
+	Permission   int       `json:"permission"` // vault permission, 0 without vault
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// OAuthClient is an application registered to obtain tokens from Bonds as
+// an OAuth 2.1 authorization server. Its ID is the client_id. Public clients
+// such as CLIs and MCP clients have no secret and must use PKCE.
+type OAuthClient struct {
+	ID         string `json:"id" gorm:"primaryKey;type:text"`
+	Name       string `json:"name" gorm:"type:text;not null"`
+	SecretHash string `json:"-" gorm:"type:text"`
+	// RedirectURIs and GrantTypes are newline- and comma-separated lists.
+	RedirectURIs string `json:"redirect_uris" gorm:"type:text"`
+	GrantTypes   string `json:"grant_types" gorm:"type:text;not null"`
+	// CreatedByUserID is empty for clients that registered themselves
+	// through dynamic client registration.
+	CreatedByUserID *string   `json:"created_by_user_id" gorm:"type:text;index"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// OAuthAuthorizationRequest is an authorization-code request waiting for the
+// user's consent. Approving it binds it to the user and sets the hash of the
+// one-time code the client redeems within a minute.
+type OAuthAuthorizationRequest struct {
+	ID            string    `json:"id" gorm:"primaryKey;type:text"`
+	ClientID      string    `json:"client_id" gorm:"type:text;not null;index"`
+	RedirectURI   string    `json:"redirect_uri" gorm:"type:text;not null"`
+	Scopes        string    `json:"scopes" gorm:"type:text"`
+	State         string    `json:"state" gorm:"type:text"`
+	CodeChallenge string    `json:"-" gorm:"type:text;not null"`
+	UserID        *string   `json:"user_id" gorm:"type:text"`
+	CodeHash      *string   `json:"-" gorm:"type:text;uniqueIndex"`
+	ExpiresAt     time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// OAuthDeviceAuthorization is a device authorization grant (RFC 8628): the
+// device polls with the device code while the user approves the short user
+// code in a browser.
+type OAuthDeviceAuthorization struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ClientID       string     `json:"client_id" gorm:"type:text;not null;index"`
+	DeviceCodeHash string     `json:"-" gorm:"type:text;uniqueIndex;not null"`
+	UserCode       string     `json:"user_code" gorm:"type:text;uniqueIndex;not null"`
+	Scopes         string     `json:"scopes" gorm:"type:text"`
+	Status         string     `json:"status" gorm:"type:text;not null;default:'pending'"`
+	UserID         *string    `json:"user_id" gorm:"type:text"`
+	LastPolledAt   *time.Time `json:"last_polled_at"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"index"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+const (
+	OAuthDeviceStatusPending  = "pending"
+	OAuthDeviceStatusApproved = "approved"
+	OAuthDeviceStatusDenied   = "denied"
+)
+
+// OAuthGrant is a user's consent for a client, listed as an authorized app.
+// Access tokens issued under it are PersonalAccessTokens pointing back at
+// it; revoking the grant deletes them together with its refresh tokens.
+type OAuthGrant struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    string    `json:"user_id" gorm:"type:text;not null;uniqueIndex:idx_oauth_grant_user_client"`
+	AccountID string    `json:"account_id" gorm:"type:text;not null"`
+	ClientID  string    `json:"client_id" gorm:"type:text;not null;uniqueIndex:idx_oauth_grant_user_client"`
+	Scopes    string    `json:"scopes" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OAuthRefreshToken is one refresh token of a grant. Like session refresh
+// tokens they are single use, and a replayed one revokes the grant.
+type OAuthRefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	GrantID   uint       `json:"grant_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"type:text;uniqueIndex;not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -17,4 +17,8 @@ type PersonalAccessToken struct {
 	LastUsedAt *time.Time `json:"last_used_at"`
 	CreatedAt  time.Time  `json:"created_at"`
 	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// OAuthGrantID is set on access tokens issued to an OAuth client. They
+	// belong to the authorized app and are not listed as API tokens.
+	OAuthGrantID *uint `json:"oauth_grant_id" gorm:"column:oauth_grant_id;index"`
 }
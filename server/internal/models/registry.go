@@ -22,8 +22,12 @@ func AllModels() []interface{} {
 		&ContactReminder{},
 		&ContactReminderSelectedUser{},
 		&ContactReminderScheduled{},
+		&ContactReminderDeliveryState{},
 		&ContactTask{},
 		&TaskContact{},
+		&TaskUserAssignee{},
+		&TaskNotificationPreference{},
+		&TaskNotificationDelivery{},
 		&TaskStatus{},
 		&ContactFeedItem{},
 
@@ -104,7 +108,31 @@ func AllModels() []interface{} {
 		&WebAuthnCredential{},
 
 		&OAuthProvider{},
+		&OAuthClaimRule{},
 		&SystemSetting{},
 		&PersonalAccessToken{},
+		&UserSession{},
+		&UserSessionRefreshToken{},
+		&OAuthClient{},
+		&OAuthAuthorizationRequest{},
+		&OAuthDeviceAuthorization{},
+		&OAuthGrant{},
+		&OAuthRefreshToken{},
+		&VaultChange{},
+		&VaultChangeCounter{},
+		&IdempotencyKey{},
+		&AutomationRule{},
+		&AutomationExecution{},
+		&AutomationQueueItem{},
+		&Job{},
+		&JobError{},
+		&EmailInbox{},
+		&IngestedEmail{},
+		&GeocodingCacheEntry{},
+		&GeocodingTask{},
+		&GeocodingRateLimit{},
+		&MemoryState{},
+		&MemoryPreference{},
+		&MemoryNotificationDelivery{},
 	}
 }
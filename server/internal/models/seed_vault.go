@@ -168,6 +168,8 @@ var interactionActivityTypeDefs = []interactionActivityTypeDef{
 	{"seed.activity_types.phone_call", "phone_call", "phone", "#1677ff"},
 	{"seed.activity_types.video_call", "video_call", "video-camera", "#722ed1"},
 	{"seed.activity_types.in_person_meeting", "in_person_meeting", "team", "#52c41a"},
+	{"seed.activity_types.email", "email", "mail", "#fa8c16"},
+	{"seed.activity_types.messages", "messages", "message", "#13c2c2"},
 }
 
 func seedInteractionActivityTypes(tx *gorm.DB, vaultID, locale string) error {
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Task notification kinds. Each kind fires at most once per
+// (task, user, key) — see TaskNotificationDelivery.
+const (
+	TaskNotificationAssigned = "assigned"
+	TaskNotificationDueSoon  = "due_soon"
+	TaskNotificationOverdue  = "overdue"
+)
+
+// TaskUserAssignee assigns a vault task to a vault user (as opposed to
+// TaskContact, which links the contacts a task is about). Assigned users are
+// the recipients of task notifications. AssignedByID is NULL for
+// assignments carried over automatically (e.g. to the next occurrence of a
+// recurring task), which are not announced again.
+type TaskUserAssignee struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ContactTaskID uint      `json:"contact_task_id" gorm:"not null;index;uniqueIndex:idx_task_user_assignee_unique"`
+	UserID        string    `json:"user_id" gorm:"type:text;not null;index;uniqueIndex:idx_task_user_assignee_unique"`
+	AssignedByID  *string   `json:"assigned_by_id" gorm:"type:text"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (TaskUserAssignee) TableName() string {
+	return "task_user_assignees"
+}
+
+// TaskNotificationPreference holds one user's task notification settings.
+// Users without a row get the defaults from
+// services.defaultTaskNotificationPreference; the columns deliberately have
+// no database defaults so an explicit false/0 is stored as-is.
+type TaskNotificationPreference struct {
+	ID                 uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID             string    `json:"user_id" gorm:"type:text;not null;uniqueIndex"`
+	NotifyOnAssignment bool      `json:"notify_on_assignment" gorm:"not null"`
+	NotifyBeforeDue    bool      `json:"notify_before_due" gorm:"not null"`
+	DueLeadMinutes     int       `json:"due_lead_minutes" gorm:"not null"`
+	NotifyWhenOverdue  bool      `json:"notify_when_overdue" gorm:"not null"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// TaskNotificationDelivery records that a task notification was claimed
+// for delivery. The unique index is the cross-replica guard: a scheduler
+// run claims a notification by inserting its row, and a concurrent run on
+// another replica loses on the constraint instead of sending a duplicate.
+// Key identifies what is being notified about — the assignee row for
+// assignments, the due date for due-soon/overdue — so moving a task's due
+// date re-arms its deadline notifications.
+type TaskNotificationDelivery struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ContactTaskID uint       `json:"contact_task_id" gorm:"not null;index;uniqueIndex:idx_task_notification_delivery_unique"`
+	UserID        string     `json:"user_id" gorm:"type:text;not null;index;uniqueIndex:idx_task_notification_delivery_unique"`
+	Kind          string     `json:"kind" gorm:"size:16;not null;uniqueIndex:idx_task_notification_delivery_unique"`
+	Key           string     `json:"key" gorm:"size:64;not null;uniqueIndex:idx_task_notification_delivery_unique"`
+	ClaimedAt     time.Time  `json:"claimed_at" gorm:"not null"`
+	SentAt        *time.Time `json:"sent_at"`
+	Error         *string    `json:"error" gorm:"type:text"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/naiba/bonds/internal/phone"
 	"gorm.io/gorm"
 )
 
@@ -40,6 +41,7 @@ type User struct {
 	DefaultMapSite            string     `json:"default_map_site" gorm:"default:'openstreetmap'"`
 	EnableAlternativeCalendar bool       `json:"enable_alternative_calendar" gorm:"default:false"`
 	Locale                    string     `json:"locale" gorm:"default:'en'"`
+	PhoneRegion               string     `json:"phone_region" gorm:"size:2"`
 	RememberToken             *string    `json:"-"`
 	CreatedAt                 time.Time  `json:"created_at"`
 	UpdatedAt                 time.Time  `json:"updated_at"`
@@ -55,3 +57,12 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// EffectivePhoneRegion is the region national phone numbers of this user are
+// read in: the phone_region preference, or a guess from the locale.
+func (u *User) EffectivePhoneRegion() string {
+	if u.PhoneRegion != "" {
+		return u.PhoneRegion
+	}
+	return phone.DefaultRegion(u.Locale)
+}
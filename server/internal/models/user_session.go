@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// UserSession is one signed-in browser or app. Its ID is the jti of every
+// access token issued for it, so revoking the row rejects those tokens
+// before they expire.
+type UserSession struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:text"`
+	UserID     string     `json:"user_id" gorm:"type:text;not null;index"`
+	UserAgent  string     `json:"user_agent" gorm:"type:text"`
+	IPAddress  string     `json:"ip_address" gorm:"type:text"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"index"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// UserSessionRefreshToken is one refresh token of a session. Refresh tokens
+// are single use: redeeming one marks it used and issues its successor, so a
+// used token that comes back was copied, and the whole session is revoked.
+type UserSessionRefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	SessionID string     `json:"session_id" gorm:"type:text;not null;index"`
+	TokenHash string     `json:"-" gorm:"type:text;uniqueIndex;not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -16,5 +16,13 @@ type UserToken struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 
+	// DisabledByClaims records that the provider's claim rules disabled the
+	// user, so a later login that matches a rule again re-enables them
+	// without overriding an administrator's decision.
+	DisabledByClaims bool `json:"disabled_by_claims" gorm:"default:false"`
+	// ProvisionedByClaims records that the provider's claim rules created the
+	// user. Only such users are managed by the rules afterwards.
+	ProvisionedByClaims bool `json:"provisioned_by_claims" gorm:"default:false"`
+
 	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	VaultChangeCreated = "created"
+	VaultChangeUpdated = "updated"
+	VaultChangeDeleted = "deleted"
+)
+
+// Entity types tracked by the vault change log.
+const (
+	VaultChangeContact       = "contact"
+	VaultChangeNote          = "note"
+	VaultChangeTask          = "task"
+	VaultChangeReminder      = "reminder"
+	VaultChangeImportantDate = "important_date"
+	VaultChangeActivity      = "activity"
+	VaultChangePost          = "post"
+	VaultChangeFile          = "file"
+	VaultChangeCall          = "call"
+)
+
+// VaultChange is one entry of a vault's change log. Seq orders the entries
+// of a vault and backs the cursor of the delta sync API, and deleted entries
+// stay behind as tombstones after the entity itself is gone.
+type VaultChange struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	VaultID    string    `json:"vault_id" gorm:"type:text;not null;index;index:idx_vault_change_seq,priority:1"`
+	Seq        uint      `json:"seq" gorm:"not null;default:0;index:idx_vault_change_seq,priority:2"`
+	EntityType string    `json:"entity_type" gorm:"size:32;not null;index:idx_vault_change_entity"`
+	EntityID   string    `json:"entity_id" gorm:"type:text;not null;index:idx_vault_change_entity"`
+	Action     string    `json:"action" gorm:"size:16;not null"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// VaultChangeCounter hands out the Seq of a vault's change log entries.
+// IDs come from a sequence and can commit out of order on PostgreSQL, so a
+// reader could see ID 11 before ID 10 and move its cursor past 10 for good.
+// The counter row is updated by the transaction that writes the entry and
+// stays locked until it commits, so a higher Seq never becomes visible
+// before a lower one. SeedVersion is the VaultChangeSeedVersion the vault's
+// existing entities were written to the log with, or 0 if they were not.
+type VaultChangeCounter struct {
+	VaultID     string `json:"vault_id" gorm:"primaryKey;type:text"`
+	Seq         uint   `json:"seq" gorm:"not null;default:0"`
+	SeedVersion int    `json:"seed_version" gorm:"not null;default:0"`
+}
+
+// ReserveVaultChangeSeqs advances the vault's change counter by n and
+// returns the first reserved Seq. tx must be a transaction: the counter row
+// stays locked until it ends, which serializes the writers of a vault.
+func ReserveVaultChangeSeqs(tx *gorm.DB, vaultID string, n uint) (uint, error) {
+	bump := func() (int64, error) {
+		result := tx.Model(&VaultChangeCounter{}).Where("vault_id = ?", vaultID).UpdateColumn("seq", gorm.Expr("seq + ?", n))
+		return result.RowsAffected, result.Error
+	}
+	affected, err := bump()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&VaultChangeCounter{VaultID: vaultID}).Error; err != nil {
+			return 0, err
+		}
+		if _, err := bump(); err != nil {
+			return 0, err
+		}
+	}
+	var last uint
+	if err := tx.Model(&VaultChangeCounter{}).Where("vault_id = ?", vaultID).Select("seq").Scan(&last).Error; err != nil {
+		return 0, err
+	}
+	return last - n + 1, nil
+}
@@ -0,0 +1,54 @@
+// Package phone normalizes phone numbers to E.164 so that numbers written in
+// different styles can be compared.
+package phone
+
+import (
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// languageRegions maps the languages Bonds is translated into to the region
+// their speakers most likely dial from, for locales without a region.
+var languageRegions = map[string]string{
+	"de": "DE",
+	"en": "US",
+	"es": "ES",
+	"fr": "FR",
+	"pt": "PT",
+	"zh": "CN",
+}
+
+// ValidRegion reports whether r is an ISO 3166-1 alpha-2 region code with
+// a known numbering plan.
+func ValidRegion(r string) bool {
+	return phonenumbers.GetCountryCodeForRegion(strings.ToUpper(r)) != 0
+}
+
+// DefaultRegion guesses a region from a locale such as "pt-BR" or "de".
+// It returns "" when the locale does not point at one region.
+func DefaultRegion(locale string) string {
+	parts := strings.FieldsFunc(locale, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) == 0 {
+		return ""
+	}
+	if len(parts) > 1 && ValidRegion(parts[1]) {
+		return strings.ToUpper(parts[1])
+	}
+	return languageRegions[strings.ToLower(parts[0])]
+}
+
+// Normalize returns the E.164 form of a phone number, such as
+// "+15550102000". Numbers without a country code are read as national
+// numbers of region, with its trunk and international prefixes. It returns
+// "" when the number cannot be parsed, has no country code and no known
+// region, or has an impossible length for its country. Short numbers that
+// only work inside an area, such as 112, are refused too. Extensions are
+// dropped.
+func Normalize(raw, r string) string {
+	num, err := phonenumbers.Parse(strings.TrimSpace(raw), strings.ToUpper(r))
+	if err != nil || phonenumbers.IsPossibleNumberWithReason(num) != phonenumbers.IS_POSSIBLE {
+		return ""
+	}
+	return phonenumbers.Format(num, phonenumbers.E164)
+}
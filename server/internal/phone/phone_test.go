@@ -0,0 +1,57 @@
+package phone
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		raw    string
+		region string
+		want   string
+	}{
+		{"+1 (555) 010-2000", "", "+15550102000"},
+		{"555-010-2000", "US", "+15550102000"},
+		{"5550102000", "US", "+15550102000"},
+		{"1 555 010 2000", "US", "+15550102000"},
+		{"011 44 20 7946 0958", "US", "+442079460958"},
+		{"020 7946 0958", "GB", "+442079460958"},
+		{"0044 20 7946 0958", "DE", "+442079460958"},
+		{"030 1234567", "DE", "+49301234567"},
+		{"06 1234 5678", "IT", "+390612345678"},
+		{"tel:+49-30-1234567", "", "+49301234567"},
+		{"+44 (0)20 7946 0958", "", "+442079460958"},
+		{"+49 30 1234567 ext. 12", "", "+49301234567"},
+		{"(11) 91234-5678", "BR", "+5511912345678"},
+		{"030 1234567", "", ""},
+		{"555-010-2000", "", ""},
+		{"020 7946 0958", "US", ""},
+		{"0049 030 1234567", "FR", "+49301234567"},
+		{"call me", "US", ""},
+		{"112", "DE", ""},
+		{"+1234567890123456", "", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.raw, tt.region); got != tt.want {
+			t.Errorf("Normalize(%q, %q) = %q, want %q", tt.raw, tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultRegion(t *testing.T) {
+	tests := map[string]string{
+		"pt-BR": "BR",
+		"pt-PT": "PT",
+		"de":    "DE",
+		"zh":    "CN",
+		"en":    "US",
+		"en_GB": "GB",
+		"":      "",
+	}
+	for locale, want := range tests {
+		if got := DefaultRegion(locale); got != want {
+			t.Errorf("DefaultRegion(%q) = %q, want %q", locale, got, want)
+		}
+	}
+	if !ValidRegion("us") || !ValidRegion("KZ") || ValidRegion("XX") {
+		t.Error("unexpected ValidRegion result")
+	}
+}
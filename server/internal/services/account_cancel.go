@@ -44,6 +44,16 @@ func (s *AccountCancelService) Cancel(userID, accountID, password string) error
 				return err
 			}
 		}
+		accountUsers := tx.Model(&models.User{}).Select("id").Where("account_id = ?", accountID)
+		if err := deleteUserSessions(tx, "user_id IN (?)", accountUsers); err != nil {
+			return err
+		}
+		if err := deleteOAuthGrants(tx, "user_id IN (?)", accountUsers); err != nil {
+			return err
+		}
+		if err := deleteJobs(tx, "user_id IN (?)", accountUsers); err != nil {
+			return err
+		}
 		if err := tx.Where("account_id = ?", accountID).Delete(&models.User{}).Error; err != nil {
 			return err
 		}
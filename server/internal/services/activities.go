@@ -104,6 +104,9 @@ func (s *ActivityService) CreateForUser(vaultID, userID string, req dto.Activity
 		if err := tx.Create(&event).Error; err != nil {
 			return err
 		}
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeActivity, event.ID, models.VaultChangeCreated); err != nil {
+			return err
+		}
 		if err := replaceActivityParticipants(tx, event.ID, contactIDs); err != nil {
 			return err
 		}
@@ -148,6 +151,9 @@ func (s *ActivityService) UpdateForUser(vaultID, userID string, id uint, req dto
 		if err := tx.Save(&replacement).Error; err != nil {
 			return err
 		}
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeActivity, id, models.VaultChangeUpdated); err != nil {
+			return err
+		}
 		if err := replaceActivityParticipantsLocked(tx, id, contactIDs); err != nil {
 			return err
 		}
@@ -167,13 +173,25 @@ func (s *ActivityService) Delete(vaultID string, id uint) error {
 		return err
 	}
 	return s.db.Transaction(func(tx *gorm.DB) error {
+		var milestoneIDs []uint
+		if err := tx.Model(&models.Activity{}).Where("parent_id = ?", id).Pluck("id", &milestoneIDs).Error; err != nil {
+			return err
+		}
 		if err := tx.Model(&models.Activity{}).Where("parent_id = ?", id).Update("parent_id", nil).Error; err != nil {
 			return err
 		}
 		if err := tx.Where("activity_id = ?", id).Delete(&models.ActivityParticipant{}).Error; err != nil {
 			return err
 		}
-		return tx.Delete(&event).Error
+		if err := tx.Delete(&event).Error; err != nil {
+			return err
+		}
+		for _, milestoneID := range milestoneIDs {
+			if err := recordVaultChange(tx, vaultID, models.VaultChangeActivity, milestoneID, models.VaultChangeUpdated); err != nil {
+				return err
+			}
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeActivity, id, models.VaultChangeDeleted)
 	})
 }
 
@@ -368,8 +386,43 @@ func updateInteractionLastTalkedTo(tx *gorm.DB, typeID *uint, happenedAt *time.T
 	if !eventType.CountsAsInteraction {
 		return nil
 	}
-	return tx.Model(&models.Contact{}).Where("id IN ? AND (last_talked_to IS NULL OR last_talked_to < ?)", contactIDs, *happenedAt).
-		Update("last_talked_to", *happenedAt).Error
+	return advanceLastTalkedTo(tx, *happenedAt, contactIDs)
+}
+
+// systemActivityTypeID returns the vault's activity type of a system kind,
+// such as "email", or nil when the vault deleted it.
+func systemActivityTypeID(tx *gorm.DB, vaultID, kind string) (*uint, error) {
+	var ids []uint
+	if err := tx.Model(&models.ActivityType{}).
+		Joins("JOIN activity_categories ON activity_categories.id = activity_types.activity_category_id").
+		Where("activity_categories.vault_id = ? AND activity_types.system_kind = ?", vaultID, kind).
+		Limit(1).Pluck("activity_types.id", &ids).Error; err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return &ids[0], nil
+}
+
+// advanceLastTalkedTo moves last_talked_to of the contacts forward to
+// happenedAt. Contacts talked to later keep their date.
+func advanceLastTalkedTo(tx *gorm.DB, happenedAt time.Time, contactIDs []string) error {
+	var contacts []models.Contact
+	if err := tx.Select("id", "vault_id").Where("id IN ? AND (last_talked_to IS NULL OR last_talked_to < ?)", contactIDs, happenedAt).
+		Find(&contacts).Error; err != nil || len(contacts) == 0 {
+		return err
+	}
+	ids := make([]string, len(contacts))
+	for i := range contacts {
+		ids[i] = contacts[i].ID
+	}
+	if err := tx.Model(&models.Contact{}).Where("id IN ?", ids).Update("last_talked_to", happenedAt).Error; err != nil {
+		return err
+	}
+	for i := range contacts {
+		if err := recordVaultChange(tx, contacts[i].VaultID, models.VaultChangeContact, contacts[i].ID, models.VaultChangeUpdated); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func replaceActivityParticipants(tx *gorm.DB, activityID uint, contactIDs []string) error {
@@ -14,7 +14,6 @@ var ErrAddressNotFound = errors.New("address not found")
 type AddressService struct {
 	db           *gorm.DB
 	feedRecorder *FeedRecorder
-	geocoder     Geocoder
 }
 
 func NewAddressService(db *gorm.DB) *AddressService {
@@ -25,10 +24,6 @@ func (s *AddressService) SetFeedRecorder(fr *FeedRecorder) {
 	s.feedRecorder = fr
 }
 
-func (s *AddressService) SetGeocoder(g Geocoder) {
-	s.geocoder = g
-}
-
 func (s *AddressService) List(contactID, vaultID string) ([]dto.AddressResponse, error) {
 	if err := validateContactBelongsToVault(s.db, contactID, vaultID); err != nil {
 		return nil, err
@@ -99,16 +94,17 @@ func (s *AddressService) Create(contactID, vaultID string, req dto.CreateAddress
 			return err
 		}
 		if !isPast {
-			return tx.Model(&pivot).Update("is_past_address", false).Error
+			if err := tx.Model(&pivot).Update("is_past_address", false).Error; err != nil {
+				return err
+			}
 		}
-		return nil
+		_, err := queueGeocoding(tx, address)
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	s.tryGeocode(&address)
-
 	if s.feedRecorder != nil {
 		entityType := "Address"
 		s.feedRecorder.Record(contactID, "", ActionAddressAdded, "Added an address", &address.ID, &entityType)
@@ -153,7 +149,11 @@ func (s *AddressService) Update(id uint, contactID, vaultID string, req dto.Upda
 		pivot.IsPastAddress = isPast
 		pivot.DateFrom = req.DateFrom
 		pivot.DateTo = req.DateTo
-		return tx.Save(&pivot).Error
+		if err := tx.Save(&pivot).Error; err != nil {
+			return err
+		}
+		_, err := queueGeocoding(tx, address)
+		return err
 	})
 	if err != nil {
 		return nil, err
@@ -175,39 +175,13 @@ func (s *AddressService) Delete(id uint, contactID, vaultID string) error {
 		if result.RowsAffected == 0 {
 			return ErrAddressNotFound
 		}
+		if err := tx.Where("address_id = ?", id).Delete(&models.GeocodingTask{}).Error; err != nil {
+			return err
+		}
 		return tx.Where("id = ?", id).Delete(&models.Address{}).Error
 	})
 }
 
-func (s *AddressService) tryGeocode(address *models.Address) {
-	if s.geocoder == nil {
-		return
-	}
-	parts := []string{}
-	for _, p := range []*string{address.Line1, address.City, address.Province, address.PostalCode, address.Country} {
-		if p != nil && *p != "" {
-			parts = append(parts, *p)
-		}
-	}
-	if len(parts) == 0 {
-		return
-	}
-	query := ""
-	for i, p := range parts {
-		if i > 0 {
-			query += ", "
-		}
-		query += p
-	}
-	result, err := s.geocoder.Geocode(query)
-	if err != nil || result == nil {
-		return
-	}
-	address.Latitude = &result.Latitude
-	address.Longitude = &result.Longitude
-	s.db.Model(address).Select("latitude", "longitude").Updates(address)
-}
-
 func toAddressResponse(a *models.Address, isPastAddress bool, dateFrom, dateTo *time.Time) dto.AddressResponse {
 	return dto.AddressResponse{
 		ID:            a.ID,
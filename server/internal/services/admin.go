@@ -297,13 +297,30 @@ func (s *AdminService) deleteUserDirectData(tx *gorm.DB, userID string) error {
 	if err := tx.Where("user_notification_channel_id IN (?)", channelSubquery).Delete(&models.UserNotificationSent{}).Error; err != nil {
 		return fmt.Errorf("delete user notification sent: %w", err)
 	}
+	if err := deleteUserSessions(tx, "user_id = ?", userID); err != nil {
+		return fmt.Errorf("delete user sessions: %w", err)
+	}
+	if err := deleteOAuthGrants(tx, "user_id = ?", userID); err != nil {
+		return fmt.Errorf("delete user oauth grants: %w", err)
+	}
+	if err := deleteJobs(tx, "user_id = ?", userID); err != nil {
+		return fmt.Errorf("delete user jobs: %w", err)
+	}
 
 	userTables := []interface{}{
 		&models.MoodTrackingEvent{},
+		&models.TaskUserAssignee{},
+		&models.TaskNotificationPreference{},
+		&models.TaskNotificationDelivery{},
+		&models.MemoryState{},
+		&models.MemoryPreference{},
+		&models.MemoryNotificationDelivery{},
+		&models.ContactReminderDeliveryState{},
 		&models.UserNotificationChannel{},
 		&models.UserToken{},
 		&models.WebAuthnCredential{},
 		&models.UserVault{},
+		&models.EmailInbox{},
 	}
 	for _, model := range userTables {
 		if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
@@ -398,6 +415,17 @@ func (s *AdminService) deleteVaultData(tx *gorm.DB, vaultID string) error {
 		&models.Loan{},
 		&models.ContactTask{},
 		&models.LifeMetric{},
+		&models.EmailInbox{},
+		&models.IngestedEmail{},
+		&models.GeocodingTask{},
+		&models.MemoryState{},
+	}
+
+	taskSubquery := tx.Model(&models.ContactTask{}).Unscoped().Select("id").Where("vault_id = ?", vaultID)
+	for _, model := range []interface{}{&models.TaskUserAssignee{}, &models.TaskNotificationDelivery{}} {
+		if err := tx.Where("contact_task_id IN (?)", taskSubquery).Delete(model).Error; err != nil {
+			return fmt.Errorf("delete vault task data for %T: %w", model, err)
+		}
 	}
 
 	for _, model := range vaultTables {
@@ -421,6 +449,11 @@ func (s *AdminService) deleteContactData(tx *gorm.DB, contactID string) error {
 	).Delete(&models.ContactReminderSelectedUser{}).Error; err != nil {
 		return fmt.Errorf("delete selected reminder recipients: %w", err)
 	}
+	if err := tx.Where("contact_reminder_id IN (?)",
+		tx.Model(&models.ContactReminder{}).Select("id").Where("contact_id = ?", contactID),
+	).Delete(&models.ContactReminderDeliveryState{}).Error; err != nil {
+		return fmt.Errorf("delete reminder delivery states: %w", err)
+	}
 	goalSubquery := tx.Model(&models.Goal{}).Select("id").Where("contact_id = ?", contactID)
 	if err := tx.Where("goal_id IN (?)", goalSubquery).Delete(&models.Streak{}).Error; err != nil {
 		return fmt.Errorf("delete streaks: %w", err)
@@ -3,6 +3,7 @@ package services
 import (
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -34,6 +35,7 @@ type AuthService struct {
 	cfg      *config.JWTConfig
 	mailer   Mailer
 	settings *SystemSettingService
+	ldap     *LDAPService
 }
 
 func NewAuthService(db *gorm.DB, cfg *config.JWTConfig) *AuthService {
@@ -48,6 +50,10 @@ func (s *AuthService) SetSystemSettings(settings *SystemSettingService) {
 	s.settings = settings
 }
 
+func (s *AuthService) SetLDAP(ldap *LDAPService) {
+	s.ldap = ldap
+}
+
 func (s *AuthService) isEmailVerificationRequired() bool {
 	if s.settings == nil {
 		return false
@@ -146,30 +152,83 @@ func (s *AuthService) Register(req dto.RegisterRequest, locale string) (*dto.Aut
 	return s.generateAuthResponse(&user)
 }
 
-func (s *AuthService) Login(req dto.LoginRequest) (*dto.AuthResponse, error) {
-	var user models.User
-	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrInvalidCredentials
+// createDirectoryUser creates a user vouched for by an external directory.
+// Without a shared account the user gets a new account of their own to
+// administer. In the shared account they get access to all of its vaults,
+// as manager when user.IsAccountAdministrator is set and as editor
+// otherwise. The first user of the instance becomes its administrator.
+func createDirectoryUser(tx *gorm.DB, user *models.User, sharedAccountID string) error {
+	var userCount int64
+	if err := tx.Model(&models.User{}).Count(&userCount).Error; err != nil {
+		return err
+	}
+	user.IsInstanceAdministrator = userCount == 0
+
+	if sharedAccountID == "" {
+		account := models.Account{}
+		if err := tx.Create(&account).Error; err != nil {
+			return err
 		}
-		return nil, err
+		user.AccountID = account.ID
+		user.IsAccountAdministrator = true
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return models.SeedAccountDefaults(tx, account.ID, user.ID, user.Email, user.Locale)
 	}
 
-	if user.Disabled {
-		return nil, ErrUserDisabled
+	var account models.Account
+	if err := tx.First(&account, "id = ?", sharedAccountID).Error; err != nil {
+		return err
+	}
+	user.AccountID = account.ID
+	if err := tx.Create(user).Error; err != nil {
+		return err
+	}
+	var vaults []models.Vault
+	if err := tx.Where("account_id = ?", account.ID).Find(&vaults).Error; err != nil {
+		return err
 	}
+	permission := models.PermissionEditor
+	if user.IsAccountAdministrator {
+		permission = models.PermissionManager
+	}
+	for _, v := range vaults {
+		if err := tx.Create(&models.UserVault{UserID: user.ID, VaultID: v.ID, Permission: permission}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	if user.Password == nil {
-		return nil, ErrInvalidCredentials
+// LinkLDAP links the user to the directory account of the login, so that
+// later LDAP logins sign in as them.
+func (s *AuthService) LinkLDAP(userID string, req dto.LinkLDAPRequest) error {
+	err := s.ldap.LinkUser(userID, req.Login, req.Password)
+	if errors.Is(err, ErrLDAPUnavailable) {
+		log.Printf("WARNING: LDAP link failed: %v", err)
 	}
+	return err
+}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(req.Password)); err != nil {
-		return nil, ErrInvalidCredentials
+func (s *AuthService) Login(req dto.LoginRequest) (*dto.AuthResponse, error) {
+	user, err := s.checkLocalPassword(req)
+	if errors.Is(err, ErrInvalidCredentials) && s.ldap.Enabled() {
+		user, err = s.ldap.Authenticate(req.Email, req.Password)
+		if errors.Is(err, ErrLDAPUnavailable) {
+			log.Printf("WARNING: LDAP login failed: %v", err)
+		}
+		if err == nil && user.Disabled {
+			err = ErrUserDisabled
+		}
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	if user.TwoFactorConfirmedAt != nil {
 		if req.TOTPCode == "" {
-			tempResp, err := s.generateTempAuthResponse(&user)
+			tempResp, err := s.generateTempAuthResponse(user)
 			if err != nil {
 				return nil, err
 			}
@@ -185,7 +244,33 @@ func (s *AuthService) Login(req dto.LoginRequest) (*dto.AuthResponse, error) {
 		}
 	}
 
-	return s.generateAuthResponse(&user)
+	return s.generateAuthResponse(user)
+}
+
+// checkLocalPassword verifies the password stored in Bonds. Users without
+// one, and everyone while password login is disabled, can only log in
+// through LDAP.
+func (s *AuthService) checkLocalPassword(req dto.LoginRequest) (*models.User, error) {
+	var user models.User
+	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	if user.Password == nil || (s.settings != nil && !s.settings.GetBool("auth.password.enabled", true)) {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(req.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &user, nil
 }
 
 func (s *AuthService) generateTempAuthResponse(user *models.User) (*dto.AuthResponse, error) {
@@ -216,7 +301,31 @@ func (s *AuthService) generateTempAuthResponse(user *models.User) (*dto.AuthResp
 	}, nil
 }
 
+// SignIn starts a new session for a user whose identity another flow, such
+// as a passkey, already proved. Refreshing an existing session takes its
+// refresh token; see RefreshSession.
+func (s *AuthService) SignIn(userID string) (*dto.AuthResponse, error) {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+	return s.generateAuthResponse(&user)
+}
+
+// RefreshToken serves clients that predate sessions: a still valid access
+// token without a jti starts a session and receives its first refresh token.
+// Tokens of a session must be renewed with their refresh token, so that
+// rotation and reuse detection cannot be bypassed.
 func (s *AuthService) RefreshToken(claims *middleware.JWTClaims) (*dto.AuthResponse, error) {
+	if claims.ID != "" {
+		return nil, ErrRefreshTokenRequired
+	}
 	var user models.User
 	if err := s.db.First(&user, "id = ?", claims.UserID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -314,7 +423,24 @@ func (s *AuthService) ResendVerification(userID string) error {
 	return nil
 }
 
+// generateAuthResponse starts a new session for a user who just signed in.
 func (s *AuthService) generateAuthResponse(user *models.User) (*dto.AuthResponse, error) {
+	session, refreshToken, err := s.startSession(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.sessionAuthResponse(user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp.RefreshToken = refreshToken
+	resp.RefreshTokenExpiresAt = &session.ExpiresAt
+	return resp, nil
+}
+
+// sessionAuthResponse signs an access token bound to a session through its
+// jti.
+func (s *AuthService) sessionAuthResponse(user *models.User, sessionID string) (*dto.AuthResponse, error) {
 	expiresAt := time.Now().Add(time.Duration(s.cfg.ExpiryHrs) * time.Hour)
 	claims := &middleware.JWTClaims{
 		UserID:          user.ID,
@@ -323,6 +449,7 @@ func (s *AuthService) generateAuthResponse(user *models.User) (*dto.AuthResponse
 		IsAdmin:         user.IsAccountAdministrator,
 		IsInstanceAdmin: user.IsInstanceAdministrator,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -4,7 +4,6 @@ import (
 	"testing"
 
 	"github.com/naiba/bonds/internal/dto"
-	"github.com/naiba/bonds/internal/middleware"
 	"github.com/naiba/bonds/internal/models"
 	"github.com/naiba/bonds/internal/testutil"
 )
@@ -264,7 +263,7 @@ func TestLoginDisabledUser(t *testing.T) {
 	}
 }
 
-func TestRefreshTokenDisabledUser(t *testing.T) {
+func TestSignInDisabledUser(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	cfg := testutil.TestJWTConfig()
 	svc := NewAuthService(db, cfg)
@@ -281,11 +280,7 @@ func TestRefreshTokenDisabledUser(t *testing.T) {
 
 	db.Model(&models.User{}).Where("id = ?", resp.User.ID).Update("disabled", true)
 
-	_, err = svc.RefreshToken(&middleware.JWTClaims{
-		UserID:    resp.User.ID,
-		AccountID: resp.User.AccountID,
-		Email:     resp.User.Email,
-	})
+	_, err = svc.SignIn(resp.User.ID)
 	if err != ErrUserDisabled {
 		t.Errorf("expected ErrUserDisabled, got %v", err)
 	}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/pkg/response"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAutomationRuleNotFound     = errors.New("automation rule not found")
+	ErrInvalidAutomationTrigger   = errors.New("invalid automation trigger")
+	ErrInvalidAutomationCondition = errors.New("invalid automation condition")
+	ErrInvalidAutomationAction    = errors.New("invalid automation action")
+	ErrTooManyAutomationRules     = errors.New("too many automation rules in vault")
+	ErrAutomationActionsRequired  = errors.New("an automation rule needs at least one action")
+)
+
+const (
+	maxAutomationRulesPerVault = 100
+	maxAutomationActions       = 10
+	maxAutomationConditions    = 20
+	// maxAutomationDepth is how many rules can follow each other when the
+	// actions of one rule trigger another.
+	maxAutomationDepth                = 3
+	defaultAutomationLogRetentionDays = 30
+	automationWebhookTimeout          = 10 * time.Second
+	// automationClaimBatch is how many queued feed items are considered
+	// per pass.
+	automationClaimBatch = 20
+)
+
+const (
+	automationActionCreateTask       = "create_task"
+	automationActionCreateReminder   = "create_reminder"
+	automationActionAddLabel         = "add_label"
+	automationActionAddToGroup       = "add_to_group"
+	automationActionSendNotification = "send_notification"
+	automationActionCallWebhook      = "call_webhook"
+)
+
+const (
+	automationOperatorEquals     = "equals"
+	automationOperatorNotEquals  = "not_equals"
+	automationOperatorContains   = "contains"
+	automationOperatorIsEmpty    = "is_empty"
+	automationOperatorIsNotEmpty = "is_not_empty"
+)
+
+// automationTriggers are the feed actions a rule can react to. Deleted
+// contacts are gone by the time their action is recorded.
+var automationTriggers = map[string]bool{
+	ActionContactCreated:     true,
+	ActionContactUpdated:     true,
+	ActionNoteCreated:        true,
+	ActionNoteUpdated:        true,
+	ActionNoteDeleted:        true,
+	ActionReminderCreated:    true,
+	ActionCallLogged:         true,
+	ActionTaskCreated:        true,
+	ActionTaskCompleted:      true,
+	ActionAddressAdded:       true,
+	ActionActivityCreated:    true,
+	ActionFileUploaded:       true,
+	ActionLoanCreated:        true,
+	ActionRelationshipAdded:  true,
+	ActionLabelAdded:         true,
+	ActionImportantDateAdded: true,
+}
+
+// automationConditionFields maps each condition field to the trigger it is
+// limited to, or "" for fields of the contact itself.
+var automationConditionFields = map[string]string{
+	"contact.first_name":   "",
+	"contact.last_name":    "",
+	"contact.nickname":     "",
+	"contact.job_position": "",
+	"contact.label_ids":    "",
+	"contact.group_ids":    "",
+	"label.id":             ActionLabelAdded,
+	"date.type":            ActionImportantDateAdded,
+	"date.label":           ActionImportantDateAdded,
+	"call.emotion":         ActionCallLogged,
+	"call.type":            ActionCallLogged,
+	"call.answered":        ActionCallLogged,
+}
+
+var automationReminderTypes = map[string]bool{
+	"one_time":        true,
+	"recurring_week":  true,
+	"recurring_month": true,
+	"recurring_year":  true,
+}
+
+type AutomationService struct {
+	db         *gorm.DB
+	settings   *SystemSettingService
+	mailer     Mailer
+	sender     NotificationSender
+	webPush    NotificationSender
+	httpClient *http.Client
+	// allowWebhookAddr decides which addresses webhooks may connect to.
+	allowWebhookAddr func(net.IP) bool
+	// wake runs the queue after a feed item was queued.
+	wake func()
+
+	mu       sync.Mutex
+	draining bool
+	again    bool
+}
+
+func NewAutomationService(db *gorm.DB) *AutomationService {
+	s := &AutomationService{db: db, allowWebhookAddr: isPublicAddr}
+	s.httpClient = newWebhookClient(func(ip net.IP) bool { return s.allowWebhookAddr(ip) })
+	s.wake = func() { go s.RunPending() }
+	return s
+}
+
+func (s *AutomationService) SetSystemSettings(settings *SystemSettingService) {
+	s.settings = settings
+}
+
+func (s *AutomationService) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+func (s *AutomationService) SetSender(sender NotificationSender) {
+	s.sender = sender
+}
+
+func (s *AutomationService) SetWebPush(webPush NotificationSender) {
+	s.webPush = webPush
+}
+
+func (s *AutomationService) List(vaultID string) ([]dto.AutomationRuleResponse, error) {
+	var rules []models.AutomationRule
+	if err := s.db.Where("vault_id = ?", vaultID).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	result := make([]dto.AutomationRuleResponse, len(rules))
+	for i := range rules {
+		result[i] = toAutomationRuleResponse(&rules[i])
+	}
+	return result, nil
+}
+
+func (s *AutomationService) Get(id uint, vaultID string) (*dto.AutomationRuleResponse, error) {
+	rule, err := s.findRule(id, vaultID)
+	if err != nil {
+		return nil, err
+	}
+	resp := toAutomationRuleResponse(rule)
+	return &resp, nil
+}
+
+func (s *AutomationService) Create(vaultID, userID string, req dto.CreateAutomationRuleRequest) (*dto.AutomationRuleResponse, error) {
+	var count int64
+	if err := s.db.Model(&models.AutomationRule{}).Where("vault_id = ?", vaultID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count >= maxAutomationRulesPerVault {
+		return nil, ErrTooManyAutomationRules
+	}
+	rule := models.AutomationRule{VaultID: vaultID, CreatedBy: userID, Enabled: true}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if err := s.applyRule(&rule, req.Name, req.Trigger, req.Conditions, req.Actions); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&rule).Error; err != nil {
+		return nil, err
+	}
+	resp := toAutomationRuleResponse(&rule)
+	return &resp, nil
+}
+
+func (s *AutomationService) Update(id uint, vaultID string, req dto.UpdateAutomationRuleRequest) (*dto.AutomationRuleResponse, error) {
+	rule, err := s.findRule(id, vaultID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if err := s.applyRule(rule, req.Name, req.Trigger, req.Conditions, req.Actions); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(rule).Error; err != nil {
+		return nil, err
+	}
+	resp := toAutomationRuleResponse(rule)
+	return &resp, nil
+}
+
+func (s *AutomationService) Delete(id uint, vaultID string) error {
+	rule, err := s.findRule(id, vaultID)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", rule.ID).Delete(&models.AutomationExecution{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(rule).Error
+	})
+}
+
+// ListExecutions returns the automation log of a vault, newest first. A
+// ruleID of 0 lists the executions of every rule.
+func (s *AutomationService) ListExecutions(vaultID string, ruleID uint, page, perPage int) ([]dto.AutomationExecutionResponse, response.Meta, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+	query := s.db.Model(&models.AutomationExecution{}).Where("vault_id = ?", vaultID)
+	if ruleID != 0 {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, response.Meta{}, err
+	}
+	var executions []models.AutomationExecution
+	if err := query.Order("id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&executions).Error; err != nil {
+		return nil, response.Meta{}, err
+	}
+	result := make([]dto.AutomationExecutionResponse, len(executions))
+	for i := range executions {
+		result[i] = toAutomationExecutionResponse(&executions[i])
+	}
+	meta := response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: int(math.Ceil(float64(total) / float64(perPage))),
+	}
+	return result, meta, nil
+}
+
+// CleanupExecutions deletes log entries older than the
+// automation.log_retention_days setting.
+func (s *AutomationService) CleanupExecutions() (int64, error) {
+	days := defaultAutomationLogRetentionDays
+	if s.settings != nil {
+		days = s.settings.GetInt("automation.log_retention_days", defaultAutomationLogRetentionDays)
+	}
+	if days < 1 {
+		days = defaultAutomationLogRetentionDays
+	}
+	result := s.db.Where("created_at < ?", time.Now().AddDate(0, 0, -days)).Delete(&models.AutomationExecution{})
+	return result.RowsAffected, result.Error
+}
+
+func (s *AutomationService) findRule(id uint, vaultID string) (*models.AutomationRule, error) {
+	var rule models.AutomationRule
+	if err := s.db.Where("id = ? AND vault_id = ?", id, vaultID).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAutomationRuleNotFound
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// applyRule validates a rule definition against the vault and stores it on
+// rule.
+func (s *AutomationService) applyRule(rule *models.AutomationRule, name, trigger string, conditions []dto.AutomationCondition, actions []dto.AutomationAction) error {
+	if !automationTriggers[trigger] {
+		return ErrInvalidAutomationTrigger
+	}
+	if len(actions) == 0 {
+		return ErrAutomationActionsRequired
+	}
+	if len(actions) > maxAutomationActions || len(conditions) > maxAutomationConditions {
+		return ErrInvalidAutomationAction
+	}
+	for _, condition := range conditions {
+		requiredTrigger, ok := automationConditionFields[condition.Field]
+		if !ok || (requiredTrigger != "" && requiredTrigger != trigger) {
+			return ErrInvalidAutomationCondition
+		}
+		switch condition.Operator {
+		case automationOperatorEquals, automationOperatorNotEquals, automationOperatorContains:
+			if condition.Value == "" {
+				return ErrInvalidAutomationCondition
+			}
+		case automationOperatorIsEmpty, automationOperatorIsNotEmpty:
+		default:
+			return ErrInvalidAutomationCondition
+		}
+	}
+	for _, action := range actions {
+		if err := s.validateAction(rule.VaultID, action); err != nil {
+			return err
+		}
+	}
+	if conditions == nil {
+		conditions = []dto.AutomationCondition{}
+	}
+	conditionsJSON, err := json.Marshal(conditions)
+	if err != nil {
+		return err
+	}
+	actionsJSON, err := json.Marshal(actions)
+	if err != nil {
+		return err
+	}
+	rule.Name = name
+	rule.Trigger = trigger
+	rule.Conditions = string(conditionsJSON)
+	rule.Actions = string(actionsJSON)
+	return nil
+}
+
+func (s *AutomationService) validateAction(vaultID string, action dto.AutomationAction) error {
+	switch action.Type {
+	case automationActionCreateTask:
+		if action.Label == "" || (action.DueInDays != nil && *action.DueInDays < 0) {
+			return ErrInvalidAutomationAction
+		}
+	case automationActionCreateReminder:
+		if action.Label == "" || !automationReminderTypes[action.ReminderType] || (action.DaysFromNow != nil && *action.DaysFromNow < 0) {
+			return ErrInvalidAutomationAction
+		}
+		if _, err := normalizeReminderLeadTimes(action.LeadTimeDays); err != nil {
+			return ErrInvalidAutomationAction
+		}
+	case automationActionAddLabel:
+		if action.LabelID == nil {
+			return ErrInvalidAutomationAction
+		}
+		var count int64
+		if err := s.db.Model(&models.Label{}).Where("id = ? AND vault_id = ?", *action.LabelID, vaultID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrLabelNotFound
+		}
+	case automationActionAddToGroup:
+		if action.GroupID == nil {
+			return ErrInvalidAutomationAction
+		}
+		var count int64
+		if err := s.db.Model(&models.Group{}).Where("id = ? AND vault_id = ?", *action.GroupID, vaultID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrGroupNotFound
+		}
+	case automationActionSendNotification:
+		if action.Message == "" {
+			return ErrInvalidAutomationAction
+		}
+	case automationActionCallWebhook:
+		u, err := url.Parse(action.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidAutomationAction
+		}
+		if err := checkWebhookURL(u, s.allowWebhookAddr); errors.Is(err, ErrAutomationWebhookBlocked) {
+			return err
+		} else if err != nil {
+			return ErrInvalidAutomationAction
+		}
+	default:
+		return ErrInvalidAutomationAction
+	}
+	return nil
+}
+
+func toAutomationRuleResponse(rule *models.AutomationRule) dto.AutomationRuleResponse {
+	resp := dto.AutomationRuleResponse{
+		ID:         rule.ID,
+		VaultID:    rule.VaultID,
+		CreatedBy:  rule.CreatedBy,
+		Name:       rule.Name,
+		Trigger:    rule.Trigger,
+		Conditions: []dto.AutomationCondition{},
+		Actions:    []dto.AutomationAction{},
+		Enabled:    rule.Enabled,
+		CreatedAt:  rule.CreatedAt,
+		UpdatedAt:  rule.UpdatedAt,
+	}
+	_ = json.Unmarshal([]byte(rule.Conditions), &resp.Conditions)
+	_ = json.Unmarshal([]byte(rule.Actions), &resp.Actions)
+	return resp
+}
+
+func toAutomationExecutionResponse(execution *models.AutomationExecution) dto.AutomationExecutionResponse {
+	resp := dto.AutomationExecutionResponse{
+		ID:         execution.ID,
+		RuleID:     execution.RuleID,
+		ContactID:  execution.ContactID,
+		Trigger:    execution.Trigger,
+		FeedItemID: execution.FeedItemID,
+		Depth:      execution.Depth,
+		Status:     execution.Status,
+		Results:    []dto.AutomationActionResult{},
+		CreatedAt:  execution.CreatedAt,
+	}
+	if execution.Error != nil {
+		resp.Error = *execution.Error
+	}
+	if execution.Results != "" {
+		_ = json.Unmarshal([]byte(execution.Results), &resp.Results)
+	}
+	return resp
+}
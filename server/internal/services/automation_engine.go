@@ -0,0 +1,485 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	errAutomationLoop       = errors.New("skipped: the rule already ran earlier in this chain")
+	errAutomationDepth      = errors.New("skipped: too many rules triggered each other")
+	errAutomationNoChannels = errors.New("the rule owner has no active notification channel in this vault")
+)
+
+// automationChain follows the rules whose actions led to a feed item. Each
+// rule runs at most once per chain and chains stop after
+// maxAutomationDepth rules, so rules cannot trigger each other forever.
+type automationChain struct {
+	depth int
+	rules map[uint]bool
+}
+
+func (c *automationChain) next(ruleID uint) *automationChain {
+	next := &automationChain{rules: map[uint]bool{ruleID: true}}
+	if c != nil {
+		next.depth = c.depth + 1
+		for id := range c.rules {
+			next.rules[id] = true
+		}
+	}
+	return next
+}
+
+// automationEvent is what the conditions and actions of a rule see of the
+// feed item that triggered it.
+type automationEvent struct {
+	item    *models.ContactFeedItem
+	contact models.Contact
+	name    string
+	fields  map[string][]string
+	date    *models.ContactImportantDate
+}
+
+// automationChainJSON is how a chain is stored with a queued feed item.
+type automationChainJSON struct {
+	Depth int    `json:"depth"`
+	Rules []uint `json:"rules"`
+}
+
+// enqueue queues the rules of item when its vault has an enabled rule for
+// its action, and reports whether it did. tx is the transaction that wrote
+// the item, so the entry goes away if the write is rolled back.
+func (s *AutomationService) enqueue(tx *gorm.DB, item *models.ContactFeedItem, chain *automationChain) (bool, error) {
+	if item.VaultID == "" || !automationTriggers[item.Action] {
+		return false, nil
+	}
+	var count int64
+	if err := tx.Model(&models.AutomationRule{}).
+		Where("vault_id = ? AND trigger_action = ? AND enabled = ?", item.VaultID, item.Action, true).
+		Count(&count).Error; err != nil || count == 0 {
+		return false, err
+	}
+	entry := models.AutomationQueueItem{VaultID: item.VaultID, FeedItemID: item.ID}
+	if chain != nil {
+		stored := automationChainJSON{Depth: chain.depth}
+		for id := range chain.rules {
+			stored.Rules = append(stored.Rules, id)
+		}
+		data, err := json.Marshal(stored)
+		if err != nil {
+			return false, err
+		}
+		entry.Chain = string(data)
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RunPending runs the rules of the queued feed items until the queue is
+// empty, including the items their actions queue in turn. Feed recorders
+// start it once their write has committed; a cron job picks up the items
+// left behind by a restart or queued on another replica.
+func (s *AutomationService) RunPending() {
+	s.mu.Lock()
+	if s.draining {
+		s.again = true
+		s.mu.Unlock()
+		return
+	}
+	s.draining = true
+	s.mu.Unlock()
+
+	for {
+		for s.runNext() {
+		}
+		s.mu.Lock()
+		if !s.again {
+			s.draining = false
+			s.mu.Unlock()
+			return
+		}
+		s.again = false
+		s.mu.Unlock()
+	}
+}
+
+// runNext claims and runs a batch of queued feed items. It reports whether
+// it ran any.
+func (s *AutomationService) runNext() bool {
+	var entries []models.AutomationQueueItem
+	if err := s.db.Order("id ASC").Limit(automationClaimBatch).Find(&entries).Error; err != nil {
+		log.Printf("[automation] failed to list queued feed items: %v", err)
+		return false
+	}
+	ran := false
+	for i := range entries {
+		// Deleting the entry claims it, so replicas draining the queue at
+		// the same time run each item once.
+		res := s.db.Delete(&models.AutomationQueueItem{}, entries[i].ID)
+		if res.Error != nil {
+			log.Printf("[automation] failed to claim queued feed item %d: %v", entries[i].FeedItemID, res.Error)
+			continue
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		ran = true
+		s.runQueued(&entries[i])
+	}
+	return ran
+}
+
+func (s *AutomationService) runQueued(entry *models.AutomationQueueItem) {
+	var item models.ContactFeedItem
+	if err := s.db.Where("id = ? AND vault_id = ?", entry.FeedItemID, entry.VaultID).First(&item).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("[automation] failed to load feed item %d: %v", entry.FeedItemID, err)
+		}
+		return
+	}
+	var chain *automationChain
+	if entry.Chain != "" {
+		var stored automationChainJSON
+		if err := json.Unmarshal([]byte(entry.Chain), &stored); err != nil {
+			log.Printf("[automation] feed item %d has an invalid chain: %v", entry.FeedItemID, err)
+			return
+		}
+		chain = &automationChain{depth: stored.Depth, rules: make(map[uint]bool, len(stored.Rules))}
+		for _, id := range stored.Rules {
+			chain.rules[id] = true
+		}
+	}
+	s.runRules(&item, chain)
+}
+
+// runRules runs the enabled rules of the item's vault that react to its
+// action. This happens in the background after the write committed, so
+// failures end up in the automation log instead of failing the request.
+func (s *AutomationService) runRules(item *models.ContactFeedItem, chain *automationChain) {
+	if item.VaultID == "" || !automationTriggers[item.Action] {
+		return
+	}
+	var rules []models.AutomationRule
+	if err := s.db.Where("vault_id = ? AND trigger_action = ? AND enabled = ?", item.VaultID, item.Action, true).
+		Order("id ASC").Find(&rules).Error; err != nil {
+		log.Printf("[automation] failed to load rules of vault %s: %v", item.VaultID, err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+	event, err := s.loadEvent(item)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("[automation] failed to load feed item %d: %v", item.ID, err)
+		}
+		return
+	}
+
+	depth := 0
+	if chain != nil {
+		depth = chain.depth + 1
+	}
+	for i := range rules {
+		rule := &rules[i]
+		var conditions []dto.AutomationCondition
+		if err := json.Unmarshal([]byte(rule.Conditions), &conditions); err != nil {
+			log.Printf("[automation] rule %d has invalid conditions: %v", rule.ID, err)
+			continue
+		}
+		if !event.matches(conditions) {
+			continue
+		}
+		execution := models.AutomationExecution{
+			VaultID:    item.VaultID,
+			RuleID:     rule.ID,
+			ContactID:  item.ContactID,
+			Trigger:    item.Action,
+			FeedItemID: item.ID,
+			Depth:      depth,
+		}
+		switch {
+		case chain != nil && chain.rules[rule.ID]:
+			execution.Status = models.AutomationExecutionSkipped
+			execution.Error = strPtrOrNil(errAutomationLoop.Error())
+		case depth >= maxAutomationDepth:
+			execution.Status = models.AutomationExecutionSkipped
+			execution.Error = strPtrOrNil(errAutomationDepth.Error())
+		default:
+			s.execute(rule, event, chain.next(rule.ID), &execution)
+		}
+		if err := s.db.Create(&execution).Error; err != nil {
+			log.Printf("[automation] failed to log rule %d for feed item %d: %v", rule.ID, item.ID, err)
+		}
+	}
+}
+
+func (s *AutomationService) loadEvent(item *models.ContactFeedItem) (*automationEvent, error) {
+	event := &automationEvent{item: item, fields: map[string][]string{}}
+	if err := s.db.Where("id = ? AND vault_id = ?", item.ContactID, item.VaultID).First(&event.contact).Error; err != nil {
+		return nil, err
+	}
+	contact := &event.contact
+	event.name = utils.FormatContactNameSnapshot(nil, contact)
+	event.fields["contact.first_name"] = []string{ptrToStr(contact.FirstName)}
+	event.fields["contact.last_name"] = []string{ptrToStr(contact.LastName)}
+	event.fields["contact.nickname"] = []string{ptrToStr(contact.Nickname)}
+	event.fields["contact.job_position"] = []string{ptrToStr(contact.JobPosition)}
+
+	var labelIDs, groupIDs []uint
+	if err := s.db.Model(&models.ContactLabel{}).Where("contact_id = ?", contact.ID).Pluck("label_id", &labelIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.ContactGroup{}).Where("contact_id = ?", contact.ID).Pluck("group_id", &groupIDs).Error; err != nil {
+		return nil, err
+	}
+	event.fields["contact.label_ids"] = uintStrings(labelIDs)
+	event.fields["contact.group_ids"] = uintStrings(groupIDs)
+
+	if item.FeedableID == nil {
+		return event, nil
+	}
+	switch item.Action {
+	case ActionLabelAdded:
+		var pivot models.ContactLabel
+		if err := s.db.Where("id = ? AND contact_id = ?", *item.FeedableID, contact.ID).First(&pivot).Error; err != nil {
+			return nil, err
+		}
+		event.fields["label.id"] = uintStrings([]uint{pivot.LabelID})
+	case ActionImportantDateAdded:
+		var date models.ContactImportantDate
+		if err := s.db.Preload("ContactImportantDateType").Where("id = ? AND contact_id = ?", *item.FeedableID, contact.ID).First(&date).Error; err != nil {
+			return nil, err
+		}
+		event.date = &date
+		event.fields["date.label"] = []string{date.Label}
+		if date.ContactImportantDateType != nil {
+			event.fields["date.type"] = []string{ptrToStr(date.ContactImportantDateType.InternalType)}
+		}
+	case ActionCallLogged:
+		var call models.Call
+		if err := s.db.Preload("Emotion").Where("id = ? AND contact_id = ?", *item.FeedableID, contact.ID).First(&call).Error; err != nil {
+			return nil, err
+		}
+		event.fields["call.type"] = []string{call.Type}
+		event.fields["call.answered"] = []string{strconv.FormatBool(call.Answered)}
+		// An emotion matches by its name or by its type, such as negative.
+		if call.Emotion != nil {
+			event.fields["call.emotion"] = []string{ptrToStr(call.Emotion.Name), call.Emotion.Type}
+		}
+	}
+	return event, nil
+}
+
+func (e *automationEvent) matches(conditions []dto.AutomationCondition) bool {
+	for _, condition := range conditions {
+		var values []string
+		for _, value := range e.fields[condition.Field] {
+			if value != "" {
+				values = append(values, value)
+			}
+		}
+		var matched bool
+		switch condition.Operator {
+		case automationOperatorEquals, automationOperatorNotEquals, automationOperatorContains:
+			for _, value := range values {
+				if strings.EqualFold(value, condition.Value) ||
+					(condition.Operator == automationOperatorContains && strings.Contains(strings.ToLower(value), strings.ToLower(condition.Value))) {
+					matched = true
+					break
+				}
+			}
+			if condition.Operator == automationOperatorNotEquals {
+				matched = !matched
+			}
+		case automationOperatorIsEmpty:
+			matched = len(values) == 0
+		case automationOperatorIsNotEmpty:
+			matched = len(values) > 0
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// execute runs every action of the rule and records their outcome. The
+// services the actions write through record feed items with chain, so
+// rules triggered by them know their origin.
+func (s *AutomationService) execute(rule *models.AutomationRule, event *automationEvent, chain *automationChain, execution *models.AutomationExecution) {
+	var actions []dto.AutomationAction
+	if err := json.Unmarshal([]byte(rule.Actions), &actions); err != nil {
+		execution.Status = models.AutomationExecutionFailed
+		execution.Error = strPtrOrNil(err.Error())
+		return
+	}
+	recorder := &FeedRecorder{db: s.db, automation: s, chain: chain}
+	results := make([]dto.AutomationActionResult, len(actions))
+	execution.Status = models.AutomationExecutionSucceeded
+	for i, action := range actions {
+		results[i] = dto.AutomationActionResult{Type: action.Type, OK: true}
+		if err := s.runAction(rule, event, action, recorder); err != nil {
+			results[i].OK = false
+			results[i].Error = err.Error()
+			if execution.Status == models.AutomationExecutionSucceeded {
+				execution.Status = models.AutomationExecutionFailed
+				execution.Error = strPtrOrNil(fmt.Sprintf("%s: %v", action.Type, err))
+			}
+		}
+	}
+	if data, err := json.Marshal(results); err == nil {
+		execution.Results = string(data)
+	}
+}
+
+func (s *AutomationService) runAction(rule *models.AutomationRule, event *automationEvent, action dto.AutomationAction, recorder *FeedRecorder) error {
+	vaultID, contactID := event.item.VaultID, event.contact.ID
+	now := time.Now()
+	switch action.Type {
+	case automationActionCreateTask:
+		tasks := NewTaskService(s.db)
+		tasks.SetFeedRecorder(recorder)
+		req := dto.CreateTaskRequest{Label: event.expand(action.Label), Description: event.expand(action.Description)}
+		if action.DueInDays != nil {
+			dueAt := now.AddDate(0, 0, *action.DueInDays)
+			req.DueAt = &dueAt
+		}
+		_, err := tasks.Create(contactID, vaultID, rule.CreatedBy, req)
+		return err
+	case automationActionCreateReminder:
+		reminders := NewReminderService(s.db)
+		reminders.SetFeedRecorder(recorder)
+		req := dto.CreateReminderRequest{
+			Label:           event.expand(action.Label),
+			Type:            action.ReminderType,
+			FrequencyNumber: action.FrequencyNumber,
+			LeadTimeDays:    action.LeadTimeDays,
+		}
+		if action.DaysFromNow == nil && event.date != nil {
+			req.Day, req.Month, req.Year = event.date.Day, event.date.Month, event.date.Year
+		} else {
+			on := now
+			if action.DaysFromNow != nil {
+				on = now.AddDate(0, 0, *action.DaysFromNow)
+			}
+			day, month, year := on.Day(), int(on.Month()), on.Year()
+			req.Day, req.Month, req.Year = &day, &month, &year
+		}
+		_, err := reminders.Create(contactID, vaultID, req)
+		return err
+	case automationActionAddLabel:
+		var count int64
+		if err := s.db.Model(&models.ContactLabel{}).Where("contact_id = ? AND label_id = ?", contactID, *action.LabelID).Count(&count).Error; err != nil || count > 0 {
+			return err
+		}
+		labels := NewContactLabelService(s.db)
+		labels.SetFeedRecorder(recorder)
+		_, err := labels.Add(contactID, vaultID, dto.AddContactLabelRequest{LabelID: *action.LabelID})
+		return err
+	case automationActionAddToGroup:
+		return NewGroupService(s.db).AddContactToGroup(contactID, vaultID, dto.AddContactToGroupRequest{GroupID: *action.GroupID})
+	case automationActionSendNotification:
+		return s.notify(rule, event, action)
+	case automationActionCallWebhook:
+		return s.callWebhook(rule, event, action)
+	}
+	return ErrInvalidAutomationAction
+}
+
+// notify sends the message to the active notification channels of the
+// rule's owner, as long as they are still a member of the vault.
+func (s *AutomationService) notify(rule *models.AutomationRule, event *automationEvent, action dto.AutomationAction) error {
+	var channels []models.UserNotificationChannel
+	if err := s.db.Where("user_id = ? AND active = ?", rule.CreatedBy, true).
+		Where("EXISTS (SELECT 1 FROM user_vault WHERE user_vault.user_id = user_notification_channels.user_id AND user_vault.vault_id = ?)", event.item.VaultID).
+		Find(&channels).Error; err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		return errAutomationNoChannels
+	}
+	subject := event.expand(action.Subject)
+	if subject == "" {
+		subject = rule.Name
+	}
+	body := event.expand(action.Message)
+	var firstErr error
+	for i := range channels {
+		sendErr := sendToNotificationChannel(s.mailer, s.sender, s.webPush, &channels[i], subject, body)
+		sent := models.UserNotificationSent{UserNotificationChannelID: channels[i].ID, SentAt: time.Now(), SubjectLine: subject, Payload: &body}
+		if sendErr != nil {
+			sent.Error = strPtrOrNil(sendErr.Error())
+			if firstErr == nil {
+				firstErr = sendErr
+			}
+		}
+		if err := s.db.Create(&sent).Error; err != nil {
+			log.Printf("[automation] failed to log notification on channel %d: %v", channels[i].ID, err)
+		}
+	}
+	return firstErr
+}
+
+type automationWebhookPayload struct {
+	RuleID      uint      `json:"rule_id"`
+	RuleName    string    `json:"rule_name"`
+	VaultID     string    `json:"vault_id"`
+	ContactID   string    `json:"contact_id"`
+	ContactName string    `json:"contact_name"`
+	Trigger     string    `json:"trigger"`
+	FeedItemID  uint      `json:"feed_item_id"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+func (s *AutomationService) callWebhook(rule *models.AutomationRule, event *automationEvent, action dto.AutomationAction) error {
+	payload, err := json.Marshal(automationWebhookPayload{
+		RuleID:      rule.ID,
+		RuleName:    rule.Name,
+		VaultID:     event.item.VaultID,
+		ContactID:   event.contact.ID,
+		ContactName: event.name,
+		Trigger:     event.item.Action,
+		FeedItemID:  event.item.ID,
+		OccurredAt:  event.item.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Post(action.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// expand replaces {{contact}} with the name of the contact.
+func (e *automationEvent) expand(text string) string {
+	return strings.ReplaceAll(text, "{{contact}}", e.name)
+}
+
+func uintStrings(ids []uint) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return out
+}
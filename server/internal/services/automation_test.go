@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupAutomationTest(t *testing.T) (*AutomationService, *FeedRecorder, string, string, string) {
+	t.Helper()
+	noteSvc, contactID, vaultID, userID := setupNoteTest(t)
+	svc := NewAutomationService(noteSvc.db)
+	// The tests run the queue themselves and call webhooks on localhost.
+	svc.wake = func() {}
+	svc.allowWebhookAddr = func(net.IP) bool { return true }
+	recorder := NewFeedRecorder(noteSvc.db)
+	recorder.SetAutomation(svc)
+	return svc, recorder, contactID, vaultID, userID
+}
+
+func countAutomationExecutions(t *testing.T, db *gorm.DB, ruleID uint, status string) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Model(&models.AutomationExecution{}).Where("rule_id = ? AND status = ?", ruleID, status).Count(&count).Error; err != nil {
+		t.Fatalf("count executions: %v", err)
+	}
+	return count
+}
+
+func TestAutomationCallConditionCreatesTaskAndCallsWebhook(t *testing.T) {
+	svc, recorder, contactID, vaultID, userID := setupAutomationTest(t)
+	var emotion models.Emotion
+	if err := svc.db.Where("type = ?", "negative").First(&emotion).Error; err != nil {
+		t.Fatalf("load emotion: %v", err)
+	}
+	hooks := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hooks <- struct{}{}
+	}))
+	defer server.Close()
+
+	dueInDays := 2
+	rule, err := svc.Create(vaultID, userID, dto.CreateAutomationRuleRequest{
+		Name:       "Follow up sad calls",
+		Trigger:    ActionCallLogged,
+		Conditions: []dto.AutomationCondition{{Field: "call.emotion", Operator: "equals", Value: "negative"}},
+		Actions: []dto.AutomationAction{
+			{Type: "create_task", Label: "Check in with {{contact}}", DueInDays: &dueInDays},
+			{Type: "call_webhook", URL: server.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	calls := NewCallService(svc.db)
+	calls.SetFeedRecorder(recorder)
+	if _, err := calls.Create(contactID, vaultID, userID, dto.CreateCallRequest{CalledAt: time.Now(), Type: "phone", WhoInitiated: "me"}); err != nil {
+		t.Fatalf("Create call failed: %v", err)
+	}
+	svc.RunPending()
+	if n := countAutomationExecutions(t, svc.db, rule.ID, models.AutomationExecutionSucceeded); n != 0 {
+		t.Fatalf("expected a call without the emotion not to match, got %d executions", n)
+	}
+
+	if _, err := calls.Create(contactID, vaultID, userID, dto.CreateCallRequest{CalledAt: time.Now(), Type: "phone", WhoInitiated: "me", EmotionID: &emotion.ID}); err != nil {
+		t.Fatalf("Create call failed: %v", err)
+	}
+	svc.RunPending()
+	var task models.ContactTask
+	if err := svc.db.Where("vault_id = ?", vaultID).First(&task).Error; err != nil {
+		t.Fatalf("expected the rule to create a task: %v", err)
+	}
+	if task.Label != "Check in with John" || task.DueAt == nil {
+		t.Errorf("unexpected task %q due %v", task.Label, task.DueAt)
+	}
+	select {
+	case <-hooks:
+	default:
+		t.Error("expected the webhook to be called")
+	}
+
+	executions, _, err := svc.ListExecutions(vaultID, rule.ID, 1, 20)
+	if err != nil {
+		t.Fatalf("ListExecutions failed: %v", err)
+	}
+	if len(executions) != 1 || executions[0].Status != models.AutomationExecutionSucceeded || len(executions[0].Results) != 2 {
+		t.Fatalf("expected one successful execution with two results, got %+v", executions)
+	}
+}
+
+func TestAutomationLoopProtection(t *testing.T) {
+	svc, recorder, contactID, vaultID, userID := setupAutomationTest(t)
+	rule, err := svc.Create(vaultID, userID, dto.CreateAutomationRuleRequest{
+		Name:    "Tasks breed tasks",
+		Trigger: ActionTaskCreated,
+		Actions: []dto.AutomationAction{{Type: "create_task", Label: "Another task"}},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	tasks := NewTaskService(svc.db)
+	tasks.SetFeedRecorder(recorder)
+	if _, err := tasks.Create(contactID, vaultID, userID, dto.CreateTaskRequest{Label: "First"}); err != nil {
+		t.Fatalf("Create task failed: %v", err)
+	}
+	svc.RunPending()
+
+	var count int64
+	svc.db.Model(&models.ContactTask{}).Where("vault_id = ?", vaultID).Count(&count)
+	if count != 2 {
+		t.Errorf("expected the rule to run once, got %d tasks", count)
+	}
+	if n := countAutomationExecutions(t, svc.db, rule.ID, models.AutomationExecutionSucceeded); n != 1 {
+		t.Errorf("expected 1 successful execution, got %d", n)
+	}
+	if n := countAutomationExecutions(t, svc.db, rule.ID, models.AutomationExecutionSkipped); n != 1 {
+		t.Errorf("expected the repeated run to be logged as skipped, got %d", n)
+	}
+}
+
+func TestAutomationImportantDateReminder(t *testing.T) {
+	svc, recorder, contactID, vaultID, userID := setupAutomationTest(t)
+	var birthdate models.ContactImportantDateType
+	if err := svc.db.Where("vault_id = ? AND internal_type = ?", vaultID, "birthdate").First(&birthdate).Error; err != nil {
+		t.Fatalf("load birthdate type: %v", err)
+	}
+	if _, err := svc.Create(vaultID, userID, dto.CreateAutomationRuleRequest{
+		Name:       "Birthday reminders",
+		Trigger:    ActionImportantDateAdded,
+		Conditions: []dto.AutomationCondition{{Field: "date.type", Operator: "equals", Value: "birthdate"}},
+		Actions:    []dto.AutomationAction{{Type: "create_reminder", Label: "Birthday of {{contact}}", ReminderType: "recurring_year", LeadTimeDays: []int{14}}},
+	}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	dates := NewImportantDateService(svc.db)
+	dates.SetFeedRecorder(recorder)
+	day, month := 3, 9
+	if _, err := dates.Create(contactID, vaultID, dto.CreateImportantDateRequest{Label: "Birthday", Day: &day, Month: &month, ContactImportantDateTypeID: &birthdate.ID}); err != nil {
+		t.Fatalf("Create date failed: %v", err)
+	}
+	svc.RunPending()
+	var reminder models.ContactReminder
+	if err := svc.db.Where("contact_id = ? AND label = ?", contactID, "Birthday of John").First(&reminder).Error; err != nil {
+		t.Fatalf("expected the rule to create a reminder: %v", err)
+	}
+	if reminder.Day == nil || *reminder.Day != 3 || reminder.Month == nil || *reminder.Month != 9 || reminder.LeadTimeDays == nil || *reminder.LeadTimeDays != "14" {
+		t.Errorf("expected the reminder on the date with a 14-day lead, got %+v", reminder)
+	}
+}
+
+func TestAutomationRuleValidation(t *testing.T) {
+	svc, _, _, vaultID, userID := setupAutomationTest(t)
+	cases := []struct {
+		req  dto.CreateAutomationRuleRequest
+		want error
+	}{
+		{dto.CreateAutomationRuleRequest{Name: "x", Trigger: ActionContactDeleted, Actions: []dto.AutomationAction{{Type: "create_task", Label: "x"}}}, ErrInvalidAutomationTrigger},
+		{dto.CreateAutomationRuleRequest{Name: "x", Trigger: ActionContactCreated, Conditions: []dto.AutomationCondition{{Field: "call.type", Operator: "equals", Value: "phone"}}, Actions: []dto.AutomationAction{{Type: "create_task", Label: "x"}}}, ErrInvalidAutomationCondition},
+		{dto.CreateAutomationRuleRequest{Name: "x", Trigger: ActionContactCreated, Actions: []dto.AutomationAction{{Type: "call_webhook", URL: "ftp://example.com"}}}, ErrInvalidAutomationAction},
+		{dto.CreateAutomationRuleRequest{Name: "x", Trigger: ActionContactCreated, Actions: []dto.AutomationAction{{Type: "add_label", LabelID: new(uint)}}}, ErrLabelNotFound},
+	}
+	svc.allowWebhookAddr = isPublicAddr
+	for _, url := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest/meta-data", "https://[::1]/hook", "http://10.1.2.3/hook", "http://localhost/hook"} {
+		cases = append(cases, struct {
+			req  dto.CreateAutomationRuleRequest
+			want error
+		}{dto.CreateAutomationRuleRequest{Name: "x", Trigger: ActionContactCreated, Actions: []dto.AutomationAction{{Type: "call_webhook", URL: url}}}, ErrAutomationWebhookBlocked})
+	}
+	for _, tc := range cases {
+		if _, err := svc.Create(vaultID, userID, tc.req); !errors.Is(err, tc.want) {
+			t.Errorf("expected %v, got %v", tc.want, err)
+		}
+	}
+}
+
+func TestAutomationRulesWaitForTheQueue(t *testing.T) {
+	svc, recorder, contactID, vaultID, userID := setupAutomationTest(t)
+	rule, err := svc.Create(vaultID, userID, dto.CreateAutomationRuleRequest{
+		Name:    "Follow up notes",
+		Trigger: ActionNoteCreated,
+		Actions: []dto.AutomationAction{{Type: "create_task", Label: "Follow up"}},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	notes := NewNoteService(svc.db)
+	notes.SetFeedRecorder(recorder)
+	if _, err := notes.Create(contactID, vaultID, userID, dto.CreateNoteRequest{Body: "Lunch"}); err != nil {
+		t.Fatalf("Create note failed: %v", err)
+	}
+
+	var queued int64
+	svc.db.Model(&models.AutomationQueueItem{}).Count(&queued)
+	if queued != 1 || countAutomationExecutions(t, svc.db, rule.ID, models.AutomationExecutionSucceeded) != 0 {
+		t.Fatalf("expected the note to be queued without running the rule, got %d queued", queued)
+	}
+	svc.RunPending()
+	svc.db.Model(&models.AutomationQueueItem{}).Count(&queued)
+	if queued != 0 || countAutomationExecutions(t, svc.db, rule.ID, models.AutomationExecutionSucceeded) != 1 {
+		t.Errorf("expected the queue to run the rule once, got %d still queued", queued)
+	}
+}
+
+func TestAutomationWebhookRefusesPrivateAddresses(t *testing.T) {
+	svc, _, _, _, _ := setupAutomationTest(t)
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// A rule saved while the host was public must not reach it once the
+	// host resolves to the server's network.
+	svc.allowWebhookAddr = isPublicAddr
+	resp, err := svc.httpClient.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, ErrAutomationWebhookBlocked) || called {
+		t.Errorf("expected the loopback webhook to be refused, got %v", err)
+	}
+	for _, addr := range []string{"169.254.169.254", "100.64.0.1", "fe80::1", "::ffff:127.0.0.1"} {
+		if isPublicAddr(net.ParseIP(addr)) {
+			t.Errorf("expected %s not to be public", addr)
+		}
+	}
+	if !isPublicAddr(net.ParseIP("93.184.216.34")) {
+		t.Error("expected a public address to be allowed")
+	}
+}
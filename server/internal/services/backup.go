@@ -289,6 +289,13 @@ func (s *BackupService) Delete(filename string) error {
 
 // Restore restores from a backup zip file.
 func (s *BackupService) Restore(filename string) error {
+	return s.RestoreWithProgress(filename, nil)
+}
+
+// RestoreWithProgress is Restore reporting one item per extracted file and
+// one each for the database and the uploads. It can only be cancelled
+// while the backup is being extracted.
+func (s *BackupService) RestoreWithProgress(filename string, progress JobProgress) error {
 	fullPath, err := s.GetFilePath(filename)
 	if err != nil {
 		return err
@@ -309,7 +316,12 @@ func (s *BackupService) Restore(filename string) error {
 	defer os.RemoveAll(tmpDir)
 
 	// Extract all files
+	progressSetTotal(progress, len(r.File)+2)
 	for _, f := range r.File {
+		if progressCancelled(progress) {
+			return ErrJobCancelled
+		}
+		progressAdvance(progress, 1)
 		if err := s.extractZipFile(f, tmpDir); err != nil {
 			return fmt.Errorf("extract %s: %w", f.Name, err)
 		}
@@ -325,15 +337,34 @@ func (s *BackupService) Restore(filename string) error {
 			return err
 		}
 	}
+	progressAdvance(progress, 1)
 
 	// Restore uploads
 	if err := s.restoreUploads(tmpDir); err != nil {
 		return err
 	}
+	progressAdvance(progress, 1)
 
 	return nil
 }
 
+// BackupRestoreJobParams are the parameters of a backup_restore job.
+type BackupRestoreJobParams struct {
+	Filename string `json:"filename"`
+}
+
+// RunRestoreJob runs a backup_restore job.
+func (s *BackupService) RunRestoreJob(run *JobRun) (interface{}, []string, error) {
+	var params BackupRestoreJobParams
+	if err := run.Params(&params); err != nil {
+		return nil, nil, err
+	}
+	if err := s.RestoreWithProgress(params.Filename, run); err != nil {
+		return nil, nil, err
+	}
+	return map[string]string{"status": "restored"}, nil, nil
+}
+
 func (s *BackupService) extractZipFile(f *zip.File, destDir string) error {
 	// Prevent path traversal
 	name := filepath.Clean(f.Name)
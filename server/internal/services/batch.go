@@ -0,0 +1,178 @@
+package services
+
+import "gorm.io/gorm"
+
+// txEffects holds the side effects of writes made in a transaction that
+// cannot roll back with it, such as automation rules, the search index and
+// DAV pushes. They run in order once the transaction has committed.
+type txEffects struct {
+	fns []func()
+}
+
+// after defers fn until the commit, or runs it at once outside a batch,
+// where e is nil.
+func (e *txEffects) after(fn func()) {
+	if e == nil {
+		fn()
+		return
+	}
+	e.fns = append(e.fns, fn)
+}
+
+func (e *txEffects) run() {
+	for _, fn := range e.fns {
+		fn()
+	}
+}
+
+// BatchService runs the operations of a batch request in one database
+// transaction. Each operation calls a service bound to the transaction.
+type BatchService struct {
+	db                        *gorm.DB
+	contactService            *ContactService
+	contactInformationService *ContactInformationService
+	contactLabelService       *ContactLabelService
+	vaultLabelService         *VaultLabelService
+	noteService               *NoteService
+	reminderService           *ReminderService
+	importantDateService      *ImportantDateService
+	taskService               *TaskService
+}
+
+func NewBatchService(
+	db *gorm.DB,
+	contactService *ContactService,
+	contactInformationService *ContactInformationService,
+	contactLabelService *ContactLabelService,
+	vaultLabelService *VaultLabelService,
+	noteService *NoteService,
+	reminderService *ReminderService,
+	importantDateService *ImportantDateService,
+	taskService *TaskService,
+) *BatchService {
+	return &BatchService{
+		db:                        db,
+		contactService:            contactService,
+		contactInformationService: contactInformationService,
+		contactLabelService:       contactLabelService,
+		vaultLabelService:         vaultLabelService,
+		noteService:               noteService,
+		reminderService:           reminderService,
+		importantDateService:      importantDateService,
+		taskService:               taskService,
+	}
+}
+
+// BatchTx gives the operations of a batch the services bound to its
+// transaction.
+type BatchTx struct {
+	s       *BatchService
+	tx      *gorm.DB
+	effects *txEffects
+}
+
+// Transaction runs fn in a transaction that commits if fn returns nil and
+// rolls back otherwise. The side effects of the writes run after the
+// commit, and not at all after a rollback.
+func (s *BatchService) Transaction(fn func(b *BatchTx) error) error {
+	effects := &txEffects{}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&BatchTx{s: s, tx: tx, effects: effects})
+	}); err != nil {
+		return err
+	}
+	effects.run()
+	return nil
+}
+
+func (b *BatchTx) Vaults() *VaultService {
+	return NewVaultService(b.tx)
+}
+
+func (b *BatchTx) Contacts() *ContactService {
+	return b.s.contactService.withTx(b.tx, b.effects)
+}
+
+func (b *BatchTx) ContactInformation() *ContactInformationService {
+	return b.s.contactInformationService.withTx(b.tx)
+}
+
+func (b *BatchTx) ContactLabels() *ContactLabelService {
+	return b.s.contactLabelService.withTx(b.tx, b.effects)
+}
+
+func (b *BatchTx) Labels() *VaultLabelService {
+	return b.s.vaultLabelService.withTx(b.tx)
+}
+
+func (b *BatchTx) Notes() *NoteService {
+	return b.s.noteService.withTx(b.tx, b.effects)
+}
+
+func (b *BatchTx) Reminders() *ReminderService {
+	return b.s.reminderService.withTx(b.tx, b.effects)
+}
+
+func (b *BatchTx) ImportantDates() *ImportantDateService {
+	return b.s.importantDateService.withTx(b.tx, b.effects)
+}
+
+func (b *BatchTx) Tasks() *TaskService {
+	return b.s.taskService.withTx(b.tx, b.effects)
+}
+
+func (s *ContactService) withTx(tx *gorm.DB, effects *txEffects) *ContactService {
+	bound := *s
+	bound.db = tx
+	bound.feedRecorder = s.feedRecorder.withTx(tx, effects)
+	bound.effects = effects
+	return &bound
+}
+
+func (s *ContactInformationService) withTx(tx *gorm.DB) *ContactInformationService {
+	bound := *s
+	bound.db = tx
+	return &bound
+}
+
+func (s *ContactLabelService) withTx(tx *gorm.DB, effects *txEffects) *ContactLabelService {
+	bound := *s
+	bound.db = tx
+	bound.feedRecorder = s.feedRecorder.withTx(tx, effects)
+	return &bound
+}
+
+func (s *VaultLabelService) withTx(tx *gorm.DB) *VaultLabelService {
+	bound := *s
+	bound.db = tx
+	return &bound
+}
+
+func (s *NoteService) withTx(tx *gorm.DB, effects *txEffects) *NoteService {
+	bound := *s
+	bound.db = tx
+	bound.feedRecorder = s.feedRecorder.withTx(tx, effects)
+	bound.effects = effects
+	return &bound
+}
+
+func (s *ReminderService) withTx(tx *gorm.DB, effects *txEffects) *ReminderService {
+	bound := *s
+	bound.db = tx
+	bound.feedRecorder = s.feedRecorder.withTx(tx, effects)
+	return &bound
+}
+
+func (s *ImportantDateService) withTx(tx *gorm.DB, effects *txEffects) *ImportantDateService {
+	bound := *s
+	bound.db = tx
+	bound.feedRecorder = s.feedRecorder.withTx(tx, effects)
+	return &bound
+}
+
+func (s *TaskService) withTx(tx *gorm.DB, effects *txEffects) *TaskService {
+	bound := *s
+	bound.db = tx
+	bound.feedRecorder = s.feedRecorder.withTx(tx, effects)
+	return &bound
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+)
+
+func TestBatchTransactionRollsBackWritesAndEffects(t *testing.T) {
+	noteSvc, contactID, vaultID, userID := setupNoteTest(t)
+	noteSvc.SetFeedRecorder(NewFeedRecorder(noteSvc.db))
+	batch := NewBatchService(noteSvc.db, NewContactService(noteSvc.db), NewContactInformationService(noteSvc.db), NewContactLabelService(noteSvc.db), NewVaultLabelService(noteSvc.db), noteSvc, NewReminderService(noteSvc.db), NewImportantDateService(noteSvc.db), NewTaskService(noteSvc.db))
+	countNotes := func() (notes, feed int64) {
+		noteSvc.db.Model(&models.Note{}).Where("contact_id = ?", contactID).Count(&notes)
+		noteSvc.db.Model(&models.ContactFeedItem{}).Where("contact_id = ? AND action = ?", contactID, ActionNoteCreated).Count(&feed)
+		return notes, feed
+	}
+
+	ran := false
+	errAbort := errors.New("abort")
+	err := batch.Transaction(func(b *BatchTx) error {
+		if _, err := b.Notes().Create(contactID, vaultID, userID, dto.CreateNoteRequest{Body: "Rolled back"}); err != nil {
+			return err
+		}
+		b.effects.after(func() { ran = true })
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected the abort error, got %v", err)
+	}
+	if notes, feed := countNotes(); notes != 0 || feed != 0 || ran {
+		t.Fatalf("expected nothing to remain of the batch, got %d notes, %d feed items, effects run %v", notes, feed, ran)
+	}
+
+	err = batch.Transaction(func(b *BatchTx) error {
+		if _, err := b.Notes().Create(contactID, vaultID, userID, dto.CreateNoteRequest{Body: "Committed"}); err != nil {
+			return err
+		}
+		b.effects.after(func() { ran = true })
+		if ran {
+			t.Error("expected the effects to wait for the commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if notes, feed := countNotes(); notes != 1 || feed != 1 || !ran {
+		t.Errorf("expected the note, its feed item and the effects, got %d notes, %d feed items, effects run %v", notes, feed, ran)
+	}
+}
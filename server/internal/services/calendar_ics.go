@@ -231,6 +231,11 @@ func icsTaskToDo(t *models.ContactTask) *ical.Component {
 	if t.DueAt != nil {
 		todo.Props.SetDateTime(ical.PropDue, *t.DueAt)
 	}
+	if rule := TaskRecurrenceRuleForExport(t); rule != "" {
+		prop := ical.NewProp(ical.PropRecurrenceRule)
+		prop.Value = rule
+		todo.Props.Set(prop)
+	}
 
 	return todo
 }
@@ -85,7 +85,12 @@ func (s *CallService) Create(contactID, vaultID, authorID string, req dto.Create
 		CallReasonID: req.CallReasonID,
 		EmotionID:    req.EmotionID,
 	}
-	if err := s.db.Create(&call).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&call).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeCall, call.ID, models.VaultChangeCreated)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -119,7 +124,12 @@ func (s *CallService) Update(id uint, contactID, vaultID string, req dto.UpdateC
 	if req.Answered != nil {
 		call.Answered = *req.Answered
 	}
-	if err := s.db.Save(&call).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&call).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeCall, call.ID, models.VaultChangeUpdated)
+	}); err != nil {
 		return nil, err
 	}
 	resp := toCallResponse(&call)
@@ -130,14 +140,16 @@ func (s *CallService) Delete(id uint, contactID, vaultID string) error {
 	if err := validateContactBelongsToVault(s.db, contactID, vaultID); err != nil {
 		return err
 	}
-	result := s.db.Where("id = ? AND contact_id = ?", id, contactID).Delete(&models.Call{})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return ErrCallNotFound
-	}
-	return nil
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND contact_id = ?", id, contactID).Delete(&models.Call{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCallNotFound
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeCall, id, models.VaultChangeDeleted)
+	})
 }
 
 func toCallResponse(c *models.Call) dto.CallResponse {
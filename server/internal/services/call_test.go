@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
 	"github.com/naiba/bonds/internal/testutil"
 )
 
@@ -70,6 +71,19 @@ func TestCreateCall(t *testing.T) {
 	if call.ID == 0 {
 		t.Error("Expected call ID to be non-zero")
 	}
+
+	answered := false
+	missed, err := svc.Create(contactID, vaultID, userID, dto.CreateCallRequest{CalledAt: calledAt, Type: "phone", WhoInitiated: "contact", Answered: &answered})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	var stored models.Call
+	if err := svc.db.First(&stored, missed.ID).Error; err != nil {
+		t.Fatalf("load call: %v", err)
+	}
+	if stored.Answered {
+		t.Error("Expected an unanswered call to be stored as such")
+	}
 }
 
 func TestListCalls(t *testing.T) {
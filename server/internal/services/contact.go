@@ -26,6 +26,8 @@ type ContactService struct {
 	feedRecorder   *FeedRecorder
 	searchService  *SearchService
 	davPushService *DavPushService
+	// effects is set on the services of a batch; see withTx.
+	effects *txEffects
 }
 
 func NewContactService(db *gorm.DB) *ContactService {
@@ -185,7 +187,10 @@ func (s *ContactService) CreateContact(vaultID, userID string, req dto.CreateCon
 			UserID:    userID,
 			VaultID:   vaultID,
 		}
-		return tx.Create(&cvu).Error
+		if err := tx.Create(&cvu).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeCreated)
 	})
 	if err != nil {
 		return nil, err
@@ -209,13 +214,12 @@ func (s *ContactService) CreateContact(vaultID, userID string, req dto.CreateCon
 		desc := "Created contact " + req.FirstName
 		s.feedRecorder.Record(contact.ID, userID, ActionContactCreated, desc, nil, nil)
 	}
-
 	if s.searchService != nil {
-		s.searchService.IndexContact(&contact)
+		s.effects.after(func() { s.searchService.IndexContact(&contact) })
 	}
 
 	if s.davPushService != nil {
-		go s.davPushService.PushContactChange(contact.ID, vaultID)
+		s.effects.after(func() { go s.davPushService.PushContactChange(contact.ID, vaultID) })
 	}
 
 	resp, err := toContactResponse(&contact, false, formatter)
@@ -305,7 +309,12 @@ func (s *ContactService) UpdateContact(contactID, vaultID, userID string, req dt
 		contact.NeedsVerification = *req.NeedsVerification
 	}
 
-	if err := s.db.Save(&contact).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&contact).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated)
+	}); err != nil {
 		return nil, err
 	}
 	if err := s.db.Preload("FirstMetThrough", "vault_id = ?", vaultID).First(&contact, "id = ?", contact.ID).Error; err != nil {
@@ -322,11 +331,11 @@ func (s *ContactService) UpdateContact(contactID, vaultID, userID string, req dt
 	}
 
 	if s.searchService != nil {
-		s.searchService.IndexContact(&contact)
+		s.effects.after(func() { s.searchService.IndexContact(&contact) })
 	}
 
 	if s.davPushService != nil {
-		go s.davPushService.PushContactChange(contactID, vaultID)
+		s.effects.after(func() { go s.davPushService.PushContactChange(contactID, vaultID) })
 	}
 
 	resp, err := toContactResponse(&contact, false, formatter)
@@ -349,7 +358,12 @@ func (s *ContactService) ToggleArchive(contactID, vaultID, userID string) (*dto.
 	}
 
 	contact.Listed = !contact.Listed
-	if err := s.db.Save(&contact).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&contact).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated)
+	}); err != nil {
 		return nil, err
 	}
 	if err := reloadContactWithSameVaultFirstMetThrough(s.db, &contact, vaultID); err != nil {
@@ -377,24 +391,26 @@ func (s *ContactService) ToggleFavorite(contactID, userID, vaultID string) (*dto
 	}
 
 	var cvu models.ContactVaultUser
-	err := s.db.Where("contact_id = ? AND user_id = ?", contactID, userID).First(&cvu).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		cvu = models.ContactVaultUser{
-			ContactID:  contactID,
-			UserID:     userID,
-			VaultID:    vaultID,
-			IsFavorite: true,
-		}
-		if err := s.db.Create(&cvu).Error; err != nil {
-			return nil, err
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("contact_id = ? AND user_id = ?", contactID, userID).First(&cvu).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			cvu = models.ContactVaultUser{
+				ContactID:  contactID,
+				UserID:     userID,
+				VaultID:    vaultID,
+				IsFavorite: true,
+			}
+			err = tx.Create(&cvu).Error
+		} else if err == nil {
+			cvu.IsFavorite = !cvu.IsFavorite
+			err = tx.Save(&cvu).Error
 		}
-	} else if err != nil {
-		return nil, err
-	} else {
-		cvu.IsFavorite = !cvu.IsFavorite
-		if err := s.db.Save(&cvu).Error; err != nil {
-			return nil, err
+		if err != nil {
+			return err
 		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated)
+	}); err != nil {
+		return nil, err
 	}
 
 	if err := reloadContactWithSameVaultFirstMetThrough(s.db, &contact, vaultID); err != nil {
@@ -474,7 +490,12 @@ func (s *ContactService) MarkCaughtUp(contactID, vaultID, userID string) (*dto.C
 	contact.LastTalkedTo = &now
 	contact.StayInTouchTriggerDate = calculateStayInTouchTriggerDate(contact.LastTalkedTo, contact.StayInTouchFrequencyDays)
 	contact.LastUpdatedAt = &now
-	if err := s.db.Save(&contact).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&contact).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated)
+	}); err != nil {
 		return nil, err
 	}
 	if err := reloadContactWithSameVaultFirstMetThrough(s.db, &contact, vaultID); err != nil {
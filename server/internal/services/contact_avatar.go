@@ -26,7 +26,12 @@ func (s *ContactAvatarService) UpdateAvatar(contactID, vaultID, userID string, f
 	}
 
 	contact.FileID = &fileID
-	if err := s.db.Save(&contact).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&contact).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated)
+	}); err != nil {
 		return nil, err
 	}
 	if err := reloadContactWithSameVaultFirstMetThrough(s.db, &contact, vaultID); err != nil {
@@ -54,7 +59,12 @@ func (s *ContactAvatarService) DeleteAvatar(contactID, vaultID, userID string) (
 	}
 
 	contact.FileID = nil
-	if err := s.db.Save(&contact).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&contact).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated)
+	}); err != nil {
 		return nil, err
 	}
 	if err := reloadContactWithSameVaultFirstMetThrough(s.db, &contact, vaultID); err != nil {
@@ -153,5 +153,50 @@ func (s *ContactService) deleteContactInTransaction(tx *gorm.DB, contact *models
 			return work, err
 		}
 	}
+	if err := RecordContactDeletion(tx, contact); err != nil {
+		return work, err
+	}
 	return work, nil
 }
+
+// RecordContactDeletion writes tombstones for a deleted contact and for the
+// notes, reminders, important dates and calls that disappear along with it.
+func RecordContactDeletion(tx *gorm.DB, contact *models.Contact) error {
+	var noteIDs, reminderIDs, dateIDs, callIDs []uint
+	if err := tx.Model(&models.Note{}).Where("contact_id = ?", contact.ID).Pluck("id", &noteIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.ContactReminder{}).Where("contact_id = ?", contact.ID).Pluck("id", &reminderIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.ContactImportantDate{}).Where("contact_id = ?", contact.ID).Pluck("id", &dateIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Call{}).Where("contact_id = ?", contact.ID).Pluck("id", &callIDs).Error; err != nil {
+		return err
+	}
+	if err := recordVaultChange(tx, contact.VaultID, models.VaultChangeContact, contact.ID, models.VaultChangeDeleted); err != nil {
+		return err
+	}
+	for _, id := range noteIDs {
+		if err := recordVaultChange(tx, contact.VaultID, models.VaultChangeNote, id, models.VaultChangeDeleted); err != nil {
+			return err
+		}
+	}
+	for _, id := range reminderIDs {
+		if err := recordVaultChange(tx, contact.VaultID, models.VaultChangeReminder, id, models.VaultChangeDeleted); err != nil {
+			return err
+		}
+	}
+	for _, id := range dateIDs {
+		if err := recordVaultChange(tx, contact.VaultID, models.VaultChangeImportantDate, id, models.VaultChangeDeleted); err != nil {
+			return err
+		}
+	}
+	for _, id := range callIDs {
+		if err := recordVaultChange(tx, contact.VaultID, models.VaultChangeCall, id, models.VaultChangeDeleted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/naiba/bonds/internal/dto"
 	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/phone"
 	"gorm.io/gorm"
 )
 
@@ -33,7 +34,7 @@ func (s *ContactInformationService) List(contactID, vaultID string) ([]dto.Conta
 	return result, nil
 }
 
-func (s *ContactInformationService) Create(contactID, vaultID string, req dto.CreateContactInformationRequest) (*dto.ContactInformationResponse, error) {
+func (s *ContactInformationService) Create(contactID, vaultID, userID string, req dto.CreateContactInformationRequest) (*dto.ContactInformationResponse, error) {
 	if err := validateContactBelongsToVault(s.db, contactID, vaultID); err != nil {
 		return nil, err
 	}
@@ -48,6 +49,7 @@ func (s *ContactInformationService) Create(contactID, vaultID string, req dto.Cr
 		Kind:      strPtrOrNil(req.Kind),
 		Pref:      pref,
 	}
+	item.NormalizedData = normalizedContactData(s.db, req.TypeID, req.Data, phoneRegionForUser(s.db, userID))
 	if err := s.db.Create(&item).Error; err != nil {
 		return nil, err
 	}
@@ -55,7 +57,7 @@ func (s *ContactInformationService) Create(contactID, vaultID string, req dto.Cr
 	return &resp, nil
 }
 
-func (s *ContactInformationService) Update(id uint, contactID, vaultID string, req dto.UpdateContactInformationRequest) (*dto.ContactInformationResponse, error) {
+func (s *ContactInformationService) Update(id uint, contactID, vaultID, userID string, req dto.UpdateContactInformationRequest) (*dto.ContactInformationResponse, error) {
 	if err := validateContactBelongsToVault(s.db, contactID, vaultID); err != nil {
 		return nil, err
 	}
@@ -68,6 +70,7 @@ func (s *ContactInformationService) Update(id uint, contactID, vaultID string, r
 	}
 	item.TypeID = req.TypeID
 	item.Data = req.Data
+	item.NormalizedData = normalizedContactData(s.db, req.TypeID, req.Data, phoneRegionForUser(s.db, userID))
 	item.Kind = strPtrOrNil(req.Kind)
 	if req.Pref != nil {
 		item.Pref = *req.Pref
@@ -94,9 +97,11 @@ func (s *ContactInformationService) Delete(id uint, contactID, vaultID string) e
 }
 
 // FindByIdentity locates contact_information rows in a vault whose data
-// matches a given identity value (case-insensitive). When typeID > 0 the
-// search is further constrained to that ContactInformationType.
-func (s *ContactInformationService) FindByIdentity(vaultID, data string, typeID uint) ([]dto.ContactInformationByIdentityMatch, error) {
+// matches a given identity value (case-insensitive). A value that parses as
+// a phone number in the user's region also matches phone numbers with the
+// same E.164 form, however they were written. When typeID > 0 the search is
+// further constrained to that ContactInformationType.
+func (s *ContactInformationService) FindByIdentity(vaultID, userID, data string, typeID uint) ([]dto.ContactInformationByIdentityMatch, error) {
 	if vaultID == "" || data == "" {
 		return []dto.ContactInformationByIdentityMatch{}, nil
 	}
@@ -111,8 +116,12 @@ func (s *ContactInformationService) FindByIdentity(vaultID, data string, typeID
 		Table("contact_information AS ci").
 		Select("ci.*, c.first_name AS first_name, c.last_name AS last_name").
 		Joins("JOIN contacts c ON c.id = ci.contact_id").
-		Where("c.vault_id = ?", vaultID).
-		Where("LOWER(ci.data) = LOWER(?)", data)
+		Where("c.vault_id = ?", vaultID)
+	if normalized := phone.Normalize(data, phoneRegionForUser(s.db, userID)); normalized != "" {
+		q = q.Where("LOWER(ci.data) = LOWER(?) OR ci.normalized_data = ?", data, normalized)
+	} else {
+		q = q.Where("LOWER(ci.data) = LOWER(?)", data)
+	}
 	if typeID > 0 {
 		q = q.Where("ci.type_id = ?", typeID)
 	}
@@ -137,13 +146,76 @@ func (s *ContactInformationService) FindByIdentity(vaultID, data string, typeID
 
 func toContactInformationResponse(ci *models.ContactInformation) dto.ContactInformationResponse {
 	return dto.ContactInformationResponse{
-		ID:        ci.ID,
-		ContactID: ci.ContactID,
-		TypeID:    ci.TypeID,
-		Data:      ci.Data,
-		Kind:      ptrToStr(ci.Kind),
-		Pref:      ci.Pref,
-		CreatedAt: ci.CreatedAt,
-		UpdatedAt: ci.UpdatedAt,
+		ID:             ci.ID,
+		ContactID:      ci.ContactID,
+		TypeID:         ci.TypeID,
+		Data:           ci.Data,
+		NormalizedData: ptrToStr(ci.NormalizedData),
+		Kind:           ptrToStr(ci.Kind),
+		Pref:           ci.Pref,
+		CreatedAt:      ci.CreatedAt,
+		UpdatedAt:      ci.UpdatedAt,
+	}
+}
+
+// phoneRegionForUser is the region the user's phone numbers without a
+// country code are read in, or "" when it is unknown.
+func phoneRegionForUser(db *gorm.DB, userID string) string {
+	if userID == "" {
+		return ""
+	}
+	var user models.User
+	if err := db.Select("phone_region", "locale").First(&user, "id = ?", userID).Error; err != nil {
+		return ""
+	}
+	return user.EffectivePhoneRegion()
+}
+
+// renormalizePhoneNumbers reads the phone numbers of the contacts in the
+// user's vaults again in region, after the user's region changed. Numbers
+// with a country code keep their normalized form.
+func renormalizePhoneNumbers(tx *gorm.DB, userID, region string) error {
+	var rows []struct {
+		ID             uint
+		Data           string
+		NormalizedData *string
+	}
+	if err := tx.Table("contact_information AS ci").
+		Select("ci.id, ci.data, ci.normalized_data").
+		Joins("JOIN contact_information_types t ON t.id = ci.type_id").
+		Joins("JOIN contacts c ON c.id = ci.contact_id").
+		Where("t.type = ? AND c.vault_id IN (?)", "phone",
+			tx.Model(&models.UserVault{}).Select("vault_id").Where("user_id = ?", userID)).
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		normalized := models.NormalizedPhone(row.Data, region)
+		if ptrToStr(normalized) == ptrToStr(row.NormalizedData) {
+			continue
+		}
+		if err := tx.Model(&models.ContactInformation{}).Where("id = ?", row.ID).UpdateColumn("normalized_data", normalized).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PhoneRegionForUser is phoneRegionForUser for the DAV backends, which write
+// rows directly instead of going through the services.
+func PhoneRegionForUser(db *gorm.DB, userID string) string {
+	return phoneRegionForUser(db, userID)
+}
+
+// normalizedContactData returns the E.164 form of data when typeID is a
+// phone type, and nil otherwise.
+func normalizedContactData(db *gorm.DB, typeID uint, data, region string) *string {
+	var infoType models.ContactInformationType
+	if err := db.Select("type").First(&infoType, typeID).Error; err != nil {
+		return nil
+	}
+	if infoType.Type == nil || *infoType.Type != "phone" {
+		return nil
 	}
+	return models.NormalizedPhone(data, region)
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
 	"github.com/naiba/bonds/internal/testutil"
 )
 
@@ -41,7 +42,7 @@ func setupContactInformationTest(t *testing.T) (*ContactInformationService, stri
 func TestCreateContactInformation(t *testing.T) {
 	svc, contactID, vaultID := setupContactInformationTest(t)
 
-	info, err := svc.Create(contactID, vaultID, dto.CreateContactInformationRequest{
+	info, err := svc.Create(contactID, vaultID, "", dto.CreateContactInformationRequest{
 		TypeID: 1,
 		Data:   "john@example.com",
 		Kind:   "personal",
@@ -72,11 +73,11 @@ func TestCreateContactInformation(t *testing.T) {
 func TestListContactInformation(t *testing.T) {
 	svc, contactID, vaultID := setupContactInformationTest(t)
 
-	_, err := svc.Create(contactID, vaultID, dto.CreateContactInformationRequest{TypeID: 1, Data: "email@test.com"})
+	_, err := svc.Create(contactID, vaultID, "", dto.CreateContactInformationRequest{TypeID: 1, Data: "email@test.com"})
 	if err != nil {
 		t.Fatalf("Create failed: %v", err)
 	}
-	_, err = svc.Create(contactID, vaultID, dto.CreateContactInformationRequest{TypeID: 2, Data: "+1234567890"})
+	_, err = svc.Create(contactID, vaultID, "", dto.CreateContactInformationRequest{TypeID: 2, Data: "+1234567890"})
 	if err != nil {
 		t.Fatalf("Create failed: %v", err)
 	}
@@ -93,7 +94,7 @@ func TestListContactInformation(t *testing.T) {
 func TestUpdateContactInformation(t *testing.T) {
 	svc, contactID, vaultID := setupContactInformationTest(t)
 
-	created, err := svc.Create(contactID, vaultID, dto.CreateContactInformationRequest{
+	created, err := svc.Create(contactID, vaultID, "", dto.CreateContactInformationRequest{
 		TypeID: 1,
 		Data:   "old@example.com",
 		Kind:   "personal",
@@ -103,7 +104,7 @@ func TestUpdateContactInformation(t *testing.T) {
 	}
 
 	pref := false
-	updated, err := svc.Update(created.ID, contactID, vaultID, dto.UpdateContactInformationRequest{
+	updated, err := svc.Update(created.ID, contactID, vaultID, "", dto.UpdateContactInformationRequest{
 		TypeID: 2,
 		Data:   "new@example.com",
 		Kind:   "work",
@@ -129,7 +130,7 @@ func TestUpdateContactInformation(t *testing.T) {
 func TestDeleteContactInformation(t *testing.T) {
 	svc, contactID, vaultID := setupContactInformationTest(t)
 
-	created, err := svc.Create(contactID, vaultID, dto.CreateContactInformationRequest{
+	created, err := svc.Create(contactID, vaultID, "", dto.CreateContactInformationRequest{
 		TypeID: 1,
 		Data:   "to-delete@example.com",
 	})
@@ -162,13 +163,13 @@ func TestDeleteContactInformationNotFound(t *testing.T) {
 func TestFindByIdentityExactMatch(t *testing.T) {
 	svc, contactID, vaultID := setupContactInformationTest(t)
 
-	if _, err := svc.Create(contactID, vaultID, dto.CreateContactInformationRequest{
+	if _, err := svc.Create(contactID, vaultID, "", dto.CreateContactInformationRequest{
 		TypeID: 1, Data: "alice@example.com",
 	}); err != nil {
 		t.Fatalf("Create failed: %v", err)
 	}
 
-	matches, err := svc.FindByIdentity(vaultID, "alice@example.com", 0)
+	matches, err := svc.FindByIdentity(vaultID, "", "alice@example.com", 0)
 	if err != nil {
 		t.Fatalf("FindByIdentity: %v", err)
 	}
@@ -189,14 +190,14 @@ func TestFindByIdentityExactMatch(t *testing.T) {
 func TestFindByIdentityCaseInsensitive(t *testing.T) {
 	svc, contactID, vaultID := setupContactInformationTest(t)
 
-	_, err := svc.Create(contactID, vaultID, dto.CreateContactInformationRequest{
+	_, err := svc.Create(contactID, vaultID, "", dto.CreateContactInformationRequest{
 		TypeID: 1, Data: "Alice@Example.COM",
 	})
 	if err != nil {
 		t.Fatalf("Create: %v", err)
 	}
 
-	matches, err := svc.FindByIdentity(vaultID, "alice@example.com", 0)
+	matches, err := svc.FindByIdentity(vaultID, "", "alice@example.com", 0)
 	if err != nil {
 		t.Fatalf("FindByIdentity: %v", err)
 	}
@@ -208,7 +209,7 @@ func TestFindByIdentityCaseInsensitive(t *testing.T) {
 func TestFindByIdentityNoMatch(t *testing.T) {
 	svc, _, vaultID := setupContactInformationTest(t)
 
-	matches, err := svc.FindByIdentity(vaultID, "nobody@example.com", 0)
+	matches, err := svc.FindByIdentity(vaultID, "", "nobody@example.com", 0)
 	if err != nil {
 		t.Fatalf("FindByIdentity: %v", err)
 	}
@@ -220,10 +221,10 @@ func TestFindByIdentityNoMatch(t *testing.T) {
 func TestFindByIdentityFiltersByType(t *testing.T) {
 	svc, contactID, vaultID := setupContactInformationTest(t)
 
-	_, _ = svc.Create(contactID, vaultID, dto.CreateContactInformationRequest{TypeID: 1, Data: "shared"})
-	_, _ = svc.Create(contactID, vaultID, dto.CreateContactInformationRequest{TypeID: 2, Data: "shared"})
+	_, _ = svc.Create(contactID, vaultID, "", dto.CreateContactInformationRequest{TypeID: 1, Data: "shared"})
+	_, _ = svc.Create(contactID, vaultID, "", dto.CreateContactInformationRequest{TypeID: 2, Data: "shared"})
 
-	all, err := svc.FindByIdentity(vaultID, "shared", 0)
+	all, err := svc.FindByIdentity(vaultID, "", "shared", 0)
 	if err != nil {
 		t.Fatalf("FindByIdentity: %v", err)
 	}
@@ -231,7 +232,7 @@ func TestFindByIdentityFiltersByType(t *testing.T) {
 		t.Errorf("without type filter expected 2, got %d", len(all))
 	}
 
-	onlyOne, err := svc.FindByIdentity(vaultID, "shared", 1)
+	onlyOne, err := svc.FindByIdentity(vaultID, "", "shared", 1)
 	if err != nil {
 		t.Fatalf("FindByIdentity typeID=1: %v", err)
 	}
@@ -245,9 +246,9 @@ func TestFindByIdentityFiltersByType(t *testing.T) {
 
 func TestFindByIdentityScopedToVault(t *testing.T) {
 	svc, contactID, vaultID := setupContactInformationTest(t)
-	_, _ = svc.Create(contactID, vaultID, dto.CreateContactInformationRequest{TypeID: 1, Data: "vault-1@example.com"})
+	_, _ = svc.Create(contactID, vaultID, "", dto.CreateContactInformationRequest{TypeID: 1, Data: "vault-1@example.com"})
 
-	matches, err := svc.FindByIdentity("non-existent-vault", "vault-1@example.com", 0)
+	matches, err := svc.FindByIdentity("non-existent-vault", "", "vault-1@example.com", 0)
 	if err != nil {
 		t.Fatalf("FindByIdentity: %v", err)
 	}
@@ -259,10 +260,10 @@ func TestFindByIdentityScopedToVault(t *testing.T) {
 func TestFindByIdentityEmptyParams(t *testing.T) {
 	svc, _, vaultID := setupContactInformationTest(t)
 
-	if got, _ := svc.FindByIdentity("", "x", 0); len(got) != 0 {
+	if got, _ := svc.FindByIdentity("", "", "x", 0); len(got) != 0 {
 		t.Error("empty vault must yield no matches")
 	}
-	if got, _ := svc.FindByIdentity(vaultID, "", 0); len(got) != 0 {
+	if got, _ := svc.FindByIdentity(vaultID, "", "", 0); len(got) != 0 {
 		t.Error("empty data must yield no matches")
 	}
 }
@@ -283,10 +284,10 @@ func TestFindByIdentityMultipleContacts(t *testing.T) {
 	c2, _ := contactSvc.CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: "Bob"})
 
 	svc := NewContactInformationService(db)
-	_, _ = svc.Create(c1.ID, vault.ID, dto.CreateContactInformationRequest{TypeID: 1, Data: "shared@example.com"})
-	_, _ = svc.Create(c2.ID, vault.ID, dto.CreateContactInformationRequest{TypeID: 1, Data: "shared@example.com"})
+	_, _ = svc.Create(c1.ID, vault.ID, "", dto.CreateContactInformationRequest{TypeID: 1, Data: "shared@example.com"})
+	_, _ = svc.Create(c2.ID, vault.ID, "", dto.CreateContactInformationRequest{TypeID: 1, Data: "shared@example.com"})
 
-	matches, err := svc.FindByIdentity(vault.ID, "shared@example.com", 0)
+	matches, err := svc.FindByIdentity(vault.ID, "", "shared@example.com", 0)
 	if err != nil {
 		t.Fatalf("FindByIdentity: %v", err)
 	}
@@ -294,3 +295,127 @@ func TestFindByIdentityMultipleContacts(t *testing.T) {
 		t.Fatalf("expected matches across both contacts, got %d", len(matches))
 	}
 }
+
+func TestFindByIdentityMatchesPhoneNumberFormats(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	authSvc := NewAuthService(db, testutil.TestJWTConfig())
+	resp, err := authSvc.Register(dto.RegisterRequest{
+		FirstName: "Test", LastName: "User",
+		Email:    "find-by-phone@example.com",
+		Password: "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if _, err := NewPreferenceService(db).UpdateAll(resp.User.ID, dto.UpdatePreferencesRequest{PhoneRegion: "US"}); err != nil {
+		t.Fatalf("UpdateAll failed: %v", err)
+	}
+	vault, _ := NewVaultService(db).CreateVault(resp.User.AccountID, resp.User.ID, dto.CreateVaultRequest{Name: "v"}, "en")
+	contact, _ := NewContactService(db).CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: "Alice"})
+	var phoneType models.ContactInformationType
+	db.Where("account_id = ? AND type = ?", resp.User.AccountID, "phone").First(&phoneType)
+
+	svc := NewContactInformationService(db)
+	created, err := svc.Create(contact.ID, vault.ID, resp.User.ID, dto.CreateContactInformationRequest{TypeID: phoneType.ID, Data: "(555) 010-2000"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.Data != "(555) 010-2000" || created.NormalizedData != "+15550102000" {
+		t.Errorf("expected the original and the E.164 form to be kept, got %+v", created)
+	}
+
+	for _, query := range []string{"+1 555 010 2000", "555.010.2000", "011 1 555 010 2000"} {
+		matches, err := svc.FindByIdentity(vault.ID, resp.User.ID, query, 0)
+		if err != nil {
+			t.Fatalf("FindByIdentity: %v", err)
+		}
+		if len(matches) != 1 || matches[0].ContactID != contact.ID {
+			t.Errorf("expected %q to match the stored number, got %d matches", query, len(matches))
+		}
+	}
+
+	email, err := svc.Create(contact.ID, vault.ID, resp.User.ID, dto.CreateContactInformationRequest{TypeID: 1, Data: "5550102000"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if email.NormalizedData != "" {
+		t.Errorf("expected only phone types to be normalized, got %q", email.NormalizedData)
+	}
+}
+
+func TestFindByIdentityMatchesNationalNumbersOfDefaultLocale(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	resp, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Test", LastName: "User",
+		Email:    "default-region@example.com",
+		Password: "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	vault, _ := NewVaultService(db).CreateVault(resp.User.AccountID, resp.User.ID, dto.CreateVaultRequest{Name: "v"}, "en")
+	contact, _ := NewContactService(db).CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: "Alice"})
+	var phoneType models.ContactInformationType
+	db.Where("account_id = ? AND type = ?", resp.User.AccountID, "phone").First(&phoneType)
+
+	svc := NewContactInformationService(db)
+	if _, err := svc.Create(contact.ID, vault.ID, resp.User.ID, dto.CreateContactInformationRequest{TypeID: phoneType.ID, Data: "+1 (555) 010-2000"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	matches, err := svc.FindByIdentity(vault.ID, resp.User.ID, "5550102000", 0)
+	if err != nil {
+		t.Fatalf("FindByIdentity: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Errorf("expected a national number to match for an English user without phone region, got %d matches", len(matches))
+	}
+}
+
+func TestChangingPhoneRegionRenormalizesNationalNumbers(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	resp, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Test", LastName: "User",
+		Email:    "renormalize@example.com",
+		Password: "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	prefs := NewPreferenceService(db)
+	if _, err := prefs.UpdateAll(resp.User.ID, dto.UpdatePreferencesRequest{PhoneRegion: "US"}); err != nil {
+		t.Fatalf("UpdateAll failed: %v", err)
+	}
+	vault, _ := NewVaultService(db).CreateVault(resp.User.AccountID, resp.User.ID, dto.CreateVaultRequest{Name: "v"}, "en")
+	contact, _ := NewContactService(db).CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: "Alice"})
+	var phoneType models.ContactInformationType
+	db.Where("account_id = ? AND type = ?", resp.User.AccountID, "phone").First(&phoneType)
+
+	svc := NewContactInformationService(db)
+	national, err := svc.Create(contact.ID, vault.ID, resp.User.ID, dto.CreateContactInformationRequest{TypeID: phoneType.ID, Data: "020 7946 0958"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	international, err := svc.Create(contact.ID, vault.ID, resp.User.ID, dto.CreateContactInformationRequest{TypeID: phoneType.ID, Data: "+1 555 010 2000"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if national.NormalizedData != "" {
+		t.Fatalf("expected a British number not to parse in the US, got %q", national.NormalizedData)
+	}
+
+	if _, err := prefs.UpdateAll(resp.User.ID, dto.UpdatePreferencesRequest{PhoneRegion: "GB"}); err != nil {
+		t.Fatalf("UpdateAll failed: %v", err)
+	}
+
+	want := map[uint]string{national.ID: "+442079460958", international.ID: "+15550102000"}
+	for id, normalized := range want {
+		var info models.ContactInformation
+		if err := db.First(&info, id).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got := ptrToStr(info.NormalizedData); got != normalized {
+			t.Errorf("row %d normalized to %q, want %q", id, got, normalized)
+		}
+	}
+}
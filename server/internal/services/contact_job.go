@@ -202,29 +202,34 @@ func (s *ContactJobService) LegacyUpdate(contactID, vaultID, userID string, req
 		}
 	}
 
-	if req.CompanyID != nil {
-		if found {
-			existingJob.CompanyID = *req.CompanyID
-			existingJob.JobPosition = strPtrOrNil(req.JobPosition)
-			if err := s.db.Save(&existingJob).Error; err != nil {
-				return nil, err
-			}
-		} else {
-			newJob := models.ContactCompany{
-				ContactID:   contactID,
-				CompanyID:   *req.CompanyID,
-				JobPosition: strPtrOrNil(req.JobPosition),
-			}
-			if err := s.db.Create(&newJob).Error; err != nil {
-				return nil, err
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if req.CompanyID != nil {
+			if found {
+				existingJob.CompanyID = *req.CompanyID
+				existingJob.JobPosition = strPtrOrNil(req.JobPosition)
+				if err := tx.Save(&existingJob).Error; err != nil {
+					return err
+				}
+			} else {
+				newJob := models.ContactCompany{
+					ContactID:   contactID,
+					CompanyID:   *req.CompanyID,
+					JobPosition: strPtrOrNil(req.JobPosition),
+				}
+				if err := tx.Create(&newJob).Error; err != nil {
+					return err
+				}
 			}
 		}
-	}
 
-	// Also update legacy fields on Contact for backward compatibility
-	contact.CompanyID = req.CompanyID
-	contact.JobPosition = strPtrOrNil(req.JobPosition)
-	if err := s.db.Save(&contact).Error; err != nil {
+		// Also update legacy fields on Contact for backward compatibility
+		contact.CompanyID = req.CompanyID
+		contact.JobPosition = strPtrOrNil(req.JobPosition)
+		if err := tx.Save(&contact).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated)
+	}); err != nil {
 		return nil, err
 	}
 	if err := reloadContactWithSameVaultFirstMetThrough(s.db, &contact, vaultID); err != nil {
@@ -252,15 +257,20 @@ func (s *ContactJobService) LegacyDelete(contactID, vaultID, userID string) (*dt
 		return nil, err
 	}
 
-	// Delete all jobs from the join table
-	if err := s.db.Where("contact_id = ?", contactID).Delete(&models.ContactCompany{}).Error; err != nil {
-		return nil, err
-	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Delete all jobs from the join table
+		if err := tx.Where("contact_id = ?", contactID).Delete(&models.ContactCompany{}).Error; err != nil {
+			return err
+		}
 
-	// Also clear legacy fields on Contact
-	contact.CompanyID = nil
-	contact.JobPosition = nil
-	if err := s.db.Save(&contact).Error; err != nil {
+		// Also clear legacy fields on Contact
+		contact.CompanyID = nil
+		contact.JobPosition = nil
+		if err := tx.Save(&contact).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated)
+	}); err != nil {
 		return nil, err
 	}
 	if err := reloadContactWithSameVaultFirstMetThrough(s.db, &contact, vaultID); err != nil {
@@ -11,13 +11,18 @@ import (
 var ErrContactLabelNotFound = errors.New("contact label not found")
 
 type ContactLabelService struct {
-	db *gorm.DB
+	db           *gorm.DB
+	feedRecorder *FeedRecorder
 }
 
 func NewContactLabelService(db *gorm.DB) *ContactLabelService {
 	return &ContactLabelService{db: db}
 }
 
+func (s *ContactLabelService) SetFeedRecorder(fr *FeedRecorder) {
+	s.feedRecorder = fr
+}
+
 func (s *ContactLabelService) List(contactID, vaultID string) ([]dto.ContactLabelResponse, error) {
 	if err := validateContactBelongsToVault(s.db, contactID, vaultID); err != nil {
 		return nil, err
@@ -77,6 +82,10 @@ func (s *ContactLabelService) Add(contactID, vaultID string, req dto.AddContactL
 	if err := s.db.Create(&pivot).Error; err != nil {
 		return nil, err
 	}
+	if s.feedRecorder != nil {
+		entityType := "ContactLabel"
+		s.feedRecorder.Record(contactID, "", ActionLabelAdded, "Added label: "+label.Name, &pivot.ID, &entityType)
+	}
 
 	resp := dto.ContactLabelResponse{
 		ID:        pivot.ID,
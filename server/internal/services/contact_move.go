@@ -110,6 +110,9 @@ func (s *ContactMoveService) MoveMany(contactIDs []string, currentVaultID, targe
 			if err := cleanMovedContactsFromActivities(tx, uniqueContactIDs, currentVaultID); err != nil {
 				return err
 			}
+			if err := recordMovedContactChanges(tx, uniqueContactIDs, currentVaultID, targetVaultID); err != nil {
+				return err
+			}
 		}
 		movedContacts = contacts
 
@@ -510,6 +513,48 @@ func cleanMovedContactDavState(tx *gorm.DB, contactIDs []string, currentVaultID
 	return nil
 }
 
+// recordMovedContactChanges reports the moved contacts and everything that
+// moved with them as deleted from the source vault and created in the target.
+func recordMovedContactChanges(tx *gorm.DB, contactIDs []string, currentVaultID, targetVaultID string) error {
+	children := []struct {
+		entityType string
+		query      *gorm.DB
+	}{
+		{models.VaultChangeNote, tx.Model(&models.Note{}).Where("contact_id IN ? AND vault_id = ?", contactIDs, targetVaultID)},
+		{models.VaultChangeReminder, tx.Model(&models.ContactReminder{}).Where("contact_id IN ?", contactIDs)},
+		{models.VaultChangeImportantDate, tx.Model(&models.ContactImportantDate{}).Where("contact_id IN ?", contactIDs)},
+		{models.VaultChangeCall, tx.Model(&models.Call{}).Where("contact_id IN ?", contactIDs)},
+		{models.VaultChangeFile, tx.Model(&models.File{}).Where("ufileable_id IN ? AND vault_id = ?", contactIDs, targetVaultID)},
+		{models.VaultChangeTask, tx.Model(&models.ContactTask{}).Where("vault_id = ? AND id IN (?)", targetVaultID,
+			tx.Model(&models.TaskContact{}).Select("contact_task_id").Where("contact_id IN ?", contactIDs))},
+	}
+	moved := make([][]uint, len(children))
+	for i, child := range children {
+		if err := child.query.Pluck("id", &moved[i]).Error; err != nil {
+			return err
+		}
+	}
+	for _, contactID := range contactIDs {
+		if err := recordVaultChange(tx, currentVaultID, models.VaultChangeContact, contactID, models.VaultChangeDeleted); err != nil {
+			return err
+		}
+		if err := recordVaultChange(tx, targetVaultID, models.VaultChangeContact, contactID, models.VaultChangeCreated); err != nil {
+			return err
+		}
+	}
+	for i, child := range children {
+		for _, id := range moved[i] {
+			if err := recordVaultChange(tx, currentVaultID, child.entityType, id, models.VaultChangeDeleted); err != nil {
+				return err
+			}
+			if err := recordVaultChange(tx, targetVaultID, child.entityType, id, models.VaultChangeCreated); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func cloneStringPtr(value *string) *string {
 	if value == nil {
 		return nil
@@ -68,21 +68,34 @@ func (s *VaultFileService) DeleteContactPhoto(fileID uint, contactID, vaultID st
 		return err
 	}
 
-	// If this file is the contact's avatar, unset the reference first.
-	if file.Type == "avatar" {
-		if err := s.db.Model(&models.Contact{}).Where("id = ? AND file_id = ?", contactID, file.ID).
-			Update("file_id", nil).Error; err != nil {
+	if err := s.ensureFileNotUsedByQuickFact(file.ID); err != nil {
+		return err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		// If this file is the contact's avatar, unset the reference first.
+		if file.Type == "avatar" {
+			result := tx.Model(&models.Contact{}).Where("id = ? AND file_id = ?", contactID, file.ID).Update("file_id", nil)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				if err := recordVaultChange(tx, vaultID, models.VaultChangeContact, contactID, models.VaultChangeUpdated); err != nil {
+					return err
+				}
+			}
+		}
+		if err := tx.Delete(&file).Error; err != nil {
 			return err
 		}
-	}
-	if err := s.ensureFileNotUsedByQuickFact(file.ID); err != nil {
+		return recordVaultChange(tx, vaultID, models.VaultChangeFile, file.ID, models.VaultChangeDeleted)
+	}); err != nil {
 		return err
 	}
 
 	destPath := s.localPath(&file)
 	os.Remove(destPath)
-
-	return s.db.Delete(&file).Error
+	return nil
 }
 
 func (s *VaultFileService) ListContactDocuments(contactID, vaultID string, page, perPage int) ([]dto.VaultFileResponse, response.Meta, error) {
@@ -132,8 +145,16 @@ func (s *VaultFileService) DeleteContactDocument(fileID uint, contactID, vaultID
 		return err
 	}
 
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&file).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeFile, file.ID, models.VaultChangeDeleted)
+	}); err != nil {
+		return err
+	}
+
 	destPath := s.localPath(&file)
 	os.Remove(destPath)
-
-	return s.db.Delete(&file).Error
+	return nil
 }
@@ -26,7 +26,12 @@ func (s *ContactReligionService) Update(contactID, vaultID, userID string, req d
 	}
 
 	contact.ReligionID = req.ReligionID
-	if err := s.db.Save(&contact).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&contact).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated)
+	}); err != nil {
 		return nil, err
 	}
 	if err := reloadContactWithSameVaultFirstMetThrough(s.db, &contact, vaultID); err != nil {
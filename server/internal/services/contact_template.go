@@ -29,7 +29,12 @@ func (s *ContactTemplateService) UpdateTemplate(contactID, vaultID, userID strin
 	}
 
 	contact.TemplateID = req.TemplateID
-	if err := s.db.Save(&contact).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&contact).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated)
+	}); err != nil {
 		return nil, err
 	}
 	if err := reloadContactWithSameVaultFirstMetThrough(s.db, &contact, vaultID); err != nil {
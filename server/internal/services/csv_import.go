@@ -3,12 +3,15 @@ package services
 import (
 	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"net/mail"
 	"strings"
 	"time"
 
 	"github.com/naiba/bonds/internal/dto"
 	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/phone"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -31,6 +34,15 @@ var birthdayLayouts = []string{
 // MaxCSVFileSize is the maximum accepted file size for CSV uploads (10 MB).
 const MaxCSVFileSize = 10 * 1024 * 1024
 
+// What an import does with a row that matches an existing contact.
+const (
+	CSVDuplicateCreate = "create"
+	CSVDuplicateSkip   = "skip"
+	CSVDuplicateUpdate = "update"
+)
+
+var ErrInvalidCSVDuplicateMode = errors.New("invalid duplicate mode")
+
 type CSVImportService struct {
 	db             *gorm.DB
 	feedRecorder   *FeedRecorder
@@ -54,40 +66,209 @@ func (s *CSVImportService) SetDavPushService(ps *DavPushService) {
 	s.davPushService = ps
 }
 
-func (s *CSVImportService) Import(vaultID, userID string, data []byte, mapping dto.CSVColumnMapping) (*dto.CSVImportResponse, error) {
+// Import applies a CSV file with the given column mapping. A dry run writes
+// nothing and reports what each row would do instead.
+func (s *CSVImportService) Import(vaultID, userID string, data []byte, mapping dto.CSVColumnMapping, opts dto.CSVImportOptions) (*dto.CSVImportResponse, error) {
+	return s.ImportWithProgress(vaultID, userID, data, mapping, opts, nil)
+}
+
+// ImportWithProgress is Import reporting one item per row. When the import
+// is cancelled it stops between rows and returns what it imported so far
+// along with ErrJobCancelled.
+func (s *CSVImportService) ImportWithProgress(vaultID, userID string, data []byte, mapping dto.CSVColumnMapping, opts dto.CSVImportOptions, progress JobProgress) (*dto.CSVImportResponse, error) {
+	switch opts.OnDuplicate {
+	case "":
+		opts.OnDuplicate = CSVDuplicateCreate
+	case CSVDuplicateCreate, CSVDuplicateSkip, CSVDuplicateUpdate:
+	default:
+		return nil, ErrInvalidCSVDuplicateMode
+	}
+
 	var vault models.Vault
 	if err := s.db.First(&vault, "id = ?", vaultID).Error; err != nil {
 		return nil, fmt.Errorf("vault not found: %w", err)
 	}
 	accountID := vault.AccountID
 
-	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
-	r := csv.NewReader(strings.NewReader(string(data)))
-	r.LazyQuotes = true
-	r.TrimLeadingSpace = true
-
-	records, err := r.ReadAll()
+	headers, rows, err := readCSV(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+		return nil, err
 	}
-	if len(records) < 2 {
-		return &dto.CSVImportResponse{Errors: []string{}}, nil
+	resp := &dto.CSVImportResponse{DryRun: opts.DryRun, Errors: []string{}}
+	if len(rows) == 0 {
+		return resp, nil
 	}
 
-	headers := records[0]
 	colIndex := buildColIndex(headers)
+	preset := detectCSVPreset(headers)
+	linkedInJobs := preset != nil && preset.name == CSVPresetLinkedIn
+	phoneRegion := phoneRegionForUser(s.db, userID)
+	index, err := s.loadContactIndex(vaultID, phoneRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	progressSetTotal(progress, len(rows))
+	var cancelled bool
+	for i, row := range rows {
+		if progressCancelled(progress) {
+			cancelled = true
+			break
+		}
+		progressAdvance(progress, 1)
+		rowNum := i + 2
+		firstName := col(row, colIndex, mapping.FirstName)
+		result := dto.CSVImportRowResult{
+			Row:    rowNum,
+			Name:   strings.TrimSpace(firstName + " " + col(row, colIndex, mapping.LastName)),
+			Action: CSVDuplicateCreate,
+		}
+		if firstName == "" {
+			resp.SkippedCount++
+			if opts.DryRun {
+				result.Action = CSVDuplicateSkip
+				result.Errors = []string{"first name is missing"}
+				resp.Rows = append(resp.Rows, result)
+			}
+			continue
+		}
 
-	resp := &dto.CSVImportResponse{Errors: []string{}}
+		keys := csvRowContactKeys(row, colIndex, mapping, phoneRegion)
+		match, matchedBy := index.find(keys)
+		if match != nil {
+			result.DuplicateOf = match.contactID
+			result.DuplicateOfRow = match.row
+			result.MatchedBy = matchedBy
+			result.Action = opts.OnDuplicate
+		}
 
-	for rowNum, row := range records[1:] {
-		if err := s.importRow(row, rowNum+2, colIndex, mapping, vaultID, accountID, userID, resp); err != nil {
-			resp.Errors = append(resp.Errors, fmt.Sprintf("row %d: %v", rowNum+2, err))
+		if opts.DryRun {
+			result.Errors = s.checkRow(row, colIndex, mapping, vaultID)
+			switch result.Action {
+			case CSVDuplicateSkip:
+				resp.SkippedCount++
+			case CSVDuplicateUpdate:
+				resp.UpdatedContacts++
+			default:
+				resp.ImportedContacts++
+				index.add(keys, "", rowNum)
+			}
+			resp.Rows = append(resp.Rows, result)
+			continue
+		}
+
+		switch result.Action {
+		case CSVDuplicateSkip:
+			resp.SkippedCount++
+			continue
+		case CSVDuplicateUpdate:
+			err = s.updateRow(match.contactID, row, rowNum, colIndex, mapping, linkedInJobs, vaultID, accountID, userID, resp)
+		default:
+			var contactID string
+			if contactID, err = s.importRow(row, rowNum, colIndex, mapping, linkedInJobs, vaultID, accountID, userID, resp); err == nil {
+				index.add(keys, contactID, rowNum)
+			}
+		}
+		if err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("row %d: %v", rowNum, err))
 			resp.SkippedCount++
 		}
 	}
+	if !opts.DryRun {
+		if err := recordImportedVaultChanges(s.db, vaultID); err != nil {
+			return resp, err
+		}
+	}
+	if cancelled {
+		return resp, ErrJobCancelled
+	}
+	return resp, nil
+}
+
+// CSVImportJobParams are the parameters of a csv_import job. The file is
+// the job's input.
+type CSVImportJobParams struct {
+	Mapping     dto.CSVColumnMapping `json:"mapping"`
+	DryRun      bool                 `json:"dry_run"`
+	OnDuplicate string               `json:"on_duplicate"`
+}
+
+// RunImportJob runs a csv_import job. Row errors become the job's errors.
+func (s *CSVImportService) RunImportJob(run *JobRun) (interface{}, []string, error) {
+	var params CSVImportJobParams
+	if err := run.Params(&params); err != nil {
+		return nil, nil, err
+	}
+	job := run.Job()
+	if job.VaultID == nil {
+		return nil, nil, errors.New("csv import job has no vault")
+	}
+	opts := dto.CSVImportOptions{DryRun: params.DryRun, OnDuplicate: params.OnDuplicate}
+	resp, err := s.ImportWithProgress(*job.VaultID, job.UserID, run.Input(), params.Mapping, opts, run)
+	if resp == nil {
+		return nil, nil, err
+	}
+	itemErrors := resp.Errors
+	resp.Errors = []string{}
+	return resp, itemErrors, err
+}
+
+// Preview reads the header of a CSV file, recognises the export format of
+// known applications and proposes a column mapping for it.
+func (s *CSVImportService) Preview(data []byte) (*dto.CSVImportPreviewResponse, error) {
+	headers, rows, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	resp := &dto.CSVImportPreviewResponse{Headers: headers, SampleRows: [][]string{}, RowCount: len(rows)}
+	if resp.Headers == nil {
+		resp.Headers = []string{}
+	}
+	fields := csvGenericHeaders
+	if preset := detectCSVPreset(headers); preset != nil {
+		resp.Preset = preset.name
+		fields = preset.fields
+	}
+	resp.Mapping = proposeCSVMapping(headers, fields)
+	if len(rows) > csvPreviewSampleRows {
+		rows = rows[:csvPreviewSampleRows]
+	}
+	resp.SampleRows = append(resp.SampleRows, rows...)
 	return resp, nil
 }
 
+// readCSV parses a file into its header and data rows.
+func readCSV(data []byte) ([]string, [][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	data = trimCSVPreamble(data)
+	r := csv.NewReader(bytes.NewReader(data))
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, nil
+	}
+	return records[0], records[1:], nil
+}
+
+// trimCSVPreamble drops the paragraph LinkedIn writes above the header of
+// its connections export.
+func trimCSVPreamble(data []byte) []byte {
+	if !bytes.HasPrefix(data, []byte("Notes:")) {
+		return data
+	}
+	for _, sep := range []string{"\r\n\r\n", "\n\n"} {
+		if i := bytes.Index(data, []byte(sep)); i >= 0 {
+			return data[i+len(sep):]
+		}
+	}
+	return data
+}
+
 // buildColIndex maps lowercased header name to its column index.
 func buildColIndex(headers []string) map[string]int {
 	m := make(map[string]int, len(headers))
@@ -109,37 +290,141 @@ func col(row []string, colIndex map[string]int, header string) string {
 	return strings.TrimSpace(row[idx])
 }
 
+// csvContactMatch is a contact that rows are matched against: an existing
+// contact, or one created by an earlier row (row is 0 for existing ones).
+// In a dry run the contacts of earlier rows have no ID yet.
+type csvContactMatch struct {
+	contactID string
+	row       int
+}
+
+// csvContactIndex finds contacts by normalised email address or phone.
+type csvContactIndex map[string]*csvContactMatch
+
+func (s *CSVImportService) loadContactIndex(vaultID, phoneRegion string) (csvContactIndex, error) {
+	var infos []struct {
+		ContactID      string
+		Data           string
+		NormalizedData *string
+		Type           string
+	}
+	err := s.db.Table("contact_information").
+		Select("contact_information.contact_id, contact_information.data, contact_information.normalized_data, contact_information_types.type").
+		Joins("JOIN contact_information_types ON contact_information_types.id = contact_information.type_id").
+		Joins("JOIN contacts ON contacts.id = contact_information.contact_id").
+		Where("contacts.vault_id = ? AND contacts.deleted_at IS NULL AND contact_information_types.type IN ?", vaultID, []string{"email", "phone"}).
+		Scan(&infos).Error
+	if err != nil {
+		return nil, err
+	}
+	index := make(csvContactIndex, len(infos))
+	for _, info := range infos {
+		key := csvContactKey(info.Type, info.Data, phoneRegion)
+		if info.NormalizedData != nil {
+			key = "phone:" + strings.TrimPrefix(*info.NormalizedData, "+")
+		}
+		if key != "" {
+			index.add([]string{key}, info.ContactID, 0)
+		}
+	}
+	return index, nil
+}
+
+// find returns the first contact matching one of keys and the kind of the key.
+func (idx csvContactIndex) find(keys []string) (*csvContactMatch, string) {
+	for _, key := range keys {
+		if match, ok := idx[key]; ok {
+			kind, _, _ := strings.Cut(key, ":")
+			return match, kind
+		}
+	}
+	return nil, ""
+}
+
+func (idx csvContactIndex) add(keys []string, contactID string, row int) {
+	for _, key := range keys {
+		if _, ok := idx[key]; !ok {
+			idx[key] = &csvContactMatch{contactID: contactID, row: row}
+		}
+	}
+}
+
+// csvContactKey normalises an email address or phone number for matching.
+// Phones compare by the digits of their E.164 form in phoneRegion, so that
+// they still match phones that could only be compared by their digits.
+// Those need at least six digits.
+func csvContactKey(kind, value, phoneRegion string) string {
+	switch kind {
+	case "email":
+		if v := strings.ToLower(strings.TrimSpace(value)); strings.Contains(v, "@") {
+			return "email:" + v
+		}
+	case "phone":
+		if normalized := phone.Normalize(value, phoneRegion); normalized != "" {
+			return "phone:" + strings.TrimPrefix(normalized, "+")
+		}
+		var digits strings.Builder
+		for _, r := range value {
+			if r >= '0' && r <= '9' {
+				digits.WriteRune(r)
+			}
+		}
+		if digits.Len() >= 6 {
+			return "phone:" + digits.String()
+		}
+	}
+	return ""
+}
+
+func csvRowContactKeys(row []string, colIndex map[string]int, m dto.CSVColumnMapping, phoneRegion string) []string {
+	var keys []string
+	for _, email := range splitCSVMultiValue(col(row, colIndex, m.Email)) {
+		if key := csvContactKey("email", email, phoneRegion); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	for _, phoneVal := range splitCSVMultiValue(col(row, colIndex, m.Phone)) {
+		if key := csvContactKey("phone", phoneVal, phoneRegion); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// checkRow reports the problems a row would run into, without writing.
+func (s *CSVImportService) checkRow(row []string, colIndex map[string]int, m dto.CSVColumnMapping, vaultID string) []string {
+	var problems []string
+	for _, email := range splitCSVMultiValue(col(row, colIndex, m.Email)) {
+		if !validCSVEmail(email) {
+			problems = append(problems, fmt.Sprintf("invalid email address: %q", email))
+		}
+	}
+	if bdVal := col(row, colIndex, m.Birthday); !isEmptyCSVDate(bdVal) {
+		if _, ok := parseBirthday(bdVal); !ok {
+			problems = append(problems, fmt.Sprintf("unrecognised birthday format: %q", bdVal))
+		}
+	}
+	for _, groupName := range splitCSVList(col(row, colIndex, m.Groups)) {
+		var count int64
+		s.db.Model(&models.Group{}).Where("vault_id = ? AND name = ?", vaultID, groupName).Count(&count)
+		if count == 0 {
+			problems = append(problems, fmt.Sprintf("group %q not found (create it first)", groupName))
+		}
+	}
+	return problems
+}
+
 func (s *CSVImportService) importRow(
 	row []string, rowNum int,
 	colIndex map[string]int,
 	m dto.CSVColumnMapping,
+	linkedInJobs bool,
 	vaultID, accountID, userID string,
 	resp *dto.CSVImportResponse,
-) error {
+) (string, error) {
 	firstName := col(row, colIndex, m.FirstName)
-	if firstName == "" {
-		resp.SkippedCount++
-		return nil
-	}
-
 	now := time.Now()
 
-	// Gender lookup: name match first, then translation key fallback.
-	var genderID *uint
-	if genderVal := col(row, colIndex, m.Gender); genderVal != "" {
-		var gender models.Gender
-		if s.db.Where("account_id = ? AND name = ?", accountID, genderVal).First(&gender).Error == nil {
-			genderID = &gender.ID
-		} else {
-			key := genderNameToTranslationKey(genderVal)
-			if key != "" {
-				if s.db.Where("account_id = ? AND name_translation_key = ?", accountID, key).First(&gender).Error == nil {
-					genderID = &gender.ID
-				}
-			}
-		}
-	}
-
 	contact := models.Contact{
 		VaultID:       vaultID,
 		FirstName:     strPtrOrNil(firstName),
@@ -149,7 +434,7 @@ func (s *CSVImportService) importRow(
 		Prefix:        strPtrOrNil(col(row, colIndex, m.Prefix)),
 		Suffix:        strPtrOrNil(col(row, colIndex, m.Suffix)),
 		JobPosition:   strPtrOrNil(col(row, colIndex, m.JobTitle)),
-		GenderID:      genderID,
+		GenderID:      s.lookupGender(accountID, col(row, colIndex, m.Gender)),
 		Listed:        true,
 		LastUpdatedAt: &now,
 		CreatedAt:     now,
@@ -172,35 +457,167 @@ func (s *CSVImportService) importRow(
 		return nil
 	})
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	resp.ImportedContacts++
+	createdNote := s.addRowDetails(&contact, row, rowNum, colIndex, m, linkedInJobs, vaultID, accountID, userID, resp, false)
+
+	// Side effects: feed, search index, DAV push — mirror ContactService.CreateContact.
+	if s.feedRecorder != nil {
+		s.feedRecorder.Record(contact.ID, userID, ActionContactCreated, "Imported contact "+firstName, nil, nil)
+	}
+	if s.searchService != nil {
+		s.searchService.IndexContact(&contact)
+	}
+	if s.davPushService != nil {
+		go s.davPushService.PushContactChange(contact.ID, vaultID)
+	}
+	s.noteSideEffects(createdNote, userID)
+	return contact.ID, nil
+}
+
+// updateRow merges a row into the contact it matched. Mapped names replace
+// the contact's, and details it does not have yet are added.
+func (s *CSVImportService) updateRow(
+	contactID string,
+	row []string, rowNum int,
+	colIndex map[string]int,
+	m dto.CSVColumnMapping,
+	linkedInJobs bool,
+	vaultID, accountID, userID string,
+	resp *dto.CSVImportResponse,
+) error {
+	var contact models.Contact
+	if err := s.db.Where("id = ? AND vault_id = ?", contactID, vaultID).First(&contact).Error; err != nil {
+		return fmt.Errorf("failed to load contact: %w", err)
+	}
+
+	updates := map[string]interface{}{"last_updated_at": time.Now()}
+	for column, header := range map[string]string{
+		"first_name":   m.FirstName,
+		"last_name":    m.LastName,
+		"middle_name":  m.MiddleName,
+		"nickname":     m.Nickname,
+		"prefix":       m.Prefix,
+		"suffix":       m.Suffix,
+		"job_position": m.JobTitle,
+	} {
+		if v := col(row, colIndex, header); v != "" {
+			updates[column] = v
+		}
+	}
+	if genderID := s.lookupGender(accountID, col(row, colIndex, m.Gender)); genderID != nil {
+		updates["gender_id"] = *genderID
+	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Contact{}).Where("id = ?", contact.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update contact: %w", err)
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated)
+	}); err != nil {
+		return err
+	}
+	if err := s.db.First(&contact, "id = ?", contact.ID).Error; err != nil {
+		return fmt.Errorf("failed to load contact: %w", err)
+	}
+
+	resp.UpdatedContacts++
+	createdNote := s.addRowDetails(&contact, row, rowNum, colIndex, m, linkedInJobs, vaultID, accountID, userID, resp, true)
+
+	// Side effects: mirror ContactService.UpdateContact.
+	if s.feedRecorder != nil {
+		s.feedRecorder.Record(contact.ID, userID, ActionContactUpdated, "Updated contact "+col(row, colIndex, m.FirstName)+" from import", nil, nil)
+	}
+	if s.searchService != nil {
+		s.searchService.IndexContact(&contact)
+	}
+	if s.davPushService != nil {
+		go s.davPushService.PushContactChange(contact.ID, vaultID)
+	}
+	s.noteSideEffects(createdNote, userID)
+	return nil
+}
+
+// addRowDetails adds the contact information, dates, labels, groups, note,
+// address and job of a row. When merging into an existing contact, details
+// the contact already has are left alone.
+func (s *CSVImportService) addRowDetails(
+	contact *models.Contact,
+	row []string, rowNum int,
+	colIndex map[string]int,
+	m dto.CSVColumnMapping,
+	linkedInJobs bool,
+	vaultID, accountID, userID string,
+	resp *dto.CSVImportResponse,
+	merge bool,
+) *models.Note {
+	now := time.Now()
+	phoneRegion := phoneRegionForUser(s.db, userID)
+	known := map[string]bool{}
+	if merge {
+		var infos []models.ContactInformation
+		s.db.Where("contact_id = ?", contact.ID).Find(&infos)
+		for _, info := range infos {
+			known[strings.ToLower(strings.TrimSpace(info.Data))] = true
+			if info.NormalizedData != nil {
+				known["phone:"+strings.TrimPrefix(*info.NormalizedData, "+")] = true
+			}
+			for _, kind := range []string{"email", "phone"} {
+				if key := csvContactKey(kind, info.Data, phoneRegion); key != "" {
+					known[key] = true
+				}
+			}
+		}
+	}
 
 	// Email.
-	if emailVal := col(row, colIndex, m.Email); emailVal != "" {
-		s.createContactInfo(accountID, contact.ID, emailVal, "seed.contact_info_types.email_address", resp, rowNum)
+	for _, emailVal := range splitCSVMultiValue(col(row, colIndex, m.Email)) {
+		if !validCSVEmail(emailVal) {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("row %d: invalid email address: %q", rowNum, emailVal))
+			continue
+		}
+		if !known[csvContactKey("email", emailVal, phoneRegion)] {
+			s.createContactInfo(accountID, contact.ID, emailVal, "seed.contact_info_types.email_address", phoneRegion, resp, rowNum)
+		}
 	}
 
 	// Phone.
-	if phoneVal := col(row, colIndex, m.Phone); phoneVal != "" {
-		s.createContactInfo(accountID, contact.ID, phoneVal, "seed.contact_info_types.phone", resp, rowNum)
+	for _, phoneVal := range splitCSVMultiValue(col(row, colIndex, m.Phone)) {
+		if key := csvContactKey("phone", phoneVal, phoneRegion); key == "" || !known[key] {
+			s.createContactInfo(accountID, contact.ID, phoneVal, "seed.contact_info_types.phone", phoneRegion, resp, rowNum)
+		}
+	}
+
+	// LinkedIn profile.
+	if urlVal := col(row, colIndex, m.LinkedIn); urlVal != "" && !known[strings.ToLower(urlVal)] {
+		s.createContactInfo(accountID, contact.ID, urlVal, "seed.contact_info_types.linkedin", phoneRegion, resp, rowNum)
 	}
 
 	// Birthday.
-	if bdVal := col(row, colIndex, m.Birthday); bdVal != "" {
-		s.createBirthday(vaultID, contact.ID, bdVal, resp, rowNum)
+	if bdVal := col(row, colIndex, m.Birthday); !isEmptyCSVDate(bdVal) {
+		var existing int64
+		if merge {
+			s.db.Model(&models.ContactImportantDate{}).
+				Joins("JOIN contact_important_date_types ON contact_important_date_types.id = contact_important_dates.contact_important_date_type_id").
+				Where("contact_important_dates.contact_id = ? AND contact_important_date_types.internal_type = ?", contact.ID, "birthdate").
+				Count(&existing)
+		}
+		if existing == 0 {
+			s.createBirthday(vaultID, contact.ID, bdVal, resp, rowNum)
+		}
 	}
 
 	// Tags (comma-separated, already unquoted by encoding/csv).
 	if tagsVal := col(row, colIndex, m.Tags); tagsVal != "" {
-		for _, tag := range splitCSVList(tagsVal) {
+		for _, tag := range splitCSVLabels(tagsVal) {
 			label, err := s.findOrCreateLabel(s.db, vaultID, tag)
 			if err != nil {
 				resp.Errors = append(resp.Errors, fmt.Sprintf("row %d: tag %q: %v", rowNum, tag, err))
 				continue
 			}
-			if err := s.db.Create(&models.ContactLabel{ContactID: contact.ID, LabelID: label.ID}).Error; err != nil {
+			link := models.ContactLabel{ContactID: contact.ID, LabelID: label.ID}
+			if err := s.db.Where(&link).FirstOrCreate(&link).Error; err != nil {
 				resp.Errors = append(resp.Errors, fmt.Sprintf("row %d: tag %q link: %v", rowNum, tag, err))
 			}
 		}
@@ -220,17 +637,19 @@ func (s *CSVImportService) importRow(
 		}
 	}
 
-	// Notes — also prepend company name if provided (company is not a plain
-	// string field on Contact; full company linking is out of scope for CSV import).
-	var createdNote *models.Note
+	// Company — LinkedIn rows become a job at the company; other formats
+	// keep the company name in the note.
 	notesVal := col(row, colIndex, m.Notes)
 	if companyVal := col(row, colIndex, m.Company); companyVal != "" {
-		if notesVal != "" {
+		if linkedInJobs {
+			s.createJob(vaultID, contact.ID, companyVal, col(row, colIndex, m.JobTitle), resp, rowNum)
+		} else if notesVal != "" {
 			notesVal = "Company: " + companyVal + "\n" + notesVal
 		} else {
 			notesVal = "Company: " + companyVal
 		}
 	}
+	var createdNote *models.Note
 	if notesVal != "" {
 		note := models.Note{
 			ContactID: contact.ID,
@@ -248,34 +667,70 @@ func (s *CSVImportService) importRow(
 	}
 
 	// Address (only created if at least one field is non-empty).
-	s.createAddress(accountID, vaultID, contact.ID, row, colIndex, m, resp, rowNum)
+	var addresses int64
+	if merge {
+		s.db.Model(&models.ContactAddress{}).Where("contact_id = ?", contact.ID).Count(&addresses)
+	}
+	if addresses == 0 {
+		s.createAddress(accountID, vaultID, contact.ID, row, colIndex, m, resp, rowNum)
+	}
+	return createdNote
+}
 
-	// Side effects: feed, search index, DAV push — mirror ContactService.CreateContact.
+// noteSideEffects mirrors NoteService.Create for a note added by a row.
+func (s *CSVImportService) noteSideEffects(note *models.Note, userID string) {
+	if note == nil {
+		return
+	}
 	if s.feedRecorder != nil {
-		s.feedRecorder.Record(contact.ID, userID, ActionContactCreated, "Imported contact "+firstName, nil, nil)
+		entityType := "Note"
+		s.feedRecorder.Record(note.ContactID, userID, ActionNoteCreated, "Created a note", &note.ID, &entityType)
 	}
 	if s.searchService != nil {
-		s.searchService.IndexContact(&contact)
-	}
-	if s.davPushService != nil {
-		go s.davPushService.PushContactChange(contact.ID, vaultID)
+		s.searchService.IndexNote(note)
 	}
+}
 
-	// Note side effects: mirror NoteService.Create.
-	if createdNote != nil {
-		if s.feedRecorder != nil {
-			entityType := "Note"
-			s.feedRecorder.Record(contact.ID, userID, ActionNoteCreated, "Created a note", &createdNote.ID, &entityType)
-		}
-		if s.searchService != nil {
-			s.searchService.IndexNote(createdNote)
+// lookupGender matches a gender by name first, then by translation key.
+func (s *CSVImportService) lookupGender(accountID, value string) *uint {
+	if value == "" {
+		return nil
+	}
+	var gender models.Gender
+	if s.db.Where("account_id = ? AND name = ?", accountID, value).First(&gender).Error == nil {
+		return &gender.ID
+	}
+	if key := genderNameToTranslationKey(value); key != "" {
+		if s.db.Where("account_id = ? AND name_translation_key = ?", accountID, key).First(&gender).Error == nil {
+			return &gender.ID
 		}
 	}
-
 	return nil
 }
 
-func (s *CSVImportService) createContactInfo(accountID, contactID, value, translationKey string, resp *dto.CSVImportResponse, rowNum int) {
+func (s *CSVImportService) createJob(vaultID, contactID, companyName, position string, resp *dto.CSVImportResponse, rowNum int) {
+	var company models.Company
+	err := s.db.Where("vault_id = ? AND LOWER(name) = LOWER(?)", vaultID, companyName).First(&company).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		company = models.Company{VaultID: vaultID, Name: companyName}
+		err = s.db.Create(&company).Error
+	}
+	if err != nil {
+		resp.Errors = append(resp.Errors, fmt.Sprintf("row %d: company %q: %v", rowNum, companyName, err))
+		return
+	}
+	var existing int64
+	s.db.Model(&models.ContactCompany{}).Where("contact_id = ? AND company_id = ?", contactID, company.ID).Count(&existing)
+	if existing > 0 {
+		return
+	}
+	job := models.ContactCompany{ContactID: contactID, CompanyID: company.ID, JobPosition: strPtrOrNil(position)}
+	if err := s.db.Create(&job).Error; err != nil {
+		resp.Errors = append(resp.Errors, fmt.Sprintf("row %d: company %q job: %v", rowNum, companyName, err))
+	}
+}
+
+func (s *CSVImportService) createContactInfo(accountID, contactID, value, translationKey, phoneRegion string, resp *dto.CSVImportResponse, rowNum int) {
 	var ciType models.ContactInformationType
 	if err := s.db.Where("account_id = ? AND name_translation_key = ?", accountID, translationKey).First(&ciType).Error; err != nil {
 		resp.Errors = append(resp.Errors, fmt.Sprintf("row %d: contact info type %q not found", rowNum, translationKey))
@@ -286,6 +741,9 @@ func (s *CSVImportService) createContactInfo(accountID, contactID, value, transl
 		TypeID:    ciType.ID,
 		Data:      value,
 	}
+	if ciType.Type != nil && *ciType.Type == "phone" {
+		ci.NormalizedData = models.NormalizedPhone(value, phoneRegion)
+	}
 	if err := s.db.Create(&ci).Error; err != nil {
 		resp.Errors = append(resp.Errors, fmt.Sprintf("row %d: contact info: %v", rowNum, err))
 	}
@@ -352,6 +810,10 @@ func (s *CSVImportService) createAddress(accountID, vaultID, contactID string, r
 
 	if err := s.db.Create(&models.ContactAddress{ContactID: contactID, AddressID: addr.ID}).Error; err != nil {
 		resp.Errors = append(resp.Errors, fmt.Sprintf("row %d: address link: %v", rowNum, err))
+		return
+	}
+	if _, err := queueGeocoding(s.db, addr); err != nil {
+		resp.Errors = append(resp.Errors, fmt.Sprintf("row %d: address geocoding: %v", rowNum, err))
 	}
 }
 
@@ -400,6 +862,41 @@ func parseBirthday(s string) (time.Time, bool) {
 	return time.Time{}, false
 }
 
+// isEmptyCSVDate reports blank dates, including the 0/0/00 Outlook writes
+// for contacts without a birthday.
+func isEmptyCSVDate(s string) bool {
+	return strings.Trim(s, "0/-. ") == ""
+}
+
+func validCSVEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// splitCSVMultiValue splits the values Google Contacts joins with " ::: ".
+func splitCSVMultiValue(s string) []string {
+	parts := strings.Split(s, ":::")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if v := strings.TrimSpace(p); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// splitCSVLabels splits a tag cell on commas and on Google's " ::: ", and
+// drops Google system groups such as "* myContacts".
+func splitCSVLabels(s string) []string {
+	var out []string
+	for _, label := range splitCSVList(strings.ReplaceAll(s, ":::", ",")) {
+		if !strings.HasPrefix(label, "*") {
+			out = append(out, label)
+		}
+	}
+	return out
+}
+
 // splitCSVList splits a comma-separated list and trims each item.
 func splitCSVList(s string) []string {
 	parts := strings.Split(s, ",")
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/naiba/bonds/internal/dto"
+)
+
+const (
+	CSVPresetGoogle       = "google"
+	CSVPresetOutlook      = "outlook"
+	CSVPresetAppleNumbers = "apple_numbers"
+	CSVPresetLinkedIn     = "linkedin"
+)
+
+// csvPreviewSampleRows is how many data rows a preview returns.
+const csvPreviewSampleRows = 5
+
+// csvPreset describes the export format of a known application. Every group
+// of signature headers must have a match for the preset to apply, and each
+// field maps to the first of its candidate headers present in the file.
+type csvPreset struct {
+	name      string
+	signature [][]string
+	fields    csvFieldHeaders
+}
+
+type csvFieldHeaders struct {
+	firstName, lastName, middleName, nickname, prefix, suffix, gender []string
+	birthday, email, phone, company, jobTitle, tags, groups, notes    []string
+	street, city, state, postalCode, country, linkedIn                []string
+}
+
+// csvPresets are tried in order, most specific first.
+var csvPresets = []csvPreset{
+	{
+		name:      CSVPresetLinkedIn,
+		signature: [][]string{{"First Name"}, {"Position"}, {"Connected On"}},
+		fields: csvFieldHeaders{
+			firstName: []string{"First Name"},
+			lastName:  []string{"Last Name"},
+			email:     []string{"Email Address"},
+			company:   []string{"Company"},
+			jobTitle:  []string{"Position"},
+			linkedIn:  []string{"URL"},
+		},
+	},
+	{
+		name:      CSVPresetGoogle,
+		signature: [][]string{{"First Name", "Given Name"}, {"E-mail 1 - Value", "Phone 1 - Value", "Group Membership", "File As"}},
+		fields: csvFieldHeaders{
+			firstName:  []string{"First Name", "Given Name"},
+			lastName:   []string{"Last Name", "Family Name"},
+			middleName: []string{"Middle Name", "Additional Name"},
+			nickname:   []string{"Nickname"},
+			prefix:     []string{"Name Prefix"},
+			suffix:     []string{"Name Suffix"},
+			gender:     []string{"Gender"},
+			birthday:   []string{"Birthday"},
+			email:      []string{"E-mail 1 - Value"},
+			phone:      []string{"Phone 1 - Value"},
+			company:    []string{"Organization Name", "Organization 1 - Name"},
+			jobTitle:   []string{"Organization Title", "Organization 1 - Title"},
+			tags:       []string{"Labels", "Group Membership"},
+			notes:      []string{"Notes"},
+			street:     []string{"Address 1 - Street"},
+			city:       []string{"Address 1 - City"},
+			state:      []string{"Address 1 - Region"},
+			postalCode: []string{"Address 1 - Postal Code"},
+			country:    []string{"Address 1 - Country"},
+		},
+	},
+	{
+		name:      CSVPresetOutlook,
+		signature: [][]string{{"First Name"}, {"E-mail Address"}, {"Home Street", "Business Street", "E-mail Display Name"}},
+		fields: csvFieldHeaders{
+			firstName:  []string{"First Name"},
+			lastName:   []string{"Last Name"},
+			middleName: []string{"Middle Name"},
+			nickname:   []string{"Nickname"},
+			prefix:     []string{"Title"},
+			suffix:     []string{"Suffix"},
+			gender:     []string{"Gender"},
+			birthday:   []string{"Birthday"},
+			email:      []string{"E-mail Address"},
+			phone:      []string{"Mobile Phone", "Home Phone", "Business Phone", "Primary Phone"},
+			company:    []string{"Company"},
+			jobTitle:   []string{"Job Title"},
+			tags:       []string{"Categories"},
+			notes:      []string{"Notes"},
+			street:     []string{"Home Street"},
+			city:       []string{"Home City"},
+			state:      []string{"Home State"},
+			postalCode: []string{"Home Postal Code"},
+			country:    []string{"Home Country/Region", "Home Country"},
+		},
+	},
+	{
+		name:      CSVPresetAppleNumbers,
+		signature: [][]string{{"First Name"}, {"Organization"}, {"Note"}},
+		fields: csvFieldHeaders{
+			firstName:  []string{"First Name"},
+			lastName:   []string{"Last Name"},
+			middleName: []string{"Middle Name"},
+			nickname:   []string{"Nickname"},
+			prefix:     []string{"Prefix"},
+			suffix:     []string{"Suffix"},
+			birthday:   []string{"Birthday"},
+			email:      []string{"Email", "Home Email", "Work Email", "Other Email"},
+			phone:      []string{"Phone", "Mobile", "Mobile Phone", "iPhone", "Home Phone", "Work Phone"},
+			company:    []string{"Organization"},
+			jobTitle:   []string{"Job Title"},
+			notes:      []string{"Note"},
+			street:     []string{"Street", "Home Street"},
+			city:       []string{"City", "Home City"},
+			state:      []string{"State", "Home State"},
+			postalCode: []string{"ZIP", "Postal Code", "Home ZIP"},
+			country:    []string{"Country", "Home Country"},
+		},
+	},
+}
+
+// csvGenericHeaders maps the column names common to most exports when the
+// file matches no preset.
+var csvGenericHeaders = csvFieldHeaders{
+	firstName:  []string{"First Name", "FirstName", "Given Name", "Prénom"},
+	lastName:   []string{"Last Name", "LastName", "Surname", "Family Name", "Nom"},
+	middleName: []string{"Middle Name"},
+	nickname:   []string{"Nickname"},
+	gender:     []string{"Gender"},
+	birthday:   []string{"Birthday", "Birthdate", "DOB", "Date of Birth", "Naissance"},
+	email:      []string{"Email", "Email Address", "Mail", "Courriel"},
+	phone:      []string{"Phone", "Phone Number", "Mobile", "Telephone", "Tel"},
+	company:    []string{"Company", "Organization", "Organisation", "Employer", "Société"},
+	jobTitle:   []string{"Job Title", "Position"},
+	tags:       []string{"Tags", "Labels", "Categories"},
+	groups:     []string{"Groups", "Groupes"},
+	notes:      []string{"Notes", "Note"},
+}
+
+// normalizeCSVHeader compares headers case-insensitively and ignores
+// punctuation and spaces.
+func normalizeCSVHeader(h string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(h) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// detectCSVPreset returns the preset the headers match, or nil.
+func detectCSVPreset(headers []string) *csvPreset {
+	present := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		present[normalizeCSVHeader(h)] = true
+	}
+	for i := range csvPresets {
+		matched := true
+		for _, group := range csvPresets[i].signature {
+			if findCSVHeader(headers, present, group) == "" {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return &csvPresets[i]
+		}
+	}
+	return nil
+}
+
+// proposeCSVMapping maps each field to the file's own spelling of the first
+// candidate header it contains.
+func proposeCSVMapping(headers []string, fields csvFieldHeaders) dto.CSVColumnMapping {
+	present := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		present[normalizeCSVHeader(h)] = true
+	}
+	find := func(candidates []string) string {
+		return findCSVHeader(headers, present, candidates)
+	}
+	return dto.CSVColumnMapping{
+		FirstName:         find(fields.firstName),
+		LastName:          find(fields.lastName),
+		MiddleName:        find(fields.middleName),
+		Nickname:          find(fields.nickname),
+		Prefix:            find(fields.prefix),
+		Suffix:            find(fields.suffix),
+		Gender:            find(fields.gender),
+		Birthday:          find(fields.birthday),
+		Email:             find(fields.email),
+		Phone:             find(fields.phone),
+		Company:           find(fields.company),
+		JobTitle:          find(fields.jobTitle),
+		Tags:              find(fields.tags),
+		Groups:            find(fields.groups),
+		Notes:             find(fields.notes),
+		AddressStreet:     find(fields.street),
+		AddressCity:       find(fields.city),
+		AddressState:      find(fields.state),
+		AddressPostalCode: find(fields.postalCode),
+		AddressCountry:    find(fields.country),
+		LinkedIn:          find(fields.linkedIn),
+	}
+}
+
+func findCSVHeader(headers []string, present map[string]bool, candidates []string) string {
+	for _, candidate := range candidates {
+		want := normalizeCSVHeader(candidate)
+		if !present[want] {
+			continue
+		}
+		for _, h := range headers {
+			if normalizeCSVHeader(h) == want {
+				return strings.TrimSpace(h)
+			}
+		}
+	}
+	return ""
+}
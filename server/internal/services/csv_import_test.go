@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -89,7 +90,7 @@ func TestCSVImport_SuccessBasic(t *testing.T) {
 		[]string{"Bob", "Jones"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, defaultMapping())
+	resp, err := svc.Import(vaultID, userID, data, defaultMapping(), dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -116,7 +117,7 @@ func TestCSVImport_UTFBOM(t *testing.T) {
 
 	raw := "\xEF\xBB\xBFfirst_name,last_name\nCarol,White\n"
 
-	resp, err := svc.Import(vaultID, userID, []byte(raw), defaultMapping())
+	resp, err := svc.Import(vaultID, userID, []byte(raw), defaultMapping(), dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -144,7 +145,7 @@ func TestCSVImport_QuotedCommas(t *testing.T) {
 	}
 	raw := `first_name,tags` + "\n" + `"Dana","friends,family"` + "\n"
 
-	resp, err := svc.Import(vaultID, userID, []byte(raw), m)
+	resp, err := svc.Import(vaultID, userID, []byte(raw), m, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -176,7 +177,7 @@ func TestCSVImport_MissingFirstName(t *testing.T) {
 		[]string{"", "Nameless"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, defaultMapping())
+	resp, err := svc.Import(vaultID, userID, data, defaultMapping(), dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -204,7 +205,7 @@ func TestCSVImport_MalformedCSV(t *testing.T) {
 	// A row with more fields than the header triggers csv.ErrFieldCount.
 	raw := "first_name,last_name\nFrank,Jones,ExtraUnexpectedField\n"
 
-	_, err := svc.Import(vaultID, userID, []byte(raw), defaultMapping())
+	_, err := svc.Import(vaultID, userID, []byte(raw), defaultMapping(), dto.CSVImportOptions{})
 	if err == nil {
 		t.Error("expected parse error for malformed CSV (field count mismatch), got nil")
 	}
@@ -226,7 +227,7 @@ func TestCSVImport_EmailMapping(t *testing.T) {
 		[]string{"Grace", "grace@example.com"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, m)
+	resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -262,7 +263,7 @@ func TestCSVImport_PhoneMapping(t *testing.T) {
 		[]string{"Henry", "+1-555-0100"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, m)
+	resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -298,7 +299,7 @@ func TestCSVImport_BirthdayMapping(t *testing.T) {
 		[]string{"Iris", "1990-06-15"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, m)
+	resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -341,7 +342,7 @@ func TestCSVImport_AddressMapping(t *testing.T) {
 		[]string{"Jack", "123 Main St", "Springfield", "US"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, m)
+	resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -375,7 +376,7 @@ func TestCSVImport_LabelCreation(t *testing.T) {
 		[]string{"Leo", "colleague"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, m)
+	resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -409,7 +410,7 @@ func TestCSVImport_GroupNotFound(t *testing.T) {
 		[]string{"Mia", "NonExistentGroup"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, m)
+	resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -440,7 +441,7 @@ func TestCSVImport_ContactAndCVUCreatedTogether(t *testing.T) {
 		[]string{"Nick"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, dto.CSVColumnMapping{FirstName: "first_name"})
+	resp, err := svc.Import(vaultID, userID, data, dto.CSVColumnMapping{FirstName: "first_name"}, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -472,7 +473,7 @@ func TestCSVImport_NonexistentVault(t *testing.T) {
 		[]string{"Ghost"},
 	)
 
-	_, err := svc.Import("nonexistent-vault-id", userID, data, dto.CSVColumnMapping{FirstName: "first_name"})
+	_, err := svc.Import("nonexistent-vault-id", userID, data, dto.CSVColumnMapping{FirstName: "first_name"}, dto.CSVImportOptions{})
 	if err == nil {
 		t.Error("expected error for nonexistent vault, got nil")
 	}
@@ -499,7 +500,7 @@ func TestCSVImport_FeedRecords(t *testing.T) {
 		[]string{"Paul"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, dto.CSVColumnMapping{FirstName: "first_name"})
+	resp, err := svc.Import(vaultID, userID, data, dto.CSVColumnMapping{FirstName: "first_name"}, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -535,7 +536,7 @@ func TestCSVImport_NoteWithFeedRecord(t *testing.T) {
 		[]string{"Quinn", "This is a note"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, m)
+	resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -573,7 +574,7 @@ func TestCSVImport_CompanyNote(t *testing.T) {
 		[]string{"Rachel", "Acme Corp"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, m)
+	resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -602,7 +603,7 @@ func TestCSVImport_LastUpdatedAt(t *testing.T) {
 		[]string{"Tina"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, dto.CSVColumnMapping{FirstName: "first_name"})
+	resp, err := svc.Import(vaultID, userID, data, dto.CSVColumnMapping{FirstName: "first_name"}, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -626,7 +627,7 @@ func TestCSVImport_EmptyCSV(t *testing.T) {
 
 	data := []byte("first_name,last_name\n")
 
-	resp, err := svc.Import(vaultID, userID, data, defaultMapping())
+	resp, err := svc.Import(vaultID, userID, data, defaultMapping(), dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -663,7 +664,7 @@ func TestCSVImport_BirthdayFormats(t *testing.T) {
 			[]string{"first_name", "birthday"},
 			[]string{name, tc.bdVal},
 		)
-		resp, err := svc.Import(vaultID, userID, data, m)
+		resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{})
 		if err != nil {
 			t.Errorf("%s: Import failed: %v", tc.name, err)
 			continue
@@ -699,7 +700,7 @@ func TestCSVImport_BadBirthdayFormat(t *testing.T) {
 		[]string{"Uma", "not-a-date"},
 	)
 
-	resp, err := svc.Import(vaultID, userID, data, m)
+	resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{})
 	if err != nil {
 		t.Fatalf("Import failed: %v", err)
 	}
@@ -720,3 +721,199 @@ func TestCSVImport_MaxFileSizeConstant(t *testing.T) {
 		t.Errorf("expected MaxCSVFileSize = 10MB, got %d", MaxCSVFileSize)
 	}
 }
+
+// ---------------------------------------------------------------------------
+// Preview: provider presets
+// ---------------------------------------------------------------------------
+
+func TestCSVImport_PreviewDetectsPresets(t *testing.T) {
+	svc, _, _, _ := setupCSVImportTest(t)
+
+	cases := []struct {
+		name      string
+		raw       string
+		preset    string
+		firstName string
+		email     string
+	}{
+		{"google", "First Name,Last Name,File As,Labels,E-mail 1 - Label,E-mail 1 - Value\nAda,Lovelace,,Friends,Home,ada@example.com\n", CSVPresetGoogle, "First Name", "E-mail 1 - Value"},
+		{"outlook", "First Name,Last Name,E-mail Address,E-mail Display Name,Home Street,Mobile Phone\nAda,Lovelace,ada@example.com,Ada,,\n", CSVPresetOutlook, "First Name", "E-mail Address"},
+		{"apple numbers", "First Name,Last Name,Organization,Note,Email\nAda,Lovelace,,,ada@example.com\n", CSVPresetAppleNumbers, "First Name", "Email"},
+		{"linkedin", "Notes:\n\"When exporting your connection data, some email addresses may be missing.\"\n\nFirst Name,Last Name,URL,Email Address,Company,Position,Connected On\nAda,Lovelace,https://www.linkedin.com/in/ada,,Analytical Engines,Engineer,10 Dec 2020\n", CSVPresetLinkedIn, "First Name", "Email Address"},
+		{"generic", "given name,surname,e-mail\nAda,Lovelace,ada@example.com\n", "", "given name", "e-mail"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			preview, err := svc.Preview([]byte(tc.raw))
+			if err != nil {
+				t.Fatalf("Preview failed: %v", err)
+			}
+			if preview.Preset != tc.preset {
+				t.Errorf("expected preset %q, got %q", tc.preset, preview.Preset)
+			}
+			if preview.Mapping.FirstName != tc.firstName || preview.Mapping.Email != tc.email {
+				t.Errorf("unexpected mapping %+v", preview.Mapping)
+			}
+			if preview.RowCount != 1 || len(preview.SampleRows) != 1 || preview.SampleRows[0][0] != "Ada" {
+				t.Errorf("expected one sample row, got %d rows %v", preview.RowCount, preview.SampleRows)
+			}
+		})
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Dry run and duplicate handling
+// ---------------------------------------------------------------------------
+
+func TestCSVImport_DryRunReportsRowsAndDuplicates(t *testing.T) {
+	svc, db, vaultID, userID := setupCSVImportTest(t)
+	m := dto.CSVColumnMapping{FirstName: "first_name", Email: "email", Phone: "phone", Birthday: "birthday"}
+	if _, err := svc.Import(vaultID, userID, csvData([]string{"first_name", "email"}, []string{"Alice", "alice@example.com"}), m, dto.CSVImportOptions{}); err != nil {
+		t.Fatalf("seed import failed: %v", err)
+	}
+	var before int64
+	db.Model(&models.Contact{}).Where("vault_id = ?", vaultID).Count(&before)
+
+	data := csvData(
+		[]string{"first_name", "email", "phone", "birthday"},
+		[]string{"Alicia", "ALICE@example.com", "", ""},
+		[]string{"Bob", "", "+1 555 0100 222", "not-a-date"},
+		[]string{"Bobby", "", "15550100-222", ""},
+		[]string{"", "nobody@example.com", "", ""},
+	)
+	resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{DryRun: true, OnDuplicate: CSVDuplicateSkip})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if !resp.DryRun || resp.ImportedContacts != 1 || resp.SkippedCount != 3 || len(resp.Rows) != 4 {
+		t.Fatalf("unexpected dry-run summary %+v", resp)
+	}
+	alice, bob, bobby, blank := resp.Rows[0], resp.Rows[1], resp.Rows[2], resp.Rows[3]
+	if alice.Action != CSVDuplicateSkip || alice.DuplicateOf == "" || alice.MatchedBy != "email" {
+		t.Errorf("expected Alicia to match the existing contact by email, got %+v", alice)
+	}
+	if bob.Action != CSVDuplicateCreate || len(bob.Errors) != 1 {
+		t.Errorf("expected Bob to be created with a birthday warning, got %+v", bob)
+	}
+	if bobby.Action != CSVDuplicateSkip || bobby.DuplicateOfRow != 3 || bobby.MatchedBy != "phone" {
+		t.Errorf("expected Bobby to match Bob's row by phone, got %+v", bobby)
+	}
+	if blank.Action != CSVDuplicateSkip || len(blank.Errors) != 1 {
+		t.Errorf("expected the row without a first name to be skipped, got %+v", blank)
+	}
+
+	var after int64
+	db.Model(&models.Contact{}).Where("vault_id = ?", vaultID).Count(&after)
+	if after != before {
+		t.Errorf("expected a dry run to write nothing, contacts went from %d to %d", before, after)
+	}
+}
+
+func TestCSVImport_OnDuplicateUpdate(t *testing.T) {
+	svc, db, vaultID, userID := setupCSVImportTest(t)
+	m := dto.CSVColumnMapping{FirstName: "first_name", LastName: "last_name", Email: "email", Phone: "phone", Tags: "tags"}
+	if _, err := svc.Import(vaultID, userID, csvData([]string{"first_name", "email"}, []string{"Alice", "alice@example.com"}), m, dto.CSVImportOptions{}); err != nil {
+		t.Fatalf("seed import failed: %v", err)
+	}
+
+	data := csvData(
+		[]string{"first_name", "last_name", "email", "phone", "tags"},
+		[]string{"Alice", "Smith", "alice@example.com", "+1 555 0100", "Friends"},
+	)
+	resp, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{OnDuplicate: CSVDuplicateUpdate})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if resp.ImportedContacts != 0 || resp.UpdatedContacts != 1 {
+		t.Fatalf("expected one update and no new contact, got %+v", resp)
+	}
+
+	var contacts []models.Contact
+	db.Where("vault_id = ? AND first_name = ?", vaultID, "Alice").Find(&contacts)
+	if len(contacts) != 1 || contacts[0].LastName == nil || *contacts[0].LastName != "Smith" {
+		t.Fatalf("expected the existing contact to gain the last name, got %+v", contacts)
+	}
+	var infos int64
+	db.Model(&models.ContactInformation{}).Where("contact_id = ?", contacts[0].ID).Count(&infos)
+	if infos != 2 {
+		t.Errorf("expected the phone to be added next to the existing email, got %d entries", infos)
+	}
+	var labels int64
+	db.Model(&models.ContactLabel{}).Where("contact_id = ?", contacts[0].ID).Count(&labels)
+	if labels != 1 {
+		t.Errorf("expected the label to be added, got %d", labels)
+	}
+
+	if _, err := svc.Import(vaultID, userID, data, m, dto.CSVImportOptions{OnDuplicate: "merge"}); !errors.Is(err, ErrInvalidCSVDuplicateMode) {
+		t.Errorf("expected ErrInvalidCSVDuplicateMode, got %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// LinkedIn connections create jobs
+// ---------------------------------------------------------------------------
+
+func TestCSVImport_LinkedInCreatesJobs(t *testing.T) {
+	svc, db, vaultID, userID := setupCSVImportTest(t)
+	raw := "Notes:\n\"When exporting your connection data, some email addresses may be missing.\"\n\n" +
+		"First Name,Last Name,URL,Email Address,Company,Position,Connected On\n" +
+		"Ada,Lovelace,https://www.linkedin.com/in/ada,,Analytical Engines,Engineer,10 Dec 2020\n" +
+		"Charles,Babbage,https://www.linkedin.com/in/charles,,analytical engines,Inventor,11 Dec 2020\n"
+	preview, err := svc.Preview([]byte(raw))
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	resp, err := svc.Import(vaultID, userID, []byte(raw), preview.Mapping, dto.CSVImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if resp.ImportedContacts != 2 || len(resp.Errors) != 0 {
+		t.Fatalf("unexpected result %+v", resp)
+	}
+
+	var companies []models.Company
+	db.Where("vault_id = ?", vaultID).Find(&companies)
+	if len(companies) != 1 {
+		t.Fatalf("expected one shared company, got %d", len(companies))
+	}
+	var jobs []models.ContactCompany
+	db.Where("company_id = ?", companies[0].ID).Order("id").Find(&jobs)
+	if len(jobs) != 2 || jobs[0].JobPosition == nil || *jobs[0].JobPosition != "Engineer" {
+		t.Fatalf("expected a job per connection, got %+v", jobs)
+	}
+	var notes int64
+	db.Model(&models.Note{}).Where("vault_id = ?", vaultID).Count(&notes)
+	if notes != 0 {
+		t.Errorf("expected no company notes for LinkedIn rows, got %d", notes)
+	}
+	var profiles int64
+	db.Model(&models.ContactInformation{}).Where("data = ?", "https://www.linkedin.com/in/ada").Count(&profiles)
+	if profiles != 1 {
+		t.Errorf("expected the profile URL to be stored, got %d", profiles)
+	}
+}
+
+func TestCSVImport_MatchesPhoneNumbersInUserRegion(t *testing.T) {
+	svc, db, vaultID, userID := setupCSVImportTest(t)
+	if err := db.Model(&models.User{}).Where("id = ?", userID).Update("phone_region", "GB").Error; err != nil {
+		t.Fatalf("set phone region: %v", err)
+	}
+	m := dto.CSVColumnMapping{FirstName: "first_name", Phone: "phone"}
+	if _, err := svc.Import(vaultID, userID, csvData([]string{"first_name", "phone"}, []string{"Ada", "020 7946 0958"}), m, dto.CSVImportOptions{}); err != nil {
+		t.Fatalf("seed import failed: %v", err)
+	}
+	var info models.ContactInformation
+	db.Where("data = ?", "020 7946 0958").First(&info)
+	if info.NormalizedData == nil || *info.NormalizedData != "+442079460958" {
+		t.Fatalf("expected the imported phone to be normalized, got %v", info.NormalizedData)
+	}
+
+	resp, err := svc.Import(vaultID, userID, csvData([]string{"first_name", "phone"}, []string{"Ada L.", "+44 (0)20 7946 0958"}), m, dto.CSVImportOptions{DryRun: true, OnDuplicate: CSVDuplicateSkip})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if len(resp.Rows) != 1 || resp.Rows[0].MatchedBy != "phone" {
+		t.Errorf("expected the row to match the existing contact by phone, got %+v", resp.Rows)
+	}
+}
@@ -453,6 +453,9 @@ func (s *DavSyncService) processDeletedPaths(
 			result.Errors++
 			continue
 		}
+		if err := RecordContactDeletion(s.db, &contact); err != nil {
+			log.Printf("[vault-changes] failed to record the deletion of contact %s: %v", contactID, err)
+		}
 		s.logSyncAction(subID, &contactID, ptrToStr(contact.DistantURI), "", "deleted", "")
 		result.Deleted++
 	}
@@ -0,0 +1,598 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/mailin"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/pkg/response"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmailInboxNotFound       = errors.New("email inbox not found")
+	ErrInboundMailNotConfigured = errors.New("inbound mail is not configured")
+	// ErrUnknownEmailInbox wraps mailin.ErrRejected, so the SMTP receiver
+	// refuses mail for addresses that no inbox answers to.
+	ErrUnknownEmailInbox = fmt.Errorf("%w: no inbox for this address", mailin.ErrRejected)
+)
+
+const (
+	emailInboxTokenBytes  = 16
+	emailSourceType       = "email"
+	maxEmailTitleLength   = 255
+	maxEmailBodyLength    = 20000
+	maxEmailSourceIDBytes = 191
+)
+
+// emailAttachmentTypes are the attachments kept as contact files, with the
+// file type they are stored as. Images must also look like images.
+var emailAttachmentTypes = map[string]string{
+	"image/jpeg":         "photo",
+	"image/png":          "photo",
+	"image/gif":          "photo",
+	"image/webp":         "photo",
+	"application/pdf":    "document",
+	"text/plain":         "document",
+	"application/msword": "document",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": "document",
+}
+
+// EmailIngestService logs mail sent to a user's inbox address against the
+// vault contacts whose email addresses appear in it.
+type EmailIngestService struct {
+	db            *gorm.DB
+	settings      *SystemSettingService
+	fileService   *VaultFileService
+	storageInfo   *StorageInfoService
+	feedRecorder  *FeedRecorder
+	searchService *SearchService
+	polling       sync.Mutex
+}
+
+func NewEmailIngestService(db *gorm.DB) *EmailIngestService {
+	return &EmailIngestService{db: db}
+}
+
+func (s *EmailIngestService) SetSystemSettings(settings *SystemSettingService) {
+	s.settings = settings
+}
+
+// SetFileService stores attachments as files of the matched contact.
+// Without it attachments are dropped.
+func (s *EmailIngestService) SetFileService(fileService *VaultFileService, storageInfo *StorageInfoService) {
+	s.fileService = fileService
+	s.storageInfo = storageInfo
+}
+
+func (s *EmailIngestService) SetFeedRecorder(fr *FeedRecorder) {
+	s.feedRecorder = fr
+}
+
+func (s *EmailIngestService) SetSearchService(ss *SearchService) {
+	s.searchService = ss
+}
+
+// GetInbox returns the user's inbox of the vault.
+func (s *EmailIngestService) GetInbox(vaultID, userID string) (*dto.EmailInboxResponse, error) {
+	inbox, err := s.findInbox(vaultID, userID)
+	if err != nil {
+		return nil, err
+	}
+	resp := s.toEmailInboxResponse(inbox)
+	return &resp, nil
+}
+
+// UpdateInbox sets the mode of the user's inbox, creating the inbox first
+// when the user has none in the vault yet.
+func (s *EmailIngestService) UpdateInbox(vaultID, userID string, req dto.UpdateEmailInboxRequest) (*dto.EmailInboxResponse, error) {
+	if s.inboundAddress() == "" {
+		return nil, ErrInboundMailNotConfigured
+	}
+	inbox, err := s.findInbox(vaultID, userID)
+	if errors.Is(err, ErrEmailInboxNotFound) {
+		token, err := newEmailInboxToken()
+		if err != nil {
+			return nil, err
+		}
+		inbox = &models.EmailInbox{UserID: userID, VaultID: vaultID, Token: token, Mode: req.Mode}
+		if err := s.db.Create(inbox).Error; err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	} else if err := s.db.Model(inbox).Update("mode", req.Mode).Error; err != nil {
+		return nil, err
+	}
+	resp := s.toEmailInboxResponse(inbox)
+	return &resp, nil
+}
+
+// RotateInbox gives the inbox a new address. Mail to the old one is
+// refused from then on.
+func (s *EmailIngestService) RotateInbox(vaultID, userID string) (*dto.EmailInboxResponse, error) {
+	inbox, err := s.findInbox(vaultID, userID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := newEmailInboxToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(inbox).Update("token", token).Error; err != nil {
+		return nil, err
+	}
+	resp := s.toEmailInboxResponse(inbox)
+	return &resp, nil
+}
+
+// DeleteInbox removes the inbox. The messages it logged are kept.
+func (s *EmailIngestService) DeleteInbox(vaultID, userID string) error {
+	inbox, err := s.findInbox(vaultID, userID)
+	if err != nil {
+		return err
+	}
+	return s.db.Delete(inbox).Error
+}
+
+// ListMessages returns the messages the user's inbox received, newest
+// first.
+func (s *EmailIngestService) ListMessages(vaultID, userID string, page, perPage int) ([]dto.IngestedEmailResponse, response.Meta, error) {
+	inbox, err := s.findInbox(vaultID, userID)
+	if err != nil {
+		return nil, response.Meta{}, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+	query := s.db.Model(&models.IngestedEmail{}).Where("inbox_id = ?", inbox.ID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, response.Meta{}, err
+	}
+	var messages []models.IngestedEmail
+	if err := query.Order("id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&messages).Error; err != nil {
+		return nil, response.Meta{}, err
+	}
+	result := make([]dto.IngestedEmailResponse, len(messages))
+	for i := range messages {
+		result[i] = toIngestedEmailResponse(&messages[i])
+	}
+	return result, response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: int(math.Ceil(float64(total) / float64(perPage))),
+	}, nil
+}
+
+// Accepts reports whether mail for the address reaches an inbox. The SMTP
+// receiver uses it to refuse everything else.
+func (s *EmailIngestService) Accepts(address string) bool {
+	return s.inboxForAddress(address) != nil
+}
+
+// Ingest logs a raw message. The inbox is found from the envelope
+// recipients, when the message came over SMTP, and else from the
+// Delivered-To, To and Cc headers. A message the vault already received is
+// not logged again.
+func (s *EmailIngestService) Ingest(raw []byte, recipients []string) (*dto.IngestedEmailResponse, error) {
+	msg, err := mailin.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", mailin.ErrRejected, err)
+	}
+	candidates := append(append([]string{}, recipients...), msg.DeliveredTo...)
+	for _, list := range [][]*mail.Address{msg.To, msg.Cc} {
+		for _, addr := range list {
+			candidates = append(candidates, addr.Address)
+		}
+	}
+	var inbox *models.EmailInbox
+	for _, candidate := range candidates {
+		if inbox = s.inboxForAddress(candidate); inbox != nil {
+			break
+		}
+	}
+	if inbox == nil {
+		return nil, ErrUnknownEmailInbox
+	}
+	var user models.User
+	if err := s.db.Model(&models.User{}).
+		Joins("JOIN user_vault ON user_vault.user_id = users.id").
+		Where("users.id = ? AND user_vault.vault_id = ? AND user_vault.permission <= ?", inbox.UserID, inbox.VaultID, models.PermissionEditor).
+		First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The user lost access to the vault, or may no longer edit it.
+			return nil, ErrUnknownEmailInbox
+		}
+		return nil, err
+	}
+
+	messageID := emailSourceID(msg.MessageID)
+	if existing, err := s.findIngested(inbox.VaultID, messageID); err != nil || existing != nil {
+		return existing, err
+	}
+
+	// The user's own and the inbox addresses are not contacts.
+	skip := map[string]bool{strings.ToLower(user.Email): true}
+	var addresses []string
+	for _, address := range msg.Addresses() {
+		if !skip[address] && s.inboxForAddress(address) == nil && !s.isInboundAddress(address) {
+			addresses = append(addresses, address)
+		}
+	}
+	contactIDs, primaryID, err := s.matchContacts(inbox.VaultID, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	happenedAt := msg.Date
+	if happenedAt.IsZero() || happenedAt.After(time.Now()) {
+		happenedAt = time.Now()
+	}
+	title := truncateRunes(msg.Subject, maxEmailTitleLength)
+	from := ""
+	if len(msg.From) > 0 {
+		from = strings.ToLower(msg.From[0].Address)
+	}
+	if title == "" {
+		title = from
+	}
+	body := truncateRunes(msg.Text, maxEmailBodyLength)
+
+	record := models.IngestedEmail{
+		VaultID:      inbox.VaultID,
+		MessageID:    messageID,
+		InboxID:      inbox.ID,
+		Subject:      title,
+		FromAddress:  from,
+		ContactCount: len(contactIDs),
+		Status:       models.IngestedEmailUnmatched,
+	}
+	var notes []models.Note
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if len(contactIDs) == 0 {
+			return tx.Create(&record).Error
+		}
+		record.Status = models.IngestedEmailLogged
+		typeID, err := systemActivityTypeID(tx, inbox.VaultID, "email")
+		if err != nil {
+			return err
+		}
+		if inbox.Mode == models.EmailInboxModeNote {
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+			for _, contactID := range contactIDs {
+				sourceType, sourceUUID := emailSourceType, messageID
+				note := models.Note{
+					ContactID:  contactID,
+					VaultID:    inbox.VaultID,
+					AuthorID:   &user.ID,
+					Title:      strPtrOrNil(title),
+					Body:       body,
+					SourceType: &sourceType,
+					SourceUUID: &sourceUUID,
+					HappenedAt: &happenedAt,
+				}
+				if note.Body == "" {
+					note.Body = title
+				}
+				if err := tx.Create(&note).Error; err != nil {
+					return err
+				}
+				if err := recordVaultChange(tx, inbox.VaultID, models.VaultChangeNote, note.ID, models.VaultChangeCreated); err != nil {
+					return err
+				}
+				notes = append(notes, note)
+			}
+		} else {
+			sourceType, sourceUUID := emailSourceType, messageID
+			day := time.Date(happenedAt.Year(), happenedAt.Month(), happenedAt.Day(), 0, 0, 0, 0, time.UTC)
+			event := models.Activity{
+				VaultID: inbox.VaultID, ActivityTypeID: typeID, Title: title, Description: strPtrOrNil(body),
+				StartDate: &day, StartPrecision: "day", EndStatus: "none", CalendarType: "gregorian",
+				SourceType: &sourceType, SourceUUID: &sourceUUID,
+			}
+			if err := tx.Create(&event).Error; err != nil {
+				return err
+			}
+			if err := recordVaultChange(tx, inbox.VaultID, models.VaultChangeActivity, event.ID, models.VaultChangeCreated); err != nil {
+				return err
+			}
+			if err := replaceActivityParticipants(tx, event.ID, contactIDs); err != nil {
+				return err
+			}
+			record.ActivityID = &event.ID
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+		}
+		return updateInteractionLastTalkedTo(tx, typeID, &happenedAt, contactIDs)
+	}); err != nil {
+		// Another delivery of the message may have won the race.
+		if existing, findErr := s.findIngested(inbox.VaultID, messageID); findErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	if s.feedRecorder != nil {
+		if record.ActivityID != nil {
+			entityType := "Activity"
+			for _, contactID := range contactIDs {
+				s.feedRecorder.Record(contactID, user.ID, ActionActivityCreated, "Logged an email", record.ActivityID, &entityType)
+			}
+		}
+		for i := range notes {
+			entityType := "Note"
+			s.feedRecorder.Record(notes[i].ContactID, user.ID, ActionNoteCreated, "Logged an email", &notes[i].ID, &entityType)
+		}
+	}
+	if s.searchService != nil {
+		for i := range notes {
+			s.searchService.IndexNote(&notes[i])
+		}
+	}
+	if primaryID != "" {
+		s.storeAttachments(inbox.VaultID, primaryID, user.ID, msg.Attachments)
+	}
+	resp := toIngestedEmailResponse(&record)
+	return &resp, nil
+}
+
+// PollIMAP ingests the unseen messages of the mailbox configured in the
+// inbound_mail.imap_* settings. It does nothing while no host is set.
+func (s *EmailIngestService) PollIMAP() (int, error) {
+	if s.settings == nil {
+		return 0, nil
+	}
+	host := s.settings.GetWithDefault("inbound_mail.imap_host", "")
+	if host == "" {
+		return 0, nil
+	}
+	if !s.polling.TryLock() {
+		return 0, nil
+	}
+	defer s.polling.Unlock()
+	password, _ := s.settings.Get("inbound_mail.imap_password")
+	cfg := mailin.IMAPConfig{
+		Host:     host,
+		Port:     s.settings.GetWithDefault("inbound_mail.imap_port", ""),
+		Username: s.settings.GetWithDefault("inbound_mail.imap_username", ""),
+		Password: password,
+		Security: s.settings.GetWithDefault("inbound_mail.imap_security", mailin.SecurityTLS),
+		Mailbox:  s.settings.GetWithDefault("inbound_mail.imap_mailbox", "INBOX"),
+	}
+	return mailin.FetchUnseen(cfg, func(raw []byte) error {
+		if _, err := s.Ingest(raw, nil); err != nil {
+			if errors.Is(err, mailin.ErrRejected) {
+				// Mail no inbox answers to is not a failure worth
+				// keeping for the user; mark it seen and move on.
+				log.Printf("[inbound_mail] skipped message: %v", err)
+				return nil
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *EmailIngestService) findInbox(vaultID, userID string) (*models.EmailInbox, error) {
+	var inbox models.EmailInbox
+	if err := s.db.Where("vault_id = ? AND user_id = ?", vaultID, userID).First(&inbox).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmailInboxNotFound
+		}
+		return nil, err
+	}
+	return &inbox, nil
+}
+
+func (s *EmailIngestService) findIngested(vaultID, messageID string) (*dto.IngestedEmailResponse, error) {
+	var existing models.IngestedEmail
+	err := s.db.Where("vault_id = ? AND message_id = ?", vaultID, messageID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp := toIngestedEmailResponse(&existing)
+	return &resp, nil
+}
+
+// matchContacts returns the vault contacts with one of the email addresses,
+// and the one attachments go to: the sender if it is a contact, else the
+// first recipient that is.
+func (s *EmailIngestService) matchContacts(vaultID string, addresses []string) ([]string, string, error) {
+	if len(addresses) == 0 {
+		return nil, "", nil
+	}
+	var rows []struct {
+		ContactID string
+		Data      string
+	}
+	if err := s.db.Table("contact_information").
+		Select("contact_information.contact_id, contact_information.data").
+		Joins("JOIN contacts ON contacts.id = contact_information.contact_id").
+		Joins("JOIN contact_information_types ON contact_information_types.id = contact_information.type_id").
+		Where("contacts.vault_id = ? AND contacts.deleted_at IS NULL AND contact_information_types.type = ? AND LOWER(TRIM(contact_information.data)) IN ?",
+			vaultID, "email", addresses).
+		Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+	byAddress := map[string]string{}
+	seen := map[string]bool{}
+	var contactIDs []string
+	for _, row := range rows {
+		address := strings.ToLower(strings.TrimSpace(row.Data))
+		if _, ok := byAddress[address]; !ok {
+			byAddress[address] = row.ContactID
+		}
+		if !seen[row.ContactID] {
+			seen[row.ContactID] = true
+			contactIDs = append(contactIDs, row.ContactID)
+		}
+	}
+	sort.Strings(contactIDs)
+	primaryID := ""
+	for _, address := range addresses {
+		if id, ok := byAddress[address]; ok {
+			primaryID = id
+			break
+		}
+	}
+	return contactIDs, primaryID, nil
+}
+
+// storeAttachments keeps the supported attachments as files of the
+// contact, within the upload size limit and the account's storage quota.
+func (s *EmailIngestService) storeAttachments(vaultID, contactID, userID string, attachments []mailin.Attachment) {
+	if s.fileService == nil || len(attachments) == 0 {
+		return
+	}
+	var maxSize int64 = 10 * 1024 * 1024
+	if s.settings != nil {
+		if mb := s.settings.GetInt64("storage.max_size_mb", 0); mb > 0 {
+			maxSize = mb * 1024 * 1024
+		}
+	}
+	var vault models.Vault
+	if err := s.db.Select("account_id").First(&vault, "id = ?", vaultID).Error; err != nil {
+		return
+	}
+	for _, attachment := range attachments {
+		mimeType := strings.ToLower(attachment.ContentType)
+		fileType, ok := emailAttachmentTypes[mimeType]
+		size := int64(len(attachment.Data))
+		if !ok || size == 0 || size > maxSize {
+			continue
+		}
+		if fileType == "photo" && http.DetectContentType(attachment.Data) != mimeType {
+			continue
+		}
+		if s.storageInfo != nil {
+			if info, err := s.storageInfo.Get(vault.AccountID); err == nil && info.LimitBytes > 0 && info.UsedBytes+size > info.LimitBytes {
+				log.Printf("[inbound_mail] dropped attachment %q: storage quota exceeded", attachment.Filename)
+				return
+			}
+		}
+		if _, err := s.fileService.Upload(vaultID, contactID, userID, fileType, attachment.Filename, mimeType, size, bytes.NewReader(attachment.Data)); err != nil {
+			log.Printf("[inbound_mail] failed to store attachment %q: %v", attachment.Filename, err)
+		}
+	}
+}
+
+// inboxForAddress finds the inbox of an address such as
+// "bonds+<token>@mail.example.com". A bare "<token>@..." is accepted too,
+// for domains that route every local part to Bonds.
+func (s *EmailIngestService) inboxForAddress(address string) *models.EmailInbox {
+	local, _, ok := strings.Cut(strings.TrimSpace(address), "@")
+	if !ok {
+		return nil
+	}
+	if i := strings.LastIndexByte(local, '+'); i >= 0 {
+		local = local[i+1:]
+	}
+	token := strings.ToLower(local)
+	if len(token) != 2*emailInboxTokenBytes {
+		return nil
+	}
+	if _, err := hex.DecodeString(token); err != nil {
+		return nil
+	}
+	var inbox models.EmailInbox
+	if err := s.db.Where("token = ?", token).First(&inbox).Error; err != nil {
+		return nil
+	}
+	return &inbox
+}
+
+func (s *EmailIngestService) inboundAddress() string {
+	if s.settings == nil {
+		return ""
+	}
+	address := strings.TrimSpace(s.settings.GetWithDefault("inbound_mail.address", ""))
+	if !strings.Contains(address, "@") {
+		return ""
+	}
+	return address
+}
+
+func (s *EmailIngestService) isInboundAddress(address string) bool {
+	return address != "" && strings.EqualFold(address, s.inboundAddress())
+}
+
+func (s *EmailIngestService) toEmailInboxResponse(inbox *models.EmailInbox) dto.EmailInboxResponse {
+	address := ""
+	if base := s.inboundAddress(); base != "" {
+		local, domain, _ := strings.Cut(base, "@")
+		address = local + "+" + inbox.Token + "@" + domain
+	}
+	return dto.EmailInboxResponse{
+		ID:        inbox.ID,
+		VaultID:   inbox.VaultID,
+		Address:   address,
+		Mode:      inbox.Mode,
+		CreatedAt: inbox.CreatedAt,
+		UpdatedAt: inbox.UpdatedAt,
+	}
+}
+
+func toIngestedEmailResponse(e *models.IngestedEmail) dto.IngestedEmailResponse {
+	return dto.IngestedEmailResponse{
+		ID:           e.ID,
+		MessageID:    e.MessageID,
+		Subject:      e.Subject,
+		FromAddress:  e.FromAddress,
+		ContactCount: e.ContactCount,
+		ActivityID:   e.ActivityID,
+		Status:       e.Status,
+		CreatedAt:    e.CreatedAt,
+	}
+}
+
+// emailSourceID keys a message in the vault. Message-IDs too long for the
+// source index are hashed.
+func emailSourceID(messageID string) string {
+	if len(messageID) <= maxEmailSourceIDBytes {
+		return messageID
+	}
+	sum := sha256.Sum256([]byte(messageID))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func newEmailInboxToken() (string, error) {
+	b := make([]byte, emailInboxTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func truncateRunes(s string, limit int) string {
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:limit]))
+}
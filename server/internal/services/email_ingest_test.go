@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/mailin"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+type emailIngestFixture struct {
+	db      *gorm.DB
+	svc     *EmailIngestService
+	vaultID string
+	userID  string
+	johnID  string
+	janeID  string
+}
+
+func setupEmailIngestTest(t *testing.T) emailIngestFixture {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	authSvc := NewAuthService(db, testutil.TestJWTConfig())
+	resp, err := authSvc.Register(dto.RegisterRequest{
+		FirstName: "Test",
+		LastName:  "User",
+		Email:     "ingest-test@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	vault, err := NewVaultService(db).CreateVault(resp.User.AccountID, resp.User.ID, dto.CreateVaultRequest{Name: "Test Vault"}, "en")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+
+	var emailType models.ContactInformationType
+	if err := db.Where("account_id = ? AND type = ?", resp.User.AccountID, "email").First(&emailType).Error; err != nil {
+		t.Fatalf("find email type: %v", err)
+	}
+	contactSvc := NewContactService(db)
+	infoSvc := NewContactInformationService(db)
+	ids := map[string]string{}
+	for name, address := range map[string]string{"John": "John@Example.com", "Jane": "jane@example.com"} {
+		contact, err := contactSvc.CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: name})
+		if err != nil {
+			t.Fatalf("CreateContact failed: %v", err)
+		}
+		if _, err := infoSvc.Create(contact.ID, vault.ID, resp.User.ID, dto.CreateContactInformationRequest{TypeID: emailType.ID, Data: address}); err != nil {
+			t.Fatalf("Create contact information failed: %v", err)
+		}
+		ids[name] = contact.ID
+	}
+
+	settings := NewSystemSettingService(db)
+	if err := settings.Set("inbound_mail.address", "bonds@mail.example.com"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	svc := NewEmailIngestService(db)
+	svc.SetSystemSettings(settings)
+	svc.SetFileService(NewVaultFileService(db, t.TempDir()), NewStorageInfoService(db, settings))
+	return emailIngestFixture{db: db, svc: svc, vaultID: vault.ID, userID: resp.User.ID, johnID: ids["John"], janeID: ids["Jane"]}
+}
+
+func testEmail(messageID, to, cc, subject string) []byte {
+	return []byte("Message-ID: <" + messageID + ">\r\n" +
+		"From: Test User <ingest-test@example.com>\r\n" +
+		"To: " + to + "\r\n" +
+		"Cc: " + cc + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Date: Mon, 05 Oct 2026 10:30:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=b\r\n" +
+		"\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"Lunch on Friday?\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/plain; name=\"menu.txt\"\r\n" +
+		"Content-Disposition: attachment; filename=\"menu.txt\"\r\n" +
+		"\r\n" +
+		"Soup\r\n" +
+		"--b\r\n" +
+		"Content-Type: application/x-msdownload; name=\"setup.exe\"\r\n" +
+		"Content-Disposition: attachment; filename=\"setup.exe\"\r\n" +
+		"\r\n" +
+		"MZ\r\n" +
+		"--b--\r\n")
+}
+
+func TestEmailInboxRequiresInboundAddress(t *testing.T) {
+	f := setupEmailIngestTest(t)
+	if err := NewSystemSettingService(f.db).Set("inbound_mail.address", ""); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	_, err := f.svc.UpdateInbox(f.vaultID, f.userID, dto.UpdateEmailInboxRequest{Mode: models.EmailInboxModeActivity})
+	if !errors.Is(err, ErrInboundMailNotConfigured) {
+		t.Errorf("Expected ErrInboundMailNotConfigured, got %v", err)
+	}
+	if _, err := f.svc.GetInbox(f.vaultID, f.userID); !errors.Is(err, ErrEmailInboxNotFound) {
+		t.Errorf("Expected ErrEmailInboxNotFound, got %v", err)
+	}
+}
+
+func TestIngestEmailLogsActivity(t *testing.T) {
+	f := setupEmailIngestTest(t)
+	inbox, err := f.svc.UpdateInbox(f.vaultID, f.userID, dto.UpdateEmailInboxRequest{Mode: models.EmailInboxModeActivity})
+	if err != nil {
+		t.Fatalf("UpdateInbox failed: %v", err)
+	}
+	if !strings.HasPrefix(inbox.Address, "bonds+") || !strings.HasSuffix(inbox.Address, "@mail.example.com") {
+		t.Fatalf("Unexpected inbox address %q", inbox.Address)
+	}
+	if !f.svc.Accepts(inbox.Address) {
+		t.Error("Expected the inbox address to be accepted")
+	}
+
+	raw := testEmail("lunch@example.com", "John <JOHN@example.com>, stranger@example.com", "jane@example.com, "+inbox.Address, "Lunch")
+	got, err := f.svc.Ingest(raw, nil)
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	if got.Status != models.IngestedEmailLogged || got.ContactCount != 2 || got.ActivityID == nil {
+		t.Fatalf("Unexpected result %+v", got)
+	}
+
+	var activity models.Activity
+	if err := f.db.First(&activity, *got.ActivityID).Error; err != nil {
+		t.Fatalf("find activity: %v", err)
+	}
+	if activity.Title != "Lunch" || activity.ActivityTypeID == nil {
+		t.Errorf("Unexpected activity %+v", activity)
+	}
+	var typeKind string
+	f.db.Model(&models.ActivityType{}).Where("id = ?", *activity.ActivityTypeID).Pluck("system_kind", &typeKind)
+	if typeKind != "email" {
+		t.Errorf("Expected the email activity type, got %q", typeKind)
+	}
+	var participants int64
+	f.db.Model(&models.ActivityParticipant{}).Where("activity_id = ?", activity.ID).Count(&participants)
+	if participants != 2 {
+		t.Errorf("Expected 2 participants, got %d", participants)
+	}
+	var john models.Contact
+	f.db.First(&john, "id = ?", f.johnID)
+	if john.LastTalkedTo == nil || john.LastTalkedTo.Format("2006-01-02") != "2026-10-05" {
+		t.Errorf("Expected last_talked_to to be the mail date, got %v", john.LastTalkedTo)
+	}
+
+	// Only the supported attachment is kept, with the first recipient.
+	var files []models.File
+	f.db.Where("vault_id = ?", f.vaultID).Find(&files)
+	if len(files) != 1 || files[0].Name != "menu.txt" || files[0].UfileableID == nil || *files[0].UfileableID != f.johnID {
+		t.Errorf("Expected menu.txt stored with John, got %+v", files)
+	}
+
+	again, err := f.svc.Ingest(raw, nil)
+	if err != nil {
+		t.Fatalf("second Ingest failed: %v", err)
+	}
+	if again.ID != got.ID {
+		t.Errorf("Expected the duplicate to return record %d, got %d", got.ID, again.ID)
+	}
+	var activities int64
+	f.db.Model(&models.Activity{}).Where("vault_id = ?", f.vaultID).Count(&activities)
+	if activities != 1 {
+		t.Errorf("Expected 1 activity after a duplicate delivery, got %d", activities)
+	}
+}
+
+func TestIngestEmailNoteMode(t *testing.T) {
+	f := setupEmailIngestTest(t)
+	inbox, err := f.svc.UpdateInbox(f.vaultID, f.userID, dto.UpdateEmailInboxRequest{Mode: models.EmailInboxModeNote})
+	if err != nil {
+		t.Fatalf("UpdateInbox failed: %v", err)
+	}
+	got, err := f.svc.Ingest(testEmail("notes@example.com", "john@example.com", "jane@example.com", "Catch-up"), []string{inbox.Address})
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	if got.ActivityID != nil || got.ContactCount != 2 {
+		t.Errorf("Unexpected result %+v", got)
+	}
+	var notes []models.Note
+	f.db.Where("vault_id = ?", f.vaultID).Order("contact_id").Find(&notes)
+	if len(notes) != 2 {
+		t.Fatalf("Expected 2 notes, got %d", len(notes))
+	}
+	for _, note := range notes {
+		if note.Title == nil || *note.Title != "Catch-up" || !strings.Contains(note.Body, "Lunch on Friday?") {
+			t.Errorf("Unexpected note %+v", note)
+		}
+		if note.SourceType == nil || *note.SourceType != emailSourceType {
+			t.Errorf("Expected an email source, got %v", note.SourceType)
+		}
+	}
+}
+
+func TestIngestEmailUnmatchedAndUnknownInbox(t *testing.T) {
+	f := setupEmailIngestTest(t)
+	inbox, err := f.svc.UpdateInbox(f.vaultID, f.userID, dto.UpdateEmailInboxRequest{Mode: models.EmailInboxModeActivity})
+	if err != nil {
+		t.Fatalf("UpdateInbox failed: %v", err)
+	}
+	got, err := f.svc.Ingest(testEmail("nobody@example.com", "stranger@example.com", inbox.Address, "Newsletter"), nil)
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	if got.Status != models.IngestedEmailUnmatched || got.ContactCount != 0 {
+		t.Errorf("Expected an unmatched record, got %+v", got)
+	}
+	messages, meta, err := f.svc.ListMessages(f.vaultID, f.userID, 1, 20)
+	if err != nil {
+		t.Fatalf("ListMessages failed: %v", err)
+	}
+	if len(messages) != 1 || meta.Total != 1 || messages[0].Subject != "Newsletter" {
+		t.Errorf("Unexpected messages %+v", messages)
+	}
+
+	rotated, err := f.svc.RotateInbox(f.vaultID, f.userID)
+	if err != nil {
+		t.Fatalf("RotateInbox failed: %v", err)
+	}
+	if rotated.Address == inbox.Address {
+		t.Fatal("Expected a new address after rotating")
+	}
+	_, err = f.svc.Ingest(testEmail("late@example.com", "john@example.com", inbox.Address, "Late"), nil)
+	if !errors.Is(err, ErrUnknownEmailInbox) || !errors.Is(err, mailin.ErrRejected) {
+		t.Errorf("Expected the old address to be rejected, got %v", err)
+	}
+
+	// A viewer cannot log into the vault any more.
+	f.db.Model(&models.UserVault{}).Where("user_id = ? AND vault_id = ?", f.userID, f.vaultID).Update("permission", models.PermissionViewer)
+	_, err = f.svc.Ingest(testEmail("viewer@example.com", "john@example.com", rotated.Address, "Hi"), nil)
+	if !errors.Is(err, mailin.ErrRejected) {
+		t.Errorf("Expected a viewer's inbox to reject mail, got %v", err)
+	}
+
+	if err := f.svc.DeleteInbox(f.vaultID, f.userID); err != nil {
+		t.Fatalf("DeleteInbox failed: %v", err)
+	}
+	if f.svc.Accepts(rotated.Address) {
+		t.Error("Expected a deleted inbox to be refused")
+	}
+}
+
+func TestEmailSourceIDBoundsLongIDs(t *testing.T) {
+	short := "abc@example.com"
+	if emailSourceID(short) != short {
+		t.Errorf("Expected short IDs unchanged")
+	}
+	long := emailSourceID(strings.Repeat("x", 300))
+	if len(long) > 191 || long == emailSourceID(strings.Repeat("x", 299)+"y") {
+		t.Errorf("Unexpected source ID %q", long)
+	}
+}
@@ -61,6 +61,36 @@ func (s *FeedService) GetFeed(vaultID string, page, perPage int, userID string)
 	return result, meta, nil
 }
 
+// vaultFeedEntry is a feed item with the Seq the vault stream follows.
+type vaultFeedEntry struct {
+	seq  uint
+	item dto.FeedItemResponse
+}
+
+// listVaultFeedSince returns up to limit feed items of the vault written
+// after the item with Seq afterSeq, oldest first.
+func (s *FeedService) listVaultFeedSince(vaultID, userID string, afterSeq uint, limit int) ([]vaultFeedEntry, error) {
+	var items []models.ContactFeedItem
+	if err := s.db.Where("vault_id = ? AND seq > ?", vaultID, afterSeq).Order("seq ASC").Limit(limit).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	formatter, err := newContactNameFormatter(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]vaultFeedEntry, len(items))
+	for i, item := range items {
+		result[i].seq = item.Seq
+		if result[i].item, err = s.projectFeedItem(item, formatter); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
 func (s *FeedService) ListContactFeed(contactID, vaultID string, page, perPage int, userID string) ([]dto.FeedItemResponse, response.Meta, error) {
 	if page < 1 {
 		page = 1
@@ -9,14 +9,16 @@ import (
 )
 
 var feedSourceModules = map[string]string{
-	"Note":            "notes",
-	"ContactReminder": "reminders",
-	"Call":            "calls",
-	"ContactTask":     "tasks",
-	"Address":         "addresses",
-	"Activity":        "activities",
-	"Loan":            "loans",
-	"Relationship":    "relationships",
+	"Note":                 "notes",
+	"ContactReminder":      "reminders",
+	"Call":                 "calls",
+	"ContactTask":          "tasks",
+	"Address":              "addresses",
+	"Activity":             "activities",
+	"Loan":                 "loans",
+	"Relationship":         "relationships",
+	"ContactLabel":         "labels",
+	"ContactImportantDate": "important_dates",
 }
 
 func (s *FeedService) sourceAvailable(item models.ContactFeedItem) (*dto.FeedSourceResponse, error) {
@@ -46,6 +48,10 @@ func (s *FeedService) sourceAvailable(item models.ContactFeedItem) (*dto.FeedSou
 		query = query.Model(&models.Loan{}).Joins("JOIN contact_loan ON contact_loan.loan_id = loans.id").Where("loans.id = ? AND loans.vault_id = ? AND (contact_loan.loaner_id = ? OR contact_loan.loanee_id = ?)", source.ID, item.VaultID, item.ContactID, item.ContactID)
 	case "Relationship":
 		query = query.Model(&models.Relationship{}).Joins("JOIN contacts ON contacts.id = relationships.contact_id").Where("relationships.id = ? AND relationships.contact_id = ? AND contacts.vault_id = ?", source.ID, item.ContactID, item.VaultID)
+	case "ContactLabel":
+		query = query.Model(&models.ContactLabel{}).Joins("JOIN contacts ON contacts.id = contact_label.contact_id").Where("contact_label.id = ? AND contact_label.contact_id = ? AND contacts.vault_id = ?", source.ID, item.ContactID, item.VaultID)
+	case "ContactImportantDate":
+		query = query.Model(&models.ContactImportantDate{}).Joins("JOIN contacts ON contacts.id = contact_important_dates.contact_id").Where("contact_important_dates.id = ? AND contact_important_dates.contact_id = ? AND contacts.vault_id = ?", source.ID, item.ContactID, item.VaultID)
 	default:
 		return source, nil
 	}
@@ -10,31 +10,53 @@ import (
 
 // Feed action constants
 const (
-	ActionContactCreated    = "contact_created"
-	ActionContactUpdated    = "contact_updated"
-	ActionContactDeleted    = "contact_deleted"
-	ActionNoteCreated       = "note_created"
-	ActionNoteUpdated       = "note_updated"
-	ActionNoteDeleted       = "note_deleted"
-	ActionReminderCreated   = "reminder_created"
-	ActionCallLogged        = "call_logged"
-	ActionTaskCreated       = "task_created"
-	ActionTaskCompleted     = "task_completed"
-	ActionAddressAdded      = "address_added"
-	ActionActivityCreated   = "activity_created"
-	ActionFileUploaded      = "file_uploaded"
-	ActionLoanCreated       = "loan_created"
-	ActionRelationshipAdded = "relationship_added"
+	ActionContactCreated     = "contact_created"
+	ActionContactUpdated     = "contact_updated"
+	ActionContactDeleted     = "contact_deleted"
+	ActionNoteCreated        = "note_created"
+	ActionNoteUpdated        = "note_updated"
+	ActionNoteDeleted        = "note_deleted"
+	ActionReminderCreated    = "reminder_created"
+	ActionCallLogged         = "call_logged"
+	ActionTaskCreated        = "task_created"
+	ActionTaskCompleted      = "task_completed"
+	ActionAddressAdded       = "address_added"
+	ActionActivityCreated    = "activity_created"
+	ActionFileUploaded       = "file_uploaded"
+	ActionLoanCreated        = "loan_created"
+	ActionRelationshipAdded  = "relationship_added"
+	ActionLabelAdded         = "label_added"
+	ActionImportantDateAdded = "important_date_added"
 )
 
 type FeedRecorder struct {
-	db *gorm.DB
+	db         *gorm.DB
+	automation *AutomationService
+	// chain is set on the recorders of rule actions, so the rules their
+	// feed items trigger know how they came about.
+	chain *automationChain
+	// effects is set on the recorders of a transaction; see withTx.
+	effects *txEffects
 }
 
 func NewFeedRecorder(db *gorm.DB) *FeedRecorder {
 	return &FeedRecorder{db: db}
 }
 
+// SetAutomation queues the vault's automation rules for every recorded item.
+func (r *FeedRecorder) SetAutomation(automation *AutomationService) {
+	r.automation = automation
+}
+
+// withTx returns a recorder that writes to tx. The stream notification and
+// the run of the automation queue wait until tx has committed.
+func (r *FeedRecorder) withTx(tx *gorm.DB, effects *txEffects) *FeedRecorder {
+	if r == nil {
+		return nil
+	}
+	return &FeedRecorder{db: tx, automation: r.automation, chain: r.chain, effects: effects}
+}
+
 // Record creates a ContactFeedItem. feedableID/feedableType are optional (for polymorphic reference).
 func (r *FeedRecorder) Record(contactID, authorID, action string, description string, feedableID *uint, feedableType *string) error {
 	if err := validateFeedActionSource(action, feedableID, feedableType); err != nil {
@@ -58,9 +80,29 @@ func (r *FeedRecorder) Record(contactID, authorID, action string, description st
 	if description != "" {
 		item.Description = &description
 	}
-	if err := r.db.Create(&item).Error; err != nil {
+	if err := r.db.Transaction(func(tx *gorm.DB) error {
+		seq, err := models.ReserveVaultChangeSeqs(tx, contact.VaultID, 1)
+		if err != nil {
+			return err
+		}
+		item.Seq = seq
+		return tx.Create(&item).Error
+	}); err != nil {
 		return fmt.Errorf("create feed item for contact %s: %w", contactID, err)
 	}
+	queued := false
+	if r.automation != nil {
+		var err error
+		if queued, err = r.automation.enqueue(r.db, &item, r.chain); err != nil {
+			return fmt.Errorf("queue automation for contact %s: %w", contactID, err)
+		}
+	}
+	r.effects.after(func() {
+		notifyVaultStreams(contact.VaultID)
+		if queued {
+			r.automation.wake()
+		}
+	})
 	return nil
 }
 
@@ -75,18 +117,20 @@ func validateFeedActionSource(action string, feedableID *uint, feedableType *str
 		return fmt.Errorf("action %s requires a source", action)
 	}
 	expectedKinds := map[string]string{
-		ActionNoteCreated:       "Note",
-		ActionNoteUpdated:       "Note",
-		ActionNoteDeleted:       "Note",
-		ActionReminderCreated:   "ContactReminder",
-		ActionCallLogged:        "Call",
-		ActionTaskCreated:       "ContactTask",
-		ActionTaskCompleted:     "ContactTask",
-		ActionAddressAdded:      "Address",
-		ActionActivityCreated:   "Activity",
-		ActionFileUploaded:      "File",
-		ActionLoanCreated:       "Loan",
-		ActionRelationshipAdded: "Relationship",
+		ActionNoteCreated:        "Note",
+		ActionNoteUpdated:        "Note",
+		ActionNoteDeleted:        "Note",
+		ActionReminderCreated:    "ContactReminder",
+		ActionCallLogged:         "Call",
+		ActionTaskCreated:        "ContactTask",
+		ActionTaskCompleted:      "ContactTask",
+		ActionAddressAdded:       "Address",
+		ActionActivityCreated:    "Activity",
+		ActionFileUploaded:       "File",
+		ActionLoanCreated:        "Loan",
+		ActionRelationshipAdded:  "Relationship",
+		ActionLabelAdded:         "ContactLabel",
+		ActionImportantDateAdded: "ContactImportantDate",
 	}
 	expectedKind, ok := expectedKinds[action]
 	if !ok {
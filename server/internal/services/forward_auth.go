@@ -0,0 +1,191 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/naiba/bonds/internal/config"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+// forwardAuthDriver is the UserToken driver linking a user to the username
+// the reverse proxy reports, so renaming the email in the IdP keeps the user.
+const forwardAuthDriver = "forward_auth"
+
+// ForwardAuthService resolves users vouched for by a trusted reverse proxy
+// and provisions the ones seen for the first time.
+type ForwardAuthService struct {
+	db  *gorm.DB
+	cfg config.ForwardAuthConfig
+	// provisionMu serialises first sign-ins: a freshly loaded SPA fires
+	// several requests at once, which must not create the user twice.
+	provisionMu sync.Mutex
+}
+
+func NewForwardAuthService(db *gorm.DB, cfg config.ForwardAuthConfig) *ForwardAuthService {
+	return &ForwardAuthService{db: db, cfg: cfg}
+}
+
+var _ middleware.ForwardAuthProvisioner = (*ForwardAuthService)(nil)
+
+// ForwardAuthUser returns the user for the identity, creating it when
+// auto-provisioning is enabled. Users of the shared account have their
+// administrator flag synced to the admin group on every request, except
+// that the last administrator of the account is never demoted.
+func (s *ForwardAuthService) ForwardAuthUser(identity middleware.ForwardAuthIdentity) (*models.User, error) {
+	// Proxies set up to use the email as the username often send no separate
+	// email header.
+	if identity.Email == "" && strings.Contains(identity.Username, "@") {
+		identity.Email = identity.Username
+	}
+	user, err := s.findUser(identity)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !s.cfg.AutoProvision {
+			return nil, middleware.ErrForwardAuthUserNotProvisioned
+		}
+		s.provisionMu.Lock()
+		defer s.provisionMu.Unlock()
+		user, err = s.findUser(identity)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			user, err = s.provision(identity)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.managesAdminFlag(user) {
+		isAdmin := s.inAdminGroup(identity)
+		last := false
+		if user.IsAccountAdministrator && !isAdmin {
+			// A proxy that stops sending the group must not leave the
+			// account without an administrator.
+			if last, err = lastAccountAdministrator(s.db, user); err != nil {
+				return nil, err
+			}
+		}
+		if user.IsAccountAdministrator != isAdmin && !last {
+			if err := s.db.Model(user).Update("is_account_administrator", isAdmin).Error; err != nil {
+				return nil, err
+			}
+			user.IsAccountAdministrator = isAdmin
+		}
+	}
+	return user, nil
+}
+
+// findUser looks the user up by proxy username, then by email. A user found
+// by email is only linked to the username when LinkByEmail is set, so that
+// anyone able to pick their email in the IdP cannot take over an account;
+// later lookups then no longer depend on the email.
+func (s *ForwardAuthService) findUser(identity middleware.ForwardAuthIdentity) (*models.User, error) {
+	var user models.User
+	var token models.UserToken
+	err := s.db.Where("driver = ? AND driver_id = ?", forwardAuthDriver, identity.Username).First(&token).Error
+	if err == nil {
+		if err := s.db.First(&user, "id = ?", token.UserID).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if identity.Email == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	if err := s.db.Where("email = ?", identity.Email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	if !s.cfg.LinkByEmail {
+		return nil, middleware.ErrForwardAuthEmailInUse
+	}
+	if err := s.link(s.db, &user, identity); err != nil {
+		return nil, err
+	}
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := s.db.Model(&user).Update("email_verified_at", now).Error; err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+	return &user, nil
+}
+
+func (s *ForwardAuthService) link(tx *gorm.DB, user *models.User, identity middleware.ForwardAuthIdentity) error {
+	return tx.Create(&models.UserToken{
+		UserID:   user.ID,
+		Driver:   forwardAuthDriver,
+		DriverID: identity.Username,
+		Format:   "header",
+		Email:    &user.Email,
+	}).Error
+}
+
+// provision creates the user in the configured shared account, or in a new
+// account of their own.
+func (s *ForwardAuthService) provision(identity middleware.ForwardAuthIdentity) (*models.User, error) {
+	if identity.Email == "" {
+		return nil, middleware.ErrForwardAuthMissingEmail
+	}
+	locale := identity.Locale
+	if locale == "" {
+		locale = "en"
+	}
+
+	now := time.Now()
+	firstName, lastName := splitForwardAuthName(identity)
+	user := models.User{
+		FirstName:              &firstName,
+		LastName:               strPtrOrNil(lastName),
+		Email:                  identity.Email,
+		Locale:                 locale,
+		EmailVerifiedAt:        &now,
+		IsAccountAdministrator: s.inAdminGroup(identity),
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := createDirectoryUser(tx, &user, s.cfg.AccountID); err != nil {
+			return err
+		}
+		return s.link(tx, &user, identity)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// managesAdminFlag reports whether the admin group decides the user's
+// administrator flag. Users with an account of their own always administer
+// it.
+func (s *ForwardAuthService) managesAdminFlag(user *models.User) bool {
+	return s.cfg.AdminGroup != "" && s.cfg.AccountID != "" && user.AccountID == s.cfg.AccountID
+}
+
+func (s *ForwardAuthService) inAdminGroup(identity middleware.ForwardAuthIdentity) bool {
+	for _, group := range identity.Groups {
+		if group == s.cfg.AdminGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// splitForwardAuthName splits the display name, falling back to the
+// username when the proxy sends no name.
+func splitForwardAuthName(identity middleware.ForwardAuthIdentity) (string, string) {
+	name := identity.Name
+	if name == "" {
+		name = identity.Username
+		if i := strings.Index(name, "@"); i > 0 {
+			name = name[:i]
+		}
+	}
+	return splitFullName(name)
+}
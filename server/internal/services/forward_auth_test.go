@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/naiba/bonds/internal/config"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+)
+
+func TestForwardAuthProvisionsOwnAccount(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewForwardAuthService(db, config.ForwardAuthConfig{AutoProvision: true})
+
+	user, err := svc.ForwardAuthUser(middleware.ForwardAuthIdentity{
+		Username: "alice",
+		Email:    "alice@example.com",
+		Name:     "Alice Liddell",
+		Locale:   "fr",
+	})
+	if err != nil {
+		t.Fatalf("ForwardAuthUser failed: %v", err)
+	}
+	if ptrToStr(user.FirstName) != "Alice" || ptrToStr(user.LastName) != "Liddell" || user.Locale != "fr" {
+		t.Errorf("unexpected profile: %+v", user)
+	}
+	if !user.IsAccountAdministrator || !user.IsInstanceAdministrator || user.EmailVerifiedAt == nil {
+		t.Errorf("expected the first user to administer a verified account of their own, got %+v", user)
+	}
+	var genders int64
+	db.Model(&models.Gender{}).Where("account_id = ?", user.AccountID).Count(&genders)
+	if genders == 0 {
+		t.Error("expected the new account to be seeded")
+	}
+
+	// The username keeps resolving after the email changes in the IdP.
+	again, err := svc.ForwardAuthUser(middleware.ForwardAuthIdentity{Username: "alice", Email: "alice@new.example.com"})
+	if err != nil || again.ID != user.ID {
+		t.Fatalf("expected the same user, got %v (%v)", again, err)
+	}
+
+	bob, err := svc.ForwardAuthUser(middleware.ForwardAuthIdentity{Username: "bob@example.com"})
+	if err != nil {
+		t.Fatalf("ForwardAuthUser failed: %v", err)
+	}
+	if bob.AccountID == user.AccountID || bob.IsInstanceAdministrator || ptrToStr(bob.FirstName) != "bob" {
+		t.Errorf("expected bob to get a separate account, got %+v", bob)
+	}
+
+	if _, err := svc.ForwardAuthUser(middleware.ForwardAuthIdentity{Username: "carol"}); !errors.Is(err, middleware.ErrForwardAuthMissingEmail) {
+		t.Errorf("expected ErrForwardAuthMissingEmail, got %v", err)
+	}
+}
+
+func TestForwardAuthJoinsSharedAccountAndSyncsAdminGroup(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	owner, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Owner",
+		LastName:  "User",
+		Email:     "owner@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	vault, err := NewVaultService(db).CreateVault(owner.User.AccountID, owner.User.ID, dto.CreateVaultRequest{Name: "Family"}, "en")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+	svc := NewForwardAuthService(db, config.ForwardAuthConfig{
+		AutoProvision: true,
+		AccountID:     owner.User.AccountID,
+		AdminGroup:    "bonds-admins",
+		LinkByEmail:   true,
+	})
+
+	admin, err := svc.ForwardAuthUser(middleware.ForwardAuthIdentity{
+		Username: "dana",
+		Email:    "dana@example.com",
+		Groups:   []string{"family", "bonds-admins"},
+	})
+	if err != nil {
+		t.Fatalf("ForwardAuthUser failed: %v", err)
+	}
+	if admin.AccountID != owner.User.AccountID || !admin.IsAccountAdministrator {
+		t.Fatalf("expected an administrator of the shared account, got %+v", admin)
+	}
+	var access models.UserVault
+	if err := db.Where("user_id = ? AND vault_id = ?", admin.ID, vault.ID).First(&access).Error; err != nil {
+		t.Fatalf("expected access to the shared vault: %v", err)
+	}
+	if access.Permission != models.PermissionManager {
+		t.Errorf("expected manager permission, got %d", access.Permission)
+	}
+
+	demoted, err := svc.ForwardAuthUser(middleware.ForwardAuthIdentity{Username: "dana", Groups: []string{"family"}})
+	if err != nil {
+		t.Fatalf("ForwardAuthUser failed: %v", err)
+	}
+	var stored models.User
+	db.First(&stored, "id = ?", admin.ID)
+	if demoted.IsAccountAdministrator || stored.IsAccountAdministrator {
+		t.Error("expected leaving the admin group to revoke account administration")
+	}
+
+	// Existing users are linked by email instead of being duplicated.
+	linked, err := svc.ForwardAuthUser(middleware.ForwardAuthIdentity{Username: "owner", Email: "owner@example.com", Groups: []string{"bonds-admins"}})
+	if err != nil || linked.ID != owner.User.ID {
+		t.Fatalf("expected the owner to be linked, got %v (%v)", linked, err)
+	}
+
+	// A missing group header never demotes the last administrator.
+	kept, err := svc.ForwardAuthUser(middleware.ForwardAuthIdentity{Username: "owner"})
+	if err != nil {
+		t.Fatalf("ForwardAuthUser failed: %v", err)
+	}
+	var storedOwner models.User
+	db.First(&storedOwner, "id = ?", owner.User.ID)
+	if !kept.IsAccountAdministrator || !storedOwner.IsAccountAdministrator {
+		t.Error("expected the last administrator to keep account administration")
+	}
+}
+
+func TestForwardAuthWithoutAutoProvision(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewForwardAuthService(db, config.ForwardAuthConfig{})
+	_, err := svc.ForwardAuthUser(middleware.ForwardAuthIdentity{Username: "eve", Email: "eve@example.com"})
+	if !errors.Is(err, middleware.ErrForwardAuthUserNotProvisioned) {
+		t.Errorf("expected ErrForwardAuthUserNotProvisioned, got %v", err)
+	}
+}
+
+func TestForwardAuthRefusesEmailLinkByDefault(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	owner, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Owner",
+		LastName:  "User",
+		Email:     "owner@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	svc := NewForwardAuthService(db, config.ForwardAuthConfig{AutoProvision: true})
+
+	_, err = svc.ForwardAuthUser(middleware.ForwardAuthIdentity{Username: "mallory", Email: "owner@example.com"})
+	if !errors.Is(err, middleware.ErrForwardAuthEmailInUse) {
+		t.Fatalf("expected ErrForwardAuthEmailInUse, got %v", err)
+	}
+	var tokens int64
+	db.Model(&models.UserToken{}).Where("user_id = ?", owner.User.ID).Count(&tokens)
+	if tokens != 0 {
+		t.Errorf("expected no link to be created, got %d", tokens)
+	}
+}
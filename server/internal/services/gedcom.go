@@ -0,0 +1,1037 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	calendarPkg "github.com/naiba/bonds/internal/calendar"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrGedcomInvalidData        = errors.New("invalid gedcom data")
+	ErrGedcomUnsupportedVersion = errors.New("unsupported gedcom version")
+)
+
+// MaxGedcomFileSize is the maximum accepted file size for GEDCOM uploads (10 MB).
+const MaxGedcomFileSize = 10 * 1024 * 1024
+
+const (
+	GedcomVersion551 = "5.5.1"
+	GedcomVersion70  = "7.0"
+)
+
+var gedcomMonths = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+type GedcomService struct {
+	db             *gorm.DB
+	feedRecorder   *FeedRecorder
+	searchService  *SearchService
+	davPushService *DavPushService
+}
+
+func NewGedcomService(db *gorm.DB) *GedcomService {
+	return &GedcomService{db: db}
+}
+
+func (s *GedcomService) SetFeedRecorder(fr *FeedRecorder) {
+	s.feedRecorder = fr
+}
+
+func (s *GedcomService) SetSearchService(ss *SearchService) {
+	s.searchService = ss
+}
+
+func (s *GedcomService) SetDavPushService(ps *DavPushService) {
+	s.davPushService = ps
+}
+
+// gedcomNode is one GEDCOM structure with its substructures. CONT/CONC lines
+// are folded into the parent's value while parsing.
+type gedcomNode struct {
+	xref     string
+	tag      string
+	value    string
+	children []*gedcomNode
+}
+
+func (n *gedcomNode) child(tag string) *gedcomNode {
+	for _, c := range n.children {
+		if c.tag == tag {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *gedcomNode) childValue(tag string) string {
+	if c := n.child(tag); c != nil {
+		return strings.TrimSpace(c.value)
+	}
+	return ""
+}
+
+func (n *gedcomNode) childrenByTag(tag string) []*gedcomNode {
+	var out []*gedcomNode
+	for _, c := range n.children {
+		if c.tag == tag {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// parseGedcom reads GEDCOM 5.5.1 and 7.0 lines into level-0 records.
+func parseGedcom(r io.Reader) ([]*gedcomNode, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), MaxGedcomFileSize)
+
+	var records []*gedcomNode
+	var stack []*gedcomNode
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			continue
+		}
+
+		levelStr, rest, _ := strings.Cut(line, " ")
+		level, err := strconv.Atoi(levelStr)
+		if err != nil || level < 0 || level > len(stack) {
+			return nil, fmt.Errorf("%w: line %d", ErrGedcomInvalidData, lineNum)
+		}
+
+		node := &gedcomNode{}
+		if strings.HasPrefix(rest, "@") {
+			xref, remaining, _ := strings.Cut(rest, " ")
+			node.xref = xref
+			rest = remaining
+		}
+		tag, value, _ := strings.Cut(rest, " ")
+		if tag == "" {
+			return nil, fmt.Errorf("%w: line %d", ErrGedcomInvalidData, lineNum)
+		}
+		node.tag = strings.ToUpper(tag)
+		node.value = value
+
+		if level > 0 && (node.tag == "CONT" || node.tag == "CONC") {
+			parent := stack[level-1]
+			if node.tag == "CONT" {
+				parent.value += "\n" + value
+			} else {
+				parent.value += value
+			}
+			continue
+		}
+
+		stack = stack[:level]
+		if level == 0 {
+			records = append(records, node)
+		} else {
+			parent := stack[level-1]
+			parent.children = append(parent.children, node)
+		}
+		stack = append(stack, node)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGedcomInvalidData, err)
+	}
+	if len(records) == 0 || records[0].tag != "HEAD" {
+		return nil, ErrGedcomInvalidData
+	}
+	return records, nil
+}
+
+// gedcomDate is a parsed GEDCOM date value. Qualifiers (ABT, BEF, ...) and
+// ranges collapse to their first concrete date because important dates have
+// no notion of approximation.
+type gedcomDate struct {
+	day   *int
+	month *int
+	year  *int
+}
+
+func parseGedcomDate(value string) (gedcomDate, bool) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if idx := strings.Index(value, "("); idx >= 0 {
+		value = strings.TrimSpace(value[:idx])
+	}
+	value = strings.TrimPrefix(value, "@#DGREGORIAN@")
+	value = strings.TrimSpace(value)
+	for _, prefix := range []string{"ABT ", "CAL ", "EST ", "BEF ", "AFT ", "FROM ", "BET ", "INT ", "TO "} {
+		if strings.HasPrefix(value, prefix) {
+			value = strings.TrimSpace(strings.TrimPrefix(value, prefix))
+			break
+		}
+	}
+	for _, sep := range []string{" AND ", " TO "} {
+		if idx := strings.Index(value, sep); idx >= 0 {
+			value = value[:idx]
+		}
+	}
+	value = strings.TrimPrefix(value, "GREGORIAN ")
+	if value == "" || strings.HasPrefix(value, "@#") || strings.Contains(value, "BCE") || strings.Contains(value, "B.C.") {
+		return gedcomDate{}, false
+	}
+	for _, calendar := range []string{"JULIAN ", "HEBREW ", "FRENCH_R "} {
+		if strings.HasPrefix(value, calendar) {
+			return gedcomDate{}, false
+		}
+	}
+
+	parts := strings.Fields(value)
+	if len(parts) == 0 || len(parts) > 3 {
+		return gedcomDate{}, false
+	}
+
+	// Dual years such as "1700/01" keep the first (Gregorian-era) year.
+	yearPart, _, _ := strings.Cut(parts[len(parts)-1], "/")
+	year, err := strconv.Atoi(yearPart)
+	if err != nil || year <= 0 {
+		return gedcomDate{}, false
+	}
+	date := gedcomDate{year: &year}
+	if len(parts) >= 2 {
+		month := gedcomMonthNumber(parts[len(parts)-2])
+		if month == 0 {
+			return gedcomDate{}, false
+		}
+		date.month = &month
+	}
+	if len(parts) == 3 {
+		day, err := strconv.Atoi(parts[0])
+		if err != nil || day < 1 || day > daysIn(time.Month(*date.month), year) {
+			return gedcomDate{}, false
+		}
+		date.day = &day
+	}
+	return date, true
+}
+
+func gedcomMonthNumber(token string) int {
+	for i, m := range gedcomMonths {
+		if token == m {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func daysIn(month time.Month, year int) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+type gedcomPerson struct {
+	xref      string
+	uid       string
+	firstName string
+	middle    string
+	lastName  string
+	prefix    string
+	suffix    string
+	nickname  string
+	sex       string
+	birth     *gedcomDate
+	death     *gedcomDate
+}
+
+func gedcomPersonFromNode(node *gedcomNode) gedcomPerson {
+	person := gedcomPerson{xref: node.xref}
+	person.uid = node.childValue("UID")
+	if person.uid == "" {
+		person.uid = node.childValue("_UID")
+	}
+	if name := node.child("NAME"); name != nil {
+		given, surname, suffix := splitGedcomName(name.value)
+		if v := name.childValue("GIVN"); v != "" {
+			given = v
+		}
+		if v := name.childValue("SURN"); v != "" {
+			surname = v
+		}
+		if v := name.childValue("NSFX"); v != "" {
+			suffix = v
+		}
+		first, middle, _ := strings.Cut(strings.TrimSpace(given), " ")
+		person.firstName = strings.TrimSpace(first)
+		person.middle = strings.TrimSpace(middle)
+		person.lastName = surname
+		person.suffix = suffix
+		person.prefix = name.childValue("NPFX")
+		person.nickname = name.childValue("NICK")
+	}
+	person.sex = strings.ToUpper(node.childValue("SEX"))
+	if birth := node.child("BIRT"); birth != nil {
+		if date, ok := parseGedcomDate(birth.childValue("DATE")); ok {
+			person.birth = &date
+		}
+	}
+	if death := node.child("DEAT"); death != nil {
+		if date, ok := parseGedcomDate(death.childValue("DATE")); ok {
+			person.death = &date
+		}
+	}
+	return person
+}
+
+// splitGedcomName splits a NAME payload such as "John Paul /Smith/ Jr.".
+func splitGedcomName(value string) (given, surname, suffix string) {
+	value = strings.TrimSpace(value)
+	start := strings.Index(value, "/")
+	if start < 0 {
+		return value, "", ""
+	}
+	end := strings.Index(value[start+1:], "/")
+	if end < 0 {
+		return strings.TrimSpace(value[:start]), strings.TrimSpace(value[start+1:]), ""
+	}
+	end += start + 1
+	return strings.TrimSpace(value[:start]), strings.TrimSpace(value[start+1 : end]), strings.TrimSpace(value[end+1:])
+}
+
+// gedcomFamilyTypes holds the account relationship types used for GEDCOM
+// families. Parent rows point from parent to child, matching how the
+// relationship graph reads them.
+type gedcomFamilyTypes struct {
+	parent *models.RelationshipType
+	spouse *models.RelationshipType
+}
+
+func loadGedcomFamilyTypes(tx *gorm.DB, accountID string) gedcomFamilyTypes {
+	var types gedcomFamilyTypes
+	types.parent = findAccountRelationshipType(tx, accountID, relKeyParent)
+	types.spouse = findAccountRelationshipType(tx, accountID, relKeySpouse)
+	return types
+}
+
+func findAccountRelationshipType(tx *gorm.DB, accountID, translationKey string) *models.RelationshipType {
+	var relationshipType models.RelationshipType
+	if err := tx.
+		Joins("JOIN relationship_group_types ON relationship_group_types.id = relationship_types.relationship_group_type_id").
+		Where("relationship_group_types.account_id = ? AND relationship_types.name_translation_key = ?", accountID, translationKey).
+		Order("relationship_types.id ASC").
+		First(&relationshipType).Error; err != nil {
+		return nil
+	}
+	return &relationshipType
+}
+
+// Import reads a GEDCOM file into the vault. Individuals are matched to
+// existing contacts by GEDCOM UID first, then by an unambiguous first/last
+// name (disambiguated by birth date). Families become parent and spouse
+// relationships using the account's seeded relationship types.
+func (s *GedcomService) Import(vaultID, userID string, data io.Reader) (*dto.GedcomImportResponse, error) {
+	records, err := parseGedcom(data)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.GedcomImportResponse{Errors: []string{}}
+	var created []models.Contact
+	touched := make(map[string]struct{})
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var vault models.Vault
+		if err := tx.First(&vault, "id = ?", vaultID).Error; err != nil {
+			return fmt.Errorf("vault not found: %w", err)
+		}
+		accountID := vault.AccountID
+
+		var existing []models.Contact
+		if err := tx.Preload("ImportantDates.ContactImportantDateType").
+			Where("vault_id = ?", vaultID).
+			Find(&existing).Error; err != nil {
+			return err
+		}
+		matcher := newGedcomContactMatcher(existing)
+
+		var birthType, deathType models.ContactImportantDateType
+		hasBirthType := tx.Where("vault_id = ? AND internal_type = ?", vaultID, "birthdate").First(&birthType).Error == nil
+		hasDeathType := tx.Where("vault_id = ? AND internal_type = ?", vaultID, "deceased_date").First(&deathType).Error == nil
+
+		genderIDs := make(map[string]uint)
+		var genders []models.Gender
+		if err := tx.Where("account_id = ?", accountID).Find(&genders).Error; err != nil {
+			return err
+		}
+		for _, g := range genders {
+			if g.NameTranslationKey != nil {
+				genderIDs[*g.NameTranslationKey] = g.ID
+			}
+		}
+
+		contactByXref := make(map[string]string)
+		now := time.Now()
+		for _, record := range records {
+			if record.tag != "INDI" {
+				continue
+			}
+			person := gedcomPersonFromNode(record)
+			if person.firstName == "" && person.lastName == "" {
+				resp.SkippedCount++
+				resp.Errors = append(resp.Errors, fmt.Sprintf("individual %s: missing name", record.xref))
+				continue
+			}
+
+			contact := matcher.match(person)
+			if contact != nil {
+				resp.MatchedContacts++
+			} else {
+				firstName := person.firstName
+				if firstName == "" {
+					firstName = person.lastName
+				}
+				contact = &models.Contact{
+					VaultID:       vaultID,
+					FirstName:     strPtrOrNil(firstName),
+					MiddleName:    strPtrOrNil(person.middle),
+					LastName:      strPtrOrNil(person.lastName),
+					Prefix:        strPtrOrNil(person.prefix),
+					Suffix:        strPtrOrNil(person.suffix),
+					Nickname:      strPtrOrNil(person.nickname),
+					GenderID:      gedcomGenderID(genderIDs, person.sex),
+					Listed:        true,
+					LastUpdatedAt: &now,
+				}
+				if err := tx.Create(contact).Error; err != nil {
+					return fmt.Errorf("failed to create contact: %w", err)
+				}
+				if err := tx.Create(&models.ContactVaultUser{ContactID: contact.ID, VaultID: vaultID, UserID: userID}).Error; err != nil {
+					return fmt.Errorf("vault user link: %w", err)
+				}
+				created = append(created, *contact)
+				resp.ImportedContacts++
+			}
+			if record.xref != "" {
+				contactByXref[record.xref] = contact.ID
+			}
+
+			if person.birth != nil && hasBirthType && !contactHasDateOfType(contact, birthType.ID) {
+				if err := createGedcomImportantDate(tx, contact.ID, &birthType, *person.birth); err != nil {
+					return err
+				}
+				resp.ImportedDates++
+				touched[contact.ID] = struct{}{}
+			}
+			if person.death != nil && hasDeathType && !contactHasDateOfType(contact, deathType.ID) {
+				if err := createGedcomImportantDate(tx, contact.ID, &deathType, *person.death); err != nil {
+					return err
+				}
+				resp.ImportedDates++
+				touched[contact.ID] = struct{}{}
+			}
+		}
+
+		types := loadGedcomFamilyTypes(tx, accountID)
+		if types.parent == nil || types.spouse == nil {
+			resp.Errors = append(resp.Errors, "family relationship types not found; relationships were not imported")
+			return nil
+		}
+		for _, record := range records {
+			if record.tag != "FAM" {
+				continue
+			}
+			var partners []string
+			for _, tag := range []string{"HUSB", "WIFE"} {
+				for _, ref := range record.childrenByTag(tag) {
+					if id, ok := contactByXref[strings.TrimSpace(ref.value)]; ok {
+						partners = append(partners, id)
+					}
+				}
+			}
+			var children []string
+			for _, ref := range record.childrenByTag("CHIL") {
+				if id, ok := contactByXref[strings.TrimSpace(ref.value)]; ok {
+					children = append(children, id)
+				}
+			}
+
+			if len(partners) == 2 && partners[0] != partners[1] {
+				n, err := ensureGedcomRelationship(tx, partners[0], partners[1], types.spouse)
+				if err != nil {
+					return err
+				}
+				resp.CreatedRelationships += n
+			}
+			for _, parentID := range partners {
+				for _, childID := range children {
+					if parentID == childID {
+						continue
+					}
+					n, err := ensureGedcomRelationship(tx, parentID, childID, types.parent)
+					if err != nil {
+						return err
+					}
+					resp.CreatedRelationships += n
+				}
+			}
+		}
+		return recordImportedVaultChanges(tx, vaultID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range created {
+		contact := &created[i]
+		if s.feedRecorder != nil {
+			s.feedRecorder.Record(contact.ID, userID, ActionContactCreated, "Imported contact "+ptrToStr(contact.FirstName), nil, nil)
+		}
+		if s.searchService != nil {
+			s.searchService.IndexContact(contact)
+		}
+		touched[contact.ID] = struct{}{}
+	}
+	if s.davPushService != nil {
+		for contactID := range touched {
+			go s.davPushService.PushContactChange(contactID, vaultID)
+		}
+	}
+	return resp, nil
+}
+
+type gedcomContactMatcher struct {
+	byID   map[string]*models.Contact
+	byName map[string][]*models.Contact
+	used   map[string]struct{}
+}
+
+func newGedcomContactMatcher(contacts []models.Contact) *gedcomContactMatcher {
+	m := &gedcomContactMatcher{
+		byID:   make(map[string]*models.Contact, len(contacts)),
+		byName: make(map[string][]*models.Contact),
+		used:   make(map[string]struct{}),
+	}
+	for i := range contacts {
+		contact := &contacts[i]
+		m.byID[contact.ID] = contact
+		key := gedcomNameKey(ptrToStr(contact.FirstName), ptrToStr(contact.LastName))
+		m.byName[key] = append(m.byName[key], contact)
+	}
+	return m
+}
+
+func gedcomNameKey(firstName, lastName string) string {
+	return strings.ToLower(strings.TrimSpace(firstName)) + "\x00" + strings.ToLower(strings.TrimSpace(lastName))
+}
+
+// match returns a pre-existing contact for the person, or nil. Each existing
+// contact is matched at most once so namesakes in one file stay distinct.
+func (m *gedcomContactMatcher) match(person gedcomPerson) *models.Contact {
+	if contact, ok := m.byID[person.uid]; ok && !m.isUsed(contact) {
+		return m.take(contact)
+	}
+
+	var candidates []*models.Contact
+	for _, contact := range m.byName[gedcomNameKey(person.firstName, person.lastName)] {
+		if m.isUsed(contact) {
+			continue
+		}
+		if person.birth != nil && !gedcomBirthCompatible(contact, *person.birth) {
+			continue
+		}
+		candidates = append(candidates, contact)
+	}
+	if len(candidates) == 1 {
+		return m.take(candidates[0])
+	}
+	if person.birth != nil {
+		var exact []*models.Contact
+		for _, contact := range candidates {
+			if gedcomBirthEqual(contact, *person.birth) {
+				exact = append(exact, contact)
+			}
+		}
+		if len(exact) == 1 {
+			return m.take(exact[0])
+		}
+	}
+	return nil
+}
+
+func (m *gedcomContactMatcher) isUsed(contact *models.Contact) bool {
+	_, ok := m.used[contact.ID]
+	return ok
+}
+
+func (m *gedcomContactMatcher) take(contact *models.Contact) *models.Contact {
+	m.used[contact.ID] = struct{}{}
+	return contact
+}
+
+func contactBirthdate(contact *models.Contact) *models.ContactImportantDate {
+	for i := range contact.ImportantDates {
+		date := &contact.ImportantDates[i]
+		if date.ContactImportantDateType != nil && ptrToStr(date.ContactImportantDateType.InternalType) == "birthdate" {
+			return date
+		}
+	}
+	return nil
+}
+
+// gedcomBirthCompatible reports whether the contact's known birth date does
+// not contradict the GEDCOM one. Contacts without a birthdate stay candidates.
+func gedcomBirthCompatible(contact *models.Contact, birth gedcomDate) bool {
+	existing := contactBirthdate(contact)
+	if existing == nil {
+		return true
+	}
+	return intPtrsCompatible(existing.Year, birth.year) &&
+		intPtrsCompatible(existing.Month, birth.month) &&
+		intPtrsCompatible(existing.Day, birth.day)
+}
+
+func gedcomBirthEqual(contact *models.Contact, birth gedcomDate) bool {
+	existing := contactBirthdate(contact)
+	return existing != nil && gedcomBirthCompatible(contact, birth)
+}
+
+func intPtrsCompatible(a, b *int) bool {
+	return a == nil || b == nil || *a == *b
+}
+
+func contactHasDateOfType(contact *models.Contact, typeID uint) bool {
+	for _, date := range contact.ImportantDates {
+		if date.ContactImportantDateTypeID != nil && *date.ContactImportantDateTypeID == typeID {
+			return true
+		}
+	}
+	return false
+}
+
+func gedcomGenderID(genderIDs map[string]uint, sex string) *uint {
+	var key string
+	switch sex {
+	case "M":
+		key = "seed.genders.male"
+	case "F":
+		key = "seed.genders.female"
+	case "X":
+		key = "seed.genders.other"
+	default:
+		return nil
+	}
+	if id, ok := genderIDs[key]; ok {
+		return &id
+	}
+	return nil
+}
+
+func createGedcomImportantDate(tx *gorm.DB, contactID string, dateType *models.ContactImportantDateType, value gedcomDate) error {
+	date := models.ContactImportantDate{
+		ContactID:                  contactID,
+		ContactImportantDateTypeID: &dateType.ID,
+		Label:                      dateType.Label,
+		Day:                        value.day,
+		Month:                      value.month,
+		Year:                       value.year,
+		CalendarType:               string(calendarPkg.Gregorian),
+	}
+	if err := applyImportantDatePrecision(&date, ""); err != nil {
+		return err
+	}
+	return tx.Create(&date).Error
+}
+
+// ensureGedcomRelationship creates contactID → relatedID with the given type
+// and its reverse row, skipping rows that already exist. It returns the number
+// of rows created.
+func ensureGedcomRelationship(tx *gorm.DB, contactID, relatedID string, relationshipType *models.RelationshipType) (int, error) {
+	rows := []models.Relationship{{ContactID: contactID, RelatedContactID: relatedID, RelationshipTypeID: relationshipType.ID}}
+	if relationshipType.ReverseRelationshipTypeID != nil {
+		rows = append(rows, models.Relationship{ContactID: relatedID, RelatedContactID: contactID, RelationshipTypeID: *relationshipType.ReverseRelationshipTypeID})
+	}
+	created := 0
+	for i := range rows {
+		var count int64
+		if err := tx.Model(&models.Relationship{}).
+			Where("contact_id = ? AND related_contact_id = ? AND relationship_type_id = ?", rows[i].ContactID, rows[i].RelatedContactID, rows[i].RelationshipTypeID).
+			Count(&count).Error; err != nil {
+			return created, err
+		}
+		if count > 0 {
+			continue
+		}
+		if err := tx.Create(&rows[i]).Error; err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
+
+type gedcomFamily struct {
+	partners []string
+	children []string
+}
+
+// Export writes the vault's family subgraph — every contact that takes part
+// in a parent, child or spouse relationship inside the vault — as GEDCOM.
+// Lunar-calendar dates are converted to Gregorian; dates that cannot be
+// placed on a Gregorian year are kept as event notes.
+func (s *GedcomService) Export(vaultID, version string) ([]byte, error) {
+	if version == "" {
+		version = GedcomVersion551
+	}
+	if version != GedcomVersion551 && version != GedcomVersion70 {
+		return nil, ErrGedcomUnsupportedVersion
+	}
+
+	var relationships []models.Relationship
+	if err := s.db.Preload("RelationshipType").
+		Where("contact_id IN (SELECT id FROM contacts WHERE vault_id = ? AND deleted_at IS NULL)", vaultID).
+		Where("related_contact_id IN (SELECT id FROM contacts WHERE vault_id = ? AND deleted_at IS NULL)", vaultID).
+		Order("id ASC").
+		Find(&relationships).Error; err != nil {
+		return nil, err
+	}
+
+	builder := newRelationshipGraphBuilder("en")
+	for _, relationship := range relationships {
+		if relationship.ContactID == relationship.RelatedContactID {
+			continue
+		}
+		builder.addExplicitRelationship(relationship)
+	}
+
+	members := make(contactSet)
+	for child, parents := range builder.parentsByChild {
+		members[child] = struct{}{}
+		for parent := range parents {
+			members[parent] = struct{}{}
+		}
+	}
+	for left, spouses := range builder.spouses {
+		members[left] = struct{}{}
+		for right := range spouses {
+			members[right] = struct{}{}
+		}
+	}
+
+	var contacts []models.Contact
+	if len(members) > 0 {
+		if err := s.db.Preload("ImportantDates.ContactImportantDateType").
+			Preload("Gender").
+			Where("id IN ? AND vault_id = ?", sortedContactIDs(members), vaultID).
+			Find(&contacts).Error; err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(contacts, func(i, j int) bool {
+		left := gedcomNameKey(ptrToStr(contacts[i].LastName), ptrToStr(contacts[i].FirstName))
+		right := gedcomNameKey(ptrToStr(contacts[j].LastName), ptrToStr(contacts[j].FirstName))
+		if left != right {
+			return left < right
+		}
+		return contacts[i].ID < contacts[j].ID
+	})
+
+	contactByID := make(map[string]*models.Contact, len(contacts))
+	xrefs := make(map[string]string, len(contacts))
+	for i := range contacts {
+		contactByID[contacts[i].ID] = &contacts[i]
+		xrefs[contacts[i].ID] = fmt.Sprintf("@I%d@", i+1)
+	}
+
+	families := buildGedcomFamilies(builder)
+	famc := make(map[string][]string)
+	fams := make(map[string][]string)
+	for i, family := range families {
+		xref := fmt.Sprintf("@F%d@", i+1)
+		for _, partner := range family.partners {
+			fams[partner] = append(fams[partner], xref)
+		}
+		for _, child := range family.children {
+			famc[child] = append(famc[child], xref)
+		}
+	}
+
+	var buf bytes.Buffer
+	writeGedcomHeader(&buf, version)
+	for i := range contacts {
+		contact := &contacts[i]
+		writeGedcomIndividual(&buf, version, xrefs[contact.ID], contact, famc[contact.ID], fams[contact.ID])
+	}
+	for i, family := range families {
+		fmt.Fprintf(&buf, "0 @F%d@ FAM\n", i+1)
+		partners := orderGedcomPartners(family.partners, contactByID)
+		for j, partner := range partners {
+			tag := "HUSB"
+			if j == 1 {
+				tag = "WIFE"
+			}
+			fmt.Fprintf(&buf, "1 %s %s\n", tag, xrefs[partner])
+		}
+		for _, child := range family.children {
+			fmt.Fprintf(&buf, "1 CHIL %s\n", xrefs[child])
+		}
+	}
+	buf.WriteString("0 TRLR\n")
+	return buf.Bytes(), nil
+}
+
+// buildGedcomFamilies groups children by their parent set. Children with more
+// than two recorded parents (e.g. biological and adoptive) are split into
+// spouse pairs. Spouses without shared children still get a family record.
+func buildGedcomFamilies(builder *relationshipGraphBuilder) []gedcomFamily {
+	byKey := make(map[string]*gedcomFamily)
+	var keys []string
+	addFamily := func(partners []string) *gedcomFamily {
+		sort.Strings(partners)
+		key := strings.Join(partners, "\x00")
+		family, ok := byKey[key]
+		if !ok {
+			family = &gedcomFamily{partners: partners}
+			byKey[key] = family
+			keys = append(keys, key)
+		}
+		return family
+	}
+
+	for _, child := range sortedContactIDs(keysOfContactMap(builder.parentsByChild)) {
+		parents := sortedContactIDs(builder.parentsByChild[child])
+		var groups [][]string
+		if len(parents) <= 2 {
+			groups = [][]string{parents}
+		} else {
+			paired := make(contactSet)
+			for _, parent := range parents {
+				if containsContact(paired, parent) {
+					continue
+				}
+				group := []string{parent}
+				paired[parent] = struct{}{}
+				for _, other := range parents {
+					if !containsContact(paired, other) && containsContact(builder.spouses[parent], other) {
+						group = append(group, other)
+						paired[other] = struct{}{}
+						break
+					}
+				}
+				groups = append(groups, group)
+			}
+		}
+		for _, group := range groups {
+			family := addFamily(group)
+			family.children = append(family.children, child)
+		}
+	}
+
+	for _, left := range sortedContactIDs(keysOfContactMap(builder.spouses)) {
+		for _, right := range sortedContactIDs(builder.spouses[left]) {
+			if left < right {
+				addFamily([]string{left, right})
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	families := make([]gedcomFamily, 0, len(keys))
+	for _, key := range keys {
+		families = append(families, *byKey[key])
+	}
+	return families
+}
+
+func keysOfContactMap(values map[string]contactSet) contactSet {
+	keys := make(contactSet, len(values))
+	for key := range values {
+		keys[key] = struct{}{}
+	}
+	return keys
+}
+
+// orderGedcomPartners puts a female partner in the WIFE slot when genders
+// allow it; otherwise the order is stable by contact ID.
+func orderGedcomPartners(partners []string, contacts map[string]*models.Contact) []string {
+	ordered := append([]string(nil), partners...)
+	if len(ordered) == 2 && gedcomSex(contacts[ordered[0]]) == "F" && gedcomSex(contacts[ordered[1]]) != "F" {
+		ordered[0], ordered[1] = ordered[1], ordered[0]
+	}
+	return ordered
+}
+
+func gedcomSex(contact *models.Contact) string {
+	if contact == nil || contact.Gender == nil {
+		return "U"
+	}
+	switch ptrToStr(contact.Gender.NameTranslationKey) {
+	case "seed.genders.male":
+		return "M"
+	case "seed.genders.female":
+		return "F"
+	case "seed.genders.other":
+		return "X"
+	}
+	return "U"
+}
+
+func writeGedcomHeader(buf *bytes.Buffer, version string) {
+	buf.WriteString("0 HEAD\n")
+	if version == GedcomVersion70 {
+		buf.WriteString("1 GEDC\n2 VERS 7.0\n")
+		buf.WriteString("1 SOUR BONDS\n2 NAME Bonds\n")
+		return
+	}
+	buf.WriteString("1 SOUR BONDS\n2 NAME Bonds\n")
+	buf.WriteString("1 GEDC\n2 VERS 5.5.1\n2 FORM LINEAGE-LINKED\n")
+	buf.WriteString("1 CHAR UTF-8\n")
+}
+
+func writeGedcomIndividual(buf *bytes.Buffer, version, xref string, contact *models.Contact, famc, fams []string) {
+	fmt.Fprintf(buf, "0 %s INDI\n", xref)
+
+	given := strings.TrimSpace(strings.Join(nonEmptyStrings(ptrToStr(contact.FirstName), ptrToStr(contact.MiddleName)), " "))
+	surname := strings.TrimSpace(ptrToStr(contact.LastName))
+	name := given
+	if surname != "" {
+		name = strings.TrimSpace(given + " /" + surname + "/")
+	}
+	if suffix := strings.TrimSpace(ptrToStr(contact.Suffix)); suffix != "" {
+		name += " " + suffix
+	}
+	writeGedcomLine(buf, 1, "NAME", name)
+	writeGedcomLine(buf, 2, "GIVN", given)
+	writeGedcomLine(buf, 2, "SURN", surname)
+	writeGedcomLine(buf, 2, "NPFX", ptrToStr(contact.Prefix))
+	writeGedcomLine(buf, 2, "NSFX", ptrToStr(contact.Suffix))
+	writeGedcomLine(buf, 2, "NICK", ptrToStr(contact.Nickname))
+	fmt.Fprintf(buf, "1 SEX %s\n", gedcomSex(contact))
+
+	if version == GedcomVersion70 {
+		fmt.Fprintf(buf, "1 UID %s\n", contact.ID)
+	} else {
+		fmt.Fprintf(buf, "1 _UID %s\n", contact.ID)
+	}
+
+	for _, date := range contact.ImportantDates {
+		if date.ContactImportantDateType == nil {
+			continue
+		}
+		var tag string
+		switch ptrToStr(date.ContactImportantDateType.InternalType) {
+		case "birthdate":
+			tag = "BIRT"
+		case "deceased_date":
+			tag = "DEAT"
+		default:
+			continue
+		}
+		value, note := gedcomDateValue(&date)
+		if value == "" && note == "" {
+			fmt.Fprintf(buf, "1 %s Y\n", tag)
+			continue
+		}
+		fmt.Fprintf(buf, "1 %s\n", tag)
+		writeGedcomLine(buf, 2, "DATE", value)
+		writeGedcomLine(buf, 2, "NOTE", note)
+	}
+
+	for _, family := range famc {
+		fmt.Fprintf(buf, "1 FAMC %s\n", family)
+	}
+	for _, family := range fams {
+		fmt.Fprintf(buf, "1 FAMS %s\n", family)
+	}
+}
+
+func writeGedcomLine(buf *bytes.Buffer, level int, tag, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	lines := strings.Split(value, "\n")
+	fmt.Fprintf(buf, "%d %s %s\n", level, tag, lines[0])
+	for _, line := range lines[1:] {
+		fmt.Fprintf(buf, "%d CONT %s\n", level+1, line)
+	}
+}
+
+// gedcomDateValue renders an important date as a GEDCOM DATE payload. Lunar
+// dates with a known year are converted to Gregorian and keep the original
+// as a note; yearless dates cannot be expressed as a GEDCOM date and are
+// returned only as a note.
+func gedcomDateValue(date *models.ContactImportantDate) (value string, note string) {
+	day, month, year := date.Day, date.Month, date.Year
+	if date.CalendarType != "" && date.CalendarType != string(calendarPkg.Gregorian) {
+		original := fmt.Sprintf("%s calendar date: %s", date.CalendarType, formatGedcomOriginalDate(date))
+		converter, ok := calendarPkg.Get(calendarPkg.CalendarType(date.CalendarType))
+		if !ok || date.OriginalYear == nil || date.OriginalMonth == nil {
+			return "", original
+		}
+		info := calendarPkg.DateInfo{Year: *date.OriginalYear, Month: *date.OriginalMonth, Day: 1}
+		if date.OriginalDay != nil {
+			info.Day = *date.OriginalDay
+		}
+		gd, err := converter.ToGregorian(info)
+		if err != nil {
+			return "", original
+		}
+		year, month = &gd.Year, &gd.Month
+		day = nil
+		if date.OriginalDay != nil {
+			day = &gd.Day
+		}
+		note = original
+	}
+
+	validMonth := month != nil && *month >= 1 && *month <= 12
+	if year == nil {
+		if validMonth && day != nil {
+			return "", fmt.Sprintf("Date without year: %d %s", *day, gedcomMonths[*month-1])
+		}
+		return "", note
+	}
+	parts := make([]string, 0, 3)
+	if validMonth {
+		if day != nil {
+			parts = append(parts, strconv.Itoa(*day))
+		}
+		parts = append(parts, gedcomMonths[*month-1])
+	}
+	parts = append(parts, strconv.Itoa(*year))
+	return strings.Join(parts, " "), note
+}
+
+func formatGedcomOriginalDate(date *models.ContactImportantDate) string {
+	var parts []string
+	if date.OriginalYear != nil {
+		parts = append(parts, strconv.Itoa(*date.OriginalYear))
+	}
+	if date.OriginalMonth != nil {
+		month := *date.OriginalMonth
+		if month < 0 {
+			parts = append(parts, fmt.Sprintf("leap %02d", -month))
+		} else {
+			parts = append(parts, fmt.Sprintf("%02d", month))
+		}
+	}
+	if date.OriginalDay != nil {
+		parts = append(parts, fmt.Sprintf("%02d", *date.OriginalDay))
+	}
+	return strings.Join(parts, "-")
+}
+
+func nonEmptyStrings(values ...string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			out = append(out, strings.TrimSpace(v))
+		}
+	}
+	return out
+}
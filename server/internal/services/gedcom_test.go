@@ -0,0 +1,365 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/search"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+type gedcomTestCtx struct {
+	svc       *GedcomService
+	db        *gorm.DB
+	vaultID   string
+	userID    string
+	accountID string
+}
+
+func setupGedcomTest(t *testing.T) gedcomTestCtx {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	authSvc := NewAuthService(db, testutil.TestJWTConfig())
+	resp, err := authSvc.Register(dto.RegisterRequest{
+		FirstName: "Test",
+		LastName:  "User",
+		Email:     "gedcom-test@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	vault, err := NewVaultService(db).CreateVault(resp.User.AccountID, resp.User.ID, dto.CreateVaultRequest{Name: "Family"}, "en")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+	svc := NewGedcomService(db)
+	svc.SetFeedRecorder(NewFeedRecorder(db))
+	svc.SetSearchService(NewSearchService(&search.NoopEngine{}))
+	return gedcomTestCtx{svc: svc, db: db, vaultID: vault.ID, userID: resp.User.ID, accountID: resp.User.AccountID}
+}
+
+const sampleGedcom551 = `0 HEAD
+1 SOUR Gramps
+1 GEDC
+2 VERS 5.5.1
+2 FORM LINEAGE-LINKED
+1 CHAR UTF-8
+0 @I1@ INDI
+1 NAME John Paul /Smith/
+2 GIVN John Paul
+2 SURN Smith
+1 SEX M
+1 BIRT
+2 DATE 12 JAN 1950
+1 DEAT
+2 DATE ABT 2010
+1 FAMS @F1@
+0 @I2@ INDI
+1 NAME Mary /Jones/
+1 SEX F
+1 BIRT
+2 DATE MAR 1952
+1 FAMS @F1@
+0 @I3@ INDI
+1 NAME Alice /Smith/
+1 SEX F
+1 BIRT
+2 DATE 3 JUL 1980
+1 FAMC @F1@
+0 @F1@ FAM
+1 HUSB @I1@
+1 WIFE @I2@
+1 CHIL @I3@
+0 TRLR
+`
+
+func findGedcomContact(t *testing.T, ctx gedcomTestCtx, firstName string) models.Contact {
+	t.Helper()
+	var contact models.Contact
+	if err := ctx.db.Preload("ImportantDates.ContactImportantDateType").Preload("Gender").
+		Where("vault_id = ? AND first_name = ?", ctx.vaultID, firstName).First(&contact).Error; err != nil {
+		t.Fatalf("find contact %q: %v", firstName, err)
+	}
+	return contact
+}
+
+func countRelationshipsOfKey(t *testing.T, db *gorm.DB, contactID, relatedID, translationKey string) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Model(&models.Relationship{}).
+		Joins("JOIN relationship_types ON relationship_types.id = relationships.relationship_type_id").
+		Where("relationships.contact_id = ? AND relationships.related_contact_id = ? AND relationship_types.name_translation_key = ?", contactID, relatedID, translationKey).
+		Count(&count).Error; err != nil {
+		t.Fatalf("count relationships: %v", err)
+	}
+	return count
+}
+
+func TestGedcomImportCreatesContactsDatesAndFamilies(t *testing.T) {
+	ctx := setupGedcomTest(t)
+
+	resp, err := ctx.svc.Import(ctx.vaultID, ctx.userID, strings.NewReader(sampleGedcom551))
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if resp.ImportedContacts != 3 || resp.MatchedContacts != 0 {
+		t.Fatalf("expected 3 imported and 0 matched, got %+v", resp)
+	}
+	if resp.ImportedDates != 4 {
+		t.Fatalf("expected 4 imported dates, got %d", resp.ImportedDates)
+	}
+	// Spouse pair plus two parent rows, each with its reverse row.
+	if resp.CreatedRelationships != 6 {
+		t.Fatalf("expected 6 relationship rows, got %d", resp.CreatedRelationships)
+	}
+
+	john := findGedcomContact(t, ctx, "John")
+	if ptrToStr(john.MiddleName) != "Paul" || ptrToStr(john.LastName) != "Smith" {
+		t.Fatalf("unexpected name split: middle=%q last=%q", ptrToStr(john.MiddleName), ptrToStr(john.LastName))
+	}
+	if john.Gender == nil || ptrToStr(john.Gender.NameTranslationKey) != "seed.genders.male" {
+		t.Fatal("expected SEX M to map to the seeded male gender")
+	}
+	birth := contactBirthdate(&john)
+	if birth == nil || *birth.Year != 1950 || *birth.Month != 1 || *birth.Day != 12 || birth.DatePrecision != importantDatePrecisionFull {
+		t.Fatalf("unexpected birthdate: %+v", birth)
+	}
+
+	mary := findGedcomContact(t, ctx, "Mary")
+	if birth := contactBirthdate(&mary); birth == nil || birth.Day != nil || birth.DatePrecision != importantDatePrecisionMonth {
+		t.Fatalf("expected month precision birthdate, got %+v", birth)
+	}
+
+	alice := findGedcomContact(t, ctx, "Alice")
+	if countRelationshipsOfKey(t, ctx.db, john.ID, alice.ID, relKeyParent) != 1 ||
+		countRelationshipsOfKey(t, ctx.db, alice.ID, john.ID, relKeyChild) != 1 ||
+		countRelationshipsOfKey(t, ctx.db, mary.ID, alice.ID, relKeyParent) != 1 {
+		t.Fatal("expected parent/child rows between the parents and Alice")
+	}
+	if countRelationshipsOfKey(t, ctx.db, john.ID, mary.ID, relKeySpouse) != 1 ||
+		countRelationshipsOfKey(t, ctx.db, mary.ID, john.ID, relKeySpouse) != 1 {
+		t.Fatal("expected spouse rows in both directions")
+	}
+}
+
+func TestGedcomImportMatchesExistingContacts(t *testing.T) {
+	ctx := setupGedcomTest(t)
+
+	existing, err := NewContactService(ctx.db).CreateContact(ctx.vaultID, ctx.userID, dto.CreateContactRequest{FirstName: "Alice", LastName: "Smith"})
+	if err != nil {
+		t.Fatalf("CreateContact failed: %v", err)
+	}
+
+	resp, err := ctx.svc.Import(ctx.vaultID, ctx.userID, strings.NewReader(sampleGedcom551))
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if resp.ImportedContacts != 2 || resp.MatchedContacts != 1 {
+		t.Fatalf("expected 2 imported and 1 matched, got %+v", resp)
+	}
+	alice := findGedcomContact(t, ctx, "Alice")
+	if alice.ID != existing.ID {
+		t.Fatal("existing Alice was not reused")
+	}
+	if contactBirthdate(&alice) == nil {
+		t.Fatal("matched contact should receive the missing birthdate")
+	}
+
+	// Re-importing the same file must not duplicate anything.
+	again, err := ctx.svc.Import(ctx.vaultID, ctx.userID, strings.NewReader(sampleGedcom551))
+	if err != nil {
+		t.Fatalf("second Import failed: %v", err)
+	}
+	if again.ImportedContacts != 0 || again.MatchedContacts != 3 || again.ImportedDates != 0 || again.CreatedRelationships != 0 {
+		t.Fatalf("re-import should be a no-op, got %+v", again)
+	}
+}
+
+func TestGedcomImportDoesNotMatchContactWithConflictingBirthdate(t *testing.T) {
+	ctx := setupGedcomTest(t)
+
+	contact, err := NewContactService(ctx.db).CreateContact(ctx.vaultID, ctx.userID, dto.CreateContactRequest{FirstName: "Alice", LastName: "Smith"})
+	if err != nil {
+		t.Fatalf("CreateContact failed: %v", err)
+	}
+	var birthType models.ContactImportantDateType
+	if err := ctx.db.Where("vault_id = ? AND internal_type = ?", ctx.vaultID, "birthdate").First(&birthType).Error; err != nil {
+		t.Fatalf("find birthdate type: %v", err)
+	}
+	year, month, day := 1999, 1, 1
+	if err := ctx.db.Create(&models.ContactImportantDate{
+		ContactID: contact.ID, ContactImportantDateTypeID: &birthType.ID, Label: birthType.Label,
+		Year: &year, Month: &month, Day: &day, CalendarType: "gregorian",
+	}).Error; err != nil {
+		t.Fatalf("create birthdate: %v", err)
+	}
+
+	resp, err := ctx.svc.Import(ctx.vaultID, ctx.userID, strings.NewReader(sampleGedcom551))
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if resp.MatchedContacts != 0 || resp.ImportedContacts != 3 {
+		t.Fatalf("namesake with a different birthdate must not be matched, got %+v", resp)
+	}
+}
+
+func TestGedcomImportRejectsInvalidData(t *testing.T) {
+	ctx := setupGedcomTest(t)
+	for _, input := range []string{"", "not a gedcom file", "0 @I1@ INDI\n1 NAME A /B/\n", "0 HEAD\n2 GEDC\n"} {
+		if _, err := ctx.svc.Import(ctx.vaultID, ctx.userID, strings.NewReader(input)); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
+
+func TestGedcomExportRoundTrip(t *testing.T) {
+	ctx := setupGedcomTest(t)
+	if _, err := ctx.svc.Import(ctx.vaultID, ctx.userID, strings.NewReader(sampleGedcom551)); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	// Unrelated contacts are not part of the family subgraph.
+	if _, err := NewContactService(ctx.db).CreateContact(ctx.vaultID, ctx.userID, dto.CreateContactRequest{FirstName: "Stranger"}); err != nil {
+		t.Fatalf("CreateContact failed: %v", err)
+	}
+
+	data, err := ctx.svc.Export(ctx.vaultID, "")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		"2 VERS 5.5.1",
+		"1 NAME John Paul /Smith/",
+		"2 DATE 12 JAN 1950",
+		"2 DATE MAR 1952",
+		"1 SEX F",
+		"1 HUSB @",
+		"1 WIFE @",
+		"1 CHIL @",
+		"0 TRLR",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("export missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Stranger") {
+		t.Fatal("contacts outside the family subgraph must not be exported")
+	}
+	if strings.Count(out, " INDI\n") != 3 || strings.Count(out, " FAM\n") != 1 {
+		t.Fatalf("expected 3 individuals and 1 family:\n%s", out)
+	}
+
+	// Importing the export into a fresh vault rebuilds the same family.
+	other := setupGedcomTest(t)
+	resp, err := other.svc.Import(other.vaultID, other.userID, strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("re-import failed: %v", err)
+	}
+	if resp.ImportedContacts != 3 || resp.CreatedRelationships != 6 || resp.ImportedDates != 4 {
+		t.Fatalf("round trip lost data: %+v", resp)
+	}
+}
+
+func TestGedcomExportConvertsLunarDates(t *testing.T) {
+	ctx := setupGedcomTest(t)
+	if _, err := ctx.svc.Import(ctx.vaultID, ctx.userID, strings.NewReader(sampleGedcom551)); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	alice := findGedcomContact(t, ctx, "Alice")
+	birth := contactBirthdate(&alice)
+	lunarYear, lunarMonth, lunarDay := 2000, 8, 15
+	if err := ctx.db.Model(birth).Updates(map[string]interface{}{
+		"calendar_type":  "lunar",
+		"original_year":  lunarYear,
+		"original_month": lunarMonth,
+		"original_day":   lunarDay,
+	}).Error; err != nil {
+		t.Fatalf("update birthdate: %v", err)
+	}
+
+	data, err := ctx.svc.Export(ctx.vaultID, GedcomVersion70)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := string(data)
+	// Lunar 2000-08-15 (Mid-Autumn) is 12 September 2000.
+	if !strings.Contains(out, "2 DATE 12 SEP 2000") {
+		t.Fatalf("lunar birthdate was not converted:\n%s", out)
+	}
+	if !strings.Contains(out, "2 NOTE lunar calendar date: 2000-08-15") {
+		t.Fatalf("original lunar date note missing:\n%s", out)
+	}
+	if !strings.Contains(out, "2 VERS 7.0") || !strings.Contains(out, "1 UID "+alice.ID) {
+		t.Fatalf("expected GEDCOM 7.0 header and UID:\n%s", out)
+	}
+}
+
+func TestGedcomExportRejectsUnknownVersion(t *testing.T) {
+	ctx := setupGedcomTest(t)
+	if _, err := ctx.svc.Export(ctx.vaultID, "4.0"); err != ErrGedcomUnsupportedVersion {
+		t.Fatalf("expected ErrGedcomUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestGedcomDateValueSkipsOutOfRangeMonths(t *testing.T) {
+	day, zero, thirteen, year := 5, 0, 13, 1990
+	tests := []struct {
+		name      string
+		date      models.ContactImportantDate
+		wantValue string
+		wantNote  string
+	}{
+		{"yearless month zero", models.ContactImportantDate{Day: &day, Month: &zero}, "", ""},
+		{"yearless month thirteen", models.ContactImportantDate{Day: &day, Month: &thirteen}, "", ""},
+		{"dated month thirteen", models.ContactImportantDate{Day: &day, Month: &thirteen, Year: &year}, "1990", ""},
+	}
+	for _, tt := range tests {
+		value, note := gedcomDateValue(&tt.date)
+		if value != tt.wantValue || note != tt.wantNote {
+			t.Fatalf("%s: gedcomDateValue = (%q, %q), want (%q, %q)", tt.name, value, note, tt.wantValue, tt.wantNote)
+		}
+	}
+}
+
+func TestParseGedcomDate(t *testing.T) {
+	tests := []struct {
+		input            string
+		ok               bool
+		year, month, day int
+	}{
+		{"12 JAN 1950", true, 1950, 1, 12},
+		{"@#DGREGORIAN@ 1 feb 1900", true, 1900, 2, 1},
+		{"GREGORIAN 5 MAY 2001", true, 2001, 5, 5},
+		{"ABT 1900", true, 1900, 0, 0},
+		{"BET 1 JAN 1900 AND 1 JAN 1901", true, 1900, 1, 1},
+		{"FROM MAR 1850 TO 1860", true, 1850, 3, 0},
+		{"11 FEB 1731/32", true, 1731, 2, 11},
+		{"INT 1900 (around the turn of the century)", true, 1900, 0, 0},
+		{"31 FEB 1900", false, 0, 0, 0},
+		{"@#DJULIAN@ 1 JAN 1700", false, 0, 0, 0},
+		{"(unknown)", false, 0, 0, 0},
+		{"", false, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		got, ok := parseGedcomDate(tt.input)
+		if ok != tt.ok {
+			t.Fatalf("parseGedcomDate(%q) ok = %v, want %v", tt.input, ok, tt.ok)
+		}
+		if !ok {
+			continue
+		}
+		if *got.year != tt.year {
+			t.Fatalf("parseGedcomDate(%q) year = %d, want %d", tt.input, *got.year, tt.year)
+		}
+		if (tt.month == 0) != (got.month == nil) || (got.month != nil && *got.month != tt.month) {
+			t.Fatalf("parseGedcomDate(%q) month mismatch, want %d", tt.input, tt.month)
+		}
+		if (tt.day == 0) != (got.day == nil) || (got.day != nil && *got.day != tt.day) {
+			t.Fatalf("parseGedcomDate(%q) day mismatch, want %d", tt.input, tt.day)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrGeocodingDisabled = errors.New("geocoding is disabled")
+
+// geocodingRateLimits are the minimum delays between two requests to a
+// provider, as their usage policies ask. Nominatim allows one request per
+// second and LocationIQ's free plan two.
+var geocodingRateLimits = map[string]time.Duration{
+	"nominatim":  time.Second,
+	"locationiq": 500 * time.Millisecond,
+}
+
+const (
+	defaultGeocodingRateLimit = time.Second
+	// A run takes at most half of each minute, so the requests of users
+	// looking up a place are not queued behind a long run.
+	geocodingRunBudget   = 30 * time.Second
+	geocodingBatchSize   = 50
+	geocodingMaxAttempts = 6
+	// After this many failed requests in a row the provider is taken to be
+	// down or limiting us, and the run ends early.
+	geocodingMaxFailures = 3
+	// Addresses the provider could not find are looked up again after a
+	// while, as the provider's data improves.
+	geocodingNotFoundTTL = 30 * 24 * time.Hour
+	// How long a lookup for a user waits for the provider's rate limit.
+	geocodingLookupWait = 10 * time.Second
+)
+
+// GeocodingService looks up the coordinates of addresses in the background.
+// Addresses are queued as they are saved, and a cron job works through the
+// queue at the pace the provider allows, answering repeated addresses from
+// a cache shared by all accounts. Failed lookups are retried with backoff.
+// The provider's rate limit is kept in the database, so it holds across
+// servers.
+type GeocodingService struct {
+	db       *gorm.DB
+	provider string
+	geocoder Geocoder
+	interval time.Duration
+}
+
+func NewGeocodingService(db *gorm.DB) *GeocodingService {
+	return &GeocodingService{db: db}
+}
+
+// SetGeocoder sets the provider lookups go to. The name chooses its rate
+// limit; any Geocoder can stand in for one, such as a local server in
+// tests.
+func (s *GeocodingService) SetGeocoder(provider string, g Geocoder) {
+	s.provider = provider
+	s.geocoder = g
+	s.interval = defaultGeocodingRateLimit
+	if interval, ok := geocodingRateLimits[provider]; ok {
+		s.interval = interval
+	}
+}
+
+// Backfill queues every address of the vault that has no coordinates.
+func (s *GeocodingService) Backfill(vaultID string) (*dto.GeocodingBackfillResponse, error) {
+	if s.geocoder == nil {
+		return nil, ErrGeocodingDisabled
+	}
+	resp := &dto.GeocodingBackfillResponse{}
+	var addresses []models.Address
+	err := s.db.Where("vault_id = ? AND (latitude IS NULL OR longitude IS NULL)", vaultID).
+		FindInBatches(&addresses, 200, func(tx *gorm.DB, _ int) error {
+			queued, err := queueGeocoding(s.db, addresses...)
+			resp.Queued += queued
+			return err
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.GeocodingTask{}).Where("vault_id = ?", vaultID).Count(&resp.Pending).Error; err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ProcessQueue geocodes the queued addresses that are due, until the queue
+// is empty or the run's time is up. It returns how many addresses got
+// coordinates.
+func (s *GeocodingService) ProcessQueue() (int, error) {
+	if s.geocoder == nil {
+		return 0, nil
+	}
+	deadline := time.Now().Add(geocodingRunBudget)
+	located, failures := 0, 0
+	for {
+		var tasks []models.GeocodingTask
+		if err := s.db.Where("next_attempt_at <= ?", time.Now()).
+			Order("next_attempt_at ASC, id ASC").Limit(geocodingBatchSize).Find(&tasks).Error; err != nil {
+			return located, err
+		}
+		if len(tasks) == 0 {
+			return located, nil
+		}
+		for i := range tasks {
+			found, err := s.processTask(&tasks[i], deadline)
+			if errors.Is(err, errGeocodingBudget) {
+				return located, nil
+			}
+			if err != nil {
+				failures++
+				if failures >= geocodingMaxFailures {
+					return located, err
+				}
+				continue
+			}
+			failures = 0
+			if found {
+				located++
+			}
+		}
+	}
+}
+
+var errGeocodingBudget = errors.New("geocoding run out of time")
+
+// processTask geocodes the address of a task and takes it off the queue,
+// or schedules another attempt when the provider fails.
+func (s *GeocodingService) processTask(task *models.GeocodingTask, deadline time.Time) (bool, error) {
+	var address models.Address
+	if err := s.db.First(&address, task.AddressID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, s.db.Delete(task).Error
+		}
+		return false, err
+	}
+	query := geocodingQuery(&address)
+	if query == "" || (address.Latitude != nil && address.Longitude != nil) {
+		return false, s.db.Delete(task).Error
+	}
+
+	entry, err := s.lookup(query, deadline)
+	if errors.Is(err, errGeocodingBudget) {
+		return false, err
+	}
+	if err != nil {
+		s.retryLater(task, err)
+		return false, err
+	}
+
+	return entry.Latitude != nil, s.db.Transaction(func(tx *gorm.DB) error {
+		if entry.Latitude != nil && entry.Longitude != nil {
+			if err := tx.Model(&address).Updates(map[string]interface{}{
+				"latitude":  *entry.Latitude,
+				"longitude": *entry.Longitude,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(task).Error
+	})
+}
+
+// Lookup geocodes a place a user typed, such as a city, answering from the
+// cache when it can. It returns nil when the provider does not know the
+// place.
+func (s *GeocodingService) Lookup(query string) (*GeocodingResult, error) {
+	if s.geocoder == nil {
+		return nil, ErrGeocodingDisabled
+	}
+	entry, err := s.lookup(query, time.Now().Add(geocodingLookupWait))
+	if err != nil || entry.Latitude == nil || entry.Longitude == nil {
+		return nil, err
+	}
+	return &GeocodingResult{Latitude: *entry.Latitude, Longitude: *entry.Longitude}, nil
+}
+
+// lookup answers a query from the cache, or asks the provider once its
+// rate limit allows and caches the answer. Entries without coordinates
+// are places the provider could not find.
+func (s *GeocodingService) lookup(query string, deadline time.Time) (*models.GeocodingCacheEntry, error) {
+	normalized := normalizeGeocodingQuery(query)
+	sum := sha256.Sum256([]byte(normalized))
+	hash := hex.EncodeToString(sum[:])
+	var entry models.GeocodingCacheEntry
+	err := s.db.Where("query_hash = ?", hash).First(&entry).Error
+	if err == nil && (entry.Latitude != nil || time.Since(entry.UpdatedAt) < geocodingNotFoundTTL) {
+		return &entry, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	ok, err := s.wait(deadline)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errGeocodingBudget
+	}
+	result, err := s.geocoder.Geocode(query)
+	if err != nil {
+		return nil, err
+	}
+	entry = models.GeocodingCacheEntry{QueryHash: hash, Query: normalized, Provider: s.provider}
+	if result != nil {
+		entry.Latitude = &result.Latitude
+		entry.Longitude = &result.Longitude
+	}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "query_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"provider", "latitude", "longitude", "updated_at"}),
+	}).Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// retryLater schedules the next attempt of a failed task, waiting four
+// times as long after each failure, or gives up on it.
+func (s *GeocodingService) retryLater(task *models.GeocodingTask, cause error) {
+	attempts := task.Attempts + 1
+	if attempts >= geocodingMaxAttempts {
+		log.Printf("[geocoding] giving up on address %d after %d attempts: %v", task.AddressID, attempts, cause)
+		if err := s.db.Delete(task).Error; err != nil {
+			log.Printf("[geocoding] failed to drop task of address %d: %v", task.AddressID, err)
+		}
+		return
+	}
+	delay := time.Minute << (2 * (attempts - 1))
+	message := cause.Error()
+	if err := s.db.Model(task).Updates(map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": time.Now().Add(delay),
+		"last_error":      message,
+	}).Error; err != nil {
+		log.Printf("[geocoding] failed to reschedule address %d: %v", task.AddressID, err)
+	}
+}
+
+// wait blocks until the provider's rate limit allows the next request. It
+// reports false, without waiting, when that is after the deadline. The
+// slot is claimed by moving the provider's next request time forward only
+// if no other lookup moved it first. The time is rounded up to the
+// millisecond, so requests are never closer than the interval.
+func (s *GeocodingService) wait(deadline time.Time) (bool, error) {
+	for {
+		var limit models.GeocodingRateLimit
+		err := s.db.Where("provider = ?", s.provider).First(&limit).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			limit = models.GeocodingRateLimit{Provider: s.provider}
+			err = s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&limit).Error
+		}
+		if err != nil {
+			return false, err
+		}
+		now := time.Now()
+		at := time.UnixMilli(limit.NextRequestAt)
+		if at.Before(now) {
+			at = now
+		}
+		if at.After(deadline) {
+			return false, nil
+		}
+		result := s.db.Model(&models.GeocodingRateLimit{}).
+			Where("provider = ? AND next_request_at = ?", s.provider, limit.NextRequestAt).
+			Update("next_request_at", at.Add(s.interval+time.Millisecond-1).UnixMilli())
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 1 {
+			time.Sleep(time.Until(at))
+			return true, nil
+		}
+	}
+}
+
+// queueGeocoding queues the addresses that have no coordinates for the
+// geocoding worker. Queuing an address again makes it due right away. It
+// returns how many addresses were queued.
+func queueGeocoding(tx *gorm.DB, addresses ...models.Address) (int, error) {
+	now := time.Now()
+	tasks := make([]models.GeocodingTask, 0, len(addresses))
+	for i := range addresses {
+		a := &addresses[i]
+		if (a.Latitude != nil && a.Longitude != nil) || geocodingQuery(a) == "" {
+			continue
+		}
+		tasks = append(tasks, models.GeocodingTask{AddressID: a.ID, VaultID: a.VaultID, NextAttemptAt: now})
+	}
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "address_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"attempts":        0,
+			"next_attempt_at": now,
+			"last_error":      nil,
+		}),
+	}).Create(&tasks).Error
+	return len(tasks), err
+}
+
+// QueueGeocoding is queueGeocoding for the DAV backends, which write rows
+// directly instead of going through the services.
+func QueueGeocoding(tx *gorm.DB, addresses ...models.Address) (int, error) {
+	return queueGeocoding(tx, addresses...)
+}
+
+// geocodingQuery is the text an address is looked up by.
+func geocodingQuery(a *models.Address) string {
+	parts := []string{}
+	for _, p := range []*string{a.Line1, a.City, a.Province, a.PostalCode, a.Country} {
+		if p != nil && strings.TrimSpace(*p) != "" {
+			parts = append(parts, strings.TrimSpace(*p))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// normalizeGeocodingQuery makes the spellings of an address that differ
+// only in case, spacing and punctuation share a cache entry.
+func normalizeGeocodingQuery(query string) string {
+	fields := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return r == ',' || r == ';' || r == '.' || r == ' ' || r == '\t' || r == '\n'
+	})
+	return strings.Join(fields, " ")
+}
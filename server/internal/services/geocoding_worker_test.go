@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// stubGeocoder stands in for a geocoding provider and counts its lookups.
+type stubGeocoder struct {
+	queries []string
+	result  *GeocodingResult
+	err     error
+}
+
+func (g *stubGeocoder) Geocode(address string) (*GeocodingResult, error) {
+	g.queries = append(g.queries, address)
+	return g.result, g.err
+}
+
+type geocodingFixture struct {
+	db        *gorm.DB
+	svc       *GeocodingService
+	geocoder  *stubGeocoder
+	addresses *AddressService
+	vaultID   string
+	contactID string
+}
+
+func setupGeocodingTest(t *testing.T) geocodingFixture {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	resp, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Test",
+		LastName:  "User",
+		Email:     "geocoding-test@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	vault, err := NewVaultService(db).CreateVault(resp.User.AccountID, resp.User.ID, dto.CreateVaultRequest{Name: "Test Vault"}, "en")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+	contact, err := NewContactService(db).CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: "John"})
+	if err != nil {
+		t.Fatalf("CreateContact failed: %v", err)
+	}
+	geocoder := &stubGeocoder{result: &GeocodingResult{Latitude: 45.52, Longitude: -122.68}}
+	svc := NewGeocodingService(db)
+	svc.SetGeocoder("stub", geocoder)
+	svc.interval = 0
+	return geocodingFixture{db: db, svc: svc, geocoder: geocoder, addresses: NewAddressService(db), vaultID: vault.ID, contactID: contact.ID}
+}
+
+func (f geocodingFixture) createAddress(t *testing.T, line1, city string) uint {
+	t.Helper()
+	addr, err := f.addresses.Create(f.contactID, f.vaultID, dto.CreateAddressRequest{Line1: line1, City: city, Country: "US"})
+	if err != nil {
+		t.Fatalf("Create address failed: %v", err)
+	}
+	return addr.ID
+}
+
+func TestGeocodingQueueUsesCache(t *testing.T) {
+	f := setupGeocodingTest(t)
+	first := f.createAddress(t, "123 Main St", "Portland")
+	second := f.createAddress(t, "123  main st.", "PORTLAND")
+
+	located, err := f.svc.ProcessQueue()
+	if err != nil {
+		t.Fatalf("ProcessQueue failed: %v", err)
+	}
+	if located != 2 {
+		t.Errorf("expected 2 located addresses, got %d", located)
+	}
+	if len(f.geocoder.queries) != 1 || f.geocoder.queries[0] != "123 Main St, Portland, US" {
+		t.Errorf("expected one lookup shared by both spellings, got %v", f.geocoder.queries)
+	}
+	for _, id := range []uint{first, second} {
+		var addr models.Address
+		f.db.First(&addr, id)
+		if addr.Latitude == nil || *addr.Latitude != 45.52 || addr.Longitude == nil || *addr.Longitude != -122.68 {
+			t.Errorf("address %d has coordinates %v, %v", id, addr.Latitude, addr.Longitude)
+		}
+	}
+	var pending int64
+	f.db.Model(&models.GeocodingTask{}).Count(&pending)
+	if pending != 0 {
+		t.Errorf("expected an empty queue, got %d tasks", pending)
+	}
+}
+
+func TestGeocodingQueueRetriesWithBackoff(t *testing.T) {
+	f := setupGeocodingTest(t)
+	id := f.createAddress(t, "1 Failing Road", "Nowhere")
+	f.geocoder.err = errors.New("status 503")
+
+	if _, err := f.svc.ProcessQueue(); err != nil {
+		t.Fatalf("ProcessQueue failed: %v", err)
+	}
+	var task models.GeocodingTask
+	if err := f.db.Where("address_id = ?", id).First(&task).Error; err != nil {
+		t.Fatalf("expected the task to stay queued: %v", err)
+	}
+	if task.Attempts != 1 || task.LastError == nil || !task.NextAttemptAt.After(time.Now().Add(50*time.Second)) {
+		t.Errorf("unexpected retry schedule %+v", task)
+	}
+
+	// Not due yet, so the provider is left alone.
+	if _, err := f.svc.ProcessQueue(); err != nil {
+		t.Fatalf("ProcessQueue failed: %v", err)
+	}
+	if len(f.geocoder.queries) != 1 {
+		t.Errorf("expected no lookup before the retry is due, got %d", len(f.geocoder.queries))
+	}
+
+	f.geocoder.err = nil
+	f.db.Model(&task).Update("next_attempt_at", time.Now().Add(-time.Second))
+	if located, err := f.svc.ProcessQueue(); err != nil || located != 1 {
+		t.Fatalf("expected the retry to locate the address, got %d, %v", located, err)
+	}
+}
+
+func TestGeocodingQueueCachesMisses(t *testing.T) {
+	f := setupGeocodingTest(t)
+	f.geocoder.result = nil
+	id := f.createAddress(t, "Unknown Lane", "Atlantis")
+	if located, err := f.svc.ProcessQueue(); err != nil || located != 0 {
+		t.Fatalf("expected nothing located, got %d, %v", located, err)
+	}
+	var addr models.Address
+	f.db.First(&addr, id)
+	if addr.Latitude != nil {
+		t.Errorf("expected no coordinates, got %v", *addr.Latitude)
+	}
+
+	f.createAddress(t, "Unknown Lane", "Atlantis")
+	if _, err := f.svc.ProcessQueue(); err != nil {
+		t.Fatalf("ProcessQueue failed: %v", err)
+	}
+	if len(f.geocoder.queries) != 1 {
+		t.Errorf("expected the miss to be cached, got %d lookups", len(f.geocoder.queries))
+	}
+}
+
+func TestGeocodingBackfill(t *testing.T) {
+	f := setupGeocodingTest(t)
+	lat, lon := 1.0, 2.0
+	addresses := []models.Address{
+		{VaultID: f.vaultID, City: strPtrOrNil("Berlin")},
+		{VaultID: f.vaultID, City: strPtrOrNil("Paris"), Latitude: &lat, Longitude: &lon},
+		{VaultID: f.vaultID},
+	}
+	if err := f.db.Create(&addresses).Error; err != nil {
+		t.Fatalf("create addresses: %v", err)
+	}
+
+	resp, err := f.svc.Backfill(f.vaultID)
+	if err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+	if resp.Queued != 1 || resp.Pending != 1 {
+		t.Errorf("expected only Berlin to be queued, got %+v", resp)
+	}
+
+	if _, err := NewGeocodingService(f.db).Backfill(f.vaultID); !errors.Is(err, ErrGeocodingDisabled) {
+		t.Errorf("expected ErrGeocodingDisabled, got %v", err)
+	}
+}
+
+func TestGeocodingRateLimit(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewGeocodingService(db)
+	svc.SetGeocoder("nominatim", &stubGeocoder{})
+	if svc.interval != time.Second {
+		t.Fatalf("expected Nominatim's one request per second, got %v", svc.interval)
+	}
+	svc.interval = 40 * time.Millisecond
+	// Another server sharing the database.
+	other := NewGeocodingService(db)
+	other.SetGeocoder("nominatim", &stubGeocoder{})
+	other.interval = svc.interval
+
+	start := time.Now()
+	deadline := start.Add(time.Second)
+	for _, s := range []*GeocodingService{svc, other, svc} {
+		ok, err := s.wait(deadline)
+		if err != nil {
+			t.Fatalf("wait failed: %v", err)
+		}
+		if !ok {
+			t.Fatal("expected the request to fit before the deadline")
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected requests to be spaced out across servers, took %v", elapsed)
+	}
+	if ok, err := other.wait(time.Now()); err != nil || ok {
+		t.Errorf("expected no slot before a deadline that has passed, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,532 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/naiba/bonds/internal/chatlog"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/i18n"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+// MaxHistoryFileSize is the maximum accepted size of a history export,
+// which for WhatsApp may be a zip that includes media (50 MB).
+const MaxHistoryFileSize = 50 * 1024 * 1024
+
+// How an import logs message threads.
+const (
+	HistoryModeActivity = "activity"
+	HistoryModeNote     = "note"
+)
+
+const (
+	maxHistoryTitleLength = 255
+	maxHistoryBodyLength  = 20000
+)
+
+var (
+	ErrInvalidHistoryFormat = errors.New("invalid history format")
+	ErrInvalidHistoryMode   = errors.New("invalid history import mode")
+	ErrInvalidHistoryFile   = errors.New("invalid history file")
+)
+
+// historySources names the export formats to users.
+var historySources = map[string]string{
+	chatlog.FormatWhatsApp: "WhatsApp",
+	chatlog.FormatTelegram: "Telegram",
+	chatlog.FormatAndroid:  "Android",
+}
+
+// HistoryImportService imports the message and call history of phones and
+// chat apps. Calls become calls of the matched contacts and each day of a
+// conversation becomes an activity or notes. Everything it creates is
+// keyed by its source, so importing a newer export of the same history
+// only adds what is new.
+type HistoryImportService struct {
+	db            *gorm.DB
+	searchService *SearchService
+}
+
+func NewHistoryImportService(db *gorm.DB) *HistoryImportService {
+	return &HistoryImportService{db: db}
+}
+
+func (s *HistoryImportService) SetSearchService(ss *SearchService) {
+	s.searchService = ss
+}
+
+func (s *HistoryImportService) Import(vaultID, userID string, data []byte, opts dto.HistoryImportOptions) (*dto.HistoryImportResponse, error) {
+	return s.ImportWithProgress(vaultID, userID, data, opts, nil)
+}
+
+// RunImportJob runs a history_import job on the export in its input.
+func (s *HistoryImportService) RunImportJob(run *JobRun) (interface{}, []string, error) {
+	var opts dto.HistoryImportOptions
+	if err := run.Params(&opts); err != nil {
+		return nil, nil, err
+	}
+	job := run.Job()
+	if job.VaultID == nil {
+		return nil, nil, errors.New("history import job has no vault")
+	}
+	resp, err := s.ImportWithProgress(*job.VaultID, job.UserID, run.Input(), opts, run)
+	if resp == nil {
+		return nil, nil, err
+	}
+	itemErrors := resp.Errors
+	resp.Errors = []string{}
+	return resp, itemErrors, err
+}
+
+// ValidateHistoryImportOptions checks the format and mode of an import
+// before it is queued.
+func ValidateHistoryImportOptions(opts dto.HistoryImportOptions) error {
+	if _, ok := historySources[opts.Format]; !ok {
+		return ErrInvalidHistoryFormat
+	}
+	switch opts.Mode {
+	case "", HistoryModeActivity, HistoryModeNote:
+		return nil
+	}
+	return ErrInvalidHistoryMode
+}
+
+// historyImport is the state of one import run.
+type historyImport struct {
+	vaultID    string
+	user       models.User
+	format     string
+	mode       string
+	location   *time.Location
+	index      historyContactIndex
+	messagesID *uint
+	resp       *dto.HistoryImportResponse
+	matched    map[string]bool
+	unmatched  map[string]bool
+	notes      []models.Note
+}
+
+func (s *HistoryImportService) ImportWithProgress(vaultID, userID string, data []byte, opts dto.HistoryImportOptions, progress JobProgress) (*dto.HistoryImportResponse, error) {
+	if err := ValidateHistoryImportOptions(opts); err != nil {
+		return nil, err
+	}
+	if opts.Mode == "" {
+		opts.Mode = HistoryModeActivity
+	}
+
+	run := &historyImport{
+		vaultID:   vaultID,
+		format:    opts.Format,
+		mode:      opts.Mode,
+		location:  time.UTC,
+		resp:      &dto.HistoryImportResponse{Format: opts.Format, Unmatched: []string{}, Errors: []string{}},
+		matched:   map[string]bool{},
+		unmatched: map[string]bool{},
+	}
+	if err := s.db.First(&run.user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	if run.user.Timezone != nil {
+		if loc, err := time.LoadLocation(*run.user.Timezone); err == nil {
+			run.location = loc
+		}
+	}
+	owner := opts.Owner
+	if owner == "" {
+		owner = strings.TrimSpace(ptrToStr(run.user.FirstName) + " " + ptrToStr(run.user.LastName))
+	}
+	history, err := chatlog.Parse(opts.Format, data, chatlog.Options{Owner: owner, Filename: opts.Filename, Location: run.location})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHistoryFile, err)
+	}
+	if run.index, err = loadHistoryContactIndex(s.db, vaultID, run.user.EffectivePhoneRegion()); err != nil {
+		return nil, err
+	}
+	if run.messagesID, err = systemActivityTypeID(s.db, vaultID, "messages"); err != nil {
+		return nil, err
+	}
+
+	progressSetTotal(progress, len(history.Calls)+len(history.Threads))
+	for _, call := range history.Calls {
+		if progressCancelled(progress) {
+			return run.finish(), ErrJobCancelled
+		}
+		if err := s.importCall(run, call); err != nil {
+			run.resp.Errors = append(run.resp.Errors, fmt.Sprintf("call with %s at %s: %v", call.Participant.Label(), call.Time.Format(time.RFC3339), err))
+		}
+		progressAdvance(progress, 1)
+	}
+	for _, thread := range history.Threads {
+		if progressCancelled(progress) {
+			return run.finish(), ErrJobCancelled
+		}
+		if err := s.importThread(run, thread); err != nil {
+			run.resp.Errors = append(run.resp.Errors, fmt.Sprintf("conversation %s: %v", thread.Name, err))
+		}
+		progressAdvance(progress, 1)
+	}
+	if s.searchService != nil {
+		for i := range run.notes {
+			s.searchService.IndexNote(&run.notes[i])
+		}
+	}
+	return run.finish(), nil
+}
+
+func (r *historyImport) finish() *dto.HistoryImportResponse {
+	r.resp.MatchedContacts = len(r.matched)
+	r.resp.Unmatched = r.resp.Unmatched[:0]
+	for label := range r.unmatched {
+		r.resp.Unmatched = append(r.resp.Unmatched, label)
+	}
+	sort.Strings(r.resp.Unmatched)
+	return r.resp
+}
+
+// match finds the contact of a participant, noting who could not be found.
+func (r *historyImport) match(p chatlog.Participant) string {
+	contactID := r.index.find(p)
+	if contactID == "" {
+		if label := p.Label(); label != "" {
+			r.unmatched[label] = true
+		}
+		return ""
+	}
+	r.matched[contactID] = true
+	return contactID
+}
+
+func (s *HistoryImportService) importCall(run *historyImport, call chatlog.Call) error {
+	contactID := run.match(call.Participant)
+	if contactID == "" {
+		return nil
+	}
+	sourceType := run.format + "_call"
+	sourceUUID := historySourceID(call.Participant.Key(), call.Time.UTC().Format(time.RFC3339), call.Direction)
+	var exists int64
+	if err := s.db.Model(&models.Call{}).
+		Where("contact_id = ? AND source_type = ? AND source_uuid = ?", contactID, sourceType, sourceUUID).
+		Count(&exists).Error; err != nil {
+		return err
+	}
+	if exists > 0 {
+		run.resp.SkippedCount++
+		return nil
+	}
+
+	callType, whoInitiated := call.Direction, "contact"
+	switch call.Direction {
+	case chatlog.CallOutgoing:
+		whoInitiated = "me"
+	case chatlog.CallRejected:
+		callType = chatlog.CallMissed
+	}
+	record := models.Call{
+		ContactID:    contactID,
+		AuthorID:     &run.user.ID,
+		AuthorName:   historySources[run.format] + " Import",
+		CalledAt:     call.Time,
+		Type:         callType,
+		WhoInitiated: whoInitiated,
+		Answered:     call.Answered(),
+		SourceType:   &sourceType,
+		SourceUUID:   &sourceUUID,
+	}
+	if call.Duration > 0 {
+		// Calls are stored in whole minutes.
+		minutes := int(math.Ceil(call.Duration.Minutes()))
+		record.Duration = &minutes
+	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		if err := recordVaultChange(tx, run.vaultID, models.VaultChangeCall, record.ID, models.VaultChangeCreated); err != nil {
+			return err
+		}
+		// Only a call that was answered counts as talking to the contact;
+		// a missed or rejected call leaves LastTalkedTo alone.
+		if !call.Answered() {
+			return nil
+		}
+		return advanceLastTalkedTo(tx, call.Time, []string{contactID})
+	}); err != nil {
+		return err
+	}
+	run.resp.ImportedCalls++
+	return nil
+}
+
+// importThread logs each day of a conversation with the matched
+// participants.
+func (s *HistoryImportService) importThread(run *historyImport, thread chatlog.Thread) error {
+	var contactIDs []string
+	seen := map[string]bool{}
+	for _, p := range thread.Participants {
+		if id := run.match(p); id != "" && !seen[id] {
+			seen[id] = true
+			contactIDs = append(contactIDs, id)
+		}
+	}
+	if len(contactIDs) == 0 {
+		return nil
+	}
+	sort.Strings(contactIDs)
+
+	var days []string
+	byDay := map[string][]chatlog.Message{}
+	for _, m := range thread.Messages {
+		day := m.Time.In(run.location).Format("2006-01-02")
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], m)
+	}
+	title := truncateRunes(i18n.Tt(run.user.Locale, "history_import.conversation_title", map[string]string{
+		"source": historyConversationSource(run.format),
+		"name":   thread.Name,
+	}), maxHistoryTitleLength)
+	for _, day := range days {
+		messages := byDay[day]
+		sourceUUID := historySourceID(thread.Key, day)
+		body := truncateRunes(run.transcript(messages), maxHistoryBodyLength)
+		happenedAt := messages[len(messages)-1].Time
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			var outcome string
+			var err error
+			if run.mode == HistoryModeNote {
+				outcome, err = run.saveNotes(tx, contactIDs, sourceUUID, title, body, happenedAt)
+			} else {
+				outcome, err = run.saveActivity(tx, contactIDs, sourceUUID, title, body, happenedAt)
+			}
+			if err != nil {
+				return err
+			}
+			switch outcome {
+			case "created":
+				run.resp.ImportedConversations++
+			case "updated":
+				run.resp.UpdatedConversations++
+			default:
+				run.resp.SkippedCount++
+				return nil
+			}
+			if run.messagesID != nil {
+				return updateInteractionLastTalkedTo(tx, run.messagesID, &happenedAt, contactIDs)
+			}
+			return advanceLastTalkedTo(tx, happenedAt, contactIDs)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveActivity creates the activity of a conversation day, or updates it
+// when a newer export has more of the day. It reports "created",
+// "updated" or "unchanged".
+func (r *historyImport) saveActivity(tx *gorm.DB, contactIDs []string, sourceUUID, title, body string, happenedAt time.Time) (string, error) {
+	sourceType := r.format + "_chat"
+	var existing models.Activity
+	err := tx.Where("vault_id = ? AND source_type = ? AND source_uuid = ?", r.vaultID, sourceType, sourceUUID).First(&existing).Error
+	if err == nil {
+		if ptrToStr(existing.Description) == body {
+			return "unchanged", nil
+		}
+		if err := tx.Model(&existing).Updates(map[string]interface{}{"title": title, "description": body}).Error; err != nil {
+			return "", err
+		}
+		if err := replaceActivityParticipants(tx, existing.ID, contactIDs); err != nil {
+			return "", err
+		}
+		if err := recordVaultChange(tx, r.vaultID, models.VaultChangeActivity, existing.ID, models.VaultChangeUpdated); err != nil {
+			return "", err
+		}
+		return "updated", nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	local := happenedAt.In(r.location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	event := models.Activity{
+		VaultID: r.vaultID, ActivityTypeID: r.messagesID, Title: title, Description: strPtrOrNil(body),
+		StartDate: &day, StartPrecision: "day", EndStatus: "none", CalendarType: "gregorian",
+		SourceType: &sourceType, SourceUUID: &sourceUUID,
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return "", err
+	}
+	if err := recordVaultChange(tx, r.vaultID, models.VaultChangeActivity, event.ID, models.VaultChangeCreated); err != nil {
+		return "", err
+	}
+	return "created", replaceActivityParticipants(tx, event.ID, contactIDs)
+}
+
+// saveNotes creates or updates the note of a conversation day on each
+// contact.
+func (r *historyImport) saveNotes(tx *gorm.DB, contactIDs []string, sourceUUID, title, body string, happenedAt time.Time) (string, error) {
+	sourceType := r.format + "_chat"
+	outcome := "unchanged"
+	for _, contactID := range contactIDs {
+		var note models.Note
+		err := tx.Where("contact_id = ? AND source_type = ? AND source_uuid = ?", contactID, sourceType, sourceUUID).First(&note).Error
+		switch {
+		case err == nil:
+			if note.Body == body {
+				continue
+			}
+			if err := tx.Model(&note).Updates(map[string]interface{}{"title": title, "body": body, "happened_at": happenedAt}).Error; err != nil {
+				return "", err
+			}
+			if err := recordVaultChange(tx, r.vaultID, models.VaultChangeNote, note.ID, models.VaultChangeUpdated); err != nil {
+				return "", err
+			}
+			if outcome == "unchanged" {
+				outcome = "updated"
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			note = models.Note{
+				ContactID:  contactID,
+				VaultID:    r.vaultID,
+				AuthorID:   &r.user.ID,
+				Title:      strPtrOrNil(title),
+				Body:       body,
+				SourceType: &sourceType,
+				SourceUUID: &sourceUUID,
+				HappenedAt: &happenedAt,
+			}
+			if err := tx.Create(&note).Error; err != nil {
+				return "", err
+			}
+			if err := recordVaultChange(tx, r.vaultID, models.VaultChangeNote, note.ID, models.VaultChangeCreated); err != nil {
+				return "", err
+			}
+			outcome = "created"
+		default:
+			return "", err
+		}
+		r.notes = append(r.notes, note)
+	}
+	return outcome, nil
+}
+
+// transcript writes the messages of a day as "15:04 Alice: Hi" lines.
+func (r *historyImport) transcript(messages []chatlog.Message) string {
+	me := strings.TrimSpace(ptrToStr(r.user.FirstName) + " " + ptrToStr(r.user.LastName))
+	if me == "" {
+		me = r.user.Email
+	}
+	lines := make([]string, 0, len(messages))
+	for _, m := range messages {
+		sender := m.Sender
+		if m.FromMe || sender == "" {
+			sender = me
+		}
+		lines = append(lines, fmt.Sprintf("%s %s: %s", m.Time.In(r.location).Format("15:04"), sender, m.Text))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// historyContactIndex finds the contacts of a vault by phone number and by
+// name. Names shared by several contacts match none of them.
+type historyContactIndex struct {
+	region string
+	phones map[string]string
+	names  map[string]string
+}
+
+func loadHistoryContactIndex(db *gorm.DB, vaultID, region string) (historyContactIndex, error) {
+	index := historyContactIndex{region: region, phones: map[string]string{}, names: map[string]string{}}
+	var infos []struct {
+		ContactID      string
+		Data           string
+		NormalizedData *string
+	}
+	if err := db.Table("contact_information").
+		Select("contact_information.contact_id, contact_information.data, contact_information.normalized_data").
+		Joins("JOIN contact_information_types ON contact_information_types.id = contact_information.type_id").
+		Joins("JOIN contacts ON contacts.id = contact_information.contact_id").
+		Where("contacts.vault_id = ? AND contacts.deleted_at IS NULL AND contact_information_types.type = ?", vaultID, "phone").
+		Scan(&infos).Error; err != nil {
+		return index, err
+	}
+	for _, info := range infos {
+		key := csvContactKey("phone", info.Data, region)
+		if info.NormalizedData != nil {
+			key = "phone:" + strings.TrimPrefix(*info.NormalizedData, "+")
+		}
+		if _, ok := index.phones[key]; key != "" && !ok {
+			index.phones[key] = info.ContactID
+		}
+	}
+
+	var contacts []models.Contact
+	if err := db.Select("id", "first_name", "middle_name", "last_name", "nickname").
+		Where("vault_id = ?", vaultID).Find(&contacts).Error; err != nil {
+		return index, err
+	}
+	for _, c := range contacts {
+		first, middle, last := ptrToStr(c.FirstName), ptrToStr(c.MiddleName), ptrToStr(c.LastName)
+		names := []string{first + " " + last, first + " " + middle + " " + last, ptrToStr(c.Nickname)}
+		if last == "" {
+			names = append(names, first)
+		}
+		for _, name := range names {
+			key := historyNameKey(name)
+			if key == "" {
+				continue
+			}
+			if other, ok := index.names[key]; ok && other != c.ID {
+				index.names[key] = ""
+				continue
+			}
+			index.names[key] = c.ID
+		}
+	}
+	return index, nil
+}
+
+func (idx historyContactIndex) find(p chatlog.Participant) string {
+	if p.Phone != "" {
+		if id := idx.phones[csvContactKey("phone", p.Phone, idx.region)]; id != "" {
+			return id
+		}
+	}
+	if p.Name != "" {
+		return idx.names[historyNameKey(p.Name)]
+	}
+	return ""
+}
+
+// findName finds a contact by name alone.
+func (idx historyContactIndex) findName(name string) string {
+	return idx.names[historyNameKey(name)]
+}
+
+func historyNameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// historyConversationSource names where a conversation took place.
+func historyConversationSource(format string) string {
+	if format == chatlog.FormatAndroid {
+		return "SMS"
+	}
+	return historySources[format]
+}
+
+// historySourceID keys an imported record by what identifies it in the
+// export.
+func historySourceID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
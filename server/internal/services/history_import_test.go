@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+type historyImportFixture struct {
+	db      *gorm.DB
+	svc     *HistoryImportService
+	vaultID string
+	userID  string
+	aliceID string
+	bobID   string
+}
+
+func setupHistoryImportTest(t *testing.T) historyImportFixture {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	authSvc := NewAuthService(db, testutil.TestJWTConfig())
+	resp, err := authSvc.Register(dto.RegisterRequest{
+		FirstName: "Test",
+		LastName:  "User",
+		Email:     "history-test@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	vault, err := NewVaultService(db).CreateVault(resp.User.AccountID, resp.User.ID, dto.CreateVaultRequest{Name: "Test Vault"}, "en")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+
+	var phoneType models.ContactInformationType
+	if err := db.Where("account_id = ? AND type = ?", resp.User.AccountID, "phone").First(&phoneType).Error; err != nil {
+		t.Fatalf("find phone type: %v", err)
+	}
+	contactSvc := NewContactService(db)
+	alice, err := contactSvc.CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: "Alice", LastName: "Smith"})
+	if err != nil {
+		t.Fatalf("CreateContact failed: %v", err)
+	}
+	if _, err := NewContactInformationService(db).Create(alice.ID, vault.ID, resp.User.ID, dto.CreateContactInformationRequest{TypeID: phoneType.ID, Data: "+1 555 010 2000"}); err != nil {
+		t.Fatalf("Create contact information failed: %v", err)
+	}
+	bob, err := contactSvc.CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: "Bob", LastName: "Jones"})
+	if err != nil {
+		t.Fatalf("CreateContact failed: %v", err)
+	}
+	return historyImportFixture{db: db, svc: NewHistoryImportService(db), vaultID: vault.ID, userID: resp.User.ID, aliceID: alice.ID, bobID: bob.ID}
+}
+
+const historyTestCalls = `<?xml version='1.0' encoding='UTF-8' standalone='yes' ?>
+<calls count="3">
+  <call number="+15550102000" duration="65" date="1704067200000" type="1" contact_name="Alice" />
+  <call number="5550102000" duration="0" date="1704070800000" type="3" contact_name="(Unknown)" />
+  <call number="+15550109999" duration="30" date="1704074400000" type="2" contact_name="Dave" />
+</calls>`
+
+func TestHistoryImportAndroidCalls(t *testing.T) {
+	f := setupHistoryImportTest(t)
+	// The missed call is logged with a national number.
+	f.db.Model(&models.User{}).Where("id = ?", f.userID).Update("phone_region", "US")
+	opts := dto.HistoryImportOptions{Format: "android"}
+	resp, err := f.svc.Import(f.vaultID, f.userID, []byte(historyTestCalls), opts)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if resp.ImportedCalls != 2 || resp.MatchedContacts != 1 {
+		t.Errorf("expected 2 calls with Alice, got %+v", resp)
+	}
+	if len(resp.Unmatched) != 1 || resp.Unmatched[0] != "Dave" {
+		t.Errorf("unmatched = %v", resp.Unmatched)
+	}
+
+	var calls []models.Call
+	f.db.Where("contact_id = ?", f.aliceID).Order("called_at").Find(&calls)
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls on Alice, got %d", len(calls))
+	}
+	if c := calls[0]; c.Type != "incoming" || !c.Answered || c.WhoInitiated != "contact" || c.Duration == nil || *c.Duration != 2 {
+		t.Errorf("answered call = %+v", c)
+	}
+	if c := calls[1]; c.Type != "missed" || c.Answered {
+		t.Errorf("missed call = %+v", c)
+	}
+	var alice models.Contact
+	f.db.First(&alice, "id = ?", f.aliceID)
+	if alice.LastTalkedTo == nil || !alice.LastTalkedTo.Equal(calls[0].CalledAt) {
+		t.Errorf("expected last talked to to be the answered call, got %v", alice.LastTalkedTo)
+	}
+	var changes int64
+	f.db.Model(&models.VaultChange{}).Where("vault_id = ? AND entity_type = ? AND action = ?", f.vaultID, models.VaultChangeCall, models.VaultChangeCreated).Count(&changes)
+	if changes != 2 {
+		t.Errorf("expected both calls in the change log, got %d", changes)
+	}
+
+	resp, err = f.svc.Import(f.vaultID, f.userID, []byte(historyTestCalls), opts)
+	if err != nil {
+		t.Fatalf("second Import failed: %v", err)
+	}
+	if resp.ImportedCalls != 0 || resp.SkippedCount != 2 {
+		t.Errorf("expected the re-run to skip both calls, got %+v", resp)
+	}
+}
+
+func TestHistoryImportWhatsAppActivities(t *testing.T) {
+	f := setupHistoryImportTest(t)
+	chat := "01/05/2024, 10:00 - Alice Smith: Lunch with Bob?\n" +
+		"01/05/2024, 10:05 - Test User: Sure\n" +
+		"01/05/2024, 10:06 - Bob Jones: Count me in\n"
+	opts := dto.HistoryImportOptions{Format: "whatsapp", Filename: "WhatsApp Chat with Lunch.txt"}
+	resp, err := f.svc.Import(f.vaultID, f.userID, []byte(chat), opts)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if resp.ImportedConversations != 1 || resp.MatchedContacts != 2 || len(resp.Unmatched) != 0 {
+		t.Errorf("unexpected result %+v", resp)
+	}
+
+	var activities []models.Activity
+	f.db.Where("vault_id = ? AND source_type = ?", f.vaultID, "whatsapp_chat").Find(&activities)
+	if len(activities) != 1 {
+		t.Fatalf("expected 1 activity, got %d", len(activities))
+	}
+	activity := activities[0]
+	if activity.Title != "WhatsApp conversation with Lunch" {
+		t.Errorf("title = %q", activity.Title)
+	}
+	if body := ptrToStr(activity.Description); !strings.Contains(body, "10:05 Test User: Sure") {
+		t.Errorf("transcript = %q", body)
+	}
+	var activityType models.ActivityType
+	if activity.ActivityTypeID == nil || f.db.First(&activityType, *activity.ActivityTypeID).Error != nil || ptrToStr(activityType.SystemKind) != "messages" {
+		t.Errorf("expected the messages activity type, got %v", activity.ActivityTypeID)
+	}
+	var participants int64
+	f.db.Model(&models.ActivityParticipant{}).Where("activity_id = ?", activity.ID).Count(&participants)
+	if participants != 2 {
+		t.Errorf("expected Alice and Bob as participants, got %d", participants)
+	}
+
+	resp, err = f.svc.Import(f.vaultID, f.userID, []byte(chat), opts)
+	if err != nil {
+		t.Fatalf("second Import failed: %v", err)
+	}
+	if resp.ImportedConversations != 0 || resp.UpdatedConversations != 0 || resp.SkippedCount != 1 {
+		t.Errorf("expected the re-run to change nothing, got %+v", resp)
+	}
+
+	longer := chat + "01/05/2024, 18:00 - Alice Smith: That was fun\n" +
+		"02/05/2024, 09:00 - Alice Smith: Morning\n"
+	resp, err = f.svc.Import(f.vaultID, f.userID, []byte(longer), opts)
+	if err != nil {
+		t.Fatalf("third Import failed: %v", err)
+	}
+	if resp.ImportedConversations != 1 || resp.UpdatedConversations != 1 {
+		t.Errorf("expected a new day and an updated one, got %+v", resp)
+	}
+	var count int64
+	f.db.Model(&models.Activity{}).Where("vault_id = ? AND source_type = ?", f.vaultID, "whatsapp_chat").Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 activities, got %d", count)
+	}
+}
+
+func TestHistoryImportNoteMode(t *testing.T) {
+	f := setupHistoryImportTest(t)
+	chat := "01/05/2024, 10:00 - Bob Jones: Hi\n" +
+		"01/05/2024, 10:01 - Test User: Hello\n"
+	resp, err := f.svc.Import(f.vaultID, f.userID, []byte(chat), dto.HistoryImportOptions{Format: "whatsapp", Mode: "note", Filename: "WhatsApp Chat with Bob Jones.txt"})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if resp.ImportedConversations != 1 {
+		t.Errorf("unexpected result %+v", resp)
+	}
+	var notes []models.Note
+	f.db.Where("contact_id = ?", f.bobID).Find(&notes)
+	if len(notes) != 1 || !strings.Contains(notes[0].Body, "10:00 Bob Jones: Hi") {
+		t.Fatalf("expected a note on Bob, got %+v", notes)
+	}
+	var activities int64
+	f.db.Model(&models.Activity{}).Where("vault_id = ? AND source_type IS NOT NULL", f.vaultID).Count(&activities)
+	if activities != 0 {
+		t.Errorf("expected no activity in note mode, got %d", activities)
+	}
+}
+
+func TestHistoryImportRejectsInvalidInput(t *testing.T) {
+	f := setupHistoryImportTest(t)
+	if _, err := f.svc.Import(f.vaultID, f.userID, nil, dto.HistoryImportOptions{Format: "signal"}); !errors.Is(err, ErrInvalidHistoryFormat) {
+		t.Errorf("expected ErrInvalidHistoryFormat, got %v", err)
+	}
+	if _, err := f.svc.Import(f.vaultID, f.userID, nil, dto.HistoryImportOptions{Format: "whatsapp", Mode: "diary"}); !errors.Is(err, ErrInvalidHistoryMode) {
+		t.Errorf("expected ErrInvalidHistoryMode, got %v", err)
+	}
+	if _, err := f.svc.Import(f.vaultID, f.userID, []byte("not json"), dto.HistoryImportOptions{Format: "telegram"}); !errors.Is(err, ErrInvalidHistoryFile) {
+		t.Errorf("expected ErrInvalidHistoryFile, got %v", err)
+	}
+}
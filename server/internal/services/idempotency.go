@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/naiba/bonds/internal/database"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdempotencyKeyMismatch   = errors.New("idempotency key was used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still running")
+)
+
+const (
+	defaultIdempotencyTTLHours = 24
+	// idempotencyPendingTimeout is how long a request may hold its key
+	// before a retry assumes the server running it has gone away.
+	idempotencyPendingTimeout = 10 * time.Minute
+)
+
+type IdempotencyService struct {
+	db       *gorm.DB
+	settings *SystemSettingService
+}
+
+func NewIdempotencyService(db *gorm.DB) *IdempotencyService {
+	return &IdempotencyService{db: db}
+}
+
+func (s *IdempotencyService) SetSystemSettings(settings *SystemSettingService) {
+	s.settings = settings
+}
+
+// ttl is how long a response is kept for replay, from the
+// idempotency.ttl_hours setting.
+func (s *IdempotencyService) ttl() time.Duration {
+	hours := defaultIdempotencyTTLHours
+	if s.settings != nil {
+		hours = s.settings.GetInt("idempotency.ttl_hours", defaultIdempotencyTTLHours)
+	}
+	if hours < 1 {
+		hours = defaultIdempotencyTTLHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// Begin claims a key for a request. When claimed is true the caller runs the
+// request and then calls Complete or Release. Otherwise the returned record
+// holds the stored response of an identical earlier request. The unique
+// index on user and key decides between concurrent requests, also across
+// replicas.
+func (s *IdempotencyService) Begin(userID, key, requestHash string) (record *models.IdempotencyKey, claimed bool, err error) {
+	// A second attempt follows taking over an expired or abandoned key.
+	for attempt := 0; attempt < 2; attempt++ {
+		now := time.Now()
+		record = &models.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: requestHash,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(s.ttl()),
+		}
+		err = s.db.Create(record).Error
+		if err == nil {
+			return record, true, nil
+		}
+		if !database.IsUniqueConstraintErr(err) {
+			return nil, false, err
+		}
+
+		var existing models.IdempotencyKey
+		if err := s.db.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, false, err
+		}
+		if existing.ExpiresAt.After(now) && (existing.Completed || existing.CreatedAt.After(now.Add(-idempotencyPendingTimeout))) {
+			if existing.RequestHash != requestHash {
+				return nil, false, ErrIdempotencyKeyMismatch
+			}
+			if !existing.Completed {
+				return nil, false, ErrIdempotencyKeyInProgress
+			}
+			return &existing, false, nil
+		}
+		if err := s.db.Where("id = ? AND (expires_at < ? OR (completed = ? AND created_at < ?))",
+			existing.ID, now, false, now.Add(-idempotencyPendingTimeout)).
+			Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return nil, false, err
+		}
+	}
+	return nil, false, ErrIdempotencyKeyInProgress
+}
+
+// Complete stores the response of a claimed request for replay.
+func (s *IdempotencyService) Complete(record *models.IdempotencyKey, statusCode int, contentType string, body []byte) error {
+	return s.db.Model(&models.IdempotencyKey{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+		"completed":    true,
+		"status_code":  statusCode,
+		"content_type": contentType,
+		"body":         body,
+	}).Error
+}
+
+// CompleteTooLarge marks a claimed request as done without storing its
+// response, so that retries are refused instead of running it again.
+func (s *IdempotencyService) CompleteTooLarge(record *models.IdempotencyKey, statusCode int) error {
+	return s.db.Model(&models.IdempotencyKey{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+		"completed":          true,
+		"status_code":        statusCode,
+		"response_too_large": true,
+	}).Error
+}
+
+// Release gives up a claimed key so that a retry runs the request again.
+// It is only safe while the request has not changed anything.
+func (s *IdempotencyService) Release(record *models.IdempotencyKey) error {
+	return s.db.Delete(&models.IdempotencyKey{}, record.ID).Error
+}
+
+// CleanupExpired deletes keys past their replay window.
+func (s *IdempotencyService) CleanupExpired() (int64, error) {
+	result := s.db.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
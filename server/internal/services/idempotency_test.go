@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+)
+
+func TestIdempotencyBeginCompleteReplay(t *testing.T) {
+	svc := NewIdempotencyService(testutil.SetupTestDB(t))
+
+	record, claimed, err := svc.Begin("user-1", "key-1", "hash-a")
+	if err != nil || !claimed {
+		t.Fatalf("expected to claim the key, got claimed=%v err=%v", claimed, err)
+	}
+	if _, _, err := svc.Begin("user-1", "key-1", "hash-a"); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Fatalf("expected ErrIdempotencyKeyInProgress while running, got %v", err)
+	}
+	// Keys are scoped to the user.
+	if _, claimed, err := svc.Begin("user-2", "key-1", "hash-a"); err != nil || !claimed {
+		t.Fatalf("expected another user to claim the same key, got claimed=%v err=%v", claimed, err)
+	}
+
+	if err := svc.Complete(record, http.StatusCreated, "application/json", []byte(`{"id":1}`)); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	replay, claimed, err := svc.Begin("user-1", "key-1", "hash-a")
+	if err != nil || claimed {
+		t.Fatalf("expected a replay, got claimed=%v err=%v", claimed, err)
+	}
+	if replay.StatusCode != http.StatusCreated || string(replay.Body) != `{"id":1}` {
+		t.Errorf("unexpected stored response %d %s", replay.StatusCode, replay.Body)
+	}
+	if _, _, err := svc.Begin("user-1", "key-1", "hash-b"); !errors.Is(err, ErrIdempotencyKeyMismatch) {
+		t.Errorf("expected ErrIdempotencyKeyMismatch, got %v", err)
+	}
+}
+
+func TestIdempotencyReleaseAndExpiry(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewIdempotencyService(db)
+
+	record, _, err := svc.Begin("user-1", "key-1", "hash-a")
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := svc.Release(record); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, claimed, err := svc.Begin("user-1", "key-1", "hash-b"); err != nil || !claimed {
+		t.Fatalf("expected a released key to be claimable, got claimed=%v err=%v", claimed, err)
+	}
+
+	// A request that never finished is taken over after the pending timeout.
+	db.Model(&models.IdempotencyKey{}).Where("idempotency_key = ?", "key-1").
+		Update("created_at", time.Now().Add(-2*idempotencyPendingTimeout))
+	if _, claimed, err := svc.Begin("user-1", "key-1", "hash-c"); err != nil || !claimed {
+		t.Fatalf("expected an abandoned key to be taken over, got claimed=%v err=%v", claimed, err)
+	}
+
+	// An expired response is no longer replayed.
+	record, _, _ = svc.Begin("user-1", "key-2", "hash-a")
+	svc.Complete(record, http.StatusOK, "application/json", nil)
+	db.Model(&models.IdempotencyKey{}).Where("id = ?", record.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, claimed, err := svc.Begin("user-1", "key-2", "hash-b"); err != nil || !claimed {
+		t.Fatalf("expected an expired key to be claimable, got claimed=%v err=%v", claimed, err)
+	}
+
+	db.Model(&models.IdempotencyKey{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Minute))
+	if n, err := svc.CleanupExpired(); err != nil || n != 2 {
+		t.Errorf("expected 2 expired keys to be deleted, got %d %v", n, err)
+	}
+}
@@ -12,13 +12,18 @@ var ErrImportantDateNotFound = errors.New("important date not found")
 var ErrImportantDateLabelRequired = errors.New("label is required when no type is selected")
 
 type ImportantDateService struct {
-	db *gorm.DB
+	db           *gorm.DB
+	feedRecorder *FeedRecorder
 }
 
 func NewImportantDateService(db *gorm.DB) *ImportantDateService {
 	return &ImportantDateService{db: db}
 }
 
+func (s *ImportantDateService) SetFeedRecorder(fr *FeedRecorder) {
+	s.feedRecorder = fr
+}
+
 func (s *ImportantDateService) List(contactID, vaultID string) ([]dto.ImportantDateResponse, error) {
 	if err := validateContactBelongsToVault(s.db, contactID, vaultID); err != nil {
 		return nil, err
@@ -64,14 +69,26 @@ func (s *ImportantDateService) Create(contactID, vaultID string, req dto.CreateI
 	if err := validateImportantDateCalendarDay(&date); err != nil {
 		return nil, err
 	}
-	if err := s.db.Create(&date).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&date).Error; err != nil {
+			return err
+		}
+		if req.RemindMe != nil && *req.RemindMe && importantDateCanScheduleReminder(&date) {
+			date.RemindMe = true
+			if err := tx.Model(&date).Update("remind_me", true).Error; err != nil {
+				return err
+			}
+			if err := ensureImportantDateReminder(tx, contactID, &date); err != nil {
+				return err
+			}
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeImportantDate, date.ID, models.VaultChangeCreated)
+	}); err != nil {
 		return nil, err
 	}
-
-	if req.RemindMe != nil && *req.RemindMe && importantDateCanScheduleReminder(&date) {
-		date.RemindMe = true
-		s.db.Model(&date).Update("remind_me", true)
-		s.ensureReminder(contactID, &date)
+	if s.feedRecorder != nil {
+		entityType := "ContactImportantDate"
+		s.feedRecorder.Record(contactID, "", ActionImportantDateAdded, "Added an important date: "+label, &date.ID, &entityType)
 	}
 
 	resp := toImportantDateResponse(&date)
@@ -113,29 +130,16 @@ func (s *ImportantDateService) Update(id uint, contactID, vaultID string, req dt
 	if err := validateImportantDateCalendarDay(&date); err != nil {
 		return nil, err
 	}
-	if err := s.db.Save(&date).Error; err != nil {
-		return nil, err
-	}
-
-	if !importantDateCanScheduleReminder(&date) {
-		if date.RemindMe {
-			s.db.Model(&date).Update("remind_me", false)
-			date.RemindMe = false
-		}
-		s.removeReminder(contactID, date.ID)
-	} else if req.RemindMe != nil {
-		newRemindMe := *req.RemindMe && importantDateCanScheduleReminder(&date)
-		if newRemindMe != oldRemindMe {
-			s.db.Model(&date).Update("remind_me", newRemindMe)
-			date.RemindMe = newRemindMe
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&date).Error; err != nil {
+			return err
 		}
-		if newRemindMe {
-			s.ensureReminder(contactID, &date)
-		} else {
-			s.removeReminder(contactID, date.ID)
+		if err := syncImportantDateReminder(tx, contactID, &date, req.RemindMe, oldRemindMe); err != nil {
+			return err
 		}
-	} else if date.RemindMe {
-		s.ensureReminder(contactID, &date)
+		return recordVaultChange(tx, vaultID, models.VaultChangeImportantDate, date.ID, models.VaultChangeUpdated)
+	}); err != nil {
+		return nil, err
 	}
 
 	resp := toImportantDateResponse(&date)
@@ -146,15 +150,19 @@ func (s *ImportantDateService) Delete(id uint, contactID, vaultID string) error
 	if err := validateContactBelongsToVault(s.db, contactID, vaultID); err != nil {
 		return err
 	}
-	s.removeReminder(contactID, id)
-	result := s.db.Where("id = ? AND contact_id = ?", id, contactID).Delete(&models.ContactImportantDate{})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return ErrImportantDateNotFound
-	}
-	return nil
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := removeImportantDateReminder(tx, contactID, id); err != nil {
+			return err
+		}
+		result := tx.Where("id = ? AND contact_id = ?", id, contactID).Delete(&models.ContactImportantDate{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrImportantDateNotFound
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeImportantDate, id, models.VaultChangeDeleted)
+	})
 }
 
 func (s *ImportantDateService) resolveTypeLabel(typeID uint) string {
@@ -165,12 +173,43 @@ func (s *ImportantDateService) resolveTypeLabel(typeID uint) string {
 	return dateType.Label
 }
 
-func (s *ImportantDateService) ensureReminder(contactID string, date *models.ContactImportantDate) error {
+// syncImportantDateReminder brings the reminder of an updated date in line
+// with its remind_me flag. remindMe is nil when the request left the flag
+// alone.
+func syncImportantDateReminder(tx *gorm.DB, contactID string, date *models.ContactImportantDate, remindMe *bool, oldRemindMe bool) error {
+	if !importantDateCanScheduleReminder(date) {
+		if date.RemindMe {
+			if err := tx.Model(date).Update("remind_me", false).Error; err != nil {
+				return err
+			}
+			date.RemindMe = false
+		}
+		return removeImportantDateReminder(tx, contactID, date.ID)
+	}
+	if remindMe == nil {
+		if date.RemindMe {
+			return ensureImportantDateReminder(tx, contactID, date)
+		}
+		return nil
+	}
+	if *remindMe != oldRemindMe {
+		if err := tx.Model(date).Update("remind_me", *remindMe).Error; err != nil {
+			return err
+		}
+		date.RemindMe = *remindMe
+	}
+	if *remindMe {
+		return ensureImportantDateReminder(tx, contactID, date)
+	}
+	return removeImportantDateReminder(tx, contactID, date.ID)
+}
+
+func ensureImportantDateReminder(tx *gorm.DB, contactID string, date *models.ContactImportantDate) error {
 	if !importantDateCanScheduleReminder(date) {
 		return nil
 	}
 	var existing models.ContactReminder
-	err := s.db.Where("contact_id = ? AND important_date_id = ?", contactID, date.ID).First(&existing).Error
+	err := tx.Where("contact_id = ? AND important_date_id = ?", contactID, date.ID).First(&existing).Error
 	if err == nil {
 		existing.Label = date.Label
 		existing.Day = date.Day
@@ -180,11 +219,16 @@ func (s *ImportantDateService) ensureReminder(contactID string, date *models.Con
 		existing.OriginalDay = date.OriginalDay
 		existing.OriginalMonth = date.OriginalMonth
 		existing.OriginalYear = date.OriginalYear
-		if err := s.db.Save(&existing).Error; err != nil {
+		if err := tx.Save(&existing).Error; err != nil {
 			return err
 		}
-		NewReminderService(s.db).reschedulePendingReminder(&existing)
-		return nil
+		if err := recordContactChange(tx, contactID, models.VaultChangeReminder, existing.ID, models.VaultChangeUpdated); err != nil {
+			return err
+		}
+		return reschedulePendingReminder(tx, &existing)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
 	}
 	reminder := models.ContactReminder{
 		ContactID:       contactID,
@@ -199,23 +243,45 @@ func (s *ImportantDateService) ensureReminder(contactID string, date *models.Con
 		OriginalYear:    date.OriginalYear,
 		Type:            "recurring_year",
 	}
-	if err := s.db.Create(&reminder).Error; err != nil {
+	if err := tx.Create(&reminder).Error; err != nil {
 		return err
 	}
-	return scheduleReminderForVaultUsers(s.db, &reminder)
+	if err := recordContactChange(tx, contactID, models.VaultChangeReminder, reminder.ID, models.VaultChangeCreated); err != nil {
+		return err
+	}
+	return scheduleReminderForVaultUsers(tx, &reminder)
 }
 
-func (s *ImportantDateService) removeReminder(contactID string, dateID uint) error {
+func removeImportantDateReminder(tx *gorm.DB, contactID string, dateID uint) error {
 	// Delete scheduled entries first
-	s.db.Where("contact_reminder_id IN (SELECT id FROM contact_reminders WHERE contact_id = ? AND important_date_id = ?)", contactID, dateID).
-		Delete(&models.ContactReminderScheduled{})
-	if err := s.db.Where("contact_reminder_id IN (SELECT id FROM contact_reminders WHERE contact_id = ? AND important_date_id = ?)", contactID, dateID).
+	if err := tx.Where("contact_reminder_id IN (SELECT id FROM contact_reminders WHERE contact_id = ? AND important_date_id = ?)", contactID, dateID).
+		Delete(&models.ContactReminderScheduled{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("contact_reminder_id IN (SELECT id FROM contact_reminders WHERE contact_id = ? AND important_date_id = ?)", contactID, dateID).
 		Delete(&models.ContactReminderSelectedUser{}).Error; err != nil {
 		return err
 	}
+	if err := tx.Where("contact_reminder_id IN (SELECT id FROM contact_reminders WHERE contact_id = ? AND important_date_id = ?)", contactID, dateID).
+		Delete(&models.ContactReminderDeliveryState{}).Error; err != nil {
+		return err
+	}
+	var reminderIDs []uint
+	if err := tx.Model(&models.ContactReminder{}).Where("contact_id = ? AND important_date_id = ?", contactID, dateID).
+		Pluck("id", &reminderIDs).Error; err != nil {
+		return err
+	}
 	// Delete the reminder
-	return s.db.Where("contact_id = ? AND important_date_id = ?", contactID, dateID).
-		Delete(&models.ContactReminder{}).Error
+	if err := tx.Where("contact_id = ? AND important_date_id = ?", contactID, dateID).
+		Delete(&models.ContactReminder{}).Error; err != nil {
+		return err
+	}
+	for _, id := range reminderIDs {
+		if err := recordContactChange(tx, contactID, models.VaultChangeReminder, id, models.VaultChangeDeleted); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func toImportantDateResponse(d *models.ContactImportantDate) dto.ImportantDateResponse {
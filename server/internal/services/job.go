@@ -0,0 +1,644 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/i18n"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/pkg/response"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobFinished    = errors.New("job already finished")
+	ErrJobCancelled   = errors.New("job cancelled")
+	ErrUnknownJobType = errors.New("unknown job type")
+)
+
+const (
+	JobTypeMonicaImport  = "monica_import"
+	JobTypeCSVImport     = "csv_import"
+	JobTypeVCardImport   = "vcard_import"
+	JobTypeHistoryImport = "history_import"
+	JobTypeJournalImport = "journal_import"
+	JobTypeSearchRebuild = "search_rebuild"
+	JobTypeBackupRestore = "backup_restore"
+)
+
+const (
+	defaultJobRetentionDays = 7
+	// jobHeartbeatInterval is how often a running job stores its progress,
+	// proves it is alive and picks up cancellation requests.
+	jobHeartbeatInterval = 2 * time.Second
+	// jobStaleAfter is how long a running job may go without a heartbeat
+	// before it is considered lost along with the process that ran it.
+	jobStaleAfter = 5 * time.Minute
+	// maxJobErrors caps the per-item errors stored for one job.
+	maxJobErrors = 1000
+	// jobClaimBatch is how many queued jobs are considered per pass.
+	jobClaimBatch = 20
+)
+
+// JobProgress receives the progress of an operation that runs as a
+// background job. Operations accept a nil JobProgress when they run inside
+// a request.
+type JobProgress interface {
+	SetTotal(total int)
+	Advance(n int)
+	Cancelled() bool
+}
+
+func progressSetTotal(p JobProgress, total int) {
+	if p != nil {
+		p.SetTotal(total)
+	}
+}
+
+func progressAdvance(p JobProgress, n int) {
+	if p != nil {
+		p.Advance(n)
+	}
+}
+
+func progressCancelled(p JobProgress) bool {
+	return p != nil && p.Cancelled()
+}
+
+// JobFunc runs a job. It returns the result to store, the problems it ran
+// into with single items, and an error when the job as a whole failed.
+// Returning ErrJobCancelled marks the job as cancelled and keeps the result.
+type JobFunc func(run *JobRun) (result interface{}, itemErrors []string, err error)
+
+// JobLocker grants one process exclusive permission to start a job. The
+// cron scheduler implements it with the locks it uses for its own jobs.
+type JobLocker interface {
+	TryLock(name string) (bool, error)
+}
+
+// JobService stores long-running operations as jobs and runs them in the
+// background. Jobs are picked up right after they are queued and by a cron
+// job, so jobs queued on a replica that went away still run.
+type JobService struct {
+	db       *gorm.DB
+	settings *SystemSettingService
+	locker   JobLocker
+	mailer   Mailer
+	sender   NotificationSender
+	webPush  NotificationSender
+	funcs    map[string]JobFunc
+
+	mu       sync.Mutex
+	draining bool
+	again    bool
+}
+
+func NewJobService(db *gorm.DB) *JobService {
+	return &JobService{db: db, funcs: make(map[string]JobFunc)}
+}
+
+func (s *JobService) SetSystemSettings(settings *SystemSettingService) {
+	s.settings = settings
+}
+
+func (s *JobService) SetLocker(locker JobLocker) {
+	s.locker = locker
+}
+
+func (s *JobService) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+func (s *JobService) SetSender(sender NotificationSender) {
+	s.sender = sender
+}
+
+func (s *JobService) SetWebPush(webPush NotificationSender) {
+	s.webPush = webPush
+}
+
+// Register sets the function that runs jobs of a type.
+func (s *JobService) Register(jobType string, fn JobFunc) {
+	s.funcs[jobType] = fn
+}
+
+// Enqueue stores a job and starts running it in the background. Input holds
+// the uploaded file, if any, until the job finishes.
+func (s *JobService) Enqueue(userID string, vaultID *string, jobType string, params interface{}, input []byte) (*dto.JobResponse, error) {
+	if _, ok := s.funcs[jobType]; !ok {
+		return nil, ErrUnknownJobType
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	job := models.Job{
+		UserID:  userID,
+		VaultID: vaultID,
+		Type:    jobType,
+		Status:  models.JobQueued,
+		Params:  string(raw),
+		Input:   input,
+	}
+	if err := s.db.Create(&job).Error; err != nil {
+		return nil, err
+	}
+	go s.RunPending()
+	resp := toJobResponse(&job)
+	return &resp, nil
+}
+
+func (s *JobService) List(userID string, page, perPage int) ([]dto.JobResponse, response.Meta, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+	query := s.db.Model(&models.Job{}).Where("user_id = ?", userID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, response.Meta{}, err
+	}
+	var jobs []models.Job
+	if err := query.Omit("input").Order("id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&jobs).Error; err != nil {
+		return nil, response.Meta{}, err
+	}
+	result := make([]dto.JobResponse, len(jobs))
+	for i := range jobs {
+		result[i] = toJobResponse(&jobs[i])
+	}
+	meta := response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: int(math.Ceil(float64(total) / float64(perPage))),
+	}
+	return result, meta, nil
+}
+
+func (s *JobService) Get(id uint, userID string) (*dto.JobResponse, error) {
+	job, err := s.findJob(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	resp := toJobResponse(job)
+	return &resp, nil
+}
+
+func (s *JobService) ListErrors(id uint, userID string, page, perPage int) ([]dto.JobErrorResponse, response.Meta, error) {
+	if _, err := s.findJob(id, userID); err != nil {
+		return nil, response.Meta{}, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+	query := s.db.Model(&models.JobError{}).Where("job_id = ?", id)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, response.Meta{}, err
+	}
+	var jobErrors []models.JobError
+	if err := query.Order("id ASC").Offset((page - 1) * perPage).Limit(perPage).Find(&jobErrors).Error; err != nil {
+		return nil, response.Meta{}, err
+	}
+	result := make([]dto.JobErrorResponse, len(jobErrors))
+	for i, e := range jobErrors {
+		result[i] = dto.JobErrorResponse{ID: e.ID, Message: e.Message, CreatedAt: e.CreatedAt}
+	}
+	meta := response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: int(math.Ceil(float64(total) / float64(perPage))),
+	}
+	return result, meta, nil
+}
+
+// Cancel cancels a queued job right away. A running job is asked to stop and
+// ends up cancelled once it notices, which it does between items.
+func (s *JobService) Cancel(id uint, userID string) (*dto.JobResponse, error) {
+	job, err := s.findJob(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	res := s.db.Model(&models.Job{}).Where("id = ? AND status = ?", id, models.JobQueued).
+		Updates(map[string]interface{}{"status": models.JobCancelled, "cancel_requested": true, "finished_at": now, "input": nil})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		res = s.db.Model(&models.Job{}).Where("id = ? AND status = ?", id, models.JobRunning).Update("cancel_requested", true)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil, ErrJobFinished
+		}
+	}
+	if job, err = s.findJob(id, userID); err != nil {
+		return nil, err
+	}
+	resp := toJobResponse(job)
+	return &resp, nil
+}
+
+// Cleanup deletes the jobs that finished more than jobs.retention_days ago,
+// along with their errors and locks.
+func (s *JobService) Cleanup() (int64, error) {
+	days := defaultJobRetentionDays
+	if s.settings != nil {
+		days = s.settings.GetInt("jobs.retention_days", defaultJobRetentionDays)
+	}
+	if days < 1 {
+		days = defaultJobRetentionDays
+	}
+	var deleted int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&models.Job{}).Where("finished_at < ?", time.Now().AddDate(0, 0, -days)).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		locks := make([]string, len(ids))
+		for i, id := range ids {
+			locks[i] = jobLockName(id)
+		}
+		if err := tx.Where("job_id IN ?", ids).Delete(&models.JobError{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("command IN ?", locks).Delete(&models.Cron{}).Error; err != nil {
+			return err
+		}
+		res := tx.Where("id IN ?", ids).Delete(&models.Job{})
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	return deleted, err
+}
+
+// RunPending runs queued jobs until none are left. A call made while this
+// process is already running jobs makes it look for new ones once more
+// instead of starting a second runner.
+func (s *JobService) RunPending() {
+	s.mu.Lock()
+	if s.draining {
+		s.again = true
+		s.mu.Unlock()
+		return
+	}
+	s.draining = true
+	s.mu.Unlock()
+
+	for {
+		s.failStaleJobs()
+		for s.runNext() {
+		}
+		s.mu.Lock()
+		if !s.again {
+			s.draining = false
+			s.mu.Unlock()
+			return
+		}
+		s.again = false
+		s.mu.Unlock()
+	}
+}
+
+// failStaleJobs fails the running jobs whose process stopped sending
+// heartbeats, for example because it was restarted.
+func (s *JobService) failStaleJobs() {
+	now := time.Now()
+	res := s.db.Model(&models.Job{}).
+		Where("status = ? AND heartbeat_at < ?", models.JobRunning, now.Add(-jobStaleAfter)).
+		Updates(map[string]interface{}{"status": models.JobFailed, "error": "interrupted", "finished_at": now, "input": nil})
+	if res.Error != nil {
+		log.Printf("[jobs] failed to fail stale jobs: %v", res.Error)
+	} else if res.RowsAffected > 0 {
+		log.Printf("[jobs] %d interrupted job(s) marked as failed", res.RowsAffected)
+	}
+}
+
+// runNext claims and runs one queued job. It reports whether it ran one.
+func (s *JobService) runNext() bool {
+	var candidates []uint
+	if err := s.db.Model(&models.Job{}).Where("status = ?", models.JobQueued).
+		Order("id ASC").Limit(jobClaimBatch).Pluck("id", &candidates).Error; err != nil {
+		log.Printf("[jobs] failed to list queued jobs: %v", err)
+		return false
+	}
+	for _, id := range candidates {
+		if s.locker != nil {
+			acquired, err := s.locker.TryLock(jobLockName(id))
+			if err != nil {
+				log.Printf("[jobs] job %d lock error: %v", id, err)
+				continue
+			}
+			if !acquired {
+				continue
+			}
+		}
+		now := time.Now()
+		res := s.db.Model(&models.Job{}).Where("id = ? AND status = ?", id, models.JobQueued).
+			Updates(map[string]interface{}{"status": models.JobRunning, "started_at": now, "heartbeat_at": now})
+		if res.Error != nil {
+			log.Printf("[jobs] failed to claim job %d: %v", id, res.Error)
+			continue
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		var job models.Job
+		if err := s.db.First(&job, id).Error; err != nil {
+			log.Printf("[jobs] failed to load job %d: %v", id, err)
+			continue
+		}
+		s.run(&job)
+		return true
+	}
+	return false
+}
+
+func (s *JobService) run(job *models.Job) {
+	log.Printf("[jobs] job %d (%s) starting", job.ID, job.Type)
+	run := &JobRun{svc: s, job: job}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run.heartbeat(stop)
+	}()
+
+	result, itemErrors, err := s.call(run)
+	close(stop)
+	<-done
+
+	s.finish(run, result, itemErrors, err)
+	log.Printf("[jobs] job %d (%s) %s", job.ID, job.Type, job.Status)
+	s.notify(job)
+}
+
+func (s *JobService) call(run *JobRun) (result interface{}, itemErrors []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	fn, ok := s.funcs[run.job.Type]
+	if !ok {
+		return nil, nil, ErrUnknownJobType
+	}
+	return fn(run)
+}
+
+func (s *JobService) finish(run *JobRun, result interface{}, itemErrors []string, err error) {
+	job := run.job
+	now := time.Now()
+	run.mu.Lock()
+	job.ProcessedItems, job.TotalItems, job.Progress = run.processed, run.total, run.progress()
+	run.mu.Unlock()
+	switch {
+	case errors.Is(err, ErrJobCancelled):
+		job.Status = models.JobCancelled
+		job.CancelRequested = true
+	case err != nil:
+		job.Status = models.JobFailed
+		job.Error = strPtrOrNil(err.Error())
+	default:
+		job.Status = models.JobSucceeded
+		job.Progress = 100
+	}
+	if result != nil {
+		if raw, mErr := json.Marshal(result); mErr == nil {
+			job.Result = strPtrOrNil(string(raw))
+		} else {
+			log.Printf("[jobs] failed to encode result of job %d: %v", job.ID, mErr)
+		}
+	}
+	if len(itemErrors) > maxJobErrors {
+		itemErrors = itemErrors[:maxJobErrors]
+	}
+	job.ErrorCount = len(itemErrors)
+	job.Input = nil
+	job.HeartbeatAt = &now
+	job.FinishedAt = &now
+	// Save writes every column and inserts the row when it is missing,
+	// which happens when the job restored a backup taken before it started.
+	if err := s.db.Save(job).Error; err != nil {
+		log.Printf("[jobs] failed to save job %d: %v", job.ID, err)
+		return
+	}
+	if len(itemErrors) == 0 {
+		return
+	}
+	rows := make([]models.JobError, len(itemErrors))
+	for i, msg := range itemErrors {
+		rows[i] = models.JobError{JobID: job.ID, Message: msg}
+	}
+	if err := s.db.CreateInBatches(rows, 100).Error; err != nil {
+		log.Printf("[jobs] failed to save errors of job %d: %v", job.ID, err)
+	}
+}
+
+// notify tells the owner of a job that it finished through their active
+// notification channels. Cancelled jobs were stopped by the user and are
+// not reported.
+func (s *JobService) notify(job *models.Job) {
+	if job.Status != models.JobSucceeded && job.Status != models.JobFailed {
+		return
+	}
+	var user models.User
+	if err := s.db.First(&user, "id = ?", job.UserID).Error; err != nil {
+		return
+	}
+	var channels []models.UserNotificationChannel
+	if err := s.db.Where("user_id = ? AND active = ?", job.UserID, true).Find(&channels).Error; err != nil {
+		log.Printf("[jobs] failed to load channels for job %d: %v", job.ID, err)
+		return
+	}
+	if len(channels) == 0 {
+		return
+	}
+	locale, _ := reminderDeliveryLocale(&user)
+	params := map[string]string{
+		"job":    i18n.T(locale, "job.type."+job.Type),
+		"errors": strconv.Itoa(job.ErrorCount),
+	}
+	if job.Error != nil {
+		params["error"] = *job.Error
+	}
+	subject := i18n.Tt(locale, "job_notification."+job.Status+".subject", params)
+	body := i18n.Tt(locale, "job_notification."+job.Status+".body", params)
+	now := time.Now()
+	for i := range channels {
+		if sendErr := sendToNotificationChannel(s.mailer, s.sender, s.webPush, &channels[i], subject, body); sendErr != nil {
+			if err := recordChannelFailure(s.db, &channels[i], subject, body, sendErr, now); err != nil {
+				log.Printf("[jobs] failed to record failure on channel %d: %v", channels[i].ID, err)
+			}
+			continue
+		}
+		if err := recordChannelSuccess(s.db, &channels[i], subject, body, now); err != nil {
+			log.Printf("[jobs] failed to record delivery on channel %d: %v", channels[i].ID, err)
+		}
+	}
+}
+
+func (s *JobService) findJob(id uint, userID string) (*models.Job, error) {
+	var job models.Job
+	if err := s.db.Omit("input").Where("id = ? AND user_id = ?", id, userID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// deleteJobs deletes the jobs matching query along with their errors.
+func deleteJobs(tx *gorm.DB, query string, args ...interface{}) error {
+	jobIDs := tx.Model(&models.Job{}).Select("id").Where(query, args...)
+	if err := tx.Where("job_id IN (?)", jobIDs).Delete(&models.JobError{}).Error; err != nil {
+		return err
+	}
+	return tx.Where(query, args...).Delete(&models.Job{}).Error
+}
+
+func jobLockName(id uint) string {
+	return fmt.Sprintf("job:%d", id)
+}
+
+// JobRun is a job while it runs. It implements JobProgress; progress is
+// kept in memory and stored by the heartbeat, so operations can report it
+// from inside a transaction.
+type JobRun struct {
+	svc       *JobService
+	job       *models.Job
+	cancelled atomic.Bool
+
+	mu        sync.Mutex
+	processed int
+	total     int
+}
+
+// Job returns the job being run.
+func (r *JobRun) Job() *models.Job {
+	return r.job
+}
+
+// Params decodes the parameters the job was queued with into v.
+func (r *JobRun) Params(v interface{}) error {
+	return json.Unmarshal([]byte(r.job.Params), v)
+}
+
+// Input returns the file the job was queued with.
+func (r *JobRun) Input() []byte {
+	return r.job.Input
+}
+
+func (r *JobRun) SetTotal(total int) {
+	r.mu.Lock()
+	r.total = total
+	r.mu.Unlock()
+}
+
+func (r *JobRun) Advance(n int) {
+	r.mu.Lock()
+	r.processed += n
+	r.mu.Unlock()
+}
+
+// Cancelled reports whether the owner asked for the job to stop.
+func (r *JobRun) Cancelled() bool {
+	return r.cancelled.Load()
+}
+
+// progress is the completed share in percent. It stays below 100 until
+// the job finishes. The caller holds r.mu.
+func (r *JobRun) progress() int {
+	if r.total <= 0 {
+		return 0
+	}
+	p := r.processed * 100 / r.total
+	if p > 99 {
+		p = 99
+	}
+	if p < 0 {
+		p = 0
+	}
+	return p
+}
+
+func (r *JobRun) heartbeat(stop <-chan struct{}) {
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.beat()
+		}
+	}
+}
+
+// beat stores the progress and reads whether cancellation was requested.
+func (r *JobRun) beat() {
+	r.mu.Lock()
+	updates := map[string]interface{}{
+		"processed_items": r.processed,
+		"total_items":     r.total,
+		"progress":        r.progress(),
+		"heartbeat_at":    time.Now(),
+	}
+	r.mu.Unlock()
+	db := r.svc.db
+	if err := db.Model(&models.Job{}).Where("id = ? AND status = ?", r.job.ID, models.JobRunning).Updates(updates).Error; err != nil {
+		log.Printf("[jobs] heartbeat of job %d failed: %v", r.job.ID, err)
+		return
+	}
+	var requested []bool
+	if err := db.Model(&models.Job{}).Where("id = ?", r.job.ID).Pluck("cancel_requested", &requested).Error; err != nil {
+		log.Printf("[jobs] failed to check cancellation of job %d: %v", r.job.ID, err)
+		return
+	}
+	if len(requested) == 1 && requested[0] {
+		r.cancelled.Store(true)
+	}
+}
+
+func toJobResponse(job *models.Job) dto.JobResponse {
+	resp := dto.JobResponse{
+		ID:              job.ID,
+		Type:            job.Type,
+		Status:          job.Status,
+		VaultID:         job.VaultID,
+		Progress:        job.Progress,
+		ProcessedItems:  job.ProcessedItems,
+		TotalItems:      job.TotalItems,
+		ErrorCount:      job.ErrorCount,
+		Error:           job.Error,
+		CancelRequested: job.CancelRequested,
+		StartedAt:       job.StartedAt,
+		FinishedAt:      job.FinishedAt,
+		CreatedAt:       job.CreatedAt,
+	}
+	if job.Result != nil {
+		resp.Result = json.RawMessage(*job.Result)
+	}
+	return resp
+}
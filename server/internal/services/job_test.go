@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+)
+
+func waitForJob(t *testing.T, svc *JobService, id uint, userID string, statuses ...string) *dto.JobResponse {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		job, err := svc.Get(id, userID)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		for _, status := range statuses {
+			if job.Status == status {
+				return job
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d stuck in %q, want one of %v", id, job.Status, statuses)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestJobRunsWithProgressAndErrors(t *testing.T) {
+	noteSvc, _, vaultID, userID := setupNoteTest(t)
+	svc := NewJobService(noteSvc.db)
+	svc.Register("test", func(run *JobRun) (interface{}, []string, error) {
+		var params struct {
+			Items int `json:"items"`
+		}
+		if err := run.Params(&params); err != nil {
+			return nil, nil, err
+		}
+		run.SetTotal(params.Items)
+		for i := 0; i < params.Items; i++ {
+			run.Advance(1)
+		}
+		return map[string]int{"lines": len(run.Input())}, []string{"item 2: broken"}, nil
+	})
+
+	job, err := svc.Enqueue(userID, &vaultID, "test", map[string]int{"items": 3}, []byte("a\nb\n"))
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if job.Status != models.JobQueued {
+		t.Errorf("expected a queued job, got %q", job.Status)
+	}
+
+	done := waitForJob(t, svc, job.ID, userID, models.JobSucceeded, models.JobFailed)
+	if done.Status != models.JobSucceeded || done.Progress != 100 || done.ProcessedItems != 3 || done.TotalItems != 3 || done.ErrorCount != 1 {
+		t.Fatalf("unexpected finished job %+v", done)
+	}
+	var result map[string]int
+	if err := json.Unmarshal(done.Result, &result); err != nil || result["lines"] != 4 {
+		t.Errorf("expected the result to be stored, got %s", done.Result)
+	}
+	var stored models.Job
+	svc.db.First(&stored, job.ID)
+	if stored.Input != nil {
+		t.Error("expected the input to be dropped once the job finished")
+	}
+
+	jobErrors, meta, err := svc.ListErrors(job.ID, userID, 1, 20)
+	if err != nil {
+		t.Fatalf("ListErrors failed: %v", err)
+	}
+	if meta.Total != 1 || jobErrors[0].Message != "item 2: broken" {
+		t.Errorf("unexpected job errors %+v", jobErrors)
+	}
+	if _, err := svc.Get(job.ID, "someone-else"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected other users not to see the job, got %v", err)
+	}
+	if _, err := svc.Cancel(job.ID, userID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("expected ErrJobFinished, got %v", err)
+	}
+}
+
+func TestJobCancelRunningJob(t *testing.T) {
+	noteSvc, _, _, userID := setupNoteTest(t)
+	svc := NewJobService(noteSvc.db)
+	svc.Register("wait", func(run *JobRun) (interface{}, []string, error) {
+		for !run.Cancelled() {
+			time.Sleep(10 * time.Millisecond)
+		}
+		return nil, nil, ErrJobCancelled
+	})
+
+	job, err := svc.Enqueue(userID, nil, "wait", nil, nil)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	waitForJob(t, svc, job.ID, userID, models.JobRunning)
+	cancelled, err := svc.Cancel(job.ID, userID)
+	if err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if !cancelled.CancelRequested {
+		t.Error("expected the running job to be asked to stop")
+	}
+	waitForJob(t, svc, job.ID, userID, models.JobCancelled)
+}
+
+func TestJobFailsStaleRunningJobs(t *testing.T) {
+	noteSvc, _, _, userID := setupNoteTest(t)
+	svc := NewJobService(noteSvc.db)
+	svc.Register("test", func(run *JobRun) (interface{}, []string, error) { return nil, nil, nil })
+	past := time.Now().Add(-time.Hour)
+	stale := models.Job{UserID: userID, Type: "test", Status: models.JobRunning, StartedAt: &past, HeartbeatAt: &past}
+	if err := svc.db.Create(&stale).Error; err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+
+	svc.RunPending()
+	job, err := svc.Get(stale.ID, userID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if job.Status != models.JobFailed || job.Error == nil || *job.Error != "interrupted" {
+		t.Errorf("expected the stale job to fail as interrupted, got %+v", job)
+	}
+}
@@ -108,6 +108,11 @@ func (s *JournalService) Delete(id uint, vaultID string) error {
 		if err := tx.Where("journal_id = ?", id).Delete(&models.Post{}).Error; err != nil {
 			return err
 		}
+		for _, postID := range postIDs {
+			if err := recordVaultChange(tx, vaultID, models.VaultChangePost, postID, models.VaultChangeDeleted); err != nil {
+				return err
+			}
+		}
 		if err := tx.Where("journal_id = ?", id).Delete(&models.JournalMetric{}).Error; err != nil {
 			return err
 		}
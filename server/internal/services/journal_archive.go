@@ -0,0 +1,478 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/journalfile"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+// MaxJournalFileSize is the maximum accepted size of a journal export,
+// which may be a zip that includes photos (100 MB).
+const MaxJournalFileSize = 100 * 1024 * 1024
+
+var (
+	ErrInvalidJournalFormat = errors.New("invalid journal format")
+	ErrInvalidJournalFile   = errors.New("invalid journal file")
+)
+
+var journalFormats = map[string]bool{
+	journalfile.FormatMarkdown: true,
+	journalfile.FormatDayOne:   true,
+	journalfile.FormatObsidian: true,
+}
+
+var (
+	// contactMentionMarkup is contactMentionPattern with the mention's
+	// name captured as well.
+	contactMentionMarkup = regexp.MustCompile(`@\[((?:\\[\\\]]|[^\]\r\n])+)\]\(contact:([0-9a-fA-F-]{36})\)`)
+	// journalWikiLink matches [[Name]] and [[Name|shown text]].
+	journalWikiLink = regexp.MustCompile(`\[\[([^\]|#\n]+)(?:#[^\]|\n]*)?(?:\|([^\]\n]*))?\]\]`)
+)
+
+// JournalArchiveService exports journals as Markdown files with YAML front
+// matter and imports them back, along with Day One and Obsidian exports.
+type JournalArchiveService struct {
+	db    *gorm.DB
+	files *VaultFileService
+}
+
+func NewJournalArchiveService(db *gorm.DB) *JournalArchiveService {
+	return &JournalArchiveService{db: db}
+}
+
+// SetVaultFileService lets exports include the photos of posts and
+// imports store them.
+func (s *JournalArchiveService) SetVaultFileService(fs *VaultFileService) {
+	s.files = fs
+}
+
+// Export writes the posts of a journal to w as a zip of Markdown files.
+// Dates are written in the user's time zone.
+func (s *JournalArchiveService) Export(journalID uint, vaultID, userID string, w io.Writer) error {
+	if err := validateJournalBelongsToVault(s.db, journalID, vaultID); err != nil {
+		return err
+	}
+	loc, err := s.userLocation(userID)
+	if err != nil {
+		return err
+	}
+	var posts []models.Post
+	if err := s.db.Where("journal_id = ?", journalID).
+		Preload("PostSections", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Contacts", "vault_id = ?", vaultID).
+		Preload("Tags").
+		Preload("PostMetrics.JournalMetric").
+		Preload("SliceOfLife").
+		Order("written_at ASC, id ASC").Find(&posts).Error; err != nil {
+		return err
+	}
+	photos, err := s.postPhotos(posts, vaultID)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]journalfile.Entry, 0, len(posts))
+	for _, p := range posts {
+		entry := journalfile.Entry{
+			Title:     ptrToStr(p.Title),
+			Date:      p.WrittenAt.In(loc),
+			Published: p.Published,
+			Photos:    photos[p.ID],
+		}
+		if p.SliceOfLife != nil {
+			entry.Slice = p.SliceOfLife.Name
+		}
+		for _, tag := range p.Tags {
+			entry.Tags = append(entry.Tags, tag.Name)
+		}
+		for _, c := range p.Contacts {
+			if name := journalContactName(c); name != "" {
+				entry.Contacts = append(entry.Contacts, name)
+			}
+		}
+		for _, m := range p.PostMetrics {
+			entry.Metrics = append(entry.Metrics, journalfile.Metric{Label: m.JournalMetric.Label, Value: m.Value})
+		}
+		for _, sec := range p.PostSections {
+			entry.Sections = append(entry.Sections, journalfile.Section{Label: sec.Label, Content: mentionsToWikiLinks(ptrToStr(sec.Content))})
+		}
+		entries = append(entries, entry)
+	}
+	return journalfile.Write(w, entries)
+}
+
+func (s *JournalArchiveService) userLocation(userID string) (*time.Location, error) {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return userLocation(&user), nil
+}
+
+// postPhotos reads the photos of posts from storage. Photos whose file is
+// missing are left out.
+func (s *JournalArchiveService) postPhotos(posts []models.Post, vaultID string) (map[uint][]journalfile.Photo, error) {
+	photos := map[uint][]journalfile.Photo{}
+	if s.files == nil || len(posts) == 0 {
+		return photos, nil
+	}
+	ids := make([]uint, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	var files []models.File
+	if err := s.db.Where("fileable_type = ? AND fileable_id IN ? AND vault_id = ?", "Post", ids, vaultID).
+		Order("created_at ASC, id ASC").Find(&files).Error; err != nil {
+		return nil, err
+	}
+	for i := range files {
+		data, err := os.ReadFile(s.files.localPath(&files[i]))
+		if err != nil {
+			continue
+		}
+		postID := *files[i].FileableID
+		photos[postID] = append(photos[postID], journalfile.Photo{Name: files[i].Name, Data: data})
+	}
+	return photos, nil
+}
+
+// ValidateJournalImportOptions checks the format of an import before it
+// is queued.
+func ValidateJournalImportOptions(opts dto.JournalImportOptions) error {
+	if !journalFormats[opts.Format] {
+		return ErrInvalidJournalFormat
+	}
+	return nil
+}
+
+func (s *JournalArchiveService) Import(vaultID, userID string, data []byte, opts dto.JournalImportOptions) (*dto.JournalImportResponse, error) {
+	return s.ImportWithProgress(vaultID, userID, data, opts, nil)
+}
+
+// RunImportJob runs a journal_import job on the export in its input.
+func (s *JournalArchiveService) RunImportJob(run *JobRun) (interface{}, []string, error) {
+	var opts dto.JournalImportOptions
+	if err := run.Params(&opts); err != nil {
+		return nil, nil, err
+	}
+	job := run.Job()
+	if job.VaultID == nil {
+		return nil, nil, errors.New("journal import job has no vault")
+	}
+	resp, err := s.ImportWithProgress(*job.VaultID, job.UserID, run.Input(), opts, run)
+	if resp == nil {
+		return nil, nil, err
+	}
+	itemErrors := resp.Errors
+	resp.Errors = []string{}
+	return resp, itemErrors, err
+}
+
+// journalImport is the state of one import run. Tags, metrics and slices
+// are found by name, ignoring case, and created when missing.
+type journalImport struct {
+	vaultID   string
+	journalID uint
+	index     historyContactIndex
+	tags      map[string]uint
+	metrics   map[string]uint
+	slices    map[string]uint
+	existing  map[string]bool
+	resp      *dto.JournalImportResponse
+	matched   map[string]bool
+	unmatched map[string]bool
+}
+
+// ImportWithProgress adds the entries of an export to a journal. Entries
+// the journal already has a post for, with the same date and title, are
+// skipped, so importing a newer export only adds what is new.
+func (s *JournalArchiveService) ImportWithProgress(vaultID, userID string, data []byte, opts dto.JournalImportOptions, progress JobProgress) (*dto.JournalImportResponse, error) {
+	if err := ValidateJournalImportOptions(opts); err != nil {
+		return nil, err
+	}
+	if err := validateJournalBelongsToVault(s.db, opts.JournalID, vaultID); err != nil {
+		return nil, err
+	}
+	loc, err := s.userLocation(userID)
+	if err != nil {
+		return nil, err
+	}
+	journal, err := journalfile.Parse(opts.Format, data, journalfile.Options{Filename: opts.Filename, Location: loc})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJournalFile, err)
+	}
+
+	run := &journalImport{
+		vaultID:   vaultID,
+		journalID: opts.JournalID,
+		tags:      map[string]uint{},
+		metrics:   map[string]uint{},
+		slices:    map[string]uint{},
+		existing:  map[string]bool{},
+		resp:      &dto.JournalImportResponse{Format: opts.Format, Unmatched: []string{}, SkippedFiles: journal.Skipped, Errors: []string{}},
+		matched:   map[string]bool{},
+		unmatched: map[string]bool{},
+	}
+	if err := s.loadJournalImport(run); err != nil {
+		return nil, err
+	}
+
+	progressSetTotal(progress, len(journal.Entries))
+	for _, entry := range journal.Entries {
+		if progressCancelled(progress) {
+			return run.finish(), ErrJobCancelled
+		}
+		if err := s.importEntry(run, entry); err != nil {
+			run.resp.Errors = append(run.resp.Errors, fmt.Sprintf("%s: %v", entry.Source, err))
+		}
+		progressAdvance(progress, 1)
+	}
+	return run.finish(), nil
+}
+
+func (s *JournalArchiveService) loadJournalImport(run *journalImport) error {
+	var err error
+	if run.index, err = loadHistoryContactIndex(s.db, run.vaultID, ""); err != nil {
+		return err
+	}
+	var tags []models.Tag
+	if err := s.db.Where("vault_id = ?", run.vaultID).Order("id ASC").Find(&tags).Error; err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if _, ok := run.tags[strings.ToLower(t.Name)]; !ok {
+			run.tags[strings.ToLower(t.Name)] = t.ID
+		}
+	}
+	var metrics []models.JournalMetric
+	if err := s.db.Where("journal_id = ?", run.journalID).Order("id ASC").Find(&metrics).Error; err != nil {
+		return err
+	}
+	for _, m := range metrics {
+		if _, ok := run.metrics[strings.ToLower(m.Label)]; !ok {
+			run.metrics[strings.ToLower(m.Label)] = m.ID
+		}
+	}
+	var slices []models.SliceOfLife
+	if err := s.db.Where("journal_id = ?", run.journalID).Order("id ASC").Find(&slices).Error; err != nil {
+		return err
+	}
+	for _, sl := range slices {
+		if _, ok := run.slices[strings.ToLower(sl.Name)]; !ok {
+			run.slices[strings.ToLower(sl.Name)] = sl.ID
+		}
+	}
+	var posts []models.Post
+	if err := s.db.Select("written_at", "title").Where("journal_id = ?", run.journalID).Find(&posts).Error; err != nil {
+		return err
+	}
+	for _, p := range posts {
+		run.existing[journalPostKey(p.WrittenAt, ptrToStr(p.Title))] = true
+	}
+	return nil
+}
+
+func (r *journalImport) finish() *dto.JournalImportResponse {
+	r.resp.MatchedContacts = len(r.matched)
+	r.resp.Unmatched = r.resp.Unmatched[:0]
+	for name := range r.unmatched {
+		r.resp.Unmatched = append(r.resp.Unmatched, name)
+	}
+	sort.Strings(r.resp.Unmatched)
+	return r.resp
+}
+
+func (s *JournalArchiveService) importEntry(run *journalImport, entry journalfile.Entry) error {
+	key := journalPostKey(entry.Date, entry.Title)
+	if run.existing[key] {
+		run.resp.SkippedCount++
+		return nil
+	}
+
+	var contactIDs []string
+	for _, name := range entry.Contacts {
+		if id := run.index.findName(name); id != "" {
+			contactIDs = append(contactIDs, id)
+			continue
+		}
+		run.unmatched[name] = true
+	}
+	for _, name := range entry.Mentions {
+		if id := run.index.findName(name); id != "" {
+			contactIDs = append(contactIDs, id)
+		}
+	}
+	contactIDs = dedupeContactIDs(contactIDs)
+
+	post := models.Post{
+		JournalID: run.journalID,
+		Title:     strPtrOrNil(entry.Title),
+		Published: entry.Published,
+		WrittenAt: entry.Date,
+	}
+	// Changes are kept until the post is saved, so a failed entry does not
+	// leave names behind that were never created.
+	tags, metrics, slices := copyIDMap(run.tags), copyIDMap(run.metrics), copyIDMap(run.slices)
+	createdTags := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if entry.Slice != "" {
+			id, ok := slices[strings.ToLower(entry.Slice)]
+			if !ok {
+				slice := models.SliceOfLife{JournalID: run.journalID, Name: entry.Slice}
+				if err := tx.Create(&slice).Error; err != nil {
+					return err
+				}
+				id = slice.ID
+				slices[strings.ToLower(entry.Slice)] = id
+			}
+			post.SliceOfLifeID = &id
+		}
+		if err := tx.Create(&post).Error; err != nil {
+			return err
+		}
+		if err := recordVaultChange(tx, run.vaultID, models.VaultChangePost, post.ID, models.VaultChangeCreated); err != nil {
+			return err
+		}
+		for i, sec := range entry.Sections {
+			section := models.PostSection{
+				PostID:   post.ID,
+				Position: i,
+				Label:    sec.Label,
+				Content:  strPtrOrNil(wikiLinksToMentions(sec.Content, run.index)),
+			}
+			if err := tx.Create(&section).Error; err != nil {
+				return err
+			}
+		}
+		if err := createContactPostAssociations(tx, post.ID, contactIDs); err != nil {
+			return err
+		}
+		for _, name := range entry.Tags {
+			id, ok := tags[strings.ToLower(name)]
+			if !ok {
+				tag := models.Tag{VaultID: run.vaultID, Name: name, Slug: strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "-"))}
+				if err := tx.Create(&tag).Error; err != nil {
+					return err
+				}
+				id = tag.ID
+				tags[strings.ToLower(name)] = id
+				createdTags++
+			}
+			if err := tx.Create(&models.PostTag{PostID: post.ID, TagID: id}).Error; err != nil {
+				return err
+			}
+		}
+		for _, m := range entry.Metrics {
+			id, ok := metrics[strings.ToLower(m.Label)]
+			if !ok {
+				metric := models.JournalMetric{JournalID: run.journalID, Label: m.Label}
+				if err := tx.Create(&metric).Error; err != nil {
+					return err
+				}
+				id = metric.ID
+				metrics[strings.ToLower(m.Label)] = id
+			}
+			if err := tx.Create(&models.PostMetric{PostID: post.ID, JournalMetricID: id, Value: m.Value}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	run.tags, run.metrics, run.slices = tags, metrics, slices
+	run.existing[key] = true
+	run.resp.ImportedPosts++
+	run.resp.CreatedTags += createdTags
+	for _, id := range contactIDs {
+		run.matched[id] = true
+	}
+
+	if s.files == nil {
+		return nil
+	}
+	for _, photo := range entry.Photos {
+		if _, err := s.files.UploadPostPhoto(post.ID, run.vaultID, photo.Name, photoMimeType(photo), int64(len(photo.Data)), bytes.NewReader(photo.Data)); err != nil {
+			return fmt.Errorf("photo %s: %w", photo.Name, err)
+		}
+		run.resp.ImportedPhotos++
+	}
+	return nil
+}
+
+// journalPostKey identifies a post by its date, to the second, and title.
+func journalPostKey(writtenAt time.Time, title string) string {
+	return fmt.Sprintf("%d\x00%s", writtenAt.Unix(), strings.TrimSpace(title))
+}
+
+func copyIDMap(m map[string]uint) map[string]uint {
+	c := make(map[string]uint, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func journalContactName(c models.Contact) string {
+	if name := strings.TrimSpace(ptrToStr(c.FirstName) + " " + ptrToStr(c.LastName)); name != "" {
+		return name
+	}
+	return ptrToStr(c.Nickname)
+}
+
+// mentionsToWikiLinks writes contact mentions as [[Name]] links.
+func mentionsToWikiLinks(content string) string {
+	return contactMentionMarkup.ReplaceAllStringFunc(content, func(marker string) string {
+		name := contactMentionMarkup.FindStringSubmatch(marker)[1]
+		name = strings.NewReplacer(`\]`, "]", `\\`, `\`).Replace(name)
+		return "[[" + name + "]]"
+	})
+}
+
+// wikiLinksToMentions turns [[Name]] links to contacts into mentions.
+// Embeds and links to anything else are left alone.
+func wikiLinksToMentions(content string, index historyContactIndex) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range journalWikiLink.FindAllStringSubmatchIndex(content, -1) {
+		if m[0] > 0 && content[m[0]-1] == '!' {
+			continue
+		}
+		name := strings.TrimSpace(content[m[2]:m[3]])
+		id := index.findName(path.Base(name))
+		if id == "" {
+			continue
+		}
+		shown := path.Base(name)
+		if m[4] >= 0 && strings.TrimSpace(content[m[4]:m[5]]) != "" {
+			shown = strings.TrimSpace(content[m[4]:m[5]])
+		}
+		b.WriteString(content[last:m[0]])
+		b.WriteString("@[" + strings.NewReplacer(`\`, `\\`, "]", `\]`).Replace(shown) + "](contact:" + id + ")")
+		last = m[1]
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+func photoMimeType(photo journalfile.Photo) string {
+	if t := mime.TypeByExtension(strings.ToLower(path.Ext(photo.Name))); t != "" {
+		return t
+	}
+	return http.DetectContentType(photo.Data)
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+type journalArchiveFixture struct {
+	db        *gorm.DB
+	svc       *JournalArchiveService
+	vaultID   string
+	userID    string
+	journalID uint
+	aliceID   string
+}
+
+func setupJournalArchiveTest(t *testing.T) journalArchiveFixture {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	resp, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Test",
+		LastName:  "User",
+		Email:     "journal-archive-test@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	vault, err := NewVaultService(db).CreateVault(resp.User.AccountID, resp.User.ID, dto.CreateVaultRequest{Name: "Test Vault"}, "en")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+	alice, err := NewContactService(db).CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: "Alice", LastName: "Smith"})
+	if err != nil {
+		t.Fatalf("CreateContact failed: %v", err)
+	}
+	journal, err := NewJournalService(db).Create(vault.ID, dto.CreateJournalRequest{Name: "Diary"})
+	if err != nil {
+		t.Fatalf("Create journal failed: %v", err)
+	}
+	svc := NewJournalArchiveService(db)
+	svc.SetVaultFileService(NewVaultFileService(db, t.TempDir()))
+	return journalArchiveFixture{db: db, svc: svc, vaultID: vault.ID, userID: resp.User.ID, journalID: journal.ID, aliceID: alice.ID}
+}
+
+func TestJournalExportImportRoundTrip(t *testing.T) {
+	f := setupJournalArchiveTest(t)
+	written := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	post, err := NewPostService(f.db).Create(f.journalID, f.vaultID, dto.CreatePostRequest{
+		Title:     "Lunch",
+		Published: true,
+		WrittenAt: written,
+		Sections: []dto.PostSectionInput{
+			{Position: 0, Label: "Story", Content: "Lunch with @[Alice Smith](contact:" + f.aliceID + ")."},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create post failed: %v", err)
+	}
+	if _, err := NewPostTagService(f.db).Add(post.ID, f.journalID, f.vaultID, dto.AddPostTagRequest{Name: "Food"}); err != nil {
+		t.Fatalf("Add tag failed: %v", err)
+	}
+	metric, err := NewJournalMetricService(f.db).Create(f.journalID, f.vaultID, dto.CreateJournalMetricRequest{Label: "Mood"})
+	if err != nil {
+		t.Fatalf("Create metric failed: %v", err)
+	}
+	f.db.Create(&models.PostMetric{PostID: post.ID, JournalMetricID: metric.ID, Value: 4})
+	if _, err := f.svc.files.UploadPostPhoto(post.ID, f.vaultID, "plate.jpg", "image/jpeg", 4, strings.NewReader("jpeg")); err != nil {
+		t.Fatalf("UploadPostPhoto failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.svc.Export(f.journalID, f.vaultID, f.userID, &buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("export is not a zip: %v", err)
+	}
+	var markdown string
+	for _, file := range zr.File {
+		if file.Name == "2024-06-01-lunch.md" {
+			rc, _ := file.Open()
+			content, _ := io.ReadAll(rc)
+			rc.Close()
+			markdown = string(content)
+		}
+	}
+	for _, want := range []string{"title: Lunch", "date: \"2024-06-01T09:00:00Z\"", "- Food", "- Alice Smith", "Mood: 4", "photos/2024-06-01-lunch/plate.jpg", "## Story", "Lunch with [[Alice Smith]]."} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("expected %q in the export:\n%s", want, markdown)
+		}
+	}
+
+	target, err := NewJournalService(f.db).Create(f.vaultID, dto.CreateJournalRequest{Name: "Copy"})
+	if err != nil {
+		t.Fatalf("Create journal failed: %v", err)
+	}
+	opts := dto.JournalImportOptions{JournalID: target.ID, Format: "markdown"}
+	resp, err := f.svc.Import(f.vaultID, f.userID, buf.Bytes(), opts)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if resp.ImportedPosts != 1 || resp.ImportedPhotos != 1 || resp.MatchedContacts != 1 || resp.CreatedTags != 0 || len(resp.Errors) != 0 {
+		t.Fatalf("unexpected result %+v", resp)
+	}
+	var imported models.Post
+	if err := f.db.Where("journal_id = ?", target.ID).Preload("PostSections").Preload("Contacts").Preload("Tags").Preload("PostMetrics.JournalMetric").First(&imported).Error; err != nil {
+		t.Fatalf("load imported post: %v", err)
+	}
+	if !imported.WrittenAt.Equal(written) || ptrToStr(imported.Title) != "Lunch" || !imported.Published {
+		t.Errorf("imported post = %+v", imported)
+	}
+	if len(imported.PostSections) != 1 || imported.PostSections[0].Label != "Story" || ptrToStr(imported.PostSections[0].Content) != "Lunch with @[Alice Smith](contact:"+f.aliceID+")." {
+		t.Errorf("sections = %+v", imported.PostSections)
+	}
+	if len(imported.Contacts) != 1 || len(imported.Tags) != 1 || imported.Tags[0].Name != "Food" {
+		t.Errorf("contacts %d, tags %+v", len(imported.Contacts), imported.Tags)
+	}
+	if len(imported.PostMetrics) != 1 || imported.PostMetrics[0].Value != 4 || imported.PostMetrics[0].JournalMetric.JournalID != target.ID {
+		t.Errorf("metrics = %+v", imported.PostMetrics)
+	}
+
+	resp, err = f.svc.Import(f.vaultID, f.userID, buf.Bytes(), opts)
+	if err != nil {
+		t.Fatalf("second Import failed: %v", err)
+	}
+	if resp.ImportedPosts != 0 || resp.SkippedCount != 1 {
+		t.Errorf("expected the re-run to skip the post, got %+v", resp)
+	}
+}
+
+func TestJournalImportObsidian(t *testing.T) {
+	f := setupJournalArchiveTest(t)
+	note := "---\nslice: Spring\ncontacts: [Zed]\n---\nCoffee with [[Alice Smith|Ali]] and [[Project X]] #coffee\n"
+	resp, err := f.svc.Import(f.vaultID, f.userID, []byte(note), dto.JournalImportOptions{JournalID: f.journalID, Format: "obsidian", Filename: "2024-04-02.md"})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if resp.ImportedPosts != 1 || resp.CreatedTags != 1 || resp.MatchedContacts != 1 || len(resp.Unmatched) != 1 || resp.Unmatched[0] != "Zed" {
+		t.Fatalf("unexpected result %+v", resp)
+	}
+	var post models.Post
+	f.db.Where("journal_id = ?", f.journalID).Preload("PostSections").Preload("SliceOfLife").First(&post)
+	if post.SliceOfLife == nil || post.SliceOfLife.Name != "Spring" {
+		t.Errorf("expected the Spring slice, got %+v", post.SliceOfLife)
+	}
+	if content := ptrToStr(post.PostSections[0].Content); content != "Coffee with @[Ali](contact:"+f.aliceID+") and [[Project X]] #coffee" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestJournalImportRejectsInvalidInput(t *testing.T) {
+	f := setupJournalArchiveTest(t)
+	if _, err := f.svc.Import(f.vaultID, f.userID, nil, dto.JournalImportOptions{JournalID: f.journalID, Format: "notion"}); !errors.Is(err, ErrInvalidJournalFormat) {
+		t.Errorf("expected ErrInvalidJournalFormat, got %v", err)
+	}
+	if _, err := f.svc.Import(f.vaultID, f.userID, []byte("{}"), dto.JournalImportOptions{JournalID: f.journalID, Format: "dayone"}); !errors.Is(err, ErrInvalidJournalFile) {
+		t.Errorf("expected ErrInvalidJournalFile, got %v", err)
+	}
+	if _, err := f.svc.Import(f.vaultID, f.userID, nil, dto.JournalImportOptions{JournalID: f.journalID + 100, Format: "markdown"}); !errors.Is(err, ErrJournalNotFound) {
+		t.Errorf("expected ErrJournalNotFound, got %v", err)
+	}
+}
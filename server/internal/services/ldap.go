@@ -0,0 +1,461 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/naiba/bonds/internal/i18n"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLDAPDisabled           = errors.New("LDAP authentication is disabled")
+	ErrLDAPUnavailable        = errors.New("LDAP directory unavailable")
+	ErrLDAPUserNotProvisioned = errors.New("LDAP user has no account and auto-provisioning is disabled")
+	ErrLDAPMissingEmail       = errors.New("LDAP entry has no email address")
+	// ErrLDAPAccountNotLinked is returned when the entry's email belongs to
+	// a Bonds user who has not linked the directory entry themselves.
+	ErrLDAPAccountNotLinked = errors.New("a Bonds user with this email exists but has not linked the directory account")
+	ErrLDAPAlreadyLinked    = errors.New("the directory account is already linked to a Bonds user")
+)
+
+const (
+	// ldapDriver is the UserToken driver linking a user to the DN of their
+	// directory entry, so changing the email in the directory keeps the user.
+	ldapDriver = "ldap"
+
+	defaultLDAPUserFilter = "(|(uid={login})(mail={login}))"
+	ldapTimeout           = 10 * time.Second
+	// ldapDAVLoginTTL is how long a DAV login is accepted without asking
+	// the directory again. DAV clients send credentials with every request.
+	ldapDAVLoginTTL = 5 * time.Minute
+)
+
+// LDAPService authenticates users against an LDAP directory such as FreeIPA,
+// lldap or OpenLDAP. It is configured through the ldap.* system settings.
+type LDAPService struct {
+	db       *gorm.DB
+	settings *SystemSettingService
+	// provisionMu serialises first logins, so that concurrent DAV requests
+	// do not create the same user twice.
+	provisionMu sync.Mutex
+
+	// davLogins remembers successful DAV logins by login and keyed password
+	// digest; the key is random per process so the digests are useless
+	// outside of it.
+	davMu     sync.Mutex
+	davLogins map[string]ldapDAVLogin
+	davKey    []byte
+}
+
+type ldapDAVLogin struct {
+	userID    string
+	expiresAt time.Time
+}
+
+func NewLDAPService(db *gorm.DB, settings *SystemSettingService) *LDAPService {
+	key := make([]byte, 32)
+	rand.Read(key) // never fails since Go 1.24
+	return &LDAPService{db: db, settings: settings, davLogins: make(map[string]ldapDAVLogin), davKey: key}
+}
+
+// ldapEntry is the part of a directory entry Bonds reads.
+type ldapEntry struct {
+	dn        string
+	firstName string
+	lastName  string
+	email     string
+	locale    string
+	groups    []string
+}
+
+// Enabled reports whether LDAP logins are enabled.
+func (s *LDAPService) Enabled() bool {
+	return s != nil && s.settings.GetBool("ldap.enabled", false) && s.settings.GetWithDefault("ldap.url", "") != ""
+}
+
+// DAVEnabled reports whether DAV clients may authenticate with directory
+// passwords.
+func (s *LDAPService) DAVEnabled() bool {
+	return s.Enabled() && s.settings.GetBool("ldap.dav_enabled", false)
+}
+
+// Authenticate checks the login (username or email, depending on the user
+// filter) and password against the directory. It returns the Bonds user of
+// the directory entry, creating it on first login when auto-provisioning is
+// enabled, with name, email and administrator flags synced from the entry.
+func (s *LDAPService) Authenticate(login, password string) (*models.User, error) {
+	if !s.Enabled() {
+		return nil, ErrLDAPDisabled
+	}
+	// An empty password would make the directory accept an unauthenticated
+	// bind.
+	if login == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	entry, err := s.lookup(login, password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.findUser(entry)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !s.settings.GetBool("ldap.auto_provision", true) {
+			return nil, ErrLDAPUserNotProvisioned
+		}
+		s.provisionMu.Lock()
+		defer s.provisionMu.Unlock()
+		user, err = s.findUser(entry)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			user, err = s.provision(entry)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sync(user, entry); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// AuthenticateDAV is Authenticate for DAV clients. A successful login is
+// remembered for ldapDAVLoginTTL, during which the same credentials are
+// accepted without binding to the directory and the user is not re-synced.
+func (s *LDAPService) AuthenticateDAV(login, password string) (*models.User, error) {
+	if !s.DAVEnabled() {
+		return nil, ErrLDAPDisabled
+	}
+	mac := hmac.New(sha256.New, s.davKey)
+	mac.Write([]byte(login))
+	mac.Write([]byte{0})
+	mac.Write([]byte(password))
+	key := hex.EncodeToString(mac.Sum(nil))
+
+	now := time.Now()
+	s.davMu.Lock()
+	cached, ok := s.davLogins[key]
+	s.davMu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		var user models.User
+		err := s.db.First(&user, "id = ?", cached.userID).Error
+		if err == nil {
+			return &user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	user, err := s.Authenticate(login, password)
+	if err != nil {
+		return nil, err
+	}
+	s.davMu.Lock()
+	defer s.davMu.Unlock()
+	for k, l := range s.davLogins {
+		if now.After(l.expiresAt) {
+			delete(s.davLogins, k)
+		}
+	}
+	s.davLogins[key] = ldapDAVLogin{userID: user.ID, expiresAt: now.Add(ldapDAVLoginTTL)}
+	return user, nil
+}
+
+// lookup searches the entry of the login with the service account and
+// verifies the password by binding as that entry.
+func (s *LDAPService) lookup(login, password string) (*ldapEntry, error) {
+	conn, err := s.dial()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLDAPUnavailable, err)
+	}
+	defer conn.Close()
+
+	if bindDN := s.settings.GetWithDefault("ldap.bind_dn", ""); bindDN != "" {
+		bindPassword, _ := s.settings.Get("ldap.bind_password")
+		if err := conn.Bind(bindDN, bindPassword); err != nil {
+			return nil, fmt.Errorf("%w: service account bind: %v", ErrLDAPUnavailable, err)
+		}
+	}
+
+	firstNameAttr := s.settings.GetWithDefault("ldap.first_name_attribute", "givenName")
+	lastNameAttr := s.settings.GetWithDefault("ldap.last_name_attribute", "sn")
+	emailAttr := s.settings.GetWithDefault("ldap.email_attribute", "mail")
+	groupAttr := s.settings.GetWithDefault("ldap.group_attribute", "memberOf")
+	filter := strings.ReplaceAll(s.settings.GetWithDefault("ldap.user_filter", defaultLDAPUserFilter), "{login}", ldap.EscapeFilter(login))
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		s.settings.GetWithDefault("ldap.base_dn", ""),
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ldapTimeout.Seconds()), false,
+		filter,
+		[]string{firstNameAttr, lastNameAttr, emailAttr, groupAttr, "cn", "preferredLanguage"},
+		nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) || ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("%w: search: %v", ErrLDAPUnavailable, err)
+	}
+	// Zero entries is an unknown login; several would make the login
+	// ambiguous.
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	e := result.Entries[0]
+
+	if err := conn.Bind(e.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("%w: user bind: %v", ErrLDAPUnavailable, err)
+	}
+
+	entry := &ldapEntry{
+		dn:        e.DN,
+		firstName: e.GetAttributeValue(firstNameAttr),
+		lastName:  e.GetAttributeValue(lastNameAttr),
+		email:     strings.TrimSpace(e.GetAttributeValue(emailAttr)),
+		locale:    ldapLocale(e.GetAttributeValue("preferredLanguage")),
+		groups:    e.GetAttributeValues(groupAttr),
+	}
+	if entry.firstName == "" && entry.lastName == "" {
+		entry.firstName, entry.lastName = splitFullName(e.GetAttributeValue("cn"))
+	}
+	return entry, nil
+}
+
+func (s *LDAPService) dial() (*ldap.Conn, error) {
+	rawURL := s.settings.GetWithDefault("ldap.url", "")
+	tlsConfig := &tls.Config{InsecureSkipVerify: s.settings.GetBool("ldap.insecure_skip_verify", false)}
+	if u, err := url.Parse(rawURL); err == nil {
+		tlsConfig.ServerName = u.Hostname()
+	}
+	conn, err := ldap.DialURL(rawURL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(ldapTimeout)
+	if s.settings.GetBool("ldap.start_tls", false) {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// findUser looks the user up by the DN of the entry. An existing user with
+// the entry's email is not taken over: they link the entry themselves with
+// LinkUser.
+func (s *LDAPService) findUser(entry *ldapEntry) (*models.User, error) {
+	var user models.User
+	var token models.UserToken
+	err := s.db.Where("driver = ? AND driver_id = ?", ldapDriver, strings.ToLower(entry.dn)).First(&token).Error
+	if err == nil {
+		if err := s.db.First(&user, "id = ?", token.UserID).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if entry.email == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	if err := s.db.Where("email = ?", entry.email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return nil, ErrLDAPAccountNotLinked
+}
+
+// LinkUser links the directory entry of the login to a signed-in user, who
+// confirms the link by entering the directory password. Later directory
+// logins then sign in as that user.
+func (s *LDAPService) LinkUser(userID, login, password string) error {
+	if !s.Enabled() {
+		return ErrLDAPDisabled
+	}
+	if login == "" || password == "" {
+		return ErrInvalidCredentials
+	}
+	entry, err := s.lookup(login, password)
+	if err != nil {
+		return err
+	}
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	s.provisionMu.Lock()
+	defer s.provisionMu.Unlock()
+	var token models.UserToken
+	err = s.db.Where("driver = ? AND driver_id = ?", ldapDriver, strings.ToLower(entry.dn)).First(&token).Error
+	if err == nil {
+		if token.UserID == user.ID {
+			return nil
+		}
+		return ErrLDAPAlreadyLinked
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return s.link(s.db, &user, entry)
+}
+
+func (s *LDAPService) link(tx *gorm.DB, user *models.User, entry *ldapEntry) error {
+	return tx.Create(&models.UserToken{
+		UserID:   user.ID,
+		Driver:   ldapDriver,
+		DriverID: strings.ToLower(entry.dn),
+		Format:   "ldap",
+		Email:    &user.Email,
+	}).Error
+}
+
+// provision creates the user in the shared account set by ldap.account_id,
+// or in a new account of their own.
+func (s *LDAPService) provision(entry *ldapEntry) (*models.User, error) {
+	if entry.email == "" {
+		return nil, ErrLDAPMissingEmail
+	}
+	firstName := entry.firstName
+	if firstName == "" {
+		firstName, _, _ = strings.Cut(entry.email, "@")
+	}
+	now := time.Now()
+	user := models.User{
+		FirstName:              &firstName,
+		LastName:               strPtrOrNil(entry.lastName),
+		Email:                  entry.email,
+		Locale:                 entry.locale,
+		EmailVerifiedAt:        &now,
+		IsAccountAdministrator: s.inGroup(entry, "ldap.admin_group"),
+	}
+	sharedAccountID := s.settings.GetWithDefault("ldap.account_id", "")
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := createDirectoryUser(tx, &user, sharedAccountID); err != nil {
+			return err
+		}
+		return s.link(tx, &user, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// sync copies the name, email and group-derived administrator flags of the
+// entry to the user. The email is only changed while no other user has it,
+// and the last administrator of the account or the instance is never
+// demoted, so a directory change cannot lock everyone out.
+func (s *LDAPService) sync(user *models.User, entry *ldapEntry) error {
+	updates := map[string]interface{}{}
+	if entry.firstName != "" && entry.firstName != ptrToStr(user.FirstName) {
+		updates["first_name"] = entry.firstName
+	}
+	if entry.lastName != "" && entry.lastName != ptrToStr(user.LastName) {
+		updates["last_name"] = entry.lastName
+	}
+	if entry.email != "" && !strings.EqualFold(entry.email, user.Email) {
+		var taken int64
+		if err := s.db.Model(&models.User{}).Where("email = ? AND id <> ?", entry.email, user.ID).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken == 0 {
+			updates["email"] = entry.email
+		}
+	}
+	// Users with an account of their own always administer it.
+	if accountID := s.settings.GetWithDefault("ldap.account_id", ""); accountID != "" && accountID == user.AccountID &&
+		s.settings.GetWithDefault("ldap.admin_group", "") != "" {
+		if isAdmin := s.inGroup(entry, "ldap.admin_group"); isAdmin != user.IsAccountAdministrator {
+			last := false
+			if !isAdmin {
+				var err error
+				if last, err = lastAccountAdministrator(s.db, user); err != nil {
+					return err
+				}
+			}
+			if !last {
+				updates["is_account_administrator"] = isAdmin
+			}
+		}
+	}
+	if s.settings.GetWithDefault("ldap.instance_admin_group", "") != "" {
+		if isAdmin := s.inGroup(entry, "ldap.instance_admin_group"); isAdmin != user.IsInstanceAdministrator {
+			last := false
+			if !isAdmin {
+				var others int64
+				if err := s.db.Model(&models.User{}).
+					Where("id <> ? AND is_instance_administrator = ? AND disabled = ?", user.ID, true, false).
+					Count(&others).Error; err != nil {
+					return err
+				}
+				last = others == 0
+			}
+			if !last {
+				updates["is_instance_administrator"] = isAdmin
+			}
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := s.db.Model(user).Updates(updates).Error; err != nil {
+		return err
+	}
+	return s.db.First(user, "id = ?", user.ID).Error
+}
+
+// inGroup reports whether the entry is a member of the group named by the
+// setting. Groups match by full DN or by the value of their first RDN, so
+// "admins" matches "cn=admins,ou=groups,dc=example,dc=com".
+func (s *LDAPService) inGroup(entry *ldapEntry, settingKey string) bool {
+	group := strings.TrimSpace(s.settings.GetWithDefault(settingKey, ""))
+	if group == "" {
+		return false
+	}
+	for _, member := range entry.groups {
+		if strings.EqualFold(member, group) {
+			return true
+		}
+		if dn, err := ldap.ParseDN(member); err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 &&
+			strings.EqualFold(dn.RDNs[0].Attributes[0].Value, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// ldapLocale maps the preferredLanguage attribute to a supported locale.
+func ldapLocale(preferred string) string {
+	for _, locale := range i18n.Supported {
+		if strings.EqualFold(locale, strings.TrimSpace(preferred)) {
+			return locale
+		}
+	}
+	return "en"
+}
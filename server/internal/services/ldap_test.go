@@ -0,0 +1,343 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+const (
+	ldapTestBaseDN  = "dc=example,dc=com"
+	ldapTestAliceDN = "uid=alice,ou=people,dc=example,dc=com"
+)
+
+func ldapTestEntries() []testutil.LDAPEntry {
+	return []testutil.LDAPEntry{
+		{
+			DN:       "cn=bonds,ou=services,dc=example,dc=com",
+			Password: "service-secret",
+		},
+		{
+			DN:       ldapTestAliceDN,
+			Password: "alice-password",
+			Attributes: map[string][]string{
+				"uid":               {"alice"},
+				"givenName":         {"Alice"},
+				"sn":                {"Liddell"},
+				"mail":              {"alice@example.com"},
+				"preferredLanguage": {"fr"},
+				"memberOf":          {"cn=bonds-admins,ou=groups,dc=example,dc=com", "cn=family,ou=groups,dc=example,dc=com"},
+			},
+		},
+		{
+			DN:       "uid=bob,ou=people,dc=example,dc=com",
+			Password: "bob-password",
+			Attributes: map[string][]string{
+				"uid":  {"bob"},
+				"cn":   {"Bob Builder"},
+				"mail": {"bob@example.com"},
+			},
+		},
+	}
+}
+
+func setupLDAPTest(t *testing.T, entries []testutil.LDAPEntry, settings map[string]string) (*gorm.DB, *LDAPService) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	settingSvc := NewSystemSettingService(db)
+	values := map[string]string{
+		"ldap.enabled":       "true",
+		"ldap.url":           testutil.StartLDAPServer(t, entries),
+		"ldap.bind_dn":       "cn=bonds,ou=services,dc=example,dc=com",
+		"ldap.bind_password": "service-secret",
+		"ldap.base_dn":       ldapTestBaseDN,
+	}
+	for k, v := range settings {
+		values[k] = v
+	}
+	for k, v := range values {
+		if err := settingSvc.Set(k, v); err != nil {
+			t.Fatalf("Set %s failed: %v", k, err)
+		}
+	}
+	return db, NewLDAPService(db, settingSvc)
+}
+
+func TestLDAPAuthenticateProvisionsAndSyncs(t *testing.T) {
+	entries := ldapTestEntries()
+	db, svc := setupLDAPTest(t, entries, nil)
+
+	user, err := svc.Authenticate("alice", "alice-password")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if ptrToStr(user.FirstName) != "Alice" || ptrToStr(user.LastName) != "Liddell" || user.Email != "alice@example.com" || user.Locale != "fr" {
+		t.Errorf("unexpected profile: %+v", user)
+	}
+	if !user.IsAccountAdministrator || !user.IsInstanceAdministrator || user.EmailVerifiedAt == nil {
+		t.Errorf("expected the first user to administer a verified account of their own, got %+v", user)
+	}
+
+	if _, err := svc.Authenticate("alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials for a wrong password, got %v", err)
+	}
+	if _, err := svc.Authenticate("alice", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected an empty password to be rejected, got %v", err)
+	}
+	if _, err := svc.Authenticate("mallory", "alice-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials for an unknown login, got %v", err)
+	}
+	if _, err := svc.Authenticate("*", "alice-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected filter characters in the login to be escaped, got %v", err)
+	}
+
+	// Directory changes are synced on the next login, which resolves the
+	// user by DN even after the email changed.
+	entries[1].Attributes["sn"] = []string{"Pleasance"}
+	entries[1].Attributes["mail"] = []string{"alice@new.example.com"}
+	again, err := svc.Authenticate("alice@new.example.com", "alice-password")
+	if err != nil || again.ID != user.ID {
+		t.Fatalf("expected the same user, got %v (%v)", again, err)
+	}
+	var stored models.User
+	db.First(&stored, "id = ?", user.ID)
+	if ptrToStr(stored.LastName) != "Pleasance" || stored.Email != "alice@new.example.com" {
+		t.Errorf("expected the profile to be synced, got %+v", stored)
+	}
+
+	bob, err := svc.Authenticate("bob", "bob-password")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if bob.AccountID == user.AccountID || bob.IsInstanceAdministrator || ptrToStr(bob.FirstName) != "Bob" || ptrToStr(bob.LastName) != "Builder" {
+		t.Errorf("expected bob to get a separate account named from cn, got %+v", bob)
+	}
+}
+
+func TestLDAPSharedAccountSyncsAdminGroups(t *testing.T) {
+	entries := ldapTestEntries()
+	db := testutil.SetupTestDB(t)
+	owner, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Owner",
+		LastName:  "User",
+		Email:     "owner@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	vault, err := NewVaultService(db).CreateVault(owner.User.AccountID, owner.User.ID, dto.CreateVaultRequest{Name: "Family"}, "en")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+	settingSvc := NewSystemSettingService(db)
+	for k, v := range map[string]string{
+		"ldap.enabled":              "true",
+		"ldap.url":                  testutil.StartLDAPServer(t, entries),
+		"ldap.bind_dn":              "cn=bonds,ou=services,dc=example,dc=com",
+		"ldap.bind_password":        "service-secret",
+		"ldap.base_dn":              ldapTestBaseDN,
+		"ldap.account_id":           owner.User.AccountID,
+		"ldap.admin_group":          "bonds-admins",
+		"ldap.instance_admin_group": "cn=family,ou=groups,dc=example,dc=com",
+	} {
+		if err := settingSvc.Set(k, v); err != nil {
+			t.Fatalf("Set %s failed: %v", k, err)
+		}
+	}
+	svc := NewLDAPService(db, settingSvc)
+
+	alice, err := svc.Authenticate("alice", "alice-password")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if alice.AccountID != owner.User.AccountID || !alice.IsAccountAdministrator || !alice.IsInstanceAdministrator {
+		t.Fatalf("expected an administrator of the shared account, got %+v", alice)
+	}
+	var access models.UserVault
+	if err := db.Where("user_id = ? AND vault_id = ?", alice.ID, vault.ID).First(&access).Error; err != nil {
+		t.Fatalf("expected access to the shared vault: %v", err)
+	}
+	if access.Permission != models.PermissionManager {
+		t.Errorf("expected manager permission, got %d", access.Permission)
+	}
+
+	entries[1].Attributes["memberOf"] = nil
+	demoted, err := svc.Authenticate("alice", "alice-password")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if demoted.IsAccountAdministrator || demoted.IsInstanceAdministrator {
+		t.Errorf("expected leaving the groups to revoke both administrator flags, got %+v", demoted)
+	}
+}
+
+func TestLDAPWithoutAutoProvisionLinksExistingUsers(t *testing.T) {
+	db, svc := setupLDAPTest(t, ldapTestEntries(), map[string]string{"ldap.auto_provision": "false"})
+	if _, err := svc.Authenticate("alice", "alice-password"); !errors.Is(err, ErrLDAPUserNotProvisioned) {
+		t.Fatalf("expected ErrLDAPUserNotProvisioned, got %v", err)
+	}
+
+	existing := models.User{AccountID: "test-account", Email: "bob@example.com"}
+	if err := db.Create(&existing).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	// A matching email alone does not take the user over.
+	if _, err := svc.Authenticate("bob", "bob-password"); !errors.Is(err, ErrLDAPAccountNotLinked) {
+		t.Fatalf("expected ErrLDAPAccountNotLinked, got %v", err)
+	}
+
+	if err := svc.LinkUser(existing.ID, "bob", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected the directory password to be checked, got %v", err)
+	}
+	if err := svc.LinkUser(existing.ID, "bob", "bob-password"); err != nil {
+		t.Fatalf("LinkUser failed: %v", err)
+	}
+	user, err := svc.Authenticate("bob", "bob-password")
+	if err != nil || user.ID != existing.ID {
+		t.Fatalf("expected bob to sign in as the linked user, got %v (%v)", user, err)
+	}
+
+	other := models.User{AccountID: "test-account", Email: "other@example.com"}
+	if err := db.Create(&other).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := svc.LinkUser(other.ID, "bob", "bob-password"); !errors.Is(err, ErrLDAPAlreadyLinked) {
+		t.Errorf("expected ErrLDAPAlreadyLinked, got %v", err)
+	}
+}
+
+func TestLDAPKeepsLastInstanceAdministrator(t *testing.T) {
+	entries := ldapTestEntries()
+	db, svc := setupLDAPTest(t, entries, map[string]string{"ldap.instance_admin_group": "bonds-admins"})
+	alice, err := svc.Authenticate("alice", "alice-password")
+	if err != nil || !alice.IsInstanceAdministrator {
+		t.Fatalf("expected an instance administrator, got %v (%v)", alice, err)
+	}
+
+	entries[1].Attributes["memberOf"] = nil
+	if _, err := svc.Authenticate("alice", "alice-password"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	var stored models.User
+	db.First(&stored, "id = ?", alice.ID)
+	if !stored.IsInstanceAdministrator {
+		t.Error("expected the last instance administrator to be kept")
+	}
+}
+
+func TestLDAPUnavailableAndDisabled(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closedURL := "ldap://" + ln.Addr().String()
+	ln.Close()
+
+	_, svc := setupLDAPTest(t, nil, map[string]string{"ldap.url": closedURL})
+	if _, err := svc.Authenticate("alice", "alice-password"); !errors.Is(err, ErrLDAPUnavailable) {
+		t.Errorf("expected ErrLDAPUnavailable, got %v", err)
+	}
+
+	_, svc = setupLDAPTest(t, ldapTestEntries(), map[string]string{"ldap.bind_password": "wrong"})
+	if _, err := svc.Authenticate("alice", "alice-password"); !errors.Is(err, ErrLDAPUnavailable) {
+		t.Errorf("expected a failing service account bind to be reported as unavailable, got %v", err)
+	}
+
+	_, svc = setupLDAPTest(t, ldapTestEntries(), map[string]string{"ldap.enabled": "false"})
+	if _, err := svc.Authenticate("alice", "alice-password"); !errors.Is(err, ErrLDAPDisabled) {
+		t.Errorf("expected ErrLDAPDisabled, got %v", err)
+	}
+}
+
+func TestLDAPAuthenticateDAVCachesLogins(t *testing.T) {
+	entries := ldapTestEntries()
+	db, svc := setupLDAPTest(t, entries, map[string]string{"ldap.dav_enabled": "true"})
+
+	user, err := svc.AuthenticateDAV("alice", "alice-password")
+	if err != nil {
+		t.Fatalf("AuthenticateDAV failed: %v", err)
+	}
+
+	// Within the TTL the directory is not asked again: a changed password
+	// or profile goes unnoticed.
+	entries[1].Password = "rotated-password"
+	entries[1].Attributes["sn"] = []string{"Pleasance"}
+	again, err := svc.AuthenticateDAV("alice", "alice-password")
+	if err != nil || again.ID != user.ID {
+		t.Fatalf("expected the cached login to be accepted, got %v (%v)", again, err)
+	}
+	if ptrToStr(again.LastName) != "Liddell" {
+		t.Errorf("expected no re-sync before the cache expires, got %q", ptrToStr(again.LastName))
+	}
+	if _, err := svc.AuthenticateDAV("alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected another password to be checked against the directory, got %v", err)
+	}
+
+	svc.davMu.Lock()
+	for k, l := range svc.davLogins {
+		l.expiresAt = time.Now().Add(-time.Second)
+		svc.davLogins[k] = l
+	}
+	svc.davMu.Unlock()
+	if _, err := svc.AuthenticateDAV("alice", "alice-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected an expired login to bind again, got %v", err)
+	}
+	refreshed, err := svc.AuthenticateDAV("alice", "rotated-password")
+	if err != nil {
+		t.Fatalf("AuthenticateDAV failed: %v", err)
+	}
+	var stored models.User
+	db.First(&stored, "id = ?", refreshed.ID)
+	if ptrToStr(stored.LastName) != "Pleasance" {
+		t.Errorf("expected the profile to be synced after the cache expired, got %+v", stored)
+	}
+}
+
+func TestLoginFallsBackToLDAP(t *testing.T) {
+	db, ldapSvc := setupLDAPTest(t, ldapTestEntries(), nil)
+	authSvc := NewAuthService(db, testutil.TestJWTConfig())
+	settingSvc := NewSystemSettingService(db)
+	authSvc.SetSystemSettings(settingSvc)
+
+	if _, err := authSvc.Login(dto.LoginRequest{Email: "alice", Password: "alice-password"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected LDAP to be unused until configured, got %v", err)
+	}
+	authSvc.SetLDAP(ldapSvc)
+
+	resp, err := authSvc.Login(dto.LoginRequest{Email: "alice", Password: "alice-password"})
+	if err != nil {
+		t.Fatalf("LDAP login failed: %v", err)
+	}
+	if resp.Token == "" || resp.RefreshToken == "" || resp.User.Email != "alice@example.com" {
+		t.Errorf("unexpected auth response %+v", resp)
+	}
+
+	// Local passwords keep working next to the directory.
+	if _, err := authSvc.Register(dto.RegisterRequest{FirstName: "Local", LastName: "User", Email: "local@example.com", Password: "password123"}, "en"); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if _, err := authSvc.Login(dto.LoginRequest{Email: "local@example.com", Password: "password123"}); err != nil {
+		t.Errorf("expected the local password to work, got %v", err)
+	}
+	if err := settingSvc.Set("auth.password.enabled", "false"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, err := authSvc.Login(dto.LoginRequest{Email: "local@example.com", Password: "password123"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected local passwords to be refused while password login is disabled, got %v", err)
+	}
+	if _, err := authSvc.Login(dto.LoginRequest{Email: "alice", Password: "alice-password"}); err != nil {
+		t.Errorf("expected LDAP login to keep working, got %v", err)
+	}
+
+	db.Model(&models.User{}).Where("id = ?", resp.User.ID).Update("disabled", true)
+	if _, err := authSvc.Login(dto.LoginRequest{Email: "alice", Password: "alice-password"}); !errors.Is(err, ErrUserDisabled) {
+		t.Errorf("expected ErrUserDisabled, got %v", err)
+	}
+}
@@ -0,0 +1,660 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	calendarPkg "github.com/naiba/bonds/internal/calendar"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// memoryMaxYears bounds how many earlier years "On this day" looks at.
+	memoryMaxYears = 100
+	// memorySummaryLength caps the excerpt shown for posts, activities,
+	// calls and notes.
+	memorySummaryLength = 200
+
+	defaultMemoryNotifyHour = 8
+)
+
+var (
+	ErrInvalidMemoryKind       = errors.New("invalid memory kind")
+	ErrMemoryNotFound          = errors.New("memory not found")
+	ErrInvalidMemoryDate       = errors.New("date must be formatted as YYYY-MM-DD")
+	ErrInvalidMemoryNotifyHour = errors.New("notify hour must be between 0 and 23")
+)
+
+// memoryKinds lists the kinds of memories in the order they are gathered.
+var memoryKinds = []string{
+	models.MemoryKindPost,
+	models.MemoryKindActivity,
+	models.MemoryKindCall,
+	models.MemoryKindNote,
+	models.MemoryKindPhoto,
+	models.MemoryKindImportantDate,
+}
+
+var memoryLoaders = map[string]func(*memoryCollector) error{
+	models.MemoryKindPost:          (*memoryCollector).posts,
+	models.MemoryKindActivity:      (*memoryCollector).activities,
+	models.MemoryKindCall:          (*memoryCollector).calls,
+	models.MemoryKindNote:          (*memoryCollector).notes,
+	models.MemoryKindPhoto:         (*memoryCollector).photos,
+	models.MemoryKindImportantDate: (*memoryCollector).importantDates,
+}
+
+// MemoryService resurfaces what happened on the same calendar day in
+// earlier years across the vaults of a user: journal posts, activities,
+// calls, notes, photos and important dates. Dates recorded in another
+// calendar, such as lunar birthdays, come up on that calendar's day. Users
+// can dismiss memories, mark them as favorites, and get them in a daily
+// notification.
+type MemoryService struct {
+	db      *gorm.DB
+	mailer  Mailer
+	sender  NotificationSender
+	webPush NotificationSender
+}
+
+func NewMemoryService(db *gorm.DB, mailer Mailer, sender NotificationSender) *MemoryService {
+	return &MemoryService{db: db, mailer: mailer, sender: sender}
+}
+
+// SetWebPushSender enables delivery to Web Push channels.
+func (s *MemoryService) SetWebPushSender(webPush NotificationSender) {
+	s.webPush = webPush
+}
+
+// OnThisDay returns the memories of a day, today by default, in the user's
+// timezone. Dismissed memories are left out and favorites come first.
+func (s *MemoryService) OnThisDay(userID, date string) (*dto.OnThisDayResponse, error) {
+	loc, err := s.location(userID)
+	if err != nil {
+		return nil, err
+	}
+	day := time.Now().In(loc)
+	if date != "" {
+		if day, err = time.ParseInLocation("2006-01-02", date, loc); err != nil {
+			return nil, ErrInvalidMemoryDate
+		}
+	}
+	memories, err := s.onThisDay(userID, day)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.OnThisDayResponse{Date: day.Format("2006-01-02"), Memories: memories}, nil
+}
+
+func (s *MemoryService) onThisDay(userID string, day time.Time) ([]dto.MemoryResponse, error) {
+	c, err := s.newCollector(userID, day, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, kind := range memoryKinds {
+		if err := memoryLoaders[kind](c); err != nil {
+			return nil, fmt.Errorf("load %s memories: %w", kind, err)
+		}
+	}
+	if err := c.finish(); err != nil {
+		return nil, err
+	}
+	memories := make([]dto.MemoryResponse, 0, len(c.out))
+	for _, m := range c.out {
+		if !m.IsDismissed {
+			memories = append(memories, m)
+		}
+	}
+	sort.SliceStable(memories, func(i, j int) bool {
+		a, b := memories[i], memories[j]
+		if a.IsFavorite != b.IsFavorite {
+			return a.IsFavorite
+		}
+		if a.YearsAgo != b.YearsAgo {
+			return a.YearsAgo < b.YearsAgo
+		}
+		return a.Date.Before(b.Date)
+	})
+	return memories, nil
+}
+
+// Favorites returns the memories the user marked as favorites, newest first.
+func (s *MemoryService) Favorites(userID string) ([]dto.MemoryResponse, error) {
+	loc, err := s.location(userID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.newCollector(userID, time.Now().In(loc), nil)
+	if err != nil {
+		return nil, err
+	}
+	var states []models.MemoryState
+	if err := s.db.Where("user_id = ? AND favorite = ? AND vault_id IN ?", userID, true, c.vaultIDs).
+		Order("id ASC").Find(&states).Error; err != nil {
+		return nil, err
+	}
+	ids := make(map[string][]uint)
+	for _, st := range states {
+		ids[st.Kind] = append(ids[st.Kind], st.ItemID)
+	}
+	for _, kind := range memoryKinds {
+		if len(ids[kind]) == 0 {
+			continue
+		}
+		c.ids = ids[kind]
+		if err := memoryLoaders[kind](c); err != nil {
+			return nil, fmt.Errorf("load %s memories: %w", kind, err)
+		}
+	}
+	if err := c.finish(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(c.out, func(i, j int) bool {
+		return c.out[i].Date.After(c.out[j].Date)
+	})
+	return c.out, nil
+}
+
+// UpdateState dismisses a memory or marks it as a favorite for the user.
+// The memory must be in one of the user's vaults.
+func (s *MemoryService) UpdateState(userID, kind string, itemID uint, req dto.UpdateMemoryStateRequest) (*dto.MemoryResponse, error) {
+	load, ok := memoryLoaders[kind]
+	if !ok {
+		return nil, ErrInvalidMemoryKind
+	}
+	loc, err := s.location(userID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.newCollector(userID, time.Now().In(loc), []uint{itemID})
+	if err != nil {
+		return nil, err
+	}
+	if err := load(c); err != nil {
+		return nil, err
+	}
+	if len(c.out) == 0 {
+		return nil, ErrMemoryNotFound
+	}
+
+	var state models.MemoryState
+	err = s.db.Where("user_id = ? AND kind = ? AND item_id = ?", userID, kind, itemID).First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		state = models.MemoryState{UserID: userID, Kind: kind, ItemID: itemID}
+	} else if err != nil {
+		return nil, err
+	}
+	state.VaultID = c.out[0].VaultID
+	if req.Dismissed != nil {
+		state.Dismissed = *req.Dismissed
+	}
+	if req.Favorite != nil {
+		state.Favorite = *req.Favorite
+	}
+	switch {
+	case state.Dismissed || state.Favorite:
+		err = s.db.Save(&state).Error
+	case state.ID != 0:
+		// Nothing left to remember about this memory.
+		err = s.db.Delete(&state).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.finish(); err != nil {
+		return nil, err
+	}
+	return &c.out[0], nil
+}
+
+func (s *MemoryService) GetPreferences(userID string) (*dto.MemoryPreferenceResponse, error) {
+	pref, err := loadMemoryPreference(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.MemoryPreferenceResponse{NotifyDaily: pref.NotifyDaily, NotifyHour: pref.NotifyHour}, nil
+}
+
+func (s *MemoryService) UpdatePreferences(userID string, req dto.UpdateMemoryPreferenceRequest) (*dto.MemoryPreferenceResponse, error) {
+	if req.NotifyHour < 0 || req.NotifyHour > 23 {
+		return nil, ErrInvalidMemoryNotifyHour
+	}
+	pref, err := loadMemoryPreference(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	pref.NotifyDaily = req.NotifyDaily
+	pref.NotifyHour = req.NotifyHour
+	if err := s.db.Save(&pref).Error; err != nil {
+		return nil, err
+	}
+	return &dto.MemoryPreferenceResponse{NotifyDaily: pref.NotifyDaily, NotifyHour: pref.NotifyHour}, nil
+}
+
+// loadMemoryPreference returns the user's stored preference, or an unsaved
+// row with the daily notification turned off.
+func loadMemoryPreference(db *gorm.DB, userID string) (models.MemoryPreference, error) {
+	var pref models.MemoryPreference
+	err := db.Where("user_id = ?", userID).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.MemoryPreference{UserID: userID, NotifyHour: defaultMemoryNotifyHour}, nil
+	}
+	return pref, err
+}
+
+func (s *MemoryService) location(userID string) (*time.Location, error) {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return userLocation(&user), nil
+}
+
+// memoryCollector gathers the memories of one user. Without ids it loads
+// the memories of day, a local date in the user's timezone; with ids it
+// loads the given rows of one kind wherever they are dated, which is how
+// favorites and state changes find their memory.
+type memoryCollector struct {
+	db       *gorm.DB
+	userID   string
+	day      time.Time
+	vaults   map[string]string
+	vaultIDs []string
+	ids      []uint
+	out      []dto.MemoryResponse
+}
+
+func (s *MemoryService) newCollector(userID string, day time.Time, ids []uint) (*memoryCollector, error) {
+	var vaults []models.Vault
+	if err := s.db.Joins("JOIN user_vault ON user_vault.vault_id = vaults.id").
+		Where("user_vault.user_id = ?", userID).Find(&vaults).Error; err != nil {
+		return nil, err
+	}
+	c := &memoryCollector{
+		db:       s.db,
+		userID:   userID,
+		day:      time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location()),
+		vaults:   make(map[string]string, len(vaults)),
+		vaultIDs: make([]string, 0, len(vaults)),
+		ids:      ids,
+	}
+	for _, v := range vaults {
+		c.vaults[v.ID] = v.Name
+		c.vaultIDs = append(c.vaultIDs, v.ID)
+	}
+	return c, nil
+}
+
+// findMemories runs base for the rows of table the collector asks for.
+// column holds the Gregorian date, compared in loc. For tables that can
+// also hold dates in other calendars, those rows are loaded separately
+// and left for the caller to match with recurs.
+func findMemories[T any](c *memoryCollector, base func() *gorm.DB, table, column string, loc *time.Location, otherCalendars bool) ([]T, error) {
+	var rows []T
+	if len(c.vaultIDs) == 0 {
+		return rows, nil
+	}
+	if c.ids != nil {
+		err := base().Where(table+".id IN ?", c.ids).Find(&rows).Error
+		return rows, err
+	}
+	cond, args := memoryDayCondition(column, c.day, loc)
+	query := base().Where(cond, args...)
+	if otherCalendars {
+		query = query.Where(memoryGregorianSQL(table))
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if !otherCalendars {
+		return rows, nil
+	}
+	var others []T
+	if err := base().Where("NOT "+memoryGregorianSQL(table)).
+		Where(column+" < ?", c.day.UTC()).Find(&others).Error; err != nil {
+		return nil, err
+	}
+	return append(rows, others...), nil
+}
+
+// memoryDayCondition matches column against the calendar day of day in
+// every earlier year, in loc. On 28 February of a common year it also
+// matches 29 February, so leap-day memories still come up.
+func memoryDayCondition(column string, day time.Time, loc *time.Location) (string, []interface{}) {
+	month, dom := day.Month(), day.Day()
+	leapEve := month == time.February && dom == 28 && !isLeapYear(day.Year())
+	clauses := make([]string, 0, memoryMaxYears)
+	args := make([]interface{}, 0, 2*memoryMaxYears)
+	for year := day.Year() - 1; year >= day.Year()-memoryMaxYears; year-- {
+		start := time.Date(year, month, dom, 0, 0, 0, 0, loc)
+		if start.Day() != dom {
+			continue // 29 February of a common year
+		}
+		end := start.AddDate(0, 0, 1)
+		if leapEve && isLeapYear(year) {
+			end = end.AddDate(0, 0, 1)
+		}
+		clauses = append(clauses, fmt.Sprintf("(%s >= ? AND %s < ?)", column, column))
+		args = append(args, start.UTC(), end.UTC())
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+// memoryGregorianSQL selects the rows of table dated in the Gregorian
+// calendar. Rows with an original date in another calendar recur on that
+// calendar's day instead.
+func memoryGregorianSQL(table string) string {
+	return fmt.Sprintf("(%[1]s.calendar_type IS NULL OR %[1]s.calendar_type IN ('', 'gregorian') OR %[1]s.original_month IS NULL OR %[1]s.original_day IS NULL)", table)
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// recurs reports whether a row dated in calendarType belongs to the
+// collector's day. Gregorian rows were already matched by the query; rows
+// of another calendar match when that calendar's yearly occurrence of
+// their original month and day falls on the day, with the same leap-month
+// and short-month rules as reminders.
+func (c *memoryCollector) recurs(calendarType string, month, dom *int) bool {
+	if c.ids != nil || month == nil || dom == nil || calendarType == "" || calendarType == string(calendarPkg.Gregorian) {
+		return true
+	}
+	converter, ok := calendarPkg.Get(calendarPkg.CalendarType(calendarType))
+	if !ok {
+		return false
+	}
+	gd, err := calendarOccurrenceInYear(converter, calendarPkg.DateInfo{Day: *dom, Month: *month}, c.day.Year())
+	return err == nil && gd.Month == int(c.day.Month()) && gd.Day == c.day.Day()
+}
+
+// add appends a memory dated at. Date-only memories keep their calendar
+// date at midnight UTC; the others count years in the user's timezone.
+func (c *memoryCollector) add(m dto.MemoryResponse, at time.Time, dateOnly bool) {
+	if dateOnly {
+		at = time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+		m.YearsAgo = c.day.Year() - at.Year()
+	} else {
+		m.YearsAgo = c.day.Year() - at.In(c.day.Location()).Year()
+	}
+	m.Date = at
+	if m.CalendarType == "" {
+		m.CalendarType = string(calendarPkg.Gregorian)
+	}
+	if c.ids == nil && m.YearsAgo < 1 {
+		return
+	}
+	c.out = append(c.out, m)
+}
+
+func (c *memoryCollector) posts() error {
+	base := func() *gorm.DB {
+		return c.db.Model(&models.Post{}).
+			Joins("JOIN journals ON journals.id = posts.journal_id").
+			Where("journals.vault_id IN ? AND posts.published = ?", c.vaultIDs, true).
+			Preload("Journal").
+			Preload("PostSections", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") })
+	}
+	posts, err := findMemories[models.Post](c, base, "posts", "posts.written_at", c.day.Location(), true)
+	if err != nil || len(posts) == 0 {
+		return err
+	}
+	ids := make([]uint, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	var photos []models.File
+	if err := c.db.Where("fileable_type = ? AND fileable_id IN ? AND type = ?", "Post", ids, "photo").
+		Order("id ASC").Find(&photos).Error; err != nil {
+		return err
+	}
+	photoIDs := make(map[uint][]uint)
+	for _, f := range photos {
+		photoIDs[*f.FileableID] = append(photoIDs[*f.FileableID], f.ID)
+	}
+	for _, p := range posts {
+		if !c.recurs(p.CalendarType, p.OriginalMonth, p.OriginalDay) {
+			continue
+		}
+		journalID := p.JournalID
+		m := dto.MemoryResponse{
+			Kind:         models.MemoryKindPost,
+			ID:           p.ID,
+			VaultID:      p.Journal.VaultID,
+			CalendarType: p.CalendarType,
+			Title:        ptrToStr(p.Title),
+			JournalID:    &journalID,
+			PhotoIDs:     photoIDs[p.ID],
+		}
+		for _, section := range p.PostSections {
+			if content := ptrToStr(section.Content); strings.TrimSpace(content) != "" {
+				m.Summary = truncateRunes(content, memorySummaryLength)
+				break
+			}
+		}
+		c.add(m, p.WrittenAt, false)
+	}
+	return nil
+}
+
+func (c *memoryCollector) activities() error {
+	base := func() *gorm.DB {
+		return c.db.Model(&models.Activity{}).
+			Where("activities.vault_id IN ? AND activities.start_date IS NOT NULL AND activities.start_precision = ?", c.vaultIDs, "day")
+	}
+	activities, err := findMemories[models.Activity](c, base, "activities", "activities.start_date", time.UTC, true)
+	if err != nil {
+		return err
+	}
+	for _, a := range activities {
+		if !c.recurs(a.CalendarType, a.OriginalMonth, a.OriginalDay) {
+			continue
+		}
+		c.add(dto.MemoryResponse{
+			Kind:         models.MemoryKindActivity,
+			ID:           a.ID,
+			VaultID:      a.VaultID,
+			CalendarType: a.CalendarType,
+			Title:        a.Title,
+			Summary:      truncateRunes(ptrToStr(a.Description), memorySummaryLength),
+		}, *a.StartDate, true)
+	}
+	return nil
+}
+
+func (c *memoryCollector) calls() error {
+	base := func() *gorm.DB {
+		return c.db.Model(&models.Call{}).
+			Joins("JOIN contacts ON contacts.id = calls.contact_id AND contacts.deleted_at IS NULL").
+			Where("contacts.vault_id IN ?", c.vaultIDs)
+	}
+	calls, err := findMemories[models.Call](c, base, "calls", "calls.called_at", c.day.Location(), false)
+	if err != nil {
+		return err
+	}
+	for _, call := range calls {
+		c.add(dto.MemoryResponse{
+			Kind:      models.MemoryKindCall,
+			ID:        call.ID,
+			Summary:   truncateRunes(ptrToStr(call.Description), memorySummaryLength),
+			ContactID: call.ContactID,
+		}, call.CalledAt, false)
+	}
+	return nil
+}
+
+func (c *memoryCollector) notes() error {
+	base := func() *gorm.DB {
+		return c.db.Model(&models.Note{}).
+			Joins("JOIN contacts ON contacts.id = notes.contact_id AND contacts.deleted_at IS NULL").
+			Where("notes.vault_id IN ?", c.vaultIDs)
+	}
+	notes, err := findMemories[models.Note](c, base, "notes", "COALESCE(notes.happened_at, notes.created_at)", c.day.Location(), false)
+	if err != nil {
+		return err
+	}
+	for _, n := range notes {
+		at := n.CreatedAt
+		if n.HappenedAt != nil {
+			at = *n.HappenedAt
+		}
+		c.add(dto.MemoryResponse{
+			Kind:      models.MemoryKindNote,
+			ID:        n.ID,
+			VaultID:   n.VaultID,
+			Title:     ptrToStr(n.Title),
+			Summary:   truncateRunes(n.Body, memorySummaryLength),
+			ContactID: n.ContactID,
+		}, at, false)
+	}
+	return nil
+}
+
+// photos loads the photos of contacts and of the vault. Photos of journal
+// posts come up with their post.
+func (c *memoryCollector) photos() error {
+	base := func() *gorm.DB {
+		return c.db.Model(&models.File{}).
+			Joins("LEFT JOIN contacts ON contacts.id = files.ufileable_id").
+			Where("files.vault_id IN ? AND files.type = ? AND (files.fileable_type IS NULL OR files.fileable_type = ?)", c.vaultIDs, "photo", "Contact").
+			Where("contacts.deleted_at IS NULL")
+	}
+	files, err := findMemories[models.File](c, base, "files", "files.created_at", c.day.Location(), false)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		c.add(dto.MemoryResponse{
+			Kind:      models.MemoryKindPhoto,
+			ID:        f.ID,
+			VaultID:   f.VaultID,
+			Title:     f.Name,
+			ContactID: ptrToStr(f.UfileableID),
+		}, f.CreatedAt, false)
+	}
+	return nil
+}
+
+// importantDates loads the important dates with a known year. Their
+// anniversaries come up like the other memories.
+func (c *memoryCollector) importantDates() error {
+	if len(c.vaultIDs) == 0 {
+		return nil
+	}
+	base := func() *gorm.DB {
+		return c.db.Model(&models.ContactImportantDate{}).
+			Joins("JOIN contacts ON contacts.id = contact_important_dates.contact_id AND contacts.deleted_at IS NULL").
+			Where("contacts.vault_id IN ?", c.vaultIDs).
+			Where("contact_important_dates.year IS NOT NULL AND contact_important_dates.month IS NOT NULL AND contact_important_dates.day IS NOT NULL").
+			Where("contact_important_dates.is_year_unknown = ?", false)
+	}
+	var dates []models.ContactImportantDate
+	if c.ids != nil {
+		if err := base().Where("contact_important_dates.id IN ?", c.ids).Find(&dates).Error; err != nil {
+			return err
+		}
+	} else {
+		day := "contact_important_dates.month = ? AND contact_important_dates.day = ?"
+		args := []interface{}{int(c.day.Month()), c.day.Day()}
+		if c.day.Month() == time.February && c.day.Day() == 28 && !isLeapYear(c.day.Year()) {
+			day = "(" + day + ") OR (contact_important_dates.month = 2 AND contact_important_dates.day = 29)"
+		}
+		if err := base().Where(memoryGregorianSQL("contact_important_dates")).
+			Where("contact_important_dates.year < ?", c.day.Year()).
+			Where("("+day+")", args...).Find(&dates).Error; err != nil {
+			return err
+		}
+		// A date in another calendar needs its original year to have an
+		// anniversary; without it the Gregorian columns hold a projection.
+		var others []models.ContactImportantDate
+		if err := base().Where("NOT "+memoryGregorianSQL("contact_important_dates")).
+			Where("contact_important_dates.original_year IS NOT NULL AND contact_important_dates.year < ?", c.day.Year()).
+			Find(&others).Error; err != nil {
+			return err
+		}
+		dates = append(dates, others...)
+	}
+	for _, d := range dates {
+		if !c.recurs(d.CalendarType, d.OriginalMonth, d.OriginalDay) {
+			continue
+		}
+		c.add(dto.MemoryResponse{
+			Kind:         models.MemoryKindImportantDate,
+			ID:           d.ID,
+			CalendarType: d.CalendarType,
+			Title:        d.Label,
+			ContactID:    d.ContactID,
+		}, time.Date(*d.Year, time.Month(*d.Month), *d.Day, 0, 0, 0, 0, time.UTC), true)
+	}
+	return nil
+}
+
+// finish names the contacts and vaults of the collected memories and
+// applies the user's dismissals and favorites.
+func (c *memoryCollector) finish() error {
+	if len(c.out) == 0 {
+		return nil
+	}
+	var contactIDs []string
+	for _, m := range c.out {
+		if m.ContactID != "" {
+			contactIDs = append(contactIDs, m.ContactID)
+		}
+	}
+	contacts := make(map[string]*models.Contact)
+	if len(contactIDs) > 0 {
+		var rows []models.Contact
+		if err := c.db.Where("id IN ?", contactIDs).Find(&rows).Error; err != nil {
+			return err
+		}
+		for i := range rows {
+			contacts[rows[i].ID] = &rows[i]
+		}
+	}
+	formatter, err := newContactNameFormatter(c.db, c.userID)
+	if err != nil {
+		return err
+	}
+
+	var states []models.MemoryState
+	if err := c.db.Where("user_id = ?", c.userID).Find(&states).Error; err != nil {
+		return err
+	}
+	byItem := make(map[string]models.MemoryState, len(states))
+	for _, st := range states {
+		byItem[memoryStateKey(st.Kind, st.ItemID)] = st
+	}
+
+	for i := range c.out {
+		m := &c.out[i]
+		if contact := contacts[m.ContactID]; contact != nil {
+			if m.ContactName, err = formatter.format(contact, ""); err != nil {
+				return err
+			}
+			if m.VaultID == "" {
+				m.VaultID = contact.VaultID
+			}
+		}
+		if m.Title == "" && m.Kind == models.MemoryKindCall {
+			m.Title = m.ContactName
+		}
+		m.VaultName = c.vaults[m.VaultID]
+		st := byItem[memoryStateKey(m.Kind, m.ID)]
+		m.IsFavorite = st.Favorite
+		m.IsDismissed = st.Dismissed
+	}
+	return nil
+}
+
+func memoryStateKey(kind string, id uint) string {
+	return fmt.Sprintf("%s|%d", kind, id)
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	calendarPkg "github.com/naiba/bonds/internal/calendar"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+type memoryFixture struct {
+	db        *gorm.DB
+	svc       *MemoryService
+	mailer    *mockMailer
+	userID    string
+	vaultID   string
+	journalID uint
+	contactID string
+}
+
+func setupMemoryTest(t *testing.T) memoryFixture {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	resp, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Test",
+		LastName:  "User",
+		Email:     "memories-test@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	vault, err := NewVaultService(db).CreateVault(resp.User.AccountID, resp.User.ID, dto.CreateVaultRequest{Name: "Family"}, "en")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+	contact, err := NewContactService(db).CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: "Alice", LastName: "Smith"})
+	if err != nil {
+		t.Fatalf("CreateContact failed: %v", err)
+	}
+	journal := models.Journal{VaultID: vault.ID, Name: "Diary"}
+	if err := db.Create(&journal).Error; err != nil {
+		t.Fatalf("create journal: %v", err)
+	}
+	mailer := &mockMailer{}
+	return memoryFixture{
+		db:        db,
+		svc:       NewMemoryService(db, mailer, nil),
+		mailer:    mailer,
+		userID:    resp.User.ID,
+		vaultID:   vault.ID,
+		journalID: journal.ID,
+		contactID: contact.ID,
+	}
+}
+
+func (f memoryFixture) createPost(t *testing.T, title string, writtenAt time.Time) uint {
+	t.Helper()
+	post := models.Post{JournalID: f.journalID, Title: strPtrOrNil(title), Published: true, WrittenAt: writtenAt, CalendarType: "gregorian"}
+	if err := f.db.Create(&post).Error; err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	return post.ID
+}
+
+func (f memoryFixture) createImportantDate(t *testing.T, date models.ContactImportantDate) uint {
+	t.Helper()
+	date.ContactID = f.contactID
+	if date.CalendarType == "" {
+		date.CalendarType = "gregorian"
+	}
+	if err := f.db.Create(&date).Error; err != nil {
+		t.Fatalf("create important date: %v", err)
+	}
+	return date.ID
+}
+
+func memoryTitles(memories []dto.MemoryResponse) []string {
+	titles := make([]string, len(memories))
+	for i, m := range memories {
+		titles[i] = m.Kind + ":" + m.Title
+	}
+	return titles
+}
+
+func TestOnThisDayGathersMemories(t *testing.T) {
+	f := setupMemoryTest(t)
+	f.createPost(t, "Lake trip", time.Date(2020, 10, 19, 12, 0, 0, 0, time.UTC))
+	f.createPost(t, "Day before", time.Date(2020, 10, 18, 12, 0, 0, 0, time.UTC))
+	f.createPost(t, "This year", time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC))
+
+	start := time.Date(2018, 10, 19, 0, 0, 0, 0, time.UTC)
+	happened := time.Date(2019, 10, 19, 20, 0, 0, 0, time.UTC)
+	photoAt := time.Date(2015, 10, 19, 9, 0, 0, 0, time.UTC)
+	rows := []interface{}{
+		&models.Activity{VaultID: f.vaultID, Title: "Concert", StartDate: &start, StartPrecision: "day", CalendarType: "gregorian"},
+		&models.Call{ContactID: f.contactID, AuthorName: "Test User", CalledAt: time.Date(2021, 10, 19, 18, 0, 0, 0, time.UTC), Type: "audio", WhoInitiated: "me"},
+		&models.Note{ContactID: f.contactID, VaultID: f.vaultID, Title: strPtrOrNil("Promotion"), Body: "Alice got the job", HappenedAt: &happened},
+		&models.File{VaultID: f.vaultID, UUID: "photo-uuid", Name: "beach.jpg", MimeType: "image/jpeg", Type: "photo", Size: 1, UfileableID: &f.contactID, FileableType: strPtrOrNil("Contact"), CreatedAt: photoAt},
+	}
+	for _, row := range rows {
+		if err := f.db.Create(row).Error; err != nil {
+			t.Fatalf("create %T: %v", row, err)
+		}
+	}
+	f.createImportantDate(t, models.ContactImportantDate{Label: "Birthday", Day: intPtr(19), Month: intPtr(10), Year: intPtr(1990)})
+	f.createImportantDate(t, models.ContactImportantDate{Label: "Name day", Day: intPtr(19), Month: intPtr(10)})
+
+	resp, err := f.svc.OnThisDay(f.userID, "2026-10-19")
+	if err != nil {
+		t.Fatalf("OnThisDay failed: %v", err)
+	}
+	got := strings.Join(memoryTitles(resp.Memories), ", ")
+	want := "call:Alice Smith, post:Lake trip, note:Promotion, activity:Concert, photo:beach.jpg, important_date:Birthday"
+	if got != want {
+		t.Fatalf("memories = %s, want %s", got, want)
+	}
+	birthday := resp.Memories[len(resp.Memories)-1]
+	if birthday.YearsAgo != 36 || birthday.ContactName != "Alice Smith" || birthday.VaultName != "Family" {
+		t.Errorf("unexpected birthday memory %+v", birthday)
+	}
+
+	if _, err := f.svc.OnThisDay(f.userID, "19/10/2026"); !errors.Is(err, ErrInvalidMemoryDate) {
+		t.Errorf("expected ErrInvalidMemoryDate, got %v", err)
+	}
+}
+
+func TestOnThisDayFollowsLunarDates(t *testing.T) {
+	f := setupMemoryTest(t)
+	converter, _ := calendarPkg.Get(calendarPkg.Lunar)
+	born, err := converter.ToGregorian(calendarPkg.DateInfo{Year: 1990, Month: 8, Day: 15})
+	if err != nil {
+		t.Fatalf("ToGregorian failed: %v", err)
+	}
+	f.createImportantDate(t, models.ContactImportantDate{
+		Label:         "Birthday",
+		Day:           &born.Day,
+		Month:         &born.Month,
+		Year:          &born.Year,
+		CalendarType:  "lunar",
+		OriginalDay:   intPtr(15),
+		OriginalMonth: intPtr(8),
+		OriginalYear:  intPtr(1990),
+	})
+
+	// Mid-Autumn 2026 falls on a different Gregorian day than in 1990.
+	festival, err := calendarOccurrenceInYear(converter, calendarPkg.DateInfo{Month: 8, Day: 15}, 2026)
+	if err != nil {
+		t.Fatalf("calendarOccurrenceInYear failed: %v", err)
+	}
+	lunarDay := time.Date(festival.Year, time.Month(festival.Month), festival.Day, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+	gregorianDay := time.Date(2026, time.Month(born.Month), born.Day, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+	if lunarDay == gregorianDay {
+		t.Fatalf("test dates should differ, both are %s", lunarDay)
+	}
+
+	resp, err := f.svc.OnThisDay(f.userID, lunarDay)
+	if err != nil {
+		t.Fatalf("OnThisDay failed: %v", err)
+	}
+	if len(resp.Memories) != 1 || resp.Memories[0].CalendarType != "lunar" || resp.Memories[0].YearsAgo != 36 {
+		t.Fatalf("expected the lunar birthday on %s, got %+v", lunarDay, resp.Memories)
+	}
+	resp, err = f.svc.OnThisDay(f.userID, gregorianDay)
+	if err != nil {
+		t.Fatalf("OnThisDay failed: %v", err)
+	}
+	if len(resp.Memories) != 0 {
+		t.Errorf("expected no memory on the Gregorian anniversary %s, got %+v", gregorianDay, resp.Memories)
+	}
+}
+
+func TestOnThisDayLeapDay(t *testing.T) {
+	f := setupMemoryTest(t)
+	f.createImportantDate(t, models.ContactImportantDate{Label: "Wedding", Day: intPtr(29), Month: intPtr(2), Year: intPtr(2000)})
+	f.createPost(t, "Leap day", time.Date(2024, 2, 29, 10, 0, 0, 0, time.UTC))
+
+	for date, want := range map[string]int{"2027-02-28": 2, "2028-02-28": 0, "2028-02-29": 2, "2027-03-01": 0} {
+		resp, err := f.svc.OnThisDay(f.userID, date)
+		if err != nil {
+			t.Fatalf("OnThisDay(%s) failed: %v", date, err)
+		}
+		if len(resp.Memories) != want {
+			t.Errorf("OnThisDay(%s) = %v, want %d memories", date, memoryTitles(resp.Memories), want)
+		}
+	}
+}
+
+func TestMemoryDismissAndFavorite(t *testing.T) {
+	f := setupMemoryTest(t)
+	older := f.createPost(t, "Older", time.Date(2016, 10, 19, 12, 0, 0, 0, time.UTC))
+	newer := f.createPost(t, "Newer", time.Date(2024, 10, 19, 12, 0, 0, 0, time.UTC))
+	dismissed := f.createPost(t, "Dismissed", time.Date(2022, 10, 19, 12, 0, 0, 0, time.UTC))
+
+	yes := true
+	if _, err := f.svc.UpdateState(f.userID, models.MemoryKindPost, dismissed, dto.UpdateMemoryStateRequest{Dismissed: &yes}); err != nil {
+		t.Fatalf("dismiss failed: %v", err)
+	}
+	m, err := f.svc.UpdateState(f.userID, models.MemoryKindPost, older, dto.UpdateMemoryStateRequest{Favorite: &yes})
+	if err != nil {
+		t.Fatalf("favorite failed: %v", err)
+	}
+	if !m.IsFavorite || m.Title != "Older" {
+		t.Errorf("unexpected memory %+v", m)
+	}
+
+	resp, err := f.svc.OnThisDay(f.userID, "2026-10-19")
+	if err != nil {
+		t.Fatalf("OnThisDay failed: %v", err)
+	}
+	if got := strings.Join(memoryTitles(resp.Memories), ", "); got != "post:Older, post:Newer" {
+		t.Errorf("expected the favorite first and the dismissed post gone, got %s", got)
+	}
+	favorites, err := f.svc.Favorites(f.userID)
+	if err != nil {
+		t.Fatalf("Favorites failed: %v", err)
+	}
+	if len(favorites) != 1 || favorites[0].ID != older {
+		t.Errorf("favorites = %+v", favorites)
+	}
+
+	no := false
+	if _, err := f.svc.UpdateState(f.userID, models.MemoryKindPost, older, dto.UpdateMemoryStateRequest{Favorite: &no}); err != nil {
+		t.Fatalf("unfavorite failed: %v", err)
+	}
+	var states int64
+	f.db.Model(&models.MemoryState{}).Where("item_id = ?", older).Count(&states)
+	if states != 0 {
+		t.Errorf("expected the state row to be removed, got %d", states)
+	}
+
+	if _, err := f.svc.UpdateState(f.userID, "letter", newer, dto.UpdateMemoryStateRequest{Favorite: &yes}); !errors.Is(err, ErrInvalidMemoryKind) {
+		t.Errorf("expected ErrInvalidMemoryKind, got %v", err)
+	}
+	if _, err := f.svc.UpdateState(f.userID, models.MemoryKindCall, newer, dto.UpdateMemoryStateRequest{Favorite: &yes}); !errors.Is(err, ErrMemoryNotFound) {
+		t.Errorf("expected ErrMemoryNotFound, got %v", err)
+	}
+}
+
+func TestMemoryDailyNotification(t *testing.T) {
+	f := setupMemoryTest(t)
+	f.createPost(t, "Lake trip", time.Date(2020, 10, 19, 12, 0, 0, 0, time.UTC))
+
+	if _, err := f.svc.UpdatePreferences(f.userID, dto.UpdateMemoryPreferenceRequest{NotifyDaily: true, NotifyHour: 24}); !errors.Is(err, ErrInvalidMemoryNotifyHour) {
+		t.Fatalf("expected ErrInvalidMemoryNotifyHour, got %v", err)
+	}
+	f.svc.processDailyNotifications(time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC))
+	if len(f.mailer.calls) != 0 {
+		t.Fatalf("expected no notification before opting in, got %d", len(f.mailer.calls))
+	}
+	if _, err := f.svc.UpdatePreferences(f.userID, dto.UpdateMemoryPreferenceRequest{NotifyDaily: true, NotifyHour: 8}); err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+
+	f.svc.processDailyNotifications(time.Date(2026, 10, 19, 7, 30, 0, 0, time.UTC))
+	if len(f.mailer.calls) != 0 {
+		t.Fatalf("expected no notification before 8:00, got %d", len(f.mailer.calls))
+	}
+	f.svc.processDailyNotifications(time.Date(2026, 10, 19, 8, 5, 0, 0, time.UTC))
+	f.svc.processDailyNotifications(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	if len(f.mailer.calls) != 1 {
+		t.Fatalf("expected one notification for the day, got %d", len(f.mailer.calls))
+	}
+	if call := f.mailer.calls[0]; call.Subject != "On this day" || !strings.Contains(call.Body, "<strong>2020</strong> Journal: Lake trip") {
+		t.Errorf("unexpected notification %+v", call)
+	}
+
+	// Nothing happened on 20 October, so nothing is sent.
+	f.svc.processDailyNotifications(time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC))
+	if len(f.mailer.calls) != 1 {
+		t.Errorf("expected no notification without memories, got %d", len(f.mailer.calls))
+	}
+}
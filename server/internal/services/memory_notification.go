@@ -0,0 +1,154 @@
+package services
+
+import (
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/naiba/bonds/internal/database"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/i18n"
+	"github.com/naiba/bonds/internal/models"
+)
+
+// memoryNotificationLimit caps how many memories one notification lists.
+const memoryNotificationLimit = 10
+
+// ProcessDailyNotifications sends the "On this day" notification to every
+// user who turned it on, once per local day from their chosen hour. Like
+// task notifications, each one is claimed by inserting its
+// MemoryNotificationDelivery row, so replicas never send it twice.
+func (s *MemoryService) ProcessDailyNotifications() {
+	s.processDailyNotifications(time.Now())
+}
+
+func (s *MemoryService) processDailyNotifications(now time.Time) {
+	var prefs []models.MemoryPreference
+	if err := s.db.Where("notify_daily = ?", true).Order("id ASC").Find(&prefs).Error; err != nil {
+		log.Printf("[memories] Failed to load preferences: %v", err)
+		return
+	}
+	for _, pref := range prefs {
+		var user models.User
+		if err := s.db.Where("id = ?", pref.UserID).First(&user).Error; err != nil {
+			log.Printf("[memories] Load user %s: %v", pref.UserID, err)
+			continue
+		}
+		local := now.In(userLocation(&user))
+		if local.Hour() < pref.NotifyHour {
+			continue
+		}
+		date := local.Format("2006-01-02")
+		var claimed int64
+		if err := s.db.Model(&models.MemoryNotificationDelivery{}).
+			Where("user_id = ? AND date = ?", user.ID, date).Count(&claimed).Error; err != nil {
+			log.Printf("[memories] Load delivery state for user %s: %v", user.ID, err)
+			continue
+		}
+		if claimed > 0 {
+			continue
+		}
+		s.deliver(&user, local, now)
+	}
+}
+
+// deliver claims today's notification of a user and sends it to their
+// active channels. Days without memories are claimed without sending
+// anything; if every channel fails the claim is released for a retry.
+func (s *MemoryService) deliver(user *models.User, local, now time.Time) {
+	claim := models.MemoryNotificationDelivery{UserID: user.ID, Date: local.Format("2006-01-02"), ClaimedAt: now}
+	if err := s.db.Create(&claim).Error; err != nil {
+		if !database.IsUniqueConstraintErr(err) {
+			log.Printf("[memories] Claim notification for user %s: %v", user.ID, err)
+		}
+		return
+	}
+
+	memories, err := s.onThisDay(user.ID, local)
+	if err != nil {
+		log.Printf("[memories] Gather memories for user %s: %v", user.ID, err)
+		s.releaseClaim(&claim)
+		return
+	}
+	if len(memories) == 0 {
+		s.finishClaim(&claim, now, "no memories")
+		return
+	}
+	var channels []models.UserNotificationChannel
+	if err := s.db.Where("user_id = ? AND active = ?", user.ID, true).Find(&channels).Error; err != nil {
+		log.Printf("[memories] Load channels for user %s: %v", user.ID, err)
+		s.releaseClaim(&claim)
+		return
+	}
+	if len(channels) == 0 {
+		s.finishClaim(&claim, now, "no active notification channels")
+		return
+	}
+
+	subject, body := memoryNotificationContent(memories, user, local.Year())
+	delivered := false
+	for i := range channels {
+		channel := &channels[i]
+		sendErr := sendToNotificationChannel(s.mailer, s.sender, s.webPush, channel, subject, body)
+		if sendErr != nil {
+			if err := recordChannelFailure(s.db, channel, subject, body, sendErr, time.Now()); err != nil {
+				log.Printf("[memories] Record failed delivery on channel %d: %v", channel.ID, err)
+			}
+			continue
+		}
+		delivered = true
+		if err := recordChannelSuccess(s.db, channel, subject, body, time.Now()); err != nil {
+			log.Printf("[memories] Record delivery on channel %d: %v", channel.ID, err)
+		}
+	}
+	if !delivered {
+		s.releaseClaim(&claim)
+		return
+	}
+	s.finishClaim(&claim, time.Now(), "")
+}
+
+func (s *MemoryService) finishClaim(claim *models.MemoryNotificationDelivery, sentAt time.Time, note string) {
+	updates := map[string]interface{}{"sent_at": sentAt}
+	if note != "" {
+		updates["error"] = note
+	}
+	if err := s.db.Model(claim).Updates(updates).Error; err != nil {
+		log.Printf("[memories] Mark delivery %d sent: %v", claim.ID, err)
+	}
+}
+
+func (s *MemoryService) releaseClaim(claim *models.MemoryNotificationDelivery) {
+	if err := s.db.Delete(claim).Error; err != nil {
+		log.Printf("[memories] Release delivery %d: %v", claim.ID, err)
+	}
+}
+
+// memoryNotificationContent lists the memories in the order of the API,
+// each with the year it happened.
+func memoryNotificationContent(memories []dto.MemoryResponse, user *models.User, year int) (string, string) {
+	locale, _ := reminderDeliveryLocale(user)
+	subject := i18n.T(locale, "memory_notification.subject")
+
+	var items strings.Builder
+	for i, m := range memories {
+		if i == memoryNotificationLimit {
+			break
+		}
+		line := i18n.T(locale, "memory.kind."+m.Kind)
+		if m.Title != "" {
+			line += ": " + m.Title
+		}
+		if m.ContactName != "" && m.ContactName != m.Title {
+			line += " (" + m.ContactName + ")"
+		}
+		items.WriteString("<li><strong>" + strconv.Itoa(year-m.YearsAgo) + "</strong> " + html.EscapeString(line) + "</li>")
+	}
+	body := i18n.Tt(locale, "memory_notification.body", map[string]string{"items": items.String()})
+	if len(memories) > memoryNotificationLimit {
+		body += i18n.Tt(locale, "memory_notification.more", map[string]string{"count": strconv.Itoa(len(memories) - memoryNotificationLimit)})
+	}
+	return subject, body
+}
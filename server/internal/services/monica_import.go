@@ -76,6 +76,14 @@ func (s *MonicaImportService) SetSearchEngine(se search.Engine) {
 }
 
 func (s *MonicaImportService) Import(vaultID, userID string, data []byte) (*dto.MonicaImportResponse, error) {
+	return s.ImportWithProgress(vaultID, userID, data, nil)
+}
+
+// ImportWithProgress is Import reporting each contact once when it is
+// created and once when its notes, calls and other details are imported.
+// When the import is cancelled it stops between contacts and returns what
+// it imported so far along with ErrJobCancelled.
+func (s *MonicaImportService) ImportWithProgress(vaultID, userID string, data []byte, progress JobProgress) (*dto.MonicaImportResponse, error) {
 	export, err := ParseMonicaExport(data)
 	if err != nil {
 		return nil, err
@@ -98,7 +106,15 @@ func (s *MonicaImportService) Import(vaultID, userID string, data []byte) (*dto.
 	contactUUIDMap := make(map[string]string)
 
 	contactRaws := getCollectionByType(export.Account.Data, "contacts")
+	progressSetTotal(progress, 2*len(contactRaws))
 	for _, raw := range contactRaws {
+		if progressCancelled(progress) {
+			if err := recordImportedVaultChanges(s.DB, vaultID); err != nil {
+				return resp, err
+			}
+			return resp, ErrJobCancelled
+		}
+		progressAdvance(progress, 1)
 		var mc MonicaContact
 		if err := json.Unmarshal(raw, &mc); err != nil {
 			resp.Errors = append(resp.Errors, fmt.Sprintf("failed to parse contact: %v", err))
@@ -121,6 +137,13 @@ func (s *MonicaImportService) Import(vaultID, userID string, data []byte) (*dto.
 
 	// Phase 2: 导入子资源 (需要重新遍历 contactRaws)
 	for _, raw := range contactRaws {
+		if progressCancelled(progress) {
+			if err := recordImportedVaultChanges(s.DB, vaultID); err != nil {
+				return resp, err
+			}
+			return resp, ErrJobCancelled
+		}
+		progressAdvance(progress, 1)
 		var mc MonicaContact
 		if err := json.Unmarshal(raw, &mc); err != nil {
 			continue
@@ -206,10 +229,28 @@ func (s *MonicaImportService) Import(vaultID, userID string, data []byte) (*dto.
 			resp.Errors = append(resp.Errors, "photo/document import skipped: no upload directory configured")
 		}
 	}
+	if err := recordImportedVaultChanges(s.DB, vaultID); err != nil {
+		return resp, err
+	}
 
 	return resp, nil
 }
 
+// RunImportJob runs a monica_import job on the export file in its input.
+func (s *MonicaImportService) RunImportJob(run *JobRun) (interface{}, []string, error) {
+	job := run.Job()
+	if job.VaultID == nil {
+		return nil, nil, errors.New("monica import job has no vault")
+	}
+	resp, err := s.ImportWithProgress(*job.VaultID, job.UserID, run.Input(), run)
+	if resp == nil {
+		return nil, nil, err
+	}
+	itemErrors := resp.Errors
+	resp.Errors = []string{}
+	return resp, itemErrors, err
+}
+
 func (s *MonicaImportService) importContact(
 	tx *gorm.DB, mc *MonicaContact, vaultID, accountID, userID string,
 	genderByUUID map[string]MonicaGenderRef, resp *dto.MonicaImportResponse,
@@ -476,7 +517,7 @@ func (s *MonicaImportService) importContactSubResources(
 	s.importTasks(tx, mc, contactID, vaultID, userID, resp)
 	s.importReminders(tx, mc, contactID, vaultID, userID, resp)
 	s.importAddresses(tx, mc, contactID, vaultID, accountID, resp)
-	s.importContactFields(tx, mc, contactID, accountID, fieldTypeByUUID, resp)
+	s.importContactFields(tx, mc, contactID, accountID, phoneRegionForUser(tx, userID), fieldTypeByUUID, resp)
 	s.importPets(tx, mc, contactID, accountID, resp)
 	s.importGifts(tx, mc, contactID, accountID, resp)
 	s.recordSkippedDebts(mc, resp)
@@ -735,12 +776,15 @@ func (s *MonicaImportService) importAddresses(
 		ca := models.ContactAddress{ContactID: contactID, AddressID: addr.ID}
 		if err := tx.Create(&ca).Error; err == nil {
 			resp.ImportedAddresses++
+			if _, err := queueGeocoding(tx, addr); err != nil {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("contact %s: could not queue address for geocoding: %v", mc.UUID, err))
+			}
 		}
 	}
 }
 
 func (s *MonicaImportService) importContactFields(
-	tx *gorm.DB, mc *MonicaContact, contactID, accountID string,
+	tx *gorm.DB, mc *MonicaContact, contactID, accountID, phoneRegion string,
 	fieldTypeByUUID map[string]MonicaContactFieldTypeRef,
 	resp *dto.MonicaImportResponse,
 ) {
@@ -775,6 +819,9 @@ func (s *MonicaImportService) importContactFields(
 			TypeID:    ciType.ID,
 			Data:      mcf.Properties.Data,
 		}
+		if ciType.Type != nil && *ciType.Type == "phone" {
+			ci.NormalizedData = models.NormalizedPhone(ci.Data, phoneRegion)
+		}
 		tx.Create(&ci)
 	}
 }
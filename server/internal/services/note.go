@@ -16,6 +16,8 @@ type NoteService struct {
 	db            *gorm.DB
 	feedRecorder  *FeedRecorder
 	searchService *SearchService
+	// effects is set on the services of a batch; see withTx.
+	effects *txEffects
 }
 
 func NewNoteService(db *gorm.DB) *NoteService {
@@ -80,7 +82,12 @@ func (s *NoteService) Create(contactID, vaultID, authorID string, req dto.Create
 		Body:      req.Body,
 		EmotionID: req.EmotionID,
 	}
-	if err := s.db.Create(&note).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeNote, note.ID, models.VaultChangeCreated)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -90,7 +97,7 @@ func (s *NoteService) Create(contactID, vaultID, authorID string, req dto.Create
 	}
 
 	if s.searchService != nil {
-		s.searchService.IndexNote(&note)
+		s.effects.after(func() { s.searchService.IndexNote(&note) })
 	}
 
 	resp := toNoteResponse(&note)
@@ -111,7 +118,12 @@ func (s *NoteService) Update(id uint, contactID, vaultID string, req dto.UpdateN
 	note.Title = strPtrOrNil(req.Title)
 	note.Body = req.Body
 	note.EmotionID = req.EmotionID
-	if err := s.db.Save(&note).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&note).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeNote, note.ID, models.VaultChangeUpdated)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -132,12 +144,17 @@ func (s *NoteService) Delete(id uint, contactID, vaultID string) error {
 	if err := validateContactBelongsToVault(s.db, contactID, vaultID); err != nil {
 		return err
 	}
-	result := s.db.Where("id = ? AND contact_id = ?", id, contactID).Delete(&models.Note{})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return ErrNoteNotFound
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND contact_id = ?", id, contactID).Delete(&models.Note{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNoteNotFound
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeNote, id, models.VaultChangeDeleted)
+	}); err != nil {
+		return err
 	}
 
 	if s.feedRecorder != nil {
@@ -111,25 +111,28 @@ func reminderAudienceIncludesUser(db *gorm.DB, reminder *models.ContactReminder,
 }
 
 func ensureFuturePendingReminderSchedule(db *gorm.DB, reminder *models.ContactReminder, channel *models.UserNotificationChannel, now time.Time) error {
-	scheduledAt := calcInitialSchedule(reminder, channel.PreferredTime, userLocation(channel.User)).UTC()
-	// Joining after an occurrence became due must not retroactively authorize a catch-up delivery.
-	if !scheduledAt.After(now) {
-		return nil
-	}
-	var existing models.ContactReminderScheduled
-	err := db.Where("user_notification_channel_id = ? AND contact_reminder_id = ? AND triggered_at IS NULL", channel.ID, reminder.ID).First(&existing).Error
-	if err == nil {
-		return nil
-	}
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return fmt.Errorf("pending schedule load: %w", err)
-	}
-	if err := db.Create(&models.ContactReminderScheduled{
-		UserNotificationChannelID: channel.ID,
-		ContactReminderID:         reminder.ID,
-		ScheduledAt:               scheduledAt,
-	}).Error; err != nil {
-		return fmt.Errorf("pending schedule create: %w", err)
+	for _, leadDays := range reminderLeadDays(reminder) {
+		scheduledAt, ok := calcInitialLeadSchedule(reminder, channel.PreferredTime, userLocation(channel.User), leadDays, now)
+		// Joining after an occurrence became due must not retroactively authorize a catch-up delivery.
+		if !ok || !scheduledAt.After(now) {
+			continue
+		}
+		var existing models.ContactReminderScheduled
+		err := db.Where("user_notification_channel_id = ? AND contact_reminder_id = ? AND lead_days = ? AND snooze_of_id IS NULL AND triggered_at IS NULL", channel.ID, reminder.ID, leadDays).First(&existing).Error
+		if err == nil {
+			continue
+		}
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("pending schedule load: %w", err)
+		}
+		if err := db.Create(&models.ContactReminderScheduled{
+			UserNotificationChannelID: channel.ID,
+			ContactReminderID:         reminder.ID,
+			ScheduledAt:               scheduledAt.UTC(),
+			LeadDays:                  leadDays,
+		}).Error; err != nil {
+			return fmt.Errorf("pending schedule create: %w", err)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+)
+
+var ErrWebPushNotConfigured = errors.New("web push is not configured")
+
+// WebPushPublicKey returns the VAPID key browsers need to subscribe.
+func (s *NotificationService) WebPushPublicKey() (*dto.WebPushPublicKeyResponse, error) {
+	if s.webPush == nil {
+		return nil, ErrWebPushNotConfigured
+	}
+	key, err := s.webPush.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &dto.WebPushPublicKeyResponse{PublicKey: key}, nil
+}
+
+// SubscribeWebPush registers a browser push subscription as a Web Push
+// channel. Push channels need no verification: the subscription can only be
+// created by the browser the user is signed in on. Subscribing the same
+// endpoint again refreshes its keys and re-activates the existing channel,
+// which is what a PWA does after the push service rotated the subscription
+// or it was disabled as expired. The bool reports whether a channel was
+// created.
+func (s *NotificationService) SubscribeWebPush(userID string, req dto.WebPushSubscribeRequest) (*dto.NotificationChannelResponse, bool, error) {
+	var sub webPushSubscription
+	sub.Endpoint = req.Endpoint
+	sub.Keys.P256dh = req.Keys.P256dh
+	sub.Keys.Auth = req.Keys.Auth
+	raw, err := json.Marshal(sub)
+	if err != nil {
+		return nil, false, err
+	}
+	content := string(raw)
+	if _, _, _, err := parseWebPushSubscription(content); err != nil {
+		return nil, false, err
+	}
+
+	var existing []models.UserNotificationChannel
+	if err := s.db.Where("user_id = ? AND type = ?", userID, webPushChannelType).Find(&existing).Error; err != nil {
+		return nil, false, err
+	}
+	now := time.Now()
+	for i := range existing {
+		ch := &existing[i]
+		var stored webPushSubscription
+		if json.Unmarshal([]byte(ch.Content), &stored) != nil || stored.Endpoint != req.Endpoint {
+			continue
+		}
+		preferredTimeChanged := ptrToStr(ch.PreferredTime) != req.PreferredTime
+		ch.Content = content
+		if req.Label != "" {
+			ch.Label = &req.Label
+		}
+		ch.PreferredTime = strPtrOrNil(req.PreferredTime)
+		ch.Active = true
+		ch.Fails = 0
+		if ch.VerifiedAt == nil {
+			ch.VerifiedAt = &now
+		}
+		if err := s.db.Save(ch).Error; err != nil {
+			return nil, false, err
+		}
+		if preferredTimeChanged {
+			if err := s.db.Where("user_notification_channel_id = ? AND triggered_at IS NULL", ch.ID).
+				Delete(&models.ContactReminderScheduled{}).Error; err != nil {
+				return nil, false, err
+			}
+		}
+		if err := s.ScheduleAllContactReminders(ch.ID, userID); err != nil {
+			return nil, false, err
+		}
+		resp := toNotificationChannelResponse(ch)
+		return &resp, false, nil
+	}
+
+	ch := models.UserNotificationChannel{
+		UserID:        &userID,
+		Type:          webPushChannelType,
+		Label:         strPtrOrNil(req.Label),
+		Content:       content,
+		PreferredTime: strPtrOrNil(req.PreferredTime),
+		VerifiedAt:    &now,
+	}
+	if err := s.db.Create(&ch).Error; err != nil {
+		return nil, false, err
+	}
+	// Active is a zero-value bool with a gorm default, so set it explicitly.
+	ch.Active = true
+	if err := s.db.Model(&ch).Update("active", true).Error; err != nil {
+		return nil, false, err
+	}
+	if err := s.ScheduleAllContactReminders(ch.ID, userID); err != nil {
+		return nil, false, err
+	}
+	resp := toNotificationChannelResponse(&ch)
+	return &resp, true, nil
+}
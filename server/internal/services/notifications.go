@@ -20,6 +20,7 @@ type NotificationService struct {
 	db       *gorm.DB
 	mailer   Mailer
 	sender   NotificationSender
+	webPush  *WebPushService
 	settings *SystemSettingService
 }
 
@@ -35,6 +36,10 @@ func (s *NotificationService) SetSender(sender NotificationSender) {
 	s.sender = sender
 }
 
+func (s *NotificationService) SetWebPush(webPush *WebPushService) {
+	s.webPush = webPush
+}
+
 func (s *NotificationService) SetSystemSettings(settings *SystemSettingService) {
 	s.settings = settings
 }
@@ -101,6 +106,11 @@ func (s *NotificationService) Update(id uint, userID string, req dto.UpdateNotif
 
 	contentChanged := ch.Content != req.Content
 	preferredTimeChanged := ptrToStr(ch.PreferredTime) != req.PreferredTime
+	if contentChanged && ch.Type == webPushChannelType {
+		if _, _, _, err := parseWebPushSubscription(req.Content); err != nil {
+			return nil, err
+		}
+	}
 
 	ch.Label = strPtrOrNil(req.Label)
 	ch.Content = req.Content
@@ -212,6 +222,10 @@ func (s *NotificationService) SendTest(id uint, userID string) error {
 		if s.sender != nil {
 			sendErr = s.sender.Send(ch.Content, subject, body)
 		}
+	case webPushChannelType:
+		if s.webPush != nil {
+			sendErr = s.webPush.Send(ch.Content, subject, body)
+		}
 	}
 
 	now := time.Now()
@@ -228,6 +242,11 @@ func (s *NotificationService) SendTest(id uint, userID string) error {
 	if err := s.db.Create(&sent).Error; err != nil {
 		return err
 	}
+	if errors.Is(sendErr, ErrWebPushSubscriptionGone) {
+		if err := s.db.Model(&ch).Update("active", false).Error; err != nil {
+			return err
+		}
+	}
 	return sendErr
 }
 
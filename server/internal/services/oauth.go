@@ -42,15 +42,23 @@ func NewOAuthService(db *gorm.DB, jwt *config.JWTConfig) *OAuthService {
 // If no matching email exists, returns ErrOAuthAccountNotLinked instead of
 // auto-creating an account — the caller must redirect the user to the
 // account-binding flow (login existing account or register new one).
-func (s *OAuthService) FindOrCreateUser(provider, providerUserID, email, name, locale string) (*dto.AuthResponse, *OAuthLinkInfo, error) {
+// Providers with claim rules are the exception: a first login whose claims
+// match a rule creates the user in the rules' account, and every login
+// re-applies the rules to users of that account.
+func (s *OAuthService) FindOrCreateUser(provider, providerUserID, email, name, locale string, claims map[string]interface{}) (*dto.AuthResponse, *OAuthLinkInfo, error) {
+	mapping, err := s.loadClaimMapping(provider)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var token models.UserToken
-	err := s.db.Where("driver = ? AND driver_id = ?", provider, providerUserID).First(&token).Error
+	err = s.db.Where("driver = ? AND driver_id = ?", provider, providerUserID).First(&token).Error
 	if err == nil {
 		var user models.User
 		if err := s.db.First(&user, "id = ?", token.UserID).Error; err != nil {
 			return nil, nil, err
 		}
-		resp, err := s.generateAuthResponse(&user)
+		resp, err := s.signIn(&user, &token, mapping, claims, locale)
 		return resp, nil, err
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -76,13 +84,22 @@ func (s *OAuthService) FindOrCreateUser(provider, providerUserID, email, name, l
 		if err := s.db.Create(&newToken).Error; err != nil {
 			return nil, nil, err
 		}
-		resp, err := s.generateAuthResponse(&existingUser)
+		resp, err := s.signIn(&existingUser, &newToken, mapping, claims, locale)
 		return resp, nil, err
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil, err
 	}
 
+	if mapping != nil && email != "" && len(mapping.matching(claims)) > 0 {
+		user, newToken, err := s.provisionClaimUser(mapping, provider, providerUserID, email, name, locale)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := s.signIn(user, newToken, mapping, claims, locale)
+		return resp, nil, err
+	}
+
 	// No matching email — do NOT auto-create account.
 	// Return link info so the handler can redirect to the binding flow.
 	linkInfo := &OAuthLinkInfo{
@@ -94,6 +111,24 @@ func (s *OAuthService) FindOrCreateUser(provider, providerUserID, email, name, l
 	return nil, linkInfo, ErrOAuthAccountNotLinked
 }
 
+// signIn applies the provider's claim rules to the users they provisioned
+// and issues the auth response.
+func (s *OAuthService) signIn(user *models.User, token *models.UserToken, mapping *oauthClaimMapping, claims map[string]interface{}, locale string) (*dto.AuthResponse, error) {
+	managed, err := s.managedByClaimRules(user, token, mapping)
+	if err != nil {
+		return nil, err
+	}
+	if managed {
+		if err := s.applyClaimRules(user, token, mapping, claims, locale); err != nil {
+			return nil, err
+		}
+	}
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+	return s.generateAuthResponse(user)
+}
+
 // SaveToken upserts an OAuth token for a user.
 func (s *OAuthService) SaveToken(userID, provider, providerUserID, accessToken, refreshToken string, expiresIn int) error {
 	var token models.UserToken
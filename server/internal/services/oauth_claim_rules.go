@@ -0,0 +1,391 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidOAuthClaimRule     = errors.New("a claim rule needs a claim, a value and a role to grant")
+	ErrOAuthClaimAccountNotFound = errors.New("claim rules account not found")
+)
+
+// GetClaimRules returns the provider's claim-to-role rules.
+func (s *OAuthProviderService) GetClaimRules(id uint) (*dto.OAuthClaimRulesResponse, error) {
+	var provider models.OAuthProvider
+	if err := s.db.First(&provider, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthProviderNotFoundByID
+		}
+		return nil, err
+	}
+	var rules []models.OAuthClaimRule
+	if err := s.db.Where("provider_id = ?", id).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return toOAuthClaimRulesResponse(&provider, rules), nil
+}
+
+// SetClaimRules replaces the provider's claim-to-role rules. Rules manage
+// the users of one account, which must exist while there are rules.
+func (s *OAuthProviderService) SetClaimRules(id uint, req dto.UpdateOAuthClaimRulesRequest) (*dto.OAuthClaimRulesResponse, error) {
+	var provider models.OAuthProvider
+	if err := s.db.First(&provider, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthProviderNotFoundByID
+		}
+		return nil, err
+	}
+
+	rules := make([]models.OAuthClaimRule, len(req.Rules))
+	for i, r := range req.Rules {
+		rule := models.OAuthClaimRule{
+			ProviderID:   id,
+			Claim:        strings.TrimSpace(r.Claim),
+			Value:        strings.TrimSpace(r.Value),
+			AccountAdmin: r.AccountAdmin,
+			VaultName:    strings.TrimSpace(r.VaultName),
+			Permission:   r.Permission,
+		}
+		if rule.Claim == "" || rule.Value == "" || !validClaimRuleGrant(rule) {
+			return nil, ErrInvalidOAuthClaimRule
+		}
+		rules[i] = rule
+	}
+	accountID := strings.TrimSpace(req.AccountID)
+	if len(rules) > 0 {
+		var count int64
+		if err := s.db.Model(&models.Account{}).Where("id = ?", accountID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrOAuthClaimAccountNotFound
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("provider_id = ?", id).Delete(&models.OAuthClaimRule{}).Error; err != nil {
+			return err
+		}
+		if len(rules) > 0 {
+			if err := tx.Create(&rules).Error; err != nil {
+				return err
+			}
+		}
+		provider.AccountID = strPtrOrNil(accountID)
+		return tx.Model(&provider).Update("account_id", provider.AccountID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toOAuthClaimRulesResponse(&provider, rules), nil
+}
+
+// validClaimRuleGrant reports whether the rule grants account
+// administration, vault access or both, and names a vault exactly when it
+// grants a vault permission.
+func validClaimRuleGrant(rule models.OAuthClaimRule) bool {
+	if (rule.VaultName == "") != (rule.Permission == 0) {
+		return false
+	}
+	switch rule.Permission {
+	case 0:
+		return rule.AccountAdmin
+	case models.PermissionManager, models.PermissionEditor, models.PermissionViewer:
+		return true
+	}
+	return false
+}
+
+func toOAuthClaimRulesResponse(provider *models.OAuthProvider, rules []models.OAuthClaimRule) *dto.OAuthClaimRulesResponse {
+	resp := &dto.OAuthClaimRulesResponse{
+		AccountID: ptrToStr(provider.AccountID),
+		Rules:     make([]dto.OAuthClaimRuleResponse, len(rules)),
+	}
+	for i, r := range rules {
+		resp.Rules[i] = dto.OAuthClaimRuleResponse{
+			ID:           r.ID,
+			Claim:        r.Claim,
+			Value:        r.Value,
+			AccountAdmin: r.AccountAdmin,
+			VaultName:    r.VaultName,
+			Permission:   r.Permission,
+		}
+	}
+	return resp
+}
+
+// oauthClaimMapping is the claim configuration of the provider a user signs
+// in with.
+type oauthClaimMapping struct {
+	accountID string
+	rules     []models.OAuthClaimRule
+}
+
+// loadClaimMapping returns the provider's claim mapping, or nil when the
+// provider has no rules.
+func (s *OAuthService) loadClaimMapping(providerName string) (*oauthClaimMapping, error) {
+	var provider models.OAuthProvider
+	if err := s.db.Where("name = ?", providerName).First(&provider).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if ptrToStr(provider.AccountID) == "" {
+		return nil, nil
+	}
+	var rules []models.OAuthClaimRule
+	if err := s.db.Where("provider_id = ?", provider.ID).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return &oauthClaimMapping{accountID: *provider.AccountID, rules: rules}, nil
+}
+
+// matching returns the rules whose claim contains their value.
+func (m *oauthClaimMapping) matching(claims map[string]interface{}) []models.OAuthClaimRule {
+	var matched []models.OAuthClaimRule
+	for _, rule := range m.rules {
+		if claimContains(lookupClaim(claims, rule.Claim), rule.Value) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+// lookupClaim resolves a dotted path such as "realm_access.roles", falling
+// back to the literal key for claims whose names contain dots.
+func lookupClaim(claims map[string]interface{}, path string) interface{} {
+	if v, ok := claims[path]; ok {
+		return v
+	}
+	var current interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+func claimContains(claim interface{}, value string) bool {
+	switch v := claim.(type) {
+	case nil:
+		return false
+	case []interface{}:
+		for _, item := range v {
+			if claimContains(item, value) {
+				return true
+			}
+		}
+		return false
+	case []string:
+		for _, item := range v {
+			if item == value {
+				return true
+			}
+		}
+		return false
+	case string:
+		return v == value
+	default:
+		return fmt.Sprint(v) == value
+	}
+}
+
+// provisionClaimUser creates a user in the mapping's account for a first
+// login whose claims match a rule. The rules then grant the roles.
+func (s *OAuthService) provisionClaimUser(mapping *oauthClaimMapping, provider, providerUserID, email, name, locale string) (*models.User, *models.UserToken, error) {
+	firstName, lastName := splitFullName(name)
+	if firstName == "" {
+		firstName, _, _ = strings.Cut(email, "@")
+	}
+	now := time.Now()
+	user := models.User{
+		AccountID:       mapping.accountID,
+		FirstName:       &firstName,
+		LastName:        strPtrOrNil(lastName),
+		Email:           email,
+		Locale:          locale,
+		EmailVerifiedAt: &now,
+	}
+	token := models.UserToken{
+		Driver:              provider,
+		DriverID:            providerUserID,
+		Format:              "oauth2",
+		Email:               &email,
+		ProvisionedByClaims: true,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		token.UserID = user.ID
+		return tx.Create(&token).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &user, &token, nil
+}
+
+// managedByClaimRules reports whether the mapping's rules manage the user:
+// only users the rules provisioned in their account, never the account
+// owner, who created the account before any rule existed.
+func (s *OAuthService) managedByClaimRules(user *models.User, token *models.UserToken, mapping *oauthClaimMapping) (bool, error) {
+	if mapping == nil || !token.ProvisionedByClaims || user.AccountID != mapping.accountID {
+		return false, nil
+	}
+	var owner models.User
+	if err := s.db.Where("account_id = ?", user.AccountID).Order("created_at ASC").First(&owner).Error; err != nil {
+		return false, err
+	}
+	return owner.ID != user.ID, nil
+}
+
+// applyClaimRules re-derives the user's roles from the claims of this login.
+// The rules are authoritative for what they mention: account administration
+// when any rule grants it, and membership of every vault a rule names. A
+// user matching no rule is disabled until a later login matches again. The
+// last administrator of the account is neither demoted nor disabled.
+func (s *OAuthService) applyClaimRules(user *models.User, token *models.UserToken, mapping *oauthClaimMapping, claims map[string]interface{}, locale string) error {
+	matched := mapping.matching(claims)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if len(matched) == 0 {
+			if user.Disabled {
+				return nil
+			}
+			if user.IsAccountAdministrator {
+				if last, err := lastAccountAdministrator(tx, user); err != nil || last {
+					return err
+				}
+			}
+			if err := tx.Model(user).Update("disabled", true).Error; err != nil {
+				return err
+			}
+			user.Disabled = true
+			return tx.Model(token).Update("disabled_by_claims", true).Error
+		}
+		if user.Disabled && token.DisabledByClaims {
+			if err := tx.Model(user).Update("disabled", false).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(token).Update("disabled_by_claims", false).Error; err != nil {
+				return err
+			}
+			user.Disabled = false
+			token.DisabledByClaims = false
+		}
+
+		managesAdmin, isAdmin := false, false
+		vaultNames := map[string]bool{}
+		for _, rule := range mapping.rules {
+			managesAdmin = managesAdmin || rule.AccountAdmin
+			if rule.VaultName != "" {
+				vaultNames[rule.VaultName] = true
+			}
+		}
+		granted := map[string]int{}
+		for _, rule := range matched {
+			isAdmin = isAdmin || rule.AccountAdmin
+			// Lower permission values are stronger.
+			if p, ok := granted[rule.VaultName]; rule.VaultName != "" && (!ok || rule.Permission < p) {
+				granted[rule.VaultName] = rule.Permission
+			}
+		}
+		if managesAdmin && user.IsAccountAdministrator && !isAdmin {
+			last, err := lastAccountAdministrator(tx, user)
+			if err != nil {
+				return err
+			}
+			managesAdmin = !last
+		}
+		if managesAdmin && user.IsAccountAdministrator != isAdmin {
+			if err := tx.Model(user).Update("is_account_administrator", isAdmin).Error; err != nil {
+				return err
+			}
+			user.IsAccountAdministrator = isAdmin
+		}
+
+		names := make([]string, 0, len(vaultNames))
+		for name := range vaultNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			permission, ok := granted[name]
+			if err := syncClaimVaultAccess(tx, user, name, permission, ok, locale); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// lastAccountAdministrator reports whether the user is the only enabled
+// administrator of their account.
+func lastAccountAdministrator(tx *gorm.DB, user *models.User) (bool, error) {
+	var others int64
+	err := tx.Model(&models.User{}).
+		Where("account_id = ? AND id <> ? AND is_account_administrator = ? AND disabled = ?", user.AccountID, user.ID, true, false).
+		Count(&others).Error
+	return others == 0, err
+}
+
+// syncClaimVaultAccess gives the user the permission on the named vault of
+// their account, creating the vault when needed, or removes their access
+// when no matching rule grants it.
+func syncClaimVaultAccess(tx *gorm.DB, user *models.User, vaultName string, permission int, granted bool, locale string) error {
+	var vault models.Vault
+	err := tx.Where("account_id = ? AND name = ?", user.AccountID, vaultName).Order("created_at ASC").First(&vault).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !granted {
+			return nil
+		}
+		vault = models.Vault{AccountID: user.AccountID, Name: vaultName, Type: "personal"}
+		if err := tx.Create(&vault).Error; err != nil {
+			return err
+		}
+		if err := models.SeedVaultDefaults(tx, vault.ID, locale); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	var access models.UserVault
+	err = tx.Where("user_id = ? AND vault_id = ?", user.ID, vault.ID).First(&access).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if !granted {
+			return nil
+		}
+		return tx.Create(&models.UserVault{UserID: user.ID, VaultID: vault.ID, Permission: permission}).Error
+	case err != nil:
+		return err
+	case !granted:
+		return tx.Delete(&access).Error
+	case access.Permission != permission:
+		return tx.Model(&access).Update("permission", permission).Error
+	}
+	return nil
+}
+
+// splitFullName splits a display name from an identity provider into first
+// and last name at its first space.
+func splitFullName(name string) (string, string) {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	return first, strings.TrimSpace(last)
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+func setupClaimRulesTest(t *testing.T) (*gorm.DB, *OAuthService, *dto.AuthResponse) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	owner, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Owner",
+		LastName:  "User",
+		Email:     "claims-owner@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	providers := NewOAuthProviderService(db)
+	provider, err := providers.Create(dto.CreateOAuthProviderRequest{
+		Type:         "oidc",
+		Name:         "family-sso",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		DiscoveryURL: "https://idp.example.com/.well-known/openid-configuration",
+		Enabled:      ptrBool(false),
+	})
+	if err != nil {
+		t.Fatalf("Create provider failed: %v", err)
+	}
+	if _, err := providers.SetClaimRules(provider.ID, dto.UpdateOAuthClaimRulesRequest{
+		AccountID: owner.User.AccountID,
+		Rules: []dto.OAuthClaimRuleRequest{
+			{Claim: "groups", Value: "bonds-admins", AccountAdmin: true},
+			{Claim: "groups", Value: "family", VaultName: "Family", Permission: models.PermissionEditor},
+			{Claim: "realm_access.roles", Value: "family-manager", VaultName: "Family", Permission: models.PermissionManager},
+			{Claim: "groups", Value: "friends", VaultName: "Friends", Permission: models.PermissionViewer},
+		},
+	}); err != nil {
+		t.Fatalf("SetClaimRules failed: %v", err)
+	}
+	return db, NewOAuthService(db, testutil.TestJWTConfig()), owner
+}
+
+func claimLogin(svc *OAuthService, claims map[string]interface{}) (*dto.AuthResponse, error) {
+	resp, _, err := svc.FindOrCreateUser("family-sso", "sub-42", "member@example.com", "Family Member", "en", claims)
+	return resp, err
+}
+
+func vaultPermission(t *testing.T, db *gorm.DB, accountID, userID, vaultName string) int {
+	t.Helper()
+	var vault models.Vault
+	if err := db.Where("account_id = ? AND name = ?", accountID, vaultName).First(&vault).Error; err != nil {
+		return 0
+	}
+	var access models.UserVault
+	if err := db.Where("user_id = ? AND vault_id = ?", userID, vault.ID).First(&access).Error; err != nil {
+		return 0
+	}
+	return access.Permission
+}
+
+func TestClaimRulesProvisionUserAndVault(t *testing.T) {
+	db, svc, owner := setupClaimRulesTest(t)
+	accountID := owner.User.AccountID
+
+	resp, err := claimLogin(svc, map[string]interface{}{"groups": []interface{}{"family"}})
+	if err != nil {
+		t.Fatalf("expected a matching first login to provision the user, got %v", err)
+	}
+	if resp.User.AccountID != accountID || resp.User.IsAdmin || resp.User.FirstName != "Family" {
+		t.Errorf("unexpected provisioned user %+v", resp.User)
+	}
+	if got := vaultPermission(t, db, accountID, resp.User.ID, "Family"); got != models.PermissionEditor {
+		t.Errorf("expected Editor on the just-in-time vault, got %d", got)
+	}
+	var friends int64
+	db.Model(&models.Vault{}).Where("account_id = ? AND name = ?", accountID, "Friends").Count(&friends)
+	if friends != 0 {
+		t.Error("expected no vault for rules that did not match")
+	}
+
+	// Role changes in the IdP apply on the next login; the strongest grant wins.
+	resp, err = claimLogin(svc, map[string]interface{}{
+		"groups":       "bonds-admins",
+		"realm_access": map[string]interface{}{"roles": []interface{}{"family-manager"}},
+	})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if !resp.User.IsAdmin {
+		t.Error("expected the admin group to grant account administration")
+	}
+	if got := vaultPermission(t, db, accountID, resp.User.ID, "Family"); got != models.PermissionManager {
+		t.Errorf("expected Manager from the nested role claim, got %d", got)
+	}
+
+	resp, err = claimLogin(svc, map[string]interface{}{"groups": []interface{}{"friends"}})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if resp.User.IsAdmin {
+		t.Error("expected leaving the admin group to revoke account administration")
+	}
+	if got := vaultPermission(t, db, accountID, resp.User.ID, "Family"); got != 0 {
+		t.Errorf("expected leaving the family group to remove vault access, got %d", got)
+	}
+	if got := vaultPermission(t, db, accountID, resp.User.ID, "Friends"); got != models.PermissionViewer {
+		t.Errorf("expected Viewer on Friends, got %d", got)
+	}
+	if got := vaultPermission(t, db, accountID, owner.User.ID, "Family"); got != 0 {
+		t.Errorf("expected other users to be unaffected, got %d", got)
+	}
+}
+
+func TestClaimRulesDisableAndReenable(t *testing.T) {
+	db, svc, _ := setupClaimRulesTest(t)
+	first, err := claimLogin(svc, map[string]interface{}{"groups": []interface{}{"family"}})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	if _, err := claimLogin(svc, map[string]interface{}{"groups": []interface{}{"unrelated"}}); !errors.Is(err, ErrUserDisabled) {
+		t.Fatalf("expected removal from all mapped groups to disable the user, got %v", err)
+	}
+	var user models.User
+	db.First(&user, "id = ?", first.User.ID)
+	if !user.Disabled {
+		t.Fatal("expected the user to be disabled")
+	}
+
+	if _, err := claimLogin(svc, map[string]interface{}{"groups": []interface{}{"family"}}); err != nil {
+		t.Fatalf("expected matching again to re-enable the user, got %v", err)
+	}
+
+	// An administrator's decision is not overridden by claims.
+	db.Model(&user).Update("disabled", true)
+	if _, err := claimLogin(svc, map[string]interface{}{"groups": []interface{}{"family"}}); !errors.Is(err, ErrUserDisabled) {
+		t.Errorf("expected a manually disabled user to stay disabled, got %v", err)
+	}
+}
+
+func TestClaimRulesLeaveUnmatchedNewUsersToLinking(t *testing.T) {
+	_, svc, _ := setupClaimRulesTest(t)
+	resp, linkInfo, err := svc.FindOrCreateUser("family-sso", "sub-7", "stranger@example.com", "Stranger", "en", map[string]interface{}{"groups": "unrelated"})
+	if !errors.Is(err, ErrOAuthAccountNotLinked) || resp != nil || linkInfo == nil {
+		t.Errorf("expected the account-binding flow, got %v", err)
+	}
+}
+
+func TestClaimRulesSkipOwnerAndLinkedUsers(t *testing.T) {
+	db, svc, owner := setupClaimRulesTest(t)
+
+	// The owner signs in through the provider with claims matching no rule.
+	if _, _, err := svc.FindOrCreateUser("family-sso", "sub-owner", "claims-owner@example.com", "Owner", "en", map[string]interface{}{"groups": "unrelated"}); err != nil {
+		t.Fatalf("expected the owner to sign in untouched, got %v", err)
+	}
+	var stored models.User
+	db.First(&stored, "id = ?", owner.User.ID)
+	if stored.Disabled || !stored.IsAccountAdministrator {
+		t.Errorf("expected the rules to leave the owner alone, got %+v", stored)
+	}
+}
+
+func TestClaimRulesKeepLastAdministrator(t *testing.T) {
+	db, svc, owner := setupClaimRulesTest(t)
+	resp, err := claimLogin(svc, map[string]interface{}{"groups": []interface{}{"bonds-admins"}})
+	if err != nil || !resp.User.IsAdmin {
+		t.Fatalf("expected an administrator, got %v (%v)", resp, err)
+	}
+	db.Model(&models.User{}).Where("id = ?", owner.User.ID).Update("is_account_administrator", false)
+
+	resp, err = claimLogin(svc, map[string]interface{}{"groups": []interface{}{"family"}})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if !resp.User.IsAdmin {
+		t.Error("expected the last administrator not to be demoted")
+	}
+	if _, err := claimLogin(svc, map[string]interface{}{"groups": []interface{}{"unrelated"}}); err != nil {
+		t.Errorf("expected the last administrator not to be disabled, got %v", err)
+	}
+}
+
+func TestSetClaimRulesValidation(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	providers := NewOAuthProviderService(db)
+	provider, err := providers.Create(dto.CreateOAuthProviderRequest{Type: "github", Name: "github", ClientID: "id", ClientSecret: "secret"})
+	if err != nil {
+		t.Fatalf("Create provider failed: %v", err)
+	}
+	account := models.Account{}
+	db.Create(&account)
+
+	invalid := []dto.OAuthClaimRuleRequest{
+		{Claim: "groups", Value: "family"},
+		{Claim: "groups", Value: "family", VaultName: "Family"},
+		{Claim: "groups", Value: "family", Permission: models.PermissionEditor},
+		{Claim: "groups", Value: "family", VaultName: "Family", Permission: 150},
+		{Value: "family", AccountAdmin: true},
+	}
+	for _, rule := range invalid {
+		_, err := providers.SetClaimRules(provider.ID, dto.UpdateOAuthClaimRulesRequest{AccountID: account.ID, Rules: []dto.OAuthClaimRuleRequest{rule}})
+		if !errors.Is(err, ErrInvalidOAuthClaimRule) {
+			t.Errorf("rule %+v: expected ErrInvalidOAuthClaimRule, got %v", rule, err)
+		}
+	}
+
+	valid := []dto.OAuthClaimRuleRequest{{Claim: "groups", Value: "admins", AccountAdmin: true}}
+	if _, err := providers.SetClaimRules(provider.ID, dto.UpdateOAuthClaimRulesRequest{AccountID: "missing", Rules: valid}); !errors.Is(err, ErrOAuthClaimAccountNotFound) {
+		t.Errorf("expected ErrOAuthClaimAccountNotFound, got %v", err)
+	}
+	if _, err := providers.SetClaimRules(999, dto.UpdateOAuthClaimRulesRequest{}); !errors.Is(err, ErrOAuthProviderNotFoundByID) {
+		t.Errorf("expected ErrOAuthProviderNotFoundByID, got %v", err)
+	}
+
+	if _, err := providers.SetClaimRules(provider.ID, dto.UpdateOAuthClaimRulesRequest{AccountID: account.ID, Rules: valid}); err != nil {
+		t.Fatalf("SetClaimRules failed: %v", err)
+	}
+	got, err := providers.GetClaimRules(provider.ID)
+	if err != nil || got.AccountID != account.ID || len(got.Rules) != 1 {
+		t.Fatalf("unexpected rules %+v (%v)", got, err)
+	}
+
+	if err := providers.Delete(provider.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	var remaining int64
+	db.Model(&models.OAuthClaimRule{}).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("expected deleting the provider to delete its rules, got %d", remaining)
+	}
+}
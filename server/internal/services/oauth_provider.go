@@ -165,7 +165,13 @@ func (s *OAuthProviderService) Delete(id uint) error {
 		return err
 	}
 
-	if err := s.db.Delete(&provider).Error; err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("provider_id = ?", provider.ID).Delete(&models.OAuthClaimRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&provider).Error
+	})
+	if err != nil {
 		return err
 	}
 
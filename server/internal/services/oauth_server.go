@@ -0,0 +1,1109 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	OAuthGrantTypeAuthorizationCode = "authorization_code"
+	OAuthGrantTypeRefreshToken      = "refresh_token"
+	OAuthGrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// OAuthScopeFull grants everything a regular personal access token can do.
+	OAuthScopeFull = "full"
+
+	oauthAuthMethodNone             = "none"
+	oauthAuthMethodBasic            = "client_secret_basic"
+	oauthAuthMethodPost             = "client_secret_post"
+	oauthClientSecretPrefix         = "bondscs_"
+	oauthRefreshTokenPrefix         = "bondsrt_"
+	oauthAccessTokenTTL             = time.Hour
+	oauthRefreshTokenTTL            = 30 * 24 * time.Hour
+	oauthAuthorizationTTL           = 10 * time.Minute
+	oauthCodeTTL                    = time.Minute
+	oauthDeviceTTL                  = 10 * time.Minute
+	oauthDevicePollInterval         = 5 * time.Second
+	oauthUserCodeAlphabet           = "BCDFGHJKLMNPQRSTVWXZ"
+	oauthUserCodeLength             = 8
+	oauthPKCEMinLength              = 43
+	oauthPKCEMaxLength              = 128
+	oauthDynamicRegistrationSetting = "oauth_server.dynamic_registration"
+)
+
+// oauthScopes maps the scopes apps may request onto personal access token
+// scopes, in the order they are advertised. "full" maps to an unscoped token.
+var oauthScopes = []struct {
+	Name     string
+	PATScope string
+}{
+	{OAuthScopeFull, ""},
+	{middleware.ScopeCalendarRead, middleware.ScopeCalendarRead},
+	{middleware.ScopeReportsRead, middleware.ScopeReportsRead},
+}
+
+// Errors of the OAuth protocol endpoints. The handler reports each one with
+// its RFC 6749 error code; the messages double as error descriptions.
+var (
+	ErrOAuthInvalidRequest          = errors.New("the request is missing a parameter or is malformed")
+	ErrOAuthInvalidClient           = errors.New("client authentication failed")
+	ErrOAuthInvalidGrant            = errors.New("the grant is invalid, expired or was issued to another client")
+	ErrOAuthUnauthorizedClient      = errors.New("the client may not use this grant type")
+	ErrOAuthUnsupportedGrantType    = errors.New("the grant type is not supported")
+	ErrOAuthUnsupportedResponseType = errors.New("only the code response type is supported")
+	ErrOAuthInvalidScope            = errors.New("the requested scope is unknown")
+	ErrOAuthInvalidRedirectURI      = errors.New("the redirect URI is not registered for this client")
+	ErrOAuthInvalidClientMetadata   = errors.New("the client metadata is invalid")
+	ErrOAuthRegistrationDisabled    = errors.New("dynamic client registration is disabled")
+	ErrOAuthAuthorizationPending    = errors.New("the user has not approved the device yet")
+	ErrOAuthSlowDown                = errors.New("polling too fast")
+	ErrOAuthAccessDenied            = errors.New("the user denied the request")
+	ErrOAuthExpiredToken            = errors.New("the device code has expired")
+
+	ErrOAuthRequestNotFound    = errors.New("authorization request not found or expired")
+	ErrOAuthDeviceCodeNotFound = errors.New("device code not found or expired")
+	ErrOAuthGrantNotFound      = errors.New("authorized app not found")
+	ErrOAuthClientNotFound     = errors.New("oauth client not found")
+)
+
+// OAuthServerService lets third-party apps, CLIs and MCP clients obtain
+// access tokens for a user through OAuth 2.1. Access tokens are personal
+// access tokens tied to the user's grant, so they pass the existing token
+// checks and scopes unchanged.
+type OAuthServerService struct {
+	db       *gorm.DB
+	issuer   string
+	settings *SystemSettingService
+}
+
+func NewOAuthServerService(db *gorm.DB, appURL string) *OAuthServerService {
+	return &OAuthServerService{db: db, issuer: strings.TrimRight(appURL, "/")}
+}
+
+func (s *OAuthServerService) SetSystemSettings(settings *SystemSettingService) {
+	s.settings = settings
+}
+
+// Issuer is the authorization server's identifier, the public app URL.
+func (s *OAuthServerService) Issuer() string {
+	return s.issuer
+}
+
+// dynamicRegistrationEnabled reports whether apps may register themselves.
+// It is off until an administrator turns it on, since anyone could
+// otherwise register an app named like a trusted one.
+func (s *OAuthServerService) dynamicRegistrationEnabled() bool {
+	if s.settings == nil {
+		return false
+	}
+	return s.settings.GetBool(oauthDynamicRegistrationSetting, false)
+}
+
+// Metadata returns the authorization server metadata (RFC 8414).
+func (s *OAuthServerService) Metadata() dto.OAuthServerMetadata {
+	meta := dto.OAuthServerMetadata{
+		Issuer:                            s.issuer,
+		AuthorizationEndpoint:             s.issuer + "/oauth/authorize",
+		TokenEndpoint:                     s.issuer + "/oauth/token",
+		RevocationEndpoint:                s.issuer + "/oauth/revoke",
+		DeviceAuthorizationEndpoint:       s.issuer + "/oauth/device_authorization",
+		ScopesSupported:                   OAuthScopeNames(),
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{OAuthGrantTypeAuthorizationCode, OAuthGrantTypeRefreshToken, OAuthGrantTypeDeviceCode},
+		TokenEndpointAuthMethodsSupported: []string{oauthAuthMethodNone, oauthAuthMethodBasic, oauthAuthMethodPost},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		AuthorizationResponseIssParameter: true,
+	}
+	if s.dynamicRegistrationEnabled() {
+		meta.RegistrationEndpoint = s.issuer + "/oauth/register"
+	}
+	return meta
+}
+
+// ProtectedResourceMetadata describes a resource protected by this server
+// (RFC 9728), which is how MCP clients discover where to authorize.
+func (s *OAuthServerService) ProtectedResourceMetadata(path string) dto.OAuthProtectedResourceMetadata {
+	return dto.OAuthProtectedResourceMetadata{
+		Resource:               s.issuer + path,
+		AuthorizationServers:   []string{s.issuer},
+		ScopesSupported:        OAuthScopeNames(),
+		BearerMethodsSupported: []string{"header"},
+	}
+}
+
+// OAuthScopeNames lists the scopes apps may request.
+func OAuthScopeNames() []string {
+	names := make([]string, len(oauthScopes))
+	for i, sc := range oauthScopes {
+		names[i] = sc.Name
+	}
+	return names
+}
+
+// RegisterClient registers an app. Clients without a creator registered
+// themselves (RFC 7591) and are refused while dynamic registration is off.
+func (s *OAuthServerService) RegisterClient(req dto.OAuthClientRegistrationRequest, createdByUserID *string) (*dto.OAuthClientRegistrationResponse, error) {
+	if createdByUserID == nil && !s.dynamicRegistrationEnabled() {
+		return nil, ErrOAuthRegistrationDisabled
+	}
+
+	grantTypes := req.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = []string{OAuthGrantTypeAuthorizationCode, OAuthGrantTypeRefreshToken}
+	}
+	seen := map[string]bool{}
+	for _, gt := range grantTypes {
+		switch gt {
+		case OAuthGrantTypeAuthorizationCode, OAuthGrantTypeRefreshToken, OAuthGrantTypeDeviceCode:
+			seen[gt] = true
+		default:
+			return nil, ErrOAuthInvalidClientMetadata
+		}
+	}
+	grantTypes = make([]string, 0, len(seen))
+	for _, gt := range []string{OAuthGrantTypeAuthorizationCode, OAuthGrantTypeRefreshToken, OAuthGrantTypeDeviceCode} {
+		if seen[gt] {
+			grantTypes = append(grantTypes, gt)
+		}
+	}
+	for _, rt := range req.ResponseTypes {
+		if rt != "code" {
+			return nil, ErrOAuthInvalidClientMetadata
+		}
+	}
+
+	redirectURIs := make([]string, 0, len(req.RedirectURIs))
+	for _, raw := range req.RedirectURIs {
+		if err := validateRedirectURI(raw); err != nil {
+			return nil, err
+		}
+		redirectURIs = append(redirectURIs, raw)
+	}
+	if seen[OAuthGrantTypeAuthorizationCode] && len(redirectURIs) == 0 {
+		return nil, ErrOAuthInvalidRedirectURI
+	}
+
+	authMethod := req.TokenEndpointAuthMethod
+	if authMethod == "" {
+		authMethod = oauthAuthMethodBasic
+	}
+	if authMethod != oauthAuthMethodNone && authMethod != oauthAuthMethodBasic && authMethod != oauthAuthMethodPost {
+		return nil, ErrOAuthInvalidClientMetadata
+	}
+
+	name := strings.TrimSpace(req.ClientName)
+	if name == "" && len(redirectURIs) > 0 {
+		if u, err := url.Parse(redirectURIs[0]); err == nil {
+			name = u.Hostname()
+		}
+	}
+	if name == "" {
+		name = "OAuth client"
+	}
+
+	client := models.OAuthClient{
+		ID:              uuid.NewString(),
+		Name:            name,
+		RedirectURIs:    strings.Join(redirectURIs, "\n"),
+		GrantTypes:      strings.Join(grantTypes, ","),
+		CreatedByUserID: createdByUserID,
+	}
+	var secret string
+	if authMethod != oauthAuthMethodNone {
+		var err error
+		if secret, err = randomOAuthToken(oauthClientSecretPrefix); err != nil {
+			return nil, err
+		}
+		client.SecretHash = hashToken(secret)
+	}
+	if err := s.db.Create(&client).Error; err != nil {
+		return nil, err
+	}
+
+	return &dto.OAuthClientRegistrationResponse{
+		ClientID:                client.ID,
+		ClientSecret:            secret,
+		ClientIDIssuedAt:        client.CreatedAt.Unix(),
+		ClientName:              client.Name,
+		RedirectURIs:            redirectURIs,
+		GrantTypes:              grantTypes,
+		ResponseTypes:           []string{"code"},
+		TokenEndpointAuthMethod: authMethod,
+	}, nil
+}
+
+func (s *OAuthServerService) ListClients() ([]dto.OAuthClientResponse, error) {
+	var clients []models.OAuthClient
+	if err := s.db.Order("created_at DESC").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	result := make([]dto.OAuthClientResponse, len(clients))
+	for i, c := range clients {
+		result[i] = dto.OAuthClientResponse{
+			ID:           c.ID,
+			Name:         c.Name,
+			RedirectURIs: splitLines(c.RedirectURIs),
+			GrantTypes:   splitTokenScopes(c.GrantTypes),
+			Confidential: c.SecretHash != "",
+			Dynamic:      c.CreatedByUserID == nil,
+			CreatedAt:    c.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+// DeleteClient removes an app together with everything it was granted.
+func (s *OAuthServerService) DeleteClient(id string) error {
+	var client models.OAuthClient
+	if err := s.db.Where("id = ?", id).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOAuthClientNotFound
+		}
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteOAuthGrants(tx, "client_id = ?", client.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("client_id = ?", client.ID).Delete(&models.OAuthAuthorizationRequest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("client_id = ?", client.ID).Delete(&models.OAuthDeviceAuthorization{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&client).Error
+	})
+}
+
+// StartAuthorization validates an authorization request and stores it until
+// the user decides. Errors come with the result once the redirect URI is
+// known, so they can be sent back to the client instead of shown to the user.
+func (s *OAuthServerService) StartAuthorization(p dto.OAuthAuthorizeParams) (*dto.OAuthAuthorizeResult, error) {
+	var client models.OAuthClient
+	if err := s.db.Where("id = ?", p.ClientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthInvalidClient
+		}
+		return nil, err
+	}
+	redirectURI, err := resolveRedirectURI(&client, p.RedirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.OAuthAuthorizeResult{RedirectURI: redirectURI, State: p.State}
+	if p.ResponseType != "code" {
+		return result, ErrOAuthUnsupportedResponseType
+	}
+	if !clientHasGrantType(&client, OAuthGrantTypeAuthorizationCode) {
+		return result, ErrOAuthUnauthorizedClient
+	}
+	if p.CodeChallengeMethod != "S256" || len(p.CodeChallenge) < oauthPKCEMinLength || len(p.CodeChallenge) > oauthPKCEMaxLength {
+		return result, ErrOAuthInvalidRequest
+	}
+	scopes, err := parseOAuthScopes(p.Scope)
+	if err != nil {
+		return result, err
+	}
+
+	now := time.Now()
+	request := models.OAuthAuthorizationRequest{
+		ID:            uuid.NewString(),
+		ClientID:      client.ID,
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		State:         p.State,
+		CodeChallenge: p.CodeChallenge,
+		ExpiresAt:     now.Add(oauthAuthorizationTTL),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", now).Delete(&models.OAuthAuthorizationRequest{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&request).Error
+	})
+	if err != nil {
+		return result, err
+	}
+	result.RequestID = request.ID
+	return result, nil
+}
+
+// ConsentURL is the page where the user approves or denies a request.
+func (s *OAuthServerService) ConsentURL(requestID string) string {
+	return s.issuer + "/oauth/consent?request_id=" + url.QueryEscape(requestID)
+}
+
+// AuthorizationErrorURL reports an authorization error back to the client,
+// which is only done once its redirect URI has been verified.
+func (s *OAuthServerService) AuthorizationErrorURL(result *dto.OAuthAuthorizeResult, code, description string) string {
+	params := url.Values{"error": {code}, "error_description": {description}, "iss": {s.issuer}}
+	if result.State != "" {
+		params.Set("state", result.State)
+	}
+	return appendQuery(result.RedirectURI, params)
+}
+
+// pendingAuthorization returns a request still waiting for consent.
+func (s *OAuthServerService) pendingAuthorization(requestID string) (*models.OAuthAuthorizationRequest, *models.OAuthClient, error) {
+	var request models.OAuthAuthorizationRequest
+	if err := s.db.Where("id = ? AND code_hash IS NULL AND expires_at > ?", requestID, time.Now()).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrOAuthRequestNotFound
+		}
+		return nil, nil, err
+	}
+	var client models.OAuthClient
+	if err := s.db.Where("id = ?", request.ClientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrOAuthRequestNotFound
+		}
+		return nil, nil, err
+	}
+	return &request, &client, nil
+}
+
+// GetAuthorization describes a pending request for the consent screen.
+// Scope descriptions are left to the caller, which knows the user's locale.
+func (s *OAuthServerService) GetAuthorization(requestID string) (*dto.OAuthConsentResponse, error) {
+	request, client, err := s.pendingAuthorization(requestID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.OAuthConsentResponse{
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		RedirectURI: request.RedirectURI,
+		Scopes:      scopeInfos(request.Scopes),
+	}, nil
+}
+
+// DecideAuthorization records the user's answer and returns the URL to send
+// the browser to: the client's redirect URI with either a one-time code or
+// an access_denied error.
+func (s *OAuthServerService) DecideAuthorization(requestID, userID string, approve bool) (string, error) {
+	request, _, err := s.pendingAuthorization(requestID)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{"iss": {s.issuer}}
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+
+	if !approve {
+		if err := s.db.Delete(request).Error; err != nil {
+			return "", err
+		}
+		params.Set("error", "access_denied")
+		return appendQuery(request.RedirectURI, params), nil
+	}
+
+	code, err := randomOAuthToken("")
+	if err != nil {
+		return "", err
+	}
+	codeHash := hashToken(code)
+	claim := s.db.Model(&models.OAuthAuthorizationRequest{}).
+		Where("id = ? AND code_hash IS NULL", request.ID).
+		Updates(map[string]interface{}{
+			"user_id":    userID,
+			"code_hash":  codeHash,
+			"expires_at": time.Now().Add(oauthCodeTTL),
+		})
+	if claim.Error != nil {
+		return "", claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return "", ErrOAuthRequestNotFound
+	}
+	params.Set("code", code)
+	return appendQuery(request.RedirectURI, params), nil
+}
+
+// StartDeviceAuthorization begins a device authorization grant (RFC 8628).
+func (s *OAuthServerService) StartDeviceAuthorization(req dto.OAuthDeviceAuthorizationRequest) (*dto.OAuthDeviceAuthorizationResponse, error) {
+	client, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !clientHasGrantType(client, OAuthGrantTypeDeviceCode) {
+		return nil, ErrOAuthUnauthorizedClient
+	}
+	scopes, err := parseOAuthScopes(req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceCode, err := randomOAuthToken("")
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := s.db.Where("expires_at < ?", now).Delete(&models.OAuthDeviceAuthorization{}).Error; err != nil {
+		return nil, err
+	}
+	device := models.OAuthDeviceAuthorization{
+		ClientID:       client.ID,
+		DeviceCodeHash: hashToken(deviceCode),
+		Scopes:         scopes,
+		Status:         models.OAuthDeviceStatusPending,
+		ExpiresAt:      now.Add(oauthDeviceTTL),
+	}
+	// User codes are short, so retry the rare collision with a pending one.
+	for attempt := 0; ; attempt++ {
+		if device.UserCode, err = generateUserCode(); err != nil {
+			return nil, err
+		}
+		var count int64
+		if err := s.db.Model(&models.OAuthDeviceAuthorization{}).Where("user_code = ?", device.UserCode).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 || attempt == 4 {
+			break
+		}
+	}
+	if err := s.db.Create(&device).Error; err != nil {
+		return nil, err
+	}
+
+	verificationURI := s.issuer + "/oauth/device"
+	return &dto.OAuthDeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                device.UserCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(device.UserCode),
+		ExpiresIn:               int(oauthDeviceTTL.Seconds()),
+		Interval:                int(oauthDevicePollInterval.Seconds()),
+	}, nil
+}
+
+func (s *OAuthServerService) pendingDevice(userCode string) (*models.OAuthDeviceAuthorization, *models.OAuthClient, error) {
+	var device models.OAuthDeviceAuthorization
+	if err := s.db.Where("user_code = ? AND status = ? AND expires_at > ?", normalizeUserCode(userCode), models.OAuthDeviceStatusPending, time.Now()).
+		First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrOAuthDeviceCodeNotFound
+		}
+		return nil, nil, err
+	}
+	var client models.OAuthClient
+	if err := s.db.Where("id = ?", device.ClientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrOAuthDeviceCodeNotFound
+		}
+		return nil, nil, err
+	}
+	return &device, &client, nil
+}
+
+// GetDeviceAuthorization describes a pending device for the consent screen.
+func (s *OAuthServerService) GetDeviceAuthorization(userCode string) (*dto.OAuthConsentResponse, error) {
+	device, client, err := s.pendingDevice(userCode)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.OAuthConsentResponse{
+		ClientID:   client.ID,
+		ClientName: client.Name,
+		UserCode:   device.UserCode,
+		Scopes:     scopeInfos(device.Scopes),
+	}, nil
+}
+
+// DecideDeviceAuthorization records the user's answer; the device picks it
+// up on its next poll.
+func (s *OAuthServerService) DecideDeviceAuthorization(userCode, userID string, approve bool) error {
+	device, _, err := s.pendingDevice(userCode)
+	if err != nil {
+		return err
+	}
+	status := models.OAuthDeviceStatusDenied
+	if approve {
+		status = models.OAuthDeviceStatusApproved
+	}
+	claim := s.db.Model(&models.OAuthDeviceAuthorization{}).
+		Where("id = ? AND status = ?", device.ID, models.OAuthDeviceStatusPending).
+		Updates(map[string]interface{}{"status": status, "user_id": userID})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return ErrOAuthDeviceCodeNotFound
+	}
+	return nil
+}
+
+// Token serves the token endpoint for all supported grant types.
+func (s *OAuthServerService) Token(req dto.OAuthTokenRequest) (*dto.OAuthTokenResponse, error) {
+	switch req.GrantType {
+	case "":
+		return nil, ErrOAuthInvalidRequest
+	case OAuthGrantTypeAuthorizationCode, OAuthGrantTypeRefreshToken, OAuthGrantTypeDeviceCode:
+	default:
+		return nil, ErrOAuthUnsupportedGrantType
+	}
+	client, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !clientHasGrantType(client, req.GrantType) {
+		return nil, ErrOAuthUnauthorizedClient
+	}
+
+	switch req.GrantType {
+	case OAuthGrantTypeAuthorizationCode:
+		return s.redeemCode(client, req)
+	case OAuthGrantTypeRefreshToken:
+		return s.redeemRefreshToken(client, req.RefreshToken)
+	default:
+		return s.redeemDeviceCode(client, req.DeviceCode)
+	}
+}
+
+func (s *OAuthServerService) redeemCode(client *models.OAuthClient, req dto.OAuthTokenRequest) (*dto.OAuthTokenResponse, error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, ErrOAuthInvalidRequest
+	}
+	var request models.OAuthAuthorizationRequest
+	if err := s.db.Where("code_hash = ?", hashToken(req.Code)).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthInvalidGrant
+		}
+		return nil, err
+	}
+	// Codes are single use: whatever happens next, this one is spent.
+	consumed := s.db.Where("id = ? AND code_hash = ?", request.ID, *request.CodeHash).Delete(&models.OAuthAuthorizationRequest{})
+	if consumed.Error != nil {
+		return nil, consumed.Error
+	}
+	if consumed.RowsAffected == 0 || request.UserID == nil || request.ClientID != client.ID || time.Now().After(request.ExpiresAt) {
+		return nil, ErrOAuthInvalidGrant
+	}
+	if req.RedirectURI != "" && req.RedirectURI != request.RedirectURI {
+		return nil, ErrOAuthInvalidGrant
+	}
+	if !verifyPKCE(req.CodeVerifier, request.CodeChallenge) {
+		return nil, ErrOAuthInvalidGrant
+	}
+	return s.issueTokens(client, *request.UserID, request.Scopes)
+}
+
+func (s *OAuthServerService) redeemRefreshToken(client *models.OAuthClient, rawToken string) (*dto.OAuthTokenResponse, error) {
+	if rawToken == "" {
+		return nil, ErrOAuthInvalidRequest
+	}
+	var stored models.OAuthRefreshToken
+	if err := s.db.Where("token_hash = ?", hashToken(rawToken)).First(&stored).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthInvalidGrant
+		}
+		return nil, err
+	}
+	var grant models.OAuthGrant
+	if err := s.db.Where("id = ?", stored.GrantID).First(&grant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthInvalidGrant
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if grant.ClientID != client.ID || now.After(stored.ExpiresAt) {
+		return nil, ErrOAuthInvalidGrant
+	}
+
+	// As with session refresh tokens, a token redeemed twice was copied, so
+	// the whole grant is revoked.
+	claim := s.db.Model(&models.OAuthRefreshToken{}).
+		Where("id = ? AND used_at IS NULL", stored.ID).
+		Update("used_at", now)
+	if claim.Error != nil {
+		return nil, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			return deleteOAuthGrants(tx, "id = ?", grant.ID)
+		}); err != nil {
+			return nil, err
+		}
+		return nil, ErrOAuthInvalidGrant
+	}
+	return s.issueTokens(client, grant.UserID, grant.Scopes)
+}
+
+func (s *OAuthServerService) redeemDeviceCode(client *models.OAuthClient, rawCode string) (*dto.OAuthTokenResponse, error) {
+	if rawCode == "" {
+		return nil, ErrOAuthInvalidRequest
+	}
+	var device models.OAuthDeviceAuthorization
+	if err := s.db.Where("device_code_hash = ?", hashToken(rawCode)).First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthInvalidGrant
+		}
+		return nil, err
+	}
+	if device.ClientID != client.ID {
+		return nil, ErrOAuthInvalidGrant
+	}
+	now := time.Now()
+	if now.After(device.ExpiresAt) {
+		return nil, ErrOAuthExpiredToken
+	}
+
+	switch device.Status {
+	case models.OAuthDeviceStatusPending:
+		tooFast := device.LastPolledAt != nil && now.Sub(*device.LastPolledAt) < oauthDevicePollInterval
+		if err := s.db.Model(&device).Update("last_polled_at", now).Error; err != nil {
+			return nil, err
+		}
+		if tooFast {
+			return nil, ErrOAuthSlowDown
+		}
+		return nil, ErrOAuthAuthorizationPending
+	case models.OAuthDeviceStatusDenied:
+		if err := s.db.Delete(&device).Error; err != nil {
+			return nil, err
+		}
+		return nil, ErrOAuthAccessDenied
+	}
+
+	consumed := s.db.Where("id = ? AND status = ?", device.ID, models.OAuthDeviceStatusApproved).Delete(&models.OAuthDeviceAuthorization{})
+	if consumed.Error != nil {
+		return nil, consumed.Error
+	}
+	if consumed.RowsAffected == 0 || device.UserID == nil {
+		return nil, ErrOAuthInvalidGrant
+	}
+	return s.issueTokens(client, *device.UserID, device.Scopes)
+}
+
+// issueTokens records the user's grant for the client and issues a fresh
+// access token for the scopes, plus a refresh token if the client may use
+// them. A grant covers every scope the user has approved for the client, so
+// approving a narrower request later does not take earlier scopes away from
+// the client's existing tokens.
+func (s *OAuthServerService) issueTokens(client *models.OAuthClient, userID, scopes string) (*dto.OAuthTokenResponse, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthInvalidGrant
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrOAuthInvalidGrant
+	}
+
+	accessToken, err := generateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	var refreshToken string
+	if clientHasGrantType(client, OAuthGrantTypeRefreshToken) {
+		if refreshToken, err = randomOAuthToken(oauthRefreshTokenPrefix); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(oauthAccessTokenTTL)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		grant := models.OAuthGrant{UserID: user.ID, ClientID: client.ID}
+		if err := tx.Where(&grant).Attrs(models.OAuthGrant{AccountID: user.AccountID, Scopes: scopes}).
+			FirstOrCreate(&grant).Error; err != nil {
+			return err
+		}
+		merged, err := parseOAuthScopes(grant.Scopes + " " + scopes)
+		if err != nil {
+			return err
+		}
+		if merged != grant.Scopes || grant.AccountID != user.AccountID {
+			if err := tx.Model(&grant).Updates(map[string]interface{}{"scopes": merged, "account_id": user.AccountID}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("oauth_grant_id = ? AND expires_at < ?", grant.ID, now).Delete(&models.PersonalAccessToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("grant_id = ? AND expires_at < ?", grant.ID, now).Delete(&models.OAuthRefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.PersonalAccessToken{
+			UserID:       user.ID,
+			AccountID:    user.AccountID,
+			Name:         "OAuth: " + client.Name,
+			Scopes:       patScopesFor(scopes),
+			TokenHash:    hashToken(accessToken),
+			TokenHint:    "..." + accessToken[len(accessToken)-6:],
+			ExpiresAt:    &expiresAt,
+			OAuthGrantID: &grant.ID,
+		}).Error; err != nil {
+			return err
+		}
+		if refreshToken == "" {
+			return nil
+		}
+		return tx.Create(&models.OAuthRefreshToken{
+			GrantID:   grant.ID,
+			TokenHash: hashToken(refreshToken),
+			ExpiresAt: now.Add(oauthRefreshTokenTTL),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.OAuthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oauthAccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        scopes,
+	}, nil
+}
+
+// Revoke revokes an access or refresh token of the client (RFC 7009).
+// Revoking a refresh token revokes the whole grant. Unknown tokens are not
+// an error, so the endpoint reveals nothing about them.
+func (s *OAuthServerService) Revoke(req dto.OAuthRevokeRequest) error {
+	client, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+	if req.Token == "" {
+		return ErrOAuthInvalidRequest
+	}
+	hash := hashToken(req.Token)
+	clientGrants := s.db.Model(&models.OAuthGrant{}).Select("id").Where("client_id = ?", client.ID)
+
+	if strings.HasPrefix(req.Token, oauthRefreshTokenPrefix) {
+		var stored models.OAuthRefreshToken
+		if err := s.db.Where("token_hash = ? AND grant_id IN (?)", hash, clientGrants).First(&stored).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			return deleteOAuthGrants(tx, "id = ?", stored.GrantID)
+		})
+	}
+	return s.db.Where("token_hash = ? AND oauth_grant_id IN (?)", hash, clientGrants).Delete(&models.PersonalAccessToken{}).Error
+}
+
+// ListAuthorizedApps lists the apps the user has granted access to.
+func (s *OAuthServerService) ListAuthorizedApps(userID string) ([]dto.AuthorizedAppResponse, error) {
+	var grants []models.OAuthGrant
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	if len(grants) == 0 {
+		return []dto.AuthorizedAppResponse{}, nil
+	}
+	grantIDs := make([]uint, len(grants))
+	clientIDs := make([]string, len(grants))
+	for i, g := range grants {
+		grantIDs[i] = g.ID
+		clientIDs[i] = g.ClientID
+	}
+	var clients []models.OAuthClient
+	if err := s.db.Where("id IN ?", clientIDs).Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(clients))
+	for _, c := range clients {
+		names[c.ID] = c.Name
+	}
+	var tokens []models.PersonalAccessToken
+	if err := s.db.Select("oauth_grant_id, last_used_at").
+		Where("oauth_grant_id IN ? AND last_used_at IS NOT NULL", grantIDs).Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	lastUsed := map[uint]*time.Time{}
+	for _, t := range tokens {
+		if prev := lastUsed[*t.OAuthGrantID]; prev == nil || t.LastUsedAt.After(*prev) {
+			lastUsed[*t.OAuthGrantID] = t.LastUsedAt
+		}
+	}
+
+	result := make([]dto.AuthorizedAppResponse, len(grants))
+	for i, g := range grants {
+		result[i] = dto.AuthorizedAppResponse{
+			ID:         g.ID,
+			ClientID:   g.ClientID,
+			ClientName: names[g.ClientID],
+			Scopes:     strings.Fields(g.Scopes),
+			LastUsedAt: lastUsed[g.ID],
+			CreatedAt:  g.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+// RevokeAuthorizedApp withdraws the user's grant; the app's tokens stop
+// working immediately.
+func (s *OAuthServerService) RevokeAuthorizedApp(userID string, grantID uint) error {
+	var grant models.OAuthGrant
+	if err := s.db.Where("id = ? AND user_id = ?", grantID, userID).First(&grant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOAuthGrantNotFound
+		}
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return deleteOAuthGrants(tx, "id = ?", grant.ID)
+	})
+}
+
+func (s *OAuthServerService) authenticateClient(clientID, secret string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, ErrOAuthInvalidClient
+	}
+	var client models.OAuthClient
+	if err := s.db.Where("id = ?", clientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthInvalidClient
+		}
+		return nil, err
+	}
+	if client.SecretHash != "" && subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, ErrOAuthInvalidClient
+	}
+	return &client, nil
+}
+
+// deleteOAuthGrants removes the grants matching the condition together with
+// their access and refresh tokens.
+func deleteOAuthGrants(tx *gorm.DB, query string, args ...interface{}) error {
+	grantIDs := tx.Model(&models.OAuthGrant{}).Select("id").Where(query, args...)
+	if err := tx.Where("oauth_grant_id IN (?)", grantIDs).Delete(&models.PersonalAccessToken{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("grant_id IN (?)", grantIDs).Delete(&models.OAuthRefreshToken{}).Error; err != nil {
+		return err
+	}
+	return tx.Where(query, args...).Delete(&models.OAuthGrant{}).Error
+}
+
+func clientHasGrantType(client *models.OAuthClient, grantType string) bool {
+	for _, gt := range splitTokenScopes(client.GrantTypes) {
+		if gt == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOAuthScopes validates a space-separated scope parameter and returns
+// it normalized. No scope means full access, which subsumes the others.
+func parseOAuthScopes(scope string) (string, error) {
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return OAuthScopeFull, nil
+	}
+	seen := map[string]bool{}
+	for _, name := range requested {
+		known := false
+		for _, sc := range oauthScopes {
+			if sc.Name == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "", ErrOAuthInvalidScope
+		}
+		seen[name] = true
+	}
+	if seen[OAuthScopeFull] {
+		return OAuthScopeFull, nil
+	}
+	names := make([]string, 0, len(seen))
+	for _, sc := range oauthScopes {
+		if seen[sc.Name] {
+			names = append(names, sc.Name)
+		}
+	}
+	return strings.Join(names, " "), nil
+}
+
+// patScopesFor converts granted OAuth scopes into personal access token
+// scopes.
+func patScopesFor(scopes string) string {
+	var patScopes []string
+	for _, name := range strings.Fields(scopes) {
+		for _, sc := range oauthScopes {
+			if sc.Name != name {
+				continue
+			}
+			if sc.PATScope == "" {
+				return ""
+			}
+			patScopes = append(patScopes, sc.PATScope)
+		}
+	}
+	sort.Strings(patScopes)
+	return strings.Join(patScopes, ",")
+}
+
+func scopeInfos(scopes string) []dto.OAuthScopeInfo {
+	names := strings.Fields(scopes)
+	infos := make([]dto.OAuthScopeInfo, len(names))
+	for i, name := range names {
+		infos[i] = dto.OAuthScopeInfo{Name: name}
+	}
+	return infos
+}
+
+// validateRedirectURI accepts absolute https URIs, http URIs on loopback
+// hosts for native apps (RFC 8252), and private-use schemes such as
+// com.example.app:/callback. Fragments are never allowed.
+func validateRedirectURI(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Fragment != "" || strings.Contains(raw, "#") {
+		return ErrOAuthInvalidRedirectURI
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		if u.Host == "" {
+			return ErrOAuthInvalidRedirectURI
+		}
+	case "http":
+		if !isLoopbackHost(u.Hostname()) {
+			return ErrOAuthInvalidRedirectURI
+		}
+	case "javascript", "data", "file", "vbscript":
+		return ErrOAuthInvalidRedirectURI
+	default:
+		if !strings.Contains(u.Scheme, ".") {
+			return ErrOAuthInvalidRedirectURI
+		}
+	}
+	return nil
+}
+
+// resolveRedirectURI picks the registered redirect URI a request refers to.
+// Loopback URIs match on any port, since native apps bind whatever port is
+// free when they start.
+func resolveRedirectURI(client *models.OAuthClient, requested string) (string, error) {
+	registered := splitLines(client.RedirectURIs)
+	if requested == "" {
+		if len(registered) == 1 {
+			return registered[0], nil
+		}
+		return "", ErrOAuthInvalidRedirectURI
+	}
+	for _, r := range registered {
+		if r == requested || sameLoopbackURI(r, requested) {
+			return requested, nil
+		}
+	}
+	return "", ErrOAuthInvalidRedirectURI
+}
+
+func sameLoopbackURI(registered, requested string) bool {
+	a, err := url.Parse(registered)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(requested)
+	if err != nil {
+		return false
+	}
+	return a.Scheme == "http" && b.Scheme == "http" &&
+		isLoopbackHost(a.Hostname()) && a.Hostname() == b.Hostname() &&
+		a.Path == b.Path && a.RawQuery == b.RawQuery && b.Fragment == ""
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func verifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < oauthPKCEMinLength || len(verifier) > oauthPKCEMaxLength {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+func appendQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func randomOAuthToken(prefix string) (string, error) {
+	b := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// generateUserCode returns a code like "BCDF-GHJK". Its alphabet has no
+// vowels or look-alike characters, so codes are easy to type and never
+// spell words.
+func generateUserCode() (string, error) {
+	b := make([]byte, oauthUserCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := make([]byte, oauthUserCodeLength)
+	for i := range b {
+		code[i] = oauthUserCodeAlphabet[int(b[i])%len(oauthUserCodeAlphabet)]
+	}
+	return string(code[:4]) + "-" + string(code[4:]), nil
+}
+
+// normalizeUserCode accepts codes typed in lower case or without the dash.
+func normalizeUserCode(code string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteRune(r)
+		}
+	}
+	s := b.String()
+	if len(s) != oauthUserCodeLength {
+		return s
+	}
+	return s[:4] + "-" + s[4:]
+}
+
+func splitLines(s string) []string {
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
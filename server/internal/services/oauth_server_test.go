@@ -0,0 +1,480 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+const oauthTestVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk-verifier"
+
+func oauthTestChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func setupOAuthServerTest(t *testing.T) (*gorm.DB, *OAuthServerService, *dto.AuthResponse) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	user, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Test",
+		LastName:  "User",
+		Email:     "oauth@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	settings := NewSystemSettingService(db)
+	if err := settings.Set("oauth_server.dynamic_registration", "true"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	svc := NewOAuthServerService(db, "https://bonds.example.com/")
+	svc.SetSystemSettings(settings)
+	return db, svc, user
+}
+
+func registerOAuthTestClient(t *testing.T, svc *OAuthServerService, req dto.OAuthClientRegistrationRequest) *dto.OAuthClientRegistrationResponse {
+	t.Helper()
+	client, err := svc.RegisterClient(req, nil)
+	if err != nil {
+		t.Fatalf("RegisterClient failed: %v", err)
+	}
+	return client
+}
+
+// authorizeOAuthTestClient runs the browser part of the code flow and
+// returns the code from the redirect.
+func authorizeOAuthTestClient(t *testing.T, svc *OAuthServerService, clientID, userID, scope string) string {
+	t.Helper()
+	result, err := svc.StartAuthorization(dto.OAuthAuthorizeParams{
+		ResponseType:        "code",
+		ClientID:            clientID,
+		RedirectURI:         "http://127.0.0.1:43123/callback",
+		Scope:               scope,
+		State:               "xyz",
+		CodeChallenge:       oauthTestChallenge(oauthTestVerifier),
+		CodeChallengeMethod: "S256",
+	})
+	if err != nil {
+		t.Fatalf("StartAuthorization failed: %v", err)
+	}
+	redirect, err := svc.DecideAuthorization(result.RequestID, userID, true)
+	if err != nil {
+		t.Fatalf("DecideAuthorization failed: %v", err)
+	}
+	u, err := url.Parse(redirect)
+	if err != nil {
+		t.Fatalf("invalid redirect %q: %v", redirect, err)
+	}
+	if u.Host != "127.0.0.1:43123" || u.Query().Get("state") != "xyz" || u.Query().Get("iss") != "https://bonds.example.com" {
+		t.Fatalf("unexpected redirect %q", redirect)
+	}
+	return u.Query().Get("code")
+}
+
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	db, svc, user := setupOAuthServerTest(t)
+	client := registerOAuthTestClient(t, svc, dto.OAuthClientRegistrationRequest{
+		ClientName:              "My CLI",
+		RedirectURIs:            []string{"http://127.0.0.1/callback"},
+		TokenEndpointAuthMethod: "none",
+	})
+	if client.ClientSecret != "" {
+		t.Fatal("expected a public client to get no secret")
+	}
+
+	consent, err := svc.StartAuthorization(dto.OAuthAuthorizeParams{
+		ResponseType: "code", ClientID: client.ClientID, Scope: "calendar:read",
+		CodeChallenge: oauthTestChallenge(oauthTestVerifier), CodeChallengeMethod: "S256",
+	})
+	if err != nil {
+		t.Fatalf("StartAuthorization failed: %v", err)
+	}
+	info, err := svc.GetAuthorization(consent.RequestID)
+	if err != nil || info.ClientName != "My CLI" || len(info.Scopes) != 1 || info.Scopes[0].Name != "calendar:read" {
+		t.Fatalf("unexpected consent info %+v (%v)", info, err)
+	}
+
+	code := authorizeOAuthTestClient(t, svc, client.ClientID, user.User.ID, "calendar:read")
+	if _, err := svc.Token(dto.OAuthTokenRequest{GrantType: OAuthGrantTypeAuthorizationCode, ClientID: client.ClientID, Code: code, CodeVerifier: "wrong-verifier-wrong-verifier-wrong-verifier"}); !errors.Is(err, ErrOAuthInvalidGrant) {
+		t.Fatalf("expected a wrong verifier to be rejected, got %v", err)
+	}
+	// A failed redemption spends the code as well.
+	if _, err := svc.Token(dto.OAuthTokenRequest{GrantType: OAuthGrantTypeAuthorizationCode, ClientID: client.ClientID, Code: code, CodeVerifier: oauthTestVerifier}); !errors.Is(err, ErrOAuthInvalidGrant) {
+		t.Fatalf("expected the code to be single use, got %v", err)
+	}
+
+	code = authorizeOAuthTestClient(t, svc, client.ClientID, user.User.ID, "calendar:read")
+	tokens, err := svc.Token(dto.OAuthTokenRequest{GrantType: OAuthGrantTypeAuthorizationCode, ClientID: client.ClientID, Code: code, CodeVerifier: oauthTestVerifier, RedirectURI: "http://127.0.0.1:43123/callback"})
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	if !strings.HasPrefix(tokens.AccessToken, "bonds_") || !strings.HasPrefix(tokens.RefreshToken, oauthRefreshTokenPrefix) || tokens.Scope != "calendar:read" || tokens.ExpiresIn != 3600 {
+		t.Fatalf("unexpected token response %+v", tokens)
+	}
+
+	pat, err := NewPersonalAccessTokenService(db).ValidateToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("expected the access token to be a valid PAT: %v", err)
+	}
+	if pat.Scopes != middleware.ScopeCalendarRead || pat.OAuthGrantID == nil || pat.ExpiresAt == nil {
+		t.Errorf("unexpected access token %+v", pat)
+	}
+	pats, _ := NewPersonalAccessTokenService(db).List(user.User.ID)
+	if len(pats) != 0 {
+		t.Errorf("expected OAuth access tokens to be hidden from the token list, got %d", len(pats))
+	}
+
+	apps, err := svc.ListAuthorizedApps(user.User.ID)
+	if err != nil || len(apps) != 1 || apps[0].ClientName != "My CLI" || apps[0].LastUsedAt == nil {
+		t.Fatalf("unexpected authorized apps %+v (%v)", apps, err)
+	}
+	if err := svc.RevokeAuthorizedApp(user.User.ID, apps[0].ID); err != nil {
+		t.Fatalf("RevokeAuthorizedApp failed: %v", err)
+	}
+	if _, err := NewPersonalAccessTokenService(db).ValidateToken(tokens.AccessToken); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("expected revoking the app to revoke its access token, got %v", err)
+	}
+	if _, err := svc.Token(dto.OAuthTokenRequest{GrantType: OAuthGrantTypeRefreshToken, ClientID: client.ClientID, RefreshToken: tokens.RefreshToken}); !errors.Is(err, ErrOAuthInvalidGrant) {
+		t.Errorf("expected revoking the app to revoke its refresh token, got %v", err)
+	}
+}
+
+func TestOAuthAuthorizationErrors(t *testing.T) {
+	_, svc, user := setupOAuthServerTest(t)
+	client := registerOAuthTestClient(t, svc, dto.OAuthClientRegistrationRequest{
+		ClientName:              "My CLI",
+		RedirectURIs:            []string{"https://app.example.com/callback", "http://localhost/callback"},
+		TokenEndpointAuthMethod: "none",
+	})
+	valid := dto.OAuthAuthorizeParams{
+		ResponseType: "code", ClientID: client.ClientID, RedirectURI: "https://app.example.com/callback",
+		CodeChallenge: oauthTestChallenge(oauthTestVerifier), CodeChallengeMethod: "S256",
+	}
+
+	cases := []struct {
+		name     string
+		modify   func(p *dto.OAuthAuthorizeParams)
+		err      error
+		redirect bool
+	}{
+		{"unknown client", func(p *dto.OAuthAuthorizeParams) { p.ClientID = "nope" }, ErrOAuthInvalidClient, false},
+		{"unregistered redirect", func(p *dto.OAuthAuthorizeParams) { p.RedirectURI = "https://evil.example.com/callback" }, ErrOAuthInvalidRedirectURI, false},
+		{"ambiguous redirect", func(p *dto.OAuthAuthorizeParams) { p.RedirectURI = "" }, ErrOAuthInvalidRedirectURI, false},
+		{"missing PKCE", func(p *dto.OAuthAuthorizeParams) { p.CodeChallenge = "" }, ErrOAuthInvalidRequest, true},
+		{"plain PKCE", func(p *dto.OAuthAuthorizeParams) { p.CodeChallengeMethod = "plain" }, ErrOAuthInvalidRequest, true},
+		{"token response", func(p *dto.OAuthAuthorizeParams) { p.ResponseType = "token" }, ErrOAuthUnsupportedResponseType, true},
+		{"unknown scope", func(p *dto.OAuthAuthorizeParams) { p.Scope = "contacts:write" }, ErrOAuthInvalidScope, true},
+	}
+	for _, tc := range cases {
+		params := valid
+		tc.modify(&params)
+		result, err := svc.StartAuthorization(params)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.err, err)
+		}
+		if (result != nil) != tc.redirect {
+			t.Errorf("%s: expected redirect=%v, got %+v", tc.name, tc.redirect, result)
+		}
+	}
+
+	// Loopback redirect URIs match on any port.
+	params := valid
+	params.RedirectURI = "http://localhost:51234/callback"
+	if _, err := svc.StartAuthorization(params); err != nil {
+		t.Errorf("expected a loopback redirect on another port to be accepted, got %v", err)
+	}
+
+	result, err := svc.StartAuthorization(valid)
+	if err != nil {
+		t.Fatalf("StartAuthorization failed: %v", err)
+	}
+	redirect, err := svc.DecideAuthorization(result.RequestID, user.User.ID, false)
+	if err != nil || !strings.Contains(redirect, "error=access_denied") {
+		t.Errorf("expected a denial to redirect with access_denied, got %q (%v)", redirect, err)
+	}
+	if _, err := svc.GetAuthorization(result.RequestID); !errors.Is(err, ErrOAuthRequestNotFound) {
+		t.Errorf("expected a decided request to be gone, got %v", err)
+	}
+}
+
+func TestOAuthRefreshTokenRotationAndReplay(t *testing.T) {
+	db, svc, user := setupOAuthServerTest(t)
+	client := registerOAuthTestClient(t, svc, dto.OAuthClientRegistrationRequest{
+		ClientName:   "Confidential App",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+	})
+	if client.ClientSecret == "" || client.TokenEndpointAuthMethod != "client_secret_basic" {
+		t.Fatalf("expected a confidential client by default, got %+v", client)
+	}
+
+	result, err := svc.StartAuthorization(dto.OAuthAuthorizeParams{
+		ResponseType: "code", ClientID: client.ClientID,
+		CodeChallenge: oauthTestChallenge(oauthTestVerifier), CodeChallengeMethod: "S256",
+	})
+	if err != nil {
+		t.Fatalf("StartAuthorization failed: %v", err)
+	}
+	redirect, err := svc.DecideAuthorization(result.RequestID, user.User.ID, true)
+	if err != nil {
+		t.Fatalf("DecideAuthorization failed: %v", err)
+	}
+	u, _ := url.Parse(redirect)
+	codeReq := dto.OAuthTokenRequest{GrantType: OAuthGrantTypeAuthorizationCode, ClientID: client.ClientID, Code: u.Query().Get("code"), CodeVerifier: oauthTestVerifier}
+	if _, err := svc.Token(codeReq); !errors.Is(err, ErrOAuthInvalidClient) {
+		t.Fatalf("expected a confidential client without its secret to be rejected, got %v", err)
+	}
+	codeReq.ClientSecret = client.ClientSecret
+	first, err := svc.Token(codeReq)
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	if first.Scope != OAuthScopeFull {
+		t.Errorf("expected no scope to mean full access, got %q", first.Scope)
+	}
+
+	refresh := dto.OAuthTokenRequest{GrantType: OAuthGrantTypeRefreshToken, ClientID: client.ClientID, ClientSecret: client.ClientSecret, RefreshToken: first.RefreshToken}
+	second, err := svc.Token(refresh)
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Fatal("expected refreshing to rotate both tokens")
+	}
+
+	// Redeeming the first refresh token again is a replay and revokes the
+	// grant, including the tokens just issued.
+	if _, err := svc.Token(refresh); !errors.Is(err, ErrOAuthInvalidGrant) {
+		t.Fatalf("expected a replayed refresh token to be rejected, got %v", err)
+	}
+	var grants int64
+	db.Model(&models.OAuthGrant{}).Count(&grants)
+	if grants != 0 {
+		t.Errorf("expected the replay to revoke the grant, %d left", grants)
+	}
+	refresh.RefreshToken = second.RefreshToken
+	if _, err := svc.Token(refresh); !errors.Is(err, ErrOAuthInvalidGrant) {
+		t.Errorf("expected the successor token to be revoked too, got %v", err)
+	}
+}
+
+func TestOAuthDeviceFlow(t *testing.T) {
+	db, svc, user := setupOAuthServerTest(t)
+	client := registerOAuthTestClient(t, svc, dto.OAuthClientRegistrationRequest{
+		ClientName:              "Terminal",
+		GrantTypes:              []string{OAuthGrantTypeDeviceCode, OAuthGrantTypeRefreshToken},
+		TokenEndpointAuthMethod: "none",
+	})
+
+	start, err := svc.StartDeviceAuthorization(dto.OAuthDeviceAuthorizationRequest{ClientID: client.ClientID})
+	if err != nil {
+		t.Fatalf("StartDeviceAuthorization failed: %v", err)
+	}
+	if len(start.UserCode) != 9 || start.UserCode[4] != '-' || start.VerificationURI != "https://bonds.example.com/oauth/device" || start.Interval != 5 {
+		t.Fatalf("unexpected device authorization %+v", start)
+	}
+
+	poll := dto.OAuthTokenRequest{GrantType: OAuthGrantTypeDeviceCode, ClientID: client.ClientID, DeviceCode: start.DeviceCode}
+	if _, err := svc.Token(poll); !errors.Is(err, ErrOAuthAuthorizationPending) {
+		t.Fatalf("expected authorization_pending, got %v", err)
+	}
+	if _, err := svc.Token(poll); !errors.Is(err, ErrOAuthSlowDown) {
+		t.Fatalf("expected slow_down when polling too fast, got %v", err)
+	}
+
+	// Codes are accepted in lower case and without the dash.
+	typed := strings.ToLower(strings.ReplaceAll(start.UserCode, "-", ""))
+	info, err := svc.GetDeviceAuthorization(typed)
+	if err != nil || info.ClientName != "Terminal" || info.UserCode != start.UserCode {
+		t.Fatalf("unexpected device consent info %+v (%v)", info, err)
+	}
+	if err := svc.DecideDeviceAuthorization(typed, user.User.ID, true); err != nil {
+		t.Fatalf("DecideDeviceAuthorization failed: %v", err)
+	}
+	if err := svc.DecideDeviceAuthorization(typed, user.User.ID, false); !errors.Is(err, ErrOAuthDeviceCodeNotFound) {
+		t.Errorf("expected a decided code to be gone, got %v", err)
+	}
+
+	tokens, err := svc.Token(poll)
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Errorf("unexpected token response %+v", tokens)
+	}
+	if _, err := svc.Token(poll); !errors.Is(err, ErrOAuthInvalidGrant) {
+		t.Errorf("expected the device code to be single use, got %v", err)
+	}
+
+	denied, err := svc.StartDeviceAuthorization(dto.OAuthDeviceAuthorizationRequest{ClientID: client.ClientID})
+	if err != nil {
+		t.Fatalf("StartDeviceAuthorization failed: %v", err)
+	}
+	if err := svc.DecideDeviceAuthorization(denied.UserCode, user.User.ID, false); err != nil {
+		t.Fatalf("DecideDeviceAuthorization failed: %v", err)
+	}
+	if _, err := svc.Token(dto.OAuthTokenRequest{GrantType: OAuthGrantTypeDeviceCode, ClientID: client.ClientID, DeviceCode: denied.DeviceCode}); !errors.Is(err, ErrOAuthAccessDenied) {
+		t.Errorf("expected access_denied, got %v", err)
+	}
+
+	expired, err := svc.StartDeviceAuthorization(dto.OAuthDeviceAuthorizationRequest{ClientID: client.ClientID})
+	if err != nil {
+		t.Fatalf("StartDeviceAuthorization failed: %v", err)
+	}
+	db.Model(&models.OAuthDeviceAuthorization{}).Where("user_code = ?", expired.UserCode).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := svc.Token(dto.OAuthTokenRequest{GrantType: OAuthGrantTypeDeviceCode, ClientID: client.ClientID, DeviceCode: expired.DeviceCode}); !errors.Is(err, ErrOAuthExpiredToken) {
+		t.Errorf("expected expired_token, got %v", err)
+	}
+
+	codeClient := registerOAuthTestClient(t, svc, dto.OAuthClientRegistrationRequest{RedirectURIs: []string{"http://127.0.0.1/cb"}, TokenEndpointAuthMethod: "none"})
+	if _, err := svc.StartDeviceAuthorization(dto.OAuthDeviceAuthorizationRequest{ClientID: codeClient.ClientID}); !errors.Is(err, ErrOAuthUnauthorizedClient) {
+		t.Errorf("expected clients without the device grant to be refused, got %v", err)
+	}
+}
+
+func TestOAuthClientRegistration(t *testing.T) {
+	_, svc, user := setupOAuthServerTest(t)
+
+	invalid := []dto.OAuthClientRegistrationRequest{
+		{RedirectURIs: []string{"http://app.example.com/callback"}},
+		{RedirectURIs: []string{"javascript:alert(1)"}},
+		{RedirectURIs: []string{"https://app.example.com/callback#frag"}},
+		{RedirectURIs: []string{"/relative"}},
+		{},
+	}
+	for _, req := range invalid {
+		if _, err := svc.RegisterClient(req, nil); !errors.Is(err, ErrOAuthInvalidRedirectURI) {
+			t.Errorf("expected %v to be rejected, got %v", req.RedirectURIs, err)
+		}
+	}
+	if _, err := svc.RegisterClient(dto.OAuthClientRegistrationRequest{RedirectURIs: []string{"https://a.example.com/cb"}, GrantTypes: []string{"implicit"}}, nil); !errors.Is(err, ErrOAuthInvalidClientMetadata) {
+		t.Errorf("expected the implicit grant to be rejected, got %v", err)
+	}
+
+	native, err := svc.RegisterClient(dto.OAuthClientRegistrationRequest{RedirectURIs: []string{"com.example.app:/oauth"}, TokenEndpointAuthMethod: "none"}, nil)
+	if err != nil {
+		t.Fatalf("expected a private-use scheme to be accepted: %v", err)
+	}
+	if native.ClientName == "" {
+		t.Error("expected a default client name")
+	}
+
+	if err := svc.settings.Set("oauth_server.dynamic_registration", "false"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, err := svc.RegisterClient(dto.OAuthClientRegistrationRequest{RedirectURIs: []string{"https://a.example.com/cb"}}, nil); !errors.Is(err, ErrOAuthRegistrationDisabled) {
+		t.Errorf("expected dynamic registration to be refused, got %v", err)
+	}
+	if svc.Metadata().RegistrationEndpoint != "" {
+		t.Error("expected the registration endpoint to be hidden from metadata")
+	}
+	admin, err := svc.RegisterClient(dto.OAuthClientRegistrationRequest{ClientName: "Admin App", RedirectURIs: []string{"https://a.example.com/cb"}}, &user.User.ID)
+	if err != nil {
+		t.Fatalf("expected administrators to register clients regardless: %v", err)
+	}
+
+	clients, err := svc.ListClients()
+	if err != nil || len(clients) != 2 {
+		t.Fatalf("expected 2 clients, got %d (%v)", len(clients), err)
+	}
+	if err := svc.DeleteClient(admin.ClientID); err != nil {
+		t.Fatalf("DeleteClient failed: %v", err)
+	}
+	if err := svc.DeleteClient(admin.ClientID); !errors.Is(err, ErrOAuthClientNotFound) {
+		t.Errorf("expected ErrOAuthClientNotFound, got %v", err)
+	}
+}
+
+func TestOAuthRevoke(t *testing.T) {
+	db, svc, user := setupOAuthServerTest(t)
+	client := registerOAuthTestClient(t, svc, dto.OAuthClientRegistrationRequest{
+		RedirectURIs:            []string{"http://127.0.0.1/callback"},
+		TokenEndpointAuthMethod: "none",
+	})
+	other := registerOAuthTestClient(t, svc, dto.OAuthClientRegistrationRequest{
+		RedirectURIs:            []string{"http://127.0.0.1/callback"},
+		TokenEndpointAuthMethod: "none",
+	})
+	issue := func() *dto.OAuthTokenResponse {
+		code := authorizeOAuthTestClient(t, svc, client.ClientID, user.User.ID, "")
+		tokens, err := svc.Token(dto.OAuthTokenRequest{GrantType: OAuthGrantTypeAuthorizationCode, ClientID: client.ClientID, Code: code, CodeVerifier: oauthTestVerifier})
+		if err != nil {
+			t.Fatalf("Token failed: %v", err)
+		}
+		return tokens
+	}
+	pats := NewPersonalAccessTokenService(db)
+
+	tokens := issue()
+	if err := svc.Revoke(dto.OAuthRevokeRequest{ClientID: other.ClientID, Token: tokens.AccessToken}); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := pats.ValidateToken(tokens.AccessToken); err != nil {
+		t.Errorf("expected another client to be unable to revoke the token, got %v", err)
+	}
+	if err := svc.Revoke(dto.OAuthRevokeRequest{ClientID: client.ClientID, Token: tokens.AccessToken}); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := pats.ValidateToken(tokens.AccessToken); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("expected the access token to be revoked, got %v", err)
+	}
+
+	tokens = issue()
+	if err := svc.Revoke(dto.OAuthRevokeRequest{ClientID: client.ClientID, Token: tokens.RefreshToken}); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := pats.ValidateToken(tokens.AccessToken); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("expected revoking the refresh token to revoke the grant's access tokens, got %v", err)
+	}
+	if err := svc.Revoke(dto.OAuthRevokeRequest{ClientID: client.ClientID, Token: "bonds_unknown"}); err != nil {
+		t.Errorf("expected unknown tokens to be ignored, got %v", err)
+	}
+	if err := svc.Revoke(dto.OAuthRevokeRequest{ClientID: "nope", Token: tokens.AccessToken}); !errors.Is(err, ErrOAuthInvalidClient) {
+		t.Errorf("expected ErrOAuthInvalidClient, got %v", err)
+	}
+}
+
+func TestOAuthDynamicRegistrationOffByDefault(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	svc := NewOAuthServerService(db, "https://bonds.example.com")
+	if _, err := svc.RegisterClient(dto.OAuthClientRegistrationRequest{RedirectURIs: []string{"https://a.example.com/cb"}}, nil); !errors.Is(err, ErrOAuthRegistrationDisabled) {
+		t.Errorf("expected dynamic registration to be off without settings, got %v", err)
+	}
+	svc.SetSystemSettings(NewSystemSettingService(db))
+	if _, err := svc.RegisterClient(dto.OAuthClientRegistrationRequest{RedirectURIs: []string{"https://a.example.com/cb"}}, nil); !errors.Is(err, ErrOAuthRegistrationDisabled) {
+		t.Errorf("expected dynamic registration to be off until enabled, got %v", err)
+	}
+}
+
+func TestOAuthGrantMergesScopes(t *testing.T) {
+	db, svc, user := setupOAuthServerTest(t)
+	client := registerOAuthTestClient(t, svc, dto.OAuthClientRegistrationRequest{
+		RedirectURIs:            []string{"http://127.0.0.1/callback"},
+		TokenEndpointAuthMethod: "none",
+	})
+
+	for _, scope := range []string{"calendar:read", "reports:read"} {
+		code := authorizeOAuthTestClient(t, svc, client.ClientID, user.User.ID, scope)
+		if _, err := svc.Token(dto.OAuthTokenRequest{GrantType: OAuthGrantTypeAuthorizationCode, ClientID: client.ClientID, Code: code, CodeVerifier: oauthTestVerifier}); err != nil {
+			t.Fatalf("Token failed: %v", err)
+		}
+	}
+	var grant models.OAuthGrant
+	if err := db.Where("user_id = ? AND client_id = ?", user.User.ID, client.ClientID).First(&grant).Error; err != nil {
+		t.Fatalf("load grant: %v", err)
+	}
+	if grant.Scopes != "calendar:read reports:read" {
+		t.Errorf("expected the grant to cover both approvals, got %q", grant.Scopes)
+	}
+}
@@ -22,6 +22,7 @@ const (
 
 var validScopes = map[string]bool{
 	middleware.ScopeCalendarRead: true,
+	middleware.ScopeReportsRead:  true,
 }
 
 var (
@@ -77,7 +78,7 @@ func NewPersonalAccessTokenService(db *gorm.DB) *PersonalAccessTokenService {
 func (s *PersonalAccessTokenService) Create(userID, accountID string, req dto.CreatePersonalAccessTokenRequest) (*dto.PersonalAccessTokenCreatedResponse, error) {
 	// Check duplicate name for same user
 	var count int64
-	s.db.Model(&models.PersonalAccessToken{}).Where("user_id = ? AND name = ?", userID, req.Name).Count(&count)
+	s.db.Model(&models.PersonalAccessToken{}).Where("user_id = ? AND name = ? AND oauth_grant_id IS NULL", userID, req.Name).Count(&count)
 	if count > 0 {
 		return nil, ErrTokenNameDuplicate
 	}
@@ -126,7 +127,7 @@ func (s *PersonalAccessTokenService) Create(userID, accountID string, req dto.Cr
 
 func (s *PersonalAccessTokenService) List(userID string) ([]dto.PersonalAccessTokenResponse, error) {
 	var tokens []models.PersonalAccessToken
-	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+	if err := s.db.Where("user_id = ? AND oauth_grant_id IS NULL", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
 		return nil, err
 	}
 	result := make([]dto.PersonalAccessTokenResponse, len(tokens))
@@ -138,7 +139,7 @@ func (s *PersonalAccessTokenService) List(userID string) ([]dto.PersonalAccessTo
 
 func (s *PersonalAccessTokenService) Delete(id uint, userID string) error {
 	var token models.PersonalAccessToken
-	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&token).Error; err != nil {
+	if err := s.db.Where("id = ? AND user_id = ? AND oauth_grant_id IS NULL", id, userID).First(&token).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return ErrTokenNotFound
 		}
@@ -47,6 +47,9 @@ func advanceContactsLastTalkedTo(tx *gorm.DB, update postContactUpdate) error {
 			}).Error; err != nil {
 			return err
 		}
+		if err := recordVaultChange(tx, update.vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeUpdated); err != nil {
+			return err
+		}
 	}
 	return nil
 }
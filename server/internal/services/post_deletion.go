@@ -34,7 +34,10 @@ func (s *PostService) Delete(id uint, journalID uint, vaultID string) error {
 			return err
 		}
 		files = postFiles
-		return tx.Delete(post).Error
+		if err := tx.Delete(post).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangePost, post.ID, models.VaultChangeDeleted)
 	}); err != nil {
 		return err
 	}
@@ -82,6 +85,11 @@ func deletePostDependents(tx *gorm.DB, vaultID string, postIDs []uint) ([]models
 	if err := tx.Where("vault_id = ? AND fileable_type = ? AND fileable_id IN ?", vaultID, postFileType, postIDs).Delete(&models.File{}).Error; err != nil {
 		return nil, err
 	}
+	for index := range files {
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeFile, files[index].ID, models.VaultChangeDeleted); err != nil {
+			return nil, err
+		}
+	}
 	return files, nil
 }
 
@@ -56,9 +56,14 @@ func (s *VaultFileService) UploadPostPhoto(postID uint, vaultID string, filename
 		FileableType: &fileableType,
 		FileableID:   &postID,
 	}
-	if err := s.db.Create(&file).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&file).Error; err != nil {
+			return fmt.Errorf("failed to save file record: %w", err)
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeFile, file.ID, models.VaultChangeCreated)
+	}); err != nil {
 		os.Remove(destPath)
-		return nil, fmt.Errorf("failed to save file record: %w", err)
+		return nil, err
 	}
 
 	resp := toVaultFileResponse(&file)
@@ -76,8 +81,16 @@ func (s *VaultFileService) DeletePostPhoto(fileID, postID uint, vaultID string)
 		return err
 	}
 
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&file).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeFile, file.ID, models.VaultChangeDeleted)
+	}); err != nil {
+		return err
+	}
+
 	destPath := filepath.Join(s.uploadDir, file.UUID)
 	os.Remove(destPath)
-
-	return s.db.Delete(&file).Error
+	return nil
 }
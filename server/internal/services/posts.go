@@ -62,6 +62,9 @@ func (s *PostService) Create(journalID uint, vaultID string, req dto.CreatePostR
 		if err := tx.Create(&post).Error; err != nil {
 			return err
 		}
+		if err := recordVaultChange(tx, vaultID, models.VaultChangePost, post.ID, models.VaultChangeCreated); err != nil {
+			return err
+		}
 		for _, sec := range req.Sections {
 			section := models.PostSection{
 				PostID:   post.ID,
@@ -183,6 +186,9 @@ func (s *PostService) Update(id uint, journalID uint, vaultID string, req dto.Up
 			if err := tx.Save(post).Error; err != nil {
 				return err
 			}
+			if err := recordVaultChange(tx, vaultID, models.VaultChangePost, post.ID, models.VaultChangeUpdated); err != nil {
+				return err
+			}
 			if req.Sections != nil {
 				if err := tx.Where("post_id = ?", id).Delete(&models.PostSection{}).Error; err != nil {
 					return err
@@ -9,6 +9,7 @@ import (
 	"github.com/naiba/bonds/internal/dto"
 	"github.com/naiba/bonds/internal/i18n"
 	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/phone"
 	"gorm.io/gorm"
 )
 
@@ -23,6 +24,10 @@ var (
 	// back to English), making the saved value look like a no-op to the user.
 	ErrUnsupportedLocale = errors.New("locale is not supported")
 
+	// ErrInvalidPhoneRegion is returned for a phone_region that is not a
+	// region code the phone normalizer knows.
+	ErrInvalidPhoneRegion = errors.New("phone_region is not a supported region code")
+
 	// validNameOrderVars lists all allowed template variables for name_order.
 	validNameOrderVars = map[string]bool{
 		"first_name":  true,
@@ -63,6 +68,7 @@ func (s *PreferenceService) Get(userID string) (*dto.PreferencesResponse, error)
 		WeekStart:                 normalizedWeekStart(user.WeekStart),
 		Timezone:                  tz,
 		Locale:                    user.Locale,
+		PhoneRegion:               user.PhoneRegion,
 		NumberFormat:              user.NumberFormat,
 		DistanceFormat:            user.DistanceFormat,
 		DefaultMapSite:            user.DefaultMapSite,
@@ -96,7 +102,7 @@ func (s *PreferenceService) UpdateLocale(userID string, req dto.UpdateLocaleRequ
 	if !i18n.IsSupported(req.Locale) {
 		return ErrUnsupportedLocale
 	}
-	return s.db.Model(&models.User{}).Where("id = ?", userID).Update("locale", req.Locale).Error
+	return s.updateUser(userID, map[string]interface{}{"locale": req.Locale})
 }
 
 func (s *PreferenceService) UpdateNumberFormat(userID string, req dto.UpdateNumberFormatRequest) error {
@@ -205,6 +211,12 @@ func (s *PreferenceService) UpdateAll(userID string, req dto.UpdatePreferencesRe
 		}
 		updates["locale"] = req.Locale
 	}
+	if req.PhoneRegion != "" {
+		if !phone.ValidRegion(req.PhoneRegion) {
+			return nil, ErrInvalidPhoneRegion
+		}
+		updates["phone_region"] = strings.ToUpper(req.PhoneRegion)
+	}
 	if req.NumberFormat != "" {
 		updates["number_format"] = req.NumberFormat
 	}
@@ -263,12 +275,28 @@ func (s *PreferenceService) UpdateAll(userID string, req dto.UpdatePreferencesRe
 	if len(updates) == 0 {
 		return s.Get(userID)
 	}
-	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+	if err := s.updateUser(userID, updates); err != nil {
 		return nil, err
 	}
 	return s.Get(userID)
 }
 
+// updateUser saves preferences of the user. The phone region and the locale
+// decide how numbers without a country code are read, so when they change
+// the region, the phone numbers in the user's vaults are normalized again.
+func (s *PreferenceService) updateUser(userID string, updates map[string]interface{}) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		before := phoneRegionForUser(tx, userID)
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if region := phoneRegionForUser(tx, userID); region != before {
+			return renormalizePhoneNumbers(tx, userID, region)
+		}
+		return nil
+	})
+}
+
 func defaultContactListColumns() []string {
 	return []string{"name", "nickname", "first_met_at", "status", "updated_at"}
 }
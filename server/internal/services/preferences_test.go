@@ -362,3 +362,18 @@ func TestPreferencesAreIsolatedPerSystemUser(t *testing.T) {
 		t.Fatalf("first user's preferences leaked to second user: %+v", secondPrefs)
 	}
 }
+
+func TestPreferenceUpdateAllPhoneRegion(t *testing.T) {
+	svc, userID := setupPreferenceTest(t)
+
+	if _, err := svc.UpdateAll(userID, dto.UpdatePreferencesRequest{PhoneRegion: "XX"}); !errors.Is(err, ErrInvalidPhoneRegion) {
+		t.Errorf("expected ErrInvalidPhoneRegion, got %v", err)
+	}
+	prefs, err := svc.UpdateAll(userID, dto.UpdatePreferencesRequest{PhoneRegion: "gb"})
+	if err != nil {
+		t.Fatalf("UpdateAll failed: %v", err)
+	}
+	if prefs.PhoneRegion != "GB" {
+		t.Errorf("expected phone_region GB, got %q", prefs.PhoneRegion)
+	}
+}
@@ -425,6 +425,9 @@ func createExternalRelationshipContact(tx *gorm.DB, vaultID, externalContactName
 		return nil, err
 	}
 	contact.Listed = false
+	if err := recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeCreated); err != nil {
+		return nil, err
+	}
 	return &contact, nil
 }
 
@@ -12,6 +12,8 @@ var (
 	ErrReminderNotFound               = errors.New("reminder not found")
 	ErrReminderInvalidAudience        = errors.New("invalid reminder audience")
 	ErrReminderAudienceUserNotInVault = errors.New("reminder audience user is not in vault")
+	ErrReminderInvalidLeadTime        = errors.New("invalid reminder lead time")
+	ErrReminderInvalidEscalation      = errors.New("invalid reminder escalation delay")
 )
 
 type ReminderService struct {
@@ -60,6 +62,12 @@ func (s *ReminderService) Create(contactID, vaultID string, req dto.CreateRemind
 		return nil, err
 	}
 	reminder.Audience = audience
+	if reminder.LeadTimeDays, err = normalizeReminderLeadTimes(req.LeadTimeDays); err != nil {
+		return nil, err
+	}
+	if reminder.EscalateAfterHours, err = normalizeReminderEscalation(req.EscalateAfterHours); err != nil {
+		return nil, err
+	}
 	if err := validateAndApplyReminderDate(&reminder.Day, &reminder.Month, &reminder.Year, &reminder.CalendarType, &reminder.OriginalDay, &reminder.OriginalMonth, &reminder.OriginalYear,
 		req.CalendarType, req.OriginalDay, req.OriginalMonth, req.OriginalYear); err != nil {
 		return nil, err
@@ -71,6 +79,9 @@ func (s *ReminderService) Create(contactID, vaultID string, req dto.CreateRemind
 		if err := tx.Create(&reminder).Error; err != nil {
 			return err
 		}
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeReminder, reminder.ID, models.VaultChangeCreated); err != nil {
+			return err
+		}
 		if err := replaceReminderSelectedUsers(tx, reminder.ID, selectedUserIDs); err != nil {
 			return err
 		}
@@ -111,6 +122,16 @@ func (s *ReminderService) Update(id uint, contactID, vaultID string, req dto.Upd
 	reminder.Type = req.Type
 	reminder.FrequencyNumber = req.FrequencyNumber
 	reminder.Audience = audience
+	if req.LeadTimeDays != nil {
+		if reminder.LeadTimeDays, err = normalizeReminderLeadTimes(*req.LeadTimeDays); err != nil {
+			return nil, err
+		}
+	}
+	if req.EscalateAfterHours != nil {
+		if reminder.EscalateAfterHours, err = normalizeReminderEscalation(req.EscalateAfterHours); err != nil {
+			return nil, err
+		}
+	}
 	if err := validateAndApplyReminderDate(&reminder.Day, &reminder.Month, &reminder.Year, &reminder.CalendarType, &reminder.OriginalDay, &reminder.OriginalMonth, &reminder.OriginalYear,
 		req.CalendarType, req.OriginalDay, req.OriginalMonth, req.OriginalYear); err != nil {
 		return nil, err
@@ -122,6 +143,9 @@ func (s *ReminderService) Update(id uint, contactID, vaultID string, req dto.Upd
 		if err := tx.Save(&reminder).Error; err != nil {
 			return err
 		}
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeReminder, reminder.ID, models.VaultChangeUpdated); err != nil {
+			return err
+		}
 		if err := replaceReminderSelectedUsers(tx, reminder.ID, selectedUserIDs); err != nil {
 			return err
 		}
@@ -154,7 +178,13 @@ func (s *ReminderService) Delete(id uint, contactID, vaultID string) error {
 		if err := tx.Where("contact_reminder_id = ?", reminder.ID).Delete(&models.ContactReminderSelectedUser{}).Error; err != nil {
 			return err
 		}
-		return tx.Delete(&reminder).Error
+		if err := tx.Where("contact_reminder_id = ?", reminder.ID).Delete(&models.ContactReminderDeliveryState{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&reminder).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeReminder, reminder.ID, models.VaultChangeDeleted)
 	}); err != nil {
 		return err
 	}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reminder actions offered as signed links in every reminder delivery.
+const (
+	ReminderActionAcknowledge = "acknowledge"
+	ReminderActionSnoozeDay   = "snooze_1d"
+	ReminderActionSnoozeWeek  = "snooze_1w"
+)
+
+// reminderActionLinkTTL bounds how long an emailed action link stays usable.
+const reminderActionLinkTTL = 30 * 24 * time.Hour
+
+var ErrReminderActionInvalid = errors.New("invalid or expired reminder action link")
+
+var reminderSnoozeDurations = map[string]time.Duration{
+	ReminderActionSnoozeDay:  24 * time.Hour,
+	ReminderActionSnoozeWeek: 7 * 24 * time.Hour,
+}
+
+type reminderActionClaims struct {
+	ScheduleID uint   `json:"sid"`
+	UserID     string `json:"uid"`
+	Action     string `json:"act"`
+	jwt.RegisteredClaims
+}
+
+// ReminderActionService signs and redeems the acknowledge/snooze links
+// embedded in reminder deliveries. The link itself is the credential, so it
+// is a short HS256 JWT bound to one delivered schedule row and its owner.
+type ReminderActionService struct {
+	db       *gorm.DB
+	secret   string
+	appURL   string
+	settings *SystemSettingService
+}
+
+func NewReminderActionService(db *gorm.DB, secret, appURL string) *ReminderActionService {
+	return &ReminderActionService{db: db, secret: secret, appURL: appURL}
+}
+
+func (s *ReminderActionService) SetSystemSettings(settings *SystemSettingService) {
+	s.settings = settings
+}
+
+func (s *ReminderActionService) getAppURL() string {
+	if s.settings != nil {
+		return s.settings.GetWithDefault("app.url", s.appURL)
+	}
+	return s.appURL
+}
+
+// ActionURL returns the signed link that performs action on a delivered
+// schedule row for userID.
+func (s *ReminderActionService) ActionURL(scheduleID uint, userID, action string) (string, error) {
+	now := time.Now()
+	claims := reminderActionClaims{
+		ScheduleID: scheduleID,
+		UserID:     userID,
+		Action:     action,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(reminderActionLinkTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.secret))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/api/reminders/actions/%s", s.getAppURL(), url.PathEscape(token)), nil
+}
+
+// Preview validates a link without acting on it, for the confirmation page.
+func (s *ReminderActionService) Preview(token string) (*dto.ReminderActionResponse, error) {
+	claims, scheduled, err := s.load(token)
+	if err != nil {
+		return nil, err
+	}
+	return reminderActionResponse(claims.Action, scheduled, nil), nil
+}
+
+// Apply performs the link's action. Acknowledging is idempotent; snoozing
+// again from the same delivery moves the pending snooze instead of adding a
+// second one.
+func (s *ReminderActionService) Apply(token string) (*dto.ReminderActionResponse, error) {
+	claims, scheduled, err := s.load(token)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	location := userLocation(scheduled.UserNotificationChannel.User)
+	occurrence, err := reminderScheduleOccurrence(s.db, scheduled, location)
+	if err != nil {
+		return nil, err
+	}
+	occurrenceOn := occurrence.Format("2006-01-02")
+
+	if claims.Action == ReminderActionAcknowledge {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := ensureReminderDeliveryState(tx, scheduled.ContactReminderID, claims.UserID, occurrenceOn); err != nil {
+				return err
+			}
+			return tx.Model(&models.ContactReminderDeliveryState{}).
+				Where("contact_reminder_id = ? AND user_id = ? AND occurrence_on = ? AND acknowledged_at IS NULL", scheduled.ContactReminderID, claims.UserID, occurrenceOn).
+				Update("acknowledged_at", now).Error
+		})
+		if err != nil {
+			return nil, err
+		}
+		return reminderActionResponse(claims.Action, scheduled, nil), nil
+	}
+
+	until := now.Add(reminderSnoozeDurations[claims.Action]).Truncate(time.Minute)
+	rootID := scheduled.ID
+	if scheduled.SnoozeOfID != nil {
+		rootID = *scheduled.SnoozeOfID
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var pending models.ContactReminderScheduled
+		err := tx.Where("user_notification_channel_id = ? AND snooze_of_id = ? AND triggered_at IS NULL", scheduled.UserNotificationChannelID, rootID).First(&pending).Error
+		switch {
+		case err == nil:
+			if err := tx.Model(&pending).Update("scheduled_at", until).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(&models.ContactReminderScheduled{
+				UserNotificationChannelID: scheduled.UserNotificationChannelID,
+				ContactReminderID:         scheduled.ContactReminderID,
+				ScheduledAt:               until,
+				LeadDays:                  scheduled.LeadDays,
+				SnoozeOfID:                &rootID,
+			}).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+		if err := ensureReminderDeliveryState(tx, scheduled.ContactReminderID, claims.UserID, occurrenceOn); err != nil {
+			return err
+		}
+		return tx.Model(&models.ContactReminderDeliveryState{}).
+			Where("contact_reminder_id = ? AND user_id = ? AND occurrence_on = ?", scheduled.ContactReminderID, claims.UserID, occurrenceOn).
+			Update("snoozed_until", until).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reminderActionResponse(claims.Action, scheduled, &until), nil
+}
+
+// load parses the token and returns the delivered schedule it refers to,
+// provided its owner is still a member of the contact's vault.
+func (s *ReminderActionService) load(token string) (*reminderActionClaims, *models.ContactReminderScheduled, error) {
+	claims := &reminderActionClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(s.secret), nil
+	})
+	if err != nil || !parsed.Valid {
+		return nil, nil, ErrReminderActionInvalid
+	}
+	if claims.Action != ReminderActionAcknowledge && reminderSnoozeDurations[claims.Action] == 0 {
+		return nil, nil, ErrReminderActionInvalid
+	}
+
+	var scheduled models.ContactReminderScheduled
+	err = s.db.Preload("ContactReminder.Contact").Preload("UserNotificationChannel.User").First(&scheduled, claims.ScheduleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrReminderActionInvalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	channel := scheduled.UserNotificationChannel
+	if scheduled.TriggeredAt == nil || channel.UserID == nil || *channel.UserID != claims.UserID || scheduled.ContactReminder.ID == 0 {
+		return nil, nil, ErrReminderActionInvalid
+	}
+	var membershipCount int64
+	if err := s.db.Model(&models.UserVault{}).
+		Where("vault_id = ? AND user_id = ?", scheduled.ContactReminder.Contact.VaultID, claims.UserID).
+		Count(&membershipCount).Error; err != nil {
+		return nil, nil, err
+	}
+	if membershipCount == 0 {
+		return nil, nil, ErrReminderActionInvalid
+	}
+	return claims, &scheduled, nil
+}
+
+func reminderActionResponse(action string, scheduled *models.ContactReminderScheduled, snoozedUntil *time.Time) *dto.ReminderActionResponse {
+	locale, _ := reminderDeliveryLocale(scheduled.UserNotificationChannel.User)
+	resp := &dto.ReminderActionResponse{
+		Action:        action,
+		ReminderLabel: scheduled.ContactReminder.Label,
+		Locale:        locale,
+	}
+	if snoozedUntil != nil {
+		local := snoozedUntil.In(userLocation(scheduled.UserNotificationChannel.User))
+		resp.SnoozedUntil = &local
+	}
+	return resp
+}
+
+// reminderScheduleOccurrence returns the occurrence a delivery belongs to,
+// in location: its fire time shifted forward by the lead time. Snoozed
+// deliveries belong to the occurrence of the delivery they were snoozed from.
+func reminderScheduleOccurrence(db *gorm.DB, scheduled *models.ContactReminderScheduled, location *time.Location) (time.Time, error) {
+	origin := scheduled
+	if scheduled.SnoozeOfID != nil {
+		var parent models.ContactReminderScheduled
+		err := db.First(&parent, *scheduled.SnoozeOfID).Error
+		if err == nil {
+			origin = &parent
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return time.Time{}, err
+		}
+	}
+	return origin.ScheduledAt.In(location).AddDate(0, 0, origin.LeadDays), nil
+}
+
+func ensureReminderDeliveryState(tx *gorm.DB, reminderID uint, userID, occurrenceOn string) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ContactReminderDeliveryState{
+		ContactReminderID: reminderID,
+		UserID:            userID,
+		OccurrenceOn:      occurrenceOn,
+	}).Error
+}
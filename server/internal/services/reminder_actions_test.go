@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+)
+
+var reminderActionLinkPattern = regexp.MustCompile(`href="https://bonds\.example/api/reminders/actions/([^"]+)"`)
+
+// reminderActionTokens returns the acknowledge, snooze-1d and snooze-1w
+// tokens linked from a delivered reminder body.
+func reminderActionTokens(t *testing.T, body string) (string, string, string) {
+	t.Helper()
+	matches := reminderActionLinkPattern.FindAllStringSubmatch(body, -1)
+	if len(matches) != 3 {
+		t.Fatalf("expected 3 action links in body, got %d: %s", len(matches), body)
+	}
+	return matches[0][1], matches[1][1], matches[2][1]
+}
+
+func setupReminderActionTest(t *testing.T) (*reminderSchedulerTestContext, *ReminderActionService) {
+	t.Helper()
+	ctx := setupReminderSchedulerTest(t)
+	actions := NewReminderActionService(ctx.db, "reminder-action-secret", "https://bonds.example")
+	ctx.svc.SetActionService(actions)
+	return ctx, actions
+}
+
+func (ctx *reminderSchedulerTestContext) createLeadScheduled(t *testing.T, reminderID, channelID uint, scheduledAt time.Time, leadDays int) *models.ContactReminderScheduled {
+	t.Helper()
+	s := models.ContactReminderScheduled{
+		UserNotificationChannelID: channelID,
+		ContactReminderID:         reminderID,
+		ScheduledAt:               scheduledAt,
+		LeadDays:                  leadDays,
+	}
+	if err := ctx.db.Create(&s).Error; err != nil {
+		t.Fatalf("create scheduled failed: %v", err)
+	}
+	return &s
+}
+
+func TestReminderLeadDeliveryAcknowledgeSilencesOccurrence(t *testing.T) {
+	ctx, actions := setupReminderActionTest(t)
+	ch := ctx.createEmailChannel(t)
+	reminder := ctx.createReminder(t, "recurring_year", nil)
+	ctx.db.Model(reminder).Update("escalate_after_hours", 1)
+
+	leadAt := time.Now().Add(-time.Minute).Truncate(time.Minute)
+	ctx.createLeadScheduled(t, reminder.ID, ch.ID, leadAt, 3)
+	ctx.svc.ProcessDueReminders()
+
+	if len(ctx.mailer.calls) != 1 {
+		t.Fatalf("expected 1 lead-time email, got %d", len(ctx.mailer.calls))
+	}
+	call := ctx.mailer.calls[0]
+	if call.Subject != "In 3 days: Test Reminder" {
+		t.Errorf("unexpected subject %q", call.Subject)
+	}
+	occurrenceOn := leadAt.UTC().AddDate(0, 0, 3).Format("2006-01-02")
+	if !strings.Contains(call.Body, occurrenceOn) {
+		t.Errorf("expected body to name the occurrence date %s, got %s", occurrenceOn, call.Body)
+	}
+
+	// The lead-time delivery continues its own chain into next year.
+	var next models.ContactReminderScheduled
+	if err := ctx.db.Where("contact_reminder_id = ? AND triggered_at IS NULL", reminder.ID).First(&next).Error; err != nil {
+		t.Fatalf("expected next lead-time schedule: %v", err)
+	}
+	if next.LeadDays != 3 || next.ScheduledAt.UTC().AddDate(0, 0, 3).Format("01-02") != leadAt.UTC().AddDate(0, 0, 3).Format("01-02") {
+		t.Errorf("unexpected next schedule: lead %d at %s", next.LeadDays, next.ScheduledAt)
+	}
+
+	ackToken, _, _ := reminderActionTokens(t, call.Body)
+	resp, err := actions.Apply(ackToken)
+	if err != nil {
+		t.Fatalf("Apply acknowledge failed: %v", err)
+	}
+	if resp.Action != ReminderActionAcknowledge || resp.ReminderLabel != "Test Reminder" {
+		t.Errorf("unexpected action response: %+v", resp)
+	}
+	var state models.ContactReminderDeliveryState
+	if err := ctx.db.Where("contact_reminder_id = ? AND user_id = ? AND occurrence_on = ?", reminder.ID, ctx.userID, occurrenceOn).First(&state).Error; err != nil {
+		t.Fatalf("expected delivery state: %v", err)
+	}
+	if state.AcknowledgedAt == nil || state.LastNotifiedAt == nil {
+		t.Fatalf("expected acknowledged state with last notification, got %+v", state)
+	}
+
+	// The on-the-day delivery for the same occurrence is skipped, and the
+	// acknowledged occurrence never escalates.
+	dayOf := ctx.createLeadScheduled(t, reminder.ID, ch.ID, leadAt.AddDate(0, 0, 3), 0)
+	ctx.svc.processOne(dayOf)
+	ctx.svc.processEscalations(time.Now().AddDate(0, 0, 4))
+	if len(ctx.mailer.calls) != 1 {
+		t.Fatalf("expected no further emails after acknowledging, got %d", len(ctx.mailer.calls))
+	}
+	var skipped models.ContactReminderScheduled
+	ctx.db.First(&skipped, dayOf.ID)
+	if skipped.TriggeredAt == nil {
+		t.Error("expected the skipped delivery to be retired")
+	}
+}
+
+func TestReminderSnoozeQueuesOneOffDelivery(t *testing.T) {
+	ctx, actions := setupReminderActionTest(t)
+	ch := ctx.createEmailChannel(t)
+	reminder := ctx.createReminder(t, "recurring_week", freqPtr(1))
+	firedAt := time.Now().Add(-time.Minute).Truncate(time.Minute)
+	root := ctx.createScheduled(t, reminder.ID, ch.ID, firedAt, nil)
+	ctx.svc.ProcessDueReminders()
+	if len(ctx.mailer.calls) != 1 {
+		t.Fatalf("expected 1 email, got %d", len(ctx.mailer.calls))
+	}
+	_, snoozeDay, snoozeWeek := reminderActionTokens(t, ctx.mailer.calls[0].Body)
+
+	preview, err := actions.Preview(snoozeDay)
+	if err != nil || preview.Action != ReminderActionSnoozeDay {
+		t.Fatalf("Preview failed: %+v %v", preview, err)
+	}
+	var pending int64
+	ctx.db.Model(&models.ContactReminderScheduled{}).Where("snooze_of_id IS NOT NULL").Count(&pending)
+	if pending != 0 {
+		t.Fatal("expected preview not to snooze")
+	}
+
+	if _, err := actions.Apply(snoozeDay); err != nil {
+		t.Fatalf("Apply snooze failed: %v", err)
+	}
+	resp, err := actions.Apply(snoozeWeek)
+	if err != nil {
+		t.Fatalf("Apply snooze failed: %v", err)
+	}
+	var snoozes []models.ContactReminderScheduled
+	ctx.db.Where("snooze_of_id = ?", root.ID).Find(&snoozes)
+	if len(snoozes) != 1 {
+		t.Fatalf("expected repeated snoozes to move one pending delivery, got %d", len(snoozes))
+	}
+	if d := snoozes[0].ScheduledAt.Sub(time.Now()); d < 6*24*time.Hour || d > 7*24*time.Hour {
+		t.Errorf("expected the snooze to fire in about a week, got %s", d)
+	}
+	if resp.SnoozedUntil == nil {
+		t.Error("expected the snooze time in the response")
+	}
+
+	var before int64
+	ctx.db.Model(&models.ContactReminderScheduled{}).Count(&before)
+	ctx.svc.processOne(&snoozes[0])
+	if len(ctx.mailer.calls) != 2 {
+		t.Fatalf("expected the snoozed delivery to send, got %d emails", len(ctx.mailer.calls))
+	}
+	if !strings.Contains(ctx.mailer.calls[1].Body, firedAt.UTC().Format("2006-01-02")) {
+		t.Errorf("expected the snoozed delivery to keep the original date, got %s", ctx.mailer.calls[1].Body)
+	}
+	var after int64
+	ctx.db.Model(&models.ContactReminderScheduled{}).Count(&after)
+	if after != before {
+		t.Errorf("expected a snoozed delivery not to reschedule, rows went %d -> %d", before, after)
+	}
+}
+
+func TestReminderActionRejectsInvalidLinks(t *testing.T) {
+	ctx, actions := setupReminderActionTest(t)
+	ch := ctx.createEmailChannel(t)
+	reminder := ctx.createReminder(t, "one_time", nil)
+	pending := ctx.createScheduled(t, reminder.ID, ch.ID, time.Now().Add(time.Hour), nil)
+
+	link, err := actions.ActionURL(pending.ID, ctx.userID, ReminderActionAcknowledge)
+	if err != nil {
+		t.Fatalf("ActionURL failed: %v", err)
+	}
+	token := link[strings.LastIndex(link, "/")+1:]
+	if _, err := actions.Apply(token); !errors.Is(err, ErrReminderActionInvalid) {
+		t.Errorf("expected a not-yet-delivered schedule to be rejected, got %v", err)
+	}
+
+	now := time.Now()
+	ctx.db.Model(pending).Update("triggered_at", now)
+	if _, err := actions.Preview(token); err != nil {
+		t.Fatalf("expected a valid link, got %v", err)
+	}
+	if _, err := actions.Preview(token[:len(token)-2] + "xx"); !errors.Is(err, ErrReminderActionInvalid) {
+		t.Errorf("expected a tampered link to be rejected, got %v", err)
+	}
+	other := NewReminderActionService(ctx.db, "another-secret", "https://bonds.example")
+	if _, err := other.Preview(token); !errors.Is(err, ErrReminderActionInvalid) {
+		t.Errorf("expected a link signed with another secret to be rejected, got %v", err)
+	}
+	expired, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, reminderActionClaims{
+		ScheduleID: pending.ID, UserID: ctx.userID, Action: ReminderActionAcknowledge,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(now.Add(-time.Hour))},
+	}).SignedString([]byte("reminder-action-secret"))
+	if _, err := actions.Preview(expired); !errors.Is(err, ErrReminderActionInvalid) {
+		t.Errorf("expected an expired link to be rejected, got %v", err)
+	}
+
+	ctx.db.Where("user_id = ?", ctx.userID).Delete(&models.UserVault{})
+	if _, err := actions.Preview(token); !errors.Is(err, ErrReminderActionInvalid) {
+		t.Errorf("expected a link of a former vault member to be rejected, got %v", err)
+	}
+}
+
+func TestReminderEscalationNotifiesOtherMembersOnce(t *testing.T) {
+	ctx, _ := setupReminderActionTest(t)
+	other, err := NewAuthService(ctx.db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Other", LastName: "Member", Email: "escalation-other@example.com", Password: "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := ctx.db.Create(&models.UserVault{VaultID: ctx.vaultID, UserID: other.User.ID, Permission: models.PermissionViewer}).Error; err != nil {
+		t.Fatalf("add member failed: %v", err)
+	}
+
+	ch := ctx.createEmailChannel(t)
+	reminder := ctx.createReminder(t, "one_time", nil)
+	ctx.db.Model(reminder).Update("escalate_after_hours", 2)
+	ctx.createScheduled(t, reminder.ID, ch.ID, time.Now().Add(-time.Minute).Truncate(time.Minute), nil)
+	ctx.svc.ProcessDueReminders()
+	if len(ctx.mailer.calls) != 1 {
+		t.Fatalf("expected 1 reminder email, got %d", len(ctx.mailer.calls))
+	}
+
+	ctx.svc.processEscalations(time.Now().Add(time.Hour))
+	if len(ctx.mailer.calls) != 1 {
+		t.Fatalf("expected no escalation before the deadline, got %d emails", len(ctx.mailer.calls))
+	}
+	ctx.svc.processEscalations(time.Now().Add(3 * time.Hour))
+	ctx.svc.processEscalations(time.Now().Add(4 * time.Hour))
+	if len(ctx.mailer.calls) != 2 {
+		t.Fatalf("expected exactly one escalation, got %d emails", len(ctx.mailer.calls)-1)
+	}
+	escalation := ctx.mailer.calls[1]
+	if escalation.To != "escalation-other@example.com" || escalation.Subject != "Not acknowledged: Test Reminder" {
+		t.Errorf("unexpected escalation: %+v", escalation)
+	}
+	if !strings.Contains(escalation.Body, "Test User") {
+		t.Errorf("expected escalation to name the recipient, got %s", escalation.Body)
+	}
+}
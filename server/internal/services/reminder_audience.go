@@ -30,6 +30,8 @@ func toReminderResponse(reminder *models.ContactReminder) dto.ReminderResponse {
 		SelectedUserIDs:      selectedUserIDs,
 		CreatedAt:            reminder.CreatedAt,
 		UpdatedAt:            reminder.UpdatedAt,
+		LeadTimeDays:         reminderLeadDays(reminder),
+		EscalateAfterHours:   reminder.EscalateAfterHours,
 	}
 }
 
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/naiba/bonds/internal/i18n"
+	"github.com/naiba/bonds/internal/models"
+)
+
+// reminderEscalationWindow bounds how far back unacknowledged occurrences
+// are considered, so enabling escalation on an old reminder does not replay
+// its history.
+const reminderEscalationWindow = time.Duration(maxReminderEscalateAfterHours+24) * time.Hour
+
+// processEscalations tells the other members of a vault when a recipient
+// has not acknowledged a reminder occurrence within the reminder's
+// EscalateAfterHours. Escalation waits for the occurrence's own day and for
+// any snooze to run out, and fires at most once per occurrence and user;
+// the escalated_at claim keeps concurrent replicas from both sending.
+func (s *ReminderSchedulerService) processEscalations(now time.Time) {
+	var states []models.ContactReminderDeliveryState
+	err := s.db.
+		Joins("JOIN contact_reminders ON contact_reminders.id = contact_reminder_delivery_states.contact_reminder_id").
+		Where("contact_reminder_delivery_states.acknowledged_at IS NULL AND contact_reminder_delivery_states.escalated_at IS NULL").
+		Where("contact_reminder_delivery_states.last_notified_at > ?", now.Add(-reminderEscalationWindow)).
+		Where("contact_reminders.escalate_after_hours > 0").
+		Preload("ContactReminder.Contact").
+		Find(&states).Error
+	if err != nil {
+		log.Printf("[reminder-scheduler] Failed to query reminder escalations: %v", err)
+		return
+	}
+	for i := range states {
+		s.escalateOne(&states[i], now)
+	}
+}
+
+func (s *ReminderSchedulerService) escalateOne(state *models.ContactReminderDeliveryState, now time.Time) {
+	reminder := &state.ContactReminder
+	if reminder.EscalateAfterHours == nil || state.LastNotifiedAt == nil {
+		return
+	}
+	if now.Before(state.LastNotifiedAt.Add(time.Duration(*reminder.EscalateAfterHours) * time.Hour)) {
+		return
+	}
+	if state.SnoozedUntil != nil && now.Before(*state.SnoozedUntil) {
+		return
+	}
+	var user models.User
+	if err := s.db.Where("id = ?", state.UserID).First(&user).Error; err != nil {
+		log.Printf("[reminder-scheduler] Load user %s for escalation: %v", state.UserID, err)
+		return
+	}
+	if now.In(userLocation(&user)).Format("2006-01-02") < state.OccurrenceOn {
+		return
+	}
+
+	claim := s.db.Model(&models.ContactReminderDeliveryState{}).
+		Where("id = ? AND escalated_at IS NULL AND acknowledged_at IS NULL", state.ID).
+		Update("escalated_at", now)
+	if claim.Error != nil {
+		log.Printf("[reminder-scheduler] Claim escalation %d: %v", state.ID, claim.Error)
+		return
+	}
+	if claim.RowsAffected == 0 {
+		return
+	}
+
+	var channels []models.UserNotificationChannel
+	err := s.db.Preload("User").
+		Where("user_id IN (?) AND active = ?",
+			s.db.Model(&models.UserVault{}).Select("user_id").Where("vault_id = ? AND user_id <> ?", reminder.Contact.VaultID, state.UserID),
+			true).
+		Find(&channels).Error
+	if err != nil {
+		log.Printf("[reminder-scheduler] Load escalation channels for reminder %d: %v", reminder.ID, err)
+		return
+	}
+	for i := range channels {
+		channel := &channels[i]
+		locale, _ := reminderDeliveryLocale(channel.User)
+		contactName, err := s.reminderContactName(reminder, channel.UserID, locale)
+		if err != nil {
+			log.Printf("[reminder-scheduler] Format contact name for reminder %d: %v", reminder.ID, err)
+			continue
+		}
+		params := map[string]string{
+			"user":    reminderUserName(&user),
+			"label":   reminder.Label,
+			"contact": contactName,
+			"date":    state.OccurrenceOn,
+		}
+		subject := i18n.Tt(locale, "reminder.escalation.subject", params)
+		body := i18n.Tt(locale, "reminder.escalation.body", params)
+		if sendErr := s.sendReminder(channel, subject, body); sendErr != nil {
+			if err := recordChannelFailure(s.db, channel, subject, body, sendErr, time.Now()); err != nil {
+				log.Printf("[reminder-scheduler] Record failed escalation on channel %d: %v", channel.ID, err)
+			}
+			continue
+		}
+		if err := recordChannelSuccess(s.db, channel, subject, body, time.Now()); err != nil {
+			log.Printf("[reminder-scheduler] Record escalation on channel %d: %v", channel.ID, err)
+		}
+	}
+}
+
+func reminderUserName(user *models.User) string {
+	var parts []string
+	for _, p := range []*string{user.FirstName, user.LastName} {
+		if p != nil && strings.TrimSpace(*p) != "" {
+			parts = append(parts, strings.TrimSpace(*p))
+		}
+	}
+	if len(parts) == 0 {
+		return user.Email
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/naiba/bonds/internal/models"
+)
+
+const (
+	maxReminderLeadTimeDays       = 365
+	maxReminderLeadTimes          = 5
+	maxReminderEscalateAfterHours = 30 * 24
+)
+
+// normalizeReminderLeadTimes validates and canonicalises requested lead
+// times: duplicates are dropped and offsets are sorted furthest first. The
+// result is nil when the reminder only fires on the day, so existing rows and
+// "[0]" share one representation.
+func normalizeReminderLeadTimes(days []int) (*string, error) {
+	seen := make(map[int]struct{}, len(days))
+	unique := make([]int, 0, len(days))
+	for _, d := range days {
+		if d < 0 || d > maxReminderLeadTimeDays {
+			return nil, ErrReminderInvalidLeadTime
+		}
+		if _, dup := seen[d]; dup {
+			continue
+		}
+		seen[d] = struct{}{}
+		unique = append(unique, d)
+	}
+	if len(unique) > maxReminderLeadTimes {
+		return nil, ErrReminderInvalidLeadTime
+	}
+	if len(unique) == 0 || (len(unique) == 1 && unique[0] == 0) {
+		return nil, nil
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(unique)))
+	parts := make([]string, len(unique))
+	for i, d := range unique {
+		parts[i] = strconv.Itoa(d)
+	}
+	joined := strings.Join(parts, ",")
+	return &joined, nil
+}
+
+// normalizeReminderEscalation maps 0 to "disabled" and rejects values
+// outside 1..maxReminderEscalateAfterHours.
+func normalizeReminderEscalation(hours *int) (*int, error) {
+	if hours == nil || *hours == 0 {
+		return nil, nil
+	}
+	if *hours < 0 || *hours > maxReminderEscalateAfterHours {
+		return nil, ErrReminderInvalidEscalation
+	}
+	h := *hours
+	return &h, nil
+}
+
+// reminderLeadDays returns the lead-time offsets of a reminder, furthest
+// first. Reminders without lead times fire on the day only.
+func reminderLeadDays(reminder *models.ContactReminder) []int {
+	if reminder.LeadTimeDays == nil || *reminder.LeadTimeDays == "" {
+		return []int{0}
+	}
+	parts := strings.Split(*reminder.LeadTimeDays, ",")
+	days := make([]int, 0, len(parts))
+	for _, p := range parts {
+		d, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || d < 0 {
+			continue
+		}
+		days = append(days, d)
+	}
+	if len(days) == 0 {
+		return []int{0}
+	}
+	return days
+}
+
+// calcInitialLeadSchedule returns the first delivery time, leadDays before
+// an occurrence, that is still ahead of now. The on-the-day delivery keeps
+// calcInitialSchedule's behaviour unchanged; an earlier delivery whose date
+// has already passed moves on to the next occurrence of a recurring
+// reminder, and is dropped for a one-time reminder.
+func calcInitialLeadSchedule(reminder *models.ContactReminder, preferredTime *string, location *time.Location, leadDays int, now time.Time) (time.Time, bool) {
+	occurrence := calcInitialSchedule(reminder, preferredTime, location)
+	if leadDays == 0 {
+		return occurrence, true
+	}
+	scheduled := occurrence.AddDate(0, 0, -leadDays)
+	for !scheduled.After(now) {
+		next, ok := nextRecurringSchedule(reminder, preferredTime, occurrence, location)
+		if !ok || !next.After(occurrence) {
+			return time.Time{}, false
+		}
+		occurrence = next
+		scheduled = occurrence.AddDate(0, 0, -leadDays)
+	}
+	return scheduled, true
+}
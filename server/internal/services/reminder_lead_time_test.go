@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+)
+
+func TestCreateReminderSchedulesEachLeadTime(t *testing.T) {
+	svc, contactID, vaultID := setupReminderTest(t)
+
+	nextYear := time.Now().Year() + 1
+	reminder, err := svc.Create(contactID, vaultID, dto.CreateReminderRequest{
+		Label:        "Birthday gift",
+		Day:          intPtr(20),
+		Month:        intPtr(1),
+		Year:         intPtr(nextYear),
+		Type:         "one_time",
+		LeadTimeDays: []int{0, 14, 3, 3},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !reflect.DeepEqual(reminder.LeadTimeDays, []int{14, 3, 0}) {
+		t.Fatalf("expected normalized lead times [14 3 0], got %v", reminder.LeadTimeDays)
+	}
+
+	var scheduled []models.ContactReminderScheduled
+	if err := svc.db.Where("contact_reminder_id = ?", reminder.ID).Order("scheduled_at ASC").Find(&scheduled).Error; err != nil {
+		t.Fatalf("Load schedules failed: %v", err)
+	}
+	want := []struct {
+		lead int
+		day  int
+	}{{14, 6}, {3, 17}, {0, 20}}
+	if len(scheduled) != len(want) {
+		t.Fatalf("expected %d schedules, got %d", len(want), len(scheduled))
+	}
+	for i, w := range want {
+		if scheduled[i].LeadDays != w.lead || scheduled[i].ScheduledAt.UTC().Day() != w.day {
+			t.Errorf("schedule %d: expected lead %d on day %d, got lead %d at %s",
+				i, w.lead, w.day, scheduled[i].LeadDays, scheduled[i].ScheduledAt.UTC())
+		}
+	}
+}
+
+func TestCreateReminderRejectsInvalidLeadTimeAndEscalation(t *testing.T) {
+	svc, contactID, vaultID := setupReminderTest(t)
+
+	base := dto.CreateReminderRequest{Label: "Bad", Day: intPtr(1), Month: intPtr(1), Type: "recurring_year"}
+	for _, leads := range [][]int{{-1}, {366}, {1, 2, 3, 4, 5, 6}} {
+		req := base
+		req.LeadTimeDays = leads
+		if _, err := svc.Create(contactID, vaultID, req); !errors.Is(err, ErrReminderInvalidLeadTime) {
+			t.Errorf("lead times %v: expected ErrReminderInvalidLeadTime, got %v", leads, err)
+		}
+	}
+	req := base
+	req.EscalateAfterHours = intPtr(maxReminderEscalateAfterHours + 1)
+	if _, err := svc.Create(contactID, vaultID, req); !errors.Is(err, ErrReminderInvalidEscalation) {
+		t.Errorf("expected ErrReminderInvalidEscalation, got %v", err)
+	}
+}
+
+func TestUpdateReminderKeepsLeadTimesUnlessProvided(t *testing.T) {
+	svc, contactID, vaultID := setupReminderTest(t)
+
+	created, err := svc.Create(contactID, vaultID, dto.CreateReminderRequest{
+		Label: "Anniversary", Day: intPtr(1), Month: intPtr(6), Type: "recurring_year",
+		LeadTimeDays: []int{7, 0}, EscalateAfterHours: intPtr(12),
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	updated, err := svc.Update(created.ID, contactID, vaultID, dto.UpdateReminderRequest{
+		Label: "Anniversary dinner", Day: intPtr(1), Month: intPtr(6), Type: "recurring_year",
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !reflect.DeepEqual(updated.LeadTimeDays, []int{7, 0}) || updated.EscalateAfterHours == nil || *updated.EscalateAfterHours != 12 {
+		t.Fatalf("expected lead times and escalation to be kept, got %v / %v", updated.LeadTimeDays, updated.EscalateAfterHours)
+	}
+
+	none := []int{}
+	updated, err = svc.Update(created.ID, contactID, vaultID, dto.UpdateReminderRequest{
+		Label: "Anniversary dinner", Day: intPtr(1), Month: intPtr(6), Type: "recurring_year",
+		LeadTimeDays: &none, EscalateAfterHours: intPtr(0),
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !reflect.DeepEqual(updated.LeadTimeDays, []int{0}) || updated.EscalateAfterHours != nil {
+		t.Fatalf("expected lead times and escalation to be reset, got %v / %v", updated.LeadTimeDays, updated.EscalateAfterHours)
+	}
+}
+
+func TestCalcInitialLeadScheduleSkipsPassedLeadTimes(t *testing.T) {
+	soon := time.Now().UTC().AddDate(0, 0, 2)
+	day, month := soon.Day(), int(soon.Month())
+	yearly := &models.ContactReminder{Type: "recurring_year", Day: &day, Month: &month}
+
+	scheduled, ok := calcInitialLeadSchedule(yearly, nil, time.UTC, 14, time.Now())
+	if !ok {
+		t.Fatal("expected a yearly reminder to roll a passed lead time over to next year")
+	}
+	if scheduled.AddDate(0, 0, 14).Year() != soon.Year()+1 {
+		t.Errorf("expected next year's occurrence, got delivery at %s", scheduled)
+	}
+
+	year := soon.Year()
+	oneTime := &models.ContactReminder{Type: "one_time", Day: &day, Month: &month, Year: &year}
+	if _, ok := calcInitialLeadSchedule(oneTime, nil, time.UTC, 14, time.Now()); ok {
+		t.Error("expected a passed lead time of a one-time reminder to be dropped")
+	}
+	if _, ok := calcInitialLeadSchedule(oneTime, nil, time.UTC, 1, time.Now()); !ok {
+		t.Error("expected a future lead time of a one-time reminder to be scheduled")
+	}
+}
@@ -12,18 +12,32 @@ import (
 const maxChannelFails = 10
 
 type ReminderSchedulerService struct {
-	db     *gorm.DB
-	mailer Mailer
-	sender NotificationSender
+	db      *gorm.DB
+	mailer  Mailer
+	sender  NotificationSender
+	webPush NotificationSender
+	actions *ReminderActionService
 }
 
 func NewReminderSchedulerService(db *gorm.DB, mailer Mailer, sender NotificationSender) *ReminderSchedulerService {
 	return &ReminderSchedulerService{db: db, mailer: mailer, sender: sender}
 }
 
+// SetWebPushSender enables delivery to Web Push channels.
+func (s *ReminderSchedulerService) SetWebPushSender(webPush NotificationSender) {
+	s.webPush = webPush
+}
+
+// SetActionService enables the signed acknowledge/snooze links in reminder
+// deliveries.
+func (s *ReminderSchedulerService) SetActionService(actions *ReminderActionService) {
+	s.actions = actions
+}
+
 // ProcessDueReminders finds all scheduled reminders that are due and processes them.
 func (s *ReminderSchedulerService) ProcessDueReminders() {
 	now := time.Now().Truncate(time.Minute)
+	s.processEscalations(time.Now())
 
 	var scheduled []models.ContactReminderScheduled
 	err := s.db.
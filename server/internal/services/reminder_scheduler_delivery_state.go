@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 
 	calendarPkg "github.com/naiba/bonds/internal/calendar"
@@ -37,13 +38,38 @@ func (s *ReminderSchedulerService) processOne(scheduled *models.ContactReminderS
 
 	channel := &current.UserNotificationChannel
 	reminder := &current.ContactReminder
+	userID := *channel.UserID
+	occurrence, err := reminderScheduleOccurrence(s.db, current, userLocation(channel.User))
+	if err != nil {
+		log.Printf("[reminder-scheduler] Resolve occurrence for scheduled reminder %d: %v", current.ID, err)
+		return
+	}
+	occurrenceOn := occurrence.Format("2006-01-02")
+
+	var acknowledged int64
+	if err := s.db.Model(&models.ContactReminderDeliveryState{}).
+		Where("contact_reminder_id = ? AND user_id = ? AND occurrence_on = ? AND acknowledged_at IS NOT NULL", reminder.ID, userID, occurrenceOn).
+		Count(&acknowledged).Error; err != nil {
+		log.Printf("[reminder-scheduler] Load acknowledgement for scheduled reminder %d: %v", current.ID, err)
+		return
+	}
+	if acknowledged > 0 {
+		// The user already acknowledged this occurrence from an earlier
+		// lead-time delivery; the remaining ones stay quiet.
+		if err := s.handleSkipped(current, channel, reminder, time.Now()); err != nil {
+			log.Printf("[reminder-scheduler] Skip acknowledged scheduled reminder %d: %v", current.ID, err)
+		}
+		return
+	}
+
 	locale, enableAltCalendar := reminderDeliveryLocale(channel.User)
 	contactName, err := s.reminderContactName(reminder, channel.UserID, locale)
 	if err != nil {
 		log.Printf("[reminder-scheduler] Format contact name for reminder %d: %v", reminder.ID, err)
 		return
 	}
-	subject, htmlBody := reminderDeliveryContent(reminder, current.ScheduledAt, locale, enableAltCalendar, contactName)
+	subject, htmlBody := reminderDeliveryContent(reminder, occurrence, current.LeadDays, locale, enableAltCalendar, contactName)
+	htmlBody += s.reminderActionLinks(current.ID, userID, locale)
 
 	sendErr := s.sendReminder(channel, subject, htmlBody)
 	if sendErr != nil {
@@ -52,7 +78,7 @@ func (s *ReminderSchedulerService) processOne(scheduled *models.ContactReminderS
 		}
 		return
 	}
-	if err := s.handleSuccess(current, channel, reminder, subject, htmlBody, time.Now()); err != nil {
+	if err := s.handleSuccess(current, channel, reminder, occurrenceOn, subject, htmlBody, time.Now()); err != nil {
 		log.Printf("[reminder-scheduler] Record successful delivery for scheduled reminder %d: %v", current.ID, err)
 	}
 }
@@ -128,28 +154,71 @@ func (s *ReminderSchedulerService) reminderContactName(reminder *models.ContactR
 	return contactName, nil
 }
 
-func reminderDeliveryContent(reminder *models.ContactReminder, scheduledAt time.Time, locale string, enableAltCalendar bool, contactName string) (string, string) {
-	date := formatReminderDate(reminder, scheduledAt, enableAltCalendar)
+// reminderDeliveryContent renders a delivery for the occurrence on
+// occurrenceAt; lead-time deliveries say how many days ahead it is.
+func reminderDeliveryContent(reminder *models.ContactReminder, occurrenceAt time.Time, leadDays int, locale string, enableAltCalendar bool, contactName string) (string, string) {
+	date := formatReminderDate(reminder, occurrenceAt, enableAltCalendar)
 	subject := i18n.Tt(locale, "reminder.subject", map[string]string{"label": reminder.Label})
+	if leadDays > 0 {
+		subject = i18n.Tt(locale, "reminder.subject_upcoming", map[string]string{"label": reminder.Label, "days": strconv.Itoa(leadDays)})
+	}
 	body := i18n.Tt(locale, "reminder.body", map[string]string{"label": reminder.Label, "contact": contactName, "date": date})
 	return subject, body
 }
 
+// reminderActionLinks renders the signed acknowledge/snooze links appended
+// to a delivery. It is empty when no action service is configured or a link
+// cannot be signed, so a signing problem never blocks the reminder itself.
+func (s *ReminderSchedulerService) reminderActionLinks(scheduleID uint, userID, locale string) string {
+	if s.actions == nil {
+		return ""
+	}
+	params := make(map[string]string, 3)
+	for key, action := range map[string]string{
+		"acknowledge": ReminderActionAcknowledge,
+		"snooze_day":  ReminderActionSnoozeDay,
+		"snooze_week": ReminderActionSnoozeWeek,
+	} {
+		link, err := s.actions.ActionURL(scheduleID, userID, action)
+		if err != nil {
+			log.Printf("[reminder-scheduler] Sign %s link for scheduled reminder %d: %v", action, scheduleID, err)
+			return ""
+		}
+		params[key] = link
+	}
+	return i18n.Tt(locale, "reminder.actions", params)
+}
+
 func (s *ReminderSchedulerService) sendReminder(channel *models.UserNotificationChannel, subject, body string) error {
+	return sendToNotificationChannel(s.mailer, s.sender, s.webPush, channel, subject, body)
+}
+
+// sendToNotificationChannel delivers one message over a user notification
+// channel, routing email channels to the mailer, Web Push channels to the
+// push sender and everything else to the shoutrrr-based sender.
+func sendToNotificationChannel(mailer Mailer, sender, webPush NotificationSender, channel *models.UserNotificationChannel, subject, body string) error {
 	switch channel.Type {
 	case "email":
-		return s.mailer.Send(channel.Content, subject, body)
+		if mailer == nil {
+			return fmt.Errorf("mailer is not configured for channel %d", channel.ID)
+		}
+		return mailer.Send(channel.Content, subject, body)
 	case "shoutrrr", "telegram", "ntfy", "gotify", "webhook":
-		if s.sender == nil {
+		if sender == nil {
 			return fmt.Errorf("notification sender is not configured for channel %d", channel.ID)
 		}
-		return s.sender.Send(channel.Content, subject, body)
+		return sender.Send(channel.Content, subject, body)
+	case webPushChannelType:
+		if webPush == nil {
+			return fmt.Errorf("web push sender is not configured for channel %d", channel.ID)
+		}
+		return webPush.Send(channel.Content, subject, body)
 	default:
 		return fmt.Errorf("unknown notification channel type %q", channel.Type)
 	}
 }
 
-func (s *ReminderSchedulerService) handleSuccess(scheduled *models.ContactReminderScheduled, channel *models.UserNotificationChannel, reminder *models.ContactReminder, subject, body string, now time.Time) error {
+func (s *ReminderSchedulerService) handleSuccess(scheduled *models.ContactReminderScheduled, channel *models.UserNotificationChannel, reminder *models.ContactReminder, occurrenceOn, subject, body string, now time.Time) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(&models.UserNotificationSent{UserNotificationChannelID: channel.ID, SentAt: now, SubjectLine: subject, Payload: &body}).Error; err != nil {
 			return fmt.Errorf("create success log: %w", err)
@@ -157,6 +226,14 @@ func (s *ReminderSchedulerService) handleSuccess(scheduled *models.ContactRemind
 		if err := tx.Model(scheduled).Where("triggered_at IS NULL").Update("triggered_at", now).Error; err != nil {
 			return fmt.Errorf("mark schedule triggered: %w", err)
 		}
+		if err := ensureReminderDeliveryState(tx, reminder.ID, *channel.UserID, occurrenceOn); err != nil {
+			return fmt.Errorf("create delivery state: %w", err)
+		}
+		if err := tx.Model(&models.ContactReminderDeliveryState{}).
+			Where("contact_reminder_id = ? AND user_id = ? AND occurrence_on = ?", reminder.ID, *channel.UserID, occurrenceOn).
+			Update("last_notified_at", now).Error; err != nil {
+			return fmt.Errorf("update delivery state: %w", err)
+		}
 		if channel.Fails > 0 {
 			if err := tx.Model(channel).Update("fails", 0).Error; err != nil {
 				return fmt.Errorf("reset channel failures: %w", err)
@@ -169,38 +246,68 @@ func (s *ReminderSchedulerService) handleSuccess(scheduled *models.ContactRemind
 	})
 }
 
-func (s *ReminderSchedulerService) handleFailure(scheduled *models.ContactReminderScheduled, channel *models.UserNotificationChannel, subject, body string, sendErr error, now time.Time) error {
+// handleSkipped retires a delivery for an occurrence that was already
+// acknowledged, keeping the recurring chain going without sending anything.
+func (s *ReminderSchedulerService) handleSkipped(scheduled *models.ContactReminderScheduled, channel *models.UserNotificationChannel, reminder *models.ContactReminder, now time.Time) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		errMsg := sendErr.Error()
-		if err := tx.Create(&models.UserNotificationSent{UserNotificationChannelID: channel.ID, SentAt: now, SubjectLine: subject, Payload: &body, Error: &errMsg}).Error; err != nil {
-			return fmt.Errorf("create failure log: %w", err)
-		}
-		newFails := channel.Fails + 1
-		updates := map[string]interface{}{"fails": newFails}
-		if newFails >= maxChannelFails {
-			updates["active"] = false
-		}
-		if err := tx.Model(channel).Updates(updates).Error; err != nil {
-			return fmt.Errorf("increment channel failures: %w", err)
+		result := tx.Model(scheduled).Where("triggered_at IS NULL").Update("triggered_at", now)
+		if result.Error != nil {
+			return fmt.Errorf("mark schedule triggered: %w", result.Error)
 		}
-		if newFails >= maxChannelFails {
-			log.Printf("[reminder-scheduler] Channel %d auto-disabled after %d failures", channel.ID, newFails)
+		if result.RowsAffected == 0 {
+			return nil
 		}
-		return nil
+		return rescheduleRecurringReminder(tx, scheduled, channel, reminder)
 	})
 }
 
+func (s *ReminderSchedulerService) handleFailure(scheduled *models.ContactReminderScheduled, channel *models.UserNotificationChannel, subject, body string, sendErr error, now time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return recordChannelFailure(tx, channel, subject, body, sendErr, now)
+	})
+}
+
+// recordChannelFailure logs a failed delivery and bumps the channel's
+// failure counter, disabling the channel once it reaches maxChannelFails.
+// A Web Push subscription the push service reports as gone is disabled
+// right away, since retrying it can never succeed.
+func recordChannelFailure(tx *gorm.DB, channel *models.UserNotificationChannel, subject, body string, sendErr error, now time.Time) error {
+	errMsg := sendErr.Error()
+	if err := tx.Create(&models.UserNotificationSent{UserNotificationChannelID: channel.ID, SentAt: now, SubjectLine: subject, Payload: &body, Error: &errMsg}).Error; err != nil {
+		return fmt.Errorf("create failure log: %w", err)
+	}
+	newFails := channel.Fails + 1
+	gone := errors.Is(sendErr, ErrWebPushSubscriptionGone)
+	updates := map[string]interface{}{"fails": newFails}
+	if newFails >= maxChannelFails || gone {
+		updates["active"] = false
+	}
+	if err := tx.Model(channel).Updates(updates).Error; err != nil {
+		return fmt.Errorf("increment channel failures: %w", err)
+	}
+	if gone {
+		log.Printf("[reminder-scheduler] Channel %d disabled: push subscription expired", channel.ID)
+	} else if newFails >= maxChannelFails {
+		log.Printf("[reminder-scheduler] Channel %d auto-disabled after %d failures", channel.ID, newFails)
+	}
+	return nil
+}
+
+// rescheduleRecurringReminder queues the same lead-time delivery for the
+// next occurrence. Snoozed deliveries are one-offs: the delivery they were
+// snoozed from already continued the chain.
 func rescheduleRecurringReminder(db *gorm.DB, scheduled *models.ContactReminderScheduled, channel *models.UserNotificationChannel, reminder *models.ContactReminder) error {
-	if reminder.Type == "one_time" {
+	if reminder.Type == "one_time" || scheduled.SnoozeOfID != nil {
 		return nil
 	}
 	location := userLocation(channel.User)
-	base := scheduled.ScheduledAt.In(location)
-	nextSchedule, ok := nextRecurringSchedule(reminder, channel.PreferredTime, base, location)
+	base := scheduled.ScheduledAt.In(location).AddDate(0, 0, scheduled.LeadDays)
+	nextOccurrence, ok := nextRecurringSchedule(reminder, channel.PreferredTime, base, location)
 	if !ok {
 		return nil
 	}
-	if err := db.Create(&models.ContactReminderScheduled{UserNotificationChannelID: channel.ID, ContactReminderID: reminder.ID, ScheduledAt: nextSchedule}).Error; err != nil {
+	nextSchedule := nextOccurrence.AddDate(0, 0, -scheduled.LeadDays)
+	if err := db.Create(&models.ContactReminderScheduled{UserNotificationChannelID: channel.ID, ContactReminderID: reminder.ID, ScheduledAt: nextSchedule, LeadDays: scheduled.LeadDays}).Error; err != nil {
 		return fmt.Errorf("create next schedule: %w", err)
 	}
 	return nil
@@ -75,6 +75,8 @@ func setupReminderSchedulerTest(t *testing.T) *reminderSchedulerTestContext {
 		contact_reminder_id INTEGER NOT NULL,
 		scheduled_at DATETIME NOT NULL,
 		triggered_at DATETIME,
+		lead_days INTEGER NOT NULL DEFAULT 0,
+		snooze_of_id INTEGER,
 		created_at DATETIME,
 		updated_at DATETIME
 	)`)
@@ -57,10 +57,17 @@ func scheduleReminderForVaultUsers(db *gorm.DB, reminder *models.ContactReminder
 	if err := db.Preload("User").Where("user_id IN ? AND active = ?", userIDs, true).Find(&channels).Error; err != nil {
 		return err
 	}
+	now := time.Now()
 	for index := range channels {
 		channel := &channels[index]
-		if err := db.Create(&models.ContactReminderScheduled{UserNotificationChannelID: channel.ID, ContactReminderID: reminder.ID, ScheduledAt: calcInitialSchedule(reminder, channel.PreferredTime, userLocation(channel.User))}).Error; err != nil {
-			return err
+		for _, leadDays := range reminderLeadDays(reminder) {
+			scheduledAt, ok := calcInitialLeadSchedule(reminder, channel.PreferredTime, userLocation(channel.User), leadDays, now)
+			if !ok {
+				continue
+			}
+			if err := db.Create(&models.ContactReminderScheduled{UserNotificationChannelID: channel.ID, ContactReminderID: reminder.ID, ScheduledAt: scheduledAt, LeadDays: leadDays}).Error; err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -7,7 +7,8 @@ import (
 )
 
 type ReportService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	geocoding *GeocodingService
 }
 
 func NewReportService(db *gorm.DB) *ReportService {
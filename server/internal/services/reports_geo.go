@@ -0,0 +1,330 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidGeoQuery = errors.New("invalid location query")
+	ErrPlaceNotFound   = errors.New("place not found")
+)
+
+const (
+	defaultNearbyRadiusKM = 50
+	maxNearbyRadiusKM     = 20000
+	earthRadiusKM         = 6371.0088
+	kmPerDegree           = earthRadiusKM * math.Pi / 180
+
+	defaultMapZoom = 2
+	maxMapZoom     = 20
+	// mapClusterDegrees is the width of a cluster cell at zoom 0, about 60
+	// pixels of the 256-pixel wide world. Each zoom level halves it.
+	mapClusterDegrees = 84.0
+)
+
+func (s *ReportService) SetGeocodingService(g *GeocodingService) {
+	s.geocoding = g
+}
+
+// geoBounds is a latitude/longitude box. A box crossing the antimeridian
+// has MinLng greater than MaxLng.
+type geoBounds struct {
+	MinLat, MinLng, MaxLat, MaxLng float64
+}
+
+func (b geoBounds) where(q *gorm.DB) *gorm.DB {
+	q = q.Where("addresses.latitude BETWEEN ? AND ?", b.MinLat, b.MaxLat)
+	switch {
+	case b.MinLng <= -180 && b.MaxLng >= 180:
+		return q
+	case b.MinLng <= b.MaxLng:
+		return q.Where("addresses.longitude BETWEEN ? AND ?", b.MinLng, b.MaxLng)
+	default:
+		return q.Where("(addresses.longitude >= ? OR addresses.longitude <= ?)", b.MinLng, b.MaxLng)
+	}
+}
+
+func (b geoBounds) center() dto.GeoPoint {
+	width := b.MaxLng - b.MinLng
+	if width < 0 {
+		width += 360
+	}
+	return dto.GeoPoint{Latitude: (b.MinLat + b.MaxLat) / 2, Longitude: wrapLongitude(b.MinLng + width/2)}
+}
+
+// radiusBounds is the box around a circle, for the database to narrow the
+// addresses down before distances are measured.
+func radiusBounds(center dto.GeoPoint, radiusKM float64) geoBounds {
+	latDelta := radiusKM / kmPerDegree
+	b := geoBounds{MinLat: center.Latitude - latDelta, MaxLat: center.Latitude + latDelta, MinLng: -180, MaxLng: 180}
+	if b.MinLat <= -90 || b.MaxLat >= 90 {
+		b.MinLat, b.MaxLat = math.Max(b.MinLat, -90), math.Min(b.MaxLat, 90)
+		return b
+	}
+	// Degrees of longitude are shortest at the latitude closest to a pole.
+	poleward := math.Max(math.Abs(b.MinLat), math.Abs(b.MaxLat))
+	lngDelta := latDelta / math.Cos(poleward*math.Pi/180)
+	if lngDelta >= 180 {
+		return b
+	}
+	b.MinLng, b.MaxLng = wrapLongitude(center.Longitude-lngDelta), wrapLongitude(center.Longitude+lngDelta)
+	return b
+}
+
+func wrapLongitude(lng float64) float64 {
+	for lng < -180 {
+		lng += 360
+	}
+	for lng > 180 {
+		lng -= 360
+	}
+	return lng
+}
+
+// distanceKM is the great-circle distance between two points.
+func distanceKM(a, b dto.GeoPoint) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKM * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func validGeoPoint(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// geoAddress is an address with coordinates and the contact living there.
+type geoAddress struct {
+	ContactID     string  `gorm:"column:contact_id"`
+	VaultID       string  `gorm:"column:vault_id"`
+	FirstName     *string `gorm:"column:first_name"`
+	LastName      *string `gorm:"column:last_name"`
+	MiddleName    *string `gorm:"column:middle_name"`
+	Nickname      *string `gorm:"column:nickname"`
+	MaidenName    *string `gorm:"column:maiden_name"`
+	Prefix        *string `gorm:"column:prefix"`
+	Suffix        *string `gorm:"column:suffix"`
+	AddressID     uint    `gorm:"column:address_id"`
+	City          *string `gorm:"column:city"`
+	Province      *string `gorm:"column:province"`
+	Country       *string `gorm:"column:country"`
+	Latitude      float64 `gorm:"column:latitude"`
+	Longitude     float64 `gorm:"column:longitude"`
+	IsPastAddress bool    `gorm:"column:is_past_address"`
+	contactName   string  `gorm:"-"`
+}
+
+func (a geoAddress) point() dto.GeoPoint {
+	return dto.GeoPoint{Latitude: a.Latitude, Longitude: a.Longitude}
+}
+
+// geoAddresses loads the addresses of a vault that have coordinates,
+// inside bounds when given. Past addresses are left out unless asked for.
+func (s *ReportService) geoAddresses(vaultID, userID string, includePast bool, bounds *geoBounds) ([]geoAddress, error) {
+	formatter, err := newContactNameFormatter(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	q := s.db.Model(&models.Address{}).
+		Select("contact_address.contact_id, contacts.vault_id, contacts.first_name, contacts.last_name, contacts.middle_name, contacts.nickname, contacts.maiden_name, contacts.prefix, contacts.suffix, addresses.id AS address_id, addresses.city, addresses.province, addresses.country, addresses.latitude, addresses.longitude, contact_address.is_past_address").
+		Joins("JOIN contact_address ON contact_address.address_id = addresses.id").
+		Joins("JOIN contacts ON contacts.id = contact_address.contact_id").
+		Where("addresses.vault_id = ? AND contacts.deleted_at IS NULL AND addresses.latitude IS NOT NULL AND addresses.longitude IS NOT NULL", vaultID)
+	if !includePast {
+		q = q.Where("contact_address.is_past_address = ?", false)
+	}
+	if bounds != nil {
+		q = bounds.where(q)
+	}
+	var rows []geoAddress
+	if err := q.Order("addresses.id ASC").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		r := &rows[i]
+		contact := models.Contact{VaultID: r.VaultID, FirstName: r.FirstName, LastName: r.LastName, MiddleName: r.MiddleName, Nickname: r.Nickname, MaidenName: r.MaidenName, Prefix: r.Prefix, Suffix: r.Suffix}
+		if r.contactName, err = formatter.format(&contact, ""); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// NearbyContacts finds the contacts living within a radius of a point or
+// place, or inside a bounding box, closest first. Each contact is listed
+// once, with their address closest to the center.
+func (s *ReportService) NearbyContacts(vaultID, userID string, q dto.NearbyContactsQuery) (*dto.NearbyContactsResponse, error) {
+	resp := &dto.NearbyContactsResponse{Contacts: []dto.NearbyContactItem{}}
+	var bounds geoBounds
+	radius := 0.0
+	switch {
+	case q.Place != "" || (q.Latitude != nil && q.Longitude != nil):
+		if q.Place != "" {
+			if s.geocoding == nil {
+				return nil, ErrGeocodingDisabled
+			}
+			result, err := s.geocoding.Lookup(q.Place)
+			if err != nil {
+				return nil, err
+			}
+			if result == nil {
+				return nil, ErrPlaceNotFound
+			}
+			resp.Center = dto.GeoPoint{Latitude: result.Latitude, Longitude: result.Longitude}
+		} else {
+			resp.Center = dto.GeoPoint{Latitude: *q.Latitude, Longitude: *q.Longitude}
+		}
+		radius = q.RadiusKM
+		if radius == 0 {
+			radius = defaultNearbyRadiusKM
+		}
+		if !validGeoPoint(resp.Center.Latitude, resp.Center.Longitude) || radius < 0 || radius > maxNearbyRadiusKM {
+			return nil, ErrInvalidGeoQuery
+		}
+		resp.RadiusKM = radius
+		bounds = radiusBounds(resp.Center, radius)
+	case q.MinLat != nil && q.MinLng != nil && q.MaxLat != nil && q.MaxLng != nil:
+		bounds = geoBounds{MinLat: *q.MinLat, MinLng: *q.MinLng, MaxLat: *q.MaxLat, MaxLng: *q.MaxLng}
+		if !validGeoPoint(bounds.MinLat, bounds.MinLng) || !validGeoPoint(bounds.MaxLat, bounds.MaxLng) || bounds.MinLat > bounds.MaxLat {
+			return nil, ErrInvalidGeoQuery
+		}
+		resp.Center = bounds.center()
+	default:
+		return nil, ErrInvalidGeoQuery
+	}
+
+	rows, err := s.geoAddresses(vaultID, userID, q.IncludePast, &bounds)
+	if err != nil {
+		return nil, err
+	}
+	closest := map[string]int{}
+	for _, r := range rows {
+		distance := distanceKM(resp.Center, r.point())
+		if radius > 0 && distance > radius {
+			continue
+		}
+		item := dto.NearbyContactItem{
+			ContactID:     r.ContactID,
+			ContactName:   r.contactName,
+			AddressID:     r.AddressID,
+			City:          ptrToStr(r.City),
+			Province:      ptrToStr(r.Province),
+			Country:       ptrToStr(r.Country),
+			Latitude:      r.Latitude,
+			Longitude:     r.Longitude,
+			IsPastAddress: r.IsPastAddress,
+			DistanceKM:    math.Round(distance*10) / 10,
+		}
+		if i, ok := closest[r.ContactID]; ok {
+			if distance < distanceKM(resp.Center, dto.GeoPoint{Latitude: resp.Contacts[i].Latitude, Longitude: resp.Contacts[i].Longitude}) {
+				resp.Contacts[i] = item
+			}
+			continue
+		}
+		closest[r.ContactID] = len(resp.Contacts)
+		resp.Contacts = append(resp.Contacts, item)
+	}
+	sort.SliceStable(resp.Contacts, func(i, j int) bool {
+		if resp.Contacts[i].DistanceKM != resp.Contacts[j].DistanceKM {
+			return resp.Contacts[i].DistanceKM < resp.Contacts[j].DistanceKM
+		}
+		return resp.Contacts[i].ContactName < resp.Contacts[j].ContactName
+	})
+	return resp, nil
+}
+
+// MapReport returns the addresses of a vault as GeoJSON points for a map,
+// clustering the ones that would overlap at the zoom level.
+func (s *ReportService) MapReport(vaultID, userID string, q dto.MapQuery) (*dto.GeoJSONFeatureCollection, error) {
+	zoom := defaultMapZoom
+	if q.Zoom != nil {
+		zoom = *q.Zoom
+	}
+	if zoom < 0 || zoom > maxMapZoom {
+		return nil, ErrInvalidGeoQuery
+	}
+	var bounds *geoBounds
+	if q.MinLat != nil || q.MinLng != nil || q.MaxLat != nil || q.MaxLng != nil {
+		if q.MinLat == nil || q.MinLng == nil || q.MaxLat == nil || q.MaxLng == nil {
+			return nil, ErrInvalidGeoQuery
+		}
+		bounds = &geoBounds{MinLat: *q.MinLat, MinLng: *q.MinLng, MaxLat: *q.MaxLat, MaxLng: *q.MaxLng}
+		if !validGeoPoint(bounds.MinLat, bounds.MinLng) || !validGeoPoint(bounds.MaxLat, bounds.MaxLng) || bounds.MinLat > bounds.MaxLat {
+			return nil, ErrInvalidGeoQuery
+		}
+	}
+	rows, err := s.geoAddresses(vaultID, userID, q.IncludePast, bounds)
+	if err != nil {
+		return nil, err
+	}
+
+	cell := mapClusterDegrees / math.Pow(2, float64(zoom))
+	type cellKey struct{ row, col int }
+	var keys []cellKey
+	cells := map[cellKey][]geoAddress{}
+	for _, r := range rows {
+		key := cellKey{int(math.Floor((r.Latitude + 90) / cell)), int(math.Floor((r.Longitude + 180) / cell))}
+		if _, ok := cells[key]; !ok {
+			keys = append(keys, key)
+		}
+		cells[key] = append(cells[key], r)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].row != keys[j].row {
+			return keys[i].row < keys[j].row
+		}
+		return keys[i].col < keys[j].col
+	})
+
+	result := &dto.GeoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]dto.GeoJSONFeature, 0, len(keys))}
+	for _, key := range keys {
+		members := cells[key]
+		if len(members) == 1 {
+			r := members[0]
+			result.Features = append(result.Features, dto.GeoJSONFeature{
+				Type:     "Feature",
+				Geometry: dto.GeoJSONPoint{Type: "Point", Coordinates: [2]float64{r.Longitude, r.Latitude}},
+				Properties: dto.MapFeatureProperties{
+					PointCount:    1,
+					ContactIDs:    []string{r.ContactID},
+					ContactID:     r.ContactID,
+					ContactName:   r.contactName,
+					AddressID:     r.AddressID,
+					City:          ptrToStr(r.City),
+					Country:       ptrToStr(r.Country),
+					IsPastAddress: r.IsPastAddress,
+				},
+			})
+			continue
+		}
+		var sumLat, sumLng float64
+		bbox := []float64{180, 90, -180, -90}
+		seen := map[string]bool{}
+		contactIDs := []string{}
+		for _, r := range members {
+			sumLat += r.Latitude
+			sumLng += r.Longitude
+			bbox[0], bbox[1] = math.Min(bbox[0], r.Longitude), math.Min(bbox[1], r.Latitude)
+			bbox[2], bbox[3] = math.Max(bbox[2], r.Longitude), math.Max(bbox[3], r.Latitude)
+			if !seen[r.ContactID] {
+				seen[r.ContactID] = true
+				contactIDs = append(contactIDs, r.ContactID)
+			}
+		}
+		n := float64(len(members))
+		result.Features = append(result.Features, dto.GeoJSONFeature{
+			Type:       "Feature",
+			Geometry:   dto.GeoJSONPoint{Type: "Point", Coordinates: [2]float64{sumLng / n, sumLat / n}},
+			BBox:       bbox,
+			Properties: dto.MapFeatureProperties{Cluster: true, PointCount: len(members), ContactIDs: contactIDs},
+		})
+	}
+	return result, nil
+}
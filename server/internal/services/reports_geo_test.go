@@ -0,0 +1,211 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/testutil"
+)
+
+type geoReportFixture struct {
+	svc      *ReportService
+	vaultID  string
+	userID   string
+	contacts map[string]string
+}
+
+// setupGeoReportTest creates Ana in Lisbon, Ben in Porto, Cai who lives in
+// Tokyo and used to live in Sintra, and Dee in Fiji next to the antimeridian.
+func setupGeoReportTest(t *testing.T) geoReportFixture {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	resp, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Test",
+		LastName:  "User",
+		Email:     "geo-report-test@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	vault, err := NewVaultService(db).CreateVault(resp.User.AccountID, resp.User.ID, dto.CreateVaultRequest{Name: "Test Vault"}, "en")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+	f := geoReportFixture{svc: NewReportService(db), vaultID: vault.ID, userID: resp.User.ID, contacts: map[string]string{}}
+	addresses := NewAddressService(db)
+	for _, a := range []struct {
+		name, city string
+		lat, lng   float64
+		past       bool
+	}{
+		{"Ana", "Lisbon", 38.7223, -9.1393, false},
+		{"Ben", "Porto", 41.1579, -8.6291, false},
+		{"Cai", "Tokyo", 35.6762, 139.6503, false},
+		{"Cai", "Sintra", 38.8029, -9.3817, true},
+		{"Dee", "Suva", -18.1248, 179.9, false},
+	} {
+		id, ok := f.contacts[a.name]
+		if !ok {
+			contact, err := NewContactService(db).CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: a.name})
+			if err != nil {
+				t.Fatalf("CreateContact failed: %v", err)
+			}
+			id = contact.ID
+			f.contacts[a.name] = id
+		}
+		lat, lng := a.lat, a.lng
+		if _, err := addresses.Create(id, vault.ID, dto.CreateAddressRequest{City: a.city, Latitude: &lat, Longitude: &lng, IsPastAddress: a.past}); err != nil {
+			t.Fatalf("Create address failed: %v", err)
+		}
+	}
+	// An address that was never geocoded stays off the map.
+	if _, err := addresses.Create(f.contacts["Ana"], vault.ID, dto.CreateAddressRequest{City: "Faro"}); err != nil {
+		t.Fatalf("Create address failed: %v", err)
+	}
+	return f
+}
+
+func nearbyNames(resp *dto.NearbyContactsResponse) []string {
+	names := []string{}
+	for _, c := range resp.Contacts {
+		names = append(names, c.ContactName)
+	}
+	return names
+}
+
+func TestNearbyContactsWithinRadius(t *testing.T) {
+	f := setupGeoReportTest(t)
+	lat, lng := 38.7223, -9.1393
+	resp, err := f.svc.NearbyContacts(f.vaultID, f.userID, dto.NearbyContactsQuery{Latitude: &lat, Longitude: &lng})
+	if err != nil {
+		t.Fatalf("NearbyContacts failed: %v", err)
+	}
+	if names := nearbyNames(resp); len(names) != 1 || names[0] != "Ana" || resp.RadiusKM != 50 {
+		t.Fatalf("expected only Ana within the default 50 km, got %v (radius %v)", names, resp.RadiusKM)
+	}
+
+	resp, err = f.svc.NearbyContacts(f.vaultID, f.userID, dto.NearbyContactsQuery{Latitude: &lat, Longitude: &lng, RadiusKM: 300, IncludePast: true})
+	if err != nil {
+		t.Fatalf("NearbyContacts failed: %v", err)
+	}
+	names := nearbyNames(resp)
+	if len(names) != 3 || names[0] != "Ana" || names[1] != "Cai" || names[2] != "Ben" {
+		t.Fatalf("expected Ana, Cai and Ben closest first, got %v", names)
+	}
+	if c := resp.Contacts[1]; c.City != "Sintra" || !c.IsPastAddress || c.DistanceKM < 20 || c.DistanceKM > 25 {
+		t.Errorf("unexpected past address match %+v", c)
+	}
+	if d := resp.Contacts[2].DistanceKM; d < 270 || d > 280 {
+		t.Errorf("expected Porto about 274 km away, got %v", d)
+	}
+}
+
+func TestNearbyContactsBoundingBox(t *testing.T) {
+	f := setupGeoReportTest(t)
+	// A box from Fiji across the antimeridian to Samoa.
+	minLat, minLng, maxLat, maxLng := -20.0, 170.0, -10.0, -170.0
+	resp, err := f.svc.NearbyContacts(f.vaultID, f.userID, dto.NearbyContactsQuery{MinLat: &minLat, MinLng: &minLng, MaxLat: &maxLat, MaxLng: &maxLng})
+	if err != nil {
+		t.Fatalf("NearbyContacts failed: %v", err)
+	}
+	if names := nearbyNames(resp); len(names) != 1 || names[0] != "Dee" {
+		t.Fatalf("expected Dee across the antimeridian, got %v", names)
+	}
+	if resp.Center.Latitude != -15 || resp.Center.Longitude != 180 {
+		t.Errorf("unexpected center %+v", resp.Center)
+	}
+
+	lat, lng := -18.0, -179.9
+	resp, err = f.svc.NearbyContacts(f.vaultID, f.userID, dto.NearbyContactsQuery{Latitude: &lat, Longitude: &lng})
+	if err != nil {
+		t.Fatalf("NearbyContacts failed: %v", err)
+	}
+	if names := nearbyNames(resp); len(names) != 1 || names[0] != "Dee" {
+		t.Errorf("expected the radius to wrap around the antimeridian, got %v", names)
+	}
+}
+
+func TestNearbyContactsPlace(t *testing.T) {
+	f := setupGeoReportTest(t)
+	if _, err := f.svc.NearbyContacts(f.vaultID, f.userID, dto.NearbyContactsQuery{Place: "Porto"}); !errors.Is(err, ErrGeocodingDisabled) {
+		t.Fatalf("expected ErrGeocodingDisabled, got %v", err)
+	}
+
+	geocoder := &stubGeocoder{result: &GeocodingResult{Latitude: 41.15, Longitude: -8.61}}
+	geocoding := NewGeocodingService(testutil.SetupTestDB(t))
+	geocoding.SetGeocoder("stub", geocoder)
+	geocoding.interval = 0
+	f.svc.SetGeocodingService(geocoding)
+	resp, err := f.svc.NearbyContacts(f.vaultID, f.userID, dto.NearbyContactsQuery{Place: "Porto, Portugal", RadiusKM: 10})
+	if err != nil {
+		t.Fatalf("NearbyContacts failed: %v", err)
+	}
+	if names := nearbyNames(resp); len(names) != 1 || names[0] != "Ben" {
+		t.Errorf("expected Ben in Porto, got %v", names)
+	}
+
+	geocoder.result = nil
+	if _, err := f.svc.NearbyContacts(f.vaultID, f.userID, dto.NearbyContactsQuery{Place: "Atlantis"}); !errors.Is(err, ErrPlaceNotFound) {
+		t.Errorf("expected ErrPlaceNotFound, got %v", err)
+	}
+}
+
+func TestNearbyContactsRejectsInvalidQuery(t *testing.T) {
+	f := setupGeoReportTest(t)
+	lat, lng := 91.0, 0.0
+	for _, q := range []dto.NearbyContactsQuery{
+		{},
+		{Latitude: &lat, Longitude: &lng},
+		{Latitude: &lng, Longitude: &lng, RadiusKM: -1},
+		{MinLat: &lat, MinLng: &lng, MaxLat: &lng, MaxLng: &lng},
+	} {
+		if _, err := f.svc.NearbyContacts(f.vaultID, f.userID, q); !errors.Is(err, ErrInvalidGeoQuery) {
+			t.Errorf("expected ErrInvalidGeoQuery for %+v, got %v", q, err)
+		}
+	}
+}
+
+func TestMapReportClusters(t *testing.T) {
+	f := setupGeoReportTest(t)
+	zoom := 2
+	resp, err := f.svc.MapReport(f.vaultID, f.userID, dto.MapQuery{Zoom: &zoom, IncludePast: true})
+	if err != nil {
+		t.Fatalf("MapReport failed: %v", err)
+	}
+	if resp.Type != "FeatureCollection" || len(resp.Features) != 3 {
+		t.Fatalf("expected Portugal clustered apart from Tokyo and Suva, got %+v", resp.Features)
+	}
+	var cluster *dto.GeoJSONFeature
+	for i := range resp.Features {
+		if resp.Features[i].Properties.Cluster {
+			cluster = &resp.Features[i]
+		}
+	}
+	if cluster == nil || cluster.Properties.PointCount != 3 || len(cluster.Properties.ContactIDs) != 3 || len(cluster.BBox) != 4 {
+		t.Fatalf("unexpected Portugal cluster %+v", cluster)
+	}
+	if lng := cluster.Geometry.Coordinates[0]; lng > -8.6 || lng < -9.4 {
+		t.Errorf("expected the cluster centered in Portugal, got %v", cluster.Geometry.Coordinates)
+	}
+
+	zoom = 10
+	resp, err = f.svc.MapReport(f.vaultID, f.userID, dto.MapQuery{Zoom: &zoom})
+	if err != nil {
+		t.Fatalf("MapReport failed: %v", err)
+	}
+	if len(resp.Features) != 4 {
+		t.Fatalf("expected the 4 current addresses apart when zoomed in, got %d", len(resp.Features))
+	}
+	for _, feature := range resp.Features {
+		if p := feature.Properties; p.Cluster || p.PointCount != 1 || p.ContactName == "" || p.IsPastAddress {
+			t.Errorf("unexpected point %+v", p)
+		}
+	}
+
+	zoom = 21
+	if _, err := f.svc.MapReport(f.vaultID, f.userID, dto.MapQuery{Zoom: &zoom}); !errors.Is(err, ErrInvalidGeoQuery) {
+		t.Errorf("expected ErrInvalidGeoQuery, got %v", err)
+	}
+}
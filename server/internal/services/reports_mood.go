@@ -0,0 +1,603 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+)
+
+var ErrInvalidMoodAnalyticsQuery = errors.New("invalid mood analytics query")
+
+const (
+	defaultMoodWindow = 7
+	maxMoodWindow     = 365
+	// minCorrelationSamples is the fewest days a correlation is computed on.
+	minCorrelationSamples = 3
+)
+
+// moodEntry is a mood event with its score and the local day it was rated.
+type moodEntry struct {
+	ratedAt     time.Time
+	parameterID uint
+	label       string
+	score       int
+	sleep       *int
+	note        string
+}
+
+// moodDay aggregates the entries of one local day.
+type moodDay struct {
+	date       time.Time
+	entries    int
+	scoreSum   int
+	sleepSum   int
+	sleepCount int
+	counts     map[uint]int
+}
+
+func (d *moodDay) add(e *moodEntry) {
+	d.entries++
+	d.scoreSum += e.score
+	if e.sleep != nil {
+		d.sleepSum += *e.sleep
+		d.sleepCount++
+	}
+	d.counts[e.parameterID]++
+}
+
+func (d *moodDay) merge(o *moodDay) {
+	d.entries += o.entries
+	d.scoreSum += o.scoreSum
+	d.sleepSum += o.sleepSum
+	d.sleepCount += o.sleepCount
+	for id, n := range o.counts {
+		d.counts[id] += n
+	}
+}
+
+func (d *moodDay) averageScore() *float64 {
+	if d.entries == 0 {
+		return nil
+	}
+	v := roundMood(float64(d.scoreSum) / float64(d.entries))
+	return &v
+}
+
+func (d *moodDay) averageSleep() *float64 {
+	if d.sleepCount == 0 {
+		return nil
+	}
+	v := roundMood(float64(d.sleepSum) / float64(d.sleepCount))
+	return &v
+}
+
+// moodData is what the analytics, the feed and the CSV export are built
+// from: the scale of the vault and the entries of the user in the range.
+type moodData struct {
+	user    models.User
+	loc     *time.Location
+	from    time.Time
+	to      time.Time
+	scale   []dto.MoodScaleItem
+	entries []moodEntry
+}
+
+// end is the instant the range stops, the local midnight after To.
+func (m *moodData) end() time.Time {
+	return m.to.AddDate(0, 0, 1)
+}
+
+func (s *ReportService) loadMoodData(vaultID, userID, from, to string) (*moodData, error) {
+	m := &moodData{}
+	if err := s.db.Where("id = ?", userID).First(&m.user).Error; err != nil {
+		return nil, err
+	}
+	m.loc = userLocation(&m.user)
+	now := time.Now().In(m.loc)
+	m.to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, m.loc)
+	if to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, m.loc)
+		if err != nil {
+			return nil, ErrInvalidMoodAnalyticsQuery
+		}
+		m.to = t
+	}
+	if from != "" {
+		f, err := time.ParseInLocation("2006-01-02", from, m.loc)
+		if err != nil || f.After(m.to) {
+			return nil, ErrInvalidMoodAnalyticsQuery
+		}
+		m.from = f
+	}
+
+	var params []models.MoodTrackingParameter
+	if err := s.db.Where("vault_id = ?", vaultID).Find(&params).Error; err != nil {
+		return nil, err
+	}
+	// Parameters are listed best first, so the first one gets the highest score.
+	sort.SliceStable(params, func(i, j int) bool {
+		pi, pj := params[i].Position, params[j].Position
+		if (pi == nil) != (pj == nil) {
+			return pj == nil
+		}
+		if pi != nil && *pi != *pj {
+			return *pi < *pj
+		}
+		return params[i].ID < params[j].ID
+	})
+	byID := make(map[uint]int, len(params))
+	m.scale = make([]dto.MoodScaleItem, len(params))
+	for i, p := range params {
+		byID[p.ID] = i
+		m.scale[i] = dto.MoodScaleItem{
+			ParameterID: p.ID,
+			Label:       ptrToStr(p.Label),
+			HexColor:    p.HexColor,
+			Score:       len(params) - i,
+		}
+	}
+
+	// Timestamps may be stored with any offset, so the database narrows the
+	// range down with a day to spare and the exact range is applied here.
+	query := s.db.Where("vault_id = ? AND user_id = ? AND rated_at < ?", vaultID, userID, m.end().AddDate(0, 0, 1).UTC())
+	if !m.from.IsZero() {
+		query = query.Where("rated_at >= ?", m.from.AddDate(0, 0, -1).UTC())
+	}
+	var events []models.MoodTrackingEvent
+	if err := query.Order("rated_at ASC, id ASC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	m.entries = make([]moodEntry, 0, len(events))
+	for _, e := range events {
+		i, ok := byID[e.MoodTrackingParameterID]
+		if !ok || !e.RatedAt.Before(m.end()) || (!m.from.IsZero() && e.RatedAt.Before(m.from)) {
+			continue
+		}
+		m.scale[i].Count++
+		m.entries = append(m.entries, moodEntry{
+			ratedAt:     e.RatedAt.In(m.loc),
+			parameterID: e.MoodTrackingParameterID,
+			label:       m.scale[i].Label,
+			score:       m.scale[i].Score,
+			sleep:       e.NumberOfHoursSlept,
+			note:        ptrToStr(e.Note),
+		})
+	}
+	if m.from.IsZero() {
+		m.from = m.to
+		if len(m.entries) > 0 {
+			m.from = localDay(m.entries[0].ratedAt)
+		}
+	}
+	return m, nil
+}
+
+// days groups the entries by local day, oldest first.
+func (m *moodData) days() []*moodDay {
+	var days []*moodDay
+	for i := range m.entries {
+		e := &m.entries[i]
+		date := localDay(e.ratedAt)
+		if len(days) == 0 || !days[len(days)-1].date.Equal(date) {
+			days = append(days, &moodDay{date: date, counts: map[uint]int{}})
+		}
+		days[len(days)-1].add(e)
+	}
+	return days
+}
+
+func (m *moodData) distribution(counts map[uint]int) []dto.MoodDistributionItem {
+	items := make([]dto.MoodDistributionItem, len(m.scale))
+	for i, p := range m.scale {
+		items[i] = dto.MoodDistributionItem{ParameterID: p.ParameterID, Count: counts[p.ParameterID]}
+	}
+	return items
+}
+
+// MoodAnalytics aggregates the mood entries of the user in a vault by week
+// or month, by day with a rolling average and by weekday, counts their
+// streaks and correlates the daily mood with sleep, journal metrics and
+// life metrics.
+func (s *ReportService) MoodAnalytics(vaultID, userID string, q dto.MoodAnalyticsQuery) (*dto.MoodAnalyticsResponse, error) {
+	period := q.Period
+	if period == "" {
+		period = "week"
+	}
+	if period != "week" && period != "month" {
+		return nil, ErrInvalidMoodAnalyticsQuery
+	}
+	window, err := moodWindow(q.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := s.loadMoodData(vaultID, userID, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	days := m.days()
+	weekStart := normalizedWeekStart(m.user.WeekStart)
+
+	resp := &dto.MoodAnalyticsResponse{
+		From:     m.from.Format("2006-01-02"),
+		To:       m.to.Format("2006-01-02"),
+		Timezone: m.loc.String(),
+		Period:   period,
+		Window:   window,
+		Scale:    m.scale,
+		Summary:  moodSummary(days, m.to),
+		Days:     moodDays(days, window),
+		Periods:  []dto.MoodPeriodItem{},
+	}
+
+	var current *moodDay
+	var currentEnd time.Time
+	for _, d := range days {
+		start, end := moodPeriod(d.date, period, weekStart)
+		if current == nil || !current.date.Equal(start) {
+			if current != nil {
+				resp.Periods = append(resp.Periods, m.periodItem(current, currentEnd))
+			}
+			current = &moodDay{date: start, counts: map[uint]int{}}
+			currentEnd = end
+		}
+		current.merge(d)
+	}
+	if current != nil {
+		resp.Periods = append(resp.Periods, m.periodItem(current, currentEnd))
+	}
+
+	var weekdays [7]moodDay
+	for i := range weekdays {
+		weekdays[i].counts = map[uint]int{}
+	}
+	for _, d := range days {
+		weekdays[d.date.Weekday()].merge(d)
+	}
+	first := time.Sunday
+	if weekStart == "monday" {
+		first = time.Monday
+	}
+	resp.Weekdays = make([]dto.MoodWeekdayItem, 7)
+	for i := range resp.Weekdays {
+		wd := (first + time.Weekday(i)) % 7
+		isoWeekday := int(wd)
+		if wd == time.Sunday {
+			isoWeekday = 7
+		}
+		resp.Weekdays[i] = dto.MoodWeekdayItem{
+			Weekday:      isoWeekday,
+			Name:         strings.ToLower(wd.String()),
+			Entries:      weekdays[wd].entries,
+			AverageScore: weekdays[wd].averageScore(),
+			Distribution: m.distribution(weekdays[wd].counts),
+		}
+	}
+
+	resp.Correlations, err = s.moodCorrelations(vaultID, userID, m, days)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *moodData) periodItem(p *moodDay, end time.Time) dto.MoodPeriodItem {
+	return dto.MoodPeriodItem{
+		Start:        p.date.Format("2006-01-02"),
+		End:          end.Format("2006-01-02"),
+		Entries:      p.entries,
+		AverageScore: p.averageScore(),
+		AverageSleep: p.averageSleep(),
+		Distribution: m.distribution(p.counts),
+	}
+}
+
+// moodPeriod returns the first and last day of the week or month of a day.
+func moodPeriod(day time.Time, period, weekStart string) (time.Time, time.Time) {
+	if period == "month" {
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+		return start, start.AddDate(0, 1, -1)
+	}
+	offset := int(day.Weekday())
+	if weekStart == "monday" {
+		offset = (offset + 6) % 7
+	}
+	start := day.AddDate(0, 0, -offset)
+	return start, start.AddDate(0, 0, 6)
+}
+
+func moodSummary(days []*moodDay, to time.Time) dto.MoodSummary {
+	total := moodDay{counts: map[uint]int{}}
+	summary := dto.MoodSummary{DaysLogged: len(days)}
+	run := 0
+	for i, d := range days {
+		total.merge(d)
+		if i > 0 && days[i-1].date.AddDate(0, 0, 1).Equal(d.date) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > summary.LongestStreak {
+			summary.LongestStreak = run
+		}
+	}
+	// Today may not be logged yet, so a streak ending yesterday still counts.
+	if len(days) > 0 {
+		last := days[len(days)-1].date
+		if last.Equal(to) || last.AddDate(0, 0, 1).Equal(to) {
+			summary.CurrentStreak = run
+		}
+	}
+	summary.Entries = total.entries
+	summary.AverageScore = total.averageScore()
+	summary.AverageSleep = total.averageSleep()
+	return summary
+}
+
+// moodDays lists the logged days with the averages of the window of days
+// ending on each of them.
+func moodDays(days []*moodDay, window int) []dto.MoodDayItem {
+	items := make([]dto.MoodDayItem, len(days))
+	start := 0
+	for i, d := range days {
+		for days[start].date.AddDate(0, 0, window).Compare(d.date) <= 0 {
+			start++
+		}
+		rolling := moodDay{counts: map[uint]int{}}
+		for _, w := range days[start : i+1] {
+			rolling.merge(w)
+		}
+		items[i] = dto.MoodDayItem{
+			Date:         d.date.Format("2006-01-02"),
+			Entries:      d.entries,
+			AverageScore: *d.averageScore(),
+			AverageSleep: d.averageSleep(),
+			RollingScore: *rolling.averageScore(),
+			RollingSleep: rolling.averageSleep(),
+		}
+	}
+	return items
+}
+
+// moodCorrelations pairs the daily average score with the hours slept, the
+// daily sum of each journal metric and the daily increments of each life
+// metric. Days without a journal metric value are left out of its pairs;
+// days without increments count as zero.
+// Values are only paired with logged days, so the spare day the queries
+// load on either side of the range is never used.
+func (s *ReportService) moodCorrelations(vaultID, userID string, m *moodData, days []*moodDay) ([]dto.MoodCorrelationItem, error) {
+	var sleepX, sleepY []float64
+	for _, d := range days {
+		if sleep := d.averageSleep(); sleep != nil {
+			sleepX = append(sleepX, *sleep)
+			sleepY = append(sleepY, *d.averageScore())
+		}
+	}
+	items := []dto.MoodCorrelationItem{{
+		Source:      "sleep",
+		Samples:     len(sleepX),
+		Coefficient: pearsonCorrelation(sleepX, sleepY),
+	}}
+
+	type journalMetricRow struct {
+		ID          uint
+		Label       string
+		JournalName string
+	}
+	var metrics []journalMetricRow
+	if err := s.db.Model(&models.JournalMetric{}).
+		Select("journal_metrics.id, journal_metrics.label, journals.name AS journal_name").
+		Joins("JOIN journals ON journals.id = journal_metrics.journal_id").
+		Where("journals.vault_id = ?", vaultID).
+		Order("journals.name ASC, journal_metrics.id ASC").
+		Scan(&metrics).Error; err != nil {
+		return nil, err
+	}
+	if len(metrics) > 0 {
+		type postMetricRow struct {
+			JournalMetricID uint
+			Value           int
+			WrittenAt       time.Time
+		}
+		var values []postMetricRow
+		if err := s.db.Model(&models.PostMetric{}).
+			Select("post_metrics.journal_metric_id, post_metrics.value, posts.written_at").
+			Joins("JOIN posts ON posts.id = post_metrics.post_id").
+			Joins("JOIN journals ON journals.id = posts.journal_id").
+			Where("journals.vault_id = ? AND posts.written_at >= ? AND posts.written_at < ?", vaultID, m.from.AddDate(0, 0, -1).UTC(), m.end().AddDate(0, 0, 1).UTC()).
+			Scan(&values).Error; err != nil {
+			return nil, err
+		}
+		sums := map[uint]map[string]float64{}
+		for _, v := range values {
+			if sums[v.JournalMetricID] == nil {
+				sums[v.JournalMetricID] = map[string]float64{}
+			}
+			sums[v.JournalMetricID][v.WrittenAt.In(m.loc).Format("2006-01-02")] += float64(v.Value)
+		}
+		for _, metric := range metrics {
+			var xs, ys []float64
+			for _, d := range days {
+				if sum, ok := sums[metric.ID][d.date.Format("2006-01-02")]; ok {
+					xs = append(xs, sum)
+					ys = append(ys, *d.averageScore())
+				}
+			}
+			id := metric.ID
+			items = append(items, dto.MoodCorrelationItem{
+				Source:      "post_metric",
+				ID:          &id,
+				Label:       metric.JournalName + ": " + metric.Label,
+				Samples:     len(xs),
+				Coefficient: pearsonCorrelation(xs, ys),
+			})
+		}
+	}
+
+	var lifeMetrics []models.LifeMetric
+	if err := s.db.Where("vault_id = ?", vaultID).Order("id ASC").Find(&lifeMetrics).Error; err != nil {
+		return nil, err
+	}
+	if len(lifeMetrics) > 0 {
+		ids := make([]uint, len(lifeMetrics))
+		for i, lm := range lifeMetrics {
+			ids[i] = lm.ID
+		}
+		var increments []models.ContactLifeMetric
+		if err := s.db.Select("life_metric_id, created_at").
+			Where("life_metric_id IN ? AND user_id = ? AND created_at >= ? AND created_at < ?", ids, userID, m.from.AddDate(0, 0, -1).UTC(), m.end().AddDate(0, 0, 1).UTC()).
+			Find(&increments).Error; err != nil {
+			return nil, err
+		}
+		counts := map[uint]map[string]float64{}
+		for _, inc := range increments {
+			if counts[inc.LifeMetricID] == nil {
+				counts[inc.LifeMetricID] = map[string]float64{}
+			}
+			counts[inc.LifeMetricID][inc.CreatedAt.In(m.loc).Format("2006-01-02")]++
+		}
+		for _, lm := range lifeMetrics {
+			xs := make([]float64, len(days))
+			ys := make([]float64, len(days))
+			for i, d := range days {
+				xs[i] = counts[lm.ID][d.date.Format("2006-01-02")]
+				ys[i] = *d.averageScore()
+			}
+			id := lm.ID
+			items = append(items, dto.MoodCorrelationItem{
+				Source:      "life_metric",
+				ID:          &id,
+				Label:       lm.Label,
+				Samples:     len(xs),
+				Coefficient: pearsonCorrelation(xs, ys),
+			})
+		}
+	}
+	return items, nil
+}
+
+// MoodFeed returns the logged days of the mood analytics as a flat series
+// for dashboards such as Grafana.
+func (s *ReportService) MoodFeed(vaultID, userID string, q dto.MoodAnalyticsQuery) ([]dto.MoodFeedItem, error) {
+	window, err := moodWindow(q.Window)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.loadMoodData(vaultID, userID, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	days := m.days()
+	items := make([]dto.MoodFeedItem, len(days))
+	for i, d := range moodDays(days, window) {
+		items[i] = dto.MoodFeedItem{
+			Time:         days[i].date.Format(time.RFC3339),
+			Entries:      d.Entries,
+			Score:        d.AverageScore,
+			Sleep:        d.AverageSleep,
+			RollingScore: d.RollingScore,
+			RollingSleep: d.RollingSleep,
+		}
+	}
+	return items, nil
+}
+
+// ExportMoodCSV writes the mood entries of the user in a vault as CSV, one
+// row per entry, with the time in the user's timezone.
+func (s *ReportService) ExportMoodCSV(vaultID, userID string, q dto.MoodAnalyticsQuery) ([]byte, error) {
+	m, err := s.loadMoodData(vaultID, userID, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"rated_at", "date", "weekday", "mood", "score", "hours_slept", "note"}); err != nil {
+		return nil, err
+	}
+	for _, e := range m.entries {
+		sleep := ""
+		if e.sleep != nil {
+			sleep = strconv.Itoa(*e.sleep)
+		}
+		if err := w.Write([]string{
+			e.ratedAt.Format(time.RFC3339),
+			e.ratedAt.Format("2006-01-02"),
+			strings.ToLower(e.ratedAt.Weekday().String()),
+			spreadsheetSafe(e.label),
+			strconv.Itoa(e.score),
+			sleep,
+			spreadsheetSafe(e.note),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// spreadsheetSafe keeps a spreadsheet from running user text as a formula
+// by prefixing cells that start like one with an apostrophe.
+func spreadsheetSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func moodWindow(window int) (int, error) {
+	if window == 0 {
+		return defaultMoodWindow, nil
+	}
+	if window < 1 || window > maxMoodWindow {
+		return 0, ErrInvalidMoodAnalyticsQuery
+	}
+	return window, nil
+}
+
+// localDay is local midnight of the day t falls on, in t's location.
+func localDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// pearsonCorrelation returns nil with too few samples or when either series
+// is constant.
+func pearsonCorrelation(xs, ys []float64) *float64 {
+	n := len(xs)
+	if n < minCorrelationSamples || n != len(ys) {
+		return nil
+	}
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+	var sxy, sxx, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return nil
+	}
+	r := math.Round(sxy/math.Sqrt(sxx*syy)*1000) / 1000
+	return &r
+}
+
+func roundMood(v float64) float64 {
+	return math.Round(v*100) / 100
+}
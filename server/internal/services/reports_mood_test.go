@@ -0,0 +1,322 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+type moodAnalyticsFixture struct {
+	db        *gorm.DB
+	svc       *ReportService
+	vaultID   string
+	userID    string
+	contactID string
+	params    []models.MoodTrackingParameter
+}
+
+func setupMoodAnalyticsTest(t *testing.T) *moodAnalyticsFixture {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	resp, err := NewAuthService(db, testutil.TestJWTConfig()).Register(dto.RegisterRequest{
+		FirstName: "Mood", LastName: "Analyst", Email: "mood-analytics@example.com", Password: "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	vault, err := NewVaultService(db).CreateVault(resp.User.AccountID, resp.User.ID, dto.CreateVaultRequest{Name: "Mood Vault"}, "en")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+	contact, err := NewContactService(db).CreateContact(vault.ID, resp.User.ID, dto.CreateContactRequest{FirstName: "Runner"})
+	if err != nil {
+		t.Fatalf("CreateContact failed: %v", err)
+	}
+	if err := db.Model(&models.User{}).Where("id = ?", resp.User.ID).Update("week_start", "monday").Error; err != nil {
+		t.Fatalf("Set week start failed: %v", err)
+	}
+	var params []models.MoodTrackingParameter
+	if err := db.Where("vault_id = ?", vault.ID).Order("position ASC").Find(&params).Error; err != nil {
+		t.Fatalf("Load mood parameters failed: %v", err)
+	}
+	if len(params) != 5 {
+		t.Fatalf("Expected 5 seeded mood parameters, got %d", len(params))
+	}
+	return &moodAnalyticsFixture{db: db, svc: NewReportService(db), vaultID: vault.ID, userID: resp.User.ID, contactID: contact.ID, params: params}
+}
+
+// mood records an entry; rank 0 is the best parameter (score 5).
+func (f *moodAnalyticsFixture) mood(t *testing.T, userID string, ratedAt string, rank int, sleep *int) {
+	t.Helper()
+	at, err := time.Parse(time.RFC3339, ratedAt)
+	if err != nil {
+		t.Fatalf("parse %s: %v", ratedAt, err)
+	}
+	event := models.MoodTrackingEvent{
+		VaultID:                 f.vaultID,
+		UserID:                  &userID,
+		MoodTrackingParameterID: f.params[rank].ID,
+		RatedAt:                 at,
+		NumberOfHoursSlept:      sleep,
+	}
+	if err := f.db.Create(&event).Error; err != nil {
+		t.Fatalf("Create mood event failed: %v", err)
+	}
+}
+
+func (f *moodAnalyticsFixture) seedMonth(t *testing.T) {
+	t.Helper()
+	f.mood(t, f.userID, "2026-03-02T09:00:00Z", 0, intPtr(8))
+	f.mood(t, f.userID, "2026-03-03T09:00:00Z", 1, intPtr(7))
+	f.mood(t, f.userID, "2026-03-03T20:00:00Z", 2, nil)
+	f.mood(t, f.userID, "2026-03-04T09:00:00Z", 3, intPtr(5))
+	f.mood(t, f.userID, "2026-03-10T09:00:00Z", 4, intPtr(4))
+	// Outside the range and from another user.
+	f.mood(t, f.userID, "2026-03-20T09:00:00Z", 0, intPtr(9))
+	f.mood(t, "someone-else", "2026-03-02T10:00:00Z", 4, intPtr(2))
+}
+
+func TestMoodAnalytics(t *testing.T) {
+	f := setupMoodAnalyticsTest(t)
+	f.seedMonth(t)
+
+	result, err := f.svc.MoodAnalytics(f.vaultID, f.userID, dto.MoodAnalyticsQuery{From: "2026-03-01", To: "2026-03-11"})
+	if err != nil {
+		t.Fatalf("MoodAnalytics failed: %v", err)
+	}
+	if result.Period != "week" || result.Window != 7 || result.Timezone != "UTC" {
+		t.Errorf("Expected week, 7 and UTC defaults, got %s, %d and %s", result.Period, result.Window, result.Timezone)
+	}
+	if len(result.Scale) != 5 || result.Scale[0].ParameterID != f.params[0].ID || result.Scale[0].Score != 5 || result.Scale[4].Score != 1 {
+		t.Fatalf("Expected scale from 5 for the first parameter to 1 for the last, got %+v", result.Scale)
+	}
+	if result.Scale[0].Count != 1 {
+		t.Errorf("Expected 1 entry of the best mood in range, got %d", result.Scale[0].Count)
+	}
+
+	s := result.Summary
+	if s.Entries != 5 || s.DaysLogged != 4 {
+		t.Errorf("Expected 5 entries on 4 days, got %d on %d", s.Entries, s.DaysLogged)
+	}
+	if s.AverageScore == nil || *s.AverageScore != 3 || s.AverageSleep == nil || *s.AverageSleep != 6 {
+		t.Errorf("Expected averages 3 and 6, got %v and %v", s.AverageScore, s.AverageSleep)
+	}
+	if s.LongestStreak != 3 || s.CurrentStreak != 1 {
+		t.Errorf("Expected longest streak 3 and current streak 1, got %d and %d", s.LongestStreak, s.CurrentStreak)
+	}
+
+	if len(result.Days) != 4 {
+		t.Fatalf("Expected 4 days, got %d", len(result.Days))
+	}
+	day := result.Days[1]
+	if day.Date != "2026-03-03" || day.Entries != 2 || day.AverageScore != 3.5 || day.RollingScore != 4 {
+		t.Errorf("Unexpected day %+v", day)
+	}
+	last := result.Days[3]
+	if last.RollingScore != 1.5 || last.RollingSleep == nil || *last.RollingSleep != 4.5 {
+		t.Errorf("Expected the rolling window of 2026-03-10 to cover 2026-03-04 only, got %+v", last)
+	}
+
+	if len(result.Periods) != 2 {
+		t.Fatalf("Expected 2 weeks, got %d", len(result.Periods))
+	}
+	week := result.Periods[0]
+	if week.Start != "2026-03-02" || week.End != "2026-03-08" || week.Entries != 4 || *week.AverageScore != 3.5 {
+		t.Errorf("Unexpected first week %+v", week)
+	}
+	if week.Distribution[0].Count != 1 || week.Distribution[4].Count != 0 {
+		t.Errorf("Unexpected first week distribution %+v", week.Distribution)
+	}
+
+	if result.Weekdays[0].Name != "monday" || result.Weekdays[0].Weekday != 1 || result.Weekdays[6].Weekday != 7 {
+		t.Errorf("Expected weekdays from monday to sunday, got %+v", result.Weekdays)
+	}
+	tuesday := result.Weekdays[1]
+	if tuesday.Entries != 3 || tuesday.AverageScore == nil || *tuesday.AverageScore != 2.67 {
+		t.Errorf("Unexpected tuesday %+v", tuesday)
+	}
+
+	sleep := result.Correlations[0]
+	if sleep.Source != "sleep" || sleep.Samples != 4 || sleep.Coefficient == nil || *sleep.Coefficient < 0.9 {
+		t.Errorf("Expected a strong positive sleep correlation on 4 days, got %+v", sleep)
+	}
+
+	monthly, err := f.svc.MoodAnalytics(f.vaultID, f.userID, dto.MoodAnalyticsQuery{From: "2026-03-01", To: "2026-03-11", Period: "month"})
+	if err != nil {
+		t.Fatalf("MoodAnalytics by month failed: %v", err)
+	}
+	if len(monthly.Periods) != 1 || monthly.Periods[0].Start != "2026-03-01" || monthly.Periods[0].End != "2026-03-31" || monthly.Periods[0].Entries != 5 {
+		t.Errorf("Unexpected months %+v", monthly.Periods)
+	}
+}
+
+func TestMoodAnalyticsCorrelatesMetrics(t *testing.T) {
+	f := setupMoodAnalyticsTest(t)
+	f.seedMonth(t)
+
+	journal := models.Journal{VaultID: f.vaultID, Name: "Training"}
+	if err := f.db.Create(&journal).Error; err != nil {
+		t.Fatalf("Create journal failed: %v", err)
+	}
+	metric := models.JournalMetric{JournalID: journal.ID, Label: "Kilometers"}
+	if err := f.db.Create(&metric).Error; err != nil {
+		t.Fatalf("Create journal metric failed: %v", err)
+	}
+	for day, km := range map[string]int{"2026-03-02": 10, "2026-03-03": 6, "2026-03-04": 2, "2026-03-10": 1} {
+		writtenAt, _ := time.Parse("2006-01-02 15:04", day+" 18:00")
+		post := models.Post{JournalID: journal.ID, WrittenAt: writtenAt}
+		if err := f.db.Create(&post).Error; err != nil {
+			t.Fatalf("Create post failed: %v", err)
+		}
+		if err := f.db.Create(&models.PostMetric{PostID: post.ID, JournalMetricID: metric.ID, Value: km}).Error; err != nil {
+			t.Fatalf("Create post metric failed: %v", err)
+		}
+	}
+
+	lifeMetric := models.LifeMetric{VaultID: f.vaultID, Label: "Headache"}
+	if err := f.db.Create(&lifeMetric).Error; err != nil {
+		t.Fatalf("Create life metric failed: %v", err)
+	}
+	increments := []struct {
+		userID string
+		at     string
+	}{
+		{f.userID, "2026-03-10T08:00:00Z"},
+		{f.userID, "2026-03-10T12:00:00Z"},
+		{f.userID, "2026-03-04T12:00:00Z"},
+		{"someone-else", "2026-03-02T12:00:00Z"},
+	}
+	for _, inc := range increments {
+		at, _ := time.Parse(time.RFC3339, inc.at)
+		row := models.ContactLifeMetric{ContactID: f.contactID, LifeMetricID: lifeMetric.ID, UserID: inc.userID, CreatedAt: at}
+		if err := f.db.Create(&row).Error; err != nil {
+			t.Fatalf("Create life metric increment failed: %v", err)
+		}
+	}
+
+	result, err := f.svc.MoodAnalytics(f.vaultID, f.userID, dto.MoodAnalyticsQuery{From: "2026-03-01", To: "2026-03-11"})
+	if err != nil {
+		t.Fatalf("MoodAnalytics failed: %v", err)
+	}
+	if len(result.Correlations) != 3 {
+		t.Fatalf("Expected sleep, journal metric and life metric correlations, got %+v", result.Correlations)
+	}
+	post := result.Correlations[1]
+	if post.Source != "post_metric" || post.ID == nil || *post.ID != metric.ID || post.Label != "Training: Kilometers" || post.Samples != 4 {
+		t.Errorf("Unexpected journal metric correlation %+v", post)
+	}
+	if post.Coefficient == nil || *post.Coefficient < 0.9 {
+		t.Errorf("Expected a strong positive journal metric correlation, got %v", post.Coefficient)
+	}
+	life := result.Correlations[2]
+	if life.Source != "life_metric" || life.Label != "Headache" || life.Samples != 4 {
+		t.Errorf("Unexpected life metric correlation %+v", life)
+	}
+	if life.Coefficient == nil || *life.Coefficient >= 0 {
+		t.Errorf("Expected a negative life metric correlation, got %v", life.Coefficient)
+	}
+}
+
+func TestMoodAnalyticsUsesUserTimezone(t *testing.T) {
+	f := setupMoodAnalyticsTest(t)
+	if err := f.db.Model(&models.User{}).Where("id = ?", f.userID).Update("timezone", "Asia/Shanghai").Error; err != nil {
+		t.Fatalf("Set timezone failed: %v", err)
+	}
+	// 20:00 UTC is 04:00 on the next day in Shanghai.
+	f.mood(t, f.userID, "2026-03-09T20:00:00Z", 0, nil)
+	f.mood(t, f.userID, "2026-03-10T20:00:00Z", 1, nil)
+
+	result, err := f.svc.MoodAnalytics(f.vaultID, f.userID, dto.MoodAnalyticsQuery{To: "2026-03-11"})
+	if err != nil {
+		t.Fatalf("MoodAnalytics failed: %v", err)
+	}
+	if result.From != "2026-03-10" || len(result.Days) != 2 || result.Days[0].Date != "2026-03-10" || result.Days[1].Date != "2026-03-11" {
+		t.Errorf("Expected local days 2026-03-10 and 2026-03-11, got from %s and %+v", result.From, result.Days)
+	}
+	if result.Summary.CurrentStreak != 2 {
+		t.Errorf("Expected a current streak of 2, got %d", result.Summary.CurrentStreak)
+	}
+
+	feed, err := f.svc.MoodFeed(f.vaultID, f.userID, dto.MoodAnalyticsQuery{From: "2026-03-11", To: "2026-03-11"})
+	if err != nil {
+		t.Fatalf("MoodFeed failed: %v", err)
+	}
+	if len(feed) != 1 || feed[0].Time != "2026-03-11T00:00:00+08:00" || feed[0].Score != 4 {
+		t.Errorf("Unexpected feed %+v", feed)
+	}
+}
+
+func TestMoodAnalyticsInvalidQuery(t *testing.T) {
+	f := setupMoodAnalyticsTest(t)
+	for _, q := range []dto.MoodAnalyticsQuery{
+		{Period: "year"},
+		{Window: 400},
+		{From: "2026-03-12", To: "2026-03-11"},
+		{To: "11/03/2026"},
+	} {
+		if _, err := f.svc.MoodAnalytics(f.vaultID, f.userID, q); !errors.Is(err, ErrInvalidMoodAnalyticsQuery) {
+			t.Errorf("Expected ErrInvalidMoodAnalyticsQuery for %+v, got %v", q, err)
+		}
+	}
+
+	result, err := f.svc.MoodAnalytics(f.vaultID, f.userID, dto.MoodAnalyticsQuery{})
+	if err != nil {
+		t.Fatalf("MoodAnalytics without entries failed: %v", err)
+	}
+	if result.Summary.Entries != 0 || result.Summary.AverageScore != nil || len(result.Days) != 0 || len(result.Periods) != 0 {
+		t.Errorf("Expected empty analytics, got %+v", result)
+	}
+	if result.Correlations[0].Coefficient != nil {
+		t.Errorf("Expected no sleep correlation without entries, got %v", *result.Correlations[0].Coefficient)
+	}
+}
+
+func TestExportMoodCSV(t *testing.T) {
+	f := setupMoodAnalyticsTest(t)
+	f.seedMonth(t)
+	note := "Long walk, then rain"
+	if err := f.db.Model(&models.MoodTrackingEvent{}).
+		Where("rated_at = ?", time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)).
+		Update("note", note).Error; err != nil {
+		t.Fatalf("Set note failed: %v", err)
+	}
+
+	data, err := f.svc.ExportMoodCSV(f.vaultID, f.userID, dto.MoodAnalyticsQuery{From: "2026-03-01", To: "2026-03-11"})
+	if err != nil {
+		t.Fatalf("ExportMoodCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected a header and 5 rows, got %d lines:\n%s", len(lines), data)
+	}
+	if lines[0] != "rated_at,date,weekday,mood,score,hours_slept,note" {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	want := "2026-03-02T09:00:00Z,2026-03-02,monday," + *f.params[0].Label + ",5,8,\"Long walk, then rain\""
+	if lines[1] != want {
+		t.Errorf("Expected first row %q, got %q", want, lines[1])
+	}
+	if !strings.HasSuffix(lines[3], ",3,,") {
+		t.Errorf("Expected an empty hours_slept and note, got %q", lines[3])
+	}
+
+	// A note that reads like a formula is not run by spreadsheets.
+	if err := f.db.Model(&models.MoodTrackingEvent{}).
+		Where("rated_at = ?", time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)).
+		Update("note", "=HYPERLINK(\"http://example.com\")").Error; err != nil {
+		t.Fatalf("Set note failed: %v", err)
+	}
+	data, err = f.svc.ExportMoodCSV(f.vaultID, f.userID, dto.MoodAnalyticsQuery{From: "2026-03-01", To: "2026-03-11"})
+	if err != nil {
+		t.Fatalf("ExportMoodCSV failed: %v", err)
+	}
+	if row := strings.Split(string(data), "\n")[1]; !strings.HasSuffix(row, `,"'=HYPERLINK(""http://example.com"")"`) {
+		t.Errorf("Expected the note to be escaped, got %q", row)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/naiba/bonds/internal/dto"
 	"github.com/naiba/bonds/internal/models"
 	"github.com/naiba/bonds/internal/search"
 	"gorm.io/gorm"
@@ -167,33 +168,57 @@ func (s *SearchService) DeleteNote(id uint) error {
 
 // RebuildIndex clears the search index and re-indexes all contacts and notes.
 func (s *SearchService) RebuildIndex(db *gorm.DB) (int, int, error) {
-	if err := s.engine.Rebuild(); err != nil {
-		return 0, 0, fmt.Errorf("failed to rebuild index: %w", err)
-	}
+	return s.RebuildIndexWithProgress(db, nil)
+}
 
+// RebuildIndexWithProgress is RebuildIndex reporting one item per contact
+// and note. A cancelled rebuild leaves the index partly built.
+func (s *SearchService) RebuildIndexWithProgress(db *gorm.DB, progress JobProgress) (int, int, error) {
 	var contacts []models.Contact
 	if err := db.Find(&contacts).Error; err != nil {
 		return 0, 0, fmt.Errorf("failed to load contacts: %w", err)
 	}
+	var notes []models.Note
+	if err := db.Find(&notes).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to load notes: %w", err)
+	}
+	progressSetTotal(progress, len(contacts)+len(notes))
+
+	if err := s.engine.Rebuild(); err != nil {
+		return 0, 0, fmt.Errorf("failed to rebuild index: %w", err)
+	}
+
 	contactCount := 0
 	for i := range contacts {
+		if progressCancelled(progress) {
+			return contactCount, 0, ErrJobCancelled
+		}
 		if err := s.IndexContact(&contacts[i]); err != nil {
 			return contactCount, 0, fmt.Errorf("failed to index contact %s: %w", contacts[i].ID, err)
 		}
 		contactCount++
+		progressAdvance(progress, 1)
 	}
 
-	var notes []models.Note
-	if err := db.Find(&notes).Error; err != nil {
-		return contactCount, 0, fmt.Errorf("failed to load notes: %w", err)
-	}
 	noteCount := 0
 	for i := range notes {
+		if progressCancelled(progress) {
+			return contactCount, noteCount, ErrJobCancelled
+		}
 		if err := s.IndexNote(&notes[i]); err != nil {
 			return contactCount, noteCount, fmt.Errorf("failed to index note %d: %w", notes[i].ID, err)
 		}
 		noteCount++
+		progressAdvance(progress, 1)
 	}
 
 	return contactCount, noteCount, nil
 }
+
+// RebuildIndexJob returns the function that runs search_rebuild jobs.
+func (s *SearchService) RebuildIndexJob(db *gorm.DB) JobFunc {
+	return func(run *JobRun) (interface{}, []string, error) {
+		contactCount, noteCount, err := s.RebuildIndexWithProgress(db, run)
+		return dto.RebuildSearchIndexResponse{ContactsIndexed: contactCount, NotesIndexed: noteCount}, nil, err
+	}
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+const refreshTokenBytes = 32
+
+var (
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrSessionRevoked      = errors.New("session has been revoked or has expired")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused means an already redeemed refresh token was
+	// presented again. Only one party can hold the legitimate successor, so
+	// the session is revoked for both.
+	ErrRefreshTokenReused = errors.New("refresh token has already been used")
+	// ErrRefreshTokenRequired is returned when an access token of a session
+	// asks the legacy refresh endpoint to renew it.
+	ErrRefreshTokenRequired = errors.New("sessions are renewed with their refresh token")
+)
+
+// SessionClient describes the device a session request came from.
+type SessionClient struct {
+	UserAgent string
+	IPAddress string
+}
+
+// sessionTTL is how long a session survives without being refreshed. It is
+// never shorter than an access token, which would outlive its session.
+func (s *AuthService) sessionTTL() time.Duration {
+	hrs := s.cfg.RefreshHrs
+	if hrs < s.cfg.ExpiryHrs {
+		hrs = s.cfg.ExpiryHrs
+	}
+	return time.Duration(hrs) * time.Hour
+}
+
+// startSession creates a session and its first refresh token. Sessions of
+// the user that ended earlier are purged on the way, so the table does not
+// grow without bound.
+func (s *AuthService) startSession(userID string) (*models.UserSession, string, error) {
+	now := time.Now()
+	session := models.UserSession{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.sessionTTL()),
+	}
+	var refreshToken string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteUserSessions(tx, "user_id = ? AND expires_at < ?", userID, now); err != nil {
+			return err
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		var err error
+		refreshToken, err = issueRefreshToken(tx, session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &session, refreshToken, nil
+}
+
+// RefreshSession redeems a refresh token: the token is marked used, the
+// session is extended and a new access token and refresh token are issued.
+func (s *AuthService) RefreshSession(rawRefreshToken string, client SessionClient) (*dto.AuthResponse, error) {
+	var stored models.UserSessionRefreshToken
+	if err := s.db.Where("token_hash = ?", hashToken(rawRefreshToken)).First(&stored).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	var session models.UserSession
+	if err := s.db.Where("id = ?", stored.SessionID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Claim the token atomically so that two concurrent redemptions cannot
+	// both succeed; the loser is treated as a replay.
+	claim := s.db.Model(&models.UserSessionRefreshToken{}).
+		Where("id = ? AND used_at IS NULL", stored.ID).
+		Update("used_at", now)
+	if claim.Error != nil {
+		return nil, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		if err := s.db.Model(&session).Update("revoked_at", now).Error; err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", session.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	session.ExpiresAt = now.Add(s.sessionTTL())
+	var refreshToken string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"expires_at":   session.ExpiresAt,
+			"last_used_at": now,
+			"user_agent":   client.UserAgent,
+			"ip_address":   client.IPAddress,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		refreshToken, err = issueRefreshToken(tx, session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.sessionAuthResponse(&user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp.RefreshToken = refreshToken
+	resp.RefreshTokenExpiresAt = &session.ExpiresAt
+	return resp, nil
+}
+
+func issueRefreshToken(tx *gorm.DB, sessionID string) (string, error) {
+	b := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	raw := hex.EncodeToString(b)
+	if err := tx.Create(&models.UserSessionRefreshToken{
+		SessionID: sessionID,
+		TokenHash: hashToken(raw),
+	}).Error; err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// deleteUserSessions removes the sessions matching the condition together
+// with their refresh tokens.
+func deleteUserSessions(tx *gorm.DB, query string, args ...interface{}) error {
+	sessionIDs := tx.Model(&models.UserSession{}).Select("id").Where(query, args...)
+	if err := tx.Where("session_id IN (?)", sessionIDs).Delete(&models.UserSessionRefreshToken{}).Error; err != nil {
+		return err
+	}
+	return tx.Where(query, args...).Delete(&models.UserSession{}).Error
+}
+
+// SessionService lists and revokes a user's own sessions.
+type SessionService struct {
+	db *gorm.DB
+}
+
+func NewSessionService(db *gorm.DB) *SessionService {
+	return &SessionService{db: db}
+}
+
+// List returns the user's active sessions, most recently used first.
+// currentSessionID marks the session the request was made with.
+func (s *SessionService) List(userID, currentSessionID string) ([]dto.UserSessionResponse, error) {
+	var sessions []models.UserSession
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	result := make([]dto.UserSessionResponse, len(sessions))
+	for i, session := range sessions {
+		result[i] = dto.UserSessionResponse{
+			ID:         session.ID,
+			Device:     describeUserAgent(session.UserAgent),
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			Current:    session.ID == currentSessionID,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			CreatedAt:  session.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+// Revoke ends one active session of the user.
+func (s *SessionService) Revoke(userID, sessionID string) error {
+	result := s.db.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, time.Now()).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAll ends every active session of the user except keepSessionID,
+// which may be empty to log out everywhere.
+func (s *SessionService) RevokeAll(userID, keepSessionID string) (int64, error) {
+	query := s.db.Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now())
+	if keepSessionID != "" {
+		query = query.Where("id <> ?", keepSessionID)
+	}
+	result := query.Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// describeUserAgent turns a user agent into a short "Browser on OS" label.
+// It only needs to be good enough for a person to recognise their devices.
+func describeUserAgent(ua string) string {
+	if ua == "" {
+		return "Unknown device"
+	}
+	browser := firstUserAgentMatch(ua, [][2]string{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	})
+	os := firstUserAgentMatch(ua, [][2]string{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Mac OS X", "macOS"},
+		{"Windows", "Windows"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	})
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+	if i := strings.IndexAny(ua, " /"); i > 0 {
+		return ua[:i]
+	}
+	return ua
+}
+
+func firstUserAgentMatch(ua string, candidates [][2]string) string {
+	for _, c := range candidates {
+		if strings.Contains(ua, c[0]) {
+			return c[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/middleware"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+)
+
+func setupSessionTest(t *testing.T) (*AuthService, *dto.AuthResponse) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	svc := NewAuthService(db, testutil.TestJWTConfig())
+	resp, err := svc.Register(dto.RegisterRequest{
+		FirstName: "Session",
+		LastName:  "User",
+		Email:     "session-test@example.com",
+		Password:  "password123",
+	}, "en")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	return svc, resp
+}
+
+func sessionIDOf(t *testing.T, svc *AuthService, token string) string {
+	t.Helper()
+	claims, err := middleware.ParseJWTClaims(token, []byte(svc.cfg.Secret))
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	return claims.ID
+}
+
+func TestLoginStartsSession(t *testing.T) {
+	svc, registered := setupSessionTest(t)
+	resp, err := svc.Login(dto.LoginRequest{Email: "session-test@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if resp.RefreshToken == "" || resp.RefreshTokenExpiresAt == nil {
+		t.Fatal("expected a refresh token")
+	}
+
+	sessionID := sessionIDOf(t, svc, resp.Token)
+	if sessionID == "" || sessionID == sessionIDOf(t, svc, registered.Token) {
+		t.Fatalf("expected a new session per sign-in, got %q", sessionID)
+	}
+	var session models.UserSession
+	if err := svc.db.First(&session, "id = ?", sessionID).Error; err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	if session.UserID != resp.User.ID || !session.ExpiresAt.Equal(*resp.RefreshTokenExpiresAt) {
+		t.Errorf("unexpected session: %+v", session)
+	}
+}
+
+func TestRefreshSessionRotatesAndDetectsReuse(t *testing.T) {
+	svc, registered := setupSessionTest(t)
+	sessionID := sessionIDOf(t, svc, registered.Token)
+	client := SessionClient{UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0", IPAddress: "203.0.113.7"}
+
+	rotated, err := svc.RefreshSession(registered.RefreshToken, client)
+	if err != nil {
+		t.Fatalf("RefreshSession failed: %v", err)
+	}
+	if rotated.RefreshToken == "" || rotated.RefreshToken == registered.RefreshToken {
+		t.Fatal("expected a new refresh token")
+	}
+	if sessionIDOf(t, svc, rotated.Token) != sessionID {
+		t.Fatal("expected the refreshed access token to keep the session")
+	}
+	var session models.UserSession
+	svc.db.First(&session, "id = ?", sessionID)
+	if session.UserAgent != client.UserAgent || session.IPAddress != client.IPAddress {
+		t.Errorf("expected the refreshing device to be recorded, got %+v", session)
+	}
+
+	if _, err := svc.RefreshSession(registered.RefreshToken, client); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+	svc.db.First(&session, "id = ?", sessionID)
+	if session.RevokedAt == nil {
+		t.Fatal("expected reuse to revoke the session")
+	}
+	if _, err := svc.RefreshSession(rotated.RefreshToken, client); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected the successor token to die with the session, got %v", err)
+	}
+	if _, err := svc.RefreshSession("unknown", client); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected ErrInvalidRefreshToken, got %v", err)
+	}
+}
+
+func TestRefreshTokenOnlyStartsSessionsForSessionlessTokens(t *testing.T) {
+	svc, registered := setupSessionTest(t)
+	claims, err := middleware.ParseJWTClaims(registered.Token, []byte(svc.cfg.Secret))
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+
+	// Tokens of a session are renewed with their refresh token only.
+	if _, err := svc.RefreshToken(claims); !errors.Is(err, ErrRefreshTokenRequired) {
+		t.Fatalf("expected ErrRefreshTokenRequired, got %v", err)
+	}
+
+	// A token issued before sessions existed starts one.
+	legacy := *claims
+	legacy.ID = ""
+	started, err := svc.RefreshToken(&legacy)
+	if err != nil {
+		t.Fatalf("RefreshToken of a session-less token failed: %v", err)
+	}
+	if id := sessionIDOf(t, svc, started.Token); id == "" || id == claims.ID || started.RefreshToken == "" {
+		t.Fatalf("expected a new session with a refresh token, got session %q", id)
+	}
+}
+
+func TestSessionServiceListAndRevoke(t *testing.T) {
+	svc, registered := setupSessionTest(t)
+	second, err := svc.Login(dto.LoginRequest{Email: "session-test@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	third, err := svc.Login(dto.LoginRequest{Email: "session-test@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	userID := registered.User.ID
+	current := sessionIDOf(t, svc, registered.Token)
+	sessions := NewSessionService(svc.db)
+
+	list, err := sessions.List(userID, current)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("expected 3 sessions, got %d", len(list))
+	}
+	for _, s := range list {
+		if s.Current != (s.ID == current) {
+			t.Errorf("session %s: unexpected current flag %v", s.ID, s.Current)
+		}
+	}
+
+	if err := sessions.Revoke(userID, sessionIDOf(t, svc, second.Token)); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if err := sessions.Revoke(userID, sessionIDOf(t, svc, second.Token)); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound for a revoked session, got %v", err)
+	}
+	if err := sessions.Revoke("someone-else", sessionIDOf(t, svc, third.Token)); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound for another user's session, got %v", err)
+	}
+
+	revoked, err := sessions.RevokeAll(userID, current)
+	if err != nil || revoked != 1 {
+		t.Fatalf("expected to revoke 1 other session, got %d (%v)", revoked, err)
+	}
+	list, _ = sessions.List(userID, current)
+	if len(list) != 1 || list[0].ID != current {
+		t.Fatalf("expected only the current session to remain, got %+v", list)
+	}
+}
+
+func TestStartSessionPurgesEndedSessions(t *testing.T) {
+	svc, registered := setupSessionTest(t)
+	old := sessionIDOf(t, svc, registered.Token)
+	svc.db.Model(&models.UserSession{}).Where("id = ?", old).Update("expires_at", time.Now().Add(-time.Hour))
+
+	if _, err := svc.Login(dto.LoginRequest{Email: "session-test@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	var sessions, tokens int64
+	svc.db.Model(&models.UserSession{}).Where("id = ?", old).Count(&sessions)
+	svc.db.Model(&models.UserSessionRefreshToken{}).Where("session_id = ?", old).Count(&tokens)
+	if sessions != 0 || tokens != 0 {
+		t.Errorf("expected the expired session and its refresh tokens to be purged, got %d/%d", sessions, tokens)
+	}
+}
+
+func TestDescribeUserAgent(t *testing.T) {
+	cases := map[string]string{
+		"": "Unknown device",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15":    "Safari on macOS",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36 Edg/126.0": "Edge on Windows",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Mobile Safari/537.36":     "Chrome on Android",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0":                                                "Firefox on Linux",
+		"BondsSync/1.2": "BondsSync",
+		"curl/8.5.0":    "curl",
+	}
+	for ua, want := range cases {
+		if got := describeUserAgent(ua); got != want {
+			t.Errorf("describeUserAgent(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
@@ -13,6 +13,7 @@ var ErrTaskNotFound = errors.New("task not found")
 var ErrInvalidTaskStatus = errors.New("invalid task status")
 var ErrInvalidParentTask = errors.New("invalid parent task")
 var ErrTaskHasSubTasks = errors.New("task has sub-tasks")
+var ErrTaskAssigneeNotInVault = errors.New("task assignee is not a member of the vault")
 
 type TaskService struct {
 	db           *gorm.DB
@@ -37,6 +38,7 @@ func (s *TaskService) List(contactID, vaultID, userID string) ([]dto.TaskRespons
 	if err := s.db.
 		Joins("JOIN task_contacts tc ON tc.contact_task_id = contact_tasks.id").
 		Where("tc.contact_id = ? AND contact_tasks.vault_id = ?", contactID, vaultID).
+		Scopes(currentTaskOccurrenceScope).
 		Order("contact_tasks.position ASC, contact_tasks.created_at DESC").
 		Find(&tasks).Error; err != nil {
 		return nil, err
@@ -85,10 +87,16 @@ func (s *TaskService) Create(contactID, vaultID, authorID string, req dto.Create
 		DueAt:        req.DueAt,
 	}
 	applyTaskCalendarFields(&task, req.CalendarType, req.OriginalDay, req.OriginalMonth, req.OriginalYear)
+	if err := ApplyTaskRecurrence(&task, req.RecurrenceRule); err != nil {
+		return nil, err
+	}
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(&task).Error; err != nil {
 			return err
 		}
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeTask, task.ID, models.VaultChangeCreated); err != nil {
+			return err
+		}
 		return replaceTaskAssigneesLocked(tx, task.ID, extras)
 	})
 	if err != nil {
@@ -133,6 +141,9 @@ func (s *TaskService) Update(id uint, contactID, vaultID string, req dto.UpdateT
 	task.Description = strPtrOrNil(req.Description)
 	task.DueAt = req.DueAt
 	applyTaskCalendarFields(&task, req.CalendarType, req.OriginalDay, req.OriginalMonth, req.OriginalYear)
+	if err := ApplyTaskRecurrence(&task, req.RecurrenceRule); err != nil {
+		return nil, err
+	}
 	if parentPatch.Present {
 		task.ParentTaskID = parentPatch.Ptr()
 	}
@@ -144,6 +155,9 @@ func (s *TaskService) Update(id uint, contactID, vaultID string, req dto.UpdateT
 		if err := tx.Save(&task).Error; err != nil {
 			return err
 		}
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeTask, task.ID, models.VaultChangeUpdated); err != nil {
+			return err
+		}
 		if req.ContactIDs == nil {
 			return nil
 		}
@@ -178,6 +192,7 @@ func (s *TaskService) ToggleCompleted(id uint, contactID, vaultID, userID string
 		}
 		return nil, err
 	}
+	wasCompleted := task.Completed
 	task.Completed = !task.Completed
 	if task.Completed {
 		now := time.Now()
@@ -189,7 +204,17 @@ func (s *TaskService) ToggleCompleted(id uint, contactID, vaultID, userID string
 			task.Status = models.TaskStatusTodo
 		}
 	}
-	if err := s.db.Save(&task).Error; err != nil {
+	// Completing a recurring task spawns its next occurrence in the same
+	// transaction, so the series never has zero open occurrences.
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&task).Error; err != nil {
+			return err
+		}
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeTask, task.ID, models.VaultChangeUpdated); err != nil {
+			return err
+		}
+		return SyncTaskOccurrence(tx, &task, wasCompleted)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -294,10 +319,20 @@ func deleteTaskCascade(db *gorm.DB, task *models.ContactTask) error {
 			ids = append(ids, children...)
 			frontier = children
 		}
-		if err := tx.Where("contact_task_id IN ?", ids).Delete(&models.TaskContact{}).Error; err != nil {
+		for _, model := range []interface{}{&models.TaskContact{}, &models.TaskUserAssignee{}, &models.TaskNotificationDelivery{}} {
+			if err := tx.Where("contact_task_id IN ?", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&models.ContactTask{}).Error; err != nil {
 			return err
 		}
-		return tx.Where("id IN ?", ids).Delete(&models.ContactTask{}).Error
+		for _, id := range ids {
+			if err := recordVaultChange(tx, task.VaultID, models.VaultChangeTask, id, models.VaultChangeDeleted); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 }
 
@@ -340,6 +375,9 @@ func toTaskResponse(t *models.ContactTask, contacts []dto.TaskContactRef) dto.Ta
 		Contacts:      contacts,
 		CreatedAt:     t.CreatedAt,
 		UpdatedAt:     t.UpdatedAt,
+
+		RecurrenceRule:   ptrToStr(t.RecurrenceRule),
+		NextOccurrenceID: t.NextOccurrenceID,
 	}
 }
 
@@ -123,6 +123,84 @@ func validateContactsBelongToVault(db *gorm.DB, contactIDs []string, vaultID str
 	return nil
 }
 
+// taskUserAssignees loads the assigned vault users for a set of task IDs,
+// returning a map from task ID to user IDs in assignment order.
+func taskUserAssignees(db *gorm.DB, taskIDs []uint) (map[uint][]string, error) {
+	result := make(map[uint][]string, len(taskIDs))
+	if len(taskIDs) == 0 {
+		return result, nil
+	}
+	var rows []models.TaskUserAssignee
+	if err := db.Where("contact_task_id IN ?", taskIDs).Order("id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		result[r.ContactTaskID] = append(result[r.ContactTaskID], r.UserID)
+	}
+	return result, nil
+}
+
+// replaceTaskUserAssignees swaps the assigned users for one task. Unlike
+// replaceTaskAssignees it keeps the rows of users who stay assigned: the
+// row ID keys the "assigned" notification, so re-saving a task must not
+// announce the assignment again. assignedByID is recorded on new rows;
+// pass nil for automatic carry-overs that should not notify.
+func replaceTaskUserAssignees(tx *gorm.DB, taskID uint, userIDs []string, assignedByID *string) error {
+	keep := make(map[string]struct{}, len(userIDs))
+	for _, uid := range userIDs {
+		if uid != "" {
+			keep[uid] = struct{}{}
+		}
+	}
+	var existing []models.TaskUserAssignee
+	if err := tx.Where("contact_task_id = ?", taskID).Find(&existing).Error; err != nil {
+		return err
+	}
+	var removed []uint
+	for _, row := range existing {
+		if _, ok := keep[row.UserID]; ok {
+			delete(keep, row.UserID)
+			continue
+		}
+		removed = append(removed, row.ID)
+	}
+	if len(removed) > 0 {
+		if err := tx.Where("id IN ?", removed).Delete(&models.TaskUserAssignee{}).Error; err != nil {
+			return err
+		}
+	}
+	rows := make([]models.TaskUserAssignee, 0, len(keep))
+	for _, uid := range userIDs {
+		if _, ok := keep[uid]; !ok {
+			continue
+		}
+		delete(keep, uid)
+		rows = append(rows, models.TaskUserAssignee{ContactTaskID: taskID, UserID: uid, AssignedByID: assignedByID})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Create(&rows).Error
+}
+
+// validateUsersBelongToVault ensures every user in the set is a member of
+// vault. Returns ErrTaskAssigneeNotInVault if any is not.
+func validateUsersBelongToVault(db *gorm.DB, userIDs []string, vaultID string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&models.UserVault{}).
+		Where("user_id IN ? AND vault_id = ?", userIDs, vaultID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != distinctCount(userIDs) {
+		return ErrTaskAssigneeNotInVault
+	}
+	return nil
+}
+
 func distinctCount(ids []string) int {
 	seen := make(map[string]struct{}, len(ids))
 	for _, id := range ids {
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/naiba/bonds/internal/database"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/i18n"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// taskNotificationLookback bounds how far in the past an assignment or
+	// due date may lie and still produce a notification, so enabling the
+	// feature (or a preference) does not flood users with stale tasks.
+	taskNotificationLookback = 7 * 24 * time.Hour
+	// maxTaskDueLeadMinutes caps the configurable lead time at 30 days.
+	maxTaskDueLeadMinutes = 30 * 24 * 60
+
+	defaultTaskDueLeadMinutes = 24 * 60
+)
+
+var ErrInvalidTaskDueLeadTime = errors.New("due lead time must be between 0 and 43200 minutes")
+
+// TaskNotificationService notifies the vault users assigned to a task when
+// they are assigned, when the task is about to be due, and when it becomes
+// overdue. Deliveries go through the user's active notification channels.
+type TaskNotificationService struct {
+	db      *gorm.DB
+	mailer  Mailer
+	sender  NotificationSender
+	webPush NotificationSender
+}
+
+func NewTaskNotificationService(db *gorm.DB, mailer Mailer, sender NotificationSender) *TaskNotificationService {
+	return &TaskNotificationService{db: db, mailer: mailer, sender: sender}
+}
+
+// SetWebPushSender enables delivery to Web Push channels.
+func (s *TaskNotificationService) SetWebPushSender(webPush NotificationSender) {
+	s.webPush = webPush
+}
+
+func (s *TaskNotificationService) GetPreferences(userID string) (*dto.TaskNotificationPreferenceResponse, error) {
+	pref, err := loadTaskNotificationPreference(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	resp := toTaskNotificationPreferenceResponse(pref)
+	return &resp, nil
+}
+
+func (s *TaskNotificationService) UpdatePreferences(userID string, req dto.UpdateTaskNotificationPreferenceRequest) (*dto.TaskNotificationPreferenceResponse, error) {
+	if req.DueLeadMinutes < 0 || req.DueLeadMinutes > maxTaskDueLeadMinutes {
+		return nil, ErrInvalidTaskDueLeadTime
+	}
+	pref, err := loadTaskNotificationPreference(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	pref.NotifyOnAssignment = req.NotifyOnAssignment
+	pref.NotifyBeforeDue = req.NotifyBeforeDue
+	pref.DueLeadMinutes = req.DueLeadMinutes
+	pref.NotifyWhenOverdue = req.NotifyWhenOverdue
+	if err := s.db.Save(&pref).Error; err != nil {
+		return nil, err
+	}
+	resp := toTaskNotificationPreferenceResponse(pref)
+	return &resp, nil
+}
+
+// loadTaskNotificationPreference returns the user's stored preferences, or
+// an unsaved row holding the defaults when the user never changed them.
+func loadTaskNotificationPreference(db *gorm.DB, userID string) (models.TaskNotificationPreference, error) {
+	var pref models.TaskNotificationPreference
+	err := db.Where("user_id = ?", userID).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultTaskNotificationPreference(userID), nil
+	}
+	return pref, err
+}
+
+func defaultTaskNotificationPreference(userID string) models.TaskNotificationPreference {
+	return models.TaskNotificationPreference{
+		UserID:             userID,
+		NotifyOnAssignment: true,
+		NotifyBeforeDue:    true,
+		DueLeadMinutes:     defaultTaskDueLeadMinutes,
+		NotifyWhenOverdue:  true,
+	}
+}
+
+func toTaskNotificationPreferenceResponse(p models.TaskNotificationPreference) dto.TaskNotificationPreferenceResponse {
+	return dto.TaskNotificationPreferenceResponse{
+		NotifyOnAssignment: p.NotifyOnAssignment,
+		NotifyBeforeDue:    p.NotifyBeforeDue,
+		DueLeadMinutes:     p.DueLeadMinutes,
+		NotifyWhenOverdue:  p.NotifyWhenOverdue,
+	}
+}
+
+// taskNotificationCandidate is one (open task, assigned vault member) pair.
+type taskNotificationCandidate struct {
+	AssigneeID   uint
+	AssignedByID *string
+	AssignedAt   time.Time
+	UserID       string
+	TaskID       uint
+	Label        string
+	DueAt        *time.Time
+	VaultID      string
+	VaultName    string
+}
+
+type taskNotification struct {
+	kind string
+	key  string
+}
+
+// ProcessTaskNotifications sends every task notification that has become
+// due since the last run. It is safe to run on several replicas at once:
+// each notification is claimed by inserting its TaskNotificationDelivery
+// row, and only the replica whose insert succeeds sends it.
+func (s *TaskNotificationService) ProcessTaskNotifications() {
+	s.processTaskNotifications(time.Now())
+}
+
+func (s *TaskNotificationService) processTaskNotifications(now time.Time) {
+	candidates, err := s.loadCandidates(now)
+	if err != nil {
+		log.Printf("[task-notifications] Failed to query tasks: %v", err)
+		return
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	claimed, err := s.claimedKeys(candidates)
+	if err != nil {
+		log.Printf("[task-notifications] Failed to load delivery state: %v", err)
+		return
+	}
+	prefs := make(map[string]models.TaskNotificationPreference)
+	for i := range candidates {
+		c := &candidates[i]
+		pref, ok := prefs[c.UserID]
+		if !ok {
+			if pref, err = loadTaskNotificationPreference(s.db, c.UserID); err != nil {
+				log.Printf("[task-notifications] Load preferences for user %s: %v", c.UserID, err)
+				continue
+			}
+			prefs[c.UserID] = pref
+		}
+		for _, n := range pendingTaskNotifications(c, pref, now) {
+			if _, done := claimed[deliveryKey(c.TaskID, c.UserID, n.kind, n.key)]; done {
+				continue
+			}
+			s.deliver(c, n, now)
+		}
+	}
+}
+
+func (s *TaskNotificationService) loadCandidates(now time.Time) ([]taskNotificationCandidate, error) {
+	since := now.Add(-taskNotificationLookback)
+	var candidates []taskNotificationCandidate
+	err := s.db.Table("task_user_assignees").
+		Select(`task_user_assignees.id AS assignee_id, task_user_assignees.assigned_by_id, task_user_assignees.created_at AS assigned_at,
+			task_user_assignees.user_id, contact_tasks.id AS task_id, contact_tasks.label, contact_tasks.due_at,
+			contact_tasks.vault_id, vaults.name AS vault_name`).
+		Joins("JOIN contact_tasks ON contact_tasks.id = task_user_assignees.contact_task_id").
+		Joins("JOIN vaults ON vaults.id = contact_tasks.vault_id").
+		// Assignees who have since left the vault are not notified.
+		Joins("JOIN user_vault ON user_vault.vault_id = contact_tasks.vault_id AND user_vault.user_id = task_user_assignees.user_id").
+		Where("contact_tasks.deleted_at IS NULL AND contact_tasks.completed = ? AND contact_tasks.next_occurrence_id IS NULL", false).
+		Where("(task_user_assignees.assigned_by_id IS NOT NULL AND task_user_assignees.created_at > ?) OR (contact_tasks.due_at > ? AND contact_tasks.due_at <= ?)",
+			since, since, now.Add(maxTaskDueLeadMinutes*time.Minute)).
+		Order("task_user_assignees.id ASC").
+		Scan(&candidates).Error
+	return candidates, err
+}
+
+// claimedKeys pre-loads the delivery rows for the candidate tasks so the
+// common "already sent" case does not need a failing insert every minute.
+func (s *TaskNotificationService) claimedKeys(candidates []taskNotificationCandidate) (map[string]struct{}, error) {
+	taskIDs := make([]uint, 0, len(candidates))
+	for _, c := range candidates {
+		taskIDs = append(taskIDs, c.TaskID)
+	}
+	var rows []models.TaskNotificationDelivery
+	if err := s.db.Where("contact_task_id IN ?", taskIDs).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	claimed := make(map[string]struct{}, len(rows))
+	for _, r := range rows {
+		claimed[deliveryKey(r.ContactTaskID, r.UserID, r.Kind, r.Key)] = struct{}{}
+	}
+	return claimed, nil
+}
+
+func deliveryKey(taskID uint, userID, kind, key string) string {
+	return fmt.Sprintf("%d|%s|%s|%s", taskID, userID, kind, key)
+}
+
+// pendingTaskNotifications lists the notifications a candidate is eligible
+// for right now under the user's preferences. Self-assignments and
+// assignments carried over to a recurring task's next occurrence are not
+// announced. Deadline notifications are keyed by the due date, so moving
+// the due date re-arms them.
+func pendingTaskNotifications(c *taskNotificationCandidate, pref models.TaskNotificationPreference, now time.Time) []taskNotification {
+	var out []taskNotification
+	since := now.Add(-taskNotificationLookback)
+	if pref.NotifyOnAssignment && c.AssignedByID != nil && *c.AssignedByID != c.UserID && c.AssignedAt.After(since) {
+		out = append(out, taskNotification{kind: models.TaskNotificationAssigned, key: strconv.FormatUint(uint64(c.AssigneeID), 10)})
+	}
+	if c.DueAt == nil {
+		return out
+	}
+	due := *c.DueAt
+	dueKey := due.UTC().Format(time.RFC3339)
+	switch {
+	case !due.After(now):
+		if pref.NotifyWhenOverdue && due.After(since) {
+			out = append(out, taskNotification{kind: models.TaskNotificationOverdue, key: dueKey})
+		}
+	case pref.NotifyBeforeDue && !due.After(now.Add(time.Duration(pref.DueLeadMinutes)*time.Minute)):
+		out = append(out, taskNotification{kind: models.TaskNotificationDueSoon, key: dueKey})
+	}
+	return out
+}
+
+// deliver claims one notification and sends it to every active channel of
+// the assignee. If every channel fails the claim is released so the next
+// run retries, mirroring how failed reminders stay pending.
+func (s *TaskNotificationService) deliver(c *taskNotificationCandidate, n taskNotification, now time.Time) {
+	claim := models.TaskNotificationDelivery{
+		ContactTaskID: c.TaskID,
+		UserID:        c.UserID,
+		Kind:          n.kind,
+		Key:           n.key,
+		ClaimedAt:     now,
+	}
+	if err := s.db.Create(&claim).Error; err != nil {
+		if !database.IsUniqueConstraintErr(err) {
+			log.Printf("[task-notifications] Claim %s notification for task %d: %v", n.kind, c.TaskID, err)
+		}
+		return
+	}
+
+	var user models.User
+	if err := s.db.Where("id = ?", c.UserID).First(&user).Error; err != nil {
+		log.Printf("[task-notifications] Load user %s: %v", c.UserID, err)
+		s.releaseClaim(&claim)
+		return
+	}
+	var channels []models.UserNotificationChannel
+	if err := s.db.Where("user_id = ? AND active = ?", c.UserID, true).Find(&channels).Error; err != nil {
+		log.Printf("[task-notifications] Load channels for user %s: %v", c.UserID, err)
+		s.releaseClaim(&claim)
+		return
+	}
+	if len(channels) == 0 {
+		s.finishClaim(&claim, now, "no active notification channels")
+		return
+	}
+
+	subject, body := taskNotificationContent(c, n.kind, &user)
+	delivered := false
+	for i := range channels {
+		channel := &channels[i]
+		sendErr := sendToNotificationChannel(s.mailer, s.sender, s.webPush, channel, subject, body)
+		if sendErr != nil {
+			if err := recordChannelFailure(s.db, channel, subject, body, sendErr, time.Now()); err != nil {
+				log.Printf("[task-notifications] Record failed delivery on channel %d: %v", channel.ID, err)
+			}
+			continue
+		}
+		delivered = true
+		if err := recordChannelSuccess(s.db, channel, subject, body, time.Now()); err != nil {
+			log.Printf("[task-notifications] Record delivery on channel %d: %v", channel.ID, err)
+		}
+	}
+	if !delivered {
+		s.releaseClaim(&claim)
+		return
+	}
+	s.finishClaim(&claim, time.Now(), "")
+}
+
+func (s *TaskNotificationService) finishClaim(claim *models.TaskNotificationDelivery, sentAt time.Time, note string) {
+	updates := map[string]interface{}{"sent_at": sentAt}
+	if note != "" {
+		updates["error"] = note
+	}
+	if err := s.db.Model(claim).Updates(updates).Error; err != nil {
+		log.Printf("[task-notifications] Mark delivery %d sent: %v", claim.ID, err)
+	}
+}
+
+func (s *TaskNotificationService) releaseClaim(claim *models.TaskNotificationDelivery) {
+	if err := s.db.Delete(claim).Error; err != nil {
+		log.Printf("[task-notifications] Release delivery %d: %v", claim.ID, err)
+	}
+}
+
+func recordChannelSuccess(db *gorm.DB, channel *models.UserNotificationChannel, subject, body string, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.UserNotificationSent{UserNotificationChannelID: channel.ID, SentAt: now, SubjectLine: subject, Payload: &body}).Error; err != nil {
+			return fmt.Errorf("create success log: %w", err)
+		}
+		if channel.Fails > 0 {
+			if err := tx.Model(channel).Update("fails", 0).Error; err != nil {
+				return fmt.Errorf("reset channel failures: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func taskNotificationContent(c *taskNotificationCandidate, kind string, user *models.User) (string, string) {
+	locale, _ := reminderDeliveryLocale(user)
+	date := i18n.T(locale, "task_notification.no_due_date")
+	if c.DueAt != nil {
+		date = c.DueAt.In(userLocation(user)).Format("2006-01-02 15:04")
+	}
+	params := map[string]string{"label": c.Label, "vault": c.VaultName, "date": date}
+	subject := i18n.Tt(locale, "task_notification."+kind+".subject", params)
+	body := i18n.Tt(locale, "task_notification."+kind+".body", params)
+	return subject, body
+}
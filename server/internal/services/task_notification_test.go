@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+	"gorm.io/gorm"
+)
+
+type taskNotificationTestContext struct {
+	db         *gorm.DB
+	svc        *TaskNotificationService
+	tasks      *VaultTaskService
+	mailer     *mockMailer
+	vaultID    string
+	ownerID    string
+	assigneeID string
+}
+
+func setupTaskNotificationTest(t *testing.T) *taskNotificationTestContext {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	authSvc := NewAuthService(db, testutil.TestJWTConfig())
+
+	owner, err := authSvc.Register(dto.RegisterRequest{FirstName: "Owner", LastName: "User", Email: "task-notify-owner@example.com", Password: "password123"}, "en")
+	if err != nil {
+		t.Fatalf("Register owner failed: %v", err)
+	}
+	assignee, err := authSvc.Register(dto.RegisterRequest{FirstName: "Assignee", LastName: "User", Email: "task-notify-assignee@example.com", Password: "password123"}, "en")
+	if err != nil {
+		t.Fatalf("Register assignee failed: %v", err)
+	}
+	vault, err := NewVaultService(db).CreateVault(owner.User.AccountID, owner.User.ID, dto.CreateVaultRequest{Name: "Family"}, "en")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+	if err := db.Create(&models.UserVault{VaultID: vault.ID, UserID: assignee.User.ID, Permission: models.PermissionEditor}).Error; err != nil {
+		t.Fatalf("add assignee to vault: %v", err)
+	}
+	// Registration already created an active email channel for each user.
+
+	mailer := &mockMailer{}
+	return &taskNotificationTestContext{
+		db:         db,
+		svc:        NewTaskNotificationService(db, mailer, nil),
+		tasks:      NewVaultTaskService(db),
+		mailer:     mailer,
+		vaultID:    vault.ID,
+		ownerID:    owner.User.ID,
+		assigneeID: assignee.User.ID,
+	}
+}
+
+func (tc *taskNotificationTestContext) createTask(t *testing.T, label string, due *time.Time) *dto.VaultTaskResponse {
+	t.Helper()
+	task, err := tc.tasks.Create(tc.vaultID, tc.ownerID, dto.CreateVaultTaskRequest{
+		Label:           label,
+		DueAt:           due,
+		AssigneeUserIDs: []string{tc.assigneeID},
+	})
+	if err != nil {
+		t.Fatalf("Create task failed: %v", err)
+	}
+	return task
+}
+
+func TestTaskNotificationAssignmentFiresOnce(t *testing.T) {
+	tc := setupTaskNotificationTest(t)
+	task := tc.createTask(t, "Book flights", nil)
+	if len(task.AssigneeUserIDs) != 1 || task.AssigneeUserIDs[0] != tc.assigneeID {
+		t.Fatalf("expected assignee in response, got %v", task.AssigneeUserIDs)
+	}
+
+	tc.svc.processTaskNotifications(time.Now())
+	tc.svc.processTaskNotifications(time.Now())
+	if len(tc.mailer.calls) != 1 {
+		t.Fatalf("expected exactly one assignment email, got %d", len(tc.mailer.calls))
+	}
+	if !strings.Contains(tc.mailer.calls[0].Subject, "Book flights") || !strings.Contains(tc.mailer.calls[0].Body, "Family") {
+		t.Errorf("unexpected message: %+v", tc.mailer.calls[0])
+	}
+
+	// Re-saving the task with the same assignee must not announce it again.
+	assignees := []string{tc.assigneeID}
+	if _, err := tc.tasks.Update(task.ID, tc.vaultID, dto.UpdateVaultTaskRequest{Label: "Book flights", AssigneeUserIDs: &assignees}, tc.ownerID); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	tc.svc.processTaskNotifications(time.Now())
+	if len(tc.mailer.calls) != 1 {
+		t.Fatalf("expected no new notification after re-save, got %d", len(tc.mailer.calls))
+	}
+
+	var sent int64
+	tc.db.Model(&models.TaskNotificationDelivery{}).Where("sent_at IS NOT NULL").Count(&sent)
+	if sent != 1 {
+		t.Errorf("expected one recorded delivery, got %d", sent)
+	}
+}
+
+func TestTaskNotificationSelfAssignmentIsSilent(t *testing.T) {
+	tc := setupTaskNotificationTest(t)
+	if _, err := tc.tasks.Create(tc.vaultID, tc.assigneeID, dto.CreateVaultTaskRequest{
+		Label: "Mine", AssigneeUserIDs: []string{tc.assigneeID},
+	}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	tc.svc.processTaskNotifications(time.Now())
+	if len(tc.mailer.calls) != 0 {
+		t.Fatalf("expected no notification for a self-assignment, got %d", len(tc.mailer.calls))
+	}
+}
+
+func TestTaskNotificationDueSoonAndOverdue(t *testing.T) {
+	tc := setupTaskNotificationTest(t)
+	if _, err := tc.svc.UpdatePreferences(tc.assigneeID, dto.UpdateTaskNotificationPreferenceRequest{
+		NotifyOnAssignment: false, NotifyBeforeDue: true, DueLeadMinutes: 60, NotifyWhenOverdue: true,
+	}); err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+	now := time.Now().Truncate(time.Minute)
+	due := now.Add(90 * time.Minute)
+	tc.createTask(t, "Renew passport", &due)
+
+	tc.svc.processTaskNotifications(now)
+	if len(tc.mailer.calls) != 0 {
+		t.Fatalf("expected nothing outside the lead time, got %d", len(tc.mailer.calls))
+	}
+	tc.svc.processTaskNotifications(now.Add(31 * time.Minute))
+	tc.svc.processTaskNotifications(now.Add(45 * time.Minute))
+	if len(tc.mailer.calls) != 1 || !strings.HasPrefix(tc.mailer.calls[0].Subject, "Task due soon") {
+		t.Fatalf("expected one due-soon notification, got %+v", tc.mailer.calls)
+	}
+	tc.svc.processTaskNotifications(now.Add(91 * time.Minute))
+	tc.svc.processTaskNotifications(now.Add(120 * time.Minute))
+	if len(tc.mailer.calls) != 2 || !strings.HasPrefix(tc.mailer.calls[1].Subject, "Task overdue") {
+		t.Fatalf("expected one overdue notification, got %+v", tc.mailer.calls)
+	}
+}
+
+func TestTaskNotificationPreferencesDisableKinds(t *testing.T) {
+	tc := setupTaskNotificationTest(t)
+	if _, err := tc.svc.UpdatePreferences(tc.assigneeID, dto.UpdateTaskNotificationPreferenceRequest{}); err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+	prefs, err := tc.svc.GetPreferences(tc.assigneeID)
+	if err != nil {
+		t.Fatalf("GetPreferences failed: %v", err)
+	}
+	if prefs.NotifyOnAssignment || prefs.NotifyBeforeDue || prefs.NotifyWhenOverdue || prefs.DueLeadMinutes != 0 {
+		t.Fatalf("expected all notifications disabled, got %+v", prefs)
+	}
+
+	due := time.Now().Add(-time.Hour)
+	tc.createTask(t, "Late", &due)
+	tc.svc.processTaskNotifications(time.Now())
+	if len(tc.mailer.calls) != 0 {
+		t.Fatalf("expected no notifications, got %d", len(tc.mailer.calls))
+	}
+}
+
+func TestTaskNotificationClaimIsExclusive(t *testing.T) {
+	tc := setupTaskNotificationTest(t)
+	task := tc.createTask(t, "Shared", nil)
+
+	// Another replica already claimed the assignment notification.
+	var assignee models.TaskUserAssignee
+	tc.db.Where("contact_task_id = ?", task.ID).First(&assignee)
+	if err := tc.db.Create(&models.TaskNotificationDelivery{
+		ContactTaskID: task.ID, UserID: tc.assigneeID, Kind: models.TaskNotificationAssigned,
+		Key: strconv.FormatUint(uint64(assignee.ID), 10), ClaimedAt: time.Now(),
+	}).Error; err != nil {
+		t.Fatalf("create claim: %v", err)
+	}
+	c := taskNotificationCandidate{AssigneeID: assignee.ID, UserID: tc.assigneeID, TaskID: task.ID, Label: "Shared"}
+	tc.svc.deliver(&c, taskNotification{kind: models.TaskNotificationAssigned, key: strconv.FormatUint(uint64(assignee.ID), 10)}, time.Now())
+	if len(tc.mailer.calls) != 0 {
+		t.Fatalf("expected the losing replica not to send, got %d", len(tc.mailer.calls))
+	}
+}
+
+func TestTaskNotificationFailedDeliveryIsRetried(t *testing.T) {
+	tc := setupTaskNotificationTest(t)
+	tc.mailer.sendErr = errors.New("smtp down")
+	tc.createTask(t, "Retry me", nil)
+
+	tc.svc.processTaskNotifications(time.Now())
+	var claims int64
+	tc.db.Model(&models.TaskNotificationDelivery{}).Count(&claims)
+	if claims != 0 {
+		t.Fatalf("expected the claim to be released after a failed send, got %d", claims)
+	}
+
+	tc.mailer.sendErr = nil
+	tc.svc.processTaskNotifications(time.Now())
+	if len(tc.mailer.calls) != 2 {
+		t.Fatalf("expected a retry, got %d attempts", len(tc.mailer.calls))
+	}
+}
+
+func TestVaultTaskAssigneeMustBeVaultMember(t *testing.T) {
+	tc := setupTaskNotificationTest(t)
+	_, err := tc.tasks.Create(tc.vaultID, tc.ownerID, dto.CreateVaultTaskRequest{
+		Label: "Nope", AssigneeUserIDs: []string{"not-a-member"},
+	})
+	if !errors.Is(err, ErrTaskAssigneeNotInVault) {
+		t.Fatalf("expected ErrTaskAssigneeNotInVault, got %v", err)
+	}
+}
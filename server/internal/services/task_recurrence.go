@@ -0,0 +1,417 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	calendarPkg "github.com/naiba/bonds/internal/calendar"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/teambition/rrule-go"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidRecurrenceRule = errors.New("invalid recurrence rule")
+var ErrRecurrenceRequiresDueDate = errors.New("recurrence rule requires a due date")
+
+// RFC 7529 RSCALE values understood by the task recurrence engine. CHINESE is
+// how CalDAV clients spell "yearly on the same lunar date"; Bonds stores the
+// lunar anchor in CalendarType/Original* instead, so RSCALE never reaches the
+// database and is re-derived on export.
+const (
+	taskRecurrenceRScaleGregorian = "GREGORIAN"
+	taskRecurrenceRScaleChinese   = "CHINESE"
+)
+
+// taskRecurrence is a parsed ContactTask.RecurrenceRule.
+type taskRecurrence struct {
+	option rrule.ROption
+	rscale string
+}
+
+// parseTaskRecurrenceRule accepts an RRULE value with or without the
+// "RRULE:" prefix. RSCALE/SKIP (RFC 7529) are peeled off before handing the
+// rest to rrule-go, which does not know about them. Lunar rules are limited
+// to plain FREQ=YEARLY (+ INTERVAL/COUNT/UNTIL): BYMONTH and friends would be
+// interpreted against the Gregorian calendar and silently drift.
+func parseTaskRecurrenceRule(raw string) (*taskRecurrence, error) {
+	raw = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(raw)), "RRULE:")
+	if raw == "" {
+		return nil, ErrInvalidRecurrenceRule
+	}
+
+	rec := &taskRecurrence{}
+	parts := make([]string, 0, 4)
+	for _, part := range strings.Split(raw, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "RSCALE":
+			if value != taskRecurrenceRScaleGregorian && value != taskRecurrenceRScaleChinese {
+				return nil, ErrInvalidRecurrenceRule
+			}
+			rec.rscale = value
+		case "SKIP":
+			// Lunar occurrences that fall on a missing day are clamped by the
+			// converter, which is the SKIP=BACKWARD behaviour; nothing to keep.
+		default:
+			parts = append(parts, part)
+		}
+	}
+
+	option, err := rrule.StrToROption(strings.Join(parts, ";"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrenceRule, err)
+	}
+	if option.Interval < 0 || option.Count < 0 {
+		return nil, ErrInvalidRecurrenceRule
+	}
+	if option.Interval == 0 {
+		option.Interval = 1
+	}
+	rec.option = *option
+	if rec.rscale == taskRecurrenceRScaleChinese && !rec.isPlainYearly() {
+		return nil, ErrInvalidRecurrenceRule
+	}
+	return rec, nil
+}
+
+func (r *taskRecurrence) isPlainYearly() bool {
+	o := r.option
+	return o.Freq == rrule.YEARLY &&
+		len(o.Bysetpos) == 0 && len(o.Bymonth) == 0 && len(o.Bymonthday) == 0 &&
+		len(o.Byyearday) == 0 && len(o.Byweekno) == 0 && len(o.Byweekday) == 0 &&
+		len(o.Byhour) == 0 && len(o.Byminute) == 0 && len(o.Bysecond) == 0 &&
+		len(o.Byeaster) == 0
+}
+
+// String serializes the rule in canonical form, without RSCALE.
+func (r *taskRecurrence) String() string {
+	o := r.option
+	if o.Interval == 1 {
+		o.Interval = 0
+	}
+	return o.RRuleString()
+}
+
+// ApplyTaskRecurrence validates and stores a requested recurrence rule on a
+// task whose DueAt/calendar fields have already been resolved. An empty rule
+// clears recurrence. A rule carrying RSCALE=CHINESE on a Gregorian task
+// re-anchors the task in the lunar calendar from its DueAt, which is how a
+// CalDAV client asks for a lunar-yearly todo. Shared by the REST services
+// and the CalDAV backend.
+func ApplyTaskRecurrence(task *models.ContactTask, raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		task.RecurrenceRule = nil
+		return nil
+	}
+	rec, err := parseTaskRecurrenceRule(raw)
+	if err != nil {
+		return err
+	}
+	if task.DueAt == nil {
+		return ErrRecurrenceRequiresDueDate
+	}
+	if rec.rscale == taskRecurrenceRScaleChinese && !taskHasLunarAnchor(task) {
+		converter, _ := calendarPkg.Get(calendarPkg.Lunar)
+		info, err := converter.FromGregorian(calendarPkg.GregorianDate{
+			Day: task.DueAt.Day(), Month: int(task.DueAt.Month()), Year: task.DueAt.Year(),
+		})
+		if err != nil {
+			return err
+		}
+		task.CalendarType = string(calendarPkg.Lunar)
+		task.OriginalDay = &info.Day
+		task.OriginalMonth = &info.Month
+		task.OriginalYear = &info.Year
+	}
+	rule := rec.String()
+	task.RecurrenceRule = &rule
+	if task.RecurrenceSeriesID == nil {
+		seriesID := uuid.New().String()
+		task.RecurrenceSeriesID = &seriesID
+	}
+	return nil
+}
+
+// TaskRecurrenceRuleForExport returns the RRULE value to publish for a task
+// over CalDAV/ICS, or "" when the task does not recur. Superseded
+// occurrences (already completed, successor spawned) are exported as one-shot
+// todos so clients do not expand the series twice. Lunar-anchored yearly
+// rules carry RSCALE=CHINESE per RFC 7529.
+func TaskRecurrenceRuleForExport(t *models.ContactTask) string {
+	if t.RecurrenceRule == nil || *t.RecurrenceRule == "" || t.NextOccurrenceID != nil {
+		return ""
+	}
+	rec, err := parseTaskRecurrenceRule(*t.RecurrenceRule)
+	if err != nil {
+		return ""
+	}
+	if rec.isPlainYearly() && taskHasLunarAnchor(t) {
+		return "RSCALE=" + taskRecurrenceRScaleChinese + ";" + rec.String()
+	}
+	return rec.String()
+}
+
+func taskHasLunarAnchor(t *models.ContactTask) bool {
+	return t.CalendarType == string(calendarPkg.Lunar) && t.OriginalDay != nil && t.OriginalMonth != nil
+}
+
+// taskOccurrence describes the occurrence that follows a completed one.
+type taskOccurrence struct {
+	dueAt time.Time
+	rule  string
+	// lunarYearly is set when dueAt was resolved in the lunar calendar, in
+	// which case the successor keeps the lunar anchor verbatim.
+	lunarYearly bool
+}
+
+// nextTaskOccurrence computes the due date and remaining rule of the
+// occurrence that follows t. ok is false when the series is exhausted
+// (COUNT reached, past UNTIL) or t does not recur.
+//
+// COUNT is tracked by decrementing it on each spawned occurrence, so every
+// row carries the number of occurrences left including itself — the same
+// convention most task apps use when they advance a recurring todo.
+func nextTaskOccurrence(t *models.ContactTask) (occ taskOccurrence, ok bool, err error) {
+	if t.RecurrenceRule == nil || *t.RecurrenceRule == "" || t.DueAt == nil {
+		return taskOccurrence{}, false, nil
+	}
+	rec, err := parseTaskRecurrenceRule(*t.RecurrenceRule)
+	if err != nil {
+		return taskOccurrence{}, false, err
+	}
+	if rec.option.Count == 1 {
+		return taskOccurrence{}, false, nil
+	}
+
+	var next time.Time
+	due := *t.DueAt
+	occ.lunarYearly = rec.isPlainYearly() && taskHasLunarAnchor(t)
+	if occ.lunarYearly {
+		converter, _ := calendarPkg.Get(calendarPkg.Lunar)
+		original := calendarPkg.DateInfo{Day: *t.OriginalDay, Month: *t.OriginalMonth}
+		after := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+		var gd calendarPkg.GregorianDate
+		for i := 0; i < rec.option.Interval; i++ {
+			gd, err = converter.NextOccurrence(original, after)
+			if err != nil {
+				return taskOccurrence{}, false, err
+			}
+			after = time.Date(gd.Year, time.Month(gd.Month), gd.Day, 0, 0, 0, 0, time.UTC)
+		}
+		next = time.Date(gd.Year, time.Month(gd.Month), gd.Day,
+			due.Hour(), due.Minute(), due.Second(), due.Nanosecond(), due.Location())
+		if !rec.option.Until.IsZero() && next.After(rec.option.Until) {
+			return taskOccurrence{}, false, nil
+		}
+	} else {
+		option := rec.option
+		option.Dtstart = due
+		option.Count = 0
+		r, err := rrule.NewRRule(option)
+		if err != nil {
+			return taskOccurrence{}, false, fmt.Errorf("%w: %v", ErrInvalidRecurrenceRule, err)
+		}
+		next = r.After(due, false)
+		if next.IsZero() {
+			return taskOccurrence{}, false, nil
+		}
+	}
+
+	if rec.option.Count > 1 {
+		rec.option.Count--
+	}
+	occ.dueAt = next
+	occ.rule = rec.String()
+	return occ, true, nil
+}
+
+// spawnNextTaskOccurrence is called inside the transaction that marks a
+// recurring task completed. It creates the follow-up occurrence with the
+// same label, assignees and calendar anchor, and links the completed row to
+// it via NextOccurrenceID. The completed row stays behind as the completion
+// history entry for its due date.
+func spawnNextTaskOccurrence(tx *gorm.DB, task *models.ContactTask) (*models.ContactTask, error) {
+	if task.NextOccurrenceID != nil {
+		return nil, nil
+	}
+	occ, ok, err := nextTaskOccurrence(task)
+	if err != nil || !ok {
+		return nil, err
+	}
+	nextDue := occ.dueAt
+
+	seriesID := task.RecurrenceSeriesID
+	if seriesID == nil {
+		id := uuid.New().String()
+		seriesID = &id
+		task.RecurrenceSeriesID = seriesID
+	}
+	next := models.ContactTask{
+		VaultID:            task.VaultID,
+		ParentTaskID:       task.ParentTaskID,
+		AuthorID:           task.AuthorID,
+		AuthorName:         task.AuthorName,
+		Label:              task.Label,
+		Description:        task.Description,
+		Status:             resolveTaskStatusOrDefault(tx, "", task.VaultID),
+		DueAt:              &nextDue,
+		CalendarType:       task.CalendarType,
+		RecurrenceRule:     &occ.rule,
+		RecurrenceSeriesID: seriesID,
+	}
+	if task.UUID != nil {
+		// DAV-created tasks need a UID of their own; the completed
+		// occurrence keeps the one the client already knows.
+		uid := uuid.New().String()
+		next.UUID = &uid
+	}
+	if taskHasLunarAnchor(task) {
+		converter, _ := calendarPkg.Get(calendarPkg.Lunar)
+		info, err := converter.FromGregorian(calendarPkg.GregorianDate{
+			Day: nextDue.Day(), Month: int(nextDue.Month()), Year: nextDue.Year(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		next.OriginalDay = &info.Day
+		next.OriginalMonth = &info.Month
+		if occ.lunarYearly {
+			// Keep the anchor verbatim: a lunar day 30 clamped to 29 in a
+			// short month must come back as day 30 next year.
+			next.OriginalDay = task.OriginalDay
+			next.OriginalMonth = task.OriginalMonth
+		}
+		if task.OriginalYear != nil {
+			next.OriginalYear = &info.Year
+		}
+	}
+	if err := tx.Create(&next).Error; err != nil {
+		return nil, err
+	}
+	if err := recordVaultChange(tx, next.VaultID, models.VaultChangeTask, next.ID, models.VaultChangeCreated); err != nil {
+		return nil, err
+	}
+
+	var contactIDs []string
+	if err := tx.Model(&models.TaskContact{}).
+		Where("contact_task_id = ?", task.ID).
+		Pluck("contact_id", &contactIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := replaceTaskAssigneesLocked(tx, next.ID, contactIDs); err != nil {
+		return nil, err
+	}
+	var userIDs []string
+	if err := tx.Model(&models.TaskUserAssignee{}).
+		Where("contact_task_id = ?", task.ID).
+		Order("id ASC").
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := replaceTaskUserAssignees(tx, next.ID, userIDs, nil); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&models.ContactTask{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
+		"next_occurrence_id":   next.ID,
+		"recurrence_series_id": *seriesID,
+	}).Error; err != nil {
+		return nil, err
+	}
+	task.NextOccurrenceID = &next.ID
+	return &next, nil
+}
+
+// discardNextTaskOccurrence undoes spawnNextTaskOccurrence when a completed
+// occurrence is reopened. The successor is only removed while it is still
+// untouched (open, no successor of its own); otherwise the series has moved
+// on and the reopened row simply stays in the history.
+func discardNextTaskOccurrence(tx *gorm.DB, task *models.ContactTask) error {
+	if task.NextOccurrenceID == nil {
+		return nil
+	}
+	var next models.ContactTask
+	if err := tx.Where("id = ?", *task.NextOccurrenceID).First(&next).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if next.Completed || next.NextOccurrenceID != nil {
+		return nil
+	}
+	if err := deleteTaskCascade(tx, &next); err != nil {
+		return err
+	}
+	if err := tx.Model(&models.ContactTask{}).Where("id = ?", task.ID).
+		Update("next_occurrence_id", nil).Error; err != nil {
+		return err
+	}
+	task.NextOccurrenceID = nil
+	return nil
+}
+
+// SyncTaskOccurrence runs the recurrence side effect of a completion
+// transition: spawn the successor when a task becomes completed, discard it
+// when a completed task is reopened. Exported for the DAV backend, which
+// updates task rows directly but must honour the same semantics.
+func SyncTaskOccurrence(tx *gorm.DB, task *models.ContactTask, wasCompleted bool) error {
+	switch {
+	case task.Completed && !wasCompleted:
+		_, err := spawnNextTaskOccurrence(tx, task)
+		return err
+	case !task.Completed && wasCompleted:
+		return discardNextTaskOccurrence(tx, task)
+	}
+	return nil
+}
+
+// syncTaskOccurrenceByID reloads a task inside tx after a map-based update
+// and runs SyncTaskOccurrence against the persisted completion state.
+func syncTaskOccurrenceByID(tx *gorm.DB, id uint, wasCompleted bool) error {
+	var task models.ContactTask
+	if err := tx.Where("id = ?", id).First(&task).Error; err != nil {
+		return err
+	}
+	return SyncTaskOccurrence(tx, &task, wasCompleted)
+}
+
+// currentTaskOccurrenceScope hides occurrences that have been superseded by
+// a spawned successor, so lists only show the live occurrence of a series.
+func currentTaskOccurrenceScope(db *gorm.DB) *gorm.DB {
+	return db.Where("contact_tasks.next_occurrence_id IS NULL")
+}
+
+// listTaskCompletions returns the completed occurrences of the series the
+// given task belongs to, newest first. A non-recurring task's history is
+// just itself when completed.
+func listTaskCompletions(db *gorm.DB, task *models.ContactTask) ([]dto.TaskCompletionResponse, error) {
+	var rows []models.ContactTask
+	q := db.Where("vault_id = ? AND completed = ?", task.VaultID, true)
+	if task.RecurrenceSeriesID != nil {
+		q = q.Where("recurrence_series_id = ?", *task.RecurrenceSeriesID)
+	} else {
+		q = q.Where("id = ?", task.ID)
+	}
+	if err := q.Order("completed_at DESC, id DESC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]dto.TaskCompletionResponse, len(rows))
+	for i, r := range rows {
+		out[i] = dto.TaskCompletionResponse{
+			TaskID:           r.ID,
+			DueAt:            r.DueAt,
+			CompletedAt:      r.CompletedAt,
+			CalendarType:     r.CalendarType,
+			OriginalDay:      r.OriginalDay,
+			OriginalMonth:    r.OriginalMonth,
+			OriginalYear:     r.OriginalYear,
+			NextOccurrenceID: r.NextOccurrenceID,
+		}
+	}
+	return out, nil
+}
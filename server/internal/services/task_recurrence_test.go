@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+)
+
+func TestVaultTaskRecurrenceSpawnsNextOccurrence(t *testing.T) {
+	svc, _, vaultID, contactID, userID := setupVaultTaskTest(t)
+
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	created, err := svc.Create(vaultID, userID, dto.CreateVaultTaskRequest{
+		Label:          "Water plants",
+		DueAt:          &due,
+		ContactIDs:     []string{contactID},
+		RecurrenceRule: "RRULE:FREQ=WEEKLY;INTERVAL=2",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.RecurrenceRule != "FREQ=WEEKLY;INTERVAL=2" {
+		t.Fatalf("expected canonical rule, got %q", created.RecurrenceRule)
+	}
+
+	done, err := svc.UpdateStatus(created.ID, vaultID, dto.UpdateTaskStatusRequest{Status: models.TaskStatusDone}, userID)
+	if err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	if done.NextOccurrenceID == nil {
+		t.Fatal("expected completed occurrence to link to its successor")
+	}
+
+	tasks, err := svc.List(vaultID, VaultTaskFilters{}, userID)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("expected only the current occurrence on the board, got %d tasks", len(tasks))
+	}
+	next := tasks[0]
+	if next.ID != *done.NextOccurrenceID || next.Completed || next.Status != models.TaskStatusTodo {
+		t.Fatalf("unexpected current occurrence: %+v", next)
+	}
+	wantDue := time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)
+	if next.DueAt == nil || !next.DueAt.Equal(wantDue) {
+		t.Fatalf("expected next due %v, got %v", wantDue, next.DueAt)
+	}
+	if !hasAssignee(&next, contactID) {
+		t.Error("expected assignees to carry over to the next occurrence")
+	}
+
+	history, err := svc.ListCompletions(next.ID, vaultID)
+	if err != nil {
+		t.Fatalf("ListCompletions failed: %v", err)
+	}
+	if len(history) != 1 || history[0].TaskID != created.ID || !history[0].DueAt.Equal(due) {
+		t.Fatalf("unexpected completion history: %+v", history)
+	}
+}
+
+func TestVaultTaskRecurrenceReopenDiscardsUntouchedSuccessor(t *testing.T) {
+	svc, _, vaultID, _, userID := setupVaultTaskTest(t)
+
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	created, err := svc.Create(vaultID, userID, dto.CreateVaultTaskRequest{
+		Label: "Pay rent", DueAt: &due, RecurrenceRule: "FREQ=MONTHLY",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := svc.UpdateStatus(created.ID, vaultID, dto.UpdateTaskStatusRequest{Status: models.TaskStatusDone}, userID); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	reopened, err := svc.UpdateStatus(created.ID, vaultID, dto.UpdateTaskStatusRequest{Status: models.TaskStatusTodo}, userID)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if reopened.NextOccurrenceID != nil {
+		t.Fatalf("expected successor link to be cleared, got %v", *reopened.NextOccurrenceID)
+	}
+
+	var count int64
+	svc.db.Model(&models.ContactTask{}).Where("vault_id = ?", vaultID).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected the untouched successor to be discarded, got %d rows", count)
+	}
+}
+
+func TestVaultTaskRecurrenceCountExhausts(t *testing.T) {
+	svc, _, vaultID, _, userID := setupVaultTaskTest(t)
+
+	due := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	created, err := svc.Create(vaultID, userID, dto.CreateVaultTaskRequest{
+		Label: "Twice", DueAt: &due, RecurrenceRule: "FREQ=DAILY;COUNT=2",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	first, err := svc.UpdateStatus(created.ID, vaultID, dto.UpdateTaskStatusRequest{Status: models.TaskStatusDone}, userID)
+	if err != nil || first.NextOccurrenceID == nil {
+		t.Fatalf("expected a second occurrence, err=%v", err)
+	}
+	second, err := svc.UpdateStatus(*first.NextOccurrenceID, vaultID, dto.UpdateTaskStatusRequest{Status: models.TaskStatusDone}, userID)
+	if err != nil {
+		t.Fatalf("complete second failed: %v", err)
+	}
+	if second.RecurrenceRule != "FREQ=DAILY;COUNT=1" {
+		t.Errorf("expected COUNT to be decremented, got %q", second.RecurrenceRule)
+	}
+	if second.NextOccurrenceID != nil {
+		t.Fatal("expected the series to end after COUNT occurrences")
+	}
+}
+
+func TestVaultTaskRecurrenceValidation(t *testing.T) {
+	svc, _, vaultID, _, userID := setupVaultTaskTest(t)
+
+	if _, err := svc.Create(vaultID, userID, dto.CreateVaultTaskRequest{
+		Label: "No due", RecurrenceRule: "FREQ=DAILY",
+	}); !errors.Is(err, ErrRecurrenceRequiresDueDate) {
+		t.Fatalf("expected ErrRecurrenceRequiresDueDate, got %v", err)
+	}
+
+	due := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, rule := range []string{"FREQ=SOMETIMES", "INTERVAL=2", "RSCALE=HEBREW;FREQ=YEARLY", "RSCALE=CHINESE;FREQ=MONTHLY"} {
+		if _, err := svc.Create(vaultID, userID, dto.CreateVaultTaskRequest{
+			Label: "Bad", DueAt: &due, RecurrenceRule: rule,
+		}); !errors.Is(err, ErrInvalidRecurrenceRule) {
+			t.Errorf("rule %q: expected ErrInvalidRecurrenceRule, got %v", rule, err)
+		}
+	}
+}
+
+func TestTaskRecurrenceLunarYearly(t *testing.T) {
+	_, taskSvc, vaultID, contactID, userID := setupVaultTaskTest(t)
+
+	// Lunar 8/15 (Mid-Autumn) 2026 is 2026-09-25; 2027 is 2027-09-15.
+	due := time.Date(2026, 9, 25, 10, 0, 0, 0, time.UTC)
+	day, month, year := 15, 8, 2026
+	created, err := taskSvc.Create(contactID, vaultID, userID, dto.CreateTaskRequest{
+		Label:          "Mooncakes",
+		DueAt:          &due,
+		CalendarType:   "lunar",
+		OriginalDay:    &day,
+		OriginalMonth:  &month,
+		OriginalYear:   &year,
+		RecurrenceRule: "FREQ=YEARLY",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	done, err := taskSvc.ToggleCompleted(created.ID, contactID, vaultID, userID)
+	if err != nil {
+		t.Fatalf("ToggleCompleted failed: %v", err)
+	}
+	if done.NextOccurrenceID == nil {
+		t.Fatal("expected next lunar occurrence")
+	}
+
+	list, err := taskSvc.List(contactID, vaultID, userID)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected only the current occurrence, got %d", len(list))
+	}
+	next := list[0]
+	want := time.Date(2027, 9, 15, 10, 0, 0, 0, time.UTC)
+	if next.DueAt == nil || !next.DueAt.Equal(want) {
+		t.Fatalf("expected lunar next due %v, got %v", want, next.DueAt)
+	}
+	if next.CalendarType != "lunar" || *next.OriginalDay != 15 || *next.OriginalMonth != 8 || *next.OriginalYear != 2027 {
+		t.Fatalf("unexpected lunar anchor on next occurrence: %+v", next)
+	}
+
+	completed, err := taskSvc.ListCompleted(contactID, vaultID, userID)
+	if err != nil {
+		t.Fatalf("ListCompleted failed: %v", err)
+	}
+	if len(completed) != 1 || completed[0].ID != created.ID {
+		t.Fatalf("expected the completed occurrence in history, got %+v", completed)
+	}
+}
+
+func TestIcsTaskToDoRecurrence(t *testing.T) {
+	due := time.Date(2026, 9, 25, 0, 0, 0, 0, time.UTC)
+	rule := "FREQ=YEARLY"
+	day, month := 15, 8
+	task := &models.ContactTask{
+		ID: 1, Label: "Mooncakes", DueAt: &due, RecurrenceRule: &rule,
+		CalendarType: "lunar", OriginalDay: &day, OriginalMonth: &month,
+	}
+	todo := icsTaskToDo(task)
+	prop := todo.Props.Get(ical.PropRecurrenceRule)
+	if prop == nil || prop.Value != "RSCALE=CHINESE;FREQ=YEARLY" {
+		t.Fatalf("expected lunar RRULE, got %+v", prop)
+	}
+
+	nextID := uint(2)
+	task.NextOccurrenceID = &nextID
+	if todo := icsTaskToDo(task); todo.Props.Get(ical.PropRecurrenceRule) != nil {
+		t.Fatal("superseded occurrences must not carry an RRULE")
+	}
+
+	parsed, err := parseTaskRecurrenceRule(prop.Value)
+	if err != nil || parsed.rscale != taskRecurrenceRScaleChinese || !strings.HasPrefix(parsed.String(), "FREQ=YEARLY") {
+		t.Fatalf("exported rule does not round-trip: %+v %v", parsed, err)
+	}
+}
@@ -93,6 +93,20 @@ func (s *UserManagementService) Delete(id, accountID, currentUserID string) erro
 		if err := tx.Where("user_id = ?", id).Delete(&models.UserNotificationChannel{}).Error; err != nil {
 			return err
 		}
+		if err := deleteUserSessions(tx, "user_id = ?", id); err != nil {
+			return err
+		}
+		if err := deleteOAuthGrants(tx, "user_id = ?", id); err != nil {
+			return err
+		}
+		if err := deleteJobs(tx, "user_id = ?", id); err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.TaskUserAssignee{}, &models.TaskNotificationPreference{}, &models.TaskNotificationDelivery{}, &models.MemoryState{}, &models.MemoryPreference{}, &models.MemoryNotificationDelivery{}, &models.ContactReminderDeliveryState{}, &models.EmailInbox{}} {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
 		return tx.Delete(&user).Error
 	})
 }
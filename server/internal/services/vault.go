@@ -179,6 +179,11 @@ func deleteVaultCascade(tx *gorm.DB, vaultID string) error {
 		).Delete(&models.ContactReminderSelectedUser{}).Error; err != nil {
 			return fmt.Errorf("delete ContactReminderSelectedUser: %w", err)
 		}
+		if err := tx.Where("contact_reminder_id IN (?)",
+			tx.Model(&models.ContactReminder{}).Select("id").Where("contact_id IN ?", contactIDs),
+		).Delete(&models.ContactReminderDeliveryState{}).Error; err != nil {
+			return fmt.Errorf("delete ContactReminderDeliveryState: %w", err)
+		}
 		// Streak → depends on Goal
 		if err := tx.Where("goal_id IN (?)",
 			tx.Model(&models.Goal{}).Select("id").Where("contact_id IN ?", contactIDs),
@@ -433,6 +438,11 @@ func deleteVaultCascade(tx *gorm.DB, vaultID string) error {
 		if err := tx.Where("contact_task_id IN ?", taskIDs).Delete(&models.TaskContact{}).Error; err != nil {
 			return fmt.Errorf("delete TaskContact: %w", err)
 		}
+		for _, model := range []interface{}{&models.TaskUserAssignee{}, &models.TaskNotificationDelivery{}} {
+			if err := tx.Where("contact_task_id IN ?", taskIDs).Delete(model).Error; err != nil {
+				return fmt.Errorf("delete %T: %w", model, err)
+			}
+		}
 		if err := tx.Unscoped().Where("id IN ?", taskIDs).Delete(&models.ContactTask{}).Error; err != nil {
 			return fmt.Errorf("delete ContactTask: %w", err)
 		}
@@ -456,12 +466,24 @@ func deleteVaultCascade(tx *gorm.DB, vaultID string) error {
 		&models.ContactTask{}, // Standalone vault tasks have vault_id but no contact_id.
 		&models.ContactVaultUser{},
 		&models.UserVault{},
+		&models.VaultChange{},
+		&models.VaultChangeCounter{},
+		&models.AutomationRule{},
+		&models.AutomationExecution{},
+		&models.AutomationQueueItem{},
+		&models.EmailInbox{},
+		&models.IngestedEmail{},
+		&models.GeocodingTask{},
+		&models.MemoryState{},
 	}
 	for _, m := range vaultChildModels {
 		if err := tx.Unscoped().Where("vault_id = ?", vaultID).Delete(m).Error; err != nil {
 			return fmt.Errorf("delete vault child %T: %w", m, err)
 		}
 	}
+	if err := deleteJobs(tx, "vault_id = ?", vaultID); err != nil {
+		return fmt.Errorf("delete vault jobs: %w", err)
+	}
 
 	// Step 4: Delete contacts (including soft-deleted ones via Unscoped).
 	if err := tx.Unscoped().Where("vault_id = ?", vaultID).Delete(&models.Contact{}).Error; err != nil {
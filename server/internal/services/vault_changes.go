@@ -0,0 +1,314 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidSyncCursor = errors.New("invalid sync cursor")
+
+const (
+	defaultVaultChangeLimit = 500
+	maxVaultChangeLimit     = 1000
+)
+
+// recordVaultChange appends an entry to the vault change log. Pass the
+// transaction of the write it records and return its error from there, so
+// that the write rolls back when the change cannot be logged; sync clients
+// could not otherwise tell that they missed it. The vault's writers wait
+// for the transaction to commit, which keeps the log in commit order.
+func recordVaultChange(db *gorm.DB, vaultID, entityType string, entityID interface{}, action string) error {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		seq, err := models.ReserveVaultChangeSeqs(tx, vaultID, 1)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.VaultChange{VaultID: vaultID, Seq: seq, EntityType: entityType, EntityID: fmt.Sprint(entityID), Action: action}).Error
+	}); err != nil {
+		return fmt.Errorf("record %s %s %v in the change log of vault %s: %w", action, entityType, entityID, vaultID, err)
+	}
+	notifyVaultStreams(vaultID)
+	return nil
+}
+
+// RecordVaultChange is recordVaultChange for the DAV backends, which write
+// rows directly instead of going through the services.
+func RecordVaultChange(db *gorm.DB, vaultID, entityType string, entityID interface{}, action string) error {
+	return recordVaultChange(db, vaultID, entityType, entityID, action)
+}
+
+// recordContactChange records a change of an entity that belongs to a
+// contact and has no vault column of its own.
+func recordContactChange(db *gorm.DB, contactID, entityType string, entityID interface{}, action string) error {
+	var vaultIDs []string
+	if err := db.Unscoped().Model(&models.Contact{}).Where("id = ?", contactID).Pluck("vault_id", &vaultIDs).Error; err != nil {
+		return err
+	}
+	if len(vaultIDs) == 0 {
+		return fmt.Errorf("find the vault of contact %s: %w", contactID, gorm.ErrRecordNotFound)
+	}
+	return recordVaultChange(db, vaultIDs[0], entityType, entityID, action)
+}
+
+// recordImportedVaultChanges records every entity a bulk import created in
+// the vault. Imports that commit row by row call it at the end and fail
+// when it does; the backfill at startup then logs what they left out.
+func recordImportedVaultChanges(db *gorm.DB, vaultID string) error {
+	if err := models.BackfillVaultChangesForVault(db, vaultID); err != nil {
+		return fmt.Errorf("record imported entities in the change log of vault %s: %w", vaultID, err)
+	}
+	notifyVaultStreams(vaultID)
+	return nil
+}
+
+type VaultChangeService struct {
+	db *gorm.DB
+}
+
+func NewVaultChangeService(db *gorm.DB) *VaultChangeService {
+	return &VaultChangeService{db: db}
+}
+
+// A sync cursor is the Seq of the last change it covers.
+func encodeSyncCursor(seq uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(seq), 10)))
+}
+
+func decodeSyncCursor(cursor string) (uint, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidSyncCursor
+	}
+	seq, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidSyncCursor
+	}
+	return uint(seq), nil
+}
+
+type vaultChangeEntity struct {
+	EntityType string
+	EntityID   string
+	LastSeq    uint
+}
+
+// List returns the entities of a vault that changed after the cursor, each
+// once with its current state, oldest change first. The returned cursor
+// resumes after the last listed change; an empty cursor starts from the
+// beginning of the log.
+func (s *VaultChangeService) List(vaultID, userID, cursor string, limit int) (*dto.VaultChangesResponse, error) {
+	since, err := decodeSyncCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		limit = defaultVaultChangeLimit
+	}
+	if limit > maxVaultChangeLimit {
+		limit = maxVaultChangeLimit
+	}
+
+	var entities []vaultChangeEntity
+	if err := s.db.Model(&models.VaultChange{}).
+		Select("entity_type, entity_id, MAX(seq) AS last_seq").
+		Where("vault_id = ? AND seq > ?", vaultID, since).
+		Group("entity_type, entity_id").
+		Order("last_seq ASC").
+		Limit(limit + 1).
+		Scan(&entities).Error; err != nil {
+		return nil, err
+	}
+	result := &dto.VaultChangesResponse{Changes: []dto.VaultChangeItem{}, Cursor: encodeSyncCursor(since)}
+	if len(entities) > limit {
+		entities = entities[:limit]
+		result.HasMore = true
+	}
+	if len(entities) == 0 {
+		return result, nil
+	}
+
+	lastSeqs := make([]uint, len(entities))
+	for i, entity := range entities {
+		lastSeqs[i] = entity.LastSeq
+	}
+	var latest []models.VaultChange
+	if err := s.db.Where("vault_id = ? AND seq IN ?", vaultID, lastSeqs).Find(&latest).Error; err != nil {
+		return nil, err
+	}
+	latestBySeq := make(map[uint]models.VaultChange, len(latest))
+	for _, change := range latest {
+		latestBySeq[change.Seq] = change
+	}
+
+	// Only entities that still exist are loaded; the rest become tombstones.
+	idsByType := make(map[string][]string)
+	for _, entity := range entities {
+		if latestBySeq[entity.LastSeq].Action != models.VaultChangeDeleted {
+			idsByType[entity.EntityType] = append(idsByType[entity.EntityType], entity.EntityID)
+		}
+	}
+	data := make(map[string]map[string]interface{}, len(idsByType))
+	created := make(map[string]map[string]bool, len(idsByType))
+	for entityType, ids := range idsByType {
+		if data[entityType], err = s.loadEntities(vaultID, userID, entityType, ids); err != nil {
+			return nil, err
+		}
+		var createdIDs []string
+		if err := s.db.Model(&models.VaultChange{}).
+			Where("vault_id = ? AND seq > ? AND entity_type = ? AND entity_id IN ? AND action = ?", vaultID, since, entityType, ids, models.VaultChangeCreated).
+			Pluck("entity_id", &createdIDs).Error; err != nil {
+			return nil, err
+		}
+		created[entityType] = make(map[string]bool, len(createdIDs))
+		for _, id := range createdIDs {
+			created[entityType][id] = true
+		}
+	}
+
+	for _, entity := range entities {
+		change := latestBySeq[entity.LastSeq]
+		item := dto.VaultChangeItem{
+			EntityType: entity.EntityType,
+			EntityID:   entity.EntityID,
+			Action:     models.VaultChangeDeleted,
+			ChangedAt:  change.CreatedAt,
+		}
+		if value, ok := data[entity.EntityType][entity.EntityID]; ok {
+			item.Data = value
+			item.Action = models.VaultChangeUpdated
+			if created[entity.EntityType][entity.EntityID] {
+				item.Action = models.VaultChangeCreated
+			}
+		}
+		result.Changes = append(result.Changes, item)
+	}
+	result.Cursor = encodeSyncCursor(entities[len(entities)-1].LastSeq)
+	return result, nil
+}
+
+// loadEntities returns the API representation of the given entities that
+// still exist in the vault, keyed by entity ID.
+func (s *VaultChangeService) loadEntities(vaultID, userID, entityType string, ids []string) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(ids))
+	liveContacts := s.db.Model(&models.Contact{}).Select("id").Where("vault_id = ?", vaultID)
+	// Every entity type except contacts has a numeric ID.
+	numericIDs := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+			numericIDs = append(numericIDs, uint(n))
+		}
+	}
+	switch entityType {
+	case models.VaultChangeContact:
+		var contacts []models.Contact
+		if err := s.db.Preload("FirstMetThrough", "vault_id = ?", vaultID).Where("vault_id = ? AND id IN ?", vaultID, ids).Find(&contacts).Error; err != nil {
+			return nil, err
+		}
+		formatter, err := newContactNameFormatter(s.db, userID)
+		if err != nil {
+			return nil, err
+		}
+		var favorites []models.ContactVaultUser
+		if err := s.db.Where("contact_id IN ? AND user_id = ? AND is_favorite = ?", ids, userID, true).Find(&favorites).Error; err != nil {
+			return nil, err
+		}
+		isFavorite := make(map[string]bool, len(favorites))
+		for _, favorite := range favorites {
+			isFavorite[favorite.ContactID] = true
+		}
+		for i := range contacts {
+			resp, err := toContactResponse(&contacts[i], isFavorite[contacts[i].ID], formatter)
+			if err != nil {
+				return nil, err
+			}
+			out[contacts[i].ID] = resp
+		}
+	case models.VaultChangeNote:
+		var notes []models.Note
+		if err := s.db.Where("vault_id = ? AND id IN ? AND contact_id IN (?)", vaultID, numericIDs, liveContacts).Find(&notes).Error; err != nil {
+			return nil, err
+		}
+		for i := range notes {
+			out[strconv.FormatUint(uint64(notes[i].ID), 10)] = toNoteResponse(&notes[i])
+		}
+	case models.VaultChangeTask:
+		var tasks []models.ContactTask
+		if err := s.db.Where("vault_id = ? AND id IN ?", vaultID, numericIDs).Find(&tasks).Error; err != nil {
+			return nil, err
+		}
+		responses, err := NewVaultTaskService(s.db).buildResponses(tasks, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, resp := range responses {
+			out[strconv.FormatUint(uint64(resp.ID), 10)] = resp
+		}
+	case models.VaultChangeReminder:
+		var reminders []models.ContactReminder
+		if err := s.db.Preload("SelectedUsers").Where("id IN ? AND contact_id IN (?)", numericIDs, liveContacts).Find(&reminders).Error; err != nil {
+			return nil, err
+		}
+		for i := range reminders {
+			out[strconv.FormatUint(uint64(reminders[i].ID), 10)] = toReminderResponse(&reminders[i])
+		}
+	case models.VaultChangeImportantDate:
+		var dates []models.ContactImportantDate
+		if err := s.db.Where("id IN ? AND contact_id IN (?)", numericIDs, liveContacts).Find(&dates).Error; err != nil {
+			return nil, err
+		}
+		for i := range dates {
+			out[strconv.FormatUint(uint64(dates[i].ID), 10)] = toImportantDateResponse(&dates[i])
+		}
+	case models.VaultChangeActivity:
+		var activities []models.Activity
+		if err := s.db.Preload("Participants").Preload("ActivityType.ActivityCategory").Where("vault_id = ? AND id IN ?", vaultID, numericIDs).Find(&activities).Error; err != nil {
+			return nil, err
+		}
+		activityService := NewActivityService(s.db)
+		for i := range activities {
+			resp, err := activityService.toActivityResponse(&activities[i], userID)
+			if err != nil {
+				return nil, err
+			}
+			out[strconv.FormatUint(uint64(activities[i].ID), 10)] = resp
+		}
+	case models.VaultChangePost:
+		var posts []models.Post
+		if err := s.db.Preload("PostSections", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC")
+		}).Preload("Contacts", "vault_id = ?", vaultID).
+			Where("id IN ? AND journal_id IN (?)", numericIDs, s.db.Model(&models.Journal{}).Select("id").Where("vault_id = ?", vaultID)).
+			Find(&posts).Error; err != nil {
+			return nil, err
+		}
+		for i := range posts {
+			out[strconv.FormatUint(uint64(posts[i].ID), 10)] = toPostResponseWithSections(&posts[i])
+		}
+	case models.VaultChangeCall:
+		var calls []models.Call
+		if err := s.db.Where("id IN ? AND contact_id IN (?)", numericIDs, liveContacts).Find(&calls).Error; err != nil {
+			return nil, err
+		}
+		for i := range calls {
+			out[strconv.FormatUint(uint64(calls[i].ID), 10)] = toCallResponse(&calls[i])
+		}
+	case models.VaultChangeFile:
+		var files []models.File
+		if err := s.db.Where("vault_id = ? AND id IN ?", vaultID, numericIDs).Find(&files).Error; err != nil {
+			return nil, err
+		}
+		for i := range files {
+			out[strconv.FormatUint(uint64(files[i].ID), 10)] = toVaultFileResponse(&files[i])
+		}
+	}
+	return out, nil
+}
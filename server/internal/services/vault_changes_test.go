@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+func findVaultChange(resp *dto.VaultChangesResponse, entityType, entityID string) *dto.VaultChangeItem {
+	for i := range resp.Changes {
+		if resp.Changes[i].EntityType == entityType && resp.Changes[i].EntityID == entityID {
+			return &resp.Changes[i]
+		}
+	}
+	return nil
+}
+
+func TestVaultChangesFullAndDeltaSync(t *testing.T) {
+	noteSvc, contactID, vaultID, userID := setupNoteTest(t)
+	svc := NewVaultChangeService(noteSvc.db)
+
+	note, err := noteSvc.Create(contactID, vaultID, userID, dto.CreateNoteRequest{Title: "First", Body: "Body"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	noteID := strconv.FormatUint(uint64(note.ID), 10)
+
+	full, err := svc.List(vaultID, userID, "", 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if full.HasMore || full.Cursor == "" {
+		t.Fatalf("unexpected full sync page: %+v", full)
+	}
+	contact := findVaultChange(full, models.VaultChangeContact, contactID)
+	if contact == nil || contact.Action != models.VaultChangeCreated {
+		t.Fatalf("expected the contact to be listed as created, got %+v", contact)
+	}
+	if resp, ok := contact.Data.(dto.ContactResponse); !ok || resp.FirstName != "John" {
+		t.Errorf("expected the contact data, got %#v", contact.Data)
+	}
+	created := findVaultChange(full, models.VaultChangeNote, noteID)
+	if created == nil || created.Action != models.VaultChangeCreated || created.Data == nil {
+		t.Fatalf("expected the note to be listed as created, got %+v", created)
+	}
+
+	if _, err := noteSvc.Update(note.ID, contactID, vaultID, dto.UpdateNoteRequest{Title: "Second", Body: "Body"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	delta, err := svc.List(vaultID, userID, full.Cursor, 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(delta.Changes) != 1 || delta.Changes[0].Action != models.VaultChangeUpdated {
+		t.Fatalf("expected only the updated note, got %+v", delta.Changes)
+	}
+	if resp, ok := delta.Changes[0].Data.(dto.NoteResponse); !ok || resp.Title != "Second" {
+		t.Errorf("expected the current note, got %#v", delta.Changes[0].Data)
+	}
+
+	if err := noteSvc.Delete(note.ID, contactID, vaultID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	delta, err = svc.List(vaultID, userID, delta.Cursor, 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(delta.Changes) != 1 || delta.Changes[0].Action != models.VaultChangeDeleted || delta.Changes[0].Data != nil {
+		t.Fatalf("expected a tombstone for the note, got %+v", delta.Changes)
+	}
+
+	empty, err := svc.List(vaultID, userID, delta.Cursor, 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(empty.Changes) != 0 || empty.Cursor != delta.Cursor {
+		t.Errorf("expected no changes and the same cursor, got %+v", empty)
+	}
+}
+
+func TestVaultChangesPaging(t *testing.T) {
+	noteSvc, contactID, vaultID, userID := setupNoteTest(t)
+	svc := NewVaultChangeService(noteSvc.db)
+	for i := 0; i < 3; i++ {
+		if _, err := noteSvc.Create(contactID, vaultID, userID, dto.CreateNoteRequest{Body: "Body"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	seen := 0
+	cursor := ""
+	for {
+		page, err := svc.List(vaultID, userID, cursor, 2)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		seen += len(page.Changes)
+		cursor = page.Cursor
+		if !page.HasMore {
+			break
+		}
+	}
+	// The contact and its three notes.
+	if seen != 4 {
+		t.Errorf("expected 4 changes across pages, got %d", seen)
+	}
+}
+
+func TestVaultChangesContactDeletionTombstones(t *testing.T) {
+	noteSvc, contactID, vaultID, userID := setupNoteTest(t)
+	svc := NewVaultChangeService(noteSvc.db)
+	note, err := noteSvc.Create(contactID, vaultID, userID, dto.CreateNoteRequest{Body: "Body"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	start, err := svc.List(vaultID, userID, "", 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	if err := NewContactService(noteSvc.db).DeleteContact(contactID, vaultID); err != nil {
+		t.Fatalf("DeleteContact failed: %v", err)
+	}
+	delta, err := svc.List(vaultID, userID, start.Cursor, 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if c := findVaultChange(delta, models.VaultChangeContact, contactID); c == nil || c.Action != models.VaultChangeDeleted {
+		t.Errorf("expected a contact tombstone, got %+v", delta.Changes)
+	}
+	if c := findVaultChange(delta, models.VaultChangeNote, strconv.FormatUint(uint64(note.ID), 10)); c == nil || c.Action != models.VaultChangeDeleted {
+		t.Errorf("expected a note tombstone, got %+v", delta.Changes)
+	}
+}
+
+func TestVaultChangesBackfill(t *testing.T) {
+	noteSvc, contactID, vaultID, userID := setupNoteTest(t)
+	if _, err := noteSvc.Create(contactID, vaultID, userID, dto.CreateNoteRequest{Body: "Body"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	// Simulate data written before the change log existed.
+	if err := noteSvc.db.Where("1 = 1").Delete(&models.VaultChange{}).Error; err != nil {
+		t.Fatalf("clear change log: %v", err)
+	}
+	if err := noteSvc.db.Where("1 = 1").Delete(&models.VaultChangeCounter{}).Error; err != nil {
+		t.Fatalf("clear change counters: %v", err)
+	}
+	if err := models.BackfillVaultChanges(noteSvc.db); err != nil {
+		t.Fatalf("BackfillVaultChanges failed: %v", err)
+	}
+	if err := models.BackfillVaultChanges(noteSvc.db); err != nil {
+		t.Fatalf("second BackfillVaultChanges failed: %v", err)
+	}
+
+	resp, err := NewVaultChangeService(noteSvc.db).List(vaultID, userID, "", 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(resp.Changes) != 2 {
+		t.Fatalf("expected the contact and the note once each, got %+v", resp.Changes)
+	}
+	var count int64
+	noteSvc.db.Model(&models.VaultChange{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected the backfill to be idempotent, got %d rows", count)
+	}
+
+	// A vault seeded with the current sources is not scanned again.
+	if err := noteSvc.db.Where("1 = 1").Delete(&models.VaultChange{}).Error; err != nil {
+		t.Fatalf("clear change log: %v", err)
+	}
+	if err := models.BackfillVaultChanges(noteSvc.db); err != nil {
+		t.Fatalf("third BackfillVaultChanges failed: %v", err)
+	}
+	noteSvc.db.Model(&models.VaultChange{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected the backfill to run once per vault, got %d rows", count)
+	}
+}
+
+func TestVaultChangesFollowSeqNotID(t *testing.T) {
+	noteSvc, contactID, vaultID, userID := setupNoteTest(t)
+	svc := NewVaultChangeService(noteSvc.db)
+	note, err := noteSvc.Create(contactID, vaultID, userID, dto.CreateNoteRequest{Body: "Body"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	start, err := svc.List(vaultID, userID, "", 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	// A change whose ID was taken before the cursor's but which committed
+	// after it, as happens with concurrent transactions on PostgreSQL.
+	var first models.VaultChange
+	if err := noteSvc.db.Where("vault_id = ?", vaultID).Order("id ASC").First(&first).Error; err != nil {
+		t.Fatalf("load change: %v", err)
+	}
+	if err := noteSvc.db.Delete(&first).Error; err != nil {
+		t.Fatalf("delete change: %v", err)
+	}
+	if err := noteSvc.db.Transaction(func(tx *gorm.DB) error {
+		seq, err := models.ReserveVaultChangeSeqs(tx, vaultID, 1)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.VaultChange{ID: first.ID, VaultID: vaultID, Seq: seq, EntityType: models.VaultChangeNote, EntityID: strconv.FormatUint(uint64(note.ID), 10), Action: models.VaultChangeUpdated}).Error
+	}); err != nil {
+		t.Fatalf("record late change: %v", err)
+	}
+
+	delta, err := svc.List(vaultID, userID, start.Cursor, 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(delta.Changes) != 1 || delta.Changes[0].EntityType != models.VaultChangeNote {
+		t.Errorf("expected the late change to be listed, got %+v", delta.Changes)
+	}
+}
+
+func TestVaultChangesInvalidCursor(t *testing.T) {
+	noteSvc, _, vaultID, userID := setupNoteTest(t)
+	_, err := NewVaultChangeService(noteSvc.db).List(vaultID, userID, "not a cursor!", 0)
+	if !errors.Is(err, ErrInvalidSyncCursor) {
+		t.Errorf("expected ErrInvalidSyncCursor, got %v", err)
+	}
+}
+
+func TestVaultChangesRollBackWritesThatCannotBeLogged(t *testing.T) {
+	noteSvc, contactID, vaultID, userID := setupNoteTest(t)
+	note, err := noteSvc.Create(contactID, vaultID, userID, dto.CreateNoteRequest{Body: "Kept"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := noteSvc.db.Migrator().DropTable(&models.VaultChange{}); err != nil {
+		t.Fatalf("drop change log: %v", err)
+	}
+
+	if _, err := noteSvc.Create(contactID, vaultID, userID, dto.CreateNoteRequest{Body: "Lost"}); err == nil {
+		t.Error("expected Create to fail when the change cannot be logged")
+	}
+	if _, err := noteSvc.Update(note.ID, contactID, vaultID, dto.UpdateNoteRequest{Body: "Changed"}); err == nil {
+		t.Error("expected Update to fail when the change cannot be logged")
+	}
+	if err := noteSvc.Delete(note.ID, contactID, vaultID); err == nil {
+		t.Error("expected Delete to fail when the change cannot be logged")
+	}
+
+	var notes []models.Note
+	if err := noteSvc.db.Where("contact_id = ?", contactID).Find(&notes).Error; err != nil {
+		t.Fatalf("load notes: %v", err)
+	}
+	if len(notes) != 1 || notes[0].ID != note.ID || notes[0].Body != "Kept" {
+		t.Errorf("expected the failed writes to roll back, got %+v", notes)
+	}
+}
@@ -187,9 +187,14 @@ func (s *VaultFileService) Upload(vaultID string, contactID string, authorID str
 		file.UfileableID = &contactID
 	}
 
-	if err := s.db.Create(&file).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&file).Error; err != nil {
+			return fmt.Errorf("failed to save file record: %w", err)
+		}
+		return recordVaultChange(tx, vaultID, models.VaultChangeFile, file.ID, models.VaultChangeCreated)
+	}); err != nil {
 		os.Remove(destPath)
-		return nil, fmt.Errorf("failed to save file record: %w", err)
+		return nil, err
 	}
 
 	if s.feedRecorder != nil && contactID != "" {
@@ -252,7 +257,12 @@ func (s *VaultFileService) deleteFileRecord(file *models.File) error {
 	if err := os.Remove(destPath); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return fmt.Errorf("remove file %s: %w", file.UUID, err)
 	}
-	return s.db.Delete(file).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(file).Error; err != nil {
+			return err
+		}
+		return recordVaultChange(tx, file.VaultID, models.VaultChangeFile, file.ID, models.VaultChangeDeleted)
+	})
 }
 
 func toVaultFileResponse(f *models.File) dto.VaultFileResponse {
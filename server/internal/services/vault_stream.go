@@ -0,0 +1,282 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidStreamEventID = errors.New("invalid stream event id")
+
+const (
+	// vaultStreamPollInterval bounds the delay of events written by other
+	// replicas; writes of this process wake the streams at once.
+	vaultStreamPollInterval = time.Second
+	vaultStreamPageSize     = 500
+	feedItemStreamEventType = "feed_item.created"
+)
+
+// vaultStreamHub wakes the streams of a vault when it is written to. Writes
+// of this process signal it directly; writes of other replicas are found by
+// one watcher per vault, so that open streams do not each poll the database.
+var vaultStreamHub = struct {
+	sync.Mutex
+	watchers map[string]*vaultStreamWatcher
+}{watchers: make(map[string]*vaultStreamWatcher)}
+
+// vaultStreamWatcher holds the streams open on one vault. Its goroutine
+// polls the head of the vault's change log and feed while it has any.
+type vaultStreamWatcher struct {
+	subscribers map[chan struct{}]struct{}
+	stop        chan struct{}
+}
+
+// vaultStreamHead is the Seq of the newest change and feed item of a vault.
+// Both come from the vault's change counter, so that an item committed late
+// never appears behind the head, as it could with IDs on PostgreSQL.
+type vaultStreamHead struct {
+	changeSeq uint
+	feedSeq   uint
+}
+
+func notifyVaultStreams(vaultID string) {
+	vaultStreamHub.Lock()
+	defer vaultStreamHub.Unlock()
+	if w := vaultStreamHub.watchers[vaultID]; w != nil {
+		w.wakeAll()
+	}
+}
+
+// wakeAll must be called with vaultStreamHub locked.
+func (w *vaultStreamWatcher) wakeAll() {
+	for wake := range w.subscribers {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *vaultStreamWatcher) run(db *gorm.DB, vaultID string, head vaultStreamHead) {
+	ticker := time.NewTicker(vaultStreamPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		next, err := loadVaultStreamHead(db, vaultID)
+		if err != nil {
+			log.Printf("[vault-stream] failed to poll vault %s: %v", vaultID, err)
+			continue
+		}
+		if next != head {
+			head = next
+			notifyVaultStreams(vaultID)
+		}
+	}
+}
+
+func loadVaultStreamHead(db *gorm.DB, vaultID string) (vaultStreamHead, error) {
+	var head vaultStreamHead
+	if err := db.Model(&models.VaultChange{}).Where("vault_id = ?", vaultID).
+		Select("COALESCE(MAX(seq), 0)").Scan(&head.changeSeq).Error; err != nil {
+		return head, err
+	}
+	err := db.Model(&models.ContactFeedItem{}).Where("vault_id = ?", vaultID).
+		Select("COALESCE(MAX(seq), 0)").Scan(&head.feedSeq).Error
+	return head, err
+}
+
+type VaultStreamService struct {
+	db                 *gorm.DB
+	vaultService       *VaultService
+	vaultChangeService *VaultChangeService
+	feedService        *FeedService
+}
+
+func NewVaultStreamService(db *gorm.DB) *VaultStreamService {
+	return &VaultStreamService{
+		db:                 db,
+		vaultService:       NewVaultService(db),
+		vaultChangeService: NewVaultChangeService(db),
+		feedService:        NewFeedService(db),
+	}
+}
+
+// VaultStream follows the change log and the feed of one vault for one
+// subscriber. Its position is the Seq of the last change and feed item it
+// delivered.
+type VaultStream struct {
+	svc       *VaultStreamService
+	vaultID   string
+	userID    string
+	sessionID string
+	changeSeq uint
+	feedSeq   uint
+	wake      chan struct{}
+}
+
+// Open starts a stream after the event with lastEventID, or at the current
+// end of the vault's history when lastEventID is empty. sessionID is the
+// session of the subscriber's access token, or "" when it has none.
+func (s *VaultStreamService) Open(vaultID, userID, sessionID, lastEventID string) (*VaultStream, error) {
+	stream := &VaultStream{svc: s, vaultID: vaultID, userID: userID, sessionID: sessionID, wake: make(chan struct{}, 1)}
+	if lastEventID != "" {
+		changeSeq, feedSeq, err := parseStreamEventID(lastEventID)
+		if err != nil {
+			return nil, err
+		}
+		stream.changeSeq, stream.feedSeq = changeSeq, feedSeq
+		// Deliver what was missed without waiting for the next write.
+		stream.wake <- struct{}{}
+	}
+
+	// Subscribe before reading the head, so that no write between the two
+	// goes unnoticed.
+	vaultStreamHub.Lock()
+	w := vaultStreamHub.watchers[vaultID]
+	started := w == nil
+	if started {
+		w = &vaultStreamWatcher{subscribers: make(map[chan struct{}]struct{}), stop: make(chan struct{})}
+		vaultStreamHub.watchers[vaultID] = w
+	}
+	w.subscribers[stream.wake] = struct{}{}
+	vaultStreamHub.Unlock()
+
+	if lastEventID == "" || started {
+		head, err := loadVaultStreamHead(s.db, vaultID)
+		if started {
+			// A failed read leaves a zero head, which only costs the
+			// watcher's other streams one spurious wake.
+			go w.run(s.db, vaultID, head)
+		}
+		if err != nil {
+			stream.Close()
+			return nil, err
+		}
+		if lastEventID == "" {
+			stream.changeSeq, stream.feedSeq = head.changeSeq, head.feedSeq
+		}
+	}
+	return stream, nil
+}
+
+// Wake receives when the vault may have new events for the stream.
+func (st *VaultStream) Wake() <-chan struct{} {
+	return st.wake
+}
+
+// Close stops the wake signals of the stream, and the vault's watcher with
+// its last stream.
+func (st *VaultStream) Close() {
+	vaultStreamHub.Lock()
+	defer vaultStreamHub.Unlock()
+	w := vaultStreamHub.watchers[st.vaultID]
+	if w == nil {
+		return
+	}
+	delete(w.subscribers, st.wake)
+	if len(w.subscribers) == 0 {
+		close(w.stop)
+		delete(vaultStreamHub.watchers, st.vaultID)
+	}
+}
+
+// CheckAccess reports whether the subscriber may still follow the vault. It
+// fails with ErrUserNotFound or ErrUserDisabled once the user is gone or
+// disabled, with ErrSessionRevoked once their session has ended, and with
+// ErrVaultForbidden or ErrInsufficientPerm once they lost access to the
+// vault.
+func (st *VaultStream) CheckAccess() error {
+	var user models.User
+	if err := st.svc.db.Select("disabled").Where("id = ?", st.userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if user.Disabled {
+		return ErrUserDisabled
+	}
+	if st.sessionID != "" {
+		var session models.UserSession
+		if err := st.svc.db.Where("id = ?", st.sessionID).First(&session).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSessionRevoked
+			}
+			return err
+		}
+		if session.UserID != st.userID || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+			return ErrSessionRevoked
+		}
+	}
+	return st.svc.vaultService.CheckUserVaultAccess(st.userID, st.vaultID, models.PermissionViewer)
+}
+
+// Poll returns the events since the last poll, changes first.
+func (st *VaultStream) Poll() ([]dto.VaultStreamEvent, error) {
+	var events []dto.VaultStreamEvent
+	for {
+		changes, err := st.svc.vaultChangeService.List(st.vaultID, st.userID, encodeSyncCursor(st.changeSeq), vaultStreamPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if st.changeSeq, err = decodeSyncCursor(changes.Cursor); err != nil {
+			return nil, err
+		}
+		// The cursor covers the whole page, so only its last event carries
+		// an ID; a client that drops mid-page gets the page again.
+		for i, change := range changes.Changes {
+			event := dto.VaultStreamEvent{Type: change.EntityType + "." + change.Action, Data: change}
+			if i == len(changes.Changes)-1 {
+				event.ID = st.eventID()
+			}
+			events = append(events, event)
+		}
+		if !changes.HasMore {
+			break
+		}
+	}
+
+	entries, err := st.svc.feedService.listVaultFeedSince(st.vaultID, st.userID, st.feedSeq, vaultStreamPageSize)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		st.feedSeq = entry.seq
+		events = append(events, dto.VaultStreamEvent{ID: st.eventID(), Type: feedItemStreamEventType, Data: entry.item})
+	}
+	return events, nil
+}
+
+// eventID encodes the position of the stream, so a reconnecting client
+// resumes from the Last-Event-ID it received.
+func (st *VaultStream) eventID() string {
+	return fmt.Sprintf("%d-%d", st.changeSeq, st.feedSeq)
+}
+
+func parseStreamEventID(id string) (changeSeq, feedSeq uint, err error) {
+	changePart, feedPart, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, ErrInvalidStreamEventID
+	}
+	change, err := strconv.ParseUint(changePart, 10, 64)
+	if err != nil {
+		return 0, 0, ErrInvalidStreamEventID
+	}
+	feed, err := strconv.ParseUint(feedPart, 10, 64)
+	if err != nil {
+		return 0, 0, ErrInvalidStreamEventID
+	}
+	return uint(change), uint(feed), nil
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestVaultStreamWakesAndResumes(t *testing.T) {
+	noteSvc, contactID, vaultID, userID := setupNoteTest(t)
+	svc := NewVaultStreamService(noteSvc.db)
+
+	stream, err := svc.Open(vaultID, userID, "", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer stream.Close()
+	events, err := stream.Poll()
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected a new stream to start after existing changes, got %+v", events)
+	}
+
+	if _, err := noteSvc.Create(contactID, vaultID, userID, dto.CreateNoteRequest{Title: "First", Body: "Body"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	select {
+	case <-stream.Wake():
+	case <-time.After(time.Second):
+		t.Fatal("expected the write to wake the stream")
+	}
+	events, err = stream.Poll()
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(events) == 0 || events[0].Type != "note.created" || events[len(events)-1].ID == "" {
+		t.Fatalf("expected a note.created event, got %+v", events)
+	}
+	lastEventID := events[len(events)-1].ID
+
+	if _, err := noteSvc.Create(contactID, vaultID, userID, dto.CreateNoteRequest{Title: "Second", Body: "Body"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	resumed, err := svc.Open(vaultID, userID, "", lastEventID)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer resumed.Close()
+	select {
+	case <-resumed.Wake():
+	default:
+		t.Fatal("expected a resumed stream to deliver what it missed at once")
+	}
+	events, err = resumed.Poll()
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(events) == 0 || events[0].Type != "note.created" {
+		t.Fatalf("expected only the second note, got %+v", events)
+	}
+	if item, ok := events[0].Data.(dto.VaultChangeItem); !ok || item.Data.(dto.NoteResponse).Title != "Second" {
+		t.Errorf("expected the second note, got %#v", events[0].Data)
+	}
+
+	if _, err := svc.Open(vaultID, userID, "", "not-an-id"); !errors.Is(err, ErrInvalidStreamEventID) {
+		t.Errorf("expected ErrInvalidStreamEventID, got %v", err)
+	}
+}
+
+func TestVaultStreamsShareOneWatcherPerVault(t *testing.T) {
+	noteSvc, contactID, vaultID, userID := setupNoteTest(t)
+	svc := NewVaultStreamService(noteSvc.db)
+
+	first, err := svc.Open(vaultID, userID, "", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	second, err := svc.Open(vaultID, userID, "", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	vaultStreamHub.Lock()
+	subscribers := len(vaultStreamHub.watchers[vaultID].subscribers)
+	vaultStreamHub.Unlock()
+	if subscribers != 2 {
+		t.Fatalf("expected one watcher with both streams, got %d streams", subscribers)
+	}
+
+	// A write of another replica reaches this process only through the
+	// watcher's poll.
+	if err := noteSvc.db.Create(&models.VaultChange{
+		VaultID: vaultID, Seq: 1000, EntityType: "note", EntityID: contactID, Action: "updated",
+	}).Error; err != nil {
+		t.Fatalf("create change: %v", err)
+	}
+	for _, stream := range []*VaultStream{first, second} {
+		select {
+		case <-stream.Wake():
+		case <-time.After(3 * vaultStreamPollInterval):
+			t.Fatal("expected the watcher to wake every stream of the vault")
+		}
+	}
+
+	first.Close()
+	second.Close()
+	vaultStreamHub.Lock()
+	defer vaultStreamHub.Unlock()
+	if _, ok := vaultStreamHub.watchers[vaultID]; ok {
+		t.Error("expected the watcher to stop with the last stream")
+	}
+}
+
+func TestVaultStreamFollowsFeedSeqNotID(t *testing.T) {
+	noteSvc, contactID, vaultID, userID := setupNoteTest(t)
+	svc := NewVaultStreamService(noteSvc.db)
+	recorder := NewFeedRecorder(noteSvc.db)
+	if err := recorder.Record(contactID, userID, ActionContactUpdated, "Early", nil, nil); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	var early models.ContactFeedItem
+	if err := noteSvc.db.Where("vault_id = ?", vaultID).Order("id DESC").First(&early).Error; err != nil {
+		t.Fatalf("load feed item: %v", err)
+	}
+	if err := recorder.Record(contactID, userID, ActionContactUpdated, "Late", nil, nil); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	// An item whose ID was taken before the other's but which committed
+	// after the stream opened, as happens with concurrent transactions on
+	// PostgreSQL.
+	if err := noteSvc.db.Delete(&early).Error; err != nil {
+		t.Fatalf("delete feed item: %v", err)
+	}
+	stream, err := svc.Open(vaultID, userID, "", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer stream.Close()
+	if err := noteSvc.db.Transaction(func(tx *gorm.DB) error {
+		seq, err := models.ReserveVaultChangeSeqs(tx, vaultID, 1)
+		if err != nil {
+			return err
+		}
+		early.Seq = seq
+		return tx.Create(&early).Error
+	}); err != nil {
+		t.Fatalf("record late feed item: %v", err)
+	}
+
+	events, err := stream.Poll()
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != feedItemStreamEventType {
+		t.Fatalf("expected the late feed item, got %+v", events)
+	}
+	if item := events[0].Data.(dto.FeedItemResponse); item.ID != early.ID {
+		t.Errorf("expected feed item %d, got %d", early.ID, item.ID)
+	}
+}
+
+func TestVaultStreamCheckAccessFollowsSessionAndUser(t *testing.T) {
+	noteSvc, _, vaultID, userID := setupNoteTest(t)
+	db := noteSvc.db
+	svc := NewVaultStreamService(db)
+	var session models.UserSession
+	if err := db.Where("user_id = ?", userID).First(&session).Error; err != nil {
+		t.Fatalf("find session: %v", err)
+	}
+
+	stream, err := svc.Open(vaultID, userID, session.ID, "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer stream.Close()
+	if err := stream.CheckAccess(); err != nil {
+		t.Fatalf("CheckAccess failed: %v", err)
+	}
+
+	if err := NewSessionService(db).Revoke(userID, session.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if err := stream.CheckAccess(); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("expected ErrSessionRevoked, got %v", err)
+	}
+
+	sessionless, err := svc.Open(vaultID, userID, "", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer sessionless.Close()
+	if err := db.Model(&models.User{}).Where("id = ?", userID).Update("disabled", true).Error; err != nil {
+		t.Fatalf("disable user: %v", err)
+	}
+	if err := sessionless.CheckAccess(); !errors.Is(err, ErrUserDisabled) {
+		t.Errorf("expected ErrUserDisabled, got %v", err)
+	}
+}
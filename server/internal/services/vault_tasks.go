@@ -36,7 +36,10 @@ type VaultTaskFilters struct {
 // List returns all tasks in a vault, ordered by status column then Position
 // within each column, with CreatedAt as a stable tiebreaker.
 func (s *VaultTaskService) List(vaultID string, filters VaultTaskFilters, userID string) ([]dto.VaultTaskResponse, error) {
-	q := s.db.Model(&models.ContactTask{}).Where("contact_tasks.vault_id = ?", vaultID)
+	// Superseded occurrences of recurring tasks are completion history, not
+	// cards: the board only shows the live occurrence of each series.
+	q := s.db.Model(&models.ContactTask{}).Where("contact_tasks.vault_id = ?", vaultID).
+		Scopes(currentTaskOccurrenceScope)
 	if filters.ContactID != nil {
 		if *filters.ContactID == "" {
 			// Standalone: no row in the pivot.
@@ -69,6 +72,9 @@ func (s *VaultTaskService) Create(vaultID, authorID string, req dto.CreateVaultT
 	if err := validateContactsBelongToVault(s.db, req.ContactIDs, vaultID); err != nil {
 		return nil, err
 	}
+	if err := validateUsersBelongToVault(s.db, req.AssigneeUserIDs, vaultID); err != nil {
+		return nil, err
+	}
 	if err := validateParentTask(s.db, req.ParentTaskID, 0, vaultID); err != nil {
 		return nil, err
 	}
@@ -87,10 +93,19 @@ func (s *VaultTaskService) Create(vaultID, authorID string, req dto.CreateVaultT
 	// DueAt and persist the original day/month/year so the reminder
 	// scheduler can re-resolve future recurrences in the same calendar.
 	applyTaskCalendarFields(&task, req.CalendarType, req.OriginalDay, req.OriginalMonth, req.OriginalYear)
+	if err := ApplyTaskRecurrence(&task, req.RecurrenceRule); err != nil {
+		return nil, err
+	}
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(&task).Error; err != nil {
 			return err
 		}
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeTask, task.ID, models.VaultChangeCreated); err != nil {
+			return err
+		}
+		if err := replaceTaskUserAssignees(tx, task.ID, req.AssigneeUserIDs, strPtrOrNil(authorID)); err != nil {
+			return err
+		}
 		return replaceTaskAssigneesLocked(tx, task.ID, req.ContactIDs)
 	})
 	if err != nil {
@@ -136,6 +151,11 @@ func (s *VaultTaskService) Update(id uint, vaultID string, req dto.UpdateVaultTa
 			return nil, err
 		}
 	}
+	if req.AssigneeUserIDs != nil {
+		if err := validateUsersBelongToVault(s.db, *req.AssigneeUserIDs, vaultID); err != nil {
+			return nil, err
+		}
+	}
 
 	updates := map[string]interface{}{
 		"label":       req.Label,
@@ -149,11 +169,16 @@ func (s *VaultTaskService) Update(id uint, vaultID string, req dto.UpdateVaultTa
 	scratch := task
 	scratch.DueAt = req.DueAt
 	applyTaskCalendarFields(&scratch, req.CalendarType, req.OriginalDay, req.OriginalMonth, req.OriginalYear)
+	if err := ApplyTaskRecurrence(&scratch, req.RecurrenceRule); err != nil {
+		return nil, err
+	}
 	updates["due_at"] = scratch.DueAt
 	updates["calendar_type"] = scratch.CalendarType
 	updates["original_day"] = scratch.OriginalDay
 	updates["original_month"] = scratch.OriginalMonth
 	updates["original_year"] = scratch.OriginalYear
+	updates["recurrence_rule"] = scratch.RecurrenceRule
+	updates["recurrence_series_id"] = scratch.RecurrenceSeriesID
 	if req.ParentTaskID.Present {
 		updates["parent_task_id"] = req.ParentTaskID.Ptr()
 	}
@@ -171,16 +196,25 @@ func (s *VaultTaskService) Update(id uint, vaultID string, req dto.UpdateVaultTa
 		}
 	}
 
+	wasCompleted := task.Completed
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Model(&task).Updates(updates).Error; err != nil {
 			return err
 		}
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeTask, task.ID, models.VaultChangeUpdated); err != nil {
+			return err
+		}
 		if req.ContactIDs != nil {
 			if err := replaceTaskAssigneesLocked(tx, task.ID, *req.ContactIDs); err != nil {
 				return err
 			}
 		}
-		return nil
+		if req.AssigneeUserIDs != nil {
+			if err := replaceTaskUserAssignees(tx, task.ID, *req.AssigneeUserIDs, strPtrOrNil(userID)); err != nil {
+				return err
+			}
+		}
+		return syncTaskOccurrenceByID(tx, task.ID, wasCompleted)
 	})
 	if err != nil {
 		return nil, err
@@ -211,6 +245,7 @@ func (s *VaultTaskService) UpdateStatus(id uint, vaultID string, req dto.UpdateT
 		return nil, err
 	}
 	updates := map[string]interface{}{"status": req.Status}
+	wasCompleted := task.Completed
 	if req.Status == models.TaskStatusDone && !task.Completed {
 		now := time.Now()
 		updates["completed"] = true
@@ -223,10 +258,18 @@ func (s *VaultTaskService) UpdateStatus(id uint, vaultID string, req dto.UpdateT
 		task.Completed = false
 		task.CompletedAt = nil
 	}
-	if err := s.db.Model(&task).Updates(updates).Error; err != nil {
+	task.Status = req.Status
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&task).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeTask, task.ID, models.VaultChangeUpdated); err != nil {
+			return err
+		}
+		return SyncTaskOccurrence(tx, &task, wasCompleted)
+	}); err != nil {
 		return nil, err
 	}
-	task.Status = req.Status
 
 	resps, err := s.buildResponses([]models.ContactTask{task}, userID)
 	if err != nil {
@@ -248,6 +291,20 @@ func (s *VaultTaskService) Delete(id uint, vaultID string) error {
 	return deleteTaskCascade(s.db, &task)
 }
 
+// ListCompletions returns the completion history of a task's recurrence
+// series, newest first. Any occurrence of the series (current or past) can
+// be used to look the history up.
+func (s *VaultTaskService) ListCompletions(id uint, vaultID string) ([]dto.TaskCompletionResponse, error) {
+	var task models.ContactTask
+	if err := s.db.Where("id = ? AND vault_id = ?", id, vaultID).First(&task).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	return listTaskCompletions(s.db, &task)
+}
+
 // UpdatePosition reorders a task within (or across) columns.
 func (s *VaultTaskService) UpdatePosition(id uint, vaultID string, req dto.UpdateTaskPositionRequest, userID string) (*dto.VaultTaskResponse, error) {
 	if req.Status != "" && !taskStatusExistsForVault(s.db, req.Status, vaultID) {
@@ -297,6 +354,12 @@ func (s *VaultTaskService) resequenceTaskPositions(task models.ContactTask, dest
 			Updates(updates).Error; err != nil {
 			return err
 		}
+		if err := recordVaultChange(tx, task.VaultID, models.VaultChangeTask, task.ID, models.VaultChangeUpdated); err != nil {
+			return err
+		}
+		if err := syncTaskOccurrenceByID(tx, task.ID, task.Completed); err != nil {
+			return err
+		}
 
 		if destinationStatus != task.Status {
 			if err := resequenceExistingTaskColumn(tx, task.VaultID, task.ID, task.Status); err != nil {
@@ -319,7 +382,7 @@ func resequenceExistingTaskColumn(tx *gorm.DB, vaultID string, movedTaskID uint,
 	for _, t := range tasks {
 		orderedIDs = append(orderedIDs, t.ID)
 	}
-	return updateTaskColumnPositions(tx, orderedIDs)
+	return updateTaskColumnPositions(tx, vaultID, orderedIDs)
 }
 
 func resequenceTaskColumn(tx *gorm.DB, vaultID string, movedTaskID uint, status, destinationStatus string, destinationPosition int) error {
@@ -349,22 +412,28 @@ func resequenceTaskColumn(tx *gorm.DB, vaultID string, movedTaskID uint, status,
 		if insertAt == len(tasks) {
 			orderedIDs = append(orderedIDs, movedTaskID)
 		}
-		return updateTaskColumnPositions(tx, orderedIDs)
+		return updateTaskColumnPositions(tx, vaultID, orderedIDs)
 	}
 
 	orderedIDs := make([]uint, 0, len(tasks))
 	for _, t := range tasks {
 		orderedIDs = append(orderedIDs, t.ID)
 	}
-	return updateTaskColumnPositions(tx, orderedIDs)
+	return updateTaskColumnPositions(tx, vaultID, orderedIDs)
 }
 
-func updateTaskColumnPositions(tx *gorm.DB, orderedIDs []uint) error {
+func updateTaskColumnPositions(tx *gorm.DB, vaultID string, orderedIDs []uint) error {
 	for position, id := range orderedIDs {
-		if err := tx.Model(&models.ContactTask{}).
-			Where("id = ?", id).
-			Update("position", position).Error; err != nil {
-			return err
+		result := tx.Model(&models.ContactTask{}).
+			Where("id = ? AND position <> ?", id, position).
+			Update("position", position)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			if err := recordVaultChange(tx, vaultID, models.VaultChangeTask, id, models.VaultChangeUpdated); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -382,9 +451,16 @@ func (s *VaultTaskService) buildResponses(tasks []models.ContactTask, userID str
 	if err != nil {
 		return nil, err
 	}
+	userAssignees, err := taskUserAssignees(s.db, ids)
+	if err != nil {
+		return nil, err
+	}
 	out := make([]dto.VaultTaskResponse, len(tasks))
 	for i, t := range tasks {
 		out[i] = toVaultTaskResponse(&t, assignees[t.ID])
+		if users := userAssignees[t.ID]; users != nil {
+			out[i].AssigneeUserIDs = users
+		}
 	}
 	return out, nil
 }
@@ -416,5 +492,9 @@ func toVaultTaskResponse(t *models.ContactTask, contacts []dto.TaskContactRef) d
 		OriginalYear:  t.OriginalYear,
 		CreatedAt:     t.CreatedAt,
 		UpdatedAt:     t.UpdatedAt,
+
+		RecurrenceRule:   ptrToStr(t.RecurrenceRule),
+		NextOccurrenceID: t.NextOccurrenceID,
+		AssigneeUserIDs:  []string{},
 	}
 }
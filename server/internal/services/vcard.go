@@ -90,6 +90,12 @@ func preloadContactVCardRelations(db *gorm.DB) *gorm.DB {
 }
 
 func (s *VCardService) ImportVCard(vaultID, userID string, data io.Reader) (*dto.VCardImportResponse, error) {
+	return s.ImportVCardWithProgress(vaultID, userID, data, nil)
+}
+
+// ImportVCardWithProgress is ImportVCard reporting one item per card. The
+// import runs in one transaction, so cancelling it rolls back every card.
+func (s *VCardService) ImportVCardWithProgress(vaultID, userID string, data io.Reader, progress JobProgress) (*dto.VCardImportResponse, error) {
 	dec := vcard.NewDecoder(data)
 	formatter, err := newContactNameFormatter(s.db, userID)
 	if err != nil {
@@ -106,12 +112,17 @@ func (s *VCardService) ImportVCard(vaultID, userID string, data io.Reader) (*dto
 			return fmt.Errorf("vault not found: %w", err)
 		}
 		accountID := vault.AccountID
+		phoneRegion := phoneRegionForUser(tx, userID)
 
 		for {
+			if progressCancelled(progress) {
+				return ErrJobCancelled
+			}
 			card, err := dec.Decode()
 			if err == io.EOF {
 				break
 			}
+			progressAdvance(progress, 1)
 			if err != nil {
 				skippedCount++
 				importErrors = append(importErrors, fmt.Sprintf("decode error: %v", err))
@@ -138,6 +149,9 @@ func (s *VCardService) ImportVCard(vaultID, userID string, data io.Reader) (*dto
 			if err := tx.Create(&contact).Error; err != nil {
 				return err
 			}
+			if err := recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeCreated); err != nil {
+				return err
+			}
 
 			cvu := models.ContactVaultUser{
 				ContactID: contact.ID,
@@ -148,7 +162,7 @@ func (s *VCardService) ImportVCard(vaultID, userID string, data io.Reader) (*dto
 				return err
 			}
 
-			if err := importVCardFields(tx, card, contact.ID, vaultID, accountID); err != nil {
+			if err := importVCardFields(tx, card, contact.ID, vaultID, accountID, phoneRegion); err != nil {
 				return err
 			}
 			if err := tx.Preload("FirstMetThrough", "vault_id = ?", vaultID).First(&contact, "id = ?", contact.ID).Error; err != nil {
@@ -175,8 +189,27 @@ func (s *VCardService) ImportVCard(vaultID, userID string, data io.Reader) (*dto
 	}, nil
 }
 
+// RunImportJob runs a vcard_import job on the .vcf file in its input.
+func (s *VCardService) RunImportJob(run *JobRun) (interface{}, []string, error) {
+	job := run.Job()
+	if job.VaultID == nil {
+		return nil, nil, errors.New("vcard import job has no vault")
+	}
+	run.SetTotal(bytes.Count(bytes.ToUpper(run.Input()), []byte("BEGIN:VCARD")))
+	resp, err := s.ImportVCardWithProgress(*job.VaultID, job.UserID, bytes.NewReader(run.Input()), run)
+	if err != nil {
+		return nil, nil, err
+	}
+	itemErrors := resp.Errors
+	resp.Errors = nil
+	// The imported contacts can be listed from the vault; the job keeps the counts.
+	resp.Contacts = nil
+	return resp, itemErrors, nil
+}
+
 // importVCardFields parses TEL, EMAIL, ADR, BDAY from a vCard and stores them.
-func importVCardFields(tx *gorm.DB, card vcard.Card, contactID, vaultID, accountID string) error {
+// Phone numbers without a country code are read in phoneRegion.
+func importVCardFields(tx *gorm.DB, card vcard.Card, contactID, vaultID, accountID, phoneRegion string) error {
 	// TEL → ContactInformation
 	if fields := card[vcard.FieldTelephone]; len(fields) > 0 {
 		var phoneType models.ContactInformationType
@@ -186,9 +219,10 @@ func importVCardFields(tx *gorm.DB, card vcard.Card, contactID, vaultID, account
 					continue
 				}
 				ci := models.ContactInformation{
-					ContactID: contactID,
-					TypeID:    phoneType.ID,
-					Data:      f.Value,
+					ContactID:      contactID,
+					TypeID:         phoneType.ID,
+					Data:           f.Value,
+					NormalizedData: models.NormalizedPhone(f.Value, phoneRegion),
 				}
 				if err := tx.Create(&ci).Error; err != nil {
 					return err
@@ -241,6 +275,9 @@ func importVCardFields(tx *gorm.DB, card vcard.Card, contactID, vaultID, account
 			if err := tx.Create(&ca).Error; err != nil {
 				return err
 			}
+			if _, err := queueGeocoding(tx, a); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -263,6 +300,9 @@ func importVCardFields(tx *gorm.DB, card vcard.Card, contactID, vaultID, account
 				if err := tx.Create(&cid).Error; err != nil {
 					return err
 				}
+				if err := recordVaultChange(tx, vaultID, models.VaultChangeImportantDate, cid.ID, models.VaultChangeCreated); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -631,8 +671,11 @@ func (s *VCardService) UpsertContactFromVCard(tx *gorm.DB, card vcard.Card, vaul
 			if err := tx.Save(&existing).Error; err != nil {
 				return "", "", err
 			}
+			if err := recordVaultChange(tx, vaultID, models.VaultChangeContact, existing.ID, models.VaultChangeUpdated); err != nil {
+				return "", "", err
+			}
 
-			if err := replaceVCardFields(tx, card, existing.ID, vaultID, accountID); err != nil {
+			if err := replaceVCardFields(tx, card, existing.ID, vaultID, accountID, phoneRegionForUser(tx, userID)); err != nil {
 				return "", "", err
 			}
 			return existing.ID, "updated", nil
@@ -663,6 +706,9 @@ func (s *VCardService) UpsertContactFromVCard(tx *gorm.DB, card vcard.Card, vaul
 	if err := tx.Create(&contact).Error; err != nil {
 		return "", "", err
 	}
+	if err := recordVaultChange(tx, vaultID, models.VaultChangeContact, contact.ID, models.VaultChangeCreated); err != nil {
+		return "", "", err
+	}
 
 	cvu := models.ContactVaultUser{
 		ContactID: contact.ID,
@@ -673,14 +719,14 @@ func (s *VCardService) UpsertContactFromVCard(tx *gorm.DB, card vcard.Card, vaul
 		return "", "", err
 	}
 
-	if err := importVCardFields(tx, card, contact.ID, vaultID, accountID); err != nil {
+	if err := importVCardFields(tx, card, contact.ID, vaultID, accountID, phoneRegionForUser(tx, userID)); err != nil {
 		return "", "", err
 	}
 
 	return contact.ID, "created", nil
 }
 
-func replaceVCardFields(tx *gorm.DB, card vcard.Card, contactID, vaultID, accountID string) error {
+func replaceVCardFields(tx *gorm.DB, card vcard.Card, contactID, vaultID, accountID, phoneRegion string) error {
 	tx.Where("contact_id = ?", contactID).Delete(&models.ContactInformation{})
 
 	var pivots []models.ContactAddress
@@ -694,7 +740,14 @@ func replaceVCardFields(tx *gorm.DB, card vcard.Card, contactID, vaultID, accoun
 		tx.Where("id IN ?", addressIDs).Delete(&models.Address{})
 	}
 
+	var dateIDs []uint
+	tx.Model(&models.ContactImportantDate{}).Where("contact_id = ?", contactID).Pluck("id", &dateIDs)
 	tx.Where("contact_id = ?", contactID).Delete(&models.ContactImportantDate{})
+	for _, id := range dateIDs {
+		if err := recordVaultChange(tx, vaultID, models.VaultChangeImportantDate, id, models.VaultChangeDeleted); err != nil {
+			return err
+		}
+	}
 
-	return importVCardFields(tx, card, contactID, vaultID, accountID)
+	return importVCardFields(tx, card, contactID, vaultID, accountID, phoneRegion)
 }
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrAutomationWebhookBlocked means a webhook URL points at an address of
+// the server's own network, which rules must not reach.
+var ErrAutomationWebhookBlocked = errors.New("webhook address is not public")
+
+const webhookLookupTimeout = 5 * time.Second
+
+// nonPublicNets are the ranges the net.IP predicates miss: "this network",
+// carrier-grade NAT and benchmarking.
+var nonPublicNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("198.18.0.0/15"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}
+
+// isPublicAddr reports whether ip may be called by a webhook. Loopback,
+// private and link-local addresses, which include cloud metadata services
+// such as 169.254.169.254, are not.
+func isPublicAddr(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, ipNet := range nonPublicNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveWebhookHost looks up host and fails unless allowed accepts every
+// address it has.
+func resolveWebhookHost(ctx context.Context, host string, allowed func(net.IP) bool) ([]net.IP, error) {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, ErrAutomationWebhookBlocked
+	}
+	for _, ip := range ips {
+		if !allowed(ip) {
+			return nil, ErrAutomationWebhookBlocked
+		}
+	}
+	return ips, nil
+}
+
+// checkWebhookURL rejects webhook URLs whose host does not resolve to
+// public addresses when the rule is saved.
+func checkWebhookURL(u *url.URL, allowed func(net.IP) bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookLookupTimeout)
+	defer cancel()
+	_, err := resolveWebhookHost(ctx, u.Hostname(), allowed)
+	return err
+}
+
+// newWebhookClient returns a client that checks every address it connects
+// to, so a redirect or a DNS answer that changed since the rule was saved
+// cannot reach the server's network either. It dials the checked address
+// itself and ignores proxy settings, which would hide the real target.
+func newWebhookClient(allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{Timeout: automationWebhookTimeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := resolveWebhookHost(ctx, host, allowed)
+		if err != nil {
+			return nil, err
+		}
+		var conn net.Conn
+		for _, ip := range ips {
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+	return &http.Client{Timeout: automationWebhookTimeout, Transport: transport}
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Web Push (RFC 8030) with VAPID authentication (RFC 8292) and aes128gcm
+// payload encryption (RFC 8291). The destination stored in
+// UserNotificationChannel.Content is the browser's PushSubscription JSON.
+const (
+	webPushChannelType = "webpush"
+
+	webPushVAPIDPrivateKeySetting = "webpush.vapid_private_key"
+	webPushVAPIDSubjectSetting    = "webpush.vapid_subject"
+
+	webPushTTLSeconds    = 7 * 24 * 60 * 60
+	webPushRecordSize    = 4096
+	webPushMaxBodyLength = 2048
+	webPushVAPIDLifetime = 12 * time.Hour
+)
+
+var (
+	ErrWebPushInvalidSubscription = errors.New("invalid web push subscription")
+	// ErrWebPushSubscriptionGone is returned when the push service reports
+	// the subscription as expired or unsubscribed (404/410); the channel is
+	// deactivated instead of being retried.
+	ErrWebPushSubscriptionGone = errors.New("web push subscription is no longer valid")
+)
+
+// webPushSubscription mirrors PushSubscription.toJSON() in the browser.
+type webPushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// webPushMessage is the JSON payload delivered to the service worker's push
+// event.
+type webPushMessage struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url"`
+}
+
+// WebPushService owns the server's VAPID key pair and delivers encrypted
+// push messages. It implements NotificationSender with the subscription JSON
+// as destination.
+type WebPushService struct {
+	settings *SystemSettingService
+	client   *http.Client
+	keyMu    sync.Mutex
+}
+
+func NewWebPushService(settings *SystemSettingService) *WebPushService {
+	return &WebPushService{settings: settings, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// PublicKey returns the VAPID application server key (uncompressed P-256
+// point, base64url) that browsers pass to pushManager.subscribe.
+func (s *WebPushService) PublicKey() (string, error) {
+	key, err := s.vapidKey()
+	if err != nil {
+		return "", err
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(pub), nil
+}
+
+// vapidKey loads the VAPID private key, generating and storing one on first
+// use. Only the private key is persisted (as a secret setting, so it is
+// encrypted at rest); the public key is derived from it, so the two can
+// never disagree.
+func (s *WebPushService) vapidKey() (*ecdsa.PrivateKey, error) {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+
+	stored, err := s.settings.Get(webPushVAPIDPrivateKeySetting)
+	if err != nil && !errors.Is(err, ErrSystemSettingNotFound) {
+		return nil, fmt.Errorf("load VAPID key: %w", err)
+	}
+	if stored == "" {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("generate VAPID key: %w", err)
+		}
+		raw, err := key.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		if err := s.settings.Set(webPushVAPIDPrivateKeySetting, base64.RawURLEncoding.EncodeToString(raw)); err != nil {
+			return nil, fmt.Errorf("store VAPID key: %w", err)
+		}
+		// Read back so that concurrent first uses agree on one key.
+		if stored, err = s.settings.Get(webPushVAPIDPrivateKeySetting); err != nil {
+			return nil, fmt.Errorf("load VAPID key: %w", err)
+		}
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(stored)
+	if err != nil {
+		return nil, fmt.Errorf("decode VAPID key: %w", err)
+	}
+	return ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+}
+
+// vapidSubject is the contact URI push services use to reach the operator:
+// the configured subject, else an https app URL, else the SMTP sender.
+func (s *WebPushService) vapidSubject() string {
+	if subject := s.settings.GetWithDefault(webPushVAPIDSubjectSetting, ""); subject != "" {
+		return subject
+	}
+	if appURL := s.appURL(); strings.HasPrefix(appURL, "https://") {
+		return appURL
+	}
+	if from := s.settings.GetWithDefault("smtp.from", ""); strings.Contains(from, "@") {
+		return "mailto:" + from
+	}
+	return "mailto:admin@localhost"
+}
+
+func (s *WebPushService) appURL() string {
+	return s.settings.GetWithDefault("app.url", "http://localhost:8080")
+}
+
+// Send encrypts and delivers one message to a push subscription.
+func (s *WebPushService) Send(subscriptionJSON, subject, message string) error {
+	sub, uaPublic, authSecret, err := parseWebPushSubscription(subscriptionJSON)
+	if err != nil {
+		return err
+	}
+	body := strings.TrimSpace(stripHTML(message))
+	if len(body) > webPushMaxBodyLength {
+		body = strings.ToValidUTF8(body[:webPushMaxBodyLength], "") + "…"
+	}
+	payload, err := json.Marshal(webPushMessage{Title: subject, Body: body, URL: s.appURL()})
+	if err != nil {
+		return err
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	encrypted, err := encryptWebPushPayload(payload, uaPublic, authSecret, asPrivate, salt)
+	if err != nil {
+		return err
+	}
+	authorization, err := s.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.Endpoint, bytes.NewReader(encrypted))
+	if err != nil {
+		return fmt.Errorf("build push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", fmt.Sprint(webPushTTLSeconds))
+	req.Header.Set("Urgency", "normal")
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrWebPushSubscriptionGone
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		log.Printf("[webpush] push service %s returned %d", truncateURL(sub.Endpoint), resp.StatusCode)
+		return fmt.Errorf("push service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// vapidAuthorization builds the RFC 8292 "vapid" Authorization header for
+// the push service that hosts endpoint.
+func (s *WebPushService) vapidAuthorization(endpoint string) (string, error) {
+	key, err := s.vapidKey()
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", ErrWebPushInvalidSubscription
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(webPushVAPIDLifetime).Unix(),
+		"sub": s.vapidSubject(),
+	}).SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("sign VAPID token: %w", err)
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("vapid t=%s, k=%s", token, base64.RawURLEncoding.EncodeToString(pub)), nil
+}
+
+// parseWebPushSubscription validates a subscription and decodes its keys.
+func parseWebPushSubscription(raw string) (*webPushSubscription, *ecdh.PublicKey, []byte, error) {
+	var sub webPushSubscription
+	if err := json.Unmarshal([]byte(raw), &sub); err != nil {
+		return nil, nil, nil, ErrWebPushInvalidSubscription
+	}
+	u, err := url.Parse(sub.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, nil, nil, ErrWebPushInvalidSubscription
+	}
+	p256dh, err := decodeWebPushKey(sub.Keys.P256dh)
+	if err != nil {
+		return nil, nil, nil, ErrWebPushInvalidSubscription
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(p256dh)
+	if err != nil {
+		return nil, nil, nil, ErrWebPushInvalidSubscription
+	}
+	authSecret, err := decodeWebPushKey(sub.Keys.Auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, nil, nil, ErrWebPushInvalidSubscription
+	}
+	return &sub, uaPublic, authSecret, nil
+}
+
+// decodeWebPushKey accepts base64url with or without padding, which is what
+// browsers produce.
+func decodeWebPushKey(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// encryptWebPushPayload encrypts payload for one user agent as a single
+// aes128gcm record (RFC 8291 §3-4, RFC 8188). asPrivate and salt must be
+// fresh for every message; they are parameters so the RFC test vector can
+// be reproduced.
+func encryptWebPushPayload(payload []byte, uaPublic *ecdh.PublicKey, authSecret []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic.Bytes()...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last (and only) record; no padding.
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	if len(plaintext)+gcm.Overhead() > webPushRecordSize {
+		return nil, fmt.Errorf("web push payload too large: %d bytes", len(payload))
+	}
+
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
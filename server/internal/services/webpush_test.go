@@ -0,0 +1,372 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/naiba/bonds/internal/dto"
+	"github.com/naiba/bonds/internal/models"
+	"github.com/naiba/bonds/internal/testutil"
+)
+
+func mustDecodeB64(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode %q: %v", s, err)
+	}
+	return b
+}
+
+// webPushTestClient is the user agent side of a push subscription.
+type webPushTestClient struct {
+	private *ecdh.PrivateKey
+	auth    []byte
+}
+
+func newWebPushTestClient(t *testing.T) *webPushTestClient {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	auth := make([]byte, 16)
+	if _, err := rand.Read(auth); err != nil {
+		t.Fatalf("generate auth: %v", err)
+	}
+	return &webPushTestClient{private: key, auth: auth}
+}
+
+func (c *webPushTestClient) subscription(endpoint string) dto.WebPushSubscribeRequest {
+	return dto.WebPushSubscribeRequest{
+		Endpoint: endpoint,
+		Keys: dto.WebPushSubscribeKeys{
+			P256dh: base64.RawURLEncoding.EncodeToString(c.private.PublicKey().Bytes()),
+			Auth:   base64.RawURLEncoding.EncodeToString(c.auth),
+		},
+	}
+}
+
+func (c *webPushTestClient) subscriptionJSON(t *testing.T, endpoint string) string {
+	t.Helper()
+	req := c.subscription(endpoint)
+	raw, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal subscription: %v", err)
+	}
+	return string(raw)
+}
+
+// decrypt reverses encryptWebPushPayload the way a browser does.
+func (c *webPushTestClient) decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+	if len(body) < 21 {
+		t.Fatalf("push body too short: %d bytes", len(body))
+	}
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != webPushRecordSize {
+		t.Fatalf("expected record size %d, got %d", webPushRecordSize, rs)
+	}
+	idLen := int(body[20])
+	asPublic, err := ecdh.P256().NewPublicKey(body[21 : 21+idLen])
+	if err != nil {
+		t.Fatalf("parse application server key: %v", err)
+	}
+	secret, err := c.private.ECDH(asPublic)
+	if err != nil {
+		t.Fatalf("ecdh: %v", err)
+	}
+	info := append([]byte("WebPush: info\x00"), c.private.PublicKey().Bytes()...)
+	info = append(info, asPublic.Bytes()...)
+	ikm, _ := hkdf.Key(sha256.New, secret, c.auth, string(info), 32)
+	prk, _ := hkdf.Extract(sha256.New, ikm, salt)
+	cek, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, body[21+idLen:], nil)
+	if err != nil {
+		t.Fatalf("decrypt push payload: %v", err)
+	}
+	if plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatalf("expected final record delimiter, got %x", plaintext[len(plaintext)-1])
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+func newTestWebPushService(t *testing.T, server *httptest.Server) *WebPushService {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	settings := NewSystemSettingServiceWithCipher(db, "test-settings-key")
+	svc := NewWebPushService(settings)
+	if server != nil {
+		svc.client = server.Client()
+	}
+	return svc
+}
+
+// TestEncryptWebPushPayloadRFC8291Vector reproduces RFC 8291 Appendix A.
+func TestEncryptWebPushPayloadRFC8291Vector(t *testing.T) {
+	asPrivate, err := ecdh.P256().NewPrivateKey(mustDecodeB64(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	if err != nil {
+		t.Fatalf("as private key: %v", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(mustDecodeB64(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"))
+	if err != nil {
+		t.Fatalf("ua public key: %v", err)
+	}
+	got, err := encryptWebPushPayload(
+		[]byte("When I grow up, I want to be a watermelon"),
+		uaPublic,
+		mustDecodeB64(t, "BTBZMqHH6r4Tts7J_aSIgg"),
+		asPrivate,
+		mustDecodeB64(t, "DGv6ra1nlYgDCS1FRnbzlw"),
+	)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	want := "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	if enc := base64.RawURLEncoding.EncodeToString(got); enc != want {
+		t.Fatalf("encrypted body mismatch:\n got %s\nwant %s", enc, want)
+	}
+}
+
+func TestWebPushPublicKeyIsGeneratedOnceAndStoredEncrypted(t *testing.T) {
+	svc := newTestWebPushService(t, nil)
+
+	first, err := svc.PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey failed: %v", err)
+	}
+	if raw := mustDecodeB64(t, first); len(raw) != 65 || raw[0] != 0x04 {
+		t.Fatalf("expected an uncompressed P-256 point, got %d bytes", len(raw))
+	}
+	second, err := NewWebPushService(svc.settings).PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey failed: %v", err)
+	}
+	if first != second {
+		t.Fatal("expected the stored VAPID key to be reused")
+	}
+
+	var row models.SystemSetting
+	if err := svc.settings.db.Where("key = ?", webPushVAPIDPrivateKeySetting).First(&row).Error; err != nil {
+		t.Fatalf("load setting: %v", err)
+	}
+	plain, _ := svc.settings.Get(webPushVAPIDPrivateKeySetting)
+	if row.Value == plain {
+		t.Fatal("expected the VAPID private key to be encrypted at rest")
+	}
+}
+
+func TestWebPushSendDeliversEncryptedPayload(t *testing.T) {
+	client := newWebPushTestClient(t)
+	var (
+		gotHeader http.Header
+		gotBody   []byte
+	)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Clone()
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	svc := newTestWebPushService(t, server)
+	_ = svc.settings.Set("app.url", "https://bonds.example.com")
+	if err := svc.Send(client.subscriptionJSON(t, server.URL+"/push/abc"), "Reminder", "<b>Call Alice</b>"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if gotHeader.Get("Content-Encoding") != "aes128gcm" || gotHeader.Get("TTL") == "" {
+		t.Errorf("unexpected push headers: %v", gotHeader)
+	}
+
+	// Authorization: vapid t=<jwt>, k=<public key>
+	auth := strings.TrimPrefix(gotHeader.Get("Authorization"), "vapid ")
+	parts := strings.SplitN(auth, ", ", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "t=") || !strings.HasPrefix(parts[1], "k=") {
+		t.Fatalf("unexpected Authorization header %q", gotHeader.Get("Authorization"))
+	}
+	publicKey, _ := svc.PublicKey()
+	if parts[1][2:] != publicKey {
+		t.Error("expected k= to carry the VAPID public key")
+	}
+	verifyKey, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), mustDecodeB64(t, publicKey))
+	if err != nil {
+		t.Fatalf("parse VAPID public key: %v", err)
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(parts[0][2:], claims, func(*jwt.Token) (any, error) {
+		return verifyKey, nil
+	}, jwt.WithValidMethods([]string{"ES256"})); err != nil {
+		t.Fatalf("VAPID token does not verify: %v", err)
+	}
+	if claims["aud"] != server.URL || claims["sub"] != "https://bonds.example.com" {
+		t.Errorf("unexpected VAPID claims: %v", claims)
+	}
+
+	var msg webPushMessage
+	if err := json.Unmarshal(client.decrypt(t, gotBody), &msg); err != nil {
+		t.Fatalf("decode push message: %v", err)
+	}
+	if msg.Title != "Reminder" || msg.Body != "Call Alice" || msg.URL != "https://bonds.example.com" {
+		t.Errorf("unexpected push message: %+v", msg)
+	}
+}
+
+func TestWebPushSendReportsGoneSubscription(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusGone} {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		svc := newTestWebPushService(t, server)
+		err := svc.Send(newWebPushTestClient(t).subscriptionJSON(t, server.URL), "s", "m")
+		server.Close()
+		if !errors.Is(err, ErrWebPushSubscriptionGone) {
+			t.Errorf("status %d: expected ErrWebPushSubscriptionGone, got %v", status, err)
+		}
+	}
+}
+
+func TestParseWebPushSubscriptionRejectsInvalid(t *testing.T) {
+	client := newWebPushTestClient(t)
+	valid := client.subscription("https://push.example.com/abc")
+	cases := map[string]func(*dto.WebPushSubscribeRequest){
+		"http endpoint": func(r *dto.WebPushSubscribeRequest) { r.Endpoint = "http://push.example.com/abc" },
+		"bad p256dh":    func(r *dto.WebPushSubscribeRequest) { r.Keys.P256dh = "AAAA" },
+		"short auth":    func(r *dto.WebPushSubscribeRequest) { r.Keys.Auth = "AAAA" },
+	}
+	for name, mutate := range cases {
+		req := valid
+		mutate(&req)
+		raw, _ := json.Marshal(req)
+		if _, _, _, err := parseWebPushSubscription(string(raw)); !errors.Is(err, ErrWebPushInvalidSubscription) {
+			t.Errorf("%s: expected ErrWebPushInvalidSubscription, got %v", name, err)
+		}
+	}
+}
+
+func TestSubscribeWebPushUpsertsByEndpoint(t *testing.T) {
+	svc, userID := setupNotificationTest(t)
+	client := newWebPushTestClient(t)
+
+	created, isNew, err := svc.SubscribeWebPush(userID, client.subscription("https://push.example.com/abc"))
+	if err != nil {
+		t.Fatalf("SubscribeWebPush failed: %v", err)
+	}
+	if !isNew || !created.Active || created.Type != webPushChannelType || created.VerifiedAt == nil {
+		t.Fatalf("expected a new active verified push channel, got %+v", created)
+	}
+
+	if err := svc.db.Model(&models.UserNotificationChannel{}).Where("id = ?", created.ID).
+		Updates(map[string]interface{}{"active": false, "fails": 3}).Error; err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	rotated := newWebPushTestClient(t).subscription("https://push.example.com/abc")
+	rotated.Label = "Laptop"
+	again, isNew, err := svc.SubscribeWebPush(userID, rotated)
+	if err != nil {
+		t.Fatalf("SubscribeWebPush failed: %v", err)
+	}
+	if isNew || again.ID != created.ID || !again.Active || again.Label != "Laptop" {
+		t.Fatalf("expected the existing channel to be refreshed, got %+v", again)
+	}
+
+	var count int64
+	svc.db.Model(&models.UserNotificationChannel{}).Where("user_id = ? AND type = ?", userID, webPushChannelType).Count(&count)
+	if count != 1 {
+		t.Errorf("expected 1 push channel, got %d", count)
+	}
+
+	if _, _, err := svc.SubscribeWebPush(userID, dto.WebPushSubscribeRequest{Endpoint: "https://push.example.com/x"}); !errors.Is(err, ErrWebPushInvalidSubscription) {
+		t.Errorf("expected ErrWebPushInvalidSubscription, got %v", err)
+	}
+	if _, err := svc.Update(created.ID, userID, dto.UpdateNotificationChannelRequest{Content: "not a subscription"}); !errors.Is(err, ErrWebPushInvalidSubscription) {
+		t.Errorf("expected Update to reject invalid push content, got %v", err)
+	}
+}
+
+func TestSendTestWebPushDeactivatesGoneSubscription(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	svc, userID := setupNotificationTest(t)
+	webPush := newTestWebPushService(t, server)
+	svc.SetWebPush(webPush)
+	ch, _, err := svc.SubscribeWebPush(userID, newWebPushTestClient(t).subscription(server.URL+"/sub"))
+	if err != nil {
+		t.Fatalf("SubscribeWebPush failed: %v", err)
+	}
+
+	if err := svc.SendTest(ch.ID, userID); !errors.Is(err, ErrWebPushSubscriptionGone) {
+		t.Fatalf("expected ErrWebPushSubscriptionGone, got %v", err)
+	}
+	var reloaded models.UserNotificationChannel
+	if err := svc.db.First(&reloaded, ch.ID).Error; err != nil {
+		t.Fatalf("reload channel: %v", err)
+	}
+	if reloaded.Active {
+		t.Error("expected the channel to be deactivated")
+	}
+}
+
+func TestProcessDueReminders_WebPushGoneDeactivatesChannel(t *testing.T) {
+	ctx := setupReminderSchedulerTest(t)
+	var delivered int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered++
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+	ctx.svc.SetWebPushSender(newTestWebPushService(t, server))
+
+	ch := models.UserNotificationChannel{
+		UserID:  &ctx.userID,
+		Type:    webPushChannelType,
+		Content: newWebPushTestClient(t).subscriptionJSON(t, server.URL+"/sub"),
+	}
+	if err := ctx.db.Create(&ch).Error; err != nil {
+		t.Fatalf("create channel failed: %v", err)
+	}
+	if err := ctx.db.Model(&ch).Update("active", true).Error; err != nil {
+		t.Fatalf("update channel active failed: %v", err)
+	}
+	reminder := ctx.createReminder(t, "one_time", nil)
+	ctx.createScheduled(t, reminder.ID, ch.ID, time.Now().Add(-10*time.Minute).Truncate(time.Minute), nil)
+
+	ctx.svc.ProcessDueReminders()
+
+	if delivered != 1 {
+		t.Fatalf("expected 1 push request, got %d", delivered)
+	}
+	var reloaded models.UserNotificationChannel
+	if err := ctx.db.First(&reloaded, ch.ID).Error; err != nil {
+		t.Fatalf("reload channel: %v", err)
+	}
+	if reloaded.Active {
+		t.Error("expected the channel to be deactivated after the first 410")
+	}
+	if len(ctx.mailer.calls) != 0 {
+		t.Errorf("expected no email, got %d", len(ctx.mailer.calls))
+	}
+}
@@ -0,0 +1,225 @@
+package testutil
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// LDAPEntry is an entry served by the test LDAP server. Entries with a
+// password accept simple binds with it.
+type LDAPEntry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// LDAP protocol operations, result codes and filter types the test server
+// speaks.
+const (
+	ldapBindRequest       = 0
+	ldapBindResponse      = 1
+	ldapUnbindRequest     = 2
+	ldapSearchRequest     = 3
+	ldapSearchResultEntry = 4
+	ldapSearchResultDone  = 5
+
+	ldapSuccess            = 0
+	ldapSizeLimitExceeded  = 4
+	ldapInvalidCredentials = 49
+	ldapUnwillingToPerform = 53
+
+	ldapFilterAnd           = 0
+	ldapFilterOr            = 1
+	ldapFilterNot           = 2
+	ldapFilterEqualityMatch = 3
+	ldapFilterPresent       = 7
+)
+
+// StartLDAPServer serves the entries over plain LDAP on a loopback port until
+// the test ends and returns the server's ldap:// URL. It supports simple
+// binds and subtree searches with and, or, not, equality and presence
+// filters, which is what a bind-and-search authenticator needs.
+func StartLDAPServer(t *testing.T, entries []LDAPEntry) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start LDAP server: %v", err)
+	}
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		ln.Close()
+		wg.Wait()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer conn.Close()
+				serveLDAP(conn, entries)
+			}()
+		}
+	}()
+	return "ldap://" + ln.Addr().String()
+}
+
+func serveLDAP(conn net.Conn, entries []LDAPEntry) {
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldapBindRequest:
+			responses = append(responses, ldapResult(id, ldapBindResponse, bindLDAP(op, entries)))
+		case ldapSearchRequest:
+			responses = searchLDAP(id, op, entries)
+		case ldapUnbindRequest:
+			return
+		default:
+			responses = append(responses, ldapResult(id, op.Tag+1, ldapUnwillingToPerform))
+		}
+		for _, r := range responses {
+			if _, err := conn.Write(r.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func bindLDAP(op *ber.Packet, entries []LDAPEntry) int64 {
+	if len(op.Children) < 3 {
+		return ldapUnwillingToPerform
+	}
+	name := ldapString(op.Children[1])
+	password := op.Children[2].Data.String()
+	if name == "" && password == "" {
+		return ldapSuccess
+	}
+	for _, e := range entries {
+		if strings.EqualFold(e.DN, name) && e.Password != "" && e.Password == password {
+			return ldapSuccess
+		}
+	}
+	return ldapInvalidCredentials
+}
+
+func searchLDAP(id int64, op *ber.Packet, entries []LDAPEntry) []*ber.Packet {
+	if len(op.Children) < 7 {
+		return []*ber.Packet{ldapResult(id, ldapSearchResultDone, ldapUnwillingToPerform)}
+	}
+	base := strings.ToLower(ldapString(op.Children[0]))
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+
+	var responses []*ber.Packet
+	for _, e := range entries {
+		if !strings.HasSuffix(strings.ToLower(e.DN), base) || !matchesLDAPFilter(filter, e) {
+			continue
+		}
+		if sizeLimit > 0 && int64(len(responses)) == sizeLimit {
+			return append(responses, ldapResult(id, ldapSearchResultDone, ldapSizeLimitExceeded))
+		}
+		responses = append(responses, ldapSearchEntry(id, e))
+	}
+	return append(responses, ldapResult(id, ldapSearchResultDone, ldapSuccess))
+}
+
+func matchesLDAPFilter(filter *ber.Packet, e LDAPEntry) bool {
+	switch filter.Tag {
+	case ldapFilterAnd:
+		for _, f := range filter.Children {
+			if !matchesLDAPFilter(f, e) {
+				return false
+			}
+		}
+		return true
+	case ldapFilterOr:
+		for _, f := range filter.Children {
+			if matchesLDAPFilter(f, e) {
+				return true
+			}
+		}
+		return false
+	case ldapFilterNot:
+		return len(filter.Children) == 1 && !matchesLDAPFilter(filter.Children[0], e)
+	case ldapFilterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		value := ldapString(filter.Children[1])
+		for _, v := range ldapAttribute(e, ldapString(filter.Children[0])) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case ldapFilterPresent:
+		return len(ldapAttribute(e, filter.Data.String())) > 0
+	}
+	return false
+}
+
+func ldapAttribute(e LDAPEntry, name string) []string {
+	for k, v := range e.Attributes {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+func ldapString(p *ber.Packet) string {
+	if s, ok := p.Value.(string); ok {
+		return s
+	}
+	return p.Data.String()
+}
+
+func ldapMessage(id int64) *ber.Packet {
+	msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP message")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "message ID"))
+	return msg
+}
+
+func ldapResult(id int64, tag ber.Tag, code int64) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "result code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnostic message"))
+	msg := ldapMessage(id)
+	msg.AppendChild(result)
+	return msg
+}
+
+func ldapSearchEntry(id int64, e LDAPEntry) *ber.Packet {
+	entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchResultEntry, nil, "search result entry")
+	entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "object name"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range e.Attributes {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	entry.AppendChild(attributes)
+	msg := ldapMessage(id)
+	msg.AppendChild(entry)
+	return msg
+}
@@ -50,6 +50,14 @@ func Created(c echo.Context, data interface{}) error {
 	})
 }
 
+// Accepted responds to a request whose work continues in the background.
+func Accepted(c echo.Context, data interface{}) error {
+	return c.JSON(http.StatusAccepted, APIResponse{
+		Success: true,
+		Data:    data,
+	})
+}
+
 func Paginated(c echo.Context, data interface{}, meta Meta) error {
 	return c.JSON(http.StatusOK, APIResponse{
 		Success: true,
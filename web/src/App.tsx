@@ -59,6 +59,8 @@ const WebAuthn = lazy(() => import("@/pages/settings/WebAuthn"));
 const OAuthProviders = lazy(() => import("@/pages/settings/OAuthProviders"));
 const StorageInfo = lazy(() => import("@/pages/settings/StorageInfo"));
 const ApiTokens = lazy(() => import("@/pages/settings/ApiTokens"));
+const Sessions = lazy(() => import("@/pages/settings/Sessions"));
+const AuthorizedApps = lazy(() => import("@/pages/settings/AuthorizedApps"));
 
 // Admin pages
 const AdminUsers = lazy(() => import("@/pages/admin/Users"));
@@ -71,6 +73,10 @@ const AcceptInvite = lazy(() => import("@/pages/auth/AcceptInvite"));
 const OAuthCallback = lazy(() => import("@/pages/auth/OAuthCallback"));
 const OAuthLink = lazy(() => import("@/pages/auth/OAuthLink"));
 
+// OAuth authorization server pages
+const OAuthConsent = lazy(() => import("@/pages/oauth/Consent"));
+const OAuthDevice = lazy(() => import("@/pages/oauth/Device"));
+
 function PageLoader() {
   return (
     <div
@@ -141,6 +147,22 @@ export default function App() {
             <Route path="/auth/callback" element={<OAuthCallback />} />
             <Route path="/auth/oauth-link" element={<OAuthLink />} />
             <Route path="/verify-email" element={<VerifyEmail />} />
+            <Route
+              path="/authorize"
+              element={
+                <ProtectedRoute>
+                  <OAuthConsent />
+                </ProtectedRoute>
+              }
+            />
+            <Route
+              path="/device"
+              element={
+                <ProtectedRoute>
+                  <OAuthDevice />
+                </ProtectedRoute>
+              }
+            />
 
             <Route
               element={
@@ -230,6 +252,11 @@ export default function App() {
               <Route path="/settings/oauth" element={<OAuthProviders />} />
               <Route path="/settings/storage" element={<StorageInfo />} />
               <Route path="/settings/tokens" element={<ApiTokens />} />
+              <Route path="/settings/sessions" element={<Sessions />} />
+              <Route
+                path="/settings/authorized-apps"
+                element={<AuthorizedApps />}
+              />
               <Route path="/admin/users" element={<AdminUsers />} />
               <Route path="/admin/settings" element={<AdminSettings />} />
               <Route path="/admin/backups" element={<AdminBackups />} />
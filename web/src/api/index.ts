@@ -15,6 +15,7 @@ import {
   StaleAuthenticationRequestError,
 } from "@/api/authenticationRequestOwnership";
 import {
+  getCurrentRefreshToken,
   isAuthenticationSubjectRevisionCurrent,
   replaceCurrentAuthenticationToken,
   terminateCurrentAuthenticationSubject,
@@ -192,9 +193,15 @@ async function refreshAuthenticationToken(
   ownership: RefreshOwnership,
 ): Promise<RefreshResult> {
   try {
-    const response = await httpClient.instance.post<{
-      data?: { token?: string };
-    }>("/auth/refresh");
+    // Tokens issued before sessions existed come without a refresh token; /auth/refresh trades them for a session.
+    const refreshToken = getCurrentRefreshToken();
+    const response = refreshToken
+      ? await httpClient.instance.post<{
+          data?: { token?: string; refresh_token?: string };
+        }>("/auth/refresh-token", { refresh_token: refreshToken })
+      : await httpClient.instance.post<{
+          data?: { token?: string; refresh_token?: string };
+        }>("/auth/refresh");
     if (!refreshOwnershipIsCurrent(ownership)) {
       return { status: "stale" };
     }
@@ -202,7 +209,7 @@ async function refreshAuthenticationToken(
     if (newToken === undefined) {
       throw new Error("Refresh response did not include a token");
     }
-    replaceCurrentAuthenticationToken(newToken);
+    replaceCurrentAuthenticationToken(newToken, response.data.data?.refresh_token);
     return { status: "refreshed", token: newToken };
   } catch (error) {
     if (refreshOwnershipIsCurrent(ownership)) {
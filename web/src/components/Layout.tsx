@@ -33,6 +33,8 @@ import {
   CloudServerOutlined,
   LinkOutlined,
   KeyOutlined,
+  LaptopOutlined,
+  AppstoreOutlined,
 } from "@ant-design/icons";
 import type { MenuProps } from "antd";
 import { useAuth } from "@/stores/auth";
@@ -137,6 +139,8 @@ export default function Layout() {
     { key: "/settings/oauth", icon: <LinkOutlined />, label: t("nav.oauth") },
     { key: "/settings/storage", icon: <CloudServerOutlined />, label: t("nav.storage") },
     { key: "/settings/tokens", icon: <KeyOutlined />, label: t("nav.api_tokens") },
+    { key: "/settings/sessions", icon: <LaptopOutlined />, label: t("nav.sessions") },
+    { key: "/settings/authorized-apps", icon: <AppstoreOutlined />, label: t("nav.authorized_apps") },
     ...(user?.is_instance_administrator
       ? [
           { type: "divider" as const },
@@ -95,16 +95,27 @@ export default function Login() {
     if (redirect && redirect.startsWith("/") && !redirect.startsWith("//")) {
       return redirect;
     }
-    return (location.state as { from?: { pathname: string } })?.from?.pathname ?? "/vaults";
+    // Keep the query too: the OAuth consent and device pages carry their request in it.
+    const state = (location.state as { from?: { pathname: string; search?: string } })?.from;
+    return state ? state.pathname + (state.search ?? "") : "/vaults";
   })();
 
+  // The OAuth consent pages are rendered by the server, outside the router.
+  function continueTo(target: string) {
+    if (target.startsWith("/oauth/")) {
+      window.location.assign(target);
+      return;
+    }
+    navigate(target, { replace: true });
+  }
+
   async function onFinish(values: LoginRequest) {
     setLoading(true);
     try {
       const completion = await login(values);
       switch (completion.status) {
         case "authenticated":
-          navigate(from, { replace: true });
+          continueTo(from);
           return;
         case "two_factor_required":
           navigate("/login/2fa", { replace: true });
@@ -137,7 +148,11 @@ export default function Login() {
         // Keep email out of the assertion body so go-webauthn can parse it unchanged.
         const verifyRes = await httpClient.instance.post<{
           success: boolean;
-          data: { token: string; user: { id: string; email: string } };
+          data: {
+            token: string;
+            refresh_token?: string;
+            user: { id: string; email: string };
+          };
         }>(
           `/auth/webauthn/login/finish?email=${encodeURIComponent(email)}`,
           assertionResponse,
@@ -145,7 +160,7 @@ export default function Login() {
         return verifyRes.data.data;
       });
       if (completion.status === "authenticated") {
-        navigate(from, { replace: true });
+        continueTo(from);
       }
     } catch (error) {
       if (isFormValidationError(error)) {
@@ -19,7 +19,7 @@ export default function OAuthCallback() {
     if (token) {
       // 必须通过 setExternalToken 同步更新 AuthProvider 的 React 状态，
       // 否则 ProtectedRoute 在检查 isAuthenticated 时 user 仍为 null，会重定向回 /login。
-      setExternalToken(token);
+      setExternalToken(token, searchParams.get("refresh_token"));
       navigate("/vaults", { replace: true });
     } else {
       navigate("/login", { replace: true });
@@ -123,7 +123,7 @@ export default function OAuthLink() {
     try {
       const loginRes = await httpClient.instance.post<{
         success: boolean;
-        data: { token: string };
+        data: { token: string; refresh_token?: string };
       }>("/auth/login", values);
       const jwt = loginRes.data.data?.token;
       if (!jwt) throw new Error(t("auth.login.failed"));
@@ -132,7 +132,7 @@ export default function OAuthLink() {
         headers: { Authorization: `Bearer ${jwt}` },
       });
 
-      setExternalToken(jwt);
+      setExternalToken(jwt, loginRes.data.data?.refresh_token);
       message.success(t("oauth.link_success"));
       navigate("/vaults", { replace: true });
     } catch (err) {
@@ -148,14 +148,14 @@ export default function OAuthLink() {
     try {
       const res = await httpClient.instance.post<{
         success: boolean;
-        data: { token: string };
+        data: { token: string; refresh_token?: string };
       }>("/auth/oauth/link-register", {
         link_token: linkToken,
         ...values,
       });
 
       const jwt = res.data.data?.token;
-      if (jwt) setExternalToken(jwt);
+      if (jwt) setExternalToken(jwt, res.data.data?.refresh_token);
       message.success(t("oauth.link_success"));
       navigate("/vaults", { replace: true });
     } catch (err) {
@@ -0,0 +1,101 @@
+import { useSearchParams } from "react-router-dom";
+import { Alert, Spin, Typography } from "antd";
+import { useMutation, useQuery } from "@tanstack/react-query";
+import { useTranslation } from "react-i18next";
+import { httpClient } from "@/api";
+import { OAuthConsentDetails, OAuthPageShell } from "./OAuthPageShell";
+import type { OAuthConsent } from "./OAuthPageShell";
+
+const { Paragraph } = Typography;
+
+function redirectHost(redirectURI?: string): string {
+  if (!redirectURI) {
+    return "";
+  }
+  try {
+    return new URL(redirectURI).host || redirectURI;
+  } catch {
+    return redirectURI;
+  }
+}
+
+export default function Consent() {
+  const { t } = useTranslation();
+  const [searchParams] = useSearchParams();
+  const requestId = searchParams.get("request_id") ?? "";
+  const path = `/oauth/authorize/${encodeURIComponent(requestId)}`;
+
+  const { data: consent, isLoading, error } = useQuery({
+    queryKey: ["oauth", "authorize", requestId],
+    queryFn: async () => {
+      const res = await httpClient.instance.get<{ data: OAuthConsent }>(path);
+      return res.data.data;
+    },
+    enabled: requestId !== "",
+    retry: false,
+    gcTime: 0,
+  });
+
+  const decideMutation = useMutation({
+    mutationFn: async (approve: boolean) => {
+      const res = await httpClient.instance.post<{
+        data: { redirect_url: string };
+      }>(path, { approve });
+      return res.data.data.redirect_url;
+    },
+    onSuccess: (redirectURL) => {
+      window.location.assign(redirectURL);
+    },
+  });
+
+  let content;
+  if (requestId === "") {
+    content = <Alert type="error" showIcon message={t("oauth_consent.not_found")} />;
+  } else if (isLoading) {
+    content = (
+      <div style={{ textAlign: "center", padding: "32px 0" }}>
+        <Spin size="large" />
+      </div>
+    );
+  } else if (error || !consent) {
+    content = (
+      <Alert
+        type="error"
+        showIcon
+        message={(error as { message?: string } | null)?.message || t("oauth_consent.failed")}
+      />
+    );
+  } else {
+    const host = redirectHost(consent.redirect_uri);
+    content = (
+      <>
+        {decideMutation.error && (
+          <Alert
+            type="error"
+            showIcon
+            style={{ marginBottom: 16 }}
+            message={(decideMutation.error as { message?: string }).message || t("oauth_consent.failed")}
+          />
+        )}
+        <OAuthConsentDetails
+          consent={consent}
+          deciding={decideMutation.isPending || decideMutation.isSuccess}
+          onDecide={(approve) => decideMutation.mutate(approve)}
+          footer={
+            host && (
+              <Paragraph type="secondary">
+                {t("oauth_consent.consent_redirect", { host })}
+              </Paragraph>
+            )
+          }
+        />
+      </>
+    );
+  }
+
+  return (
+    <OAuthPageShell title={t("oauth_consent.consent_title")}>
+      {content}
+    </OAuthPageShell>
+  );
+}
@@ -0,0 +1,135 @@
+import { useEffect, useRef, useState } from "react";
+import { useSearchParams } from "react-router-dom";
+import { Alert, Button, Form, Input, Result, Space, Typography } from "antd";
+import { useMutation } from "@tanstack/react-query";
+import { useTranslation } from "react-i18next";
+import { httpClient } from "@/api";
+import { OAuthConsentDetails, OAuthPageShell } from "./OAuthPageShell";
+import type { OAuthConsent } from "./OAuthPageShell";
+
+const { Paragraph, Text } = Typography;
+
+export default function Device() {
+  const { t } = useTranslation();
+  const [searchParams, setSearchParams] = useSearchParams();
+  const initialCode = searchParams.get("user_code") ?? "";
+  const [consent, setConsent] = useState<OAuthConsent | null>(null);
+  const [decision, setDecision] = useState<boolean | null>(null);
+
+  const lookupMutation = useMutation({
+    mutationFn: async (userCode: string) => {
+      const res = await httpClient.instance.get<{ data: OAuthConsent }>(
+        "/oauth/device",
+        { params: { user_code: userCode } },
+      );
+      return res.data.data;
+    },
+    onSuccess: (found) => {
+      setConsent(found);
+      setSearchParams({ user_code: found.user_code ?? "" }, { replace: true });
+    },
+  });
+
+  const decideMutation = useMutation({
+    mutationFn: async (approve: boolean) => {
+      await httpClient.instance.post("/oauth/device", {
+        user_code: consent?.user_code,
+        approve,
+      });
+      return approve;
+    },
+    onSuccess: (approve) => setDecision(approve),
+  });
+
+  // verification_uri_complete carries the code, so look it up straight away; the user still confirms it below.
+  const lookedUpInitialCode = useRef(false);
+  const lookup = lookupMutation.mutate;
+  useEffect(() => {
+    if (initialCode && !lookedUpInitialCode.current) {
+      lookedUpInitialCode.current = true;
+      lookup(initialCode);
+    }
+  }, [initialCode, lookup]);
+
+  const errorMessage = (error: unknown) =>
+    (error as { message?: string } | null)?.message || t("oauth_consent.failed");
+
+  let content;
+  if (decision !== null) {
+    content = (
+      <Result
+        status={decision ? "success" : "info"}
+        title={decision ? t("oauth_consent.device_approved") : t("oauth_consent.device_denied")}
+      />
+    );
+  } else if (consent) {
+    content = (
+      <>
+        {decideMutation.error && (
+          <Alert
+            type="error"
+            showIcon
+            style={{ marginBottom: 16 }}
+            message={errorMessage(decideMutation.error)}
+          />
+        )}
+        <OAuthConsentDetails
+          consent={consent}
+          deciding={decideMutation.isPending}
+          onDecide={(approve) => decideMutation.mutate(approve)}
+          footer={
+            <Paragraph style={{ textAlign: "center" }}>
+              <Text code style={{ fontSize: 18 }}>
+                {consent.user_code}
+              </Text>
+            </Paragraph>
+          }
+        />
+      </>
+    );
+  } else {
+    content = (
+      <>
+        <Paragraph>{t("oauth_consent.device_prompt")}</Paragraph>
+        <Form
+          layout="vertical"
+          initialValues={{ user_code: initialCode }}
+          onFinish={(values: { user_code: string }) =>
+            lookupMutation.mutate(values.user_code.trim())
+          }
+        >
+          <Form.Item
+            name="user_code"
+            label={t("oauth_consent.device_code")}
+            rules={[{ required: true, whitespace: true }]}
+          >
+            <Input autoComplete="off" autoFocus placeholder="BCDF-GHJK" />
+          </Form.Item>
+          {lookupMutation.error && (
+            <Alert
+              type="error"
+              showIcon
+              style={{ marginBottom: 16 }}
+              message={errorMessage(lookupMutation.error)}
+            />
+          )}
+          <Space style={{ width: "100%", justifyContent: "flex-end" }}>
+            <Button
+              type="primary"
+              htmlType="submit"
+              loading={lookupMutation.isPending}
+            >
+              {t("oauth_consent.device_continue")}
+            </Button>
+          </Space>
+        </Form>
+      </>
+    );
+  }
+
+  return (
+    <OAuthPageShell title={t("oauth_consent.device_title")}>
+      {content}
+    </OAuthPageShell>
+  );
+}
@@ -0,0 +1,145 @@
+import { useEffect } from "react";
+import type { ReactNode } from "react";
+import { Alert, Button, Card, List, Space, Typography, theme } from "antd";
+import { useTranslation } from "react-i18next";
+import logoImg from "@/assets/logo.svg";
+
+const { Title, Paragraph } = Typography;
+
+export interface OAuthScope {
+  name: string;
+  description: string;
+}
+
+export interface OAuthConsent {
+  client_id: string;
+  client_name: string;
+  redirect_uri?: string;
+  user_code?: string;
+  scopes: OAuthScope[];
+}
+
+// Another site must not frame the consent pages and trick users into clicking Allow.
+function isFramed(): boolean {
+  try {
+    return window.top !== window.self;
+  } catch {
+    return true;
+  }
+}
+
+// The request in the URL must not leak to the app through the Referer when the browser is sent back.
+function useNoReferrer() {
+  useEffect(() => {
+    const meta = document.createElement("meta");
+    meta.name = "referrer";
+    meta.content = "no-referrer";
+    document.head.appendChild(meta);
+    return () => {
+      meta.remove();
+    };
+  }, []);
+}
+
+export function OAuthPageShell({
+  title,
+  children,
+}: {
+  title: string;
+  children: ReactNode;
+}) {
+  const { t } = useTranslation();
+  const { token: colorToken } = theme.useToken();
+  useNoReferrer();
+  const framed = isFramed();
+
+  return (
+    <div
+      style={{
+        minHeight: "100vh",
+        display: "flex",
+        flexDirection: "column",
+        alignItems: "center",
+        justifyContent: "center",
+        background: `linear-gradient(145deg, ${colorToken.colorBgLayout} 0%, ${colorToken.colorPrimaryBg} 50%, ${colorToken.colorBgLayout} 100%)`,
+        padding: 16,
+      }}
+    >
+      <Card
+        style={{
+          width: "100%",
+          maxWidth: 480,
+          border: `1px solid ${colorToken.colorBorderSecondary}`,
+          boxShadow: "0 8px 32px rgba(0,0,0,0.08), 0 2px 8px rgba(0,0,0,0.04)",
+          borderRadius: colorToken.borderRadiusLG,
+        }}
+      >
+        <div style={{ textAlign: "center", marginBottom: 24 }}>
+          <div style={{ display: "flex", alignItems: "center", justifyContent: "center", gap: 10, marginBottom: 20 }}>
+            <img src={logoImg} alt="Bonds" style={{ width: 36, height: 36, borderRadius: 10, flexShrink: 0 }} />
+            <span style={{
+              fontWeight: 700,
+              fontSize: 22,
+              letterSpacing: "-0.02em",
+              color: colorToken.colorPrimary,
+            }}>
+              Bonds
+            </span>
+          </div>
+          <Title level={3} style={{ marginBottom: 4 }}>
+            {title}
+          </Title>
+        </div>
+        {framed ? (
+          <Alert type="error" showIcon message={t("oauth_consent.framed")} />
+        ) : (
+          children
+        )}
+      </Card>
+    </div>
+  );
+}
+
+export function OAuthConsentDetails({
+  consent,
+  footer,
+  deciding,
+  onDecide,
+}: {
+  consent: OAuthConsent;
+  footer?: ReactNode;
+  deciding: boolean;
+  onDecide: (approve: boolean) => void;
+}) {
+  const { t } = useTranslation();
+  return (
+    <>
+      <Paragraph>
+        {t("oauth_consent.consent_intro", { app: consent.client_name })}
+      </Paragraph>
+      <List
+        size="small"
+        bordered
+        dataSource={consent.scopes}
+        rowKey="name"
+        renderItem={(scope) => (
+          <List.Item>{scope.description || scope.name}</List.Item>
+        )}
+        style={{ marginBottom: 16 }}
+      />
+      {footer}
+      <Space style={{ width: "100%", justifyContent: "flex-end" }}>
+        <Button disabled={deciding} onClick={() => onDecide(false)}>
+          {t("oauth_consent.deny")}
+        </Button>
+        <Button
+          type="primary"
+          loading={deciding}
+          onClick={() => onDecide(true)}
+        >
+          {t("oauth_consent.approve")}
+        </Button>
+      </Space>
+    </>
+  );
+}
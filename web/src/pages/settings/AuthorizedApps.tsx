@@ -0,0 +1,144 @@
+import {
+  Card,
+  Typography,
+  Button,
+  Table,
+  Popconfirm,
+  Spin,
+  App,
+  Tag,
+} from "antd";
+import { DeleteOutlined } from "@ant-design/icons";
+import { useQuery, useMutation, useQueryClient } from "@tanstack/react-query";
+import { useTranslation } from "react-i18next";
+import { httpClient } from "@/api";
+import type { ColumnsType } from "antd/es/table";
+import { useDateFormat, formatDate, formatDateTime } from "@/utils/dateFormat";
+
+const { Title, Text } = Typography;
+
+interface AuthorizedApp {
+  id: number;
+  client_id: string;
+  client_name: string;
+  scopes: string[];
+  last_used_at: string | null;
+  created_at: string;
+}
+
+export default function AuthorizedApps() {
+  const queryClient = useQueryClient();
+  const { message } = App.useApp();
+  const { t } = useTranslation();
+  const dateFormats = useDateFormat();
+  const qk = ["settings", "authorized-apps"];
+
+  const { data: apps = [], isLoading } = useQuery({
+    queryKey: qk,
+    queryFn: async () => {
+      const res = await httpClient.instance.get<{ data: AuthorizedApp[] }>(
+        "/settings/authorized-apps",
+      );
+      return res.data.data ?? [];
+    },
+  });
+
+  const revokeMutation = useMutation({
+    mutationFn: async (id: number) => {
+      await httpClient.instance.delete(`/settings/authorized-apps/${id}`);
+    },
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: qk });
+      message.success(t("authorized_apps.revoked"));
+    },
+    onError: (e: { message: string }) => message.error(e.message),
+  });
+
+  const columns: ColumnsType<AuthorizedApp> = [
+    {
+      title: t("authorized_apps.app"),
+      dataIndex: "client_name",
+      key: "client_name",
+      render: (name: string) => <Text strong>{name}</Text>,
+    },
+    {
+      title: t("authorized_apps.scopes"),
+      dataIndex: "scopes",
+      key: "scopes",
+      render: (scopes: string[]) =>
+        scopes.map((s) => (
+          <Tag key={s} color="blue">
+            {s}
+          </Tag>
+        )),
+    },
+    {
+      title: t("authorized_apps.last_used"),
+      dataIndex: "last_used_at",
+      key: "last_used_at",
+      render: (val: string | null) =>
+        val ? (
+          <Text type="secondary">{formatDateTime(val, dateFormats)}</Text>
+        ) : (
+          <Text type="secondary">{t("authorized_apps.never_used")}</Text>
+        ),
+    },
+    {
+      title: t("authorized_apps.authorized"),
+      dataIndex: "created_at",
+      key: "created_at",
+      render: (val: string) => (
+        <Text type="secondary">{formatDate(val, dateFormats)}</Text>
+      ),
+    },
+    {
+      title: "",
+      key: "actions",
+      render: (_, record) => (
+        <Popconfirm
+          title={t("authorized_apps.revoke_confirm", {
+            app: record.client_name,
+          })}
+          onConfirm={() => revokeMutation.mutate(record.id)}
+        >
+          <Button
+            type="text"
+            size="small"
+            danger
+            icon={<DeleteOutlined />}
+            aria-label={t("authorized_apps.revoke")}
+          />
+        </Popconfirm>
+      ),
+    },
+  ];
+
+  if (isLoading) {
+    return (
+      <div style={{ textAlign: "center", padding: 80 }}>
+        <Spin size="large" />
+      </div>
+    );
+  }
+
+  return (
+    <div style={{ maxWidth: 720, margin: "0 auto" }}>
+      <div style={{ marginBottom: 24 }}>
+        <Title level={4} style={{ marginBottom: 4 }}>
+          {t("authorized_apps.title")}
+        </Title>
+        <Text type="secondary">{t("authorized_apps.description")}</Text>
+      </div>
+
+      <Card>
+        <Table<AuthorizedApp>
+          columns={columns}
+          dataSource={apps}
+          rowKey="id"
+          pagination={false}
+          locale={{ emptyText: t("authorized_apps.empty") }}
+        />
+      </Card>
+    </div>
+  );
+}
@@ -0,0 +1,198 @@
+import {
+  Card,
+  Typography,
+  Button,
+  Table,
+  Popconfirm,
+  Spin,
+  App,
+  Tag,
+  Tooltip,
+} from "antd";
+import { DeleteOutlined, LogoutOutlined } from "@ant-design/icons";
+import { useQuery, useMutation, useQueryClient } from "@tanstack/react-query";
+import { useTranslation } from "react-i18next";
+import { httpClient } from "@/api";
+import { useAuth } from "@/stores/auth";
+import type { ColumnsType } from "antd/es/table";
+import { useDateFormat, formatDateTime } from "@/utils/dateFormat";
+
+const { Title, Text } = Typography;
+
+interface UserSession {
+  id: string;
+  device: string;
+  user_agent: string;
+  ip_address: string;
+  current: boolean;
+  last_used_at: string;
+  expires_at: string;
+  created_at: string;
+}
+
+export default function Sessions() {
+  const queryClient = useQueryClient();
+  const { message } = App.useApp();
+  const { t } = useTranslation();
+  const { logout } = useAuth();
+  const dateFormats = useDateFormat();
+  const qk = ["settings", "sessions"];
+
+  const { data: sessions = [], isLoading } = useQuery({
+    queryKey: qk,
+    queryFn: async () => {
+      const res = await httpClient.instance.get<{ data: UserSession[] }>(
+        "/settings/sessions",
+      );
+      return res.data.data ?? [];
+    },
+  });
+
+  const revokeMutation = useMutation({
+    mutationFn: async (session: UserSession) => {
+      await httpClient.instance.delete(`/settings/sessions/${session.id}`);
+      return session;
+    },
+    onSuccess: (session) => {
+      if (session.current) {
+        logout();
+        return;
+      }
+      queryClient.invalidateQueries({ queryKey: qk });
+      message.success(t("sessions.revoked"));
+    },
+    onError: (e: { message: string }) => message.error(e.message),
+  });
+
+  const revokeOthersMutation = useMutation({
+    mutationFn: async () => {
+      const res = await httpClient.instance.delete<{
+        data: { revoked: number };
+      }>("/settings/sessions", { params: { keep_current: true } });
+      return res.data.data?.revoked ?? 0;
+    },
+    onSuccess: (revoked) => {
+      queryClient.invalidateQueries({ queryKey: qk });
+      message.success(t("sessions.revoked_others", { count: revoked }));
+    },
+    onError: (e: { message: string }) => message.error(e.message),
+  });
+
+  const columns: ColumnsType<UserSession> = [
+    {
+      title: t("sessions.device"),
+      dataIndex: "device",
+      key: "device",
+      render: (device: string, record) => (
+        <>
+          <Tooltip title={record.user_agent || undefined}>
+            <Text strong>{device || t("sessions.unknown_device")}</Text>
+          </Tooltip>
+          {record.current && (
+            <Tag color="green" style={{ marginLeft: 8 }}>
+              {t("sessions.current")}
+            </Tag>
+          )}
+        </>
+      ),
+    },
+    {
+      title: t("sessions.ip_address"),
+      dataIndex: "ip_address",
+      key: "ip_address",
+      render: (ip: string) => <Text type="secondary">{ip || "-"}</Text>,
+    },
+    {
+      title: t("sessions.last_used"),
+      dataIndex: "last_used_at",
+      key: "last_used_at",
+      render: (val: string) => (
+        <Text type="secondary">{formatDateTime(val, dateFormats)}</Text>
+      ),
+    },
+    {
+      title: t("sessions.signed_in"),
+      dataIndex: "created_at",
+      key: "created_at",
+      render: (val: string) => (
+        <Text type="secondary">{formatDateTime(val, dateFormats)}</Text>
+      ),
+    },
+    {
+      title: "",
+      key: "actions",
+      render: (_, record) => (
+        <Popconfirm
+          title={
+            record.current
+              ? t("sessions.revoke_current_confirm")
+              : t("sessions.revoke_confirm")
+          }
+          onConfirm={() => revokeMutation.mutate(record)}
+        >
+          <Button
+            type="text"
+            size="small"
+            danger
+            icon={<DeleteOutlined />}
+            aria-label={t("sessions.revoke")}
+          />
+        </Popconfirm>
+      ),
+    },
+  ];
+
+  if (isLoading) {
+    return (
+      <div style={{ textAlign: "center", padding: 80 }}>
+        <Spin size="large" />
+      </div>
+    );
+  }
+
+  const hasOtherSessions = sessions.some((s) => !s.current);
+
+  return (
+    <div style={{ maxWidth: 720, margin: "0 auto" }}>
+      <div
+        style={{
+          display: "flex",
+          justifyContent: "space-between",
+          alignItems: "flex-start",
+          marginBottom: 24,
+        }}
+      >
+        <div>
+          <Title level={4} style={{ marginBottom: 4 }}>
+            {t("sessions.title")}
+          </Title>
+          <Text type="secondary">{t("sessions.description")}</Text>
+        </div>
+        <Popconfirm
+          title={t("sessions.revoke_others_confirm")}
+          onConfirm={() => revokeOthersMutation.mutate()}
+          disabled={!hasOtherSessions}
+        >
+          <Button
+            danger
+            icon={<LogoutOutlined />}
+            disabled={!hasOtherSessions}
+            loading={revokeOthersMutation.isPending}
+            style={{ flexShrink: 0, marginTop: 4 }}
+          >
+            {t("sessions.revoke_others")}
+          </Button>
+        </Popconfirm>
+      </div>
+
+      <Card>
+        <Table<UserSession>
+          columns={columns}
+          dataSource={sessions}
+          rowKey="id"
+          pagination={false}
+        />
+      </Card>
+    </div>
+  );
+}
@@ -15,8 +15,10 @@ import {
   advanceAuthenticationAttemptRevision,
   advanceAuthenticationSubjectRevision,
   captureAuthenticationSubjectRevision,
+  clearAuthenticationTokens,
   isAuthenticationAttemptRevisionCurrent,
   isAuthenticationSubjectRevisionCurrent,
+  storeAuthenticationTokens,
   subscribeAuthenticationSubjectTermination,
   subscribeAuthenticationTokenReplacement,
   terminateCurrentAuthenticationSubject,
@@ -29,6 +31,7 @@ export type AuthenticationCompletion =
 
 export type WebAuthnAuthentication = Readonly<{
   token: string;
+  refresh_token?: string;
   user: User;
 }>;
 
@@ -58,7 +61,8 @@ interface AuthContextType {
   ) => Promise<AuthenticationCompletion>;
   register: (data: RegisterRequest) => Promise<AuthenticationCompletion>;
   logout: () => void;
-  setExternalToken: (jwt: string) => void;
+  setExternalToken: (jwt: string, refreshToken?: string | null) => void;
+  restoreForwardAuthSession: () => Promise<boolean>;
   verifyTwoFactor: (code: string) => Promise<AuthenticationCompletion>;
 }
 
@@ -151,11 +155,11 @@ export function AuthProvider({ children }: { children: ReactNode }) {
           setTwoFactorPending(true);
           setTempToken(auth.temp_token ?? null);
           setUser(auth.user ?? null);
-          localStorage.removeItem("token");
+          clearAuthenticationTokens();
           setToken(null);
           return TWO_FACTOR_REQUIRED_COMPLETION;
         }
-        localStorage.setItem("token", auth.token);
+        storeAuthenticationTokens(auth.token, auth.refresh_token);
         setToken(auth.token);
         setUser(auth.user);
         return AUTHENTICATED_COMPLETION;
@@ -181,7 +185,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
           return STALE_COMPLETION;
         }
         commitAuthenticationSubject();
-        localStorage.setItem("token", auth.token);
+        storeAuthenticationTokens(auth.token, auth.refresh_token);
         setIsLoading(false);
         setToken(auth.token);
         setUser(auth.user);
@@ -207,7 +211,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
         }
         const auth = requireAuthenticationData(res.data);
         commitAuthenticationSubject();
-        localStorage.setItem("token", auth.token);
+        storeAuthenticationTokens(auth.token, auth.refresh_token);
         setIsLoading(false);
         setToken(auth.token);
         setUser(auth.user);
@@ -243,7 +247,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
         }
         const auth = requireAuthenticationData(res.data);
         commitAuthenticationSubject();
-        localStorage.setItem("token", auth.token);
+        storeAuthenticationTokens(auth.token, auth.refresh_token);
         setIsLoading(false);
         setToken(auth.token);
         setUser(auth.user);
@@ -260,19 +264,41 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   );
 
   const setExternalToken = useCallback(
-    (jwt: string) => {
+    (jwt: string, refreshToken?: string | null) => {
       if (jwt === token) {
         return;
       }
       advanceAuthenticationSubjectRevision();
       retireAuthenticationSubject();
-      localStorage.setItem("token", jwt);
+      storeAuthenticationTokens(jwt, refreshToken);
       setIsLoading(true);
       setToken(jwt);
     },
     [token, retireAuthenticationSubject],
   );
 
+  // Behind an authenticating proxy the request itself proves the user, so there is no token to keep.
+  const restoreForwardAuthSession = useCallback(async (): Promise<boolean> => {
+    if (localStorage.getItem("token")) {
+      return false;
+    }
+    const revision = captureAuthenticationSubjectRevision();
+    try {
+      const res = await api.auth.getAuth();
+      if (
+        !isAuthenticationSubjectRevisionCurrent(revision) ||
+        localStorage.getItem("token") ||
+        !res.data
+      ) {
+        return false;
+      }
+      setUser(res.data);
+      return true;
+    } catch {
+      return false;
+    }
+  }, []);
+
   const value = useMemo(
     () => ({
       user,
@@ -286,6 +312,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       register,
       logout,
       setExternalToken,
+      restoreForwardAuthSession,
       verifyTwoFactor,
     }),
     [
@@ -299,6 +326,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       register,
       logout,
       setExternalToken,
+      restoreForwardAuthSession,
       verifyTwoFactor,
     ],
   );
@@ -313,10 +341,20 @@ export function useAuth() {
 }
 
 export function ProtectedRoute({ children }: { children: ReactNode }) {
-  const { isAuthenticated, isLoading, user } = useAuth();
+  const { isAuthenticated, isLoading, user, token, restoreForwardAuthSession } =
+    useAuth();
   const location = useLocation();
   const [instanceInfo, setInstanceInfo] = useState<InstanceInfo | null>(null);
   const [loadingInstanceInfo, setLoadingInstanceInfo] = useState(true);
+  const [forwardAuthAttempted, setForwardAuthAttempted] = useState(false);
+  const [restoringForwardAuth, setRestoringForwardAuth] = useState(false);
+  const shouldRestoreForwardAuth =
+    !isLoading &&
+    !loadingInstanceInfo &&
+    !isAuthenticated &&
+    !token &&
+    !!instanceInfo?.forward_auth_enabled &&
+    !forwardAuthAttempted;
 
   useEffect(() => {
     let cancelled = false;
@@ -342,7 +380,26 @@ export function ProtectedRoute({ children }: { children: ReactNode }) {
     };
   }, []);
 
-  if (isLoading || loadingInstanceInfo) return null;
+  useEffect(() => {
+    if (!shouldRestoreForwardAuth) {
+      return;
+    }
+    // Only once per mount, so signing out lands on the login page instead of signing straight back in.
+    setForwardAuthAttempted(true);
+    setRestoringForwardAuth(true);
+    void restoreForwardAuthSession().finally(() =>
+      setRestoringForwardAuth(false),
+    );
+  }, [shouldRestoreForwardAuth, restoreForwardAuthSession]);
+
+  if (
+    isLoading ||
+    loadingInstanceInfo ||
+    shouldRestoreForwardAuth ||
+    restoringForwardAuth
+  ) {
+    return null;
+  }
   if (!isAuthenticated) {
     return <Navigate to="/login" state={{ from: location }} replace />;
   }
@@ -0,0 +1,103 @@
+import { render, screen } from "@testing-library/react";
+import { QueryClient, QueryClientProvider } from "@tanstack/react-query";
+import { MemoryRouter, Route, Routes } from "react-router-dom";
+import { beforeEach, describe, expect, it, vi } from "vitest";
+import { api } from "@/api";
+import { AuthProvider, ProtectedRoute } from "@/stores/auth";
+
+vi.mock("@/api", () => ({
+  api: {
+    auth: {
+      getAuth: vi.fn(),
+    },
+    instance: {
+      infoList: vi.fn(),
+    },
+  },
+}));
+
+const PROXY_USER = {
+  id: "proxy-user",
+  email: "proxy@example.com",
+  email_verified_at: "2026-01-01T00:00:00Z",
+};
+
+function renderProtectedPage() {
+  const queryClient = new QueryClient({
+    defaultOptions: { queries: { retry: false } },
+  });
+  render(
+    <QueryClientProvider client={queryClient}>
+      <AuthProvider>
+        <MemoryRouter initialEntries={["/vaults"]}>
+          <Routes>
+            <Route
+              path="/vaults"
+              element={
+                <ProtectedRoute>
+                  <div data-testid="vaults-page">Vaults</div>
+                </ProtectedRoute>
+              }
+            />
+            <Route
+              path="/login"
+              element={<div data-testid="login-page">Login</div>}
+            />
+          </Routes>
+        </MemoryRouter>
+      </AuthProvider>
+    </QueryClientProvider>,
+  );
+}
+
+describe("forward auth session", () => {
+  beforeEach(() => {
+    localStorage.clear();
+    vi.resetAllMocks();
+  });
+
+  it("signs in the user the proxy authenticated", async () => {
+    // Given
+    vi.mocked(api.instance.infoList).mockResolvedValue({
+      data: { forward_auth_enabled: true },
+    } as Awaited<ReturnType<typeof api.instance.infoList>>);
+    vi.mocked(api.auth.getAuth).mockResolvedValue({
+      data: PROXY_USER,
+    } as Awaited<ReturnType<typeof api.auth.getAuth>>);
+
+    // When
+    renderProtectedPage();
+
+    // Then
+    expect(await screen.findByTestId("vaults-page")).toBeInTheDocument();
+    expect(localStorage.getItem("token")).toBeNull();
+  });
+
+  it("sends the user to the login page when the proxy did not authenticate", async () => {
+    // Given
+    vi.mocked(api.instance.infoList).mockResolvedValue({
+      data: { forward_auth_enabled: true },
+    } as Awaited<ReturnType<typeof api.instance.infoList>>);
+    vi.mocked(api.auth.getAuth).mockRejectedValue(new Error("unauthorized"));
+
+    // When
+    renderProtectedPage();
+
+    // Then
+    expect(await screen.findByTestId("login-page")).toBeInTheDocument();
+  });
+
+  it("does not ask for the user when forward auth is off", async () => {
+    // Given
+    vi.mocked(api.instance.infoList).mockResolvedValue({
+      data: { forward_auth_enabled: false },
+    } as Awaited<ReturnType<typeof api.instance.infoList>>);
+
+    // When
+    renderProtectedPage();
+
+    // Then
+    expect(await screen.findByTestId("login-page")).toBeInTheDocument();
+    expect(api.auth.getAuth).not.toHaveBeenCalled();
+  });
+});
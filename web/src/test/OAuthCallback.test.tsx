@@ -38,8 +38,11 @@ function renderOAuthCallback(search = "?token=fake-oauth-token") {
 
 describe("OAuthCallback", () => {
   it("calls setExternalToken and navigates to vaults when token present", () => {
-    renderOAuthCallback("?token=my-token");
-    expect(mockSetExternalToken).toHaveBeenCalledWith("my-token");
+    renderOAuthCallback("?token=my-token&refresh_token=my-refresh-token");
+    expect(mockSetExternalToken).toHaveBeenCalledWith(
+      "my-token",
+      "my-refresh-token",
+    );
     expect(mockNavigate).toHaveBeenCalledWith("/vaults", { replace: true });
   });
 
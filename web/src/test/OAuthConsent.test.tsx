@@ -0,0 +1,99 @@
+import { render, screen, waitFor } from "@testing-library/react";
+import userEvent from "@testing-library/user-event";
+import { QueryClient, QueryClientProvider } from "@tanstack/react-query";
+import { MemoryRouter } from "react-router-dom";
+import { App as AntApp, ConfigProvider } from "antd";
+import { beforeEach, describe, expect, it, vi } from "vitest";
+import { httpClient } from "@/api";
+import Consent from "@/pages/oauth/Consent";
+
+vi.mock("@/api", () => ({
+  httpClient: {
+    instance: {
+      get: vi.fn(),
+      post: vi.fn(),
+    },
+  },
+}));
+
+const CONSENT = {
+  client_id: "cli",
+  client_name: "Bonds CLI",
+  redirect_uri: "https://cli.example.com/callback",
+  scopes: [{ name: "contacts:read", description: "Read your contacts" }],
+};
+
+function renderConsent(path: string) {
+  const queryClient = new QueryClient({
+    defaultOptions: { queries: { retry: false } },
+  });
+  return render(
+    <ConfigProvider>
+      <AntApp>
+        <QueryClientProvider client={queryClient}>
+          <MemoryRouter initialEntries={[path]}>
+            <Consent />
+          </MemoryRouter>
+        </QueryClientProvider>
+      </AntApp>
+    </ConfigProvider>,
+  );
+}
+
+describe("OAuth consent page", () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+  });
+
+  it("shows the app and the scopes it asks for", async () => {
+    // Given
+    vi.mocked(httpClient.instance.get).mockResolvedValue({
+      data: { success: true, data: CONSENT },
+    });
+
+    // When
+    renderConsent("/authorize?request_id=req-1");
+
+    // Then
+    expect(await screen.findByText("Read your contacts")).toBeInTheDocument();
+    expect(screen.getByText(/Bonds CLI/)).toBeInTheDocument();
+    expect(screen.getByText(/cli\.example\.com/)).toBeInTheDocument();
+    expect(httpClient.instance.get).toHaveBeenCalledWith(
+      "/oauth/authorize/req-1",
+    );
+  });
+
+  it("answers the request when the user allows it", async () => {
+    // Given
+    vi.mocked(httpClient.instance.get).mockResolvedValue({
+      data: { success: true, data: CONSENT },
+    });
+    vi.mocked(httpClient.instance.post).mockResolvedValue({
+      data: {
+        success: true,
+        data: { redirect_url: "https://cli.example.com/callback?code=abc" },
+      },
+    });
+    renderConsent("/authorize?request_id=req-1");
+    await screen.findByText("Read your contacts");
+
+    // When
+    await userEvent.click(screen.getByRole("button", { name: "Allow" }));
+
+    // Then
+    await waitFor(() =>
+      expect(httpClient.instance.post).toHaveBeenCalledWith(
+        "/oauth/authorize/req-1",
+        { approve: true },
+      ),
+    );
+  });
+
+  it("does not look anything up without a request ID", () => {
+    // When
+    renderConsent("/authorize");
+
+    // Then
+    expect(httpClient.instance.get).not.toHaveBeenCalled();
+  });
+});
@@ -0,0 +1,86 @@
+import type { AxiosAdapter } from "axios";
+import { afterEach, beforeEach, describe, expect, it } from "vitest";
+import { httpClient } from "@/api";
+import {
+  createAxiosResponse,
+  createUnauthorizedAxiosError,
+} from "@/test/authRefreshTestSupport";
+
+const EXPIRED_TOKEN = "expired-access-token";
+const NEW_TOKEN = "new-access-token";
+
+function installRefreshTokenAdapter() {
+  const refreshRequests: { url?: string; data: unknown }[] = [];
+  const protectedAuthorizations: unknown[] = [];
+  const adapter: AxiosAdapter = async (config) => {
+    if (config.url?.startsWith("/auth/refresh")) {
+      refreshRequests.push({
+        url: config.url,
+        data: config.data ? JSON.parse(config.data as string) : undefined,
+      });
+      return createAxiosResponse(config, {
+        data: { token: NEW_TOKEN, refresh_token: `next-${refreshRequests.length}` },
+      });
+    }
+    protectedAuthorizations.push(config.headers.get("Authorization"));
+    if (protectedAuthorizations.length === 1) {
+      throw createUnauthorizedAxiosError(config);
+    }
+    return createAxiosResponse(config, { ok: true });
+  };
+  httpClient.instance.defaults.adapter = adapter;
+  return { refreshRequests, protectedAuthorizations };
+}
+
+describe("authentication refresh tokens", () => {
+  const originalAdapter = httpClient.instance.defaults.adapter;
+
+  beforeEach(() => {
+    localStorage.clear();
+    window.history.replaceState({}, "", "/login");
+  });
+
+  afterEach(() => {
+    httpClient.instance.defaults.adapter = originalAdapter;
+  });
+
+  it("redeems the stored refresh token and keeps its successor", async () => {
+    // Given
+    localStorage.setItem("token", EXPIRED_TOKEN);
+    localStorage.setItem("refresh_token", "first-refresh-token");
+    const { refreshRequests, protectedAuthorizations } =
+      installRefreshTokenAdapter();
+
+    // When
+    await httpClient.instance.get("/protected");
+
+    // Then
+    expect(refreshRequests).toEqual([
+      {
+        url: "/auth/refresh-token",
+        data: { refresh_token: "first-refresh-token" },
+      },
+    ]);
+    expect(protectedAuthorizations).toEqual([
+      `Bearer ${EXPIRED_TOKEN}`,
+      `Bearer ${NEW_TOKEN}`,
+    ]);
+    expect(localStorage.getItem("token")).toBe(NEW_TOKEN);
+    expect(localStorage.getItem("refresh_token")).toBe("next-1");
+  });
+
+  it("trades a session without a refresh token for one", async () => {
+    // Given
+    localStorage.setItem("token", EXPIRED_TOKEN);
+    const { refreshRequests } = installRefreshTokenAdapter();
+
+    // When
+    await httpClient.instance.get("/protected");
+
+    // Then
+    expect(refreshRequests.map((request) => request.url)).toEqual([
+      "/auth/refresh",
+    ]);
+    expect(localStorage.getItem("refresh_token")).toBe("next-1");
+  });
+});
@@ -71,14 +71,44 @@ export function subscribeAuthenticationTokenReplacement(
   return () => tokenReplacementListeners.delete(listener);
 }
 
-export function replaceCurrentAuthenticationToken(token: string): void {
+const REFRESH_TOKEN_KEY = "refresh_token";
+
+export function getCurrentRefreshToken(): string | null {
+  return localStorage.getItem(REFRESH_TOKEN_KEY);
+}
+
+// A new sign-in replaces both tokens; one without a refresh token must not keep the previous subject's.
+export function storeAuthenticationTokens(
+  token: string,
+  refreshToken?: string | null,
+): void {
+  localStorage.setItem("token", token);
+  if (refreshToken) {
+    localStorage.setItem(REFRESH_TOKEN_KEY, refreshToken);
+  } else {
+    localStorage.removeItem(REFRESH_TOKEN_KEY);
+  }
+}
+
+export function clearAuthenticationTokens(): void {
+  localStorage.removeItem("token");
+  localStorage.removeItem(REFRESH_TOKEN_KEY);
+}
+
+export function replaceCurrentAuthenticationToken(
+  token: string,
+  refreshToken?: string,
+): void {
   // Token rotation keeps the same subject revision, so Provider state needs a separate replacement signal.
   localStorage.setItem("token", token);
+  if (refreshToken) {
+    localStorage.setItem(REFRESH_TOKEN_KEY, refreshToken);
+  }
   tokenReplacementListeners.forEach((listener) => listener(token));
 }
 
 export function terminateCurrentAuthenticationSubject(): void {
   advanceAuthenticationSubjectRevision();
-  localStorage.removeItem("token");
+  clearAuthenticationTokens();
   subjectTerminationListeners.forEach((listener) => listener());
 }